@@ -0,0 +1,10 @@
+// Package api содержит контракты API сервера метрик: спецификацию OpenAPI HTTP API (openapi.json)
+// и описание gRPC API (metrical.proto).
+package api
+
+import _ "embed"
+
+// OpenAPI спецификация OpenAPI 3 HTTP API в формате JSON
+//
+//go:embed openapi.json
+var OpenAPI []byte
@@ -21,6 +21,14 @@ var (
 	pollInterval   int
 	reportInterval int
 	verboseLogging bool
+	signingKey     string
+	cryptoKey      string
+	tlsCA          string
+	tlsCert        string
+	tlsKey         string
+	authToken      string
+	compressionAlg string
+	grpcAddr       string
 )
 
 // rootCmd представляет корневую команду приложения
@@ -33,11 +41,29 @@ Supported flags:
   -a: HTTP server endpoint address (default: localhost:8080)
   -p: Poll interval in seconds (default: 2)
   -r: Report interval in seconds (default: 10)
+  -k: Shared key for HMAC-SHA256 request signing (default: empty, signing disabled)
+  --crypto-key: Path to server RSA public key for request encryption (default: empty, encryption disabled)
+  --tls-ca: Path to CA bundle for server certificate verification (enables HTTPS)
+  --tls-cert: Path to client certificate for mutual TLS (enables HTTPS)
+  --tls-key: Path to client certificate private key
+  --token: API token with write scope sent as Authorization: Bearer (default: empty)
+  --compression: Request body compression: gzip, deflate or zstd (default: gzip)
+  --grpc-addr: gRPC API address host:port for sending metric batches (default: empty, HTTP is used)
 
 Environment variables:
   ADDRESS: HTTP server endpoint address
   POLL_INTERVAL: Poll interval in seconds
-  REPORT_INTERVAL: Report interval in seconds`,
+  REPORT_INTERVAL: Report interval in seconds
+  KEY: Shared key for HMAC-SHA256 request signing
+  CRYPTO_KEY: Path to server RSA public key for request encryption
+  TLS_CA: Path to CA bundle for server certificate verification
+  TLS_CERT: Path to client certificate for mutual TLS
+  TLS_KEY: Path to client certificate private key
+  AUTH_TOKEN: API token with write scope
+  COMPRESSION: Request body compression (gzip, deflate, zstd)
+  GRPC_ADDRESS: gRPC API address for sending metric batches
+
+The agent connects over HTTPS when any TLS option is set or the address starts with https://.`,
 	RunE: runAgent,
 }
 
@@ -52,6 +78,14 @@ func init() {
 	rootCmd.Flags().IntVarP(&pollInterval, "p", "p", defaultPollInterval, "Poll interval in seconds")
 	rootCmd.Flags().IntVarP(&reportInterval, "r", "r", defaultReportInterval, "Report interval in seconds")
 	rootCmd.Flags().BoolVarP(&verboseLogging, "v", "v", false, "Enable verbose logging")
+	rootCmd.Flags().StringVarP(&signingKey, "k", "k", getEnvOrDefault("KEY", ""), "Shared key for HMAC-SHA256 request signing")
+	rootCmd.Flags().StringVar(&cryptoKey, "crypto-key", getEnvOrDefault("CRYPTO_KEY", ""), "Path to server RSA public key for request encryption")
+	rootCmd.Flags().StringVar(&tlsCA, "tls-ca", getEnvOrDefault("TLS_CA", ""), "Path to CA bundle for server certificate verification")
+	rootCmd.Flags().StringVar(&tlsCert, "tls-cert", getEnvOrDefault("TLS_CERT", ""), "Path to client certificate for mutual TLS")
+	rootCmd.Flags().StringVar(&tlsKey, "tls-key", getEnvOrDefault("TLS_KEY", ""), "Path to client certificate private key")
+	rootCmd.Flags().StringVar(&authToken, "token", getEnvOrDefault("AUTH_TOKEN", ""), "API token with write scope")
+	rootCmd.Flags().StringVar(&compressionAlg, "compression", getEnvOrDefault("COMPRESSION", agent.DefaultCompression), "Request body compression: gzip, deflate or zstd")
+	rootCmd.Flags().StringVar(&grpcAddr, "grpc-addr", getEnvOrDefault("GRPC_ADDRESS", ""), "gRPC API address host:port for sending metric batches")
 
 	// Отключаем автоматическое использование флага help, так как Cobra его добавляет автоматически
 	rootCmd.Flags().BoolP("help", "h", false, "Show help")
@@ -116,6 +150,9 @@ func runAgent(cmd *cobra.Command, args []string) error {
 	finalServerURL := getFinalValue("ADDRESS", serverURL, agent.DefaultServerURL)
 	finalPollInterval := getFinalIntValue("POLL_INTERVAL", pollInterval, int(agent.DefaultPollInterval.Seconds()))
 	finalReportInterval := getFinalIntValue("REPORT_INTERVAL", reportInterval, int(agent.DefaultReportInterval.Seconds()))
+	finalKey := getFinalValue("KEY", signingKey, "")
+	finalCryptoKey := getFinalValue("CRYPTO_KEY", cryptoKey, "")
+	finalGRPCAddr := getFinalValue("GRPC_ADDRESS", grpcAddr, "")
 
 	// Создаем конфигурацию из финальных значений
 	config := &agent.Config{
@@ -123,6 +160,12 @@ func runAgent(cmd *cobra.Command, args []string) error {
 		PollInterval:   time.Duration(finalPollInterval) * time.Second,
 		ReportInterval: time.Duration(finalReportInterval) * time.Second,
 		VerboseLogging: verboseLogging,
+		Key:            finalKey,
+		TLSCAFile:      getFinalValue("TLS_CA", tlsCA, ""),
+		TLSCertFile:    getFinalValue("TLS_CERT", tlsCert, ""),
+		TLSKeyFile:     getFinalValue("TLS_KEY", tlsKey, ""),
+		Token:          getFinalValue("AUTH_TOKEN", authToken, ""),
+		Compression:    getFinalValue("COMPRESSION", compressionAlg, agent.DefaultCompression),
 	}
 
 	// Валидируем конфигурацию
@@ -130,15 +173,31 @@ func runAgent(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	// gRPC сообщения не шифруются ключом сервера: для защиты канала используется TLS
+	if finalGRPCAddr != "" && finalCryptoKey != "" {
+		return fmt.Errorf("invalid configuration: crypto key is not supported with gRPC, use TLS options instead")
+	}
+
 	// Логируем конфигурацию при запуске
-	log.Printf("Agent configuration: server=%s, poll=%v, report=%v, verbose=%v",
-		config.ServerURL, config.PollInterval, config.ReportInterval, config.VerboseLogging)
+	log.Printf("Agent configuration: server=%s, poll=%v, report=%v, verbose=%v, signing=%v, tls=%v, compression=%s",
+		config.BaseURL(), config.PollInterval, config.ReportInterval, config.VerboseLogging, config.Key != "", config.UsesTLS(), config.ContentEncoding())
 
 	// Создаем логгер
 	agentLogger := logger.NewSlogLogger()
 
 	// Создаем и запускаем агент
 	metricsAgent := agent.NewAgent(config, agentLogger)
+	if finalCryptoKey != "" {
+		if err := metricsAgent.EnableEncryption(finalCryptoKey); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+	}
+	if finalGRPCAddr != "" {
+		if err := metricsAgent.EnableGRPC(finalGRPCAddr); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+		log.Printf("Sending metric batches via gRPC: %s", finalGRPCAddr)
+	}
 
 	// Создаем контекст с отменой для graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -77,6 +77,57 @@ func TestRootCmdFlags(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "with signing key",
+			args: []string{"-k", "secret"},
+			expectedConfig: &agent.Config{
+				ServerURL:      agent.DefaultServerURL,
+				PollInterval:   agent.DefaultPollInterval,
+				ReportInterval: agent.DefaultReportInterval,
+				Key:            "secret",
+			},
+			expectError: false,
+		},
+		{
+			name: "with TLS settings",
+			args: []string{"--tls-ca", "/etc/metrical/ca.pem", "--tls-cert", "/etc/metrical/client.pem", "--tls-key", "/etc/metrical/client-key.pem"},
+			expectedConfig: &agent.Config{
+				ServerURL:      agent.DefaultServerURL,
+				PollInterval:   agent.DefaultPollInterval,
+				ReportInterval: agent.DefaultReportInterval,
+				TLSCAFile:      "/etc/metrical/ca.pem",
+				TLSCertFile:    "/etc/metrical/client.pem",
+				TLSKeyFile:     "/etc/metrical/client-key.pem",
+			},
+			expectError: false,
+		},
+		{
+			name: "with API token",
+			args: []string{"--token", "agent-token"},
+			expectedConfig: &agent.Config{
+				ServerURL:      agent.DefaultServerURL,
+				PollInterval:   agent.DefaultPollInterval,
+				ReportInterval: agent.DefaultReportInterval,
+				Token:          "agent-token",
+			},
+			expectError: false,
+		},
+		{
+			name: "with zstd compression",
+			args: []string{"--compression", "zstd"},
+			expectedConfig: &agent.Config{
+				ServerURL:      agent.DefaultServerURL,
+				PollInterval:   agent.DefaultPollInterval,
+				ReportInterval: agent.DefaultReportInterval,
+				Compression:    "zstd",
+			},
+			expectError: false,
+		},
+		{
+			name:        "unsupported compression",
+			args:        []string{"--compression", "br"},
+			expectError: true,
+		},
 		{
 			name:        "unknown argument",
 			args:        []string{"unknown"},
@@ -111,6 +162,7 @@ func TestRootCmdFlags(t *testing.T) {
 						ServerURL:      serverURL,
 						PollInterval:   time.Duration(pollInterval) * time.Second,
 						ReportInterval: time.Duration(reportInterval) * time.Second,
+						Compression:    compressionAlg,
 					}
 
 					// Валидируем конфигурацию
@@ -127,6 +179,12 @@ func TestRootCmdFlags(t *testing.T) {
 			cmd.Flags().IntVarP(&pollInterval, "p", "p", int(agent.DefaultPollInterval.Seconds()), "Poll interval in seconds")
 			cmd.Flags().IntVarP(&reportInterval, "r", "r", int(agent.DefaultReportInterval.Seconds()), "Report interval in seconds")
 			cmd.Flags().BoolVarP(&verboseLogging, "v", "v", false, "Enable verbose logging")
+			cmd.Flags().StringVarP(&signingKey, "k", "k", "", "Shared key for HMAC-SHA256 request signing")
+			cmd.Flags().StringVar(&tlsCA, "tls-ca", "", "Path to CA bundle for server certificate verification")
+			cmd.Flags().StringVar(&tlsCert, "tls-cert", "", "Path to client certificate for mutual TLS")
+			cmd.Flags().StringVar(&tlsKey, "tls-key", "", "Path to client certificate private key")
+			cmd.Flags().StringVar(&authToken, "token", "", "API token with write scope")
+			cmd.Flags().StringVar(&compressionAlg, "compression", agent.DefaultCompression, "Request body compression: gzip, deflate or zstd")
 
 			// Устанавливаем аргументы
 			cmd.SetArgs(tt.args)
@@ -149,9 +207,21 @@ func TestRootCmdFlags(t *testing.T) {
 					PollInterval:   time.Duration(pollInterval) * time.Second,
 					ReportInterval: time.Duration(reportInterval) * time.Second,
 					VerboseLogging: verboseLogging,
+					Key:            signingKey,
+					TLSCAFile:      tlsCA,
+					TLSCertFile:    tlsCert,
+					TLSKeyFile:     tlsKey,
+					Token:          authToken,
+					Compression:    compressionAlg,
 				}
 
 				assert.Equal(t, tt.expectedConfig.ServerURL, config.ServerURL)
+				assert.Equal(t, tt.expectedConfig.Key, config.Key)
+				assert.Equal(t, tt.expectedConfig.TLSCAFile, config.TLSCAFile)
+				assert.Equal(t, tt.expectedConfig.TLSCertFile, config.TLSCertFile)
+				assert.Equal(t, tt.expectedConfig.TLSKeyFile, config.TLSKeyFile)
+				assert.Equal(t, tt.expectedConfig.Token, config.Token)
+				assert.Equal(t, tt.expectedConfig.ContentEncoding(), config.ContentEncoding())
 				assert.Equal(t, tt.expectedConfig.PollInterval, config.PollInterval)
 				assert.Equal(t, tt.expectedConfig.ReportInterval, config.ReportInterval)
 				// Проверяем VerboseLogging только для теста с verbose
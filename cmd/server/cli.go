@@ -10,12 +10,66 @@ import (
 
 // ServerConfig содержит конфигурацию сервера
 type ServerConfig struct {
-	Address         string
-	StoreInterval   int
-	FileStoragePath string
-	Restore         bool
+	Address               string
+	StoreInterval         int
+	FileStoragePath       string
+	Restore               bool
+	DatabaseDSN           string
+	WALEnabled            bool
+	WALCheckpointInterval int
+	SnapshotKeep          int
+	HistoryRetention      int
+	HistorySize           int
+	IdempotencyTTL        int
+	IdempotencySize       int
+	Key                   string
+	CryptoKey             string
+	TLSCert               string
+	TLSKey                string
+	TLSClientCA           string
+	TrustedSubnet         string
+	TrustedReadSubnet     string
+	TrustedProxies        string // CIDR доверенных прокси через запятую
+	AuthTokensFile        string
+	RateLimitWrite        int
+	RateLimitWriteBurst   int
+	RateLimitRead         int
+	RateLimitReadBurst    int
+	MaxBodySize           int64
+	MaxCompressedBodySize int64
+	AlertRules            string
+	AlertInterval         int
+	AlertWebhooks         string // Адреса webhook через запятую
+	StatsDAddr            string
+	StatsDAllowUnsigned   bool
+	GRPCAddr              string
+
+	RemoteWriteMaxBodySize    int64
+	RemoteWriteMaxDecodedSize int64
+	RemoteWriteMaxSeries      int
+	RemoteWriteSeriesTTL      int
+	OTLPMaxStreams            int
+	OTLPStreamTTL             int
 }
 
+// Ограничения размера тела запроса по умолчанию
+const (
+	defaultMaxBodySize           int64 = 16 << 20
+	defaultMaxCompressedBodySize int64 = 4 << 20
+
+	defaultRemoteWriteMaxBodySize    int64 = 8 << 20
+	defaultRemoteWriteMaxDecodedSize int64 = 32 << 20
+)
+
+// Ограничения состояния рядов counter remote_write по умолчанию
+const (
+	defaultRemoteWriteMaxSeries = 100000
+	defaultRemoteWriteSeriesTTL = 3600
+
+	defaultOTLPMaxStreams = 100000
+	defaultOTLPStreamTTL  = 3600
+)
+
 // parseFlags парсит флаги командной строки
 func parseFlags() (ServerConfig, error) {
 	var config ServerConfig
@@ -29,7 +83,42 @@ Environment variables:
   ADDRESS: адрес эндпоинта HTTP-сервера
   STORE_INTERVAL: интервал сохранения метрик в секундах (по умолчанию 300)
   FILE_STORAGE_PATH: путь к файлу для сохранения метрик
-  RESTORE: загружать ли метрики при старте (true/false)`,
+  RESTORE: загружать ли метрики при старте (true/false)
+  DATABASE_DSN: строка подключения к PostgreSQL (если задана, метрики хранятся в БД)
+  WAL_ENABLED: использовать журнал упреждающей записи при STORE_INTERVAL=0 (true/false)
+  WAL_CHECKPOINT_INTERVAL: интервал контрольных точек журнала в секундах (по умолчанию 60)
+  SNAPSHOT_KEEP: количество хранимых снапшотов файла метрик (по умолчанию 3)
+  HISTORY_RETENTION: срок хранения истории значений метрик в секундах (по умолчанию 3600, 0 - отключить)
+  HISTORY_SIZE: максимальное количество значений истории на метрику (по умолчанию 3600)
+  IDEMPOTENCY_TTL: время хранения результатов запросов с Idempotency-Key в секундах (по умолчанию 300, 0 - отключить)
+  IDEMPOTENCY_SIZE: максимальное количество хранимых ключей идемпотентности (по умолчанию 10000)
+  KEY: общий ключ подписи запросов и ответов HMAC-SHA256 (пустая строка - подпись отключена)
+  CRYPTO_KEY: путь к приватному RSA ключу для расшифровки запросов агента (пустая строка - отключено)
+  TLS_CERT: путь к сертификату сервера в формате PEM (если задан вместе с TLS_KEY, сервер обслуживает HTTPS)
+  TLS_KEY: путь к приватному ключу сертификата сервера
+  TLS_CLIENT_CA: путь к бандлу CA для проверки клиентских сертификатов (mutual TLS)
+  TRUSTED_SUBNET: CIDR подсети, из которой разрешена запись метрик (по X-Real-IP или адресу соединения)
+  TRUSTED_READ_SUBNET: CIDR подсети, из которой разрешено чтение метрик (пустая строка - без ограничений)
+  TRUSTED_PROXIES: CIDR прокси через запятую, которым доверяется X-Real-IP при ограничении частоты (пустая строка - лимит по адресу соединения)
+  AUTH_TOKENS_FILE: путь к JSON файлу с API токенами (если задан, запросы к метрикам требуют Authorization: Bearer)
+  RATE_LIMIT_WRITE: лимит запросов на запись в секунду для каждого клиента (по умолчанию 0 - без ограничений)
+  RATE_LIMIT_WRITE_BURST: допустимый всплеск запросов на запись (по умолчанию 0 - равен лимиту)
+  RATE_LIMIT_READ: лимит запросов на чтение в секунду для каждого клиента (по умолчанию 0 - без ограничений)
+  RATE_LIMIT_READ_BURST: допустимый всплеск запросов на чтение (по умолчанию 0 - равен лимиту)
+  MAX_BODY_SIZE: максимальный размер несжатого тела запроса в байтах (по умолчанию 16777216, 0 - без ограничений)
+  MAX_COMPRESSED_BODY_SIZE: максимальный размер сжатого тела запроса в байтах (по умолчанию 4194304, 0 - без ограничений)
+  ALERT_RULES: путь к YAML/JSON файлу правил алертинга (пустая строка - алертинг отключен)
+  ALERT_INTERVAL: интервал вычисления правил алертинга в секундах (по умолчанию 15)
+  ALERT_WEBHOOKS: адреса webhook для уведомлений об алертах через запятую (дополняют адреса из файла правил)
+  STATSD_ADDR: UDP адрес приема метрик по протоколу StatsD, например :8125 (пустая строка - отключен)
+  STATSD_ALLOW_UNSIGNED: разрешить прием StatsD при заданных KEY или AUTH_TOKENS_FILE - StatsD не проверяет подпись и токены (true/false)
+  GRPC_ADDRESS: адрес gRPC API, например :3200 (пустая строка - отключен)
+  REMOTE_WRITE_MAX_BODY_SIZE: максимальный размер сжатого тела запроса Prometheus remote_write в байтах (по умолчанию 8388608, 0 - без ограничений)
+  REMOTE_WRITE_MAX_DECODED_SIZE: максимальный размер распакованного тела запроса remote_write в байтах (по умолчанию 33554432, 0 - без ограничений)
+  REMOTE_WRITE_MAX_SERIES: максимальное количество отслеживаемых рядов counter remote_write (по умолчанию 100000, 0 - без ограничений)
+  REMOTE_WRITE_SERIES_TTL: время хранения состояния ряда remote_write без новых отсчетов в секундах (по умолчанию 3600, 0 - без ограничения)
+  OTLP_MAX_STREAMS: максимальное количество отслеживаемых потоков монотонных сумм OTLP (по умолчанию 100000, 0 - без ограничений)
+  OTLP_STREAM_TTL: время хранения состояния потока OTLP без новых точек в секундах (по умолчанию 3600, 0 - без ограничения)`,
 		Version: Version,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Проверяем на неизвестные аргументы
@@ -46,6 +135,42 @@ Environment variables:
 	cmd.Flags().IntVarP(&config.StoreInterval, "interval", "i", 300, "интервал сохранения метрик в секундах (0 для синхронного сохранения)")
 	cmd.Flags().StringVarP(&config.FileStoragePath, "file", "f", "/tmp/metrics-db.json", "путь к файлу для сохранения метрик")
 	cmd.Flags().BoolVarP(&config.Restore, "restore", "r", true, "загружать ли метрики при старте")
+	cmd.Flags().StringVarP(&config.DatabaseDSN, "database-dsn", "d", "", "строка подключения к PostgreSQL")
+	cmd.Flags().BoolVar(&config.WALEnabled, "wal", false, "использовать журнал упреждающей записи при синхронном сохранении (-i 0)")
+	cmd.Flags().IntVar(&config.WALCheckpointInterval, "wal-checkpoint-interval", 60, "интервал контрольных точек журнала в секундах")
+	cmd.Flags().IntVar(&config.SnapshotKeep, "snapshot-keep", 3, "количество хранимых снапшотов файла метрик (текущий + предыдущие)")
+	cmd.Flags().IntVar(&config.HistoryRetention, "history-retention", 3600, "срок хранения истории значений метрик в секундах (0 - отключить)")
+	cmd.Flags().IntVar(&config.HistorySize, "history-size", 3600, "максимальное количество значений истории на метрику")
+	cmd.Flags().IntVar(&config.IdempotencyTTL, "idempotency-ttl", 300, "время хранения результатов запросов с Idempotency-Key в секундах (0 - отключить)")
+	cmd.Flags().IntVar(&config.IdempotencySize, "idempotency-size", 10000, "максимальное количество хранимых ключей идемпотентности")
+	cmd.Flags().StringVarP(&config.Key, "key", "k", "", "общий ключ подписи запросов и ответов HMAC-SHA256")
+	cmd.Flags().StringVar(&config.CryptoKey, "crypto-key", "", "путь к приватному RSA ключу для расшифровки запросов агента")
+	cmd.Flags().StringVar(&config.TLSCert, "tls-cert", "", "путь к сертификату сервера в формате PEM (включает HTTPS)")
+	cmd.Flags().StringVar(&config.TLSKey, "tls-key", "", "путь к приватному ключу сертификата сервера")
+	cmd.Flags().StringVar(&config.TLSClientCA, "tls-client-ca", "", "путь к бандлу CA для проверки клиентских сертификатов (mutual TLS)")
+	cmd.Flags().StringVarP(&config.TrustedSubnet, "trusted-subnet", "t", "", "CIDR подсети, из которой разрешена запись метрик")
+	cmd.Flags().StringVar(&config.TrustedReadSubnet, "trusted-read-subnet", "", "CIDR подсети, из которой разрешено чтение метрик")
+	cmd.Flags().StringVar(&config.TrustedProxies, "trusted-proxies", "", "CIDR прокси через запятую, которым доверяется X-Real-IP при ограничении частоты")
+	cmd.Flags().StringVar(&config.AuthTokensFile, "auth-tokens-file", "", "путь к JSON файлу с API токенами (read, write, admin)")
+	cmd.Flags().IntVar(&config.RateLimitWrite, "rate-limit-write", 0, "лимит запросов на запись в секунду для каждого клиента (0 - без ограничений)")
+	cmd.Flags().IntVar(&config.RateLimitWriteBurst, "rate-limit-write-burst", 0, "допустимый всплеск запросов на запись (0 - равен лимиту)")
+	cmd.Flags().IntVar(&config.RateLimitRead, "rate-limit-read", 0, "лимит запросов на чтение в секунду для каждого клиента (0 - без ограничений)")
+	cmd.Flags().IntVar(&config.RateLimitReadBurst, "rate-limit-read-burst", 0, "допустимый всплеск запросов на чтение (0 - равен лимиту)")
+	cmd.Flags().Int64Var(&config.MaxBodySize, "max-body-size", defaultMaxBodySize, "максимальный размер несжатого тела запроса в байтах (0 - без ограничений)")
+	cmd.Flags().Int64Var(&config.MaxCompressedBodySize, "max-compressed-body-size", defaultMaxCompressedBodySize, "максимальный размер сжатого тела запроса в байтах (0 - без ограничений)")
+
+	cmd.Flags().StringVar(&config.AlertRules, "alert-rules", "", "путь к YAML/JSON файлу правил алертинга")
+	cmd.Flags().IntVar(&config.AlertInterval, "alert-interval", 15, "интервал вычисления правил алертинга в секундах")
+	cmd.Flags().StringVar(&config.AlertWebhooks, "alert-webhooks", "", "адреса webhook для уведомлений об алертах через запятую")
+	cmd.Flags().StringVar(&config.StatsDAddr, "statsd-addr", "", "UDP адрес приема метрик по протоколу StatsD (например, :8125)")
+	cmd.Flags().BoolVar(&config.StatsDAllowUnsigned, "statsd-allow-unsigned", false, "разрешить прием StatsD без подписи и API токенов при заданных --key или --auth-tokens-file")
+	cmd.Flags().StringVar(&config.GRPCAddr, "grpc-addr", "", "адрес gRPC API (например, :3200)")
+	cmd.Flags().Int64Var(&config.RemoteWriteMaxBodySize, "remote-write-max-body-size", defaultRemoteWriteMaxBodySize, "максимальный размер сжатого тела запроса Prometheus remote_write в байтах (0 - без ограничений)")
+	cmd.Flags().Int64Var(&config.RemoteWriteMaxDecodedSize, "remote-write-max-decoded-size", defaultRemoteWriteMaxDecodedSize, "максимальный размер распакованного тела запроса remote_write в байтах (0 - без ограничений)")
+	cmd.Flags().IntVar(&config.RemoteWriteMaxSeries, "remote-write-max-series", defaultRemoteWriteMaxSeries, "максимальное количество отслеживаемых рядов counter remote_write (0 - без ограничений)")
+	cmd.Flags().IntVar(&config.RemoteWriteSeriesTTL, "remote-write-series-ttl", defaultRemoteWriteSeriesTTL, "время хранения состояния ряда remote_write без новых отсчетов в секундах (0 - без ограничения)")
+	cmd.Flags().IntVar(&config.OTLPMaxStreams, "otlp-max-streams", defaultOTLPMaxStreams, "максимальное количество отслеживаемых потоков монотонных сумм OTLP (0 - без ограничений)")
+	cmd.Flags().IntVar(&config.OTLPStreamTTL, "otlp-stream-ttl", defaultOTLPStreamTTL, "время хранения состояния потока OTLP без новых точек в секундах (0 - без ограничения)")
 
 	// Парсим аргументы
 	if err := cmd.Execute(); err != nil {
@@ -67,12 +192,106 @@ Environment variables:
 	config.StoreInterval = getFinalIntValue("STORE_INTERVAL", config.StoreInterval, 300)
 	config.FileStoragePath = getFinalValue("FILE_STORAGE_PATH", config.FileStoragePath, "/tmp/metrics-db.json")
 	config.Restore = getFinalBoolValue("RESTORE", config.Restore, true)
+	config.DatabaseDSN = getFinalValue("DATABASE_DSN", config.DatabaseDSN, "")
+	config.WALEnabled = getFinalBoolValue("WAL_ENABLED", config.WALEnabled, false)
+	config.WALCheckpointInterval = getFinalIntValue("WAL_CHECKPOINT_INTERVAL", config.WALCheckpointInterval, 60)
+	config.SnapshotKeep = getFinalIntValue("SNAPSHOT_KEEP", config.SnapshotKeep, 3)
+	config.HistoryRetention = getFinalIntValue("HISTORY_RETENTION", config.HistoryRetention, 3600)
+	config.HistorySize = getFinalIntValue("HISTORY_SIZE", config.HistorySize, 3600)
+	config.IdempotencyTTL = getFinalIntValue("IDEMPOTENCY_TTL", config.IdempotencyTTL, 300)
+	config.IdempotencySize = getFinalIntValue("IDEMPOTENCY_SIZE", config.IdempotencySize, 10000)
+	config.Key = getFinalValue("KEY", config.Key, "")
+	config.CryptoKey = getFinalValue("CRYPTO_KEY", config.CryptoKey, "")
+	config.TLSCert = getFinalValue("TLS_CERT", config.TLSCert, "")
+	config.TLSKey = getFinalValue("TLS_KEY", config.TLSKey, "")
+	config.TLSClientCA = getFinalValue("TLS_CLIENT_CA", config.TLSClientCA, "")
+	config.TrustedSubnet = getFinalValue("TRUSTED_SUBNET", config.TrustedSubnet, "")
+	config.TrustedReadSubnet = getFinalValue("TRUSTED_READ_SUBNET", config.TrustedReadSubnet, "")
+	config.TrustedProxies = getFinalValue("TRUSTED_PROXIES", config.TrustedProxies, "")
+	config.AuthTokensFile = getFinalValue("AUTH_TOKENS_FILE", config.AuthTokensFile, "")
+	config.RateLimitWrite = getFinalIntValue("RATE_LIMIT_WRITE", config.RateLimitWrite, 0)
+	config.RateLimitWriteBurst = getFinalIntValue("RATE_LIMIT_WRITE_BURST", config.RateLimitWriteBurst, 0)
+	config.RateLimitRead = getFinalIntValue("RATE_LIMIT_READ", config.RateLimitRead, 0)
+	config.RateLimitReadBurst = getFinalIntValue("RATE_LIMIT_READ_BURST", config.RateLimitReadBurst, 0)
+	config.MaxBodySize = getFinalInt64Value("MAX_BODY_SIZE", config.MaxBodySize)
+	config.MaxCompressedBodySize = getFinalInt64Value("MAX_COMPRESSED_BODY_SIZE", config.MaxCompressedBodySize)
+	config.AlertRules = getFinalValue("ALERT_RULES", config.AlertRules, "")
+	config.AlertInterval = getFinalIntValue("ALERT_INTERVAL", config.AlertInterval, 15)
+	config.AlertWebhooks = getFinalValue("ALERT_WEBHOOKS", config.AlertWebhooks, "")
+	config.StatsDAddr = getFinalValue("STATSD_ADDR", config.StatsDAddr, "")
+	config.StatsDAllowUnsigned = getFinalBoolValue("STATSD_ALLOW_UNSIGNED", config.StatsDAllowUnsigned, false)
+	config.GRPCAddr = getFinalValue("GRPC_ADDRESS", config.GRPCAddr, "")
+	config.RemoteWriteMaxBodySize = getFinalInt64Value("REMOTE_WRITE_MAX_BODY_SIZE", config.RemoteWriteMaxBodySize)
+	config.RemoteWriteMaxDecodedSize = getFinalInt64Value("REMOTE_WRITE_MAX_DECODED_SIZE", config.RemoteWriteMaxDecodedSize)
+	config.RemoteWriteMaxSeries = getFinalIntValue("REMOTE_WRITE_MAX_SERIES", config.RemoteWriteMaxSeries, defaultRemoteWriteMaxSeries)
+	config.RemoteWriteSeriesTTL = getFinalIntValue("REMOTE_WRITE_SERIES_TTL", config.RemoteWriteSeriesTTL, defaultRemoteWriteSeriesTTL)
+	config.OTLPMaxStreams = getFinalIntValue("OTLP_MAX_STREAMS", config.OTLPMaxStreams, defaultOTLPMaxStreams)
+	config.OTLPStreamTTL = getFinalIntValue("OTLP_STREAM_TTL", config.OTLPStreamTTL, defaultOTLPStreamTTL)
 
 	// Валидируем финальный адрес
 	if err := validateAddress(config.Address); err != nil {
 		return ServerConfig{}, err
 	}
 
+	if err := validateTLS(config); err != nil {
+		return ServerConfig{}, err
+	}
+
+	if err := validateSubnet(config.TrustedSubnet); err != nil {
+		return ServerConfig{}, err
+	}
+	if err := validateSubnet(config.TrustedReadSubnet); err != nil {
+		return ServerConfig{}, err
+	}
+	for _, proxy := range splitList(config.TrustedProxies) {
+		if err := validateSubnet(proxy); err != nil {
+			return ServerConfig{}, err
+		}
+	}
+
+	if err := validateRateLimit("на запись", config.RateLimitWrite, config.RateLimitWriteBurst); err != nil {
+		return ServerConfig{}, err
+	}
+	if err := validateRateLimit("на чтение", config.RateLimitRead, config.RateLimitReadBurst); err != nil {
+		return ServerConfig{}, err
+	}
+
+	if err := validateBodySize(config.MaxBodySize, config.MaxCompressedBodySize); err != nil {
+		return ServerConfig{}, err
+	}
+
+	if err := validateBodySize(config.RemoteWriteMaxBodySize, config.RemoteWriteMaxDecodedSize); err != nil {
+		return ServerConfig{}, err
+	}
+
+	if config.RemoteWriteMaxSeries < 0 {
+		return ServerConfig{}, fmt.Errorf("максимальное количество рядов remote_write не может быть отрицательным: %d", config.RemoteWriteMaxSeries)
+	}
+	if config.RemoteWriteSeriesTTL < 0 {
+		return ServerConfig{}, fmt.Errorf("время хранения рядов remote_write не может быть отрицательным: %d", config.RemoteWriteSeriesTTL)
+	}
+	if config.OTLPMaxStreams < 0 {
+		return ServerConfig{}, fmt.Errorf("максимальное количество потоков OTLP не может быть отрицательным: %d", config.OTLPMaxStreams)
+	}
+	if config.OTLPStreamTTL < 0 {
+		return ServerConfig{}, fmt.Errorf("время хранения потоков OTLP не может быть отрицательным: %d", config.OTLPStreamTTL)
+	}
+
+	if err := validateAlerting(config.AlertInterval, config.AlertWebhooks); err != nil {
+		return ServerConfig{}, err
+	}
+
+	if err := validateStatsDAddr(config.StatsDAddr); err != nil {
+		return ServerConfig{}, err
+	}
+	if err := validateStatsDAuth(config); err != nil {
+		return ServerConfig{}, err
+	}
+
+	if err := validateGRPCAddr(config.GRPCAddr); err != nil {
+		return ServerConfig{}, err
+	}
+
 	return config, nil
 }
 
@@ -103,6 +322,18 @@ func getFinalIntValue(envKey string, flagValue, defaultValue int) int {
 	return flagValue
 }
 
+// getFinalInt64Value возвращает финальное значение int64 с учетом приоритета
+func getFinalInt64Value(envKey string, flagValue int64) int64 {
+	// 1. Переменная окружения (высший приоритет)
+	if envValue := os.Getenv(envKey); envValue != "" {
+		if intValue, err := strconv.ParseInt(envValue, 10, 64); err == nil {
+			return intValue
+		}
+	}
+	// 2. Флаг командной строки (средний приоритет)
+	return flagValue
+}
+
 // getFinalBoolValue возвращает финальное булево значение с учетом приоритета
 func getFinalBoolValue(envKey string, flagValue, defaultValue bool) bool {
 	// 1. Переменная окружения (высший приоритет)
@@ -3,6 +3,10 @@ package main
 import (
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
+
+	"github.com/IgorKilipenko/metrical/internal/alerting"
 )
 
 // HelpRequestedError представляет ошибку запроса справки
@@ -81,3 +85,107 @@ func validateAddress(addr string) error {
 
 	return nil
 }
+
+// validateTLS проверяет согласованность настроек TLS
+func validateTLS(config ServerConfig) error {
+	if (config.TLSCert == "") != (config.TLSKey == "") {
+		return fmt.Errorf("сертификат и ключ TLS должны задаваться вместе")
+	}
+	if config.TLSClientCA != "" && config.TLSCert == "" {
+		return fmt.Errorf("проверка клиентских сертификатов требует сертификат и ключ TLS сервера")
+	}
+	return nil
+}
+
+// validateSubnet проверяет CIDR доверенной подсети (пустая строка - без ограничений)
+func validateSubnet(cidr string) error {
+	if cidr == "" {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(cidr); err != nil {
+		return fmt.Errorf("некорректная подсеть '%s': ожидается CIDR, например 192.168.1.0/24", cidr)
+	}
+	return nil
+}
+
+// validateRateLimit проверяет лимит запросов и допустимый всплеск (0 - без ограничений / равен лимиту)
+func validateRateLimit(name string, rate, burst int) error {
+	if rate < 0 || burst < 0 {
+		return fmt.Errorf("лимит запросов %s не может быть отрицательным", name)
+	}
+	return nil
+}
+
+// validateBodySize проверяет ограничения размера тела запроса (0 - без ограничений)
+func validateBodySize(maxBodySize, maxCompressedBodySize int64) error {
+	if maxBodySize < 0 || maxCompressedBodySize < 0 {
+		return fmt.Errorf("ограничение размера тела запроса не может быть отрицательным")
+	}
+	return nil
+}
+
+// validateAlerting проверяет интервал вычисления правил и адреса webhook
+func validateAlerting(interval int, webhooks string) error {
+	if interval <= 0 {
+		return fmt.Errorf("интервал вычисления правил алертинга должен быть положительным")
+	}
+	for _, webhook := range splitList(webhooks) {
+		if err := alerting.ValidateWebhookURL(webhook); err != nil {
+			return fmt.Errorf("некорректный адрес webhook '%s': ожидается http(s)://host/path", webhook)
+		}
+	}
+	return nil
+}
+
+// validateStatsDAddr проверяет UDP адрес StatsD в формате host:port (пустая строка - отключен)
+func validateStatsDAddr(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("некорректный адрес StatsD '%s': ожидается host:port, например :8125", addr)
+	}
+	if portNum, err := strconv.Atoi(port); err != nil || portNum < 0 || portNum > 65535 {
+		return fmt.Errorf("некорректный порт StatsD '%s': ожидается число от 0 до 65535", port)
+	}
+	return nil
+}
+
+// validateStatsDAuth запрещает прием StatsD вместе с подписью или API токенами без явного разрешения:
+// StatsD пакеты не подписываются и не содержат токен, поэтому обходят эти проверки
+func validateStatsDAuth(config ServerConfig) error {
+	if config.StatsDAddr == "" || config.StatsDAllowUnsigned {
+		return nil
+	}
+	if config.Key != "" || config.AuthTokensFile != "" {
+		return fmt.Errorf("прием StatsD не проверяет подпись и API токены: задайте --statsd-allow-unsigned, чтобы разрешить его вместе с --key или --auth-tokens-file")
+	}
+	return nil
+}
+
+// validateGRPCAddr проверяет адрес gRPC API в формате host:port (пустая строка - отключен)
+func validateGRPCAddr(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("некорректный адрес gRPC '%s': ожидается host:port, например :3200", addr)
+	}
+	if portNum, err := strconv.Atoi(port); err != nil || portNum < 0 || portNum > 65535 {
+		return fmt.Errorf("некорректный порт gRPC '%s': ожидается число от 0 до 65535", port)
+	}
+	return nil
+}
+
+// splitList разбирает список значений через запятую, пропуская пустые элементы
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
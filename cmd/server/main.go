@@ -52,6 +52,41 @@ func main() {
 
 	appConfig, err := app.NewConfig(config.Address, config.StoreInterval, config.FileStoragePath, config.Restore)
 	handleError(err)
+	appConfig.DatabaseDSN = config.DatabaseDSN
+	appConfig.WALEnabled = config.WALEnabled
+	appConfig.WALCheckpointInterval = config.WALCheckpointInterval
+	appConfig.SnapshotKeep = config.SnapshotKeep
+	appConfig.HistoryRetention = config.HistoryRetention
+	appConfig.HistorySize = config.HistorySize
+	appConfig.IdempotencyTTL = config.IdempotencyTTL
+	appConfig.IdempotencySize = config.IdempotencySize
+	appConfig.Key = config.Key
+	appConfig.CryptoKey = config.CryptoKey
+	appConfig.TLSCertFile = config.TLSCert
+	appConfig.TLSKeyFile = config.TLSKey
+	appConfig.TLSClientCAFile = config.TLSClientCA
+	appConfig.TrustedSubnet = config.TrustedSubnet
+	appConfig.TrustedReadSubnet = config.TrustedReadSubnet
+	appConfig.TrustedProxies = splitList(config.TrustedProxies)
+	appConfig.AuthTokensFile = config.AuthTokensFile
+	appConfig.RateLimitWrite = config.RateLimitWrite
+	appConfig.RateLimitWriteBurst = config.RateLimitWriteBurst
+	appConfig.RateLimitRead = config.RateLimitRead
+	appConfig.RateLimitReadBurst = config.RateLimitReadBurst
+	appConfig.MaxBodySize = config.MaxBodySize
+	appConfig.MaxCompressedBodySize = config.MaxCompressedBodySize
+	appConfig.AlertRulesFile = config.AlertRules
+	appConfig.AlertInterval = config.AlertInterval
+	appConfig.AlertWebhooks = splitList(config.AlertWebhooks)
+	appConfig.StatsDAddr = config.StatsDAddr
+	appConfig.StatsDAllowUnsigned = config.StatsDAllowUnsigned
+	appConfig.GRPCAddr = config.GRPCAddr
+	appConfig.RemoteWriteMaxBodySize = config.RemoteWriteMaxBodySize
+	appConfig.RemoteWriteMaxDecodedSize = config.RemoteWriteMaxDecodedSize
+	appConfig.RemoteWriteMaxSeries = config.RemoteWriteMaxSeries
+	appConfig.RemoteWriteSeriesTTL = config.RemoteWriteSeriesTTL
+	appConfig.OTLPMaxStreams = config.OTLPMaxStreams
+	appConfig.OTLPStreamTTL = config.OTLPStreamTTL
 
 	application := app.New(appConfig)
 
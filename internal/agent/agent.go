@@ -1,18 +1,18 @@
 package agent
 
 import (
-	"bytes"
-	"compress/gzip"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"runtime"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/IgorKilipenko/metrical/internal/encryption"
 	"github.com/IgorKilipenko/metrical/internal/logger"
 	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/pkg/metricalclient"
 )
 
 // Константы для retry логики
@@ -21,6 +21,16 @@ const (
 	DefaultRetryDelay = 100 * time.Millisecond
 )
 
+// IdempotencyKeyHeader заголовок с ключом идемпотентности отправки.
+// Повторы одной отправки передают тот же ключ, и сервер не применяет приращения дважды.
+const IdempotencyKeyHeader = metricalclient.IdempotencyKeyHeader
+
+// RealIPHeader заголовок с IP адресом агента, по которому сервер проверяет доверенную подсеть
+const RealIPHeader = metricalclient.RealIPHeader
+
+// errClientUnavailable клиент HTTP API не создан из-за некорректной конфигурации
+var errClientUnavailable = errors.New("metrics API client is not configured")
+
 // MetricValue структура для хранения метрики
 type MetricValue struct {
 	Value     float64
@@ -33,9 +43,24 @@ type Agent struct {
 	config     *Config
 	metrics    *Metrics
 	mu         sync.RWMutex
-	httpClient HTTPClient
+	httpClient metricalclient.HTTPClient // HTTP клиент с TLS транспортом агента
+	client     *metricalclient.Client    // Клиент HTTP API сервера (сжатие, подпись, повторы)
+	ctx        context.Context           // Контекст отправок, отменяется при остановке агента
+	cancel     context.CancelFunc
 	done       chan struct{} // Канал для graceful shutdown
 	logger     logger.Logger
+	encryptor  *encryption.Encryptor // Шифрование тел запросов публичным ключом сервера (nil - отключено)
+	grpcClient *GRPCClient           // Отправка пакетов через gRPC API (nil - через HTTP)
+	pending    *pendingBatch         // Неподтвержденный пакет (используется только горутиной отправки)
+}
+
+// pendingBatch пакет метрик, отправка которого завершилась неоднозначной ошибкой: сервер мог
+// применить его, но ответ не получен. Пакет повторяется без изменений и с тем же ключом
+// идемпотентности, пока сервер его не подтвердит.
+type pendingBatch struct {
+	metrics map[string]any   // Исходные значения для списания приращений счетчиков
+	batch   []models.Metrics // Тело запроса (порядок метрик входит в отпечаток запроса на сервере)
+	key     string
 }
 
 // NewAgent создает новый экземпляр агента
@@ -44,27 +69,101 @@ func NewAgent(config *Config, agentLogger logger.Logger) *Agent {
 		agentLogger = logger.NewSlogLogger()
 	}
 
-	// Создаем базовый HTTP клиент
-	baseClient := &http.Client{
-		Timeout: DefaultHTTPTimeout,
+	ctx, cancel := context.WithCancel(context.Background())
+	agent := &Agent{
+		config:  config,
+		metrics: NewMetrics(),
+		httpClient: &http.Client{
+			Timeout:   DefaultHTTPTimeout,
+			Transport: newTransport(config, agentLogger),
+		},
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		logger: agentLogger,
 	}
+	agent.client = agent.newClient()
+
+	return agent
+}
+
+// newClient создает клиента HTTP API по конфигурации агента.
+// Config.Validate проверяет настройки заранее; при ошибке отправки по HTTP завершаются ошибкой.
+func (a *Agent) newClient() *metricalclient.Client {
+	config := metricalclient.DefaultConfig()
+	config.ServerURL = a.config.BaseURL()
+	config.Key = a.config.Key
+	config.Token = a.config.Token
+	config.Compression = a.config.ContentEncoding()
+	config.HTTPClient = a.httpClient
+	config.MaxAttempts = DefaultMaxRetries
+	config.RetryDelay = DefaultRetryDelay
+	config.SendRealIP = true
+	if a.encryptor != nil {
+		config.Encryptor = a.encryptor
+	}
+
+	client, err := metricalclient.New(config)
+	if err != nil {
+		a.logger.Error("failed to create metrics API client", "error", err)
+		return nil
+	}
+	return client
+}
 
-	// Обертываем в retry клиент
-	retryClient := NewRetryHTTPClient(baseClient, DefaultMaxRetries, DefaultRetryDelay, agentLogger)
+// newTransport создает HTTP транспорт с TLS конфигурацией агента
+func newTransport(config *Config, agentLogger logger.Logger) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config == nil || !config.UsesTLS() {
+		return transport
+	}
+
+	tlsConfig, err := config.TLSConfig()
+	if err != nil {
+		// Config.Validate проверяет сертификаты заранее; здесь остаются системные корневые
+		// сертификаты, и соединение с сервером не пройдет проверку
+		agentLogger.Error("failed to load TLS configuration", "error", err)
+		return transport
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	return transport
+}
+
+// EnableEncryption включает шифрование тел запросов публичным ключом сервера из PEM файла
+func (a *Agent) EnableEncryption(publicKeyPath string) error {
+	encryptor, err := encryption.NewEncryptorFromFile(publicKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load public key: %w", err)
+	}
+
+	a.encryptor = encryptor
+	a.client = a.newClient()
+	return nil
+}
 
-	return &Agent{
-		config:     config,
-		metrics:    NewMetrics(),
-		httpClient: retryClient,
-		done:       make(chan struct{}),
-		logger:     agentLogger,
+// EnableGRPC включает отправку пакетов метрик через gRPC API сервера по адресу host:port
+func (a *Agent) EnableGRPC(addr string) error {
+	client, err := NewGRPCClient(addr, a.config, a.logger)
+	if err != nil {
+		return err
 	}
+
+	a.grpcClient = client
+	return nil
 }
 
 // Stop останавливает агента gracefully
 func (a *Agent) Stop() {
 	a.logger.Info("stopping agent")
 	close(a.done)
+	a.cancel()
+
+	if a.grpcClient != nil {
+		if err := a.grpcClient.Close(); err != nil {
+			a.logger.Warn("failed to close gRPC connection", "error", err)
+		}
+	}
 }
 
 // Run запускает агента
@@ -141,12 +240,121 @@ func (a *Agent) reportMetrics() {
 	}
 }
 
-// sendMetrics отправляет все метрики на сервер
+// sendMetrics отправляет все метрики на сервер одним пакетным запросом.
+// Сначала повторяется неподтвержденный пакет предыдущего отчета: пока он не принят, приращения
+// счетчиков из него не списаны и не должны уйти в новом пакете с другим ключом идемпотентности.
+// Если сервер не поддерживает пакетное обновление, метрики отправляются по одной.
 func (a *Agent) sendMetrics() {
+	if a.pending != nil {
+		err := a.sendPending()
+		if err != nil && !isRejected(err) {
+			a.logger.Error("error resending unacknowledged metrics batch", "count", len(a.pending.batch), "error", err)
+			return
+		}
+		if err != nil {
+			// Сервер отклонил пакет и не применил его - приращения уйдут в новом пакете
+			a.logger.Warn("unacknowledged metrics batch rejected by server", "error", err)
+		}
+	}
+
 	a.mu.RLock()
 	metrics := a.metrics.GetAllMetrics()
 	a.mu.RUnlock()
 
+	batch := a.prepareBatch(metrics)
+	if len(batch) == 0 {
+		return
+	}
+	key, err := metricalclient.NewIdempotencyKey()
+	if err != nil {
+		a.logger.Error("failed to generate idempotency key", "error", err)
+		return
+	}
+
+	a.pending = &pendingBatch{metrics: metrics, batch: batch, key: key}
+	err = a.sendPending()
+	if err == nil {
+		a.logger.Info("successfully sent metrics batch", "count", len(batch))
+		return
+	}
+
+	var statusErr *metricalclient.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		a.logger.Warn("batch endpoint is not supported by server, sending metrics one by one")
+		a.sendMetricsOneByOne(metrics)
+		return
+	}
+
+	// Приращения счетчиков сохраняются и будут отправлены в следующем отчете:
+	// отклоненные - в новом пакете, после неоднозначной ошибки - повтором этого же пакета
+	a.logger.Error("error sending metrics batch", "count", len(batch), "error", err)
+}
+
+// sendPending отправляет неподтвержденный пакет с его ключом идемпотентности.
+// После подтверждения списывает приращения счетчиков; если сервер отклонил пакет, забывает его.
+func (a *Agent) sendPending() error {
+	pending := a.pending
+	err := a.sendMetricsBatch(metricalclient.WithIdempotencyKey(a.ctx, pending.key), pending.batch)
+	switch {
+	case err == nil:
+		// Сервер подтвердил запись - списываем отправленные приращения счетчиков
+		for name, value := range pending.metrics {
+			a.acknowledgeCounter(name, value)
+		}
+		a.pending = nil
+	case isRejected(err):
+		a.pending = nil
+	}
+	return err
+}
+
+// acknowledgeCounter списывает подтвержденное приращение, если метрика - counter
+func (a *Agent) acknowledgeCounter(name string, value any) {
+	delta, ok := value.(int64)
+	if !ok {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.metrics.AcknowledgeCounter(name, delta)
+}
+
+// isRejected сообщает, что сервер отклонил пакет (HTTP 4xx или ошибка запроса gRPC) и не применил его.
+// После остальных ошибок пакет мог быть применен и повторяется с тем же ключом идемпотентности.
+func isRejected(err error) bool {
+	return metricalclient.IsRejected(err) || rejectedByServer(err)
+}
+
+// prepareBatch преобразует метрики в пакет для отправки
+func (a *Agent) prepareBatch(metrics map[string]any) []models.Metrics {
+	batch := make([]models.Metrics, 0, len(metrics))
+	for name, value := range metrics {
+		metric, err := a.prepareMetricJSON(name, value)
+		if err != nil {
+			// Некорректная метрика не должна блокировать отправку остальных
+			a.logger.Error("skipping metric", "name", name, "error", err)
+			continue
+		}
+		batch = append(batch, *metric)
+	}
+	return batch
+}
+
+// sendMetricsBatch отправляет пакет одним запросом на /updates или через gRPC API
+func (a *Agent) sendMetricsBatch(ctx context.Context, batch []models.Metrics) error {
+	if a.grpcClient != nil {
+		return a.grpcClient.SendBatch(ctx, batch)
+	}
+
+	if a.client == nil {
+		return errClientUnavailable
+	}
+	return a.client.UpdateBatch(ctx, batch)
+}
+
+// sendMetricsOneByOne отправляет метрики отдельными запросами на /update
+func (a *Agent) sendMetricsOneByOne(metrics map[string]any) {
 	successCount := 0
 	errorCount := 0
 
@@ -158,6 +366,7 @@ func (a *Agent) sendMetrics() {
 				a.logger.Error("error sending metric", "name", name, "error", err)
 			}
 		} else {
+			a.acknowledgeCounter(name, value)
 			successCount++
 		}
 	}
@@ -180,8 +389,12 @@ func (a *Agent) sendSingleMetricJSON(name string, value interface{}) error {
 		return err
 	}
 
+	if a.client == nil {
+		return errClientUnavailable
+	}
+
 	// Отправляем HTTP запрос
-	if err := a.sendJSONRequest(metric); err != nil {
+	if err := a.client.Update(a.ctx, *metric); err != nil {
 		return fmt.Errorf("failed to send metric %s: %w", name, err)
 	}
 
@@ -214,70 +427,3 @@ func (a *Agent) prepareMetricJSON(name string, value interface{}) (*models.Metri
 
 	return &metric, nil
 }
-
-// compressData сжимает данные с помощью gzip
-func (a *Agent) compressData(data []byte) ([]byte, error) {
-	var buf bytes.Buffer
-	gzWriter := gzip.NewWriter(&buf)
-
-	if _, err := gzWriter.Write(data); err != nil {
-		return nil, fmt.Errorf("failed to write to gzip writer: %w", err)
-	}
-
-	if err := gzWriter.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close gzip writer: %w", err)
-	}
-
-	return buf.Bytes(), nil
-}
-
-// sendJSONRequest отправляет JSON запрос на сервер
-func (a *Agent) sendJSONRequest(metric *models.Metrics) error {
-	// Убеждаемся, что URL содержит протокол
-	serverURL := a.config.ServerURL
-	if !strings.HasPrefix(serverURL, "http://") && !strings.HasPrefix(serverURL, "https://") {
-		serverURL = "http://" + serverURL
-	}
-	url := fmt.Sprintf("%s/update", serverURL)
-
-	// Кодируем метрику в JSON
-	jsonData, err := json.Marshal(metric)
-	if err != nil {
-		return fmt.Errorf("failed to marshal metric: %w", err)
-	}
-
-	// Сжимаем данные
-	compressedData, err := a.compressData(jsonData)
-	if err != nil {
-		return fmt.Errorf("failed to compress data: %w", err)
-	}
-
-	// Создаем запрос
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(compressedData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Устанавливаем заголовки
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Content-Encoding", "gzip")
-	req.Header.Set("Accept-Encoding", "gzip")
-
-	// Выполняем запрос с retry логикой
-	return a.sendHTTPRequestWithRetry(req)
-}
-
-// sendHTTPRequestWithRetry выполняет HTTP запрос с retry логикой
-func (a *Agent) sendHTTPRequestWithRetry(req *http.Request) error {
-	resp, err := a.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send HTTP request with retry: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("server returned status %d", resp.StatusCode)
-	}
-
-	return nil
-}
@@ -1,11 +1,26 @@
 package agent
 
 import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/encryption"
+	"github.com/IgorKilipenko/metrical/internal/middleware"
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/signature"
 	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/IgorKilipenko/metrical/internal/tlsconfig"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewAgent(t *testing.T) {
@@ -275,3 +290,634 @@ func TestAgent_prepareMetricJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestAgent_PrepareMetricJSON(t *testing.T) {
+	agent := &Agent{}
+
+	t.Run("prepare gauge metric", func(t *testing.T) {
+		name := "test_gauge"
+		value := float64(42.5)
+
+		metric, err := agent.prepareMetricJSON(name, value)
+		if err != nil {
+			t.Fatalf("Failed to prepare gauge metric: %v", err)
+		}
+
+		if metric.ID != name {
+			t.Errorf("Expected metric ID %s, got %s", name, metric.ID)
+		}
+
+		if metric.MType != "gauge" {
+			t.Errorf("Expected metric type 'gauge', got %s", metric.MType)
+		}
+
+		if metric.Value == nil || *metric.Value != value {
+			t.Errorf("Expected metric value %f, got %v", value, metric.Value)
+		}
+
+		if metric.Delta != nil {
+			t.Errorf("Expected delta to be nil for gauge, got %v", metric.Delta)
+		}
+	})
+
+	t.Run("prepare counter metric", func(t *testing.T) {
+		name := "test_counter"
+		value := int64(100)
+
+		metric, err := agent.prepareMetricJSON(name, value)
+		if err != nil {
+			t.Fatalf("Failed to prepare counter metric: %v", err)
+		}
+
+		if metric.ID != name {
+			t.Errorf("Expected metric ID %s, got %s", name, metric.ID)
+		}
+
+		if metric.MType != "counter" {
+			t.Errorf("Expected metric type 'counter', got %s", metric.MType)
+		}
+
+		if metric.Delta == nil || *metric.Delta != value {
+			t.Errorf("Expected metric delta %d, got %v", value, metric.Delta)
+		}
+
+		if metric.Value != nil {
+			t.Errorf("Expected value to be nil for counter, got %v", metric.Value)
+		}
+	})
+
+	t.Run("prepare metric with unknown type", func(t *testing.T) {
+		name := "test_unknown"
+		value := "string_value"
+
+		_, err := agent.prepareMetricJSON(name, value)
+		if err == nil {
+			t.Error("Expected error for unknown metric type")
+		}
+
+		expectedError := "unknown metric type for test_unknown: string"
+		if err.Error() != expectedError {
+			t.Errorf("Expected error '%s', got '%s'", expectedError, err.Error())
+		}
+	})
+}
+
+// decodeGzipJSON распаковывает и декодирует тело запроса агента
+func decodeGzipJSON(t *testing.T, r *http.Request, target any) {
+	t.Helper()
+
+	gzReader, err := gzip.NewReader(r.Body)
+	require.NoError(t, err)
+	defer gzReader.Close()
+
+	require.NoError(t, json.NewDecoder(gzReader).Decode(target))
+}
+
+func TestAgent_sendMetrics_Batch(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		paths    []string
+		received []models.Metrics
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		paths = append(paths, r.URL.Path)
+		decodeGzipJSON(t, r, &received)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	agent := NewAgent(NewConfigWithURL(server.URL), testutils.NewMockLogger())
+	agent.collectMetrics()
+	expected := len(agent.metrics.Gauges) + len(agent.metrics.Counters)
+	agent.sendMetrics()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, []string{"/updates"}, paths, "All metrics should be sent in a single batch request")
+	assert.Len(t, received, expected)
+}
+
+func TestAgent_sendMetrics_FallbackWithoutBatchEndpoint(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		singleCount int
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/updates":
+			http.NotFound(w, r)
+		case "/update":
+			var metric models.Metrics
+			decodeGzipJSON(t, r, &metric)
+			singleCount++
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	agent := NewAgent(NewConfigWithURL(server.URL), testutils.NewMockLogger())
+	agent.collectMetrics()
+	expected := len(agent.metrics.Gauges) + len(agent.metrics.Counters)
+	agent.sendMetrics()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, expected, singleCount,
+		"Agent should fall back to single metric requests")
+}
+
+// counterServer тестовый сервер, накапливающий counter метрики как настоящий сервер
+type counterServer struct {
+	mu       sync.Mutex
+	counters map[string]int64
+	fail     bool            // Отвечать ошибкой, не применяя пакет
+	applied  map[string]bool // Примененные ключи идемпотентности: повтор с тем же ключом не применяется
+}
+
+func (s *counterServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	var batch []models.Metrics
+	gzReader, err := gzip.NewReader(r.Body)
+	if err == nil {
+		err = json.NewDecoder(gzReader).Decode(&batch)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+		if s.applied[key] {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if s.applied == nil {
+			s.applied = make(map[string]bool)
+		}
+		s.applied[key] = true
+	}
+
+	for _, metric := range batch {
+		if metric.MType == models.Counter {
+			s.counters[metric.ID] += *metric.Delta
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *counterServer) get(name string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counters[name]
+}
+
+func TestAgent_CounterDeltas(t *testing.T) {
+	backend := &counterServer{counters: make(map[string]int64)}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	agent := NewAgent(NewConfigWithURL(server.URL), testutils.NewMockLogger())
+
+	// Три опроса и отчет: на сервере PollCount = 3
+	for i := 0; i < 3; i++ {
+		agent.collectMetrics()
+	}
+	agent.sendMetrics()
+	assert.Equal(t, int64(3), backend.get(MetricPollCount))
+
+	// Повторный отчет без новых опросов не увеличивает счетчик
+	agent.sendMetrics()
+	assert.Equal(t, int64(3), backend.get(MetricPollCount), "Acknowledged delta must not be sent again")
+
+	// Еще два опроса: счетчик растет линейно, а не на накопленную сумму
+	agent.collectMetrics()
+	agent.collectMetrics()
+	agent.sendMetrics()
+	assert.Equal(t, int64(5), backend.get(MetricPollCount))
+}
+
+func TestAgent_CounterDeltas_KeptOnFailure(t *testing.T) {
+	backend := &counterServer{counters: make(map[string]int64), fail: true}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	agent := NewAgent(NewConfigWithURL(server.URL), testutils.NewMockLogger())
+
+	agent.collectMetrics()
+	agent.collectMetrics()
+	agent.sendMetrics()
+	assert.Equal(t, int64(2), agent.metrics.Counters[MetricPollCount], "Delta should be kept when send fails")
+
+	// После восстановления сервера отправляется все накопленное приращение
+	backend.mu.Lock()
+	backend.fail = false
+	backend.mu.Unlock()
+
+	agent.collectMetrics()
+	agent.sendMetrics()
+	assert.Equal(t, int64(3), backend.get(MetricPollCount))
+	_, pending := agent.metrics.Counters[MetricPollCount]
+	assert.False(t, pending, "Delta should be reset after acknowledgement")
+}
+
+// lostResponseClient выполняет запросы, но теряет ответы первых lost из них,
+// как при таймауте после того, как сервер применил запись
+type lostResponseClient struct {
+	next interface {
+		Do(req *http.Request) (*http.Response, error)
+	}
+	lost int
+}
+
+func (c *lostResponseClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.next.Do(req)
+	if err != nil || c.lost == 0 {
+		return resp, err
+	}
+	c.lost--
+	resp.Body.Close()
+	return nil, &net.OpError{Op: "read", Net: "tcp", Err: errors.New("i/o timeout")}
+}
+
+func TestAgent_CounterDeltas_LostResponse(t *testing.T) {
+	backend := &counterServer{counters: make(map[string]int64)}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	agent := NewAgent(NewConfigWithURL(server.URL), testutils.NewMockLogger())
+	agent.httpClient = &lostResponseClient{next: agent.httpClient, lost: DefaultMaxRetries}
+	agent.client = agent.newClient()
+
+	// Сервер применил пакет, но агент не получил ответ ни на одну попытку
+	agent.collectMetrics()
+	agent.collectMetrics()
+	agent.sendMetrics()
+	assert.Equal(t, int64(2), backend.get(MetricPollCount))
+	assert.Equal(t, int64(2), agent.metrics.Counters[MetricPollCount], "Delta should stay unacknowledged")
+
+	// Неподтвержденный пакет повторяется с прежним ключом, новое приращение уходит отдельно
+	agent.collectMetrics()
+	agent.sendMetrics()
+	assert.Equal(t, int64(3), backend.get(MetricPollCount), "Batch applied before the timeout must not be applied again")
+	_, pending := agent.metrics.Counters[MetricPollCount]
+	assert.False(t, pending)
+	assert.Nil(t, agent.pending)
+}
+
+func TestAgent_sendMetrics_IdempotencyKey(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		keys     []string
+		payloads [][]models.Metrics
+	)
+
+	// Первая попытка завершается ошибкой сервера, повтор - успехом
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var batch []models.Metrics
+		decodeGzipJSON(t, r, &batch)
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		payloads = append(payloads, batch)
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	agent := NewAgent(NewConfigWithURL(server.URL), testutils.NewMockLogger())
+	agent.collectMetrics()
+	agent.sendMetrics()
+
+	mu.Lock()
+	require.Len(t, keys, 2, "Request should be retried once")
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1], "Retries of one send must reuse the idempotency key")
+	assert.Equal(t, payloads[0], payloads[1], "Retry must resend the full body")
+	mu.Unlock()
+
+	// Следующая отправка - новая логическая операция с новым ключом
+	agent.collectMetrics()
+	agent.sendMetrics()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, keys, 3)
+	assert.NotEqual(t, keys[0], keys[2])
+}
+
+// newSignedServer создает тестовый сервер с проверкой подписи, накапливающий полученные метрики
+func newSignedServer(t *testing.T, key string, received *[]models.Metrics, mu *sync.Mutex) *httptest.Server {
+	t.Helper()
+
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []models.Metrics
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		*received = append(*received, batch...)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+
+	return httptest.NewServer(middleware.CompressionMiddleware(nil)(middleware.SignatureMiddleware(key)(inner)))
+}
+
+func TestAgent_sendMetrics_Signed(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []models.Metrics
+	)
+	server := newSignedServer(t, "secret", &received, &mu)
+	defer server.Close()
+
+	config := NewConfigWithURL(server.URL)
+	config.Key = "secret"
+	agent := NewAgent(config, testutils.NewMockLogger())
+
+	agent.collectMetrics()
+	agent.sendMetrics()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.NotEmpty(t, received, "Signed batch should be accepted by server")
+	for _, metric := range received {
+		assert.True(t, signature.VerifyMetric(&metric, "secret"), "Metric %s should carry a valid hash", metric.ID)
+	}
+
+	// Подтвержденная отправка списывает приращение счетчика
+	_, pending := agent.metrics.Counters[MetricPollCount]
+	assert.False(t, pending)
+}
+
+func TestAgent_sendMetrics_Compression(t *testing.T) {
+	for _, enc := range []string{"gzip", "deflate", "zstd"} {
+		t.Run(enc, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				received []models.Metrics
+			)
+			server := newSignedServer(t, "secret", &received, &mu)
+			defer server.Close()
+
+			config := NewConfigWithURL(server.URL)
+			config.Key = "secret"
+			config.Compression = enc
+			agent := NewAgent(config, testutils.NewMockLogger())
+
+			agent.collectMetrics()
+			agent.sendMetrics()
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.NotEmpty(t, received, "Batch compressed with %s should be accepted by server", enc)
+		})
+	}
+}
+
+func TestAgent_sendMetrics_SignatureMismatch(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []models.Metrics
+	)
+	server := newSignedServer(t, "server-key", &received, &mu)
+	defer server.Close()
+
+	config := NewConfigWithURL(server.URL)
+	config.Key = "agent-key"
+	agent := NewAgent(config, testutils.NewMockLogger())
+
+	agent.collectMetrics()
+	agent.sendMetrics()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Empty(t, received, "Server must reject requests signed with another key")
+	assert.Equal(t, int64(1), agent.metrics.Counters[MetricPollCount], "Delta should be kept when request is rejected")
+}
+
+func TestAgent_sendMetrics_Encrypted(t *testing.T) {
+	privateKeyPath, publicKeyPath := testutils.WriteTestRSAKeys(t)
+	decryptor, err := encryption.NewDecryptorFromFile(privateKeyPath)
+	require.NoError(t, err)
+
+	var (
+		mu        sync.Mutex
+		received  []models.Metrics
+		encrypted bool
+	)
+
+	// Сервер расшифровывает тело до распаковки, как в routes.SetupMetricsRoutes
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []models.Metrics
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		received = append(received, batch...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	})
+	stack := middleware.DecryptMiddleware(decryptor, 0)(middleware.CompressionMiddleware(nil)(inner))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		encrypted = r.Header.Get(encryption.HeaderName) == encryption.Scheme
+		mu.Unlock()
+		stack.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	agent := NewAgent(NewConfigWithURL(server.URL), testutils.NewMockLogger())
+	require.NoError(t, agent.EnableEncryption(publicKeyPath))
+
+	agent.collectMetrics()
+	agent.sendMetrics()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, encrypted, "Request should be marked as encrypted")
+	assert.NotEmpty(t, received, "Server should decrypt and apply the batch")
+}
+
+func TestAgent_EnableEncryption_InvalidKey(t *testing.T) {
+	agent := NewAgent(NewConfig(), testutils.NewMockLogger())
+
+	assert.Error(t, agent.EnableEncryption("/nonexistent/public.pem"))
+	assert.Nil(t, agent.encryptor)
+}
+
+func TestAgent_sendMetrics_MutualTLS(t *testing.T) {
+	certs := testutils.WriteTestCertificates(t)
+
+	var (
+		mu       sync.Mutex
+		received []models.Metrics
+	)
+	server := httptest.NewUnstartedServer(middleware.CompressionMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []models.Metrics
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		received = append(received, batch...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	})))
+	serverTLS, err := tlsconfig.NewServerConfig(certs.ServerCertFile, certs.ServerKeyFile, certs.CAFile)
+	require.NoError(t, err)
+	server.TLS = serverTLS
+	server.StartTLS()
+	defer server.Close()
+
+	// Адрес без схемы: агент выбирает https по настройкам TLS
+	address := strings.TrimPrefix(server.URL, "https://")
+
+	t.Run("with client certificate", func(t *testing.T) {
+		config := NewConfigWithURL(address)
+		config.TLSCAFile = certs.CAFile
+		config.TLSCertFile = certs.ClientCertFile
+		config.TLSKeyFile = certs.ClientKeyFile
+		require.NoError(t, config.Validate())
+
+		agent := NewAgent(config, testutils.NewMockLogger())
+		agent.collectMetrics()
+		agent.sendMetrics()
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.NotEmpty(t, received, "Batch should be delivered over mutual TLS")
+		_, pending := agent.metrics.Counters[MetricPollCount]
+		assert.False(t, pending)
+	})
+
+	t.Run("without client certificate", func(t *testing.T) {
+		mu.Lock()
+		received = nil
+		mu.Unlock()
+
+		config := NewConfigWithURL(address)
+		config.TLSCAFile = certs.CAFile
+
+		agent := NewAgent(config, testutils.NewMockLogger())
+		agent.collectMetrics()
+		agent.sendMetrics()
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Empty(t, received, "Server must reject agent without client certificate")
+		assert.Equal(t, int64(1), agent.metrics.Counters[MetricPollCount], "Delta should be kept when handshake fails")
+	})
+}
+
+func TestAgent_sendMetrics_RealIP(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		realIPs []string
+	)
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		realIPs = append(realIPs, r.Header.Get(RealIPHeader))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("trusted subnet", func(t *testing.T) {
+		_, subnet, _ := net.ParseCIDR("127.0.0.0/8")
+		server := httptest.NewServer(middleware.TrustedSubnetMiddleware(subnet)(inner))
+		defer server.Close()
+
+		agent := NewAgent(NewConfigWithURL(server.URL), testutils.NewMockLogger())
+		agent.collectMetrics()
+		agent.sendMetrics()
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, realIPs)
+		assert.Equal(t, "127.0.0.1", realIPs[0], "Agent should send outbound interface address")
+		_, pending := agent.metrics.Counters[MetricPollCount]
+		assert.False(t, pending)
+	})
+
+	t.Run("untrusted subnet", func(t *testing.T) {
+		_, subnet, _ := net.ParseCIDR("10.0.0.0/8")
+		server := httptest.NewServer(middleware.TrustedSubnetMiddleware(subnet)(inner))
+		defer server.Close()
+
+		agent := NewAgent(NewConfigWithURL(server.URL), testutils.NewMockLogger())
+		agent.collectMetrics()
+		agent.sendMetrics()
+
+		assert.Equal(t, int64(1), agent.metrics.Counters[MetricPollCount], "Delta should be kept when server rejects agent")
+	})
+}
+
+func TestAgent_sendMetrics_Token(t *testing.T) {
+	store, err := auth.NewStore([]auth.Token{{Name: "agent", Token: "agent-token", Scope: auth.ScopeWrite}})
+	require.NoError(t, err)
+
+	var (
+		mu         sync.Mutex
+		principals []string
+	)
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := auth.PrincipalFromContext(r.Context())
+		mu.Lock()
+		principals = append(principals, principal.Name)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(middleware.AuthMiddleware(store, auth.ScopeWrite)(inner))
+	defer server.Close()
+
+	t.Run("valid token", func(t *testing.T) {
+		config := NewConfigWithURL(server.URL)
+		config.Token = "agent-token"
+		agent := NewAgent(config, testutils.NewMockLogger())
+		agent.collectMetrics()
+		agent.sendMetrics()
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, principals)
+		assert.Equal(t, "agent", principals[0])
+	})
+
+	t.Run("without token", func(t *testing.T) {
+		agent := NewAgent(NewConfigWithURL(server.URL), testutils.NewMockLogger())
+		agent.collectMetrics()
+		agent.sendMetrics()
+
+		assert.Equal(t, int64(1), agent.metrics.Counters[MetricPollCount], "Delta should be kept when request is unauthorized")
+	})
+}
@@ -1,8 +1,13 @@
 package agent
 
 import (
+	"crypto/tls"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/compression"
+	"github.com/IgorKilipenko/metrical/internal/tlsconfig"
 )
 
 // Константы конфигурации по умолчанию
@@ -11,6 +16,7 @@ const (
 	DefaultPollInterval   = 2 * time.Second
 	DefaultReportInterval = 10 * time.Second
 	DefaultHTTPTimeout    = 10 * time.Second
+	DefaultCompression    = compression.Gzip
 )
 
 // Config конфигурация агента.
@@ -27,6 +33,24 @@ type Config struct {
 
 	// VerboseLogging - подробное логирование (включая ошибки отправки метрик)
 	VerboseLogging bool
+
+	// Key - общий с сервером ключ подписи HMAC-SHA256 (пустая строка - подпись отключена)
+	Key string
+
+	// Token - API токен с областью доступа write (пустая строка - без заголовка Authorization)
+	Token string
+
+	// TLSCAFile - бандл CA для проверки сертификата сервера (пустая строка - системные корневые сертификаты)
+	TLSCAFile string
+
+	// TLSCertFile - клиентский сертификат для mutual TLS (задается вместе с TLSKeyFile)
+	TLSCertFile string
+
+	// TLSKeyFile - приватный ключ клиентского сертификата
+	TLSKeyFile string
+
+	// Compression - кодировка тел запросов: gzip, deflate или zstd (пустая строка - gzip)
+	Compression string
 }
 
 // NewConfig создает конфигурацию с значениями по умолчанию.
@@ -78,9 +102,50 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("report interval must be positive")
 	}
 
+	if c.Compression != "" && !compression.Supported(c.Compression) {
+		return fmt.Errorf("unsupported compression %q: expected one of %s", c.Compression, strings.Join(compression.Encodings(), ", "))
+	}
+
+	// Загружаем сертификаты, чтобы ошибки обнаруживались до запуска агента
+	if c.UsesTLS() {
+		if _, err := c.TLSConfig(); err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// UsesTLS сообщает, подключается ли агент к серверу по HTTPS.
+// TLS используется, если задан CA, клиентский сертификат или URL сервера со схемой https://.
+func (c *Config) UsesTLS() bool {
+	return c.TLSCAFile != "" || c.TLSCertFile != "" || c.TLSKeyFile != "" ||
+		strings.HasPrefix(c.ServerURL, "https://")
+}
+
+// TLSConfig создает TLS конфигурацию клиента из файлов сертификатов
+func (c *Config) TLSConfig() (*tls.Config, error) {
+	return tlsconfig.NewClientConfig(c.TLSCAFile, c.TLSCertFile, c.TLSKeyFile)
+}
+
+// BaseURL возвращает URL сервера со схемой, выбранной по настройкам TLS.
+// Схема из ServerURL заменяется: при заданных сертификатах запросы не отправляются по HTTP.
+func (c *Config) BaseURL() string {
+	host := strings.TrimPrefix(strings.TrimPrefix(c.ServerURL, "http://"), "https://")
+	if c.UsesTLS() {
+		return "https://" + host
+	}
+	return "http://" + host
+}
+
+// ContentEncoding возвращает кодировку тел запросов (по умолчанию gzip)
+func (c *Config) ContentEncoding() string {
+	if c.Compression == "" {
+		return DefaultCompression
+	}
+	return compression.Normalize(c.Compression)
+}
+
 // IsValid проверяет, является ли конфигурация корректной.
 //
 // Возвращает:
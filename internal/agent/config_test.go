@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/IgorKilipenko/metrical/internal/testutils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -152,3 +153,74 @@ func TestConfig_IsValid(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_BaseURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *Config
+		expected string
+	}{
+		{
+			name:     "Host without scheme",
+			config:   &Config{ServerURL: "localhost:8080"},
+			expected: "http://localhost:8080",
+		},
+		{
+			name:     "Explicit HTTP",
+			config:   &Config{ServerURL: "http://localhost:8080"},
+			expected: "http://localhost:8080",
+		},
+		{
+			name:     "Explicit HTTPS",
+			config:   &Config{ServerURL: "https://localhost:8443"},
+			expected: "https://localhost:8443",
+		},
+		{
+			name:     "CA bundle selects HTTPS",
+			config:   &Config{ServerURL: "localhost:8443", TLSCAFile: "/etc/metrical/ca.pem"},
+			expected: "https://localhost:8443",
+		},
+		{
+			name:     "Client certificate overrides HTTP scheme",
+			config:   &Config{ServerURL: "http://localhost:8443", TLSCertFile: "/etc/metrical/client.pem", TLSKeyFile: "/etc/metrical/client-key.pem"},
+			expected: "https://localhost:8443",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.config.BaseURL())
+		})
+	}
+}
+
+func TestConfig_Validate_TLS(t *testing.T) {
+	certs := testutils.WriteTestCertificates(t)
+
+	config := NewConfigWithURL("localhost:8443")
+	config.TLSCAFile = certs.CAFile
+	config.TLSCertFile = certs.ClientCertFile
+	config.TLSKeyFile = certs.ClientKeyFile
+	assert.NoError(t, config.Validate())
+
+	config.TLSKeyFile = ""
+	assert.Error(t, config.Validate(), "Client certificate without key should be rejected")
+
+	config = NewConfigWithURL("localhost:8443")
+	config.TLSCAFile = "/nonexistent/ca.pem"
+	assert.Error(t, config.Validate(), "Missing CA bundle should be rejected")
+}
+
+func TestConfig_Compression(t *testing.T) {
+	config := NewConfig()
+	assert.Equal(t, DefaultCompression, config.ContentEncoding(), "Empty compression should fall back to gzip")
+
+	for _, enc := range []string{"gzip", "deflate", "zstd", "x-gzip"} {
+		config.Compression = enc
+		assert.NoError(t, config.Validate(), "Compression %q should be accepted", enc)
+	}
+	assert.Equal(t, "gzip", config.ContentEncoding(), "x-gzip should be normalized")
+
+	config.Compression = "br"
+	assert.Error(t, config.Validate(), "Unsupported compression should be rejected")
+}
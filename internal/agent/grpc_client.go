@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/compression"
+	"github.com/IgorKilipenko/metrical/internal/grpcapi"
+	"github.com/IgorKilipenko/metrical/internal/logger"
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/signature"
+	"github.com/IgorKilipenko/metrical/pkg/metricalclient"
+	pb "github.com/IgorKilipenko/metrical/pkg/metricalpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCClient отправляет пакеты метрик через gRPC API сервера (метод UpdateMetrics)
+type GRPCClient struct {
+	conn       *grpc.ClientConn
+	client     pb.MetricsServiceClient
+	config     *Config
+	addr       string
+	maxRetries int
+	retryDelay time.Duration
+	logger     logger.Logger
+	sleepFunc  func(time.Duration) // Ожидание между попытками (подменяется в тестах)
+}
+
+// NewGRPCClient создает клиента gRPC API; соединение устанавливается при первой отправке.
+// TLS используется по тем же настройкам, что и для HTTP.
+func NewGRPCClient(addr string, config *Config, logger logger.Logger) (*GRPCClient, error) {
+	if addr == "" {
+		return nil, fmt.Errorf("gRPC address cannot be empty")
+	}
+
+	transport := insecure.NewCredentials()
+	if config.UsesTLS() {
+		tlsConfig, err := config.TLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS configuration: %w", err)
+		}
+		transport = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(transport))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+
+	return &GRPCClient{
+		conn:       conn,
+		client:     pb.NewMetricsServiceClient(conn),
+		config:     config,
+		addr:       addr,
+		maxRetries: DefaultMaxRetries,
+		retryDelay: DefaultRetryDelay,
+		logger:     logger,
+		sleepFunc:  time.Sleep,
+	}, nil
+}
+
+// SendBatch отправляет пакет метрик, подписывая их ключом из конфигурации.
+// Все попытки передают один ключ идемпотентности (из ctx, см. metricalclient.WithIdempotencyKey,
+// или новый), поэтому сервер не применяет приращения counter повторно.
+// Недоступность сервера и превышение лимита повторяются.
+func (c *GRPCClient) SendBatch(ctx context.Context, metrics []models.Metrics) error {
+	key, ok := metricalclient.IdempotencyKeyFromContext(ctx)
+	if !ok {
+		var err error
+		if key, err = metricalclient.NewIdempotencyKey(); err != nil {
+			return fmt.Errorf("failed to generate idempotency key: %w", err)
+		}
+	}
+
+	req := &pb.UpdateMetricsRequest{Metrics: make([]*pb.Metric, 0, len(metrics))}
+	for i := range metrics {
+		metric := metrics[i]
+		if c.config.Key != "" {
+			signature.SignMetric(&metric, c.config.Key)
+		}
+		req.Metrics = append(req.Metrics, grpcapi.FromModel(&metric))
+	}
+
+	var options []grpc.CallOption
+	// gRPC поддерживает только gzip: при других кодировках сообщения отправляются без сжатия
+	if c.config.ContentEncoding() == compression.Gzip {
+		options = append(options, grpc.UseCompressor(gzip.Name))
+	}
+
+	for attempt := 1; ; attempt++ {
+		callCtx, cancel := context.WithTimeout(c.outgoingContext(ctx, key), DefaultHTTPTimeout)
+		_, err := c.client.UpdateMetrics(callCtx, req, options...)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		if !retryableCode(status.Code(err)) || attempt >= c.maxRetries || ctx.Err() != nil {
+			return fmt.Errorf("failed to send metrics via gRPC: %w", err)
+		}
+		c.logger.Warn("gRPC request failed, retrying", "attempt", attempt, "error", err)
+		c.sleepFunc(c.retryDelay)
+	}
+}
+
+// outgoingContext возвращает контекст с метаданными токена, ключа идемпотентности и IP адреса агента
+func (c *GRPCClient) outgoingContext(ctx context.Context, idempotencyKey string) context.Context {
+	md := metadata.Pairs(grpcapi.IdempotencyKey, idempotencyKey)
+	if c.config.Token != "" {
+		md.Set(grpcapi.AuthorizationKey, "Bearer "+c.config.Token)
+	}
+
+	// Передаем адрес исходящего интерфейса для проверки доверенной подсети на сервере
+	if ip, err := metricalclient.OutboundIP(&url.URL{Host: c.addr}); err == nil {
+		md.Set(grpcapi.RealIPKey, ip.String())
+	} else {
+		c.logger.Debug("failed to determine outbound IP", "error", err)
+	}
+
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// Close закрывает соединение с сервером
+func (c *GRPCClient) Close() error {
+	return c.conn.Close()
+}
+
+// retryableCode сообщает, имеет ли смысл повторить вызов с таким кодом ответа
+func retryableCode(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// rejectedByServer сообщает, что сервер отклонил вызов и точно не применил метрики
+func rejectedByServer(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied, codes.FailedPrecondition:
+		return true
+	default:
+		return false
+	}
+}
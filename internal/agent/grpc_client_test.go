@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/grpcapi"
+	"github.com/IgorKilipenko/metrical/internal/grpcserver"
+	"github.com/IgorKilipenko/metrical/internal/idempotency"
+	"github.com/IgorKilipenko/metrical/internal/repository"
+	"github.com/IgorKilipenko/metrical/internal/service"
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/IgorKilipenko/metrical/pkg/metricalclient"
+	pb "github.com/IgorKilipenko/metrical/pkg/metricalpb"
+	"github.com/IgorKilipenko/metrical/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// startGRPCServer запускает gRPC сервер метрик и возвращает его адрес и сервис
+func startGRPCServer(t *testing.T, config *grpcserver.ServerConfig) (string, *service.MetricsService) {
+	t.Helper()
+
+	mockLogger := testutils.NewMockLogger()
+	metricsService := service.NewMetricsService(repository.NewInMemoryMetricsRepository(mockLogger, "", false), mockLogger)
+
+	config.Addr = "127.0.0.1:0"
+	server, err := grpcserver.NewServerWithConfig(config, metricsService, mockLogger)
+	require.NoError(t, err)
+	require.NoError(t, server.Listen())
+
+	go server.Start()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	})
+
+	return server.Addr().String(), metricsService
+}
+
+func TestAgent_sendMetrics_GRPC(t *testing.T) {
+	store, err := auth.NewStore([]auth.Token{{Name: "agent", Token: "write-token", Scope: auth.ScopeWrite}})
+	require.NoError(t, err)
+
+	serverConfig := grpcserver.DefaultServerConfig()
+	serverConfig.SigningKey = "secret"
+	serverConfig.Auth = store
+	addr, metricsService := startGRPCServer(t, serverConfig)
+
+	config := NewConfig()
+	config.Key = "secret"
+	config.Token = "write-token"
+	agent := NewAgent(config, testutils.NewMockLogger())
+	require.NoError(t, agent.EnableGRPC(addr))
+	defer agent.grpcClient.Close()
+
+	agent.collectMetrics()
+	agent.collectMetrics()
+	agent.sendMetrics()
+
+	ctx := context.Background()
+	pollCount, exists, err := metricsService.GetCounter(ctx, MetricPollCount)
+	require.NoError(t, err)
+	require.True(t, exists, "Metrics should be written via gRPC")
+	assert.Equal(t, int64(2), pollCount)
+
+	_, pending := agent.metrics.Counters[MetricPollCount]
+	assert.False(t, pending, "Delta should be reset after acknowledgement")
+}
+
+func TestGRPCClient_SendBatch_Errors(t *testing.T) {
+	store, err := auth.NewStore([]auth.Token{{Name: "agent", Token: "write-token", Scope: auth.ScopeWrite}})
+	require.NoError(t, err)
+
+	serverConfig := grpcserver.DefaultServerConfig()
+	serverConfig.Auth = store
+	addr, _ := startGRPCServer(t, serverConfig)
+
+	client, err := NewGRPCClient(addr, NewConfig(), testutils.NewMockLogger())
+	require.NoError(t, err)
+	defer client.Close()
+
+	attempts := 0
+	client.sleepFunc = func(time.Duration) { attempts++ }
+
+	err = client.SendBatch(context.Background(), nil)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "Missing token should be reported")
+	assert.Zero(t, attempts, "Client errors should not be retried")
+
+	_, err = NewGRPCClient("", NewConfig(), testutils.NewMockLogger())
+	assert.Error(t, err)
+}
+
+// flakyMetricsServer отвечает Unavailable на первый вызов и запоминает ключи идемпотентности вызовов
+type flakyMetricsServer struct {
+	pb.UnimplementedMetricsServiceServer
+	keys []string
+}
+
+func (s *flakyMetricsServer) UpdateMetrics(ctx context.Context, _ *pb.UpdateMetricsRequest) (*pb.UpdateMetricsResponse, error) {
+	s.keys = append(s.keys, metadata.ValueFromIncomingContext(ctx, grpcapi.IdempotencyKey)...)
+	if len(s.keys) == 1 {
+		return nil, status.Error(codes.Unavailable, "response lost")
+	}
+	return &pb.UpdateMetricsResponse{}, nil
+}
+
+func TestGRPCClient_SendBatch_IdempotencyKey(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	fake := &flakyMetricsServer{}
+	server := grpc.NewServer()
+	pb.RegisterMetricsServiceServer(server, fake)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	client, err := NewGRPCClient(listener.Addr().String(), NewConfig(), testutils.NewMockLogger())
+	require.NoError(t, err)
+	defer client.Close()
+	client.sleepFunc = func(time.Duration) {}
+
+	ctx := metricalclient.WithIdempotencyKey(context.Background(), "batch-1")
+	require.NoError(t, client.SendBatch(ctx, []models.Metrics{models.NewCounter("requests", 1)}))
+	assert.Equal(t, []string{"batch-1", "batch-1"}, fake.keys, "Retries must reuse the idempotency key")
+
+	fake.keys = []string{"skip-failure"}
+	require.NoError(t, client.SendBatch(context.Background(), nil))
+	require.Len(t, fake.keys, 2)
+	assert.NotEmpty(t, fake.keys[1], "Key should be generated when the context has none")
+}
+
+func TestGRPCClient_SendBatch_ResendIsNotApplied(t *testing.T) {
+	store, err := idempotency.NewStore(nil)
+	require.NoError(t, err)
+	serverConfig := grpcserver.DefaultServerConfig()
+	serverConfig.Idempotency = store
+	addr, metricsService := startGRPCServer(t, serverConfig)
+
+	client, err := NewGRPCClient(addr, NewConfig(), testutils.NewMockLogger())
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Агент повторяет неподтвержденный пакет с тем же ключом
+	ctx := metricalclient.WithIdempotencyKey(context.Background(), "batch-1")
+	batch := []models.Metrics{models.NewCounter("requests", 3)}
+	require.NoError(t, client.SendBatch(ctx, batch))
+	require.NoError(t, client.SendBatch(ctx, batch))
+
+	value, _, err := metricsService.GetCounter(context.Background(), "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), value)
+}
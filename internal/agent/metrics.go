@@ -13,7 +13,8 @@ type Metrics struct {
 	// Gauges содержит gauge метрики (заменяют предыдущие значения)
 	Gauges models.GaugeMetrics
 
-	// Counters содержит counter метрики (накапливают значения)
+	// Counters содержит неотправленные приращения counter метрик.
+	// Приращение уменьшается только после подтверждения записи сервером.
 	Counters models.CounterMetrics
 }
 
@@ -180,7 +181,7 @@ func FillAdditionalMetrics(metrics *Metrics) {
 	metrics.Gauges[MetricRandomValue] = rand.Float64()
 }
 
-// UpdateCounterMetrics обновляет counter метрики (накапливает значения).
+// UpdateCounterMetrics обновляет counter метрики (накапливает неотправленные приращения).
 // Увеличивает PollCount на 1 при каждом вызове.
 // PollCount используется для отслеживания количества обновлений метрик.
 //
@@ -190,6 +191,22 @@ func UpdateCounterMetrics(metrics *Metrics) {
 	metrics.Counters[MetricPollCount]++
 }
 
+// AcknowledgeCounter списывает подтвержденное сервером приращение counter метрики.
+// Вычитается именно отправленное значение: приращения, накопленные во время отправки,
+// остаются для следующего отчета. Полностью отправленный счетчик удаляется.
+//
+// Параметры:
+//   - name: имя counter метрики
+//   - sent: отправленное и подтвержденное приращение
+func (m *Metrics) AcknowledgeCounter(name string, sent int64) {
+	remaining := m.Counters[name] - sent
+	if remaining == 0 {
+		delete(m.Counters, name)
+		return
+	}
+	m.Counters[name] = remaining
+}
+
 // GetAllMetrics возвращает все метрики в виде map[string]any для совместимости.
 // Объединяет gauge и counter метрики в один map.
 // Используется для отправки метрик на сервер.
@@ -268,3 +268,40 @@ func TestUpdateCounterMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestMetrics_AcknowledgeCounter(t *testing.T) {
+	tests := []struct {
+		name          string
+		pending       int64
+		sent          int64
+		expectPending bool
+		expectedValue int64
+	}{
+		{
+			name:          "whole delta acknowledged",
+			pending:       5,
+			sent:          5,
+			expectPending: false,
+		},
+		{
+			name:          "delta accumulated during send is kept",
+			pending:       7,
+			sent:          5,
+			expectPending: true,
+			expectedValue: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := NewMetrics()
+			metrics.Counters[MetricPollCount] = tt.pending
+
+			metrics.AcknowledgeCounter(MetricPollCount, tt.sent)
+
+			value, exists := metrics.Counters[MetricPollCount]
+			assert.Equal(t, tt.expectPending, exists)
+			assert.Equal(t, tt.expectedValue, value)
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/logger"
+	models "github.com/IgorKilipenko/metrical/internal/model"
+)
+
+// DefaultInterval интервал вычисления правил по умолчанию
+const DefaultInterval = 15 * time.Second
+
+// State состояние правила
+type State string
+
+const (
+	// StateInactive условие не выполняется
+	StateInactive State = "inactive"
+	// StatePending условие выполняется меньше длительности for
+	StatePending State = "pending"
+	// StateFiring условие выполняется дольше длительности for, уведомление отправлено
+	StateFiring State = "firing"
+	// StateResolved условие перестало выполняться после срабатывания
+	StateResolved State = "resolved"
+)
+
+// MetricsSource источник значений метрик (реализуется service.MetricsService)
+type MetricsSource interface {
+	GetGauge(ctx context.Context, name string) (float64, bool, error)
+	GetCounter(ctx context.Context, name string) (int64, bool, error)
+}
+
+// Notifier получатель уведомлений о срабатывании и снятии алертов
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// Alert состояние правила для API и уведомлений
+type Alert struct {
+	Rule           Rule       `json:"rule"`
+	For            string     `json:"for,omitempty"`
+	State          State      `json:"state"`
+	Value          *float64   `json:"value,omitempty"`
+	ActiveAt       *time.Time `json:"activeAt,omitempty"`
+	FiredAt        *time.Time `json:"firedAt,omitempty"`
+	ResolvedAt     *time.Time `json:"resolvedAt,omitempty"`
+	LastEvaluation *time.Time `json:"lastEvaluation,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+}
+
+// Notification уведомление о смене состояния алерта (тело запроса webhook)
+type Notification struct {
+	Status State `json:"status"` // firing или resolved
+	Alert  Alert `json:"alert"`
+}
+
+// ruleState состояние вычисления правила
+type ruleState struct {
+	alert Alert
+
+	// Для правил "stops increasing": последнее значение и время его изменения
+	lastValue  *float64
+	lastChange time.Time
+}
+
+// Engine периодически вычисляет правила по значениям метрик и отправляет уведомления о переходах
+type Engine struct {
+	source   MetricsSource
+	notifier Notifier // nil - уведомления не отправляются
+	logger   logger.Logger
+
+	mu    sync.RWMutex
+	rules []*ruleState
+}
+
+// NewEngine создает движок правил. Выражения правил должны быть разобраны (Rule.Parse или LoadFile).
+func NewEngine(rules []Rule, source MetricsSource, notifier Notifier, logger logger.Logger) (*Engine, error) {
+	if source == nil {
+		return nil, fmt.Errorf("metrics source cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+
+	states := make([]*ruleState, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Kind == "" {
+			return nil, fmt.Errorf("rule %q is not parsed", rule.Name)
+		}
+		alert := Alert{Rule: rule, State: StateInactive}
+		if rule.For > 0 {
+			alert.For = rule.For.String()
+		}
+		states = append(states, &ruleState{alert: alert})
+	}
+
+	return &Engine{
+		source:   source,
+		notifier: notifier,
+		logger:   logger,
+		rules:    states,
+	}, nil
+}
+
+// Run вычисляет правила с заданным интервалом до отмены контекста
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	e.logger.Info("alerting engine started", "rules", len(e.rules), "interval", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			e.logger.Info("alerting engine stopped")
+			return
+		case now := <-ticker.C:
+			e.Evaluate(ctx, now)
+		}
+	}
+}
+
+// Evaluate вычисляет все правила на момент now и отправляет уведомления о срабатывании и снятии алертов.
+// Возвращает отправленные уведомления.
+func (e *Engine) Evaluate(ctx context.Context, now time.Time) []Notification {
+	e.mu.Lock()
+	var notifications []Notification
+	for _, state := range e.rules {
+		if notification, ok := e.evaluateRule(ctx, state, now); ok {
+			notifications = append(notifications, notification)
+		}
+	}
+	e.mu.Unlock()
+
+	// Уведомления отправляются без блокировки, чтобы медленный получатель не задерживал чтение состояния
+	for _, notification := range notifications {
+		e.notify(ctx, notification)
+	}
+	return notifications
+}
+
+// Alerts возвращает копию текущего состояния всех правил
+func (e *Engine) Alerts() []Alert {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	alerts := make([]Alert, 0, len(e.rules))
+	for _, state := range e.rules {
+		alerts = append(alerts, state.alert)
+	}
+	return alerts
+}
+
+// evaluateRule обновляет состояние правила и возвращает уведомление, если алерт сработал или снят
+func (e *Engine) evaluateRule(ctx context.Context, state *ruleState, now time.Time) (Notification, bool) {
+	rule := &state.alert.Rule
+	evaluatedAt := now
+	state.alert.LastEvaluation = &evaluatedAt
+
+	value, exists, err := e.readMetric(ctx, rule)
+	if err != nil {
+		// При ошибке чтения состояние не меняется: недоступное хранилище не должно снимать алерты
+		e.logger.Warn("failed to evaluate alert rule", "rule", rule.Name, "error", err)
+		state.alert.LastError = err.Error()
+		return Notification{}, false
+	}
+	state.alert.LastError = ""
+
+	state.alert.Value = nil
+	if exists {
+		state.alert.Value = &value
+	}
+
+	var active bool
+	activeSince := now
+	switch rule.Kind {
+	case KindThreshold:
+		active = exists && rule.matches(value)
+	case KindStale:
+		// Условие выполняется, пока значение не меняется с прошлого вычисления;
+		// пропавшая метрика тоже считается нерастущей
+		changed := state.lastChange.IsZero() || (exists && (state.lastValue == nil || *state.lastValue != value))
+		if changed {
+			state.lastChange = now
+		}
+		if exists {
+			state.lastValue = &value
+		}
+		active = !changed
+		activeSince = state.lastChange
+	}
+
+	return e.transition(state, active, activeSince, now)
+}
+
+// transition выполняет переход состояния правила
+func (e *Engine) transition(state *ruleState, active bool, activeSince, now time.Time) (Notification, bool) {
+	alert := &state.alert
+
+	if !active {
+		switch alert.State {
+		case StateFiring:
+			resolvedAt := now
+			alert.State = StateResolved
+			alert.ResolvedAt = &resolvedAt
+			alert.ActiveAt = nil
+			e.logger.Info("alert resolved", "rule", alert.Rule.Name)
+			return Notification{Status: StateResolved, Alert: *alert}, true
+		case StatePending:
+			alert.State = StateInactive
+			alert.ActiveAt = nil
+		}
+		return Notification{}, false
+	}
+
+	if alert.State == StateFiring {
+		return Notification{}, false
+	}
+
+	if alert.State != StatePending {
+		since := activeSince
+		alert.State = StatePending
+		alert.ActiveAt = &since
+		alert.FiredAt = nil
+		alert.ResolvedAt = nil
+	}
+
+	if now.Sub(*alert.ActiveAt) < alert.Rule.For {
+		return Notification{}, false
+	}
+
+	firedAt := now
+	alert.State = StateFiring
+	alert.FiredAt = &firedAt
+	e.logger.Warn("alert firing", "rule", alert.Rule.Name, "expr", alert.Rule.Expr)
+	return Notification{Status: StateFiring, Alert: *alert}, true
+}
+
+// readMetric читает значение метрики правила
+func (e *Engine) readMetric(ctx context.Context, rule *Rule) (float64, bool, error) {
+	switch rule.MetricType {
+	case models.Gauge:
+		return e.source.GetGauge(ctx, rule.Metric)
+	case models.Counter:
+		value, exists, err := e.source.GetCounter(ctx, rule.Metric)
+		return float64(value), exists, err
+	default:
+		return 0, false, fmt.Errorf("%w: %s", models.ErrUnsupportedMetricType, rule.MetricType)
+	}
+}
+
+// notify отправляет уведомление, ошибки доставки только логируются
+func (e *Engine) notify(ctx context.Context, notification Notification) {
+	if e.notifier == nil {
+		return
+	}
+	if err := e.notifier.Notify(ctx, notification); err != nil {
+		e.logger.Error("failed to deliver alert notification",
+			"rule", notification.Alert.Rule.Name,
+			"status", notification.Status,
+			"error", err)
+	}
+}
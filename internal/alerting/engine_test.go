@@ -0,0 +1,222 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource источник метрик с изменяемыми значениями
+type fakeSource struct {
+	mu       sync.Mutex
+	gauges   map[string]float64
+	counters map[string]int64
+	err      error
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{gauges: map[string]float64{}, counters: map[string]int64{}}
+}
+
+func (s *fakeSource) GetGauge(_ context.Context, name string) (float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.gauges[name]
+	return value, ok, s.err
+}
+
+func (s *fakeSource) GetCounter(_ context.Context, name string) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.counters[name]
+	return value, ok, s.err
+}
+
+// recordingNotifier запоминает полученные уведомления
+type recordingNotifier struct {
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, notification Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+// newTestEngine создает движок с одним правилом
+func newTestEngine(t *testing.T, expr string, source MetricsSource, notifier Notifier) *Engine {
+	t.Helper()
+
+	rule := Rule{Name: "test", Expr: expr}
+	require.NoError(t, rule.Parse())
+
+	engine, err := NewEngine([]Rule{rule}, source, notifier, testutils.NewMockLogger())
+	require.NoError(t, err)
+	return engine
+}
+
+func TestEngine_ThresholdLifecycle(t *testing.T) {
+	source := newFakeSource()
+	notifier := &recordingNotifier{}
+	engine := newTestEngine(t, "gauge HeapAlloc > 500MB for 2m", source, notifier)
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Метрики еще нет - правило неактивно
+	assert.Empty(t, engine.Evaluate(ctx, start))
+	assert.Equal(t, StateInactive, engine.Alerts()[0].State)
+
+	// Порог превышен - ожидание длительности for
+	source.gauges["HeapAlloc"] = 600 << 20
+	assert.Empty(t, engine.Evaluate(ctx, start.Add(time.Minute)))
+	alert := engine.Alerts()[0]
+	assert.Equal(t, StatePending, alert.State)
+	require.NotNil(t, alert.ActiveAt)
+	assert.Equal(t, start.Add(time.Minute), *alert.ActiveAt)
+
+	// Условие выполняется дольше 2m - алерт срабатывает один раз
+	notifications := engine.Evaluate(ctx, start.Add(3*time.Minute))
+	require.Len(t, notifications, 1)
+	assert.Equal(t, StateFiring, notifications[0].Status)
+	assert.Equal(t, float64(600<<20), *notifications[0].Alert.Value)
+	assert.Empty(t, engine.Evaluate(ctx, start.Add(4*time.Minute)), "Firing alert must not be sent again")
+
+	// Значение ниже порога - алерт снят
+	source.gauges["HeapAlloc"] = 100 << 20
+	notifications = engine.Evaluate(ctx, start.Add(5*time.Minute))
+	require.Len(t, notifications, 1)
+	assert.Equal(t, StateResolved, notifications[0].Status)
+	alert = engine.Alerts()[0]
+	assert.Equal(t, StateResolved, alert.State)
+	require.NotNil(t, alert.ResolvedAt)
+
+	assert.Len(t, notifier.notifications, 2, "Notifier should receive firing and resolved")
+}
+
+func TestEngine_PendingResetsWithoutNotification(t *testing.T) {
+	source := newFakeSource()
+	notifier := &recordingNotifier{}
+	engine := newTestEngine(t, "gauge HeapAlloc > 100 for 2m", source, notifier)
+	ctx := context.Background()
+	start := time.Now()
+
+	source.gauges["HeapAlloc"] = 200
+	engine.Evaluate(ctx, start)
+	assert.Equal(t, StatePending, engine.Alerts()[0].State)
+
+	source.gauges["HeapAlloc"] = 50
+	assert.Empty(t, engine.Evaluate(ctx, start.Add(time.Minute)))
+	assert.Equal(t, StateInactive, engine.Alerts()[0].State)
+
+	// Повторное превышение начинает ожидание заново
+	source.gauges["HeapAlloc"] = 200
+	engine.Evaluate(ctx, start.Add(2*time.Minute))
+	assert.Empty(t, engine.Evaluate(ctx, start.Add(3*time.Minute)))
+	assert.Len(t, engine.Evaluate(ctx, start.Add(4*time.Minute)), 1)
+}
+
+func TestEngine_ZeroDurationFiresImmediately(t *testing.T) {
+	source := newFakeSource()
+	source.counters["PollCount"] = 10
+	engine := newTestEngine(t, "counter PollCount >= 10", source, nil)
+
+	notifications := engine.Evaluate(context.Background(), time.Now())
+	require.Len(t, notifications, 1)
+	assert.Equal(t, StateFiring, notifications[0].Status)
+}
+
+func TestEngine_StopsIncreasing(t *testing.T) {
+	source := newFakeSource()
+	engine := newTestEngine(t, "counter PollCount stops increasing for 5m", source, nil)
+	ctx := context.Background()
+	start := time.Now()
+
+	// Счетчик растет - условие не выполняется
+	for i := range 3 {
+		source.counters["PollCount"] = int64(i + 1)
+		assert.Empty(t, engine.Evaluate(ctx, start.Add(time.Duration(i)*time.Minute)))
+		assert.Equal(t, StateInactive, engine.Alerts()[0].State)
+	}
+	lastIncrease := start.Add(2 * time.Minute)
+
+	// Счетчик остановился - ожидание отсчитывается от последнего роста
+	engine.Evaluate(ctx, start.Add(4*time.Minute))
+	alert := engine.Alerts()[0]
+	assert.Equal(t, StatePending, alert.State)
+	assert.Equal(t, lastIncrease, *alert.ActiveAt)
+
+	notifications := engine.Evaluate(ctx, start.Add(7*time.Minute))
+	require.Len(t, notifications, 1)
+	assert.Equal(t, StateFiring, notifications[0].Status)
+
+	// Рост возобновился - алерт снят
+	source.counters["PollCount"] = 100
+	notifications = engine.Evaluate(ctx, start.Add(8*time.Minute))
+	require.Len(t, notifications, 1)
+	assert.Equal(t, StateResolved, notifications[0].Status)
+}
+
+func TestEngine_SourceErrorKeepsState(t *testing.T) {
+	source := newFakeSource()
+	source.gauges["HeapAlloc"] = 200
+	engine := newTestEngine(t, "gauge HeapAlloc > 100", source, nil)
+	ctx := context.Background()
+	start := time.Now()
+
+	require.Len(t, engine.Evaluate(ctx, start), 1)
+
+	source.err = models.ErrStorageUnavailable
+	assert.Empty(t, engine.Evaluate(ctx, start.Add(time.Minute)), "Storage outage must not resolve alerts")
+	alert := engine.Alerts()[0]
+	assert.Equal(t, StateFiring, alert.State)
+	assert.Contains(t, alert.LastError, "storage unavailable")
+
+	source.err = nil
+	engine.Evaluate(ctx, start.Add(2*time.Minute))
+	assert.Empty(t, engine.Alerts()[0].LastError)
+}
+
+func TestEngine_Run(t *testing.T) {
+	source := newFakeSource()
+	source.gauges["HeapAlloc"] = 200
+	notifier := &recordingNotifier{}
+	engine := newTestEngine(t, "gauge HeapAlloc > 100", source, notifier)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		engine.Run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		notifier.mu.Lock()
+		defer notifier.mu.Unlock()
+		return len(notifier.notifications) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestNewEngine_Errors(t *testing.T) {
+	logger := testutils.NewMockLogger()
+
+	_, err := NewEngine(nil, nil, nil, logger)
+	assert.Error(t, err)
+
+	_, err = NewEngine([]Rule{{Name: "raw", Expr: "gauge Alloc > 1"}}, newFakeSource(), nil, logger)
+	assert.Error(t, err, "Unparsed rules should be rejected")
+
+	_, err = NewEngine(nil, newFakeSource(), nil, nil)
+	assert.True(t, err != nil && !errors.Is(err, models.ErrStorageUnavailable))
+}
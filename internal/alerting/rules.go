@@ -0,0 +1,195 @@
+package alerting
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// Kind вид условия правила
+type Kind string
+
+const (
+	// KindThreshold значение метрики сравнивается с порогом
+	KindThreshold Kind = "threshold"
+	// KindStale значение метрики перестало расти
+	KindStale Kind = "stale"
+)
+
+// Rule правило алертинга.
+// Условие задается выражением вида "gauge HeapAlloc > 500MB for 2m"
+// или "counter PollCount stops increasing for 5m".
+type Rule struct {
+	Name    string `yaml:"name" json:"name"`
+	Expr    string `yaml:"expr" json:"expr"`
+	Summary string `yaml:"summary" json:"summary,omitempty"`
+
+	// Поля, заполняемые разбором Expr
+	MetricType string        `yaml:"-" json:"type"`
+	Metric     string        `yaml:"-" json:"metric"`
+	Kind       Kind          `yaml:"-" json:"kind"`
+	Op         string        `yaml:"-" json:"op,omitempty"`
+	Threshold  float64       `yaml:"-" json:"threshold,omitempty"`
+	For        time.Duration `yaml:"-" json:"-"`
+}
+
+// File содержимое файла правил (YAML или JSON)
+type File struct {
+	Webhooks []string `yaml:"webhooks" json:"webhooks"`
+	Rules    []Rule   `yaml:"rules" json:"rules"`
+}
+
+// operators поддерживаемые операторы сравнения
+var operators = map[string]func(value, threshold float64) bool{
+	">":  func(v, t float64) bool { return v > t },
+	">=": func(v, t float64) bool { return v >= t },
+	"<":  func(v, t float64) bool { return v < t },
+	"<=": func(v, t float64) bool { return v <= t },
+	"==": func(v, t float64) bool { return v == t },
+	"!=": func(v, t float64) bool { return v != t },
+}
+
+// byteUnits множители суффиксов порога (двоичные: 1KB = 1024 байта)
+var byteUnits = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"B", 1},
+}
+
+// LoadFile читает правила из YAML или JSON файла и разбирает их выражения
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	// JSON является подмножеством YAML, поэтому один разборщик подходит для обоих форматов
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file: %w", err)
+	}
+
+	if err := file.Validate(); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// Validate разбирает выражения правил и проверяет адреса webhook
+func (f *File) Validate() error {
+	names := make(map[string]bool, len(f.Rules))
+	for i := range f.Rules {
+		rule := &f.Rules[i]
+		if err := rule.Parse(); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("rule %d: duplicate rule name %q", i, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+
+	for _, webhook := range f.Webhooks {
+		if err := ValidateWebhookURL(webhook); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse разбирает выражение правила и заполняет поля условия
+func (r *Rule) Parse() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("rule name cannot be empty")
+	}
+
+	tokens := strings.Fields(r.Expr)
+	if len(tokens) < 4 {
+		return fmt.Errorf("rule %q: invalid expression %q: expected '<type> <metric> <op> <threshold> [for <duration>]' or '<type> <metric> stops increasing for <duration>'", r.Name, r.Expr)
+	}
+
+	r.MetricType, r.Metric = tokens[0], tokens[1]
+	if r.MetricType != models.Gauge && r.MetricType != models.Counter {
+		return fmt.Errorf("rule %q: %w: %s", r.Name, models.ErrUnsupportedMetricType, r.MetricType)
+	}
+
+	rest := tokens[2:]
+	if rest[0] == "stops" {
+		if rest[1] != "increasing" {
+			return fmt.Errorf("rule %q: expected 'stops increasing', got 'stops %s'", r.Name, rest[1])
+		}
+		r.Kind, r.Op, r.Threshold = KindStale, "", 0
+		rest = rest[2:]
+	} else {
+		if _, ok := operators[rest[0]]; !ok {
+			return fmt.Errorf("rule %q: unsupported operator %q", r.Name, rest[0])
+		}
+		threshold, err := parseThreshold(rest[1])
+		if err != nil {
+			return fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		r.Kind, r.Op, r.Threshold = KindThreshold, rest[0], threshold
+		rest = rest[2:]
+	}
+
+	r.For = 0
+	switch {
+	case len(rest) == 0:
+	case len(rest) == 2 && rest[0] == "for":
+		duration, err := time.ParseDuration(rest[1])
+		if err != nil || duration < 0 {
+			return fmt.Errorf("rule %q: invalid duration %q", r.Name, rest[1])
+		}
+		r.For = duration
+	default:
+		return fmt.Errorf("rule %q: unexpected %q in expression", r.Name, strings.Join(rest, " "))
+	}
+
+	if r.Kind == KindStale && r.For <= 0 {
+		return fmt.Errorf("rule %q: 'stops increasing' requires a positive 'for' duration", r.Name)
+	}
+	return nil
+}
+
+// matches проверяет порог правила для значения метрики
+func (r *Rule) matches(value float64) bool {
+	return operators[r.Op](value, r.Threshold)
+}
+
+// parseThreshold разбирает число с необязательным суффиксом размера (KB, MB, GB, TB, KiB...)
+func parseThreshold(value string) (float64, error) {
+	upper := strings.ToUpper(value)
+	for _, unit := range byteUnits {
+		if number, ok := strings.CutSuffix(upper, unit.suffix); ok && number != "" {
+			parsed, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid threshold %q", value)
+			}
+			return parsed * unit.multiplier, nil
+		}
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid threshold %q", value)
+	}
+	return parsed, nil
+}
+
+// ValidateWebhookURL проверяет, что адрес webhook - абсолютный HTTP(S) URL
+func ValidateWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q: expected http(s)://host/path", raw)
+	}
+	return nil
+}
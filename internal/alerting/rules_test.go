@@ -0,0 +1,143 @@
+package alerting
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRule_Parse(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		expected Rule
+	}{
+		{
+			name: "gauge threshold with size suffix",
+			expr: "gauge HeapAlloc > 500MB for 2m",
+			expected: Rule{MetricType: "gauge", Metric: "HeapAlloc", Kind: KindThreshold,
+				Op: ">", Threshold: 500 << 20, For: 2 * time.Minute},
+		},
+		{
+			name:     "threshold without duration",
+			expr:     "gauge RandomValue <= 0.5",
+			expected: Rule{MetricType: "gauge", Metric: "RandomValue", Kind: KindThreshold, Op: "<=", Threshold: 0.5},
+		},
+		{
+			name:     "counter threshold",
+			expr:     "counter PollCount >= 1000 for 30s",
+			expected: Rule{MetricType: "counter", Metric: "PollCount", Kind: KindThreshold, Op: ">=", Threshold: 1000, For: 30 * time.Second},
+		},
+		{
+			name:     "binary suffix is case insensitive",
+			expr:     "gauge Sys != 1gib",
+			expected: Rule{MetricType: "gauge", Metric: "Sys", Kind: KindThreshold, Op: "!=", Threshold: 1 << 30},
+		},
+		{
+			name:     "counter stops increasing",
+			expr:     "counter PollCount stops increasing for 5m",
+			expected: Rule{MetricType: "counter", Metric: "PollCount", Kind: KindStale, For: 5 * time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := Rule{Name: "test", Expr: tt.expr}
+			require.NoError(t, rule.Parse())
+
+			assert.Equal(t, tt.expected.MetricType, rule.MetricType)
+			assert.Equal(t, tt.expected.Metric, rule.Metric)
+			assert.Equal(t, tt.expected.Kind, rule.Kind)
+			assert.Equal(t, tt.expected.Op, rule.Op)
+			assert.Equal(t, tt.expected.Threshold, rule.Threshold)
+			assert.Equal(t, tt.expected.For, rule.For)
+		})
+	}
+}
+
+func TestRule_Parse_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "empty name", rule: Rule{Expr: "gauge Alloc > 1"}},
+		{name: "too short", rule: Rule{Name: "r", Expr: "gauge Alloc >"}},
+		{name: "unknown type", rule: Rule{Name: "r", Expr: "histogram Alloc > 1"}},
+		{name: "unknown operator", rule: Rule{Name: "r", Expr: "gauge Alloc => 1"}},
+		{name: "invalid threshold", rule: Rule{Name: "r", Expr: "gauge Alloc > lots"}},
+		{name: "invalid duration", rule: Rule{Name: "r", Expr: "gauge Alloc > 1 for soon"}},
+		{name: "trailing tokens", rule: Rule{Name: "r", Expr: "gauge Alloc > 1 for 1m please"}},
+		{name: "stops without increasing", rule: Rule{Name: "r", Expr: "counter PollCount stops growing for 1m"}},
+		{name: "stale without duration", rule: Rule{Name: "r", Expr: "counter PollCount stops increasing"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.rule.Parse())
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("yaml", func(t *testing.T) {
+		path := filepath.Join(dir, "rules.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+webhooks:
+  - http://localhost:9093/hooks/metrical
+rules:
+  - name: HighHeap
+    expr: gauge HeapAlloc > 500MB for 2m
+    summary: Heap usage is above 500MB
+  - name: AgentStalled
+    expr: counter PollCount stops increasing for 5m
+`), 0o600))
+
+		file, err := LoadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"http://localhost:9093/hooks/metrical"}, file.Webhooks)
+		require.Len(t, file.Rules, 2)
+		assert.Equal(t, "Heap usage is above 500MB", file.Rules[0].Summary)
+		assert.Equal(t, KindStale, file.Rules[1].Kind)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		path := filepath.Join(dir, "rules.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"rules": [{"name": "HighHeap", "expr": "gauge HeapAlloc > 500MB"}]}`), 0o600))
+
+		file, err := LoadFile(path)
+		require.NoError(t, err)
+		require.Len(t, file.Rules, 1)
+		assert.Equal(t, float64(500<<20), file.Rules[0].Threshold)
+	})
+
+	t.Run("duplicate names", func(t *testing.T) {
+		path := filepath.Join(dir, "duplicate.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+rules:
+  - {name: A, expr: gauge Alloc > 1}
+  - {name: A, expr: gauge Sys > 1}
+`), 0o600))
+
+		_, err := LoadFile(path)
+		assert.ErrorContains(t, err, "duplicate rule name")
+	})
+
+	t.Run("invalid webhook", func(t *testing.T) {
+		path := filepath.Join(dir, "webhook.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("webhooks: [ftp://example.com]\n"), 0o600))
+
+		_, err := LoadFile(path)
+		assert.ErrorContains(t, err, "invalid webhook URL")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadFile(filepath.Join(dir, "missing.yaml"))
+		assert.Error(t, err)
+	})
+}
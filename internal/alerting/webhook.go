@@ -0,0 +1,119 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/logger"
+)
+
+const (
+	// DefaultWebhookTimeout таймаут одного запроса к webhook
+	DefaultWebhookTimeout = 5 * time.Second
+	// DefaultWebhookAttempts количество попыток доставки уведомления
+	DefaultWebhookAttempts = 3
+	// DefaultWebhookRetryDelay пауза между попытками доставки
+	DefaultWebhookRetryDelay = 500 * time.Millisecond
+)
+
+// WebhookNotifier отправляет уведомления POST запросом с JSON телом на каждый из адресов
+type WebhookNotifier struct {
+	urls       []string
+	client     *http.Client
+	attempts   int
+	retryDelay time.Duration
+	logger     logger.Logger
+}
+
+// NewWebhookNotifier создает получателя уведомлений для адресов webhook.
+// При nil клиенте используется http.Client с таймаутом DefaultWebhookTimeout.
+func NewWebhookNotifier(urls []string, client *http.Client, logger logger.Logger) (*WebhookNotifier, error) {
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+	for _, url := range urls {
+		if err := ValidateWebhookURL(url); err != nil {
+			return nil, err
+		}
+	}
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+
+	return &WebhookNotifier{
+		urls:       urls,
+		client:     client,
+		attempts:   DefaultWebhookAttempts,
+		retryDelay: DefaultWebhookRetryDelay,
+		logger:     logger,
+	}, nil
+}
+
+// Notify доставляет уведомление на все адреса; ошибки отдельных адресов объединяются
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	var errs []error
+	for _, url := range n.urls {
+		if err := n.deliver(ctx, url, body); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", url, err))
+			continue
+		}
+		n.logger.Debug("alert notification delivered", "url", url, "rule", notification.Alert.Rule.Name, "status", notification.Status)
+	}
+	return errors.Join(errs...)
+}
+
+// deliver отправляет тело на адрес, повторяя попытку при сетевой ошибке или ответе 5xx
+func (n *WebhookNotifier) deliver(ctx context.Context, url string, body []byte) error {
+	var lastErr error
+	for attempt := 1; attempt <= n.attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(n.retryDelay):
+			}
+		}
+
+		retry, err := n.post(ctx, url, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+// post выполняет один запрос; возвращает признак того, что попытку стоит повторить
+func (n *WebhookNotifier) post(ctx context.Context, url string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	return resp.StatusCode >= 500, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
@@ -0,0 +1,109 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver локальный получатель уведомлений
+type webhookReceiver struct {
+	mu            sync.Mutex
+	notifications []Notification
+	failures      int // Количество ответов 500 перед успешным
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var notification Notification
+	if err := json.NewDecoder(req.Body).Decode(&notification); err != nil || req.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.notifications = append(r.notifications, notification)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *webhookReceiver) received() []Notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Notification(nil), r.notifications...)
+}
+
+func TestWebhookNotifier_EngineIntegration(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier([]string{server.URL + "/alerts"}, nil, testutils.NewMockLogger())
+	require.NoError(t, err)
+
+	source := newFakeSource()
+	source.gauges["HeapAlloc"] = 600 << 20
+	engine := newTestEngine(t, "gauge HeapAlloc > 500MB", source, notifier)
+	ctx := context.Background()
+	start := time.Now()
+
+	engine.Evaluate(ctx, start)
+	source.gauges["HeapAlloc"] = 0
+	engine.Evaluate(ctx, start.Add(time.Minute))
+
+	received := receiver.received()
+	require.Len(t, received, 2)
+	assert.Equal(t, StateFiring, received[0].Status)
+	assert.Equal(t, "test", received[0].Alert.Rule.Name)
+	assert.Equal(t, "HeapAlloc", received[0].Alert.Rule.Metric)
+	assert.Equal(t, StateResolved, received[1].Status)
+	assert.NotNil(t, received[1].Alert.ResolvedAt)
+}
+
+func TestWebhookNotifier_Retries(t *testing.T) {
+	receiver := &webhookReceiver{failures: 2}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier([]string{server.URL}, nil, testutils.NewMockLogger())
+	require.NoError(t, err)
+	notifier.retryDelay = time.Millisecond
+
+	require.NoError(t, notifier.Notify(context.Background(), Notification{Status: StateFiring}))
+	assert.Len(t, receiver.received(), 1, "Notification should be delivered after 5xx retries")
+}
+
+func TestWebhookNotifier_Errors(t *testing.T) {
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+
+	receiver := &webhookReceiver{}
+	accepting := httptest.NewServer(receiver)
+	defer accepting.Close()
+
+	notifier, err := NewWebhookNotifier([]string{rejecting.URL, accepting.URL}, nil, testutils.NewMockLogger())
+	require.NoError(t, err)
+	notifier.retryDelay = time.Millisecond
+
+	err = notifier.Notify(context.Background(), Notification{Status: StateFiring})
+	assert.ErrorContains(t, err, "unexpected status 400")
+	assert.Len(t, receiver.received(), 1, "Failure of one webhook must not block the others")
+
+	_, err = NewWebhookNotifier([]string{"not a url"}, nil, testutils.NewMockLogger())
+	assert.Error(t, err)
+}
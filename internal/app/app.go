@@ -4,32 +4,82 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/IgorKilipenko/metrical/internal/alerting"
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/config/db"
+	"github.com/IgorKilipenko/metrical/internal/encryption"
+	"github.com/IgorKilipenko/metrical/internal/grpcserver"
 	"github.com/IgorKilipenko/metrical/internal/handler"
 	"github.com/IgorKilipenko/metrical/internal/httpserver"
+	"github.com/IgorKilipenko/metrical/internal/idempotency"
 	"github.com/IgorKilipenko/metrical/internal/logger"
+	"github.com/IgorKilipenko/metrical/internal/otlp"
+	"github.com/IgorKilipenko/metrical/internal/ratelimit"
+	"github.com/IgorKilipenko/metrical/internal/remotewrite"
 	"github.com/IgorKilipenko/metrical/internal/repository"
 	"github.com/IgorKilipenko/metrical/internal/service"
+	"github.com/IgorKilipenko/metrical/internal/statsd"
+	"github.com/IgorKilipenko/metrical/migrations"
 )
 
 // App представляет основное приложение
 type App struct {
-	server *httpserver.Server
-	addr   string
-	config Config
+	server     *httpserver.Server
+	grpcServer *grpcserver.Server // gRPC сервер (nil - gRPC API отключен)
+	addr       string
+	config     Config
 }
 
 // Config содержит конфигурацию приложения
 type Config struct {
-	Addr            string // Адрес сервера (например, "localhost")
-	Port            string // Порт сервера (например, "8080")
-	FileStoragePath string // Путь к файлу для сохранения метрик
-	Restore         bool   // Флаг для восстановления метрик из файла
-	StoreInterval   int    // Интервал сохранения метрик в секундах
+	Addr                  string       // Адрес сервера (например, "localhost")
+	Port                  string       // Порт сервера (например, "8080")
+	FileStoragePath       string       // Путь к файлу для сохранения метрик
+	Restore               bool         // Флаг для восстановления метрик из файла
+	StoreInterval         int          // Интервал сохранения метрик в секундах
+	DatabaseDSN           string       // Строка подключения к PostgreSQL (пустая строка - хранение в памяти)
+	WALEnabled            bool         // Журнал упреждающей записи вместо перезаписи файла при синхронном сохранении
+	WALCheckpointInterval int          // Интервал контрольных точек журнала в секундах (снапшот + очистка журнала)
+	SnapshotKeep          int          // Количество хранимых снапшотов (0 - значение по умолчанию репозитория)
+	HistoryRetention      int          // Срок хранения истории значений метрик в секундах (0 - история отключена)
+	HistorySize           int          // Максимальное количество значений истории на метрику
+	IdempotencyTTL        int          // Время хранения результатов запросов с Idempotency-Key в секундах (0 - отключено)
+	IdempotencySize       int          // Максимальное количество хранимых ключей идемпотентности
+	Key                   string       // Общий ключ подписи HMAC-SHA256 (пустая строка - подпись отключена)
+	CryptoKey             string       // Путь к приватному RSA ключу для расшифровки запросов (пустая строка - отключено)
+	TLSCertFile           string       // Путь к сертификату сервера (пустая строка - HTTP без TLS)
+	TLSKeyFile            string       // Путь к приватному ключу сертификата сервера
+	TLSClientCAFile       string       // Путь к бандлу CA клиентских сертификатов (пустая строка - mTLS отключен)
+	TrustedSubnet         string       // CIDR подсети, из которой разрешена запись метрик (пустая строка - без ограничений)
+	TrustedReadSubnet     string       // CIDR подсети, из которой разрешено чтение метрик (пустая строка - без ограничений)
+	TrustedProxies        []string     // CIDR прокси, которым доверяется X-Real-IP при ограничении частоты
+	AuthTokensFile        string       // Путь к JSON файлу с API токенами (пустая строка - токены только из AuthTokens)
+	AuthTokens            []auth.Token // API токены из конфигурации (без токенов аутентификация отключена)
+	RateLimitWrite        int          // Лимит запросов на запись в секунду для каждого клиента (0 - без ограничений)
+	RateLimitWriteBurst   int          // Допустимый всплеск запросов на запись (0 - равен лимиту)
+	RateLimitRead         int          // Лимит запросов на чтение в секунду для каждого клиента (0 - без ограничений)
+	RateLimitReadBurst    int          // Допустимый всплеск запросов на чтение (0 - равен лимиту)
+	MaxBodySize           int64        // Максимальный размер несжатого тела запроса в байтах (0 - без ограничений)
+	MaxCompressedBodySize int64        // Максимальный размер сжатого тела запроса в байтах (0 - без ограничений)
+	AlertRulesFile        string       // Путь к YAML/JSON файлу правил алертинга (пустая строка - алертинг отключен)
+	AlertInterval         int          // Интервал вычисления правил алертинга в секундах (0 - значение по умолчанию)
+	AlertWebhooks         []string     // Адреса webhook для уведомлений (дополняют адреса из файла правил)
+	StatsDAddr            string       // UDP адрес приема метрик StatsD (пустая строка - отключен)
+	StatsDAllowUnsigned   bool         // Разрешить StatsD при включенной подписи или API токенах (StatsD их не проверяет)
+	GRPCAddr              string       // Адрес gRPC сервера (пустая строка - gRPC API отключен)
+
+	RemoteWriteMaxBodySize    int64 // Максимальный размер сжатого тела запроса remote_write в байтах (0 - без ограничений)
+	RemoteWriteMaxDecodedSize int64 // Максимальный размер распакованного тела запроса remote_write в байтах (0 - без ограничений)
+	RemoteWriteMaxSeries      int   // Максимальное количество отслеживаемых рядов counter remote_write (0 - без ограничений)
+	RemoteWriteSeriesTTL      int   // Время хранения состояния ряда remote_write без новых отсчетов в секундах (0 - без ограничения)
+	OTLPMaxStreams            int   // Максимальное количество отслеживаемых потоков сумм OTLP (0 - без ограничений)
+	OTLPStreamTTL             int   // Время хранения состояния потока OTLP без новых точек в секундах (0 - без ограничения)
 }
 
 // New создает новое приложение с заданной конфигурацией
@@ -48,11 +98,15 @@ func (a *App) Run() error {
 	appLogger := logger.NewSlogLogger()
 
 	// Создаем зависимости (Dependency Injection)
-	repository := repository.NewInMemoryMetricsRepository(appLogger, a.config.FileStoragePath, a.config.Restore)
+	repository, closeRepository, err := a.createRepository(appLogger)
+	if err != nil {
+		return fmt.Errorf("failed to create repository: %w", err)
+	}
+	defer closeRepository()
 
-	// Устанавливаем синхронное сохранение, если интервал = 0
-	if a.config.StoreInterval == 0 {
-		repository.SetSyncSave(true)
+	repository, err = a.withHistory(repository, appLogger)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics history: %w", err)
 	}
 
 	service := service.NewMetricsService(repository, appLogger)
@@ -61,22 +115,115 @@ func (a *App) Run() error {
 		return fmt.Errorf("failed to create metrics handler: %w", err)
 	}
 
+	idempotencyStore, err := a.createIdempotencyStore(appLogger)
+	if err != nil {
+		return fmt.Errorf("failed to configure idempotency: %w", err)
+	}
+	handler.SetIdempotencyStore(idempotencyStore)
+
+	if err := handler.SetMaxBodySize(a.config.MaxBodySize); err != nil {
+		return fmt.Errorf("invalid max body size: %w", err)
+	}
+
+	if err := handler.SetRemoteWriteConfig(&remotewrite.Config{
+		MaxBodySize:    a.config.RemoteWriteMaxBodySize,
+		MaxDecodedSize: a.config.RemoteWriteMaxDecodedSize,
+		MaxSeries:      a.config.RemoteWriteMaxSeries,
+		SeriesTTL:      time.Duration(a.config.RemoteWriteSeriesTTL) * time.Second,
+	}); err != nil {
+		return fmt.Errorf("invalid remote write limits: %w", err)
+	}
+
+	if err := handler.SetOTLPConfig(&otlp.Config{
+		MaxStreams: a.config.OTLPMaxStreams,
+		StreamTTL:  time.Duration(a.config.OTLPStreamTTL) * time.Second,
+	}); err != nil {
+		return fmt.Errorf("invalid OTLP limits: %w", err)
+	}
+
+	alertEngine, err := a.createAlertEngine(service, appLogger)
+	if err != nil {
+		return fmt.Errorf("failed to configure alerting: %w", err)
+	}
+	if alertEngine != nil {
+		if err := handler.EnableAlerts(alertEngine); err != nil {
+			return fmt.Errorf("failed to enable alerts API: %w", err)
+		}
+	}
+
 	// Создаем сервер с переданными зависимостями
-	server, err := httpserver.NewServer(a.addr, handler, appLogger)
+	serverConfig := httpserver.DefaultServerConfig()
+	serverConfig.Addr = a.addr
+	serverConfig.SigningKey = a.config.Key
+	serverConfig.TLSCertFile = a.config.TLSCertFile
+	serverConfig.TLSKeyFile = a.config.TLSKeyFile
+	serverConfig.TLSClientCAFile = a.config.TLSClientCAFile
+	serverConfig.MaxBodySize = a.config.MaxBodySize
+	serverConfig.MaxCompressedBodySize = a.config.MaxCompressedBodySize
+	if serverConfig.TrustedSubnet, err = parseSubnet(a.config.TrustedSubnet); err != nil {
+		return fmt.Errorf("invalid trusted subnet: %w", err)
+	}
+	if serverConfig.TrustedReadSubnet, err = parseSubnet(a.config.TrustedReadSubnet); err != nil {
+		return fmt.Errorf("invalid trusted read subnet: %w", err)
+	}
+	if serverConfig.TrustedProxies, err = parseSubnets(a.config.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	statsdListener, err := a.createStatsDListener(service, serverConfig.TrustedSubnet, appLogger)
+	if err != nil {
+		return fmt.Errorf("failed to start statsd listener: %w", err)
+	}
+	if serverConfig.Decryptor, err = a.createDecryptor(appLogger); err != nil {
+		return fmt.Errorf("failed to load crypto key: %w", err)
+	}
+	if serverConfig.Auth, err = a.createAuthStore(appLogger); err != nil {
+		return fmt.Errorf("failed to load API tokens: %w", err)
+	}
+	if serverConfig.WriteLimiter, err = createRateLimiter(a.config.RateLimitWrite, a.config.RateLimitWriteBurst); err != nil {
+		return fmt.Errorf("invalid write rate limit: %w", err)
+	}
+	if serverConfig.ReadLimiter, err = createRateLimiter(a.config.RateLimitRead, a.config.RateLimitReadBurst); err != nil {
+		return fmt.Errorf("invalid read rate limit: %w", err)
+	}
+	server, err := httpserver.NewServerWithConfig(serverConfig, handler, appLogger)
 	if err != nil {
 		return fmt.Errorf("failed to create server: %w", err)
 	}
 	a.server = server
 
+	if a.grpcServer, err = a.createGRPCServer(serverConfig, idempotencyStore, service, appLogger); err != nil {
+		return fmt.Errorf("failed to create gRPC server: %w", err)
+	}
+
 	// Запускаем периодическое сохранение метрик, если интервал > 0
-	if a.config.StoreInterval > 0 {
-		go a.startPeriodicSaving(repository, appLogger)
+	if a.usesFileStorage() && a.config.StoreInterval > 0 {
+		go a.startPeriodicSaving(repository, time.Duration(a.config.StoreInterval)*time.Second, appLogger)
+	}
+
+	// В режиме WAL периодически выполняем контрольные точки
+	if a.usesWAL() && a.config.WALCheckpointInterval > 0 {
+		go a.startPeriodicSaving(repository, time.Duration(a.config.WALCheckpointInterval)*time.Second, appLogger)
 	}
 
 	// Создаем контекст для graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Вычисляем правила алертинга до завершения приложения
+	if alertEngine != nil {
+		go alertEngine.Run(ctx, a.alertInterval())
+	}
+
+	// Принимаем метрики StatsD до завершения приложения
+	if statsdListener != nil {
+		go func() {
+			if err := statsdListener.Serve(ctx); err != nil {
+				appLogger.Error("statsd listener error", "error", err)
+			}
+		}()
+	}
+
 	// Запускаем сервер в горутине
 	go func() {
 		if err := a.server.Start(); err != nil {
@@ -85,13 +232,246 @@ func (a *App) Run() error {
 		}
 	}()
 
+	// gRPC сервер работает рядом с HTTP сервером и использует тот же сервис метрик
+	if a.grpcServer != nil {
+		go func() {
+			if err := a.grpcServer.Start(); err != nil {
+				log.Printf("gRPC server error: %v", err)
+				cancel()
+			}
+		}()
+	}
+
 	// Ожидаем сигналы для graceful shutdown
 	return a.waitForShutdown(ctx, repository, appLogger)
 }
 
+// createRepository выбирает хранилище метрик: PostgreSQL при заданном DSN, иначе память + файл.
+// Возвращает функцию освобождения ресурсов хранилища.
+func (a *App) createRepository(appLogger logger.Logger) (repository.MetricsRepository, func(), error) {
+	if !a.usesFileStorage() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		pool, err := db.NewPool(ctx, db.NewConfig(a.config.DatabaseDSN))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := repository.ApplyMigrations(ctx, pool, migrations.FS, appLogger); err != nil {
+			pool.Close()
+			return nil, nil, err
+		}
+
+		appLogger.Info("using PostgreSQL metrics storage")
+		repo := repository.NewPostgresMetricsRepository(pool, appLogger)
+		return repo, repo.Close, nil
+	}
+
+	repo := repository.NewInMemoryMetricsRepository(appLogger, a.config.FileStoragePath, a.config.Restore)
+	if a.config.SnapshotKeep > 0 {
+		repo.SetSnapshotRetention(a.config.SnapshotKeep)
+	}
+
+	// Устанавливаем синхронное сохранение, если интервал = 0:
+	// либо через журнал упреждающей записи, либо перезаписью всего файла
+	if a.usesWAL() {
+		if err := repo.EnableWAL(); err != nil {
+			return nil, nil, err
+		}
+	} else if a.config.StoreInterval == 0 {
+		repo.SetSyncSave(true)
+	}
+
+	closeRepo := func() {
+		if err := repo.Close(); err != nil {
+			appLogger.Error("failed to close metrics repository", "error", err)
+		}
+	}
+
+	appLogger.Info("using in-memory metrics storage", "file", a.config.FileStoragePath, "wal", a.usesWAL())
+	return repo, closeRepo, nil
+}
+
+// withHistory оборачивает репозиторий хранилищем истории значений, если история включена
+func (a *App) withHistory(repo repository.MetricsRepository, appLogger logger.Logger) (repository.MetricsRepository, error) {
+	if a.config.HistoryRetention <= 0 {
+		return repo, nil
+	}
+
+	config := repository.DefaultHistoryConfig()
+	config.Retention = time.Duration(a.config.HistoryRetention) * time.Second
+	if a.config.HistorySize > 0 {
+		config.MaxSamples = a.config.HistorySize
+	}
+
+	history, err := repository.NewHistoryRepository(repo, config, appLogger)
+	if err != nil {
+		return nil, err
+	}
+
+	appLogger.Info("metrics history enabled", "retention", config.Retention, "max_samples", config.MaxSamples)
+	return history, nil
+}
+
+// createIdempotencyStore создает кэш ключей идемпотентности, общий для HTTP и gRPC API.
+// При отключенном кэше возвращает nil.
+func (a *App) createIdempotencyStore(appLogger logger.Logger) (*idempotency.Store, error) {
+	if a.config.IdempotencyTTL <= 0 {
+		return nil, nil
+	}
+
+	config := idempotency.DefaultConfig()
+	config.TTL = time.Duration(a.config.IdempotencyTTL) * time.Second
+	if a.config.IdempotencySize > 0 {
+		config.MaxEntries = a.config.IdempotencySize
+	}
+
+	store, err := idempotency.NewStore(config)
+	if err != nil {
+		return nil, err
+	}
+
+	appLogger.Info("idempotency keys enabled", "ttl", config.TTL, "max_entries", config.MaxEntries)
+	return store, nil
+}
+
+// createAlertEngine загружает правила алертинга и создает движок с уведомлениями через webhook.
+// Без файла правил возвращает nil - алертинг отключен.
+func (a *App) createAlertEngine(source alerting.MetricsSource, appLogger logger.Logger) (*alerting.Engine, error) {
+	if a.config.AlertRulesFile == "" {
+		return nil, nil
+	}
+
+	file, err := alerting.LoadFile(a.config.AlertRulesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := append(append([]string(nil), file.Webhooks...), a.config.AlertWebhooks...)
+	var notifier alerting.Notifier
+	if len(webhooks) > 0 {
+		if notifier, err = alerting.NewWebhookNotifier(webhooks, nil, appLogger); err != nil {
+			return nil, err
+		}
+	}
+
+	engine, err := alerting.NewEngine(file.Rules, source, notifier, appLogger)
+	if err != nil {
+		return nil, err
+	}
+
+	appLogger.Info("alerting enabled",
+		"rules", len(file.Rules),
+		"webhooks", len(webhooks),
+		"interval", a.alertInterval())
+	return engine, nil
+}
+
+// alertInterval возвращает интервал вычисления правил алертинга
+func (a *App) alertInterval() time.Duration {
+	if a.config.AlertInterval <= 0 {
+		return alerting.DefaultInterval
+	}
+	return time.Duration(a.config.AlertInterval) * time.Second
+}
+
+// createStatsDListener открывает UDP сокет приема метрик StatsD, если адрес задан.
+// StatsD не поддерживает подпись и токены, поэтому при их настройке слушатель запускается
+// только с явным StatsDAllowUnsigned; пакеты принимаются только из доверенной подсети записи.
+func (a *App) createStatsDListener(service statsd.MetricsWriter, trustedSubnet *net.IPNet, appLogger logger.Logger) (*statsd.Listener, error) {
+	if a.config.StatsDAddr == "" {
+		return nil, nil
+	}
+
+	authenticated := a.config.Key != "" || a.config.AuthTokensFile != "" || len(a.config.AuthTokens) > 0
+	if authenticated && !a.config.StatsDAllowUnsigned {
+		return nil, fmt.Errorf("statsd ingestion bypasses signature and API token checks: enable it explicitly with StatsDAllowUnsigned")
+	}
+
+	listener, err := statsd.Listen(a.config.StatsDAddr, service, appLogger)
+	if err != nil {
+		return nil, err
+	}
+	listener.SetTrustedSubnet(trustedSubnet)
+	if authenticated {
+		appLogger.Warn("statsd ingestion accepts unsigned metrics without API tokens", "addr", listener.Addr().String())
+	}
+
+	appLogger.Info("statsd ingestion enabled", "addr", listener.Addr().String())
+	return listener, nil
+}
+
+// createGRPCServer создает gRPC сервер с проверками HTTP сервера, если адрес задан.
+// Сокет открывается сразу, чтобы занятый порт обнаруживался до запуска.
+func (a *App) createGRPCServer(httpConfig *httpserver.ServerConfig, idempotencyStore *idempotency.Store,
+	service grpcserver.MetricsService, appLogger logger.Logger) (*grpcserver.Server, error) {
+	if a.config.GRPCAddr == "" {
+		return nil, nil
+	}
+
+	config := grpcserver.DefaultServerConfig()
+	config.Addr = a.config.GRPCAddr
+	config.SigningKey = httpConfig.SigningKey
+	config.TLSCertFile = httpConfig.TLSCertFile
+	config.TLSKeyFile = httpConfig.TLSKeyFile
+	config.TLSClientCAFile = httpConfig.TLSClientCAFile
+	config.TrustedSubnet = httpConfig.TrustedSubnet
+	config.TrustedReadSubnet = httpConfig.TrustedReadSubnet
+	config.TrustedProxies = httpConfig.TrustedProxies
+	config.Auth = httpConfig.Auth
+	config.WriteLimiter = httpConfig.WriteLimiter
+	config.Idempotency = idempotencyStore
+	config.ReadLimiter = httpConfig.ReadLimiter
+	if httpConfig.MaxBodySize > 0 {
+		config.MaxMessageSize = int(httpConfig.MaxBodySize)
+	}
+
+	server, err := grpcserver.NewServerWithConfig(config, service, appLogger)
+	if err != nil {
+		return nil, err
+	}
+	if err := server.Listen(); err != nil {
+		return nil, err
+	}
+
+	appLogger.Info("gRPC API enabled", "addr", server.Addr().String())
+	return server, nil
+}
+
+// createDecryptor загружает приватный ключ для расшифровки запросов, если он задан
+func (a *App) createDecryptor(appLogger logger.Logger) (*encryption.Decryptor, error) {
+	if a.config.CryptoKey == "" {
+		return nil, nil
+	}
+
+	decryptor, err := encryption.NewDecryptorFromFile(a.config.CryptoKey)
+	if err != nil {
+		return nil, err
+	}
+
+	appLogger.Info("request decryption enabled", "key", a.config.CryptoKey)
+	return decryptor, nil
+}
+
+// usesFileStorage сообщает, хранятся ли метрики в памяти с сохранением в файл
+func (a *App) usesFileStorage() bool {
+	return a.config.DatabaseDSN == ""
+}
+
+// usesWAL сообщает, используется ли журнал упреждающей записи (только для синхронного режима)
+func (a *App) usesWAL() bool {
+	return a.usesFileStorage() && a.config.StoreInterval == 0 && a.config.WALEnabled
+}
+
+// savesOnShutdown сообщает, нужно ли сохранить снапшот при завершении
+func (a *App) savesOnShutdown() bool {
+	return a.usesFileStorage() && (a.config.StoreInterval > 0 || a.usesWAL())
+}
+
 // startPeriodicSaving запускает периодическое сохранение метрик
-func (a *App) startPeriodicSaving(repo repository.MetricsRepository, logger logger.Logger) {
-	ticker := time.NewTicker(time.Duration(a.config.StoreInterval) * time.Second)
+func (a *App) startPeriodicSaving(repo repository.MetricsRepository, interval time.Duration, logger logger.Logger) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for range ticker.C {
@@ -114,7 +494,7 @@ func (a *App) waitForShutdown(ctx context.Context, repo repository.MetricsReposi
 	case sig := <-sigChan:
 		log.Printf("Received signal %v, shutting down gracefully...", sig)
 		// Останавливаем периодическое сохранение перед завершением
-		if a.config.StoreInterval > 0 {
+		if a.savesOnShutdown() {
 			if err := repo.SaveToFile(); err != nil {
 				logger.Error("failed to save metrics to file on shutdown", "error", err)
 			} else {
@@ -124,7 +504,7 @@ func (a *App) waitForShutdown(ctx context.Context, repo repository.MetricsReposi
 	case <-ctx.Done():
 		log.Println("Server stopped, shutting down...")
 		// Останавливаем периодическое сохранение перед завершением
-		if a.config.StoreInterval > 0 {
+		if a.savesOnShutdown() {
 			if err := repo.SaveToFile(); err != nil {
 				logger.Error("failed to save metrics to file on graceful shutdown", "error", err)
 			} else {
@@ -137,7 +517,12 @@ func (a *App) waitForShutdown(ctx context.Context, repo repository.MetricsReposi
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Gracefully останавливаем сервер
+	// Gracefully останавливаем серверы
+	if a.grpcServer != nil {
+		if err := a.grpcServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error during gRPC server shutdown: %v", err)
+		}
+	}
 	if err := a.server.Shutdown(shutdownCtx); err != nil {
 		log.Printf("Error during shutdown: %v", err)
 		return err
@@ -152,7 +537,81 @@ func (a *App) GetServer() *httpserver.Server {
 	return a.server
 }
 
+// GetGRPCServer возвращает gRPC сервер или nil, если gRPC API отключен (для тестирования)
+func (a *App) GetGRPCServer() *grpcserver.Server {
+	return a.grpcServer
+}
+
 // GetPort возвращает адрес приложения
 func (a *App) GetPort() string {
 	return a.addr
 }
+
+// parseSubnet разбирает CIDR доверенной подсети (пустая строка - без ограничений)
+func parseSubnet(cidr string) (*net.IPNet, error) {
+	if cidr == "" {
+		return nil, nil
+	}
+
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CIDR %q: %w", cidr, err)
+	}
+	return subnet, nil
+}
+
+// parseSubnets разбирает список CIDR подсетей
+func parseSubnets(cidrs []string) ([]*net.IPNet, error) {
+	subnets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		subnet, err := parseSubnet(cidr)
+		if err != nil {
+			return nil, err
+		}
+		if subnet != nil {
+			subnets = append(subnets, subnet)
+		}
+	}
+	return subnets, nil
+}
+
+// createRateLimiter создает ограничитель частоты запросов для каждого клиента.
+// Нулевой лимит отключает ограничение (nil), нулевой всплеск равен лимиту.
+func createRateLimiter(rate, burst int) (*ratelimit.Limiter, error) {
+	if rate == 0 {
+		return nil, nil
+	}
+	if burst == 0 {
+		burst = rate
+	}
+
+	config := ratelimit.DefaultConfig()
+	config.Rate = float64(rate)
+	config.Burst = burst
+	return ratelimit.NewLimiter(config)
+}
+
+// createAuthStore создает хранилище API токенов из файла и конфигурации.
+// Без токенов возвращает nil - аутентификация отключена.
+func (a *App) createAuthStore(appLogger logger.Logger) (*auth.Store, error) {
+	tokens := append([]auth.Token(nil), a.config.AuthTokens...)
+	if a.config.AuthTokensFile != "" {
+		fileTokens, err := auth.LoadTokensFile(a.config.AuthTokensFile)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, fileTokens...)
+	}
+
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	store, err := auth.NewStore(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	appLogger.Info("API token authentication enabled", "tokens", store.Len())
+	return store, nil
+}
@@ -1,8 +1,20 @@
 package app
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/IgorKilipenko/metrical/internal/alerting"
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/handler"
+	"github.com/IgorKilipenko/metrical/internal/httpserver"
+	"github.com/IgorKilipenko/metrical/internal/repository"
+	"github.com/IgorKilipenko/metrical/internal/service"
 	"github.com/IgorKilipenko/metrical/internal/testutils"
 )
 
@@ -164,3 +176,399 @@ func TestApp_GetPort(t *testing.T) {
 		t.Errorf("GetPort() = %s, want localhost:8080", addr)
 	}
 }
+
+func TestApp_StorageMode(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          Config
+		usesFileStorage bool
+		usesWAL         bool
+		savesOnShutdown bool
+	}{
+		{
+			name:            "Periodic file storage",
+			config:          Config{StoreInterval: 300},
+			usesFileStorage: true,
+			savesOnShutdown: true,
+		},
+		{
+			name:            "Synchronous file storage",
+			config:          Config{StoreInterval: 0},
+			usesFileStorage: true,
+		},
+		{
+			name:            "Synchronous storage with WAL",
+			config:          Config{StoreInterval: 0, WALEnabled: true},
+			usesFileStorage: true,
+			usesWAL:         true,
+			savesOnShutdown: true,
+		},
+		{
+			name:            "WAL ignored for periodic storage",
+			config:          Config{StoreInterval: 300, WALEnabled: true},
+			usesFileStorage: true,
+			savesOnShutdown: true,
+		},
+		{
+			name:   "PostgreSQL storage",
+			config: Config{StoreInterval: 0, WALEnabled: true, DatabaseDSN: "postgres://localhost/db"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New(tt.config)
+
+			if got := app.usesFileStorage(); got != tt.usesFileStorage {
+				t.Errorf("usesFileStorage() = %v, want %v", got, tt.usesFileStorage)
+			}
+			if got := app.usesWAL(); got != tt.usesWAL {
+				t.Errorf("usesWAL() = %v, want %v", got, tt.usesWAL)
+			}
+			if got := app.savesOnShutdown(); got != tt.savesOnShutdown {
+				t.Errorf("savesOnShutdown() = %v, want %v", got, tt.savesOnShutdown)
+			}
+		})
+	}
+}
+
+func TestApp_WithHistory(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repo := repository.NewInMemoryMetricsRepository(mockLogger, "", false)
+
+	t.Run("History disabled", func(t *testing.T) {
+		app := New(Config{HistoryRetention: 0})
+
+		got, err := app.withHistory(repo, mockLogger)
+		if err != nil {
+			t.Fatalf("withHistory() error = %v", err)
+		}
+		if _, ok := got.(repository.MetricsHistory); ok {
+			t.Error("withHistory() should return repository without history")
+		}
+	})
+
+	t.Run("History enabled", func(t *testing.T) {
+		app := New(Config{HistoryRetention: 60, HistorySize: 10})
+
+		got, err := app.withHistory(repo, mockLogger)
+		if err != nil {
+			t.Fatalf("withHistory() error = %v", err)
+		}
+		if _, ok := got.(repository.MetricsHistory); !ok {
+			t.Error("withHistory() should return repository with history")
+		}
+	})
+}
+
+func TestApp_CreateIdempotencyStore(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+
+	// sendTwice отправляет одно и то же приращение дважды с одним ключом и возвращает итоговое значение
+	sendTwice := func(t *testing.T, app *App) int64 {
+		repo := repository.NewInMemoryMetricsRepository(mockLogger, "", false)
+		svc := service.NewMetricsService(repo, mockLogger)
+		h, err := handler.NewMetricsHandler(svc, mockLogger)
+		if err != nil {
+			t.Fatalf("NewMetricsHandler() error = %v", err)
+		}
+		store, err := app.createIdempotencyStore(mockLogger)
+		if err != nil {
+			t.Fatalf("createIdempotencyStore() error = %v", err)
+		}
+		h.SetIdempotencyStore(store)
+
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPost, "/update", strings.NewReader(`{"id": "PollCount", "type": "counter", "delta": 1}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(handler.IdempotencyKeyHeader, "key")
+			h.UpdateMetricJSON(httptest.NewRecorder(), req)
+		}
+
+		value, _, err := svc.GetCounter(context.Background(), "PollCount")
+		if err != nil {
+			t.Fatalf("GetCounter() error = %v", err)
+		}
+		return value
+	}
+
+	t.Run("Idempotency disabled", func(t *testing.T) {
+		if got := sendTwice(t, New(Config{IdempotencyTTL: 0})); got != 2 {
+			t.Errorf("counter = %d, want 2", got)
+		}
+	})
+
+	t.Run("Idempotency enabled", func(t *testing.T) {
+		if got := sendTwice(t, New(Config{IdempotencyTTL: 60, IdempotencySize: 10})); got != 1 {
+			t.Errorf("counter = %d, want 1", got)
+		}
+	})
+}
+
+func TestApp_CreateDecryptor(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+
+	t.Run("Encryption disabled", func(t *testing.T) {
+		decryptor, err := New(Config{}).createDecryptor(mockLogger)
+		if err != nil {
+			t.Fatalf("createDecryptor() error = %v", err)
+		}
+		if decryptor != nil {
+			t.Error("createDecryptor() should return nil without crypto key")
+		}
+	})
+
+	t.Run("Valid private key", func(t *testing.T) {
+		privateKeyPath, _ := testutils.WriteTestRSAKeys(t)
+
+		decryptor, err := New(Config{CryptoKey: privateKeyPath}).createDecryptor(mockLogger)
+		if err != nil {
+			t.Fatalf("createDecryptor() error = %v", err)
+		}
+		if decryptor == nil {
+			t.Error("createDecryptor() should return decryptor")
+		}
+	})
+
+	t.Run("Public key instead of private", func(t *testing.T) {
+		_, publicKeyPath := testutils.WriteTestRSAKeys(t)
+
+		if _, err := New(Config{CryptoKey: publicKeyPath}).createDecryptor(mockLogger); err == nil {
+			t.Error("createDecryptor() should fail for public key")
+		}
+	})
+}
+
+func TestParseSubnet(t *testing.T) {
+	subnet, err := parseSubnet("")
+	if err != nil || subnet != nil {
+		t.Errorf("parseSubnet(\"\") = %v, %v; want nil, nil", subnet, err)
+	}
+
+	subnet, err = parseSubnet("192.168.1.0/24")
+	if err != nil {
+		t.Fatalf("parseSubnet() error = %v", err)
+	}
+	if subnet.String() != "192.168.1.0/24" {
+		t.Errorf("parseSubnet() = %s, want 192.168.1.0/24", subnet)
+	}
+
+	if _, err := parseSubnet("192.168.1.1"); err == nil {
+		t.Error("parseSubnet() should fail for address without prefix length")
+	}
+}
+
+func TestCreateRateLimiter(t *testing.T) {
+	limiter, err := createRateLimiter(0, 0)
+	if err != nil || limiter != nil {
+		t.Errorf("createRateLimiter(0, 0) = %v, %v; want nil, nil", limiter, err)
+	}
+
+	// Нулевой всплеск равен лимиту
+	limiter, err = createRateLimiter(2, 0)
+	if err != nil {
+		t.Fatalf("createRateLimiter() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if allowed, _ := limiter.Allow("client"); !allowed {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+	if allowed, _ := limiter.Allow("client"); allowed {
+		t.Error("request over burst should be limited")
+	}
+
+	if _, err := createRateLimiter(-1, 0); err == nil {
+		t.Error("createRateLimiter() should fail for negative rate")
+	}
+}
+
+func TestApp_CreateAuthStore(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+
+	t.Run("Authentication disabled", func(t *testing.T) {
+		store, err := New(Config{}).createAuthStore(mockLogger)
+		if err != nil {
+			t.Fatalf("createAuthStore() error = %v", err)
+		}
+		if store != nil {
+			t.Error("createAuthStore() should return nil without tokens")
+		}
+	})
+
+	t.Run("Tokens from file and config", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tokens.json")
+		if err := os.WriteFile(path, []byte(`[{"name": "agent", "token": "file-token", "scope": "write"}]`), 0600); err != nil {
+			t.Fatalf("failed to write tokens file: %v", err)
+		}
+
+		store, err := New(Config{
+			AuthTokensFile: path,
+			AuthTokens:     []auth.Token{{Name: "admin", Token: "config-token", Scope: auth.ScopeAdmin}},
+		}).createAuthStore(mockLogger)
+		if err != nil {
+			t.Fatalf("createAuthStore() error = %v", err)
+		}
+		if store.Len() != 2 {
+			t.Errorf("createAuthStore() tokens = %d, want 2", store.Len())
+		}
+		if _, ok := store.Authenticate("file-token"); !ok {
+			t.Error("token from file should be accepted")
+		}
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		if _, err := New(Config{AuthTokensFile: "/nonexistent/tokens.json"}).createAuthStore(mockLogger); err == nil {
+			t.Error("createAuthStore() should fail for missing file")
+		}
+	})
+}
+
+func TestApp_CreateAlertEngine(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	metricsService := service.NewMetricsService(repository.NewInMemoryMetricsRepository(mockLogger, "", false), mockLogger)
+
+	t.Run("Alerting disabled", func(t *testing.T) {
+		engine, err := New(Config{}).createAlertEngine(metricsService, mockLogger)
+		if err != nil {
+			t.Fatalf("createAlertEngine() error = %v", err)
+		}
+		if engine != nil {
+			t.Error("createAlertEngine() should return nil without rules file")
+		}
+	})
+
+	t.Run("Rules file with config webhooks", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.yaml")
+		rules := "rules:\n  - name: HighHeap\n    expr: gauge HeapAlloc > 500MB for 2m\n"
+		if err := os.WriteFile(path, []byte(rules), 0600); err != nil {
+			t.Fatalf("failed to write rules file: %v", err)
+		}
+
+		app := New(Config{AlertRulesFile: path, AlertWebhooks: []string{"http://localhost:9093/hook"}})
+		engine, err := app.createAlertEngine(metricsService, mockLogger)
+		if err != nil {
+			t.Fatalf("createAlertEngine() error = %v", err)
+		}
+		if alerts := engine.Alerts(); len(alerts) != 1 || alerts[0].Rule.Name != "HighHeap" {
+			t.Errorf("createAlertEngine() alerts = %+v, want HighHeap rule", alerts)
+		}
+		if app.alertInterval() != alerting.DefaultInterval {
+			t.Errorf("alertInterval() = %v, want %v", app.alertInterval(), alerting.DefaultInterval)
+		}
+	})
+
+	t.Run("Invalid webhook", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.yaml")
+		if err := os.WriteFile(path, []byte("rules: []\n"), 0600); err != nil {
+			t.Fatalf("failed to write rules file: %v", err)
+		}
+
+		if _, err := New(Config{AlertRulesFile: path, AlertWebhooks: []string{"localhost"}}).createAlertEngine(metricsService, mockLogger); err == nil {
+			t.Error("createAlertEngine() should fail for invalid webhook URL")
+		}
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		if _, err := New(Config{AlertRulesFile: "/nonexistent/rules.yaml"}).createAlertEngine(metricsService, mockLogger); err == nil {
+			t.Error("createAlertEngine() should fail for missing file")
+		}
+	})
+}
+
+func TestApp_CreateStatsDListener(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	metricsService := service.NewMetricsService(repository.NewInMemoryMetricsRepository(mockLogger, "", false), mockLogger)
+
+	t.Run("StatsD disabled", func(t *testing.T) {
+		listener, err := New(Config{}).createStatsDListener(metricsService, nil, mockLogger)
+		if err != nil {
+			t.Fatalf("createStatsDListener() error = %v", err)
+		}
+		if listener != nil {
+			t.Error("createStatsDListener() should return nil without address")
+		}
+	})
+
+	t.Run("Listener serves packets", func(t *testing.T) {
+		listener, err := New(Config{StatsDAddr: "127.0.0.1:0"}).createStatsDListener(metricsService, nil, mockLogger)
+		if err != nil {
+			t.Fatalf("createStatsDListener() error = %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go listener.Serve(ctx)
+
+		if err := listener.HandlePacket(ctx, []byte("legacy.hits:3|c")); err != nil {
+			t.Fatalf("HandlePacket() error = %v", err)
+		}
+		if hits, _, _ := metricsService.GetCounter(ctx, "legacy.hits"); hits != 3 {
+			t.Errorf("legacy.hits = %d, want 3", hits)
+		}
+	})
+
+	t.Run("Signing or tokens without opt-in", func(t *testing.T) {
+		for name, config := range map[string]Config{
+			"signing key": {StatsDAddr: "127.0.0.1:0", Key: "secret"},
+			"tokens file": {StatsDAddr: "127.0.0.1:0", AuthTokensFile: "/etc/metrical/tokens.json"},
+			"tokens":      {StatsDAddr: "127.0.0.1:0", AuthTokens: []auth.Token{{Name: "agent", Token: "t", Scope: auth.ScopeWrite}}},
+		} {
+			if _, err := New(config).createStatsDListener(metricsService, nil, mockLogger); err == nil {
+				t.Errorf("%s: createStatsDListener() should require StatsDAllowUnsigned", name)
+			}
+		}
+	})
+
+	t.Run("Signing with opt-in", func(t *testing.T) {
+		config := Config{StatsDAddr: "127.0.0.1:0", Key: "secret", StatsDAllowUnsigned: true}
+		listener, err := New(config).createStatsDListener(metricsService, nil, mockLogger)
+		if err != nil {
+			t.Fatalf("createStatsDListener() error = %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		listener.Serve(ctx)
+	})
+
+	t.Run("Invalid address", func(t *testing.T) {
+		if _, err := New(Config{StatsDAddr: "invalid-address"}).createStatsDListener(metricsService, nil, mockLogger); err == nil {
+			t.Error("createStatsDListener() should fail for invalid address")
+		}
+	})
+}
+
+func TestApp_CreateGRPCServer(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	metricsService := service.NewMetricsService(repository.NewInMemoryMetricsRepository(mockLogger, "", false), mockLogger)
+	httpConfig := httpserver.DefaultServerConfig()
+
+	t.Run("gRPC disabled", func(t *testing.T) {
+		server, err := New(Config{}).createGRPCServer(httpConfig, nil, metricsService, mockLogger)
+		if err != nil {
+			t.Fatalf("createGRPCServer() error = %v", err)
+		}
+		if server != nil {
+			t.Error("createGRPCServer() should return nil without address")
+		}
+	})
+
+	t.Run("Server listens on address", func(t *testing.T) {
+		server, err := New(Config{GRPCAddr: "127.0.0.1:0"}).createGRPCServer(httpConfig, nil, metricsService, mockLogger)
+		if err != nil {
+			t.Fatalf("createGRPCServer() error = %v", err)
+		}
+		if server.Addr() == nil {
+			t.Fatal("createGRPCServer() should open listener")
+		}
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Errorf("Shutdown() error = %v", err)
+		}
+	})
+
+	t.Run("Invalid address", func(t *testing.T) {
+		if _, err := New(Config{GRPCAddr: "invalid-address"}).createGRPCServer(httpConfig, nil, metricsService, mockLogger); err == nil {
+			t.Error("createGRPCServer() should fail for invalid address")
+		}
+	})
+}
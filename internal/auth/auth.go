@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Scope область доступа токена
+type Scope string
+
+const (
+	// ScopeRead чтение метрик (/value, /, история)
+	ScopeRead Scope = "read"
+	// ScopeWrite запись метрик (/update*)
+	ScopeWrite Scope = "write"
+	// ScopeAdmin полный доступ, включает чтение и запись
+	ScopeAdmin Scope = "admin"
+)
+
+// Valid проверяет, что область доступа известна
+func (s Scope) Valid() bool {
+	return s == ScopeRead || s == ScopeWrite || s == ScopeAdmin
+}
+
+// Token описание API токена
+type Token struct {
+	Name   string `json:"name"`             // Имя владельца токена для логов
+	Token  string `json:"token"`            // Секрет, передаваемый в заголовке Authorization
+	Scope  Scope  `json:"scope"`            // Область доступа
+	Prefix string `json:"prefix,omitempty"` // Разрешенный префикс имен метрик (пустая строка - любые метрики)
+}
+
+// Validate проверяет корректность описания токена
+func (t Token) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("token name cannot be empty")
+	}
+	if t.Token == "" {
+		return fmt.Errorf("token %q has empty secret", t.Name)
+	}
+	if !t.Scope.Valid() {
+		return fmt.Errorf("token %q has invalid scope %q", t.Name, t.Scope)
+	}
+	return nil
+}
+
+// Principal аутентифицированный владелец токена
+type Principal struct {
+	Name   string
+	Scope  Scope
+	Prefix string
+}
+
+// HasScope проверяет, разрешена ли владельцу токена область доступа
+func (p *Principal) HasScope(required Scope) bool {
+	return p.Scope == ScopeAdmin || p.Scope == required
+}
+
+// AllowsMetric проверяет, разрешен ли владельцу токена доступ к метрике с указанным именем
+func (p *Principal) AllowsMetric(name string) bool {
+	return strings.HasPrefix(name, p.Prefix)
+}
+
+// Store хранилище токенов.
+// Токены хранятся по SHA-256, чтобы время поиска не зависело от совпадения префикса секрета.
+type Store struct {
+	principals map[[sha256.Size]byte]*Principal
+}
+
+// NewStore создает хранилище из описаний токенов
+func NewStore(tokens []Token) (*Store, error) {
+	store := &Store{principals: make(map[[sha256.Size]byte]*Principal, len(tokens))}
+
+	for _, token := range tokens {
+		if err := token.Validate(); err != nil {
+			return nil, err
+		}
+
+		digest := sha256.Sum256([]byte(token.Token))
+		if _, exists := store.principals[digest]; exists {
+			return nil, fmt.Errorf("duplicate secret for token %q", token.Name)
+		}
+		store.principals[digest] = &Principal{Name: token.Name, Scope: token.Scope, Prefix: token.Prefix}
+	}
+
+	return store, nil
+}
+
+// LoadTokensFile читает описания токенов из JSON файла (массив объектов Token)
+func LoadTokensFile(path string) ([]Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokens file: %w", err)
+	}
+
+	var tokens []Token
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse tokens file: %w", err)
+	}
+	return tokens, nil
+}
+
+// Authenticate возвращает владельца токена
+func (s *Store) Authenticate(token string) (*Principal, bool) {
+	principal, ok := s.principals[sha256.Sum256([]byte(token))]
+	return principal, ok
+}
+
+// Len возвращает количество токенов
+func (s *Store) Len() int {
+	return len(s.principals)
+}
+
+// ParseBearer извлекает токен из значения заголовка Authorization ("Bearer <token>").
+// Схема "Token <token>" клиентов InfluxDB (Telegraf) принимается как синоним.
+func ParseBearer(header string) (string, bool) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found || (!strings.EqualFold(scheme, "Bearer") && !strings.EqualFold(scheme, "Token")) {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// principalKey ключ контекста для владельца токена
+type principalKey struct{}
+
+// WithPrincipal возвращает контекст с аутентифицированным владельцем токена
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext возвращает владельца токена из контекста запроса
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
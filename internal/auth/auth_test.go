@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Authenticate(t *testing.T) {
+	store, err := NewStore([]Token{
+		{Name: "agent", Token: "agent-secret", Scope: ScopeWrite, Prefix: "host1."},
+		{Name: "dashboard", Token: "dashboard-secret", Scope: ScopeRead},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, store.Len())
+
+	principal, ok := store.Authenticate("agent-secret")
+	require.True(t, ok)
+	assert.Equal(t, "agent", principal.Name)
+	assert.Equal(t, ScopeWrite, principal.Scope)
+	assert.Equal(t, "host1.", principal.Prefix)
+
+	_, ok = store.Authenticate("agent-secre")
+	assert.False(t, ok)
+	_, ok = store.Authenticate("")
+	assert.False(t, ok)
+}
+
+func TestNewStore_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []Token
+	}{
+		{name: "empty name", tokens: []Token{{Token: "secret", Scope: ScopeRead}}},
+		{name: "empty secret", tokens: []Token{{Name: "agent", Scope: ScopeRead}}},
+		{name: "invalid scope", tokens: []Token{{Name: "agent", Token: "secret", Scope: "owner"}}},
+		{name: "duplicate secret", tokens: []Token{
+			{Name: "a", Token: "secret", Scope: ScopeRead},
+			{Name: "b", Token: "secret", Scope: ScopeWrite},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewStore(tt.tokens)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPrincipal_Access(t *testing.T) {
+	writer := &Principal{Name: "agent", Scope: ScopeWrite, Prefix: "host1."}
+	assert.True(t, writer.HasScope(ScopeWrite))
+	assert.False(t, writer.HasScope(ScopeRead))
+	assert.False(t, writer.HasScope(ScopeAdmin))
+	assert.True(t, writer.AllowsMetric("host1.cpu"))
+	assert.False(t, writer.AllowsMetric("host2.cpu"))
+
+	admin := &Principal{Name: "root", Scope: ScopeAdmin}
+	assert.True(t, admin.HasScope(ScopeRead))
+	assert.True(t, admin.HasScope(ScopeWrite))
+	assert.True(t, admin.AllowsMetric("anything"))
+}
+
+func TestLoadTokensFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"name": "agent", "token": "agent-secret", "scope": "write", "prefix": "host1."},
+		{"name": "admin", "token": "admin-secret", "scope": "admin"}
+	]`), 0600))
+
+	tokens, err := LoadTokensFile(path)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, Token{Name: "agent", Token: "agent-secret", Scope: ScopeWrite, Prefix: "host1."}, tokens[0])
+
+	_, err = LoadTokensFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"tokens": []}`), 0600))
+	_, err = LoadTokensFile(path)
+	assert.Error(t, err)
+}
+
+func TestParseBearer(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+		ok       bool
+	}{
+		{header: "Bearer secret", expected: "secret", ok: true},
+		{header: "bearer  secret ", expected: "secret", ok: true},
+		{header: "Token secret", expected: "secret", ok: true},
+		{header: "Basic dXNlcjpwYXNz", ok: false},
+		{header: "Bearer", ok: false},
+		{header: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			token, ok := ParseBearer(tt.header)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, token)
+		})
+	}
+}
+
+func TestPrincipalContext(t *testing.T) {
+	_, ok := PrincipalFromContext(context.Background())
+	assert.False(t, ok)
+
+	principal := &Principal{Name: "agent", Scope: ScopeWrite}
+	got, ok := PrincipalFromContext(WithPrincipal(context.Background(), principal))
+	require.True(t, ok)
+	assert.Same(t, principal, got)
+}
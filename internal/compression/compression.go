@@ -0,0 +1,230 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Поддерживаемые кодировки содержимого (Content-Encoding)
+const (
+	Gzip     = "gzip"
+	Deflate  = "deflate" // Формат zlib (RFC 1950), как требует HTTP
+	Zstd     = "zstd"
+	Identity = "identity"
+)
+
+// maxZstdWindow ограничивает окно zstd, а с ним и память декодера на один запрос
+const maxZstdWindow = 8 << 20
+
+// Encodings возвращает поддерживаемые кодировки в порядке предпочтения сервера
+func Encodings() []string {
+	return []string{Zstd, Gzip, Deflate}
+}
+
+// Supported проверяет, поддерживается ли кодировка
+func Supported(encoding string) bool {
+	_, ok := codecs[Normalize(encoding)]
+	return ok
+}
+
+// Normalize приводит имя кодировки к каноническому виду (x-gzip - устаревший синоним gzip)
+func Normalize(encoding string) string {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	if encoding == "x-gzip" {
+		return Gzip
+	}
+	return encoding
+}
+
+// encoder сжимающий writer, который можно переиспользовать после Close
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// decoder распаковывающий reader, который можно переиспользовать для нового потока
+type decoder interface {
+	io.Reader
+	Reset(r io.Reader) error
+}
+
+// codec реализация кодировки с пулами reader и writer: их создание дорого по памяти
+type codec struct {
+	readers   sync.Pool
+	writers   sync.Pool
+	newReader func(r io.Reader) (decoder, error)
+	newWriter func(w io.Writer) (encoder, error)
+}
+
+var codecs = map[string]*codec{
+	Gzip: {
+		newReader: func(r io.Reader) (decoder, error) { return gzip.NewReader(r) },
+		newWriter: func(w io.Writer) (encoder, error) { return gzip.NewWriter(w), nil },
+	},
+	Deflate: {
+		newReader: func(r io.Reader) (decoder, error) {
+			reader, err := zlib.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return &zlibDecoder{ReadCloser: reader}, nil
+		},
+		newWriter: func(w io.Writer) (encoder, error) { return zlib.NewWriter(w), nil },
+	},
+	Zstd: {
+		newReader: func(r io.Reader) (decoder, error) {
+			return zstd.NewReader(r,
+				zstd.WithDecoderConcurrency(1),
+				zstd.WithDecoderLowmem(true),
+				zstd.WithDecoderMaxWindow(maxZstdWindow))
+		},
+		newWriter: func(w io.Writer) (encoder, error) {
+			return zstd.NewWriter(w,
+				zstd.WithEncoderConcurrency(1),
+				zstd.WithWindowSize(1<<20))
+		},
+	},
+}
+
+// zlibDecoder приводит zlib reader к интерфейсу decoder
+type zlibDecoder struct {
+	io.ReadCloser
+}
+
+// Reset начинает чтение нового zlib потока
+func (d *zlibDecoder) Reset(r io.Reader) error {
+	return d.ReadCloser.(zlib.Resetter).Reset(r, nil)
+}
+
+// NewReader возвращает распаковывающий reader для кодировки.
+// Close возвращает reader в пул и не закрывает r.
+func NewReader(encoding string, r io.Reader) (io.ReadCloser, error) {
+	c, ok := codecs[Normalize(encoding)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	if pooled, ok := c.readers.Get().(decoder); ok {
+		if err := pooled.Reset(r); err != nil {
+			c.readers.Put(pooled)
+			return nil, err
+		}
+		return &pooledReader{decoder: pooled, pool: &c.readers}, nil
+	}
+
+	reader, err := c.newReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledReader{decoder: reader, pool: &c.readers}, nil
+}
+
+// NewWriter возвращает сжимающий writer для кодировки.
+// Close дописывает конец потока, возвращает writer в пул и не закрывает w.
+func NewWriter(encoding string, w io.Writer) (io.WriteCloser, error) {
+	c, ok := codecs[Normalize(encoding)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	if pooled, ok := c.writers.Get().(encoder); ok {
+		pooled.Reset(w)
+		return &pooledWriter{encoder: pooled, pool: &c.writers}, nil
+	}
+
+	writer, err := c.newWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledWriter{encoder: writer, pool: &c.writers}, nil
+}
+
+// Compress сжимает данные целиком
+func Compress(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(encoding, &buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := writer.Write(data); err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("failed to write %s data: %w", encoding, err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close %s writer: %w", encoding, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress распаковывает данные целиком
+func Decompress(encoding string, data []byte) ([]byte, error) {
+	reader, err := NewReader(encoding, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// pooledReader возвращает decoder в пул при закрытии
+type pooledReader struct {
+	decoder
+	pool   *sync.Pool
+	closed bool
+}
+
+// Read читает распакованные данные
+func (r *pooledReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, fmt.Errorf("read on closed decompressor")
+	}
+	return r.decoder.Read(p)
+}
+
+// Close возвращает decoder в пул (повторный вызов ничего не делает)
+func (r *pooledReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	r.pool.Put(r.decoder)
+	return nil
+}
+
+// pooledWriter возвращает encoder в пул при закрытии
+type pooledWriter struct {
+	encoder
+	pool   *sync.Pool
+	closed bool
+}
+
+// Write сжимает данные
+func (w *pooledWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fmt.Errorf("write on closed compressor")
+	}
+	return w.encoder.Write(p)
+}
+
+// Close завершает поток и возвращает encoder в пул (повторный вызов ничего не делает)
+func (w *pooledWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	err := w.encoder.Close()
+	// Сбрасываем ссылку на выходной writer, чтобы пул не удерживал ответ
+	w.encoder.Reset(io.Discard)
+	w.pool.Put(w.encoder)
+	return err
+}
@@ -0,0 +1,110 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressDecompress(t *testing.T) {
+	data := []byte(strings.Repeat(`{"id":"Alloc","type":"gauge","value":1.5},`, 50))
+
+	for _, encoding := range Encodings() {
+		t.Run(encoding, func(t *testing.T) {
+			compressed, err := Compress(encoding, data)
+			require.NoError(t, err)
+			assert.Less(t, len(compressed), len(data), "Repetitive data should shrink")
+
+			decompressed, err := Decompress(encoding, compressed)
+			require.NoError(t, err)
+			assert.Equal(t, data, decompressed)
+		})
+	}
+}
+
+func TestStandardFormats(t *testing.T) {
+	data := []byte("metrical")
+
+	// gzip совместим со стандартной библиотекой
+	compressed, err := Compress(Gzip, data)
+	require.NoError(t, err)
+	gzReader, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(gzReader)
+	require.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+
+	// deflate в HTTP - это поток zlib
+	compressed, err = Compress(Deflate, data)
+	require.NoError(t, err)
+	zReader, err := zlib.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	decompressed, err = io.ReadAll(zReader)
+	require.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestNewReader_Errors(t *testing.T) {
+	_, err := NewReader("br", bytes.NewReader(nil))
+	assert.Error(t, err, "Unsupported encoding should fail")
+
+	_, err = NewWriter("br", io.Discard)
+	assert.Error(t, err, "Unsupported encoding should fail")
+
+	for _, encoding := range []string{Gzip, Deflate} {
+		_, err := NewReader(encoding, strings.NewReader("not compressed"))
+		assert.Error(t, err, "Invalid %s header should fail", encoding)
+	}
+
+	_, err = Decompress(Zstd, []byte("not compressed"))
+	assert.Error(t, err, "Invalid zstd frame should fail")
+}
+
+func TestPooledReuse(t *testing.T) {
+	// Параллельные потоки не смешивают данные при переиспользовании reader и writer из пула
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			encoding := Encodings()[i%len(Encodings())]
+			data := []byte(strings.Repeat(string(rune('a'+i)), 1000))
+
+			compressed, err := Compress(encoding, data)
+			assert.NoError(t, err)
+			decompressed, err := Decompress(encoding, compressed)
+			assert.NoError(t, err)
+			assert.Equal(t, data, decompressed)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestPooledReader_Close(t *testing.T) {
+	compressed, err := Compress(Gzip, []byte("data"))
+	require.NoError(t, err)
+
+	reader, err := NewReader(Gzip, bytes.NewReader(compressed))
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	require.NoError(t, reader.Close(), "Second close should be a no-op")
+
+	_, err = reader.Read(make([]byte, 1))
+	assert.Error(t, err, "Read after close should fail")
+}
+
+func TestSupported(t *testing.T) {
+	assert.True(t, Supported("gzip"))
+	assert.True(t, Supported("x-gzip"))
+	assert.True(t, Supported(" ZSTD "))
+	assert.True(t, Supported("deflate"))
+	assert.False(t, Supported("br"))
+	assert.False(t, Supported("identity"))
+}
@@ -0,0 +1,119 @@
+package compression
+
+import (
+	"strconv"
+	"strings"
+)
+
+// acceptedEncoding элемент заголовка Accept-Encoding
+type acceptedEncoding struct {
+	name string
+	q    float64
+}
+
+// parseAcceptEncoding разбирает заголовок Accept-Encoding (RFC 9110, раздел 12.5.3).
+// Элементы с некорректным q-значением пропускаются.
+func parseAcceptEncoding(header string) []acceptedEncoding {
+	var result []acceptedEncoding
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = Normalize(name)
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		valid := true
+		for _, param := range strings.Split(params, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || !strings.EqualFold(strings.TrimSpace(key), "q") {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				valid = false
+				break
+			}
+			q = parsed
+		}
+		if valid {
+			result = append(result, acceptedEncoding{name: name, q: q})
+		}
+	}
+	return result
+}
+
+// Negotiate выбирает кодировку ответа по заголовку Accept-Encoding среди supported
+// (порядок supported задает предпочтение сервера при равных q).
+// Пустая encoding означает ответ без сжатия; identityAllowed - допустим ли ответ без сжатия
+// (запрещается явным "identity;q=0" или "*;q=0" без отдельного элемента identity).
+func Negotiate(header string, supported []string) (encoding string, identityAllowed bool) {
+	accepted := parseAcceptEncoding(header)
+
+	explicit := make(map[string]float64, len(accepted))
+	wildcard, hasWildcard := 0.0, false
+	for _, a := range accepted {
+		if a.name == "*" {
+			wildcard, hasWildcard = a.q, true
+			continue
+		}
+		// При повторах учитывается наибольшее значение
+		if q, ok := explicit[a.name]; !ok || a.q > q {
+			explicit[a.name] = a.q
+		}
+	}
+
+	// Без заголовка сжатие не применяется
+	if len(accepted) == 0 {
+		return "", true
+	}
+
+	qualityOf := func(name string) float64 {
+		if q, ok := explicit[name]; ok {
+			return q
+		}
+		if hasWildcard {
+			return wildcard
+		}
+		return 0
+	}
+
+	bestQ := 0.0
+	for _, name := range supported {
+		name = Normalize(name)
+		if q := qualityOf(name); q > bestQ {
+			encoding, bestQ = name, q
+		}
+	}
+
+	identityQ, identityExplicit := explicit[Identity]
+	switch {
+	case identityExplicit:
+		identityAllowed = identityQ > 0
+	case hasWildcard:
+		identityAllowed = wildcard > 0
+	default:
+		identityAllowed = true
+	}
+
+	// Явно предпочтенный ответ без сжатия
+	if identityExplicit && identityQ > bestQ {
+		return "", true
+	}
+	return encoding, identityAllowed
+}
+
+// AcceptEncoding формирует заголовок Accept-Encoding для клиента, предпочитающего кодировку preferred
+func AcceptEncoding(preferred string) string {
+	preferred = Normalize(preferred)
+	values := []string{}
+	if Supported(preferred) {
+		values = append(values, preferred)
+	}
+	for _, encoding := range Encodings() {
+		if encoding != preferred {
+			values = append(values, encoding+";q=0.5")
+		}
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,56 @@
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	supported := Encodings()
+
+	tests := []struct {
+		name             string
+		header           string
+		expectedEncoding string
+		expectedIdentity bool
+	}{
+		{name: "no header", header: "", expectedEncoding: "", expectedIdentity: true},
+		{name: "single gzip", header: "gzip", expectedEncoding: Gzip, expectedIdentity: true},
+		{name: "x-gzip alias", header: "x-gzip", expectedEncoding: Gzip, expectedIdentity: true},
+		{name: "server preference on tie", header: "gzip, deflate, zstd", expectedEncoding: Zstd, expectedIdentity: true},
+		{name: "q-values", header: "zstd;q=0.5, gzip;q=0.8, deflate;q=0.1", expectedEncoding: Gzip, expectedIdentity: true},
+		{name: "zero q excludes", header: "gzip;q=0, deflate", expectedEncoding: Deflate, expectedIdentity: true},
+		{name: "unsupported only", header: "br", expectedEncoding: "", expectedIdentity: true},
+		{name: "wildcard", header: "br, *;q=0.3", expectedEncoding: Zstd, expectedIdentity: true},
+		{name: "wildcard with exclusion", header: "*, zstd;q=0", expectedEncoding: Gzip, expectedIdentity: true},
+		{name: "identity forbidden", header: "gzip, identity;q=0", expectedEncoding: Gzip, expectedIdentity: false},
+		{name: "wildcard forbids identity", header: "deflate, *;q=0", expectedEncoding: Deflate, expectedIdentity: false},
+		{name: "explicit identity overrides wildcard", header: "*;q=0, identity", expectedEncoding: "", expectedIdentity: true},
+		{name: "nothing acceptable", header: "br, identity;q=0", expectedEncoding: "", expectedIdentity: false},
+		{name: "identity preferred", header: "gzip;q=0.2, identity;q=0.9", expectedEncoding: "", expectedIdentity: true},
+		{name: "case and spaces", header: " GZIP ; Q=0.7 ,  Deflate;q=0.9", expectedEncoding: Deflate, expectedIdentity: true},
+		{name: "invalid q skipped", header: "zstd;q=abc, gzip;q=2, deflate;q=0.4", expectedEncoding: Deflate, expectedIdentity: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoding, identityAllowed := Negotiate(tt.header, supported)
+			assert.Equal(t, tt.expectedEncoding, encoding)
+			assert.Equal(t, tt.expectedIdentity, identityAllowed)
+		})
+	}
+}
+
+func TestNegotiate_RestrictedSupported(t *testing.T) {
+	encoding, _ := Negotiate("zstd, gzip;q=0.5", []string{Gzip})
+	assert.Equal(t, Gzip, encoding, "Only server-enabled encodings should be chosen")
+}
+
+func TestAcceptEncoding(t *testing.T) {
+	assert.Equal(t, "zstd, gzip;q=0.5, deflate;q=0.5", AcceptEncoding(Zstd))
+	assert.Equal(t, "gzip, zstd;q=0.5, deflate;q=0.5", AcceptEncoding(Gzip))
+
+	encoding, _ := Negotiate(AcceptEncoding(Deflate), Encodings())
+	assert.Equal(t, Deflate, encoding)
+}
@@ -0,0 +1,231 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	// HeaderName заголовок запроса, сообщающий о шифровании тела
+	HeaderName = "Content-Encryption"
+	// Scheme схема гибридного шифрования: ключ AES-256 шифруется RSA-OAEP (SHA-256), данные - AES-GCM
+	Scheme = "rsa-oaep-aes256gcm"
+
+	// sessionKeySize размер сеансового ключа AES-256
+	sessionKeySize = 32
+	// keyLengthSize размер поля длины зашифрованного сеансового ключа
+	keyLengthSize = 2
+	// gcmNonceSize размер nonce AES-GCM
+	gcmNonceSize = 12
+	// gcmTagSize размер тега аутентификации AES-GCM
+	gcmTagSize = 16
+)
+
+// ErrInvalidCiphertext возвращается, если зашифрованные данные повреждены или зашифрованы другим ключом
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// Encryptor шифрует данные публичным ключом получателя
+type Encryptor struct {
+	publicKey *rsa.PublicKey
+}
+
+// NewEncryptor создает шифратор с публичным ключом
+func NewEncryptor(publicKey *rsa.PublicKey) (*Encryptor, error) {
+	if publicKey == nil {
+		return nil, fmt.Errorf("public key cannot be nil")
+	}
+	return &Encryptor{publicKey: publicKey}, nil
+}
+
+// NewEncryptorFromFile создает шифратор с публичным ключом из PEM файла
+func NewEncryptorFromFile(path string) (*Encryptor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	publicKey, err := ParsePublicKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewEncryptor(publicKey)
+}
+
+// Encrypt шифрует данные новым сеансовым ключом.
+// Формат результата: длина зашифрованного ключа (2 байта, big-endian), зашифрованный ключ,
+// nonce AES-GCM и зашифрованные данные с тегом аутентификации.
+func (e *Encryptor) Encrypt(plaintext []byte) ([]byte, error) {
+	sessionKey := make([]byte, sessionKeySize)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return nil, fmt.Errorf("failed to generate session key: %w", err)
+	}
+
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, e.publicKey, sessionKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt session key: %w", err)
+	}
+
+	gcm, err := newGCM(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	result := make([]byte, keyLengthSize, keyLengthSize+len(encryptedKey)+len(nonce)+len(plaintext)+gcm.Overhead())
+	binary.BigEndian.PutUint16(result, uint16(len(encryptedKey)))
+	result = append(result, encryptedKey...)
+	result = append(result, nonce...)
+	return gcm.Seal(result, nonce, plaintext, nil), nil
+}
+
+// Decryptor расшифровывает данные приватным ключом
+type Decryptor struct {
+	privateKey *rsa.PrivateKey
+}
+
+// NewDecryptor создает дешифратор с приватным ключом
+func NewDecryptor(privateKey *rsa.PrivateKey) (*Decryptor, error) {
+	if privateKey == nil {
+		return nil, fmt.Errorf("private key cannot be nil")
+	}
+	return &Decryptor{privateKey: privateKey}, nil
+}
+
+// NewDecryptorFromFile создает дешифратор с приватным ключом из PEM файла
+func NewDecryptorFromFile(path string) (*Decryptor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	privateKey, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewDecryptor(privateKey)
+}
+
+// Overhead возвращает, на сколько байт зашифрованные данные длиннее исходных:
+// поле длины, зашифрованный сеансовый ключ, nonce и тег GCM
+func (d *Decryptor) Overhead() int {
+	return keyLengthSize + d.privateKey.Size() + gcmNonceSize + gcmTagSize
+}
+
+// Decrypt расшифровывает данные, зашифрованные Encryptor
+func (d *Decryptor) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < keyLengthSize {
+		return nil, ErrInvalidCiphertext
+	}
+
+	keyLength := int(binary.BigEndian.Uint16(ciphertext))
+	ciphertext = ciphertext[keyLengthSize:]
+	if len(ciphertext) < keyLength {
+		return nil, ErrInvalidCiphertext
+	}
+
+	sessionKey, err := rsa.DecryptOAEP(sha256.New(), nil, d.privateKey, ciphertext[:keyLength], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt session key: %w", ErrInvalidCiphertext)
+	}
+	ciphertext = ciphertext[keyLength:]
+
+	gcm, err := newGCM(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt payload: %w", ErrInvalidCiphertext)
+	}
+	return plaintext, nil
+}
+
+// newGCM создает AES-GCM для сеансового ключа
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+// ParsePublicKeyPEM разбирает публичный RSA ключ в формате PKIX ("PUBLIC KEY") или PKCS#1 ("RSA PUBLIC KEY")
+func ParsePublicKeyPEM(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM public key")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key is not RSA: %T", key)
+		}
+		return publicKey, nil
+	case "RSA PUBLIC KEY":
+		publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		return publicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key PEM type: %s", block.Type)
+	}
+}
+
+// ParsePrivateKeyPEM разбирает приватный RSA ключ в формате PKCS#8 ("PRIVATE KEY") или PKCS#1 ("RSA PRIVATE KEY")
+func ParsePrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM private key")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		privateKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("private key is not RSA: %T", key)
+		}
+		return privateKey, nil
+	case "RSA PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		return privateKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key PEM type: %s", block.Type)
+	}
+}
@@ -0,0 +1,138 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestKeyPair создает шифратор и дешифратор из ключей во временных файлах
+func newTestKeyPair(t *testing.T) (*Encryptor, *Decryptor) {
+	t.Helper()
+
+	privateKeyPath, publicKeyPath := testutils.WriteTestRSAKeys(t)
+
+	encryptor, err := NewEncryptorFromFile(publicKeyPath)
+	require.NoError(t, err)
+	decryptor, err := NewDecryptorFromFile(privateKeyPath)
+	require.NoError(t, err)
+
+	return encryptor, decryptor
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	encryptor, decryptor := newTestKeyPair(t)
+
+	tests := []struct {
+		name      string
+		plaintext []byte
+	}{
+		{name: "empty payload", plaintext: []byte{}},
+		{name: "small payload", plaintext: []byte(`[{"id":"PollCount","type":"counter","delta":5}]`)},
+		// Данные больше размера RSA ключа шифруются сеансовым ключом AES
+		{name: "large payload", plaintext: bytes.Repeat([]byte("metrics"), 10000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ciphertext, err := encryptor.Encrypt(tt.plaintext)
+			require.NoError(t, err)
+			if len(tt.plaintext) > 0 {
+				assert.False(t, bytes.Contains(ciphertext, tt.plaintext), "Ciphertext should not contain plaintext")
+			}
+
+			plaintext, err := decryptor.Decrypt(ciphertext)
+			require.NoError(t, err)
+			assert.Equal(t, string(tt.plaintext), string(plaintext))
+		})
+	}
+}
+
+func TestEncrypt_UniqueCiphertexts(t *testing.T) {
+	encryptor, _ := newTestKeyPair(t)
+
+	first, err := encryptor.Encrypt([]byte("data"))
+	require.NoError(t, err)
+	second, err := encryptor.Encrypt([]byte("data"))
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first, second, "Each message should use a new session key and nonce")
+}
+
+func TestDecrypt_InvalidCiphertext(t *testing.T) {
+	encryptor, decryptor := newTestKeyPair(t)
+	_, otherDecryptor := newTestKeyPair(t)
+
+	ciphertext, err := encryptor.Encrypt([]byte("payload"))
+	require.NoError(t, err)
+
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 0xff
+
+	tests := []struct {
+		name       string
+		decryptor  *Decryptor
+		ciphertext []byte
+	}{
+		{name: "empty", decryptor: decryptor, ciphertext: nil},
+		{name: "truncated key", decryptor: decryptor, ciphertext: ciphertext[:10]},
+		{name: "truncated nonce", decryptor: decryptor, ciphertext: ciphertext[:keyLengthSize+256+4]},
+		{name: "tampered payload", decryptor: decryptor, ciphertext: tampered},
+		{name: "wrong private key", decryptor: otherDecryptor, ciphertext: ciphertext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.decryptor.Decrypt(tt.ciphertext)
+			assert.True(t, errors.Is(err, ErrInvalidCiphertext), "unexpected error: %v", err)
+		})
+	}
+}
+
+func TestParseKeysPEM(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	t.Run("PKCS1 keys", func(t *testing.T) {
+		publicPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey)})
+		privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+
+		publicKey, err := ParsePublicKeyPEM(publicPEM)
+		require.NoError(t, err)
+		assert.True(t, publicKey.Equal(&privateKey.PublicKey))
+
+		parsedPrivateKey, err := ParsePrivateKeyPEM(privatePEM)
+		require.NoError(t, err)
+		assert.True(t, parsedPrivateKey.Equal(privateKey))
+	})
+
+	t.Run("invalid PEM", func(t *testing.T) {
+		_, err := ParsePublicKeyPEM([]byte("not a key"))
+		assert.Error(t, err)
+		_, err = ParsePrivateKeyPEM([]byte("not a key"))
+		assert.Error(t, err)
+	})
+
+	t.Run("unsupported block type", func(t *testing.T) {
+		block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1, 2, 3}})
+		_, err := ParsePublicKeyPEM(block)
+		assert.Error(t, err)
+		_, err = ParsePrivateKeyPEM(block)
+		assert.Error(t, err)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := NewEncryptorFromFile("/nonexistent/public.pem")
+		assert.Error(t, err)
+		_, err = NewDecryptorFromFile("/nonexistent/private.pem")
+		assert.Error(t, err)
+	})
+}
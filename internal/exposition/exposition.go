@@ -0,0 +1,178 @@
+// Package exposition формирует текстовое представление метрик для Prometheus
+// (text exposition format 0.0.4 и OpenMetrics 1.0).
+package exposition
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+)
+
+// Format формат представления метрик
+type Format string
+
+const (
+	// FormatText текстовый формат Prometheus 0.0.4
+	FormatText Format = "text"
+	// FormatOpenMetrics формат OpenMetrics 1.0
+	FormatOpenMetrics Format = "openmetrics"
+)
+
+const (
+	// ContentTypeText Content-Type текстового формата Prometheus
+	ContentTypeText = "text/plain; version=0.0.4; charset=utf-8"
+	// ContentTypeOpenMetrics Content-Type формата OpenMetrics
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// openMetricsMediaType тип содержимого OpenMetrics в заголовке Accept
+const openMetricsMediaType = "application/openmetrics-text"
+
+// counterSuffix обязательный суффикс значения счетчика
+const counterSuffix = "_total"
+
+// ContentType возвращает Content-Type ответа для формата
+func (f Format) ContentType() string {
+	if f == FormatOpenMetrics {
+		return ContentTypeOpenMetrics
+	}
+	return ContentTypeText
+}
+
+// Negotiate выбирает формат по заголовку Accept: OpenMetrics, если клиент его принимает, иначе текстовый формат
+func Negotiate(accept string) Format {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != openMetricsMediaType {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			if weight, err := strconv.ParseFloat(q, 64); err != nil || weight <= 0 {
+				continue
+			}
+		}
+		return FormatOpenMetrics
+	}
+	return FormatText
+}
+
+// SanitizeName приводит имя метрики к допустимому в Prometheus виду [a-zA-Z_:][a-zA-Z0-9_:]*:
+// недопустимые символы заменяются на '_', имя, начинающееся с цифры, получает префикс '_'
+func SanitizeName(name string) string {
+	var b strings.Builder
+	b.Grow(len(name) + 1)
+	for i, r := range name {
+		switch {
+		case r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// family семейство метрик в выводе
+type family struct {
+	name     string // Имя семейства после приведения (для counter без суффикса _total)
+	original string // Исходное имя метрики
+	kind     string // models.Gauge или models.Counter
+	value    string
+}
+
+// Write записывает gauge и counter метрики в заданном формате.
+// Метрики выводятся в порядке имен; метрика, имя которой после приведения совпало с уже выведенной,
+// пропускается, а ее исходное имя возвращается в skipped.
+func Write(w io.Writer, format Format, gauges models.GaugeMetrics, counters models.CounterMetrics) (skipped []string, err error) {
+	families := make([]family, 0, len(gauges)+len(counters))
+	for _, name := range sortedKeys(gauges) {
+		families = append(families, family{
+			name:     SanitizeName(name),
+			original: name,
+			kind:     models.Gauge,
+			value:    formatFloat(gauges[name]),
+		})
+	}
+	for _, name := range sortedKeys(counters) {
+		families = append(families, family{
+			name:     strings.TrimSuffix(SanitizeName(name), counterSuffix),
+			original: name,
+			kind:     models.Counter,
+			value:    strconv.FormatInt(counters[name], 10),
+		})
+	}
+
+	bw := bufio.NewWriter(w)
+	seen := make(map[string]bool, len(families))
+	for _, f := range families {
+		// Имена семейств и значений не должны пересекаться: gauge "x_total" совпал бы со значением counter "x"
+		if seen[f.name] || (f.kind == models.Counter && seen[f.name+counterSuffix]) {
+			skipped = append(skipped, f.original)
+			continue
+		}
+		seen[f.name] = true
+		if f.kind == models.Counter {
+			seen[f.name+counterSuffix] = true
+		}
+		writeFamily(bw, format, f)
+	}
+
+	if format == FormatOpenMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return skipped, bw.Flush()
+}
+
+// writeFamily записывает строки HELP, TYPE и значение семейства
+func writeFamily(w *bufio.Writer, format Format, f family) {
+	sample := f.name
+	typeName := f.name
+	if f.kind == models.Counter {
+		sample += counterSuffix
+		// В формате 0.0.4 TYPE относится к имени значения, в OpenMetrics - к имени семейства
+		if format == FormatText {
+			typeName = sample
+		}
+	}
+
+	w.WriteString("# HELP " + typeName + " " + escapeHelp(format, "metrical "+f.kind+" "+f.original) + "\n")
+	w.WriteString("# TYPE " + typeName + " " + f.kind + "\n")
+	w.WriteString(sample + " " + f.value + "\n")
+}
+
+// escapeHelp экранирует текст HELP: '\' и перевод строки, в OpenMetrics также '"'
+func escapeHelp(format Format, text string) string {
+	replacements := []string{`\`, `\\`, "\n", `\n`}
+	if format == FormatOpenMetrics {
+		replacements = append(replacements, `"`, `\"`)
+	}
+	return strings.NewReplacer(replacements...).Replace(text)
+}
+
+// formatFloat форматирует значение gauge (NaN и ±Inf в виде, принятом Prometheus)
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// sortedKeys возвращает ключи в порядке возрастания
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
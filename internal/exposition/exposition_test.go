@@ -0,0 +1,121 @@
+package exposition
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"HeapAlloc", "HeapAlloc"},
+		{"host1.cpu-usage", "host1_cpu_usage"},
+		{"http:requests_total", "http:requests_total"},
+		{"9lives", "_9lives"},
+		{"Загрузка", "________"},
+		{"", "_"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SanitizeName(tt.name))
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected Format
+	}{
+		{"", FormatText},
+		{"*/*", FormatText},
+		{"text/plain;version=0.0.4;q=0.5,*/*;q=0.1", FormatText},
+		{"application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5", FormatOpenMetrics},
+		{"application/openmetrics-text; version=0.0.1; q=0.75, text/plain; q=0.5", FormatOpenMetrics},
+		{"application/openmetrics-text;q=0", FormatText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			format := Negotiate(tt.accept)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+
+	assert.Equal(t, ContentTypeText, FormatText.ContentType())
+	assert.Equal(t, ContentTypeOpenMetrics, FormatOpenMetrics.ContentType())
+}
+
+func TestWrite_Text(t *testing.T) {
+	var buf bytes.Buffer
+	skipped, err := Write(&buf, FormatText,
+		models.GaugeMetrics{"HeapAlloc": 1.5e+06, "host1.load": 0.25, "Inf": math.Inf(1)},
+		models.CounterMetrics{"PollCount": 42})
+	require.NoError(t, err)
+	assert.Empty(t, skipped)
+
+	expected := `# HELP HeapAlloc metrical gauge HeapAlloc
+# TYPE HeapAlloc gauge
+HeapAlloc 1.5e+06
+# HELP Inf metrical gauge Inf
+# TYPE Inf gauge
+Inf +Inf
+# HELP host1_load metrical gauge host1.load
+# TYPE host1_load gauge
+host1_load 0.25
+# HELP PollCount_total metrical counter PollCount
+# TYPE PollCount_total counter
+PollCount_total 42
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWrite_OpenMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	skipped, err := Write(&buf, FormatOpenMetrics,
+		models.GaugeMetrics{"Alloc": 100},
+		models.CounterMetrics{"requests_total": 7, `say"hi"`: 1})
+	require.NoError(t, err)
+	assert.Empty(t, skipped)
+
+	expected := `# HELP Alloc metrical gauge Alloc
+# TYPE Alloc gauge
+Alloc 100
+# HELP requests metrical counter requests_total
+# TYPE requests counter
+requests_total 7
+# HELP say_hi_ metrical counter say\"hi\"
+# TYPE say_hi_ counter
+say_hi__total 1
+# EOF
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWrite_NameCollisions(t *testing.T) {
+	var buf bytes.Buffer
+	skipped, err := Write(&buf, FormatText,
+		models.GaugeMetrics{"cpu.load": 1, "cpu_load": 2, "requests_total": 3},
+		models.CounterMetrics{"cpu-load": 4, "requests": 5})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"cpu_load", "cpu-load", "requests"}, skipped)
+	assert.Contains(t, buf.String(), "cpu_load 1\n", "First name in sort order wins")
+	assert.Contains(t, buf.String(), "requests_total 3\n")
+	assert.NotContains(t, buf.String(), "counter")
+}
+
+func TestWrite_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := Write(&buf, FormatOpenMetrics, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "# EOF\n", buf.String())
+}
@@ -0,0 +1,93 @@
+// Package grpcapi связывает сообщения gRPC API (pkg/metricalpb) с моделью метрик сервера.
+package grpcapi
+
+import (
+	"fmt"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/signature"
+	pb "github.com/IgorKilipenko/metrical/pkg/metricalpb"
+)
+
+// MetricType возвращает тип метрики gRPC API по типу модели (METRIC_TYPE_UNSPECIFIED для неизвестного типа)
+func MetricType(metricType string) pb.MetricType {
+	switch metricType {
+	case models.Gauge:
+		return pb.MetricType_METRIC_TYPE_GAUGE
+	case models.Counter:
+		return pb.MetricType_METRIC_TYPE_COUNTER
+	default:
+		return pb.MetricType_METRIC_TYPE_UNSPECIFIED
+	}
+}
+
+// ModelType возвращает тип модели по типу метрики gRPC API
+func ModelType(metricType pb.MetricType) (string, error) {
+	switch metricType {
+	case pb.MetricType_METRIC_TYPE_GAUGE:
+		return models.Gauge, nil
+	case pb.MetricType_METRIC_TYPE_COUNTER:
+		return models.Counter, nil
+	default:
+		return "", fmt.Errorf("%w: %s", models.ErrUnsupportedMetricType, metricType)
+	}
+}
+
+// FromModel преобразует метрику модели в сообщение gRPC API
+func FromModel(metric *models.Metrics) *pb.Metric {
+	result := &pb.Metric{
+		Id:   metric.ID,
+		Type: MetricType(metric.MType),
+		Hash: metric.Hash,
+	}
+	if metric.Delta != nil {
+		delta := *metric.Delta
+		result.Delta = &delta
+	}
+	if metric.Value != nil {
+		value := *metric.Value
+		result.Value = &value
+	}
+	return result
+}
+
+// ToModel преобразует сообщение gRPC API в метрику модели
+func ToModel(metric *pb.Metric) (models.Metrics, error) {
+	if metric == nil {
+		return models.Metrics{}, models.ValidationError{Field: "metric", Value: "null", Message: "is required"}
+	}
+
+	metricType, err := ModelType(metric.GetType())
+	if err != nil {
+		return models.Metrics{}, err
+	}
+
+	result := models.Metrics{ID: metric.GetId(), MType: metricType, Hash: metric.GetHash()}
+	if metric.Delta != nil {
+		delta := metric.GetDelta()
+		result.Delta = &delta
+	}
+	if metric.Value != nil {
+		value := metric.GetValue()
+		result.Value = &value
+	}
+	return result, nil
+}
+
+// SignMetric заполняет поле Hash метрики подписью "id:type:value", как в JSON API
+func SignMetric(metric *pb.Metric, key string) {
+	model, err := ToModel(metric)
+	if err != nil {
+		return
+	}
+	metric.Hash = signature.MetricHash(&model, key)
+}
+
+// VerifyMetric проверяет поле Hash метрики
+func VerifyMetric(metric *pb.Metric, key string) bool {
+	model, err := ToModel(metric)
+	if err != nil {
+		return false
+	}
+	return signature.VerifyMetric(&model, key)
+}
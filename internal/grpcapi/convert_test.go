@@ -0,0 +1,46 @@
+package grpcapi
+
+import (
+	"errors"
+	"testing"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/signature"
+	pb "github.com/IgorKilipenko/metrical/pkg/metricalpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert_RoundTrip(t *testing.T) {
+	value := 21.5
+	delta := int64(7)
+
+	for _, metric := range []models.Metrics{
+		{ID: "temperature", MType: models.Gauge, Value: &value},
+		{ID: "requests", MType: models.Counter, Delta: &delta, Hash: "abc"},
+	} {
+		converted, err := ToModel(FromModel(&metric))
+		require.NoError(t, err)
+		assert.Equal(t, metric, converted)
+	}
+}
+
+func TestToModel_Errors(t *testing.T) {
+	_, err := ToModel(nil)
+	assert.True(t, models.IsValidationError(err))
+
+	_, err = ToModel(&pb.Metric{Id: "requests"})
+	assert.True(t, errors.Is(err, models.ErrUnsupportedMetricType))
+}
+
+func TestSignMetric_MatchesJSONHash(t *testing.T) {
+	delta := int64(7)
+	metric := &pb.Metric{Id: "requests", Type: pb.MetricType_METRIC_TYPE_COUNTER, Delta: &delta}
+	SignMetric(metric, "secret")
+
+	// Подпись совпадает с полем hash JSON API, поэтому агент подписывает метрики одинаково
+	expected := signature.MetricHash(&models.Metrics{ID: "requests", MType: models.Counter, Delta: &delta}, "secret")
+	assert.Equal(t, expected, metric.GetHash())
+	assert.True(t, VerifyMetric(metric, "secret"))
+	assert.False(t, VerifyMetric(metric, "other"))
+}
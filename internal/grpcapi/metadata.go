@@ -0,0 +1,15 @@
+package grpcapi
+
+// Ключи метаданных вызовов gRPC API
+const (
+	// AuthorizationKey bearer токен ("Bearer <token>")
+	AuthorizationKey = "authorization"
+	// RealIPKey IP адрес агента для проверки доверенной подсети (аналог заголовка X-Real-IP)
+	RealIPKey = "x-real-ip"
+	// RetryAfterKey время ожидания в секундах при превышении лимита частоты (метаданные ответа)
+	RetryAfterKey = "retry-after"
+	// IdempotencyKey ключ идемпотентности вызова на запись (аналог заголовка Idempotency-Key)
+	IdempotencyKey = "idempotency-key"
+	// IdempotentReplayedKey признак ответа, повторенного из кэша идемпотентности (метаданные ответа)
+	IdempotentReplayedKey = "idempotent-replayed"
+)
@@ -0,0 +1,253 @@
+package grpcserver
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/grpcapi"
+	"github.com/IgorKilipenko/metrical/internal/logger"
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/validation"
+	pb "github.com/IgorKilipenko/metrical/pkg/metricalpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// requestTimeout время на обращение к сервису метрик, как у HTTP обработчиков
+const requestTimeout = 5 * time.Second
+
+// MetricsService операции сервиса метрик, используемые gRPC API (реализуется service.MetricsService)
+type MetricsService interface {
+	UpdateMetricJSON(ctx context.Context, metric *models.Metrics) error
+	UpdateMetricsBatch(ctx context.Context, metrics []models.Metrics) error
+	GetMetricJSON(ctx context.Context, metric *models.Metrics) (*models.Metrics, error)
+	GetAllGauges(ctx context.Context) (models.GaugeMetrics, error)
+	GetAllCounters(ctx context.Context) (models.CounterMetrics, error)
+}
+
+// MetricsServer реализация gRPC сервиса metrical.v1.MetricsService поверх сервиса метрик
+type MetricsServer struct {
+	pb.UnimplementedMetricsServiceServer
+
+	service MetricsService
+	logger  logger.Logger
+}
+
+// NewMetricsServer создает реализацию gRPC сервиса
+func NewMetricsServer(service MetricsService, logger logger.Logger) (*MetricsServer, error) {
+	if service == nil {
+		return nil, errors.New("service cannot be nil")
+	}
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+	return &MetricsServer{service: service, logger: logger}, nil
+}
+
+// UpdateMetric обновляет метрику и возвращает ее значение после обновления
+func (s *MetricsServer) UpdateMetric(ctx context.Context, req *pb.UpdateMetricRequest) (*pb.UpdateMetricResponse, error) {
+	metric, err := grpcapi.ToModel(req.GetMetric())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	if err := validation.ValidateMetric(&metric); err != nil {
+		return nil, s.statusError(err)
+	}
+	if err := s.authorizeMetric(ctx, metric.ID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	if err := s.service.UpdateMetricJSON(ctx, &metric); err != nil {
+		return nil, s.statusError(err)
+	}
+
+	updated, err := s.service.GetMetricJSON(ctx, &models.Metrics{ID: metric.ID, MType: metric.MType})
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+
+	s.logger.Info("metric updated via gRPC", "id", metric.ID, "type", metric.MType, "principal", principalName(ctx))
+	return &pb.UpdateMetricResponse{Metric: grpcapi.FromModel(updated)}, nil
+}
+
+// UpdateMetrics обновляет пакет метрик целиком
+func (s *MetricsServer) UpdateMetrics(ctx context.Context, req *pb.UpdateMetricsRequest) (*pb.UpdateMetricsResponse, error) {
+	updated, err := s.updateBatch(ctx, req.GetMetrics())
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("metrics batch updated via gRPC", "count", updated, "principal", principalName(ctx))
+	return &pb.UpdateMetricsResponse{Updated: int64(updated)}, nil
+}
+
+// PushMetrics записывает пакеты потока по мере получения.
+// Ошибка пакета завершает поток; пакеты, полученные до нее, остаются записанными.
+func (s *MetricsServer) PushMetrics(stream pb.MetricsService_PushMetricsServer) error {
+	ctx := stream.Context()
+	response := &pb.PushMetricsResponse{}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			s.logger.Info("metrics stream completed via gRPC",
+				"batches", response.Batches,
+				"count", response.Updated,
+				"principal", principalName(ctx))
+			return stream.SendAndClose(response)
+		}
+		if err != nil {
+			return err
+		}
+
+		updated, err := s.updateBatch(ctx, req.GetMetrics())
+		if err != nil {
+			return err
+		}
+		response.Batches++
+		response.Updated += int64(updated)
+	}
+}
+
+// updateBatch проверяет доступ к метрикам и записывает пакет
+func (s *MetricsServer) updateBatch(ctx context.Context, batch []*pb.Metric) (int, error) {
+	metrics := make([]models.Metrics, 0, len(batch))
+	for _, metric := range batch {
+		model, err := grpcapi.ToModel(metric)
+		if err != nil {
+			return 0, s.statusError(err)
+		}
+		// Пакет применяется целиком, поэтому недоступная метрика отклоняет весь пакет
+		if err := s.authorizeMetric(ctx, model.ID); err != nil {
+			return 0, err
+		}
+		metrics = append(metrics, model)
+	}
+
+	if len(metrics) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	if err := s.service.UpdateMetricsBatch(ctx, metrics); err != nil {
+		return 0, s.statusError(err)
+	}
+	return len(metrics), nil
+}
+
+// GetMetric возвращает значение метрики
+func (s *MetricsServer) GetMetric(ctx context.Context, req *pb.GetMetricRequest) (*pb.GetMetricResponse, error) {
+	metricType, err := grpcapi.ModelType(req.GetType())
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	if err := s.authorizeMetric(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	metric, err := s.service.GetMetricJSON(ctx, &models.Metrics{ID: req.GetId(), MType: metricType})
+	if err != nil {
+		return nil, s.statusError(err)
+	}
+	return &pb.GetMetricResponse{Metric: grpcapi.FromModel(metric)}, nil
+}
+
+// ListMetrics возвращает метрики, доступные токену, в порядке имен (gauge перед counter при равных именах)
+func (s *MetricsServer) ListMetrics(ctx context.Context, req *pb.ListMetricsRequest) (*pb.ListMetricsResponse, error) {
+	filter := req.GetType()
+	if filter != pb.MetricType_METRIC_TYPE_UNSPECIFIED {
+		if _, err := grpcapi.ModelType(filter); err != nil {
+			return nil, s.statusError(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	principal, restricted := auth.PrincipalFromContext(ctx)
+	allowed := func(name string) bool { return !restricted || principal.AllowsMetric(name) }
+
+	var metrics []*pb.Metric
+	if filter != pb.MetricType_METRIC_TYPE_COUNTER {
+		gauges, err := s.service.GetAllGauges(ctx)
+		if err != nil {
+			return nil, s.statusError(err)
+		}
+		for name, value := range gauges {
+			if allowed(name) {
+				metrics = append(metrics, &pb.Metric{Id: name, Type: pb.MetricType_METRIC_TYPE_GAUGE, Value: &value})
+			}
+		}
+	}
+	if filter != pb.MetricType_METRIC_TYPE_GAUGE {
+		counters, err := s.service.GetAllCounters(ctx)
+		if err != nil {
+			return nil, s.statusError(err)
+		}
+		for name, delta := range counters {
+			if allowed(name) {
+				metrics = append(metrics, &pb.Metric{Id: name, Type: pb.MetricType_METRIC_TYPE_COUNTER, Delta: &delta})
+			}
+		}
+	}
+
+	slices.SortFunc(metrics, func(a, b *pb.Metric) int {
+		return cmp.Or(cmp.Compare(a.GetId(), b.GetId()), cmp.Compare(a.GetType(), b.GetType()))
+	})
+	return &pb.ListMetricsResponse{Metrics: metrics}, nil
+}
+
+// authorizeMetric проверяет, что токен вызова разрешает доступ к метрике.
+// При отказе возвращает PermissionDenied; вызовы без аутентификации не ограничиваются.
+func (s *MetricsServer) authorizeMetric(ctx context.Context, name string) error {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || principal.AllowsMetric(name) {
+		return nil
+	}
+
+	s.logger.Warn("metric access denied by token prefix",
+		"principal", principal.Name,
+		"prefix", principal.Prefix,
+		"name", name)
+	return status.Error(codes.PermissionDenied, "metric is outside token prefix")
+}
+
+// statusError сопоставляет ошибку предметной области с кодом gRPC, как writeProblem HTTP обработчиков
+func (s *MetricsServer) statusError(err error) error {
+	switch {
+	case models.IsValidationError(err), errors.Is(err, models.ErrUnsupportedMetricType):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, models.ErrMetricNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, models.ErrStorageUnavailable):
+		return status.Error(codes.Unavailable, "metrics storage is temporarily unavailable")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		s.logger.Error("gRPC request failed", "error", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+// principalName возвращает имя владельца токена вызова (пустая строка без аутентификации)
+func principalName(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		return principal.Name
+	}
+	return ""
+}
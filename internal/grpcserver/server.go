@@ -0,0 +1,221 @@
+// Package grpcserver запускает gRPC API сервера метрик (api/metrical.proto) рядом с HTTP сервером.
+package grpcserver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/idempotency"
+	"github.com/IgorKilipenko/metrical/internal/interceptor"
+	"github.com/IgorKilipenko/metrical/internal/logger"
+	"github.com/IgorKilipenko/metrical/internal/ratelimit"
+	"github.com/IgorKilipenko/metrical/internal/tlsconfig"
+	pb "github.com/IgorKilipenko/metrical/pkg/metricalpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip" // Регистрирует кодек gzip для сжатых сообщений клиентов
+)
+
+// DefaultMaxMessageSize максимальный размер сообщения по умолчанию
+const DefaultMaxMessageSize = 4 << 20
+
+// WriteMethods методы gRPC API, требующие области доступа write
+var WriteMethods = interceptor.NewMethods(
+	pb.MetricsService_UpdateMetric_FullMethodName,
+	pb.MetricsService_UpdateMetrics_FullMethodName,
+	pb.MetricsService_PushMetrics_FullMethodName,
+)
+
+// ReadMethods методы gRPC API, требующие области доступа read
+var ReadMethods = interceptor.NewMethods(
+	pb.MetricsService_GetMetric_FullMethodName,
+	pb.MetricsService_ListMetrics_FullMethodName,
+)
+
+// ServerConfig конфигурация gRPC сервера; проверки совпадают с HTTP сервером
+type ServerConfig struct {
+	Addr           string
+	SigningKey     string // Общий ключ подписи метрик (пустая строка - подпись отключена)
+	MaxMessageSize int    // Максимальный размер входящего сообщения в байтах
+
+	TLSCertFile     string // Сертификат сервера в формате PEM (пустая строка - без TLS)
+	TLSKeyFile      string // Приватный ключ сертификата сервера
+	TLSClientCAFile string // Бандл CA для проверки клиентских сертификатов (пустая строка - mTLS отключен)
+
+	TrustedSubnet     *net.IPNet // Доверенная подсеть для записи (nil - без ограничений)
+	TrustedReadSubnet *net.IPNet // Доверенная подсеть для чтения (nil - без ограничений)
+
+	// Прокси, которым доверяется x-real-ip при ограничении частоты (пусто - ключом служит адрес соединения)
+	TrustedProxies []*net.IPNet
+
+	Auth *auth.Store // API токены с областями доступа (nil - аутентификация отключена)
+
+	WriteLimiter *ratelimit.Limiter // Ограничение частоты записи для каждого клиента (nil - без ограничений)
+	ReadLimiter  *ratelimit.Limiter // Ограничение частоты чтения для каждого клиента (nil - без ограничений)
+
+	Idempotency *idempotency.Store // Кэш ключей идемпотентности, общий с HTTP API (nil - ключи не учитываются)
+}
+
+// DefaultServerConfig возвращает конфигурацию по умолчанию
+func DefaultServerConfig() *ServerConfig {
+	return &ServerConfig{
+		Addr:           ":3200",
+		MaxMessageSize: DefaultMaxMessageSize,
+	}
+}
+
+// Server gRPC сервер метрик
+type Server struct {
+	config    *ServerConfig
+	server    *grpc.Server
+	tlsConfig *tls.Config // TLS конфигурация (nil - без TLS)
+	logger    logger.Logger
+
+	listener net.Listener
+}
+
+// NewServerWithConfig создает gRPC сервер с реализацией сервиса поверх service
+func NewServerWithConfig(config *ServerConfig, service MetricsService, logger logger.Logger) (*Server, error) {
+	if config == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	if config.Addr == "" {
+		return nil, errors.New("address cannot be empty")
+	}
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+
+	metricsServer, err := NewMetricsServer(service, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &Server{config: config, logger: logger}
+
+	// Сертификаты загружаются при создании, чтобы ошибки конфигурации обнаруживались до запуска
+	if config.TLSCertFile != "" || config.TLSKeyFile != "" {
+		tlsConfig, err := tlsconfig.NewServerConfig(config.TLSCertFile, config.TLSKeyFile, config.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure TLS: %w", err)
+		}
+		srv.tlsConfig = tlsConfig
+	} else if config.TLSClientCAFile != "" {
+		return nil, errors.New("client CA requires TLS certificate and key")
+	}
+
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(srv.unaryInterceptors()...),
+		grpc.ChainStreamInterceptor(srv.streamInterceptors()...),
+	}
+	if config.MaxMessageSize > 0 {
+		options = append(options, grpc.MaxRecvMsgSize(config.MaxMessageSize))
+	}
+	if srv.tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(srv.tlsConfig)))
+	}
+
+	srv.server = grpc.NewServer(options...)
+	pb.RegisterMetricsServiceServer(srv.server, metricsServer)
+
+	logger.Info("creating gRPC server with config", "addr", config.Addr)
+	return srv, nil
+}
+
+// unaryInterceptors возвращает цепочку interceptors в порядке HTTP middleware:
+// логирование, подпись, затем подсеть, токен и лимит частоты для записи или чтения,
+// ключ идемпотентности - последним для записи
+func (s *Server) unaryInterceptors() []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		interceptor.UnaryLogging(s.logger),
+		interceptor.UnarySignature(s.config.SigningKey),
+		interceptor.UnaryFor(WriteMethods, interceptor.UnaryTrustedSubnet(s.config.TrustedSubnet)),
+		interceptor.UnaryFor(WriteMethods, interceptor.UnaryAuth(s.config.Auth, auth.ScopeWrite)),
+		interceptor.UnaryFor(WriteMethods, interceptor.UnaryRateLimit(s.config.WriteLimiter, s.config.TrustedProxies)),
+		interceptor.UnaryFor(WriteMethods, interceptor.UnaryIdempotency(s.config.Idempotency)),
+		interceptor.UnaryFor(ReadMethods, interceptor.UnaryTrustedSubnet(s.config.TrustedReadSubnet)),
+		interceptor.UnaryFor(ReadMethods, interceptor.UnaryAuth(s.config.Auth, auth.ScopeRead)),
+		interceptor.UnaryFor(ReadMethods, interceptor.UnaryRateLimit(s.config.ReadLimiter, s.config.TrustedProxies)),
+	}
+}
+
+// streamInterceptors возвращает цепочку interceptors потоковых методов
+func (s *Server) streamInterceptors() []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{
+		interceptor.StreamLogging(s.logger),
+		interceptor.StreamSignature(s.config.SigningKey),
+		interceptor.StreamFor(WriteMethods, interceptor.StreamTrustedSubnet(s.config.TrustedSubnet)),
+		interceptor.StreamFor(WriteMethods, interceptor.StreamAuth(s.config.Auth, auth.ScopeWrite)),
+		interceptor.StreamFor(WriteMethods, interceptor.StreamRateLimit(s.config.WriteLimiter, s.config.TrustedProxies)),
+		interceptor.StreamFor(WriteMethods, interceptor.StreamIdempotency(s.config.Idempotency)),
+		interceptor.StreamFor(ReadMethods, interceptor.StreamTrustedSubnet(s.config.TrustedReadSubnet)),
+		interceptor.StreamFor(ReadMethods, interceptor.StreamAuth(s.config.Auth, auth.ScopeRead)),
+		interceptor.StreamFor(ReadMethods, interceptor.StreamRateLimit(s.config.ReadLimiter, s.config.TrustedProxies)),
+	}
+}
+
+// Listen открывает TCP сокет сервера; после Listen адрес доступен через Addr
+func (s *Server) Listen() error {
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.config.Addr, err)
+	}
+	s.listener = listener
+	return nil
+}
+
+// Addr возвращает адрес открытого сокета (nil до Listen)
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Start принимает соединения до остановки сервера; открывает сокет, если Listen не вызывался
+func (s *Server) Start() error {
+	if s.listener == nil {
+		if err := s.Listen(); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Info("starting gRPC server",
+		"addr", s.listener.Addr().String(),
+		"tls", s.tlsConfig != nil,
+		"mtls", s.config.TLSClientCAFile != "")
+
+	if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		s.logger.Error("gRPC server error", "error", err)
+		return fmt.Errorf("failed to start gRPC server: %w", err)
+	}
+
+	s.logger.Info("gRPC server stopped")
+	return nil
+}
+
+// Shutdown дожидается завершения текущих вызовов; по истечении ctx соединения закрываются принудительно
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.logger.Info("shutting down gRPC server gracefully")
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		s.logger.Info("gRPC server shutdown completed successfully")
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		<-stopped
+		s.logger.Warn("gRPC server shutdown timed out, connections closed")
+		return ctx.Err()
+	}
+}
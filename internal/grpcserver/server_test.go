@@ -0,0 +1,304 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/grpcapi"
+	"github.com/IgorKilipenko/metrical/internal/idempotency"
+	"github.com/IgorKilipenko/metrical/internal/repository"
+	"github.com/IgorKilipenko/metrical/internal/service"
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	pb "github.com/IgorKilipenko/metrical/pkg/metricalpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// startTestServer запускает сервер на свободном порту и возвращает клиента
+func startTestServer(t *testing.T, config *ServerConfig) pb.MetricsServiceClient {
+	t.Helper()
+
+	mockLogger := testutils.NewMockLogger()
+	repo := repository.NewInMemoryMetricsRepository(mockLogger, "", false)
+	metricsService := service.NewMetricsService(repo, mockLogger)
+
+	config.Addr = "127.0.0.1:0"
+	server, err := NewServerWithConfig(config, metricsService, mockLogger)
+	require.NoError(t, err)
+	require.NoError(t, server.Listen())
+
+	go server.Start()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	})
+
+	conn, err := grpc.NewClient(server.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewMetricsServiceClient(conn)
+}
+
+// gauge создает метрику gauge
+func gauge(id string, value float64) *pb.Metric {
+	return &pb.Metric{Id: id, Type: pb.MetricType_METRIC_TYPE_GAUGE, Value: &value}
+}
+
+// counter создает метрику counter
+func counter(id string, delta int64) *pb.Metric {
+	return &pb.Metric{Id: id, Type: pb.MetricType_METRIC_TYPE_COUNTER, Delta: &delta}
+}
+
+// withToken добавляет bearer токен в метаданные вызова
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, grpcapi.AuthorizationKey, "Bearer "+token)
+}
+
+func TestServer_MetricsService(t *testing.T) {
+	client := startTestServer(t, DefaultServerConfig())
+	ctx := context.Background()
+
+	t.Run("UpdateMetric returns updated value", func(t *testing.T) {
+		_, err := client.UpdateMetric(ctx, &pb.UpdateMetricRequest{Metric: counter("requests", 3)})
+		require.NoError(t, err)
+
+		resp, err := client.UpdateMetric(ctx, &pb.UpdateMetricRequest{Metric: counter("requests", 2)})
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), resp.GetMetric().GetDelta(), "Counter should accumulate deltas")
+	})
+
+	t.Run("UpdateMetrics and GetMetric", func(t *testing.T) {
+		resp, err := client.UpdateMetrics(ctx, &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{
+			gauge("temperature", 21.5),
+			counter("requests", 1),
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), resp.GetUpdated())
+
+		metric, err := client.GetMetric(ctx, &pb.GetMetricRequest{Id: "temperature", Type: pb.MetricType_METRIC_TYPE_GAUGE})
+		require.NoError(t, err)
+		assert.Equal(t, 21.5, metric.GetMetric().GetValue())
+	})
+
+	t.Run("PushMetrics writes every batch", func(t *testing.T) {
+		stream, err := client.PushMetrics(ctx)
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&pb.PushMetricsRequest{Metrics: []*pb.Metric{counter("pushed", 1), gauge("load", 0.5)}}))
+		require.NoError(t, stream.Send(&pb.PushMetricsRequest{Metrics: []*pb.Metric{counter("pushed", 4)}}))
+
+		resp, err := stream.CloseAndRecv()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), resp.GetBatches())
+		assert.Equal(t, int64(3), resp.GetUpdated())
+
+		metric, err := client.GetMetric(ctx, &pb.GetMetricRequest{Id: "pushed", Type: pb.MetricType_METRIC_TYPE_COUNTER})
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), metric.GetMetric().GetDelta())
+	})
+
+	t.Run("ListMetrics is sorted and filtered by type", func(t *testing.T) {
+		resp, err := client.ListMetrics(ctx, &pb.ListMetricsRequest{})
+		require.NoError(t, err)
+
+		var ids []string
+		for _, metric := range resp.GetMetrics() {
+			ids = append(ids, metric.GetId())
+		}
+		assert.Equal(t, []string{"load", "pushed", "requests", "temperature"}, ids)
+
+		resp, err = client.ListMetrics(ctx, &pb.ListMetricsRequest{Type: pb.MetricType_METRIC_TYPE_GAUGE})
+		require.NoError(t, err)
+		assert.Len(t, resp.GetMetrics(), 2)
+	})
+
+	t.Run("Errors map to status codes", func(t *testing.T) {
+		_, err := client.GetMetric(ctx, &pb.GetMetricRequest{Id: "missing", Type: pb.MetricType_METRIC_TYPE_GAUGE})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = client.GetMetric(ctx, &pb.GetMetricRequest{Id: "requests"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Type is required")
+
+		_, err = client.UpdateMetric(ctx, &pb.UpdateMetricRequest{Metric: &pb.Metric{Id: "temperature", Type: pb.MetricType_METRIC_TYPE_GAUGE}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Gauge without value")
+
+		_, err = client.UpdateMetrics(ctx, &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{gauge("ok", 1), {Id: "bad", Type: pb.MetricType_METRIC_TYPE_COUNTER}}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.GetMetric(ctx, &pb.GetMetricRequest{Id: "ok", Type: pb.MetricType_METRIC_TYPE_GAUGE})
+		assert.Equal(t, codes.NotFound, status.Code(err), "Invalid batch should not be applied")
+	})
+}
+
+func TestServer_Auth(t *testing.T) {
+	store, err := auth.NewStore([]auth.Token{
+		{Name: "agent", Token: "write-token", Scope: auth.ScopeWrite, Prefix: "app."},
+		{Name: "dashboard", Token: "read-token", Scope: auth.ScopeRead},
+	})
+	require.NoError(t, err)
+
+	config := DefaultServerConfig()
+	config.Auth = store
+	client := startTestServer(t, config)
+	ctx := context.Background()
+
+	_, err = client.UpdateMetric(ctx, &pb.UpdateMetricRequest{Metric: gauge("app.load", 1)})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "Token is required")
+
+	_, err = client.UpdateMetric(withToken(ctx, "other-token"), &pb.UpdateMetricRequest{Metric: gauge("app.load", 1)})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "Unknown token")
+
+	_, err = client.UpdateMetric(withToken(ctx, "read-token"), &pb.UpdateMetricRequest{Metric: gauge("app.load", 1)})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "Read token cannot write")
+
+	_, err = client.UpdateMetric(withToken(ctx, "write-token"), &pb.UpdateMetricRequest{Metric: gauge("other.load", 1)})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "Metric outside token prefix")
+
+	_, err = client.UpdateMetric(withToken(ctx, "write-token"), &pb.UpdateMetricRequest{Metric: gauge("app.load", 1)})
+	require.NoError(t, err)
+
+	_, err = client.ListMetrics(withToken(ctx, "write-token"), &pb.ListMetricsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "Write token cannot read")
+
+	resp, err := client.ListMetrics(withToken(ctx, "read-token"), &pb.ListMetricsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, resp.GetMetrics(), 1)
+
+	stream, err := client.PushMetrics(ctx)
+	require.NoError(t, err)
+	_, err = stream.CloseAndRecv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "Stream requires token")
+}
+
+func TestServer_Signature(t *testing.T) {
+	const key = "secret"
+
+	config := DefaultServerConfig()
+	config.SigningKey = key
+	client := startTestServer(t, config)
+	ctx := context.Background()
+
+	_, err := client.UpdateMetric(ctx, &pb.UpdateMetricRequest{Metric: gauge("load", 1)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "Unsigned metric should be rejected")
+
+	tampered := gauge("load", 1)
+	grpcapi.SignMetric(tampered, key)
+	tampered.Value = new(float64)
+	_, err = client.UpdateMetric(ctx, &pb.UpdateMetricRequest{Metric: tampered})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "Tampered metric should be rejected")
+
+	signed := gauge("load", 1)
+	grpcapi.SignMetric(signed, key)
+	resp, err := client.UpdateMetric(ctx, &pb.UpdateMetricRequest{Metric: signed})
+	require.NoError(t, err)
+	assert.True(t, grpcapi.VerifyMetric(resp.GetMetric(), key), "Response metric should be signed")
+
+	stream, err := client.PushMetrics(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.PushMetricsRequest{Metrics: []*pb.Metric{counter("pushed", 1)}}))
+	_, err = stream.CloseAndRecv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "Unsigned stream message should be rejected")
+}
+
+func TestServer_TrustedSubnet(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	config := DefaultServerConfig()
+	config.TrustedSubnet = subnet
+	client := startTestServer(t, config)
+	ctx := context.Background()
+
+	_, err = client.UpdateMetric(ctx, &pb.UpdateMetricRequest{Metric: gauge("load", 1)})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "Loopback is outside trusted subnet")
+
+	spoofed := metadata.AppendToOutgoingContext(ctx, grpcapi.RealIPKey, "10.1.2.3")
+	_, err = client.UpdateMetric(spoofed, &pb.UpdateMetricRequest{Metric: gauge("load", 1)})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "x-real-ip must not admit a connection from outside the subnet")
+
+	_, err = client.GetMetric(ctx, &pb.GetMetricRequest{Id: "load", Type: pb.MetricType_METRIC_TYPE_GAUGE})
+	assert.Equal(t, codes.NotFound, status.Code(err), "Read subnet is not restricted")
+
+	_, loopback, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	config.TrustedSubnet = loopback
+	client = startTestServer(t, config)
+
+	trusted := metadata.AppendToOutgoingContext(ctx, grpcapi.RealIPKey, "127.0.0.2")
+	_, err = client.UpdateMetric(trusted, &pb.UpdateMetricRequest{Metric: gauge("load", 1)})
+	require.NoError(t, err)
+
+	outside := metadata.AppendToOutgoingContext(ctx, grpcapi.RealIPKey, "10.1.2.3")
+	_, err = client.UpdateMetric(outside, &pb.UpdateMetricRequest{Metric: gauge("load", 1)})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "x-real-ip outside subnet is rejected")
+}
+
+func TestServer_Idempotency(t *testing.T) {
+	store, err := idempotency.NewStore(nil)
+	require.NoError(t, err)
+
+	config := DefaultServerConfig()
+	config.Idempotency = store
+	client := startTestServer(t, config)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), grpcapi.IdempotencyKey, "batch-1")
+	req := &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{counter("requests", 5)}}
+
+	_, err = client.UpdateMetrics(ctx, req)
+	require.NoError(t, err)
+
+	// Повтор после неоднозначной ошибки не применяет приращение повторно
+	var header metadata.MD
+	_, err = client.UpdateMetrics(ctx, req, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"true"}, header.Get(grpcapi.IdempotentReplayedKey))
+
+	metric, err := client.GetMetric(context.Background(), &pb.GetMetricRequest{Id: "requests", Type: pb.MetricType_METRIC_TYPE_COUNTER})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), metric.GetMetric().GetDelta())
+
+	stream, err := client.PushMetrics(ctx)
+	require.NoError(t, err)
+	_, err = stream.CloseAndRecv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "Streams do not support idempotency keys")
+}
+
+func TestMethodsCoverService(t *testing.T) {
+	// Каждый метод сервиса должен проходить проверки записи или чтения
+	for _, method := range pb.MetricsService_ServiceDesc.Methods {
+		name := "/" + pb.MetricsService_ServiceDesc.ServiceName + "/" + method.MethodName
+		assert.True(t, WriteMethods[name] != ReadMethods[name], "method %s", name)
+	}
+	for _, stream := range pb.MetricsService_ServiceDesc.Streams {
+		name := "/" + pb.MetricsService_ServiceDesc.ServiceName + "/" + stream.StreamName
+		assert.True(t, WriteMethods[name] != ReadMethods[name], "method %s", name)
+	}
+}
+
+func TestNewServerWithConfig_Errors(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repo := repository.NewInMemoryMetricsRepository(mockLogger, "", false)
+	metricsService := service.NewMetricsService(repo, mockLogger)
+
+	_, err := NewServerWithConfig(nil, metricsService, mockLogger)
+	assert.Error(t, err)
+
+	_, err = NewServerWithConfig(&ServerConfig{}, metricsService, mockLogger)
+	assert.Error(t, err, "Address is required")
+
+	_, err = NewServerWithConfig(DefaultServerConfig(), nil, mockLogger)
+	assert.Error(t, err, "Service is required")
+
+	config := DefaultServerConfig()
+	config.TLSClientCAFile = "ca.pem"
+	_, err = NewServerWithConfig(config, metricsService, mockLogger)
+	assert.Error(t, err, "Client CA requires certificate")
+}
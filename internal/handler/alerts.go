@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/IgorKilipenko/metrical/internal/alerting"
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+)
+
+// AlertsProvider источник текущего состояния правил алертинга (реализуется alerting.Engine)
+type AlertsProvider interface {
+	Alerts() []alerting.Alert
+}
+
+// AlertsResponse ответ эндпоинта /api/v1/alerts
+type AlertsResponse struct {
+	Alerts []alerting.Alert `json:"alerts"`
+}
+
+// EnableAlerts подключает движок правил алертинга к эндпоинту /api/v1/alerts
+func (h *MetricsHandler) EnableAlerts(provider AlertsProvider) error {
+	if provider == nil {
+		return fmt.Errorf("alerts provider cannot be nil")
+	}
+	h.alerts = provider
+	return nil
+}
+
+// GetAlerts возвращает состояние правил алертинга в JSON формате.
+// Токен с префиксом видит только правила по метрикам внутри префикса.
+func (h *MetricsHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("processing get alerts request",
+		"method", r.Method,
+		"url", r.URL.Path,
+		"remote_addr", r.RemoteAddr)
+
+	if h.alerts == nil {
+		h.writeProblem(w, r, problem.New(http.StatusNotImplemented, "alerting is disabled"))
+		return
+	}
+
+	alerts := filterAllowedAlerts(r, h.alerts.Alerts())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(AlertsResponse{Alerts: alerts}); err != nil {
+		h.logger.Error("failed to encode response", "error", err)
+		return
+	}
+
+	h.logger.Info("alerts retrieved successfully", "count", len(alerts))
+}
+
+// filterAllowedAlerts оставляет только алерты по метрикам, доступным токену запроса
+func filterAllowedAlerts(r *http.Request, alerts []alerting.Alert) []alerting.Alert {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok || principal.Prefix == "" {
+		return alerts
+	}
+
+	allowed := make([]alerting.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		if principal.AllowsMetric(alert.Rule.Metric) {
+			allowed = append(allowed, alert)
+		}
+	}
+	return allowed
+}
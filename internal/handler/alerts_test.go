@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/alerting"
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticAlerts фиксированный набор алертов
+type staticAlerts []alerting.Alert
+
+func (a staticAlerts) Alerts() []alerting.Alert {
+	return a
+}
+
+func TestMetricsHandler_GetAlerts(t *testing.T) {
+	handler := createTestHandler()
+	value := 600.0
+	require.NoError(t, handler.EnableAlerts(staticAlerts{
+		{Rule: alerting.Rule{Name: "HighHeap", Expr: "gauge host1.HeapAlloc > 500", MetricType: "gauge", Metric: "host1.HeapAlloc"}, State: alerting.StateFiring, Value: &value},
+		{Rule: alerting.Rule{Name: "Stalled", Expr: "counter host2.PollCount stops increasing for 5m", MetricType: "counter", Metric: "host2.PollCount"}, State: alerting.StateInactive},
+	}))
+
+	get := func(principal *auth.Principal) AlertsResponse {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/v1/alerts", nil)
+		if principal != nil {
+			req = withPrincipal(req, principal)
+		}
+		w := httptest.NewRecorder()
+		handler.GetAlerts(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var response AlertsResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		return response
+	}
+
+	response := get(nil)
+	require.Len(t, response.Alerts, 2)
+	assert.Equal(t, alerting.StateFiring, response.Alerts[0].State)
+	assert.Equal(t, 600.0, *response.Alerts[0].Value)
+
+	response = get(&auth.Principal{Name: "dashboard", Scope: auth.ScopeRead, Prefix: "host2."})
+	require.Len(t, response.Alerts, 1, "Token prefix should hide alerts on other metrics")
+	assert.Equal(t, "Stalled", response.Alerts[0].Rule.Name)
+
+	assert.Error(t, handler.EnableAlerts(nil))
+}
+
+func TestMetricsHandler_GetAlerts_Disabled(t *testing.T) {
+	handler := createTestHandler()
+
+	req := httptest.NewRequest("GET", "/api/v1/alerts", nil)
+	w := httptest.NewRecorder()
+	handler.GetAlerts(w, req)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+}
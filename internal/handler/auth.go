@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+)
+
+// principalName возвращает имя владельца токена запроса для логов (пустая строка без аутентификации)
+func principalName(r *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		return principal.Name
+	}
+	return ""
+}
+
+// authorizeMetric проверяет, что токен запроса разрешает доступ к метрике.
+// При отказе возвращает ошибку со статусом 403; запросы без аутентификации не ограничиваются.
+func (h *MetricsHandler) authorizeMetric(r *http.Request, name string) error {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok || principal.AllowsMetric(name) {
+		return nil
+	}
+
+	h.logger.Warn("metric access denied by token prefix",
+		"principal", principal.Name,
+		"prefix", principal.Prefix,
+		"name", name,
+		"url", r.URL.Path)
+	return problem.New(http.StatusForbidden, "metric is outside token prefix")
+}
+
+// filterAllowedMetrics оставляет только метрики, доступные токену запроса
+func filterAllowedMetrics(r *http.Request, gauges models.GaugeMetrics, counters models.CounterMetrics) (models.GaugeMetrics, models.CounterMetrics) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok || principal.Prefix == "" {
+		return gauges, counters
+	}
+
+	allowedGauges := make(models.GaugeMetrics)
+	for name, value := range gauges {
+		if principal.AllowsMetric(name) {
+			allowedGauges[name] = value
+		}
+	}
+
+	allowedCounters := make(models.CounterMetrics)
+	for name, value := range counters {
+		if principal.AllowsMetric(name) {
+			allowedCounters[name] = value
+		}
+	}
+
+	return allowedGauges, allowedCounters
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withPrincipal добавляет владельца токена в контекст запроса, как это делает AuthMiddleware
+func withPrincipal(r *http.Request, principal *auth.Principal) *http.Request {
+	return r.WithContext(auth.WithPrincipal(r.Context(), principal))
+}
+
+func TestMetricsHandler_TokenPrefix_Updates(t *testing.T) {
+	handler := createTestHandler()
+	principal := &auth.Principal{Name: "agent", Scope: auth.ScopeWrite, Prefix: "host1."}
+
+	post := func(handlerFunc http.HandlerFunc, path, body string) int {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handlerFunc(w, withPrincipal(req, principal))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, post(handler.UpdateMetricJSON, "/update", `{"id": "host1.requests", "type": "counter", "delta": 1}`))
+	assert.Equal(t, http.StatusForbidden, post(handler.UpdateMetricJSON, "/update", `{"id": "host2.requests", "type": "counter", "delta": 1}`))
+
+	// Недоступная метрика отклоняет весь пакет
+	assert.Equal(t, http.StatusForbidden, post(handler.UpdateMetricsBatch, "/updates",
+		`[{"id": "host1.requests", "type": "counter", "delta": 1}, {"id": "host2.requests", "type": "counter", "delta": 1}]`))
+	assert.Equal(t, int64(1), counterValue(t, handler, "host1.requests"))
+
+	req, w := createChiContext("/update/counter/host2.requests/1", map[string]string{
+		"type":  "counter",
+		"name":  "host2.requests",
+		"value": "1",
+	})
+	req.Method = "POST"
+	handler.UpdateMetric(w, withPrincipal(req, principal))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	_, exists, err := handler.service.GetCounter(context.Background(), "host2.requests")
+	require.NoError(t, err)
+	assert.False(t, exists, "Metrics outside token prefix must not be written")
+}
+
+func TestMetricsHandler_TokenPrefix_Reads(t *testing.T) {
+	handler := createTestHandler()
+	require.Equal(t, http.StatusOK, postJSONWithKey(handler.UpdateMetricJSON, "/update", `{"id": "host1.cpu", "type": "gauge", "value": 1.5}`, "").Code)
+	require.Equal(t, http.StatusOK, postJSONWithKey(handler.UpdateMetricJSON, "/update", `{"id": "host2.cpu", "type": "gauge", "value": 2.5}`, "").Code)
+
+	principal := &auth.Principal{Name: "dashboard", Scope: auth.ScopeRead, Prefix: "host1."}
+
+	t.Run("value", func(t *testing.T) {
+		req, w := createChiContext("/value/gauge/host2.cpu", map[string]string{"type": "gauge", "name": "host2.cpu"})
+		handler.GetMetricValue(w, withPrincipal(req, principal))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		req, w = createChiContext("/value/gauge/host1.cpu", map[string]string{"type": "gauge", "name": "host1.cpu"})
+		handler.GetMetricValue(w, withPrincipal(req, principal))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("JSON value", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/value", strings.NewReader(`{"id": "host2.cpu", "type": "gauge"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.GetMetricJSON(w, withPrincipal(req, principal))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("all metrics are filtered", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+		handler.GetAllMetrics(w, withPrincipal(req, principal))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "host1.cpu")
+		assert.NotContains(t, w.Body.String(), "host2.cpu")
+	})
+}
+
+func TestMetricsHandler_Idempotency_PerPrincipal(t *testing.T) {
+	handler := createTestIdempotentHandler(t)
+	body := `{"id": "PollCount", "type": "counter", "delta": 5}`
+
+	post := func(principal *auth.Principal) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/update", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(IdempotencyKeyHeader, "shared-key")
+		w := httptest.NewRecorder()
+		handler.UpdateMetricJSON(w, withPrincipal(req, principal))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, post(&auth.Principal{Name: "agent-1", Scope: auth.ScopeWrite}).Code)
+	second := post(&auth.Principal{Name: "agent-2", Scope: auth.ScopeWrite})
+	assert.Equal(t, http.StatusUnprocessableEntity, second.Code, "Key of another principal must not be replayed")
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+	"github.com/IgorKilipenko/metrical/internal/service"
+)
+
+// errorWriter отправляет клиенту ошибку обработки запроса в формате эндпоинта
+type errorWriter func(w http.ResponseWriter, r *http.Request, err error)
+
+// badRequest возвращает ошибку некорректного запроса (400) с описанием для клиента
+func badRequest(format string, args ...any) error {
+	return problem.New(http.StatusBadRequest, fmt.Sprintf(format, args...))
+}
+
+// metricNotFound возвращает ошибку отсутствующей метрики
+func metricNotFound(metricType, name string) error {
+	return fmt.Errorf("%s %w: %s", metricType, models.ErrMetricNotFound, name)
+}
+
+// problemFromError сопоставляет ошибку с HTTP статусом и описанием RFC 7807.
+// Это единственное место, где типизированные ошибки сервиса превращаются в коды ответа;
+// текст внутренних ошибок (5xx) клиенту не передается.
+func problemFromError(err error) *problem.Problem {
+	var p *problem.Problem
+	if errors.As(err, &p) {
+		result := *p
+		return &result
+	}
+
+	var validationErr models.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return &problem.Problem{
+			Type:   problem.TypeValidation,
+			Title:  "Validation failed",
+			Status: http.StatusBadRequest,
+			Detail: err.Error(),
+			Errors: []problem.FieldError{{
+				Field:   validationErr.Field,
+				Value:   validationErr.Value,
+				Message: validationErr.Message,
+			}},
+		}
+	case errors.Is(err, models.ErrUnsupportedMetricType):
+		return &problem.Problem{
+			Type:   problem.TypeUnsupportedType,
+			Title:  "Unsupported metric type",
+			Status: http.StatusBadRequest,
+			Detail: err.Error(),
+		}
+	case errors.Is(err, models.ErrMetricNotFound):
+		return &problem.Problem{
+			Type:   problem.TypeNotFound,
+			Title:  "Metric not found",
+			Status: http.StatusNotFound,
+			Detail: err.Error(),
+		}
+	case errors.Is(err, models.ErrStorageUnavailable):
+		return &problem.Problem{
+			Type:   problem.TypeStorageUnavailable,
+			Title:  "Storage unavailable",
+			Status: http.StatusServiceUnavailable,
+			Detail: "metrics storage is temporarily unavailable",
+		}
+	case errors.Is(err, service.ErrHistoryDisabled):
+		return problem.New(http.StatusNotImplemented, "metrics history is disabled")
+	case isBodyTooLarge(err):
+		return problem.New(http.StatusRequestEntityTooLarge, "request body too large")
+	default:
+		return problem.New(http.StatusInternalServerError, "")
+	}
+}
+
+// writeProblem отправляет ошибку JSON эндпоинта в формате application/problem+json
+func (h *MetricsHandler) writeProblem(w http.ResponseWriter, r *http.Request, err error) {
+	p := problemFromError(err)
+	p.Instance = r.URL.Path
+
+	if writeErr := problem.Write(w, p); writeErr != nil {
+		h.logger.Error("failed to write problem response", "error", writeErr)
+	}
+}
+
+// writeTextError отправляет ошибку текстового эндпоинта (legacy API и HTML страница) как text/plain
+func (h *MetricsHandler) writeTextError(w http.ResponseWriter, r *http.Request, err error) {
+	p := problemFromError(err)
+	http.Error(w, p.Error(), p.Status)
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+	"github.com/IgorKilipenko/metrical/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemFromError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedType   string
+	}{
+		{
+			name:           "validation error",
+			err:            models.ValidationError{Field: "id", Value: "", Message: "is required"},
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   problem.TypeValidation,
+		},
+		{
+			name:           "wrapped validation error",
+			err:            fmt.Errorf("batch rejected: %w", models.ValidationError{Field: "metrics[1].delta", Value: "null", Message: "is required"}),
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   problem.TypeValidation,
+		},
+		{
+			name:           "unsupported metric type",
+			err:            fmt.Errorf("%w: histogram", models.ErrUnsupportedMetricType),
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   problem.TypeUnsupportedType,
+		},
+		{
+			name:           "metric not found",
+			err:            metricNotFound(models.Gauge, "Alloc"),
+			expectedStatus: http.StatusNotFound,
+			expectedType:   problem.TypeNotFound,
+		},
+		{
+			name:           "storage unavailable",
+			err:            fmt.Errorf("failed to select gauge metric: %w: dial tcp: connection refused", models.ErrStorageUnavailable),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedType:   problem.TypeStorageUnavailable,
+		},
+		{
+			name:           "history disabled",
+			err:            service.ErrHistoryDisabled,
+			expectedStatus: http.StatusNotImplemented,
+			expectedType:   problem.TypeDefault,
+		},
+		{
+			name:           "body too large",
+			err:            fmt.Errorf("failed to decode: %w", &http.MaxBytesError{Limit: 64}),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedType:   problem.TypeDefault,
+		},
+		{
+			name:           "explicit problem",
+			err:            badRequest("invalid 'step' parameter"),
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   problem.TypeDefault,
+		},
+		{
+			name:           "internal error",
+			err:            errors.New("pq: relation \"metrics\" does not exist"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedType:   problem.TypeDefault,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := problemFromError(tt.err)
+
+			assert.Equal(t, tt.expectedStatus, p.Status)
+			assert.Equal(t, tt.expectedType, p.Type)
+			assert.NotEmpty(t, p.Title)
+		})
+	}
+}
+
+func TestProblemFromError_HidesInternalDetails(t *testing.T) {
+	storageErr := fmt.Errorf("%w: dial tcp 10.0.0.5:5432: connection refused", models.ErrStorageUnavailable)
+	assert.NotContains(t, problemFromError(storageErr).Detail, "10.0.0.5")
+
+	internalErr := errors.New("pq: relation \"metrics\" does not exist")
+	assert.Empty(t, problemFromError(internalErr).Detail)
+}
+
+func TestProblemFromError_DoesNotShareExplicitProblem(t *testing.T) {
+	original := problem.New(http.StatusForbidden, "metric is outside token prefix")
+
+	p := problemFromError(original)
+	p.Instance = "/update"
+
+	assert.Empty(t, original.Instance, "Mapping must copy the problem before filling request fields")
+}
+
+func TestMetricsHandler_ErrorFormats(t *testing.T) {
+	handler := createTestHandler()
+
+	t.Run("json endpoint returns problem", func(t *testing.T) {
+		w := postJSONWithKey(handler.UpdateMetricsBatch, "/updates",
+			`[{"id": "TestGauge", "type": "gauge", "value": 1}, {"id": "TestCounter", "type": "counter"}]`, "")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assertProblem(t, w, http.StatusBadRequest, problem.TypeValidation, "metrics[1].delta")
+		assert.Contains(t, w.Body.String(), `"instance":"/updates"`)
+	})
+
+	t.Run("legacy endpoint keeps plain text", func(t *testing.T) {
+		r, w := createChiContext("/value/gauge/Unknown", map[string]string{"type": "gauge", "name": "Unknown"})
+		handler.GetMetricValue(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain"))
+		assert.Equal(t, "gauge metric not found: Unknown\n", w.Body.String())
+	})
+
+	t.Run("history endpoint returns problem", func(t *testing.T) {
+		r, w := createChiContext("/api/v1/history/gauge/HeapAlloc", map[string]string{"type": "gauge", "name": "HeapAlloc"})
+		handler.GetMetricHistory(w, r)
+
+		assertProblem(t, w, http.StatusNotImplemented, problem.TypeDefault, "")
+	})
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/exposition"
+)
+
+// GetPrometheusMetrics отдает все метрики в текстовом формате Prometheus
+// или в OpenMetrics, если он запрошен заголовком Accept
+func (h *MetricsHandler) GetPrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	format := exposition.Negotiate(r.Header.Get("Accept"))
+
+	h.logger.Info("processing prometheus scrape request",
+		"method", r.Method,
+		"url", r.URL.Path,
+		"format", format,
+		"remote_addr", r.RemoteAddr)
+
+	gauges, err := h.service.GetAllGauges(ctx)
+	if err != nil {
+		h.logger.Error("failed to get all gauges", "error", err)
+		h.writeTextError(w, r, err)
+		return
+	}
+
+	counters, err := h.service.GetAllCounters(ctx)
+	if err != nil {
+		h.logger.Error("failed to get all counters", "error", err)
+		h.writeTextError(w, r, err)
+		return
+	}
+
+	// Токен с префиксом видит только свои метрики
+	gauges, counters = filterAllowedMetrics(r, gauges, counters)
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.WriteHeader(http.StatusOK)
+
+	skipped, err := exposition.Write(w, format, gauges, counters)
+	if err != nil {
+		h.logger.Error("failed to write metrics exposition", "error", err)
+		return
+	}
+	if len(skipped) > 0 {
+		h.logger.Warn("metrics skipped due to name collisions after sanitizing", "names", skipped)
+	}
+
+	h.logger.Info("prometheus metrics exposed successfully",
+		"gauge_count", len(gauges),
+		"counter_count", len(counters))
+}
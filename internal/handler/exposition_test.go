@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/exposition"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsHandler_GetPrometheusMetrics(t *testing.T) {
+	handler := createTestHandler()
+	require.Equal(t, http.StatusOK, postJSONWithKey(handler.UpdateMetricJSON, "/update", `{"id": "host1.HeapAlloc", "type": "gauge", "value": 1024}`, "").Code)
+	require.Equal(t, http.StatusOK, postJSONWithKey(handler.UpdateMetricJSON, "/update", `{"id": "host2.PollCount", "type": "counter", "delta": 5}`, "").Code)
+
+	scrape := func(accept string, principal *auth.Principal) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if principal != nil {
+			req = withPrincipal(req, principal)
+		}
+		w := httptest.NewRecorder()
+		handler.GetPrometheusMetrics(w, req)
+		return w
+	}
+
+	t.Run("Text format", func(t *testing.T) {
+		w := scrape("text/plain;version=0.0.4;q=0.5,*/*;q=0.1", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, exposition.ContentTypeText, w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "# TYPE host1_HeapAlloc gauge\nhost1_HeapAlloc 1024\n")
+		assert.Contains(t, w.Body.String(), "# TYPE host2_PollCount_total counter\nhost2_PollCount_total 5\n")
+		assert.NotContains(t, w.Body.String(), "# EOF")
+	})
+
+	t.Run("OpenMetrics", func(t *testing.T) {
+		w := scrape("application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, exposition.ContentTypeOpenMetrics, w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "# TYPE host2_PollCount counter\nhost2_PollCount_total 5\n")
+		assert.Contains(t, w.Body.String(), "# EOF\n")
+	})
+
+	t.Run("Token prefix", func(t *testing.T) {
+		w := scrape("", &auth.Principal{Name: "prometheus", Scope: auth.ScopeRead, Prefix: "host1."})
+
+		assert.Contains(t, w.Body.String(), "host1_HeapAlloc")
+		assert.NotContains(t, w.Body.String(), "host2_PollCount")
+	})
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/IgorKilipenko/metrical/internal/idempotency"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+)
+
+const (
+	// IdempotencyKeyHeader заголовок с ключом идемпотентности запроса на запись
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader заголовок ответа, повторенного из кэша идемпотентности
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// IdempotencyConfig настройки кэша ключей идемпотентности
+type IdempotencyConfig = idempotency.Config
+
+// DefaultIdempotencyConfig возвращает настройки кэша идемпотентности по умолчанию
+func DefaultIdempotencyConfig() *IdempotencyConfig {
+	return idempotency.DefaultConfig()
+}
+
+// recordedResponse сохраненный результат запроса
+type recordedResponse struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+// responseRecorder передает ответ клиенту и одновременно сохраняет его копию
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+// withIdempotency выполняет обработчик записи не более одного раза для каждого ключа идемпотентности.
+// Повторный запрос с тем же ключом получает сохраненный ответ; запросы без ключа обрабатываются как обычно.
+// Ошибки отправляются через respond в формате обернутого эндпоинта.
+func (h *MetricsHandler) withIdempotency(w http.ResponseWriter, r *http.Request, next http.HandlerFunc, respond errorWriter) {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if h.idempotency == nil || key == "" {
+		next(w, r)
+		return
+	}
+
+	if len(key) > idempotency.MaxKeyLength {
+		h.logger.Warn("idempotency key is too long", "length", len(key))
+		respond(w, r, badRequest("%s must not exceed %d characters", IdempotencyKeyHeader, idempotency.MaxKeyLength))
+		return
+	}
+
+	// Отпечаток запроса защищает от повторного использования ключа с другими данными
+	reader := r.Body
+	if h.maxBodySize > 0 {
+		reader = http.MaxBytesReader(w, r.Body, h.maxBodySize)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		h.logger.Warn("failed to read request body", "error", err)
+		if isBodyTooLarge(err) {
+			respond(w, r, err)
+			return
+		}
+		respond(w, r, badRequest("Failed to read request body"))
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	fingerprint := requestFingerprint(r, body)
+
+	for {
+		entry, owner := h.idempotency.Begin(key, fingerprint)
+		if owner {
+			// Запись завершается даже при панике обработчика, чтобы не блокировать ожидающие запросы
+			var result any
+			defer func() { h.idempotency.Complete(entry, result) }()
+
+			recorder := &responseRecorder{ResponseWriter: w}
+			next(recorder, r)
+			if response := recordResponse(recorder); response != nil {
+				result = response
+			}
+			return
+		}
+
+		if entry.Fingerprint() != fingerprint {
+			h.logger.Warn("idempotency key reused with different request", "key", key, "url", r.URL.Path)
+			respond(w, r, problem.New(http.StatusUnprocessableEntity, fmt.Sprintf("%s was already used with a different request", IdempotencyKeyHeader)))
+			return
+		}
+
+		// Ожидаем завершения запроса с тем же ключом
+		select {
+		case <-entry.Done():
+		case <-r.Context().Done():
+			respond(w, r, problem.New(http.StatusServiceUnavailable, ""))
+			return
+		}
+
+		response, ok := entry.Result().(*recordedResponse)
+		if !ok {
+			// Исходный запрос завершился серверной ошибкой - выполняем заново
+			continue
+		}
+
+		h.logger.Info("replaying idempotent response", "key", key, "url", r.URL.Path, "status", response.status)
+		if response.contentType != "" {
+			w.Header().Set("Content-Type", response.contentType)
+		}
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.WriteHeader(response.status)
+		w.Write(response.body)
+		return
+	}
+}
+
+// recordResponse возвращает сохраняемую копию ответа или nil для серверных ошибок,
+// которые клиент должен иметь возможность повторить
+func recordResponse(recorder *responseRecorder) *recordedResponse {
+	status := recorder.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status >= http.StatusInternalServerError {
+		return nil
+	}
+
+	return &recordedResponse{
+		status:      status,
+		contentType: recorder.Header().Get("Content-Type"),
+		body:        bytes.Clone(recorder.body.Bytes()),
+	}
+}
+
+// requestFingerprint вычисляет отпечаток запроса по владельцу токена, методу, пути и телу.
+// Владелец токена входит в отпечаток, чтобы ключ одного клиента не возвращал ответ другому.
+func requestFingerprint(r *http.Request, body []byte) idempotency.Fingerprint {
+	return idempotency.NewFingerprint([]byte(principalName(r)), []byte(r.Method), []byte(r.URL.Path), body)
+}
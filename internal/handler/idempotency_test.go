@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/idempotency"
+	"github.com/IgorKilipenko/metrical/internal/repository"
+	"github.com/IgorKilipenko/metrical/internal/service"
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestIdempotentHandler создает тестовый handler с включенным кэшем идемпотентности
+func createTestIdempotentHandler(t *testing.T) *MetricsHandler {
+	t.Helper()
+
+	handler := createTestHandler()
+	require.NoError(t, handler.EnableIdempotency(nil))
+	return handler
+}
+
+// postJSONWithKey выполняет JSON запрос на запись с ключом идемпотентности
+func postJSONWithKey(handlerFunc http.HandlerFunc, path, body, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	handlerFunc(w, req)
+	return w
+}
+
+// counterValue возвращает значение counter метрики из сервиса
+func counterValue(t *testing.T, handler *MetricsHandler, name string) int64 {
+	t.Helper()
+
+	value, _, err := handler.service.GetCounter(context.Background(), name)
+	require.NoError(t, err)
+	return value
+}
+
+func TestMetricsHandler_Idempotency_JSON(t *testing.T) {
+	handler := createTestIdempotentHandler(t)
+	body := `{"id": "PollCount", "type": "counter", "delta": 5}`
+
+	first := postJSONWithKey(handler.UpdateMetricJSON, "/update", body, "key-1")
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	// Повтор с тем же ключом не применяет приращение повторно
+	second := postJSONWithKey(handler.UpdateMetricJSON, "/update", body, "key-1")
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, int64(5), counterValue(t, handler, "PollCount"))
+
+	// Новый ключ - новая логическая отправка
+	third := postJSONWithKey(handler.UpdateMetricJSON, "/update", body, "key-2")
+	assert.Equal(t, http.StatusOK, third.Code)
+	assert.Equal(t, int64(10), counterValue(t, handler, "PollCount"))
+}
+
+func TestMetricsHandler_Idempotency_URL(t *testing.T) {
+	handler := createTestIdempotentHandler(t)
+
+	for i := 0; i < 2; i++ {
+		req, w := createChiContext("/update/counter/requests/7", map[string]string{
+			"type":  "counter",
+			"name":  "requests",
+			"value": "7",
+		})
+		req.Method = "POST"
+		req.Header.Set(IdempotencyKeyHeader, "url-key")
+
+		handler.UpdateMetric(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	assert.Equal(t, int64(7), counterValue(t, handler, "requests"))
+}
+
+func TestMetricsHandler_Idempotency_Batch(t *testing.T) {
+	handler := createTestIdempotentHandler(t)
+	body := `[{"id": "TestCounter", "type": "counter", "delta": 3}, {"id": "TestCounter", "type": "counter", "delta": 4}]`
+
+	for i := 0; i < 3; i++ {
+		w := postJSONWithKey(handler.UpdateMetricsBatch, "/updates", body, "batch-key")
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	assert.Equal(t, int64(7), counterValue(t, handler, "TestCounter"))
+}
+
+func TestMetricsHandler_Idempotency_ReplaysClientErrors(t *testing.T) {
+	handler := createTestIdempotentHandler(t)
+	body := `{"id": "PollCount", "type": "counter"}`
+
+	first := postJSONWithKey(handler.UpdateMetricJSON, "/update", body, "bad-key")
+	second := postJSONWithKey(handler.UpdateMetricJSON, "/update", body, "bad-key")
+
+	assert.Equal(t, http.StatusBadRequest, first.Code)
+	assert.Equal(t, http.StatusBadRequest, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String(), "Original response should be replayed")
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+}
+
+func TestMetricsHandler_Idempotency_KeyReusedWithDifferentBody(t *testing.T) {
+	handler := createTestIdempotentHandler(t)
+
+	first := postJSONWithKey(handler.UpdateMetricJSON, "/update", `{"id": "PollCount", "type": "counter", "delta": 5}`, "key")
+	second := postJSONWithKey(handler.UpdateMetricJSON, "/update", `{"id": "PollCount", "type": "counter", "delta": 50}`, "key")
+
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, second.Code)
+	assert.Equal(t, int64(5), counterValue(t, handler, "PollCount"))
+}
+
+func TestMetricsHandler_Idempotency_ServerErrorNotCached(t *testing.T) {
+	handler := createTestIdempotentHandler(t)
+	body := `{"id": "PollCount", "type": "counter", "delta": 5}`
+
+	// Отмененный контекст приводит к ошибке хранилища (500)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("POST", "/update", strings.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, "retry-key")
+	w := httptest.NewRecorder()
+	handler.UpdateMetricJSON(w, req)
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// Повтор после серверной ошибки выполняется заново
+	retry := postJSONWithKey(handler.UpdateMetricJSON, "/update", body, "retry-key")
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Empty(t, retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, int64(5), counterValue(t, handler, "PollCount"))
+}
+
+func TestMetricsHandler_Idempotency_PersistFailureRetried(t *testing.T) {
+	// Синхронное сохранение в отсутствующий каталог завершается ошибкой после изменения в памяти
+	mockLogger := testutils.NewMockLogger()
+	path := filepath.Join(t.TempDir(), "missing", "metrics.json")
+	repo := repository.NewInMemoryMetricsRepository(mockLogger, path, false)
+	repo.SetSyncSave(true)
+	handler, err := NewMetricsHandler(service.NewMetricsService(repo, mockLogger), mockLogger)
+	require.NoError(t, err)
+	require.NoError(t, handler.EnableIdempotency(nil))
+	body := `{"id": "PollCount", "type": "counter", "delta": 5}`
+
+	first := postJSONWithKey(handler.UpdateMetricJSON, "/update", body, "persist-key")
+	require.Equal(t, http.StatusInternalServerError, first.Code)
+	assert.Equal(t, int64(0), counterValue(t, handler, "PollCount"), "Unsaved update must be rolled back")
+
+	// Повтор с тем же ключом после восстановления хранилища применяет приращение один раз
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	retry := postJSONWithKey(handler.UpdateMetricJSON, "/update", body, "persist-key")
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, int64(5), counterValue(t, handler, "PollCount"))
+}
+
+func TestMetricsHandler_Idempotency_Concurrent(t *testing.T) {
+	handler := createTestIdempotentHandler(t)
+	body := `{"id": "PollCount", "type": "counter", "delta": 1}`
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := postJSONWithKey(handler.UpdateMetricJSON, "/update", body, "concurrent-key")
+			assert.Equal(t, http.StatusOK, w.Code)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1), counterValue(t, handler, "PollCount"))
+}
+
+func TestMetricsHandler_Idempotency_WithoutKeyOrDisabled(t *testing.T) {
+	body := `{"id": "PollCount", "type": "counter", "delta": 5}`
+
+	t.Run("request without key", func(t *testing.T) {
+		handler := createTestIdempotentHandler(t)
+		postJSONWithKey(handler.UpdateMetricJSON, "/update", body, "")
+		postJSONWithKey(handler.UpdateMetricJSON, "/update", body, "")
+		assert.Equal(t, int64(10), counterValue(t, handler, "PollCount"))
+	})
+
+	t.Run("idempotency disabled", func(t *testing.T) {
+		handler := createTestHandler()
+		postJSONWithKey(handler.UpdateMetricJSON, "/update", body, "key")
+		postJSONWithKey(handler.UpdateMetricJSON, "/update", body, "key")
+		assert.Equal(t, int64(10), counterValue(t, handler, "PollCount"))
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		handler := createTestIdempotentHandler(t)
+		w := postJSONWithKey(handler.UpdateMetricJSON, "/update", body, strings.Repeat("k", idempotency.MaxKeyLength+1))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, int64(0), counterValue(t, handler, "PollCount"))
+	})
+}
+
+func TestMetricsHandler_EnableIdempotency_Validation(t *testing.T) {
+	handler := createTestHandler()
+
+	assert.Error(t, handler.EnableIdempotency(&IdempotencyConfig{TTL: 0, MaxEntries: 10}))
+	assert.Error(t, handler.EnableIdempotency(&IdempotencyConfig{TTL: time.Minute, MaxEntries: 0}))
+	assert.NoError(t, handler.EnableIdempotency(&IdempotencyConfig{TTL: time.Minute, MaxEntries: 10}))
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/lineprotocol"
+	models "github.com/IgorKilipenko/metrical/internal/model"
+)
+
+// WriteLineProtocol принимает метрики в формате InfluxDB line protocol (POST /api/v2/write)
+func (h *MetricsHandler) WriteLineProtocol(w http.ResponseWriter, r *http.Request) {
+	h.withIdempotency(w, r, h.writeLineProtocol, h.writeProblem)
+}
+
+// writeLineProtocol разбирает точки из тела запроса и записывает все поля одним пакетом.
+// Параметры org и bucket клиентов InfluxDB не используются; временные метки проверяются,
+// но значения сохраняются на момент приема, как и в остальных эндпоинтах записи.
+func (h *MetricsHandler) writeLineProtocol(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("processing line protocol write request",
+		"method", r.Method,
+		"url", r.URL.String(),
+		"remote_addr", r.RemoteAddr)
+
+	precision, err := lineprotocol.ParsePrecision(r.URL.Query().Get("precision"))
+	if err != nil {
+		h.writeProblem(w, r, badRequest("%s", err))
+		return
+	}
+
+	body := r.Body
+	if h.maxBodySize > 0 {
+		body = http.MaxBytesReader(w, r.Body, h.maxBodySize)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		h.logger.Warn("failed to read line protocol body", "error", err)
+		if !isBodyTooLarge(err) {
+			err = badRequest("failed to read request body")
+		}
+		h.writeProblem(w, r, err)
+		return
+	}
+
+	points, err := lineprotocol.Parse(data, precision)
+	if err != nil {
+		h.logger.Warn("failed to parse line protocol", "error", err)
+		h.writeProblem(w, r, badRequest("%s", err))
+		return
+	}
+
+	metrics, skipped := lineprotocol.Metrics(points)
+	if len(skipped) > 0 {
+		h.logger.Debug("string fields skipped", "names", skipped)
+	}
+
+	// Пакет применяется целиком, поэтому недоступная метрика отклоняет весь запрос
+	for _, metric := range metrics {
+		if err := h.authorizeMetric(r, metric.ID); err != nil {
+			h.writeProblem(w, r, err)
+			return
+		}
+	}
+
+	if len(metrics) > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		if err := h.service.UpdateMetricsBatch(ctx, metrics); err != nil {
+			if models.IsValidationError(err) {
+				h.logger.Warn("line protocol batch validation failed", "error", err)
+			} else {
+				h.logger.Error("failed to update line protocol batch", "error", err)
+			}
+			h.writeProblem(w, r, err)
+			return
+		}
+	}
+
+	h.logger.Info("line protocol batch written successfully",
+		"points", len(points),
+		"metrics", len(metrics),
+		"principal", principalName(r))
+	w.WriteHeader(http.StatusNoContent)
+}
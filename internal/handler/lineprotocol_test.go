@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsHandler_WriteLineProtocol(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "telegraf batch",
+			query:          "?org=metrical&bucket=telegraf&precision=s",
+			body:           "cpu,host=server01 usage_idle=98.5,procs=12i 1700000000\nmem,host=server01 used_percent=41.2 1700000000\n",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "only string fields",
+			body:           `event,host=server01 message="deployed"`,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "empty body",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "invalid precision",
+			query:          "?precision=h",
+			body:           "cpu usage=1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed line",
+			body:           "cpu usage=1\ncpu usage\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := createTestHandler()
+
+			req := httptest.NewRequest("POST", "/api/v2/write"+tt.query, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.WriteLineProtocol(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, "Body: %s", w.Body.String())
+			if tt.expectedStatus == http.StatusBadRequest {
+				assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_WriteLineProtocol_Metrics(t *testing.T) {
+	handler := createTestHandler()
+	ctx := context.Background()
+
+	write := func(body string) int {
+		req := httptest.NewRequest("POST", "/api/v2/write", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.WriteLineProtocol(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusNoContent, write("cpu,host=server01 usage_idle=98.5,procs=12i"))
+	require.Equal(t, http.StatusNoContent, write("cpu,host=server01 usage_idle=97,procs=3i"))
+
+	usage, exists, err := handler.service.GetGauge(ctx, "cpu.host=server01.usage_idle")
+	require.NoError(t, err)
+	require.True(t, exists)
+	assert.Equal(t, 97.0, usage, "Float fields replace gauge value")
+
+	procs, exists, err := handler.service.GetCounter(ctx, "cpu.host=server01.procs")
+	require.NoError(t, err)
+	require.True(t, exists)
+	assert.Equal(t, int64(15), procs, "Integer fields are added to counter")
+}
+
+func TestMetricsHandler_WriteLineProtocol_TokenPrefix(t *testing.T) {
+	handler := createTestHandler()
+	principal := &auth.Principal{Name: "telegraf", Scope: auth.ScopeWrite, Prefix: "cpu."}
+
+	req := httptest.NewRequest("POST", "/api/v2/write", strings.NewReader("cpu usage=1\nmem used=2"))
+	w := httptest.NewRecorder()
+	handler.WriteLineProtocol(w, withPrincipal(req, principal))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	_, exists, err := handler.service.GetGauge(context.Background(), "cpu.usage")
+	require.NoError(t, err)
+	assert.False(t, exists, "Rejected request must not write any field")
+}
+
+func TestMetricsHandler_WriteLineProtocol_BodyTooLarge(t *testing.T) {
+	handler := createTestHandler()
+	require.NoError(t, handler.SetMaxBodySize(16))
+
+	req := httptest.NewRequest("POST", "/api/v2/write", strings.NewReader("cpu,host=server01 usage_idle=98.5"))
+	w := httptest.NewRecorder()
+	handler.WriteLineProtocol(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
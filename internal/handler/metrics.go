@@ -3,24 +3,38 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"strconv"
 	"time"
 
+	"github.com/IgorKilipenko/metrical/internal/idempotency"
 	"github.com/IgorKilipenko/metrical/internal/logger"
 	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/otlp"
+	"github.com/IgorKilipenko/metrical/internal/remotewrite"
 	"github.com/IgorKilipenko/metrical/internal/service"
 	"github.com/IgorKilipenko/metrical/internal/template"
 	"github.com/IgorKilipenko/metrical/internal/validation"
 	"github.com/go-chi/chi/v5"
 )
 
+// DefaultMaxBodySize максимальный размер JSON тела запроса по умолчанию
+const DefaultMaxBodySize int64 = 16 << 20
+
 // MetricsHandler обработчик HTTP запросов для метрик
 type MetricsHandler struct {
-	service  *service.MetricsService
-	template *template.MetricsTemplate
-	logger   logger.Logger
+	service     *service.MetricsService
+	template    *template.MetricsTemplate
+	logger      logger.Logger
+	idempotency *idempotency.Store // nil - ключи идемпотентности не учитываются
+	maxBodySize int64              // Максимальный размер JSON тела запроса (0 - без ограничений)
+	alerts      AlertsProvider     // nil - алертинг отключен
+
+	remoteWrite       *remotewrite.Receiver
+	remoteWriteConfig remotewrite.Config // Ограничения размера запросов remote_write
+	otlp              *otlp.Receiver
 }
 
 // NewMetricsHandler создает новый экземпляр MetricsHandler
@@ -37,15 +51,59 @@ func NewMetricsHandler(service *service.MetricsService, logger logger.Logger) (*
 		return nil, fmt.Errorf("failed to create metrics template: %w", err)
 	}
 
+	remoteWrite, err := remotewrite.NewReceiver(service, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create remote write receiver: %w", err)
+	}
+
+	otlpReceiver, err := otlp.NewReceiver(service, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP receiver: %w", err)
+	}
+
 	return &MetricsHandler{
-		service:  service,
-		template: template,
-		logger:   logger,
+		service:           service,
+		template:          template,
+		logger:            logger,
+		maxBodySize:       DefaultMaxBodySize,
+		remoteWrite:       remoteWrite,
+		remoteWriteConfig: *remotewrite.DefaultConfig(),
+		otlp:              otlpReceiver,
 	}, nil
 }
 
+// SetMaxBodySize задает максимальный размер JSON тела запроса (0 - без ограничений)
+func (h *MetricsHandler) SetMaxBodySize(size int64) error {
+	if size < 0 {
+		return fmt.Errorf("max body size cannot be negative")
+	}
+	h.maxBodySize = size
+	return nil
+}
+
+// EnableIdempotency включает обработку заголовка Idempotency-Key для запросов на запись
+func (h *MetricsHandler) EnableIdempotency(config *IdempotencyConfig) error {
+	store, err := idempotency.NewStore(config)
+	if err != nil {
+		return err
+	}
+
+	h.SetIdempotencyStore(store)
+	return nil
+}
+
+// SetIdempotencyStore задает кэш ключей идемпотентности, например общий с gRPC сервером (nil - отключить)
+func (h *MetricsHandler) SetIdempotencyStore(store *idempotency.Store) {
+	h.idempotency = store
+}
+
 // UpdateMetric обновляет метрику
 func (h *MetricsHandler) UpdateMetric(w http.ResponseWriter, r *http.Request) {
+	h.withIdempotency(w, r, h.updateMetric, h.writeTextError)
+}
+
+// updateMetric обновляет метрику из параметров URL
+func (h *MetricsHandler) updateMetric(w http.ResponseWriter, r *http.Request) {
 	metricType := chi.URLParam(r, "type")
 	metricName := chi.URLParam(r, "name")
 	metricValue := chi.URLParam(r, "value")
@@ -66,7 +124,12 @@ func (h *MetricsHandler) UpdateMetric(w http.ResponseWriter, r *http.Request) {
 			"name", metricName,
 			"value", metricValue,
 			"error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeTextError(w, r, err)
+		return
+	}
+
+	if err := h.authorizeMetric(r, metricName); err != nil {
+		h.writeTextError(w, r, err)
 		return
 	}
 
@@ -83,19 +146,25 @@ func (h *MetricsHandler) UpdateMetric(w http.ResponseWriter, r *http.Request) {
 			"name", metricName,
 			"value", metricValue,
 			"error", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		h.writeTextError(w, r, err)
 		return
 	}
 
 	h.logger.Info("metric updated successfully",
 		"type", metricType,
 		"name", metricType,
-		"value", metricValue)
+		"value", metricValue,
+		"principal", principalName(r))
 	w.WriteHeader(http.StatusOK)
 }
 
 // UpdateMetricJSON обновляет метрику из JSON запроса
 func (h *MetricsHandler) UpdateMetricJSON(w http.ResponseWriter, r *http.Request) {
+	h.withIdempotency(w, r, h.updateMetricJSON, h.writeProblem)
+}
+
+// updateMetricJSON обновляет метрику из тела JSON запроса
+func (h *MetricsHandler) updateMetricJSON(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("processing update metric JSON request",
 		"method", r.Method,
 		"url", r.URL.Path,
@@ -104,22 +173,25 @@ func (h *MetricsHandler) UpdateMetricJSON(w http.ResponseWriter, r *http.Request
 	// Проверяем Content-Type
 	if r.Header.Get("Content-Type") != "application/json" {
 		h.logger.Warn("invalid content type", "content_type", r.Header.Get("Content-Type"))
-		http.Error(w, "Content-Type must be application/json", http.StatusBadRequest)
+		h.writeProblem(w, r, badRequest("Content-Type must be application/json"))
 		return
 	}
 
 	// Декодируем JSON
 	var metric models.Metrics
-	if err := json.NewDecoder(r.Body).Decode(&metric); err != nil {
-		h.logger.Warn("failed to decode JSON", "error", err)
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+	if !h.decodeJSON(w, r, &metric) {
 		return
 	}
 
 	// Валидация метрики
 	if err := h.validateMetricJSON(&metric); err != nil {
 		h.logger.Warn("metric validation failed", "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeProblem(w, r, err)
+		return
+	}
+
+	if err := h.authorizeMetric(r, metric.ID); err != nil {
+		h.writeProblem(w, r, err)
 		return
 	}
 
@@ -131,13 +203,66 @@ func (h *MetricsHandler) UpdateMetricJSON(w http.ResponseWriter, r *http.Request
 	err := h.service.UpdateMetricJSON(ctx, &metric)
 	if err != nil {
 		h.logger.Error("failed to update metric", "error", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		h.writeProblem(w, r, err)
 		return
 	}
 
 	h.logger.Info("metric updated successfully from JSON",
 		"id", metric.ID,
-		"type", metric.MType)
+		"type", metric.MType,
+		"principal", principalName(r))
+	w.WriteHeader(http.StatusOK)
+}
+
+// UpdateMetricsBatch обновляет пакет метрик из JSON массива
+func (h *MetricsHandler) UpdateMetricsBatch(w http.ResponseWriter, r *http.Request) {
+	h.withIdempotency(w, r, h.updateMetricsBatch, h.writeProblem)
+}
+
+// updateMetricsBatch обновляет пакет метрик из тела JSON запроса
+func (h *MetricsHandler) updateMetricsBatch(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("processing update metrics batch request",
+		"method", r.Method,
+		"url", r.URL.Path,
+		"remote_addr", r.RemoteAddr)
+
+	// Проверяем Content-Type
+	if r.Header.Get("Content-Type") != "application/json" {
+		h.logger.Warn("invalid content type", "content_type", r.Header.Get("Content-Type"))
+		h.writeProblem(w, r, badRequest("Content-Type must be application/json"))
+		return
+	}
+
+	// Декодируем JSON массив
+	var metrics []models.Metrics
+	if !h.decodeJSON(w, r, &metrics) {
+		return
+	}
+
+	// Пакет применяется целиком, поэтому недоступная метрика отклоняет весь пакет
+	for _, metric := range metrics {
+		if err := h.authorizeMetric(r, metric.ID); err != nil {
+			h.writeProblem(w, r, err)
+			return
+		}
+	}
+
+	// Создаем контекст с таймаутом
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// Обновляем пакет через сервис (валидация всего пакета выполняется до применения)
+	if err := h.service.UpdateMetricsBatch(ctx, metrics); err != nil {
+		if models.IsValidationError(err) {
+			h.logger.Warn("metrics batch validation failed", "error", err)
+		} else {
+			h.logger.Error("failed to update metrics batch", "error", err)
+		}
+		h.writeProblem(w, r, err)
+		return
+	}
+
+	h.logger.Info("metrics batch updated successfully", "count", len(metrics), "principal", principalName(r))
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -163,7 +288,12 @@ func (h *MetricsHandler) GetMetricValue(w http.ResponseWriter, r *http.Request)
 			"type", metricType,
 			"name", metricName,
 			"error", err)
-		http.Error(w, err.Error(), http.StatusNotFound)
+		h.writeTextError(w, r, metricNotFound(metricType, metricName))
+		return
+	}
+
+	if err := h.authorizeMetric(r, metricName); err != nil {
+		h.writeTextError(w, r, err)
 		return
 	}
 
@@ -172,7 +302,7 @@ func (h *MetricsHandler) GetMetricValue(w http.ResponseWriter, r *http.Request)
 	var err error
 
 	switch metricType {
-	case "gauge":
+	case models.Gauge:
 		var gaugeValue float64
 		var exists bool
 		gaugeValue, exists, err = h.service.GetGauge(ctx, metricName)
@@ -180,18 +310,18 @@ func (h *MetricsHandler) GetMetricValue(w http.ResponseWriter, r *http.Request)
 			h.logger.Error("failed to get gauge metric",
 				"name", metricName,
 				"error", err)
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			h.writeTextError(w, r, err)
 			return
 		}
 		if !exists {
 			h.logger.Debug("gauge metric not found",
 				"name", metricName)
-			http.Error(w, "Metric not found", http.StatusNotFound)
+			h.writeTextError(w, r, metricNotFound(metricType, metricName))
 			return
 		}
 		value = gaugeValue
 
-	case "counter":
+	case models.Counter:
 		var counterValue int64
 		var exists bool
 		counterValue, exists, err = h.service.GetCounter(ctx, metricName)
@@ -199,13 +329,13 @@ func (h *MetricsHandler) GetMetricValue(w http.ResponseWriter, r *http.Request)
 			h.logger.Error("failed to get counter metric",
 				"name", metricName,
 				"error", err)
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			h.writeTextError(w, r, err)
 			return
 		}
 		if !exists {
 			h.logger.Debug("counter metric not found",
 				"name", metricName)
-			http.Error(w, "Metric not found", http.StatusNotFound)
+			h.writeTextError(w, r, metricNotFound(metricType, metricName))
 			return
 		}
 		value = counterValue
@@ -214,7 +344,7 @@ func (h *MetricsHandler) GetMetricValue(w http.ResponseWriter, r *http.Request)
 		h.logger.Warn("invalid metric type requested",
 			"type", metricType,
 			"name", metricName)
-		http.Error(w, "Invalid metric type", http.StatusBadRequest)
+		h.writeTextError(w, r, fmt.Errorf("%w: %s", models.ErrUnsupportedMetricType, metricType))
 		return
 	}
 
@@ -236,22 +366,25 @@ func (h *MetricsHandler) GetMetricJSON(w http.ResponseWriter, r *http.Request) {
 	// Проверяем Content-Type
 	if r.Header.Get("Content-Type") != "application/json" {
 		h.logger.Warn("invalid content type", "content_type", r.Header.Get("Content-Type"))
-		http.Error(w, "Content-Type must be application/json", http.StatusBadRequest)
+		h.writeProblem(w, r, badRequest("Content-Type must be application/json"))
 		return
 	}
 
 	// Декодируем JSON
 	var metric models.Metrics
-	if err := json.NewDecoder(r.Body).Decode(&metric); err != nil {
-		h.logger.Warn("failed to decode JSON", "error", err)
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+	if !h.decodeJSON(w, r, &metric) {
 		return
 	}
 
 	// Валидация запроса
 	if err := h.validateMetricRequestJSON(&metric); err != nil {
 		h.logger.Warn("metric request validation failed", "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeProblem(w, r, err)
+		return
+	}
+
+	if err := h.authorizeMetric(r, metric.ID); err != nil {
+		h.writeProblem(w, r, err)
 		return
 	}
 
@@ -262,12 +395,12 @@ func (h *MetricsHandler) GetMetricJSON(w http.ResponseWriter, r *http.Request) {
 	// Получаем метрику через сервис
 	result, err := h.service.GetMetricJSON(ctx, &metric)
 	if err != nil {
-		h.logger.Error("failed to get metric", "error", err)
-		if strings.Contains(err.Error(), "not found") {
-			http.Error(w, err.Error(), http.StatusNotFound)
+		if errors.Is(err, models.ErrMetricNotFound) {
+			h.logger.Debug("metric not found", "id", metric.ID, "type", metric.MType)
 		} else {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			h.logger.Error("failed to get metric", "error", err)
 		}
+		h.writeProblem(w, r, err)
 		return
 	}
 
@@ -303,16 +436,20 @@ func (h *MetricsHandler) GetAllMetrics(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		h.logger.Error("failed to get metrics data",
 			"error", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		h.writeTextError(w, r, err)
 		return
 	}
 
+	// Токен с префиксом видит только свои метрики
+	metricsData.Gauges, metricsData.Counters = filterAllowedMetrics(r, metricsData.Gauges, metricsData.Counters)
+	metricsData.GaugeCount, metricsData.CounterCount = len(metricsData.Gauges), len(metricsData.Counters)
+
 	// Выполняем шаблон
 	htmlBytes, err := h.template.Execute(*metricsData)
 	if err != nil {
 		h.logger.Error("failed to execute template",
 			"error", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		h.writeTextError(w, r, err)
 		return
 	}
 
@@ -352,27 +489,48 @@ func (h *MetricsHandler) getAllMetricsData(ctx context.Context) (*template.Metri
 	}, nil
 }
 
-// validateMetricJSON валидирует метрику из JSON
-func (h *MetricsHandler) validateMetricJSON(metric *models.Metrics) error {
-	if metric.ID == "" {
-		return fmt.Errorf("metric ID is required")
+// decodeJSON декодирует JSON тело запроса с ограничением размера.
+// При ошибке отвечает клиенту в формате problem+json (413 при превышении размера, иначе 400) и возвращает false.
+func (h *MetricsHandler) decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	body := r.Body
+	if h.maxBodySize > 0 {
+		body = http.MaxBytesReader(w, r.Body, h.maxBodySize)
 	}
 
-	if metric.MType == "" {
-		return fmt.Errorf("metric type is required")
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		if isBodyTooLarge(err) {
+			h.logger.Warn("request body too large", "error", err)
+			h.writeProblem(w, r, err)
+			return false
+		}
+		h.logger.Warn("failed to decode JSON", "error", err)
+		h.writeProblem(w, r, badRequest("Invalid JSON format"))
+		return false
+	}
+	return true
+}
+
+// isBodyTooLarge проверяет, вызвана ли ошибка чтения тела превышением ограничения размера
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// validateMetricJSON валидирует метрику из JSON
+func (h *MetricsHandler) validateMetricJSON(metric *models.Metrics) error {
+	if err := h.validateMetricRequestJSON(metric); err != nil {
+		return err
 	}
 
 	switch metric.MType {
 	case models.Gauge:
 		if metric.Value == nil {
-			return fmt.Errorf("value is required for gauge metric")
+			return models.ValidationError{Field: "value", Value: "null", Message: "is required for gauge metric"}
 		}
 	case models.Counter:
 		if metric.Delta == nil {
-			return fmt.Errorf("delta is required for counter metric")
+			return models.ValidationError{Field: "delta", Value: "null", Message: "is required for counter metric"}
 		}
-	default:
-		return fmt.Errorf("unsupported metric type: %s", metric.MType)
 	}
 
 	return nil
@@ -381,19 +539,139 @@ func (h *MetricsHandler) validateMetricJSON(metric *models.Metrics) error {
 // validateMetricRequestJSON валидирует запрос на получение метрики
 func (h *MetricsHandler) validateMetricRequestJSON(metric *models.Metrics) error {
 	if metric.ID == "" {
-		return fmt.Errorf("metric ID is required")
+		return models.ValidationError{Field: "id", Value: metric.ID, Message: "is required"}
 	}
 
 	if metric.MType == "" {
-		return fmt.Errorf("metric type is required")
+		return models.ValidationError{Field: "type", Value: metric.MType, Message: "is required"}
 	}
 
 	switch metric.MType {
 	case models.Gauge, models.Counter:
 		// Тип поддерживается
 	default:
-		return fmt.Errorf("unsupported metric type: %s", metric.MType)
+		return fmt.Errorf("%w: %s", models.ErrUnsupportedMetricType, metric.MType)
 	}
 
 	return nil
 }
+
+// defaultHistoryWindow интервал истории по умолчанию, если параметр from не задан
+const defaultHistoryWindow = time.Hour
+
+// GetMetricHistory возвращает историю значений метрики в JSON формате.
+// Параметры запроса: from, to (RFC3339 или Unix время в секундах), step (например, 1m или 60).
+func (h *MetricsHandler) GetMetricHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	metricType := chi.URLParam(r, "type")
+	metricName := chi.URLParam(r, "name")
+
+	h.logger.Info("processing get metric history request",
+		"method", r.Method,
+		"url", r.URL.String(),
+		"type", metricType,
+		"name", metricName,
+		"remote_addr", r.RemoteAddr)
+
+	if metricType != models.Gauge && metricType != models.Counter {
+		h.logger.Warn("invalid metric type requested", "type", metricType, "name", metricName)
+		h.writeProblem(w, r, fmt.Errorf("%w: %s", models.ErrUnsupportedMetricType, metricType))
+		return
+	}
+
+	if err := validation.ValidateMetricName(metricName); err != nil {
+		h.logger.Warn("metric name validation failed", "name", metricName, "error", err)
+		h.writeProblem(w, r, metricNotFound(metricType, metricName))
+		return
+	}
+
+	if err := h.authorizeMetric(r, metricName); err != nil {
+		h.writeProblem(w, r, err)
+		return
+	}
+
+	from, to, step, err := parseHistoryQuery(r, time.Now())
+	if err != nil {
+		h.logger.Warn("invalid history query", "query", r.URL.RawQuery, "error", err)
+		h.writeProblem(w, r, badRequest("%s", err))
+		return
+	}
+
+	history, exists, err := h.service.GetMetricHistory(ctx, metricType, metricName, from, to, step)
+	if err != nil {
+		if !errors.Is(err, service.ErrHistoryDisabled) {
+			h.logger.Error("failed to get metric history", "type", metricType, "name", metricName, "error", err)
+		}
+		h.writeProblem(w, r, err)
+		return
+	}
+	if !exists {
+		h.writeProblem(w, r, metricNotFound(metricType, metricName))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		h.logger.Error("failed to encode response", "error", err)
+		return
+	}
+
+	h.logger.Info("metric history retrieved successfully",
+		"type", metricType,
+		"name", metricName,
+		"count", len(history.Samples))
+}
+
+// parseHistoryQuery разбирает параметры запроса истории
+func parseHistoryQuery(r *http.Request, now time.Time) (from, to time.Time, step time.Duration, err error) {
+	query := r.URL.Query()
+
+	to = now
+	if value := query.Get("to"); value != "" {
+		if to, err = parseTimeParam(value); err != nil {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("invalid 'to' parameter: %w", err)
+		}
+	}
+
+	from = to.Add(-defaultHistoryWindow)
+	if value := query.Get("from"); value != "" {
+		if from, err = parseTimeParam(value); err != nil {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("invalid 'from' parameter: %w", err)
+		}
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, 0, fmt.Errorf("'from' must not be after 'to'")
+	}
+
+	if value := query.Get("step"); value != "" {
+		if step, err = parseDurationParam(value); err != nil {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("invalid 'step' parameter: %w", err)
+		}
+		if step <= 0 {
+			return time.Time{}, time.Time{}, 0, fmt.Errorf("'step' must be positive")
+		}
+	}
+
+	return from, to, step, nil
+}
+
+// parseTimeParam разбирает время в формате RFC3339 или Unix время в секундах
+func parseTimeParam(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseDurationParam разбирает длительность в формате Go (1m30s) или количество секунд
+func parseDurationParam(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -2,18 +2,22 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/problem"
 	"github.com/IgorKilipenko/metrical/internal/repository"
 	"github.com/IgorKilipenko/metrical/internal/service"
 	"github.com/IgorKilipenko/metrical/internal/testutils"
 	"github.com/IgorKilipenko/metrical/internal/validation"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // createTestHandler создает тестовый handler
@@ -308,6 +312,8 @@ func TestMetricsHandler_UpdateMetricJSON(t *testing.T) {
 		contentType    string
 		expectedStatus int
 		expectedBody   string
+		expectedType   string // Тип problem+json для ответов с ошибкой
+		expectedField  string // Поле из списка errors для ошибок валидации
 	}{
 		{
 			name:           "successful gauge metric update",
@@ -328,49 +334,53 @@ func TestMetricsHandler_UpdateMetricJSON(t *testing.T) {
 			requestBody:    `{"id": "TestMetric", "type": "gauge", "value": 42.5}`,
 			contentType:    "text/plain",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Content-Type must be application/json\n",
+			expectedType:   problem.TypeDefault,
 		},
 		{
 			name:           "invalid JSON format",
 			requestBody:    `{invalid json}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Invalid JSON format\n",
+			expectedType:   problem.TypeDefault,
 		},
 		{
 			name:           "missing metric ID",
 			requestBody:    `{"type": "gauge", "value": 42.5}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "metric ID is required\n",
+			expectedType:   problem.TypeValidation,
+			expectedField:  "id",
 		},
 		{
 			name:           "missing metric type",
 			requestBody:    `{"id": "TestMetric", "value": 42.5}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "metric type is required\n",
+			expectedType:   problem.TypeValidation,
+			expectedField:  "type",
 		},
 		{
 			name:           "gauge metric without value",
 			requestBody:    `{"id": "TestMetric", "type": "gauge"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "value is required for gauge metric\n",
+			expectedType:   problem.TypeValidation,
+			expectedField:  "value",
 		},
 		{
 			name:           "counter metric without delta",
 			requestBody:    `{"id": "TestCounter", "type": "counter"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "delta is required for counter metric\n",
+			expectedType:   problem.TypeValidation,
+			expectedField:  "delta",
 		},
 		{
 			name:           "unsupported metric type",
 			requestBody:    `{"id": "TestMetric", "type": "invalid", "value": 42.5}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "unsupported metric type: invalid\n",
+			expectedType:   problem.TypeUnsupportedType,
 		},
 	}
 
@@ -383,7 +393,11 @@ func TestMetricsHandler_UpdateMetricJSON(t *testing.T) {
 			handler.UpdateMetricJSON(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
-			assert.Equal(t, tt.expectedBody, w.Body.String())
+			if tt.expectedType == "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+				return
+			}
+			assertProblem(t, w, tt.expectedStatus, tt.expectedType, tt.expectedField)
 		})
 	}
 }
@@ -408,6 +422,8 @@ func TestMetricsHandler_GetMetricJSON(t *testing.T) {
 		contentType    string
 		expectedStatus int
 		expectedBody   string
+		expectedType   string // Тип problem+json для ответов с ошибкой
+		expectedField  string // Поле из списка errors для ошибок валидации
 	}{
 		{
 			name:           "successful gauge metric retrieval",
@@ -428,42 +444,44 @@ func TestMetricsHandler_GetMetricJSON(t *testing.T) {
 			requestBody:    `{"id": "TestGauge", "type": "gauge"}`,
 			contentType:    "text/plain",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Content-Type must be application/json\n",
+			expectedType:   problem.TypeDefault,
 		},
 		{
 			name:           "invalid JSON format",
 			requestBody:    `{invalid json}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Invalid JSON format\n",
+			expectedType:   problem.TypeDefault,
 		},
 		{
 			name:           "missing metric ID",
 			requestBody:    `{"type": "gauge"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "metric ID is required\n",
+			expectedType:   problem.TypeValidation,
+			expectedField:  "id",
 		},
 		{
 			name:           "missing metric type",
 			requestBody:    `{"id": "TestMetric"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "metric type is required\n",
+			expectedType:   problem.TypeValidation,
+			expectedField:  "type",
 		},
 		{
 			name:           "unsupported metric type",
 			requestBody:    `{"id": "TestMetric", "type": "invalid"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "unsupported metric type: invalid\n",
+			expectedType:   problem.TypeUnsupportedType,
 		},
 		{
 			name:           "metric not found",
 			requestBody:    `{"id": "NonExistent", "type": "gauge"}`,
 			contentType:    "application/json",
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   "gauge metric not found: NonExistent\n",
+			expectedType:   problem.TypeNotFound,
 		},
 	}
 
@@ -476,7 +494,244 @@ func TestMetricsHandler_GetMetricJSON(t *testing.T) {
 			handler.GetMetricJSON(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
-			assert.Equal(t, tt.expectedBody, w.Body.String())
+			if tt.expectedType == "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+				return
+			}
+			assertProblem(t, w, tt.expectedStatus, tt.expectedType, tt.expectedField)
+		})
+	}
+}
+
+// createTestHistoryHandler создает тестовый handler с хранением истории метрик
+func createTestHistoryHandler(t *testing.T) *MetricsHandler {
+	t.Helper()
+
+	mockLogger := testutils.NewMockLogger()
+	inner := repository.NewInMemoryMetricsRepository(mockLogger, testutils.TestMetricsFile, false)
+	history, err := repository.NewHistoryRepository(inner, nil, mockLogger)
+	require.NoError(t, err)
+
+	handler, err := NewMetricsHandler(service.NewMetricsService(history, mockLogger), mockLogger)
+	require.NoError(t, err)
+	return handler
+}
+
+func TestMetricsHandler_GetMetricHistory(t *testing.T) {
+	handler := createTestHistoryHandler(t)
+
+	for _, value := range []string{"10", "20", "30"} {
+		r, w := createChiContext("/update/gauge/HeapAlloc/"+value, map[string]string{
+			"type": "gauge", "name": "HeapAlloc", "value": value,
 		})
+		handler.UpdateMetric(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	tests := []struct {
+		name            string
+		metricType      string
+		metricName      string
+		query           string
+		expectedStatus  int
+		expectedSamples int
+	}{
+		{
+			name:            "Raw samples",
+			metricType:      "gauge",
+			metricName:      "HeapAlloc",
+			expectedStatus:  http.StatusOK,
+			expectedSamples: 3,
+		},
+		{
+			name:            "Downsampled with duration step",
+			metricType:      "gauge",
+			metricName:      "HeapAlloc",
+			query:           "?step=1h",
+			expectedStatus:  http.StatusOK,
+			expectedSamples: 1,
+		},
+		{
+			name:            "Range in the past",
+			metricType:      "gauge",
+			metricName:      "HeapAlloc",
+			query:           "?from=2020-01-01T00:00:00Z&to=1577923200",
+			expectedStatus:  http.StatusOK,
+			expectedSamples: 0,
+		},
+		{
+			name:           "Unknown metric",
+			metricType:     "gauge",
+			metricName:     "Unknown",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid metric type",
+			metricType:     "invalid",
+			metricName:     "HeapAlloc",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid from",
+			metricType:     "gauge",
+			metricName:     "HeapAlloc",
+			query:          "?from=yesterday",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "From after to",
+			metricType:     "gauge",
+			metricName:     "HeapAlloc",
+			query:          "?from=1700000100&to=1700000000",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Non-positive step",
+			metricType:     "gauge",
+			metricName:     "HeapAlloc",
+			query:          "?step=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/api/v1/history/" + tt.metricType + "/" + tt.metricName + tt.query
+			r, w := createChiContext(path, map[string]string{"type": tt.metricType, "name": tt.metricName})
+
+			handler.GetMetricHistory(w, r)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, "Body: %s", w.Body.String())
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+			var history models.MetricHistory
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&history))
+			assert.Equal(t, tt.metricName, history.ID)
+			assert.Equal(t, tt.metricType, history.MType)
+			assert.Len(t, history.Samples, tt.expectedSamples)
+		})
+	}
+}
+
+func TestMetricsHandler_GetMetricHistory_Disabled(t *testing.T) {
+	handler := createTestHandler()
+
+	r, w := createChiContext("/api/v1/history/gauge/HeapAlloc", map[string]string{"type": "gauge", "name": "HeapAlloc"})
+	handler.GetMetricHistory(w, r)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestMetricsHandler_UpdateMetricsBatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    string
+		contentType    string
+		expectedStatus int
+	}{
+		{
+			name:           "successful batch update",
+			requestBody:    `[{"id": "TestGauge", "type": "gauge", "value": 42.5}, {"id": "TestCounter", "type": "counter", "delta": 5}, {"id": "TestCounter", "type": "counter", "delta": 5}]`,
+			contentType:    "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid content type",
+			requestBody:    `[{"id": "TestGauge", "type": "gauge", "value": 42.5}]`,
+			contentType:    "text/plain",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "single object instead of array",
+			requestBody:    `{"id": "TestGauge", "type": "gauge", "value": 42.5}`,
+			contentType:    "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "empty batch",
+			requestBody:    `[]`,
+			contentType:    "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid item rejects whole batch",
+			requestBody:    `[{"id": "TestGauge", "type": "gauge", "value": 42.5}, {"id": "TestCounter", "type": "counter"}]`,
+			contentType:    "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := createTestHandler()
+
+			req := httptest.NewRequest("POST", "/updates", strings.NewReader(tt.requestBody))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			handler.UpdateMetricsBatch(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, "Body: %s", w.Body.String())
+
+			// Проверяем, что пакет применен целиком или не применен совсем
+			counter, exists, err := handler.service.GetCounter(context.Background(), "TestCounter")
+			require.NoError(t, err)
+			_, gaugeExists, err := handler.service.GetGauge(context.Background(), "TestGauge")
+			require.NoError(t, err)
+
+			if tt.expectedStatus == http.StatusOK {
+				assert.True(t, exists)
+				assert.Equal(t, int64(10), counter)
+				assert.True(t, gaugeExists)
+			} else {
+				assert.False(t, exists, "Rejected batch must not be applied")
+				assert.False(t, gaugeExists, "Rejected batch must not be applied")
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_BodyTooLarge(t *testing.T) {
+	handler := createTestHandler()
+	require.NoError(t, handler.SetMaxBodySize(64))
+
+	large := `{"id": "PollCount", "type": "counter", "delta": 1, "padding": "` + strings.Repeat("x", 128) + `"}`
+	small := `{"id": "PollCount", "type": "counter", "delta": 1}`
+
+	w := postJSONWithKey(handler.UpdateMetricJSON, "/update", large, "")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = postJSONWithKey(handler.UpdateMetricsBatch, "/updates", "["+large+"]", "")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = postJSONWithKey(handler.UpdateMetricJSON, "/update", small, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Тело, прочитанное для отпечатка идемпотентности, ограничивается так же
+	require.NoError(t, handler.EnableIdempotency(nil))
+	w = postJSONWithKey(handler.UpdateMetricJSON, "/update", large, "key-1")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	assert.Error(t, handler.SetMaxBodySize(-1))
+}
+
+// assertProblem проверяет ответ в формате application/problem+json
+func assertProblem(t *testing.T, w *httptest.ResponseRecorder, status int, problemType, field string) {
+	t.Helper()
+
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p), "Body: %s", w.Body.String())
+	assert.Equal(t, status, p.Status)
+	assert.Equal(t, problemType, p.Type)
+	assert.NotEmpty(t, p.Title)
+	if field != "" {
+		require.Len(t, p.Errors, 1)
+		assert.Equal(t, field, p.Errors[0].Field)
 	}
 }
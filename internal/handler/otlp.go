@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/otlp"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+)
+
+// SetOTLPConfig задает ограничения количества отслеживаемых потоков монотонных сумм OTLP
+func (h *MetricsHandler) SetOTLPConfig(config *otlp.Config) error {
+	if config == nil {
+		config = otlp.DefaultConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	h.otlp.SetLimits(config.MaxStreams, config.StreamTTL)
+	return nil
+}
+
+// WriteOTLPMetrics принимает метрики OpenTelemetry по протоколу OTLP/HTTP (POST /v1/metrics).
+// Тело запроса - ExportMetricsServiceRequest в protobuf или JSON, ответ возвращается в той же
+// кодировке. Отклоненные точки передаются в partialSuccess с кодом 200, как требует OTLP.
+func (h *MetricsHandler) WriteOTLPMetrics(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("processing OTLP metrics request",
+		"method", r.Method,
+		"url", r.URL.String(),
+		"remote_addr", r.RemoteAddr)
+
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (contentType != otlp.ContentTypeProtobuf && contentType != otlp.ContentTypeJSON) {
+		h.writeProblem(w, r, problem.New(http.StatusUnsupportedMediaType,
+			fmt.Sprintf("OTLP requires Content-Type %s or %s", otlp.ContentTypeProtobuf, otlp.ContentTypeJSON)))
+		return
+	}
+
+	body := r.Body
+	if h.maxBodySize > 0 {
+		body = http.MaxBytesReader(w, r.Body, h.maxBodySize)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		h.logger.Warn("failed to read OTLP body", "error", err)
+		if !isBodyTooLarge(err) {
+			err = badRequest("failed to read request body")
+		}
+		h.writeProblem(w, r, err)
+		return
+	}
+
+	var req *otlp.ExportMetricsServiceRequest
+	if contentType == otlp.ContentTypeProtobuf {
+		req, err = otlp.UnmarshalProto(data)
+	} else {
+		req, err = otlp.UnmarshalJSON(data)
+	}
+	if err != nil {
+		h.logger.Warn("failed to decode OTLP request", "error", err)
+		h.writeProblem(w, r, badRequest("%s", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	result, err := h.otlp.Write(ctx, req, func(name string) error {
+		return h.authorizeMetric(r, name)
+	})
+	if err != nil {
+		h.logger.Error("failed to write OTLP batch", "error", err)
+		h.writeProblem(w, r, err)
+		return
+	}
+	if result.Rejected > 0 {
+		h.logger.Warn("OTLP data points rejected", "rejected", result.Rejected, "errors", result.Errors)
+	}
+
+	response := result.Response()
+	var payload []byte
+	if contentType == otlp.ContentTypeProtobuf {
+		payload = response.MarshalProto()
+	} else if payload, err = json.Marshal(response); err != nil {
+		h.logger.Error("failed to encode response", "error", err)
+		h.writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(payload); err != nil {
+		h.logger.Error("failed to write response", "error", err)
+		return
+	}
+
+	h.logger.Info("OTLP metrics written",
+		"data_points", result.DataPoints,
+		"gauges", result.Gauges,
+		"counters", result.Counters,
+		"rejected", result.Rejected,
+		"principal", principalName(r))
+}
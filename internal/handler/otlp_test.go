@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/otlp"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// otlpGaugeRequest создает запрос OTLP с одним gauge сервиса checkout
+func otlpGaugeRequest(name string, value float64) *otlp.ExportMetricsServiceRequest {
+	return &otlp.ExportMetricsServiceRequest{ResourceMetrics: []otlp.ResourceMetrics{{
+		Resource: otlp.Resource{Attributes: []otlp.KeyValue{otlp.StringAttribute("service.name", "checkout")}},
+		ScopeMetrics: []otlp.ScopeMetrics{{Metrics: []otlp.Metric{{
+			Name:  name,
+			Gauge: &otlp.Gauge{DataPoints: []otlp.NumberDataPoint{{AsDouble: &value}}},
+		}}}},
+	}}}
+}
+
+func TestMetricsHandler_WriteOTLPMetrics_Protobuf(t *testing.T) {
+	handler := createTestHandler()
+
+	req := otlpGaugeRequest("queue.size", 7)
+	req.ResourceMetrics[0].ScopeMetrics[0].Metrics = append(req.ResourceMetrics[0].ScopeMetrics[0].Metrics,
+		otlp.Metric{Name: "latency", Histogram: &otlp.UnsupportedData{DataPoints: []json.RawMessage{nil}}})
+
+	r := httptest.NewRequest("POST", "/v1/metrics", bytes.NewReader(req.MarshalProto()))
+	r.Header.Set("Content-Type", "application/x-protobuf")
+	w := httptest.NewRecorder()
+	handler.WriteOTLPMetrics(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	response, err := otlp.UnmarshalResponseProto(body)
+	require.NoError(t, err)
+	require.NotNil(t, response.PartialSuccess)
+	assert.Equal(t, int64(1), response.PartialSuccess.RejectedDataPoints)
+
+	value, _, err := handler.service.GetGauge(context.Background(), "checkout.queue.size")
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, value)
+}
+
+func TestMetricsHandler_WriteOTLPMetrics_JSON(t *testing.T) {
+	handler := createTestHandler()
+
+	body := `{"resourceMetrics":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"billing"}}]},
+		"scopeMetrics":[{"metrics":[{"name":"invoices","sum":{"aggregationTemporality":1,"isMonotonic":true,
+		"dataPoints":[{"asInt":"3"}]}}]}]}]}`
+	r := httptest.NewRequest("POST", "/v1/metrics", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+	handler.WriteOTLPMetrics(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{}`, w.Body.String())
+
+	invoices, _, err := handler.service.GetCounter(context.Background(), "billing.invoices")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), invoices)
+}
+
+func TestMetricsHandler_WriteOTLPMetrics_Errors(t *testing.T) {
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		maxBodySize    int64
+		expectedStatus int
+	}{
+		{
+			name:           "unsupported content type",
+			contentType:    "text/plain",
+			body:           "{}",
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "malformed JSON",
+			contentType:    "application/json",
+			body:           `{"resourceMetrics":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed protobuf",
+			contentType:    "application/x-protobuf",
+			body:           "\x0a\x10short",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "body too large",
+			contentType:    "application/json",
+			body:           `{"resourceMetrics":[]}`,
+			maxBodySize:    4,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := createTestHandler()
+			if tt.maxBodySize > 0 {
+				require.NoError(t, handler.SetMaxBodySize(tt.maxBodySize))
+			}
+
+			r := httptest.NewRequest("POST", "/v1/metrics", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			handler.WriteOTLPMetrics(w, r)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, "Body: %s", w.Body.String())
+			assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestMetricsHandler_WriteOTLPMetrics_TokenPrefix(t *testing.T) {
+	handler := createTestHandler()
+	principal := &auth.Principal{Name: "checkout", Scope: auth.ScopeWrite, Prefix: "billing."}
+
+	data, err := json.Marshal(otlpGaugeRequest("queue.size", 1))
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("POST", "/v1/metrics", bytes.NewReader(data))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.WriteOTLPMetrics(w, withPrincipal(r, principal))
+
+	require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+	var response otlp.ExportMetricsServiceResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	require.NotNil(t, response.PartialSuccess)
+	assert.Equal(t, int64(1), response.PartialSuccess.RejectedDataPoints)
+	assert.Contains(t, response.PartialSuccess.ErrorMessage, "outside token prefix")
+
+	_, exists, err := handler.service.GetGauge(context.Background(), "checkout.queue.size")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestMetricsHandler_SetOTLPConfig(t *testing.T) {
+	handler := createTestHandler()
+
+	assert.NoError(t, handler.SetOTLPConfig(nil))
+	assert.NoError(t, handler.SetOTLPConfig(&otlp.Config{MaxStreams: 10, StreamTTL: time.Minute}))
+	assert.Error(t, handler.SetOTLPConfig(&otlp.Config{MaxStreams: -1}))
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/problem"
+	"github.com/IgorKilipenko/metrical/internal/remotewrite"
+)
+
+// SetRemoteWriteConfig задает ограничения размера запросов Prometheus remote_write
+// и количества отслеживаемых рядов counter
+func (h *MetricsHandler) SetRemoteWriteConfig(config *remotewrite.Config) error {
+	if config == nil {
+		config = remotewrite.DefaultConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	h.remoteWriteConfig = *config
+	h.remoteWrite.SetLimits(config.MaxSeries, config.SeriesTTL)
+	return nil
+}
+
+// RemoteWrite принимает метрики Prometheus remote_write 1.0 (POST /api/v1/write).
+// Тело запроса - protobuf WriteRequest, сжатый snappy. В ответе возвращается статистика
+// записанных и отклоненных рядов; если не удалось записать ни одного ряда, ответ 400,
+// чтобы Prometheus не повторял заведомо некорректный запрос.
+func (h *MetricsHandler) RemoteWrite(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("processing remote write request",
+		"method", r.Method,
+		"url", r.URL.String(),
+		"remote_addr", r.RemoteAddr)
+
+	if encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding != "snappy" {
+		h.writeProblem(w, r, problem.New(http.StatusUnsupportedMediaType,
+			fmt.Sprintf("remote write requires Content-Encoding: snappy, got %q", encoding)))
+		return
+	}
+	// Remote write 2.0 использует другую схему сообщения
+	if contentType := r.Header.Get("Content-Type"); strings.Contains(contentType, "io.prometheus.write.v2") {
+		h.writeProblem(w, r, problem.New(http.StatusUnsupportedMediaType,
+			fmt.Sprintf("unsupported remote write message %q, only prometheus.WriteRequest (1.0) is accepted", contentType)))
+		return
+	}
+
+	body := r.Body
+	if h.remoteWriteConfig.MaxBodySize > 0 {
+		body = http.MaxBytesReader(w, r.Body, h.remoteWriteConfig.MaxBodySize)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		h.logger.Warn("failed to read remote write body", "error", err)
+		if !isBodyTooLarge(err) {
+			err = badRequest("failed to read request body")
+		}
+		h.writeProblem(w, r, err)
+		return
+	}
+
+	req, err := remotewrite.Decode(data, h.remoteWriteConfig.MaxDecodedSize)
+	if err != nil {
+		h.logger.Warn("failed to decode remote write request", "error", err)
+		if !isBodyTooLarge(err) {
+			err = badRequest("%s", err)
+		}
+		h.writeProblem(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	stats, err := h.remoteWrite.Write(ctx, req, func(name string) error {
+		return h.authorizeMetric(r, name)
+	})
+	if err != nil {
+		h.logger.Error("failed to write remote write batch", "error", err)
+		h.writeProblem(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if stats.Failed > 0 && stats.Gauges+stats.Counters == 0 {
+		status = http.StatusBadRequest
+	}
+	if stats.Failed > 0 {
+		h.logger.Warn("remote write series rejected", "failed", stats.Failed, "errors", stats.Errors)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		h.logger.Error("failed to encode response", "error", err)
+		return
+	}
+
+	h.logger.Info("remote write batch written",
+		"series", stats.Series,
+		"gauges", stats.Gauges,
+		"counters", stats.Counters,
+		"failed", stats.Failed,
+		"principal", principalName(r))
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+	"github.com/IgorKilipenko/metrical/internal/remotewrite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// remoteWriteRequest создает запрос remote_write с заголовками Prometheus
+func remoteWriteRequest(body []byte) *http.Request {
+	req := httptest.NewRequest("POST", "/api/v1/write", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	return req
+}
+
+func TestMetricsHandler_RemoteWrite(t *testing.T) {
+	handler := createTestHandler()
+	ctx := context.Background()
+
+	body := remotewrite.Encode(&remotewrite.WriteRequest{Timeseries: []remotewrite.TimeSeries{
+		{
+			Labels:  []remotewrite.Label{{Name: "__name__", Value: "node_load1"}, {Name: "instance", Value: "a"}},
+			Samples: []remotewrite.Sample{{Value: 0.25, Timestamp: 1700000000000}},
+		},
+		{
+			Labels:  []remotewrite.Label{{Name: "__name__", Value: "scrapes_total"}},
+			Samples: []remotewrite.Sample{{Value: 12, Timestamp: 1700000000000}},
+		},
+		{
+			Labels:  []remotewrite.Label{{Name: "job", Value: "node"}},
+			Samples: []remotewrite.Sample{{Value: 1}},
+		},
+	}})
+
+	w := httptest.NewRecorder()
+	handler.RemoteWrite(w, remoteWriteRequest(body))
+	require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var stats remotewrite.Stats
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&stats))
+	assert.Equal(t, 3, stats.Series)
+	assert.Equal(t, 1, stats.Gauges)
+	assert.Equal(t, 1, stats.Counters)
+	assert.Equal(t, 1, stats.Failed)
+	require.Len(t, stats.Errors, 1)
+
+	load, _, err := handler.service.GetGauge(ctx, "node_load1.instance=a")
+	require.NoError(t, err)
+	assert.Equal(t, 0.25, load)
+
+	scrapes, _, err := handler.service.GetCounter(ctx, "scrapes_total")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), scrapes)
+}
+
+func TestMetricsHandler_RemoteWrite_Errors(t *testing.T) {
+	valid := remotewrite.Encode(&remotewrite.WriteRequest{Timeseries: []remotewrite.TimeSeries{{
+		Labels:  []remotewrite.Label{{Name: "__name__", Value: "up"}},
+		Samples: []remotewrite.Sample{{Value: 1}},
+	}}})
+	allFailed := remotewrite.Encode(&remotewrite.WriteRequest{Timeseries: []remotewrite.TimeSeries{{
+		Labels:  []remotewrite.Label{{Name: "job", Value: "node"}},
+		Samples: []remotewrite.Sample{{Value: 1}},
+	}}})
+
+	tests := []struct {
+		name           string
+		body           []byte
+		prepare        func(req *http.Request)
+		config         *remotewrite.Config
+		expectedStatus int
+		problem        bool
+	}{
+		{
+			name:           "missing snappy encoding",
+			body:           valid,
+			prepare:        func(req *http.Request) { req.Header.Del("Content-Encoding") },
+			expectedStatus: http.StatusUnsupportedMediaType,
+			problem:        true,
+		},
+		{
+			name: "remote write 2.0 message",
+			body: valid,
+			prepare: func(req *http.Request) {
+				req.Header.Set("Content-Type", "application/x-protobuf;proto=io.prometheus.write.v2.Request")
+			},
+			expectedStatus: http.StatusUnsupportedMediaType,
+			problem:        true,
+		},
+		{
+			name:           "not snappy",
+			body:           []byte("plain text"),
+			expectedStatus: http.StatusBadRequest,
+			problem:        true,
+		},
+		{
+			name:           "compressed body too large",
+			body:           valid,
+			config:         &remotewrite.Config{MaxBodySize: 4},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			problem:        true,
+		},
+		{
+			name:           "decoded body too large",
+			body:           valid,
+			config:         &remotewrite.Config{MaxDecodedSize: 4},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			problem:        true,
+		},
+		{
+			name:           "all series failed",
+			body:           allFailed,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := createTestHandler()
+			if tt.config != nil {
+				require.NoError(t, handler.SetRemoteWriteConfig(tt.config))
+			}
+
+			req := remoteWriteRequest(tt.body)
+			if tt.prepare != nil {
+				tt.prepare(req)
+			}
+			w := httptest.NewRecorder()
+			handler.RemoteWrite(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, "Body: %s", w.Body.String())
+			if tt.problem {
+				assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+			} else {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_RemoteWrite_TokenPrefix(t *testing.T) {
+	handler := createTestHandler()
+	principal := &auth.Principal{Name: "prometheus", Scope: auth.ScopeWrite, Prefix: "node_"}
+
+	body := remotewrite.Encode(&remotewrite.WriteRequest{Timeseries: []remotewrite.TimeSeries{
+		{Labels: []remotewrite.Label{{Name: "__name__", Value: "node_load1"}}, Samples: []remotewrite.Sample{{Value: 1}}},
+		{Labels: []remotewrite.Label{{Name: "__name__", Value: "app_load"}}, Samples: []remotewrite.Sample{{Value: 1}}},
+	}})
+
+	w := httptest.NewRecorder()
+	handler.RemoteWrite(w, withPrincipal(remoteWriteRequest(body), principal))
+	require.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+
+	var stats remotewrite.Stats
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&stats))
+	assert.Equal(t, 1, stats.Gauges)
+	assert.Equal(t, 1, stats.Failed)
+
+	_, exists, err := handler.service.GetGauge(context.Background(), "app_load")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestMetricsHandler_SetRemoteWriteConfig(t *testing.T) {
+	handler := createTestHandler()
+
+	assert.NoError(t, handler.SetRemoteWriteConfig(nil))
+	assert.Equal(t, *remotewrite.DefaultConfig(), handler.remoteWriteConfig)
+	assert.Error(t, handler.SetRemoteWriteConfig(&remotewrite.Config{MaxBodySize: -1}))
+}
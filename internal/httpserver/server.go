@@ -2,15 +2,22 @@ package httpserver
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/encryption"
 	"github.com/IgorKilipenko/metrical/internal/handler"
 	"github.com/IgorKilipenko/metrical/internal/logger"
+	"github.com/IgorKilipenko/metrical/internal/middleware"
+	"github.com/IgorKilipenko/metrical/internal/ratelimit"
 	"github.com/IgorKilipenko/metrical/internal/router"
 	"github.com/IgorKilipenko/metrical/internal/routes"
+	"github.com/IgorKilipenko/metrical/internal/tlsconfig"
 )
 
 // ServerConfig конфигурация HTTP сервера
@@ -19,6 +26,26 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+	SigningKey   string                // Общий ключ подписи запросов и ответов (пустая строка - подпись отключена)
+	Decryptor    *encryption.Decryptor // Расшифровка тел запросов (nil - отключена)
+
+	TLSCertFile     string // Сертификат сервера в формате PEM (пустая строка - HTTP без TLS)
+	TLSKeyFile      string // Приватный ключ сертификата сервера
+	TLSClientCAFile string // Бандл CA для проверки клиентских сертификатов (пустая строка - mTLS отключен)
+
+	TrustedSubnet     *net.IPNet // Доверенная подсеть для запросов на запись (nil - без ограничений)
+	TrustedReadSubnet *net.IPNet // Доверенная подсеть для чтения метрик (nil - без ограничений)
+
+	// Прокси, которым доверяется X-Real-IP при ограничении частоты (пусто - ключом служит адрес соединения)
+	TrustedProxies []*net.IPNet
+
+	Auth *auth.Store // API токены с областями доступа (nil - аутентификация отключена)
+
+	WriteLimiter *ratelimit.Limiter // Ограничение частоты записи для каждого клиента (nil - без ограничений)
+	ReadLimiter  *ratelimit.Limiter // Ограничение частоты чтения для каждого клиента (nil - без ограничений)
+
+	MaxBodySize           int64 // Максимальный размер несжатого тела запроса в байтах (0 - без ограничений)
+	MaxCompressedBodySize int64 // Максимальный размер сжатого тела запроса в байтах (0 - без ограничений)
 }
 
 // DefaultServerConfig возвращает конфигурацию по умолчанию
@@ -28,16 +55,20 @@ func DefaultServerConfig() *ServerConfig {
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
+
+		MaxBodySize:           middleware.DefaultMaxBodySize,
+		MaxCompressedBodySize: middleware.DefaultMaxCompressedBodySize,
 	}
 }
 
 // Server представляет HTTP сервер
 type Server struct {
-	config  *ServerConfig
-	handler *handler.MetricsHandler
-	router  *router.Router // Кэшированный роутер
-	server  *http.Server   // Ссылка на HTTP сервер для graceful shutdown
-	logger  logger.Logger
+	config    *ServerConfig
+	handler   *handler.MetricsHandler
+	router    *router.Router // Кэшированный роутер
+	server    *http.Server   // Ссылка на HTTP сервер для graceful shutdown
+	tlsConfig *tls.Config    // TLS конфигурация (nil - HTTP без TLS)
+	logger    logger.Logger
 }
 
 // NewServer создает новый HTTP сервер с переданными зависимостями
@@ -70,6 +101,17 @@ func NewServerWithConfig(config *ServerConfig, handler *handler.MetricsHandler,
 		logger:  logger,
 	}
 
+	// Сертификаты загружаются при создании, чтобы ошибки конфигурации обнаруживались до запуска
+	if config.TLSCertFile != "" || config.TLSKeyFile != "" {
+		tlsConfig, err := tlsconfig.NewServerConfig(config.TLSCertFile, config.TLSKeyFile, config.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure TLS: %w", err)
+		}
+		srv.tlsConfig = tlsConfig
+	} else if config.TLSClientCAFile != "" {
+		return nil, errors.New("client CA requires TLS certificate and key")
+	}
+
 	// Инициализируем роутер один раз
 	logger.Info("creating router")
 	srv.router = srv.createRouter()
@@ -84,7 +126,9 @@ func (s *Server) Start() error {
 		"addr", s.config.Addr,
 		"read_timeout", s.config.ReadTimeout,
 		"write_timeout", s.config.WriteTimeout,
-		"idle_timeout", s.config.IdleTimeout)
+		"idle_timeout", s.config.IdleTimeout,
+		"tls", s.tlsConfig != nil,
+		"mtls", s.config.TLSClientCAFile != "")
 
 	s.server = &http.Server{
 		Addr:         s.config.Addr,
@@ -92,9 +136,18 @@ func (s *Server) Start() error {
 		ReadTimeout:  s.config.ReadTimeout,
 		WriteTimeout: s.config.WriteTimeout,
 		IdleTimeout:  s.config.IdleTimeout,
+		TLSConfig:    s.tlsConfig,
+	}
+
+	var err error
+	if s.tlsConfig != nil {
+		// Сертификат уже загружен в TLSConfig
+		err = s.server.ListenAndServeTLS("", "")
+	} else {
+		err = s.server.ListenAndServe()
 	}
 
-	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if err != nil && err != http.ErrServerClosed {
 		s.logger.Error("server error", "error", err)
 		return fmt.Errorf("failed to start server: %w", err)
 	}
@@ -127,6 +180,22 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // createRouter создает и настраивает роутер с маршрутами
 func (s *Server) createRouter() *router.Router {
 	// Используем отдельный пакет для настройки маршрутов
-	chiRouter := routes.SetupMetricsRoutes(s.handler)
+	routesConfig := &routes.Config{
+		SigningKey:            s.config.SigningKey,
+		Decryptor:             s.config.Decryptor,
+		TrustedSubnet:         s.config.TrustedSubnet,
+		TrustedReadSubnet:     s.config.TrustedReadSubnet,
+		TrustedProxies:        s.config.TrustedProxies,
+		Auth:                  s.config.Auth,
+		WriteLimiter:          s.config.WriteLimiter,
+		ReadLimiter:           s.config.ReadLimiter,
+		MaxBodySize:           s.config.MaxBodySize,
+		MaxCompressedBodySize: s.config.MaxCompressedBodySize,
+	}
+	if !routesConfig.UnsignedIngestionEnabled() {
+		s.logger.Warn("remote_write, line protocol and OTLP endpoints are disabled: they do not verify signatures, configure API tokens to enable them")
+	}
+
+	chiRouter := routes.SetupMetricsRoutesWithConfig(s.handler, routesConfig)
 	return router.NewWithChiRouter(chiRouter)
 }
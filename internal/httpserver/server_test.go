@@ -13,6 +13,7 @@ import (
 	"github.com/IgorKilipenko/metrical/internal/repository"
 	"github.com/IgorKilipenko/metrical/internal/service"
 	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/IgorKilipenko/metrical/internal/tlsconfig"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -550,3 +551,95 @@ func TestServerPerformance(t *testing.T) {
 	assert.Less(t, avgTime, 10*time.Millisecond,
 		"Average response time should be less than 10ms")
 }
+
+// startTLSTestServer запускает сервер с TLS конфигурацией, построенной NewServerWithConfig
+func startTLSTestServer(t *testing.T, config *ServerConfig) *httptest.Server {
+	t.Helper()
+
+	srv := createTestServerWithConfig(t, config)
+	require.NotNil(t, srv.tlsConfig)
+
+	server := httptest.NewUnstartedServer(srv)
+	server.TLS = srv.tlsConfig
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestServerTLS(t *testing.T) {
+	certs := testutils.WriteTestCertificates(t)
+
+	t.Run("HTTPS", func(t *testing.T) {
+		server := startTLSTestServer(t, &ServerConfig{
+			Addr:        ":8443",
+			TLSCertFile: certs.ServerCertFile,
+			TLSKeyFile:  certs.ServerKeyFile,
+		})
+
+		clientConfig, err := tlsconfig.NewClientConfig(certs.CAFile, "", "")
+		require.NoError(t, err)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+
+		resp, err := client.Post(server.URL+"/update/gauge/temperature/23.5", "text/plain", nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("mutual TLS", func(t *testing.T) {
+		server := startTLSTestServer(t, &ServerConfig{
+			Addr:            ":8443",
+			TLSCertFile:     certs.ServerCertFile,
+			TLSKeyFile:      certs.ServerKeyFile,
+			TLSClientCAFile: certs.CAFile,
+		})
+
+		withoutCert, err := tlsconfig.NewClientConfig(certs.CAFile, "", "")
+		require.NoError(t, err)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: withoutCert}}
+		_, err = client.Get(server.URL + "/")
+		assert.Error(t, err, "Client without certificate should be rejected")
+
+		withCert, err := tlsconfig.NewClientConfig(certs.CAFile, certs.ClientCertFile, certs.ClientKeyFile)
+		require.NoError(t, err)
+		client = &http.Client{Transport: &http.Transport{TLSClientConfig: withCert}}
+		resp, err := client.Get(server.URL + "/")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestServerTLSConfigValidation(t *testing.T) {
+	certs := testutils.WriteTestCertificates(t)
+
+	tests := []struct {
+		name   string
+		config *ServerConfig
+	}{
+		{
+			name:   "Certificate without key",
+			config: &ServerConfig{Addr: ":8443", TLSCertFile: certs.ServerCertFile},
+		},
+		{
+			name:   "Key without certificate",
+			config: &ServerConfig{Addr: ":8443", TLSKeyFile: certs.ServerKeyFile},
+		},
+		{
+			name:   "Client CA without certificate",
+			config: &ServerConfig{Addr: ":8443", TLSClientCAFile: certs.CAFile},
+		},
+		{
+			name:   "Missing certificate file",
+			config: &ServerConfig{Addr: ":8443", TLSCertFile: "/nonexistent/server.pem", TLSKeyFile: certs.ServerKeyFile},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, err := NewServerWithConfig(tt.config, createTestHandler(), testutils.NewMockLogger())
+			assert.Error(t, err)
+			assert.Nil(t, srv)
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Package idempotency хранит результаты запросов на запись по ключу идемпотентности,
+// чтобы повтор запроса не применял метрики повторно. Кэш общий для HTTP и gRPC API.
+package idempotency
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MaxKeyLength максимальная длина ключа идемпотентности
+const MaxKeyLength = 255
+
+// Config настройки кэша ключей идемпотентности
+type Config struct {
+	TTL        time.Duration // Время хранения результата запроса
+	MaxEntries int           // Максимальное количество хранимых ключей
+}
+
+// DefaultConfig возвращает настройки кэша идемпотентности по умолчанию
+func DefaultConfig() *Config {
+	return &Config{
+		TTL:        5 * time.Minute,
+		MaxEntries: 10000,
+	}
+}
+
+// Validate проверяет корректность настроек кэша идемпотентности
+func (c *Config) Validate() error {
+	if c.TTL <= 0 {
+		return fmt.Errorf("idempotency TTL must be positive")
+	}
+	if c.MaxEntries <= 0 {
+		return fmt.Errorf("idempotency cache size must be positive")
+	}
+	return nil
+}
+
+// Fingerprint отпечаток запроса, защищающий от повторного использования ключа с другими данными
+type Fingerprint [sha256.Size]byte
+
+// NewFingerprint вычисляет отпечаток по частям запроса (владелец токена, метод, тело и т.д.)
+func NewFingerprint(parts ...[]byte) Fingerprint {
+	hash := sha256.New()
+	for i, part := range parts {
+		if i > 0 {
+			hash.Write([]byte{0})
+		}
+		hash.Write(part)
+	}
+
+	var fingerprint Fingerprint
+	copy(fingerprint[:], hash.Sum(nil))
+	return fingerprint
+}
+
+// Entry запись кэша: выполняемый или завершенный запрос
+type Entry struct {
+	key         string
+	fingerprint Fingerprint
+	expires     time.Time
+	done        chan struct{} // Закрывается по завершении запроса
+	result      any           // nil, если запрос завершился серверной ошибкой
+	element     *list.Element
+}
+
+// Fingerprint возвращает отпечаток запроса, создавшего запись
+func (e *Entry) Fingerprint() Fingerprint {
+	return e.fingerprint
+}
+
+// Done возвращает канал, закрываемый по завершении запроса
+func (e *Entry) Done() <-chan struct{} {
+	return e.done
+}
+
+// Result возвращает сохраненный результат; читать его можно только после закрытия Done
+func (e *Entry) Result() any {
+	return e.result
+}
+
+// Store ограниченный по размеру и времени кэш результатов запросов
+type Store struct {
+	config  Config
+	entries map[string]*Entry
+	order   *list.List // Записи в порядке создания
+	mu      sync.Mutex
+	nowFunc func() time.Time // Источник времени (подменяется в тестах)
+}
+
+// NewStore создает кэш идемпотентности; при nil конфигурации используются настройки по умолчанию
+func NewStore(config *Config) (*Store, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		config:  *config,
+		entries: make(map[string]*Entry),
+		order:   list.New(),
+		nowFunc: time.Now,
+	}, nil
+}
+
+// Begin возвращает запись для ключа. Второе значение true, если запись создана
+// и вызывающий должен выполнить запрос и завершить запись через Complete.
+func (s *Store) Begin(key string, fingerprint Fingerprint) (*Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.nowFunc()
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		return entry, false
+	} else if ok {
+		s.removeUnsafe(entry)
+	}
+
+	entry := &Entry{
+		key:         key,
+		fingerprint: fingerprint,
+		expires:     now.Add(s.config.TTL),
+		done:        make(chan struct{}),
+	}
+	entry.element = s.order.PushBack(entry)
+	s.entries[key] = entry
+
+	s.evictUnsafe(now)
+	return entry, true
+}
+
+// Complete сохраняет результат запроса. При result == nil запись удаляется,
+// чтобы повтор с тем же ключом выполнил запрос заново.
+func (s *Store) Complete(entry *Entry, result any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.result = result
+	if result == nil {
+		s.removeUnsafe(entry)
+	}
+	close(entry.done)
+}
+
+// Len возвращает количество записей в кэше
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// removeUnsafe удаляет запись, если она еще принадлежит кэшу (вызывается под блокировкой)
+func (s *Store) removeUnsafe(entry *Entry) {
+	if s.entries[entry.key] == entry {
+		delete(s.entries, entry.key)
+	}
+	if entry.element != nil {
+		s.order.Remove(entry.element)
+		entry.element = nil
+	}
+}
+
+// evictUnsafe удаляет завершенные записи с истекшим сроком и самые старые записи сверх лимита.
+// Выполняемые запросы не вытесняются (вызывается под блокировкой).
+func (s *Store) evictUnsafe(now time.Time) {
+	for element := s.order.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*Entry)
+
+		expired := !now.Before(entry.expires)
+		overflow := len(s.entries) > s.config.MaxEntries
+		if !expired && !overflow {
+			break
+		}
+
+		select {
+		case <-entry.done:
+			s.removeUnsafe(entry)
+		default:
+		}
+		element = next
+	}
+}
@@ -0,0 +1,87 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, DefaultConfig().Validate())
+	assert.Error(t, (&Config{TTL: 0, MaxEntries: 10}).Validate())
+	assert.Error(t, (&Config{TTL: time.Minute, MaxEntries: 0}).Validate())
+
+	_, err := NewStore(&Config{TTL: time.Minute})
+	assert.Error(t, err)
+}
+
+func TestNewFingerprint(t *testing.T) {
+	assert.Equal(t, NewFingerprint([]byte("a"), []byte("b")), NewFingerprint([]byte("a"), []byte("b")))
+	assert.NotEqual(t, NewFingerprint([]byte("ab"), []byte("")), NewFingerprint([]byte("a"), []byte("b")),
+		"Parts are separated, so moving bytes between them changes the fingerprint")
+}
+
+func TestStore_BeginComplete(t *testing.T) {
+	store, err := NewStore(nil)
+	require.NoError(t, err)
+	fingerprint := NewFingerprint([]byte("request"))
+
+	entry, owner := store.Begin("key", fingerprint)
+	require.True(t, owner)
+
+	waiting, owner := store.Begin("key", fingerprint)
+	assert.False(t, owner, "Concurrent request should wait for the owner")
+	assert.Same(t, entry, waiting)
+
+	store.Complete(entry, nil)
+	<-waiting.Done()
+	assert.Nil(t, waiting.Result())
+	assert.Zero(t, store.Len(), "Server error should not be cached")
+
+	entry, owner = store.Begin("key", fingerprint)
+	require.True(t, owner, "Request should be executed again after a server error")
+	store.Complete(entry, "result")
+
+	replayed, owner := store.Begin("key", fingerprint)
+	assert.False(t, owner)
+	assert.Equal(t, "result", replayed.Result())
+	assert.Equal(t, fingerprint, replayed.Fingerprint())
+}
+
+func TestStore_Eviction(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store, err := NewStore(&Config{TTL: time.Minute, MaxEntries: 2})
+	require.NoError(t, err)
+	store.nowFunc = func() time.Time { return now }
+
+	fingerprint := NewFingerprint([]byte("request"))
+
+	complete := func(key string) {
+		entry, owner := store.Begin(key, fingerprint)
+		require.True(t, owner)
+		store.Complete(entry, "result")
+	}
+
+	t.Run("size limit", func(t *testing.T) {
+		complete("a")
+		complete("b")
+		complete("c")
+		assert.Equal(t, 2, store.Len(), "Oldest entry should be evicted")
+
+		_, owner := store.Begin("a", fingerprint)
+		assert.True(t, owner, "Evicted key should be executed again")
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		complete("d")
+		entry, owner := store.Begin("d", fingerprint)
+		assert.False(t, owner)
+		assert.Equal(t, "result", entry.Result())
+
+		now = now.Add(2 * time.Minute)
+		_, owner = store.Begin("d", fingerprint)
+		assert.True(t, owner, "Expired key should be executed again")
+	})
+}
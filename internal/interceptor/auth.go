@@ -0,0 +1,69 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/grpcapi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryAuth проверяет bearer токен из метаданных authorization и требуемую область доступа.
+// Владелец токена сохраняется в контексте вызова (auth.PrincipalFromContext).
+// Вызов без токена или с неизвестным токеном - Unauthenticated, с недостаточной областью доступа -
+// PermissionDenied; при nil хранилище interceptor ничего не делает.
+func UnaryAuth(store *auth.Store, scope auth.Scope) grpc.UnaryServerInterceptor {
+	if store == nil {
+		return unaryNoop
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, store, scope)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuth проверяет токен при открытии потока, как UnaryAuth
+func StreamAuth(store *auth.Store, scope auth.Scope) grpc.StreamServerInterceptor {
+	if store == nil {
+		return streamNoop
+	}
+
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(stream.Context(), store, scope)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authenticate возвращает контекст с владельцем токена
+func authenticate(ctx context.Context, store *auth.Store, scope auth.Scope) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, grpcapi.AuthorizationKey)
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "bearer token required")
+	}
+
+	token, ok := auth.ParseBearer(values[0])
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "bearer token required")
+	}
+
+	principal, ok := store.Authenticate(token)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	if !principal.HasScope(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "insufficient token scope: %s required", scope)
+	}
+
+	return auth.WithPrincipal(ctx, principal), nil
+}
@@ -0,0 +1,150 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/grpcapi"
+	"github.com/IgorKilipenko/metrical/internal/idempotency"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// recordedCall сохраненный результат вызова
+type recordedCall struct {
+	response proto.Message // nil, если вызов завершился ошибкой
+	err      error
+}
+
+// UnaryIdempotency выполняет вызов не более одного раза для каждого ключа из метаданных idempotency-key.
+// Повтор с тем же ключом получает сохраненный ответ и метаданные idempotent-replayed; повтор с другим
+// запросом отклоняется с InvalidArgument. Серверные ошибки не сохраняются, чтобы вызов можно было повторить.
+// Кэш общий с HTTP API; при nil кэше или без ключа interceptor ничего не делает.
+func UnaryIdempotency(store *idempotency.Store) grpc.UnaryServerInterceptor {
+	if store == nil {
+		return unaryNoop
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		key := idempotencyKey(ctx)
+		if key == "" {
+			return handler(ctx, req)
+		}
+		if len(key) > idempotency.MaxKeyLength {
+			return nil, status.Errorf(codes.InvalidArgument, "%s must not exceed %d characters", grpcapi.IdempotencyKey, idempotency.MaxKeyLength)
+		}
+
+		message, ok := req.(proto.Message)
+		if !ok {
+			return handler(ctx, req)
+		}
+		fingerprint, err := callFingerprint(ctx, info.FullMethod, message)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to marshal request")
+		}
+
+		for {
+			entry, owner := store.Begin(key, fingerprint)
+			if owner {
+				return executeOnce(ctx, store, entry, req, handler)
+			}
+
+			if entry.Fingerprint() != fingerprint {
+				return nil, status.Errorf(codes.InvalidArgument, "%s was already used with a different request", grpcapi.IdempotencyKey)
+			}
+
+			// Ожидаем завершения вызова с тем же ключом
+			select {
+			case <-entry.Done():
+			case <-ctx.Done():
+				return nil, status.FromContextError(ctx.Err()).Err()
+			}
+
+			call, ok := entry.Result().(*recordedCall)
+			if !ok {
+				// Исходный вызов завершился серверной ошибкой - выполняем заново
+				continue
+			}
+
+			_ = grpc.SetHeader(ctx, metadata.Pairs(grpcapi.IdempotentReplayedKey, "true"))
+			if call.err != nil {
+				return nil, call.err
+			}
+			// Ответ копируется: внешние interceptors (подпись) изменяют его
+			return proto.Clone(call.response), nil
+		}
+	}
+}
+
+// StreamIdempotency отклоняет потоки с ключом идемпотентности: результат потока нельзя сохранить
+// до получения всех сообщений, поэтому повторять с ключом нужно унарный вызов UpdateMetrics
+func StreamIdempotency(store *idempotency.Store) grpc.StreamServerInterceptor {
+	if store == nil {
+		return streamNoop
+	}
+
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if idempotencyKey(stream.Context()) != "" {
+			return status.Errorf(codes.InvalidArgument, "%s is not supported for streaming calls", grpcapi.IdempotencyKey)
+		}
+		return handler(srv, stream)
+	}
+}
+
+// executeOnce выполняет вызов и сохраняет его результат. Запись завершается даже при панике обработчика,
+// чтобы не блокировать ожидающие вызовы.
+func executeOnce(ctx context.Context, store *idempotency.Store, entry *idempotency.Entry, req any, handler grpc.UnaryHandler) (response any, err error) {
+	var result any
+	defer func() { store.Complete(entry, result) }()
+
+	response, err = handler(ctx, req)
+	if err != nil {
+		if !retryableCode(status.Code(err)) {
+			result = &recordedCall{err: err}
+		}
+		return response, err
+	}
+
+	if message, ok := response.(proto.Message); ok {
+		result = &recordedCall{response: proto.Clone(message)}
+	}
+	return response, nil
+}
+
+// idempotencyKey возвращает ключ идемпотентности из метаданных вызова
+func idempotencyKey(ctx context.Context) string {
+	values := metadata.ValueFromIncomingContext(ctx, grpcapi.IdempotencyKey)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// callFingerprint вычисляет отпечаток вызова по владельцу токена, методу и сообщению.
+// Владелец токена входит в отпечаток, чтобы ключ одного клиента не возвращал ответ другому.
+func callFingerprint(ctx context.Context, method string, req proto.Message) (idempotency.Fingerprint, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return idempotency.Fingerprint{}, err
+	}
+
+	var principal string
+	if p, ok := auth.PrincipalFromContext(ctx); ok {
+		principal = p.Name
+	}
+	return idempotency.NewFingerprint([]byte(principal), []byte(method), data), nil
+}
+
+// retryableCode сообщает, что вызов завершился серверной ошибкой и клиент может его повторить
+func retryableCode(code codes.Code) bool {
+	switch code {
+	case codes.Canceled, codes.Unknown, codes.DeadlineExceeded, codes.ResourceExhausted,
+		codes.Aborted, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
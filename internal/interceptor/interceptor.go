@@ -0,0 +1,62 @@
+// Package interceptor содержит interceptors gRPC сервера - аналоги HTTP middleware:
+// логирование, проверку доверенной подсети, аутентификацию токенами, ограничение частоты и подписи.
+package interceptor
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Methods набор полных имен методов gRPC (/package.Service/Method)
+type Methods map[string]bool
+
+// NewMethods создает набор методов
+func NewMethods(names ...string) Methods {
+	methods := make(Methods, len(names))
+	for _, name := range names {
+		methods[name] = true
+	}
+	return methods
+}
+
+// UnaryFor применяет interceptor только к методам из набора
+func UnaryFor(methods Methods, interceptor grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !methods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, info, handler)
+	}
+}
+
+// StreamFor применяет interceptor только к методам из набора
+func StreamFor(methods Methods, interceptor grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !methods[info.FullMethod] {
+			return handler(srv, stream)
+		}
+		return interceptor(srv, stream, info, handler)
+	}
+}
+
+// unaryNoop interceptor, который только вызывает обработчик
+func unaryNoop(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(ctx, req)
+}
+
+// streamNoop interceptor, который только вызывает обработчик
+func streamNoop(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, stream)
+}
+
+// contextStream ServerStream с замененным контекстом (например, с владельцем токена)
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context возвращает контекст потока
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
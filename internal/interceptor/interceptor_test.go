@@ -0,0 +1,260 @@
+package interceptor
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/grpcapi"
+	"github.com/IgorKilipenko/metrical/internal/idempotency"
+	"github.com/IgorKilipenko/metrical/internal/ratelimit"
+	pb "github.com/IgorKilipenko/metrical/pkg/metricalpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const testMethod = "/metrical.v1.MetricsService/UpdateMetric"
+
+var testInfo = &grpc.UnaryServerInfo{FullMethod: testMethod}
+
+// okHandler возвращает запрос как ответ
+func okHandler(ctx context.Context, req any) (any, error) {
+	return req, nil
+}
+
+// incoming создает входящий контекст с метаданными и адресом соединения
+func incoming(addr string, pairs ...string) context.Context {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(pairs...))
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+	return peer.NewContext(ctx, &peer.Peer{Addr: tcpAddr})
+}
+
+func TestUnaryAuth(t *testing.T) {
+	store, err := auth.NewStore([]auth.Token{
+		{Name: "agent", Token: "write-token", Scope: auth.ScopeWrite},
+		{Name: "dashboard", Token: "read-token", Scope: auth.ScopeRead},
+		{Name: "root", Token: "admin-token", Scope: auth.ScopeAdmin},
+	})
+	require.NoError(t, err)
+
+	// Обработчик возвращает имя владельца токена из контекста
+	whoami := func(ctx context.Context, req any) (any, error) {
+		principal, ok := auth.PrincipalFromContext(ctx)
+		require.True(t, ok, "Principal should be available in call context")
+		return principal.Name, nil
+	}
+	interceptor := UnaryAuth(store, auth.ScopeWrite)
+
+	tests := []struct {
+		name          string
+		authorization string
+		expectedCode  codes.Code
+		expectedName  string
+	}{
+		{name: "write token", authorization: "Bearer write-token", expectedCode: codes.OK, expectedName: "agent"},
+		{name: "admin token", authorization: "Bearer admin-token", expectedCode: codes.OK, expectedName: "root"},
+		{name: "read token", authorization: "Bearer read-token", expectedCode: codes.PermissionDenied},
+		{name: "unknown token", authorization: "Bearer other-token", expectedCode: codes.Unauthenticated},
+		{name: "invalid scheme", authorization: "Basic write-token", expectedCode: codes.Unauthenticated},
+		{name: "missing token", expectedCode: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.authorization != "" {
+				ctx = incoming("127.0.0.1:5000", grpcapi.AuthorizationKey, tt.authorization)
+			}
+
+			resp, err := interceptor(ctx, nil, testInfo, whoami)
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			if tt.expectedCode == codes.OK {
+				assert.Equal(t, tt.expectedName, resp)
+			}
+		})
+	}
+
+	t.Run("nil store", func(t *testing.T) {
+		_, err := UnaryAuth(nil, auth.ScopeWrite)(context.Background(), "req", testInfo, okHandler)
+		assert.NoError(t, err)
+	})
+}
+
+func TestUnaryTrustedSubnet(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("192.168.1.0/24")
+	require.NoError(t, err)
+	interceptor := UnaryTrustedSubnet(subnet)
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		expectedCode codes.Code
+	}{
+		{name: "x-real-ip in subnet", ctx: incoming("192.168.1.1:5000", grpcapi.RealIPKey, "192.168.1.10"), expectedCode: codes.OK},
+		{name: "spoofed x-real-ip from outside subnet", ctx: incoming("203.0.113.7:5000", grpcapi.RealIPKey, "192.168.1.10"), expectedCode: codes.PermissionDenied},
+		{name: "x-real-ip outside subnet", ctx: incoming("192.168.1.2:5000", grpcapi.RealIPKey, "10.0.0.1"), expectedCode: codes.PermissionDenied},
+		{name: "invalid x-real-ip", ctx: incoming("192.168.1.2:5000", grpcapi.RealIPKey, "not-an-ip"), expectedCode: codes.PermissionDenied},
+		{name: "peer address in subnet", ctx: incoming("192.168.1.20:5000"), expectedCode: codes.OK},
+		{name: "peer address outside subnet", ctx: incoming("127.0.0.1:5000"), expectedCode: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := interceptor(tt.ctx, nil, testInfo, okHandler)
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestUnaryRateLimit(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(&ratelimit.Config{Rate: 1, Burst: 2, MaxClients: 10})
+	require.NoError(t, err)
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+	interceptor := UnaryRateLimit(limiter, []*net.IPNet{proxies})
+
+	ctx := incoming("127.0.0.1:5000")
+	for i := 0; i < 2; i++ {
+		_, err := interceptor(ctx, nil, testInfo, okHandler)
+		require.NoError(t, err, "call %d should fit burst", i)
+	}
+
+	_, err = interceptor(ctx, nil, testInfo, okHandler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = interceptor(incoming("127.0.0.2:5000"), nil, testInfo, okHandler)
+	assert.NoError(t, err, "Other clients should have their own limit")
+
+	// x-real-ip от клиента вне доверенных прокси игнорируется
+	_, err = interceptor(incoming("127.0.0.1:5001", grpcapi.RealIPKey, "192.168.1.1"), nil, testInfo, okHandler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Доверенный прокси передает адрес клиента
+	_, err = interceptor(incoming("10.0.0.1:5000", grpcapi.RealIPKey, "192.168.1.1"), nil, testInfo, okHandler)
+	assert.NoError(t, err)
+}
+
+func TestUnaryIdempotency(t *testing.T) {
+	store, err := idempotency.NewStore(nil)
+	require.NoError(t, err)
+	interceptor := UnaryIdempotency(store)
+
+	calls := 0
+	var failure error
+	handler := func(ctx context.Context, req any) (any, error) {
+		calls++
+		if failure != nil {
+			return nil, failure
+		}
+		return &pb.UpdateMetricsResponse{}, nil
+	}
+
+	delta := int64(1)
+	req := &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Id: "requests", Type: pb.MetricType_METRIC_TYPE_COUNTER, Delta: &delta}}}
+	call := func(key string, req any) (any, error) {
+		return interceptor(incoming("127.0.0.1:5000", grpcapi.IdempotencyKey, key), req, testInfo, handler)
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		calls = 0
+		first, err := call("batch-1", req)
+		require.NoError(t, err)
+		replayed, err := call("batch-1", req)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, calls, "Retry with the same key must not apply the batch again")
+		assert.NotSame(t, first, replayed, "Replayed response is a copy")
+	})
+
+	t.Run("key reused with different request", func(t *testing.T) {
+		other := &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Id: "other", Type: pb.MetricType_METRIC_TYPE_COUNTER, Delta: &delta}}}
+		_, err := call("batch-1", other)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("server error is not cached", func(t *testing.T) {
+		calls = 0
+		failure = status.Error(codes.Unavailable, "storage unavailable")
+		_, err := call("batch-2", req)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+
+		failure = nil
+		_, err = call("batch-2", req)
+		require.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("client error is cached", func(t *testing.T) {
+		calls = 0
+		failure = status.Error(codes.InvalidArgument, "bad metric")
+		_, err := call("batch-3", req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		failure = nil
+		_, err = call("batch-3", req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("without key", func(t *testing.T) {
+		calls = 0
+		for range 2 {
+			_, err := interceptor(incoming("127.0.0.1:5000"), req, testInfo, handler)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestUnarySignature(t *testing.T) {
+	const key = "secret"
+	interceptor := UnarySignature(key)
+
+	value := 1.5
+	signed := &pb.Metric{Id: "load", Type: pb.MetricType_METRIC_TYPE_GAUGE, Value: &value}
+	grpcapi.SignMetric(signed, key)
+
+	t.Run("signed request and signed response", func(t *testing.T) {
+		handler := func(ctx context.Context, req any) (any, error) {
+			return &pb.GetMetricResponse{Metric: &pb.Metric{Id: "load", Type: pb.MetricType_METRIC_TYPE_GAUGE, Value: &value}}, nil
+		}
+		resp, err := interceptor(context.Background(), &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{signed}}, testInfo, handler)
+		require.NoError(t, err)
+		assert.True(t, grpcapi.VerifyMetric(resp.(*pb.GetMetricResponse).GetMetric(), key))
+	})
+
+	t.Run("missing hash", func(t *testing.T) {
+		unsigned := &pb.Metric{Id: "load", Type: pb.MetricType_METRIC_TYPE_GAUGE, Value: &value}
+		_, err := interceptor(context.Background(), &pb.UpdateMetricRequest{Metric: unsigned}, testInfo, okHandler)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		_, err := UnarySignature("other")(context.Background(), &pb.UpdateMetricRequest{Metric: signed}, testInfo, okHandler)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("request without metrics", func(t *testing.T) {
+		_, err := interceptor(context.Background(), &pb.GetMetricRequest{Id: "load"}, testInfo, okHandler)
+		assert.NoError(t, err)
+	})
+}
+
+func TestUnaryFor(t *testing.T) {
+	reject := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return nil, status.Error(codes.PermissionDenied, "rejected")
+	}
+	interceptor := UnaryFor(NewMethods(testMethod), reject)
+
+	_, err := interceptor(context.Background(), nil, testInfo, okHandler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "Selected method should be intercepted")
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/other/Method"}, okHandler)
+	assert.NoError(t, err, "Other methods should be passed through")
+}
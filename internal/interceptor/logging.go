@@ -0,0 +1,59 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryLogging логирует начало и завершение вызова: метод, адрес клиента, код ответа и длительность
+func UnaryLogging(log logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		log.Info("gRPC request started", "method", info.FullMethod, "peer", peerAddr(ctx))
+
+		resp, err := handler(ctx, req)
+		logCompleted(log, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamLogging логирует начало и завершение потокового вызова
+func StreamLogging(log logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		log.Info("gRPC stream started", "method", info.FullMethod, "peer", peerAddr(stream.Context()))
+
+		err := handler(srv, stream)
+		logCompleted(log, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// logCompleted логирует результат вызова; ошибки сервера логируются с уровнем Error
+func logCompleted(log logger.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	args := []any{"method", method, "code", code.String(), "duration", time.Since(start)}
+
+	switch code {
+	case codes.OK:
+		log.Info("gRPC request completed", args...)
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+		log.Error("gRPC request failed", append(args, "error", err)...)
+	default:
+		log.Warn("gRPC request rejected", append(args, "error", err)...)
+	}
+}
+
+// peerAddr возвращает адрес соединения клиента
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package interceptor
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/grpcapi"
+	"github.com/IgorKilipenko/metrical/internal/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryRateLimit ограничивает частоту вызовов каждого клиента.
+// Клиент определяется по имени токена (если вызов аутентифицирован), иначе по IP адреса соединения;
+// метаданным x-real-ip interceptor доверяет только для соединений из trustedProxies.
+// При превышении лимита возвращается ResourceExhausted с метаданными retry-after;
+// при nil ограничителе interceptor ничего не делает.
+func UnaryRateLimit(limiter *ratelimit.Limiter, trustedProxies []*net.IPNet) grpc.UnaryServerInterceptor {
+	if limiter == nil {
+		return unaryNoop
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := allow(ctx, limiter, trustedProxies, grpc.SetHeader); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamRateLimit учитывает открытие потока как один вызов; сообщения потока не ограничиваются
+func StreamRateLimit(limiter *ratelimit.Limiter, trustedProxies []*net.IPNet) grpc.StreamServerInterceptor {
+	if limiter == nil {
+		return streamNoop
+	}
+
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		setHeader := func(_ context.Context, md metadata.MD) error { return stream.SetHeader(md) }
+		if err := allow(stream.Context(), limiter, trustedProxies, setHeader); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// allow проверяет лимит клиента и при превышении передает время ожидания в метаданных ответа
+func allow(ctx context.Context, limiter *ratelimit.Limiter, trustedProxies []*net.IPNet, setHeader func(context.Context, metadata.MD) error) error {
+	allowed, wait := limiter.Allow(clientKey(ctx, trustedProxies))
+	if allowed {
+		return nil
+	}
+
+	_ = setHeader(ctx, metadata.Pairs(grpcapi.RetryAfterKey, strconv.Itoa(retryAfterSeconds(wait))))
+	return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+}
+
+// clientKey возвращает идентификатор клиента для ограничения частоты вызовов
+func clientKey(ctx context.Context, trustedProxies []*net.IPNet) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		return "token:" + principal.Name
+	}
+
+	ip := peerIP(ctx)
+	if ip == nil {
+		return "addr:" + peerAddr(ctx)
+	}
+	if containsIP(trustedProxies, ip) {
+		if values := metadata.ValueFromIncomingContext(ctx, grpcapi.RealIPKey); len(values) > 0 {
+			if realIP := net.ParseIP(strings.TrimSpace(values[0])); realIP != nil {
+				return "ip:" + realIP.String()
+			}
+		}
+	}
+	return "ip:" + ip.String()
+}
+
+// containsIP проверяет, входит ли IP в одну из подсетей
+func containsIP(subnets []*net.IPNet, ip net.IP) bool {
+	for _, subnet := range subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfterSeconds округляет время ожидания вверх до целых секунд (не меньше 1)
+func retryAfterSeconds(wait time.Duration) int {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
@@ -0,0 +1,107 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/IgorKilipenko/metrical/internal/grpcapi"
+	pb "github.com/IgorKilipenko/metrical/pkg/metricalpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// metricMessage сообщение с одной метрикой (UpdateMetricRequest, GetMetricResponse и т.д.)
+type metricMessage interface {
+	GetMetric() *pb.Metric
+}
+
+// metricsMessage сообщение с несколькими метриками (UpdateMetricsRequest, ListMetricsResponse и т.д.)
+type metricsMessage interface {
+	GetMetrics() []*pb.Metric
+}
+
+// UnarySignature проверяет подписи метрик в запросах и подписывает метрики в ответах общим ключом.
+// Каждая метрика запроса обязана содержать hash - подпись HMAC-SHA256 строки "id:type:value"
+// (аналог обязательного заголовка HashSHA256 для POST запросов HTTP API);
+// отсутствующая или неверная подпись - InvalidArgument. При пустом ключе interceptor ничего не делает.
+func UnarySignature(key string) grpc.UnaryServerInterceptor {
+	if key == "" {
+		return unaryNoop
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := verifyMessage(req, key); err != nil {
+			return nil, err
+		}
+
+		resp, err := handler(ctx, req)
+		if err == nil {
+			signMessage(resp, key)
+		}
+		return resp, err
+	}
+}
+
+// StreamSignature проверяет подписи метрик в каждом сообщении потока и подписывает ответы
+func StreamSignature(key string) grpc.StreamServerInterceptor {
+	if key == "" {
+		return streamNoop
+	}
+
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &signedStream{ServerStream: stream, key: key})
+	}
+}
+
+// signedStream проверяет подписи полученных сообщений и подписывает отправляемые
+type signedStream struct {
+	grpc.ServerStream
+	key string
+}
+
+// RecvMsg получает сообщение и проверяет подписи его метрик
+func (s *signedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return verifyMessage(m, s.key)
+}
+
+// SendMsg подписывает метрики сообщения перед отправкой
+func (s *signedStream) SendMsg(m any) error {
+	signMessage(m, s.key)
+	return s.ServerStream.SendMsg(m)
+}
+
+// verifyMessage проверяет подписи всех метрик сообщения
+func verifyMessage(message any, key string) error {
+	for _, metric := range messageMetrics(message) {
+		if metric.GetHash() == "" {
+			return status.Errorf(codes.InvalidArgument, "missing hash for metric %s", metric.GetId())
+		}
+		if !grpcapi.VerifyMetric(metric, key) {
+			return status.Errorf(codes.InvalidArgument, "invalid hash for metric %s", metric.GetId())
+		}
+	}
+	return nil
+}
+
+// signMessage подписывает все метрики сообщения
+func signMessage(message any, key string) {
+	for _, metric := range messageMetrics(message) {
+		grpcapi.SignMetric(metric, key)
+	}
+}
+
+// messageMetrics возвращает метрики сообщения
+func messageMetrics(message any) []*pb.Metric {
+	switch m := message.(type) {
+	case metricMessage:
+		if metric := m.GetMetric(); metric != nil {
+			return []*pb.Metric{metric}
+		}
+	case metricsMessage:
+		return m.GetMetrics()
+	}
+	return nil
+}
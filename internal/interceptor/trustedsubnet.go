@@ -0,0 +1,79 @@
+package interceptor
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/IgorKilipenko/metrical/internal/grpcapi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryTrustedSubnet пропускает только вызовы из доверенной подсети.
+// В подсети должен находиться адрес соединения и, если переданы метаданные x-real-ip, адрес из них.
+// Вызовы из других подсетей и с некорректным x-real-ip отклоняются с кодом PermissionDenied;
+// при nil подсети interceptor ничего не делает.
+func UnaryTrustedSubnet(subnet *net.IPNet) grpc.UnaryServerInterceptor {
+	if subnet == nil {
+		return unaryNoop
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := checkSubnet(ctx, subnet); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamTrustedSubnet проверяет подсеть клиента при открытии потока, как UnaryTrustedSubnet
+func StreamTrustedSubnet(subnet *net.IPNet) grpc.StreamServerInterceptor {
+	if subnet == nil {
+		return streamNoop
+	}
+
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkSubnet(stream.Context(), subnet); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// checkSubnet проверяет, что клиент находится в доверенной подсети
+func checkSubnet(ctx context.Context, subnet *net.IPNet) error {
+	if !inSubnet(ctx, subnet) {
+		return status.Error(codes.PermissionDenied, "client is not in trusted subnet")
+	}
+	return nil
+}
+
+// inSubnet проверяет, что адрес соединения и x-real-ip (если передан) входят в подсеть:
+// метаданные задает клиент, поэтому одного x-real-ip недостаточно
+func inSubnet(ctx context.Context, subnet *net.IPNet) bool {
+	if ip := peerIP(ctx); ip == nil || !subnet.Contains(ip) {
+		return false
+	}
+	if values := metadata.ValueFromIncomingContext(ctx, grpcapi.RealIPKey); len(values) > 0 {
+		ip := net.ParseIP(strings.TrimSpace(values[0]))
+		return ip != nil && subnet.Contains(ip)
+	}
+	return true
+}
+
+// peerIP возвращает IP адреса соединения (nil, если адрес некорректен)
+func peerIP(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return net.ParseIP(host)
+}
@@ -0,0 +1,278 @@
+// Package lineprotocol разбирает метрики в формате InfluxDB line protocol
+// и преобразует поля в метрики gauge и counter.
+package lineprotocol
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+)
+
+// Precision точность временных меток
+type Precision string
+
+const (
+	PrecisionNanoseconds  Precision = "ns"
+	PrecisionMicroseconds Precision = "us"
+	PrecisionMilliseconds Precision = "ms"
+	PrecisionSeconds      Precision = "s"
+)
+
+// ParsePrecision разбирает параметр precision (пустая строка - наносекунды)
+func ParsePrecision(value string) (Precision, error) {
+	switch value {
+	case "", "ns", "n":
+		return PrecisionNanoseconds, nil
+	case "us", "u":
+		return PrecisionMicroseconds, nil
+	case "ms":
+		return PrecisionMilliseconds, nil
+	case "s":
+		return PrecisionSeconds, nil
+	default:
+		return "", fmt.Errorf("invalid precision %q: expected ns, us, ms or s", value)
+	}
+}
+
+// unit длительность единицы временной метки
+func (p Precision) unit() time.Duration {
+	switch p {
+	case PrecisionMicroseconds:
+		return time.Microsecond
+	case PrecisionMilliseconds:
+		return time.Millisecond
+	case PrecisionSeconds:
+		return time.Second
+	default:
+		return time.Nanosecond
+	}
+}
+
+// FieldType тип значения поля
+type FieldType int
+
+const (
+	FieldFloat FieldType = iota
+	FieldInteger
+	FieldUnsigned
+	FieldBoolean
+	FieldString
+)
+
+// Tag тег точки
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Field поле точки
+type Field struct {
+	Key   string
+	Type  FieldType
+	Float float64 // Значение для FieldFloat и FieldBoolean (1 или 0)
+	Int   int64   // Значение для FieldInteger и FieldUnsigned
+	Str   string  // Значение для FieldString
+}
+
+// Point точка: measurement, теги, поля и необязательная временная метка
+type Point struct {
+	Measurement string
+	Tags        []Tag // В порядке ключей
+	Fields      []Field
+	Time        time.Time // Нулевое значение - метка не задана
+}
+
+// Parse разбирает тело запроса: одна точка на строку, пустые строки и комментарии (#) пропускаются.
+// Ошибка содержит номер строки.
+func Parse(data []byte, precision Precision) ([]Point, error) {
+	var points []Point
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		point, err := ParseLine(string(line), precision)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		points = append(points, *point)
+	}
+	return points, nil
+}
+
+// ParseLine разбирает одну строку вида measurement[,tag=value...] field=value[,field=value...] [timestamp]
+func ParseLine(line string, precision Precision) (*Point, error) {
+	// Строка состоит из трех секций, разделенных неэкранированными пробелами вне кавычек
+	sections := splitUnescaped(line, ' ', true)
+	sections = slices.DeleteFunc(sections, func(s string) bool { return s == "" })
+	if len(sections) < 2 || len(sections) > 3 {
+		return nil, fmt.Errorf("invalid line %q: expected measurement[,tags] fields [timestamp]", line)
+	}
+
+	point := &Point{}
+	key := splitUnescaped(sections[0], ',', false)
+	point.Measurement = unescape(key[0])
+	if point.Measurement == "" {
+		return nil, fmt.Errorf("measurement cannot be empty")
+	}
+
+	for _, rawTag := range key[1:] {
+		tagKey, tagValue, ok := cutUnescaped(rawTag, '=')
+		if !ok || tagKey == "" || tagValue == "" {
+			return nil, fmt.Errorf("invalid tag %q", rawTag)
+		}
+		point.Tags = append(point.Tags, Tag{Key: unescape(tagKey), Value: unescape(tagValue)})
+	}
+	slices.SortFunc(point.Tags, func(a, b Tag) int { return strings.Compare(a.Key, b.Key) })
+
+	for _, rawField := range splitUnescaped(sections[1], ',', true) {
+		field, err := parseField(rawField)
+		if err != nil {
+			return nil, err
+		}
+		point.Fields = append(point.Fields, *field)
+	}
+
+	if len(sections) == 3 {
+		timestamp, err := strconv.ParseInt(sections[2], 10, 64)
+		unit := int64(precision.unit())
+		if err != nil || timestamp > math.MaxInt64/unit || timestamp < math.MinInt64/unit {
+			return nil, fmt.Errorf("invalid timestamp %q", sections[2])
+		}
+		point.Time = time.Unix(0, timestamp*unit)
+	}
+
+	return point, nil
+}
+
+// parseField разбирает поле key=value
+func parseField(raw string) (*Field, error) {
+	key, value, ok := cutUnescaped(raw, '=')
+	if !ok || key == "" || value == "" {
+		return nil, fmt.Errorf("invalid field %q", raw)
+	}
+
+	field := &Field{Key: unescape(key)}
+	switch {
+	case strings.HasPrefix(value, `"`):
+		if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+			return nil, fmt.Errorf("invalid string field %q", raw)
+		}
+		field.Type = FieldString
+		field.Str = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+	case strings.HasSuffix(value, "i"):
+		parsed, err := strconv.ParseInt(strings.TrimSuffix(value, "i"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer field %q", raw)
+		}
+		field.Type, field.Int = FieldInteger, parsed
+	case strings.HasSuffix(value, "u"):
+		parsed, err := strconv.ParseUint(strings.TrimSuffix(value, "u"), 10, 63)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unsigned field %q", raw)
+		}
+		field.Type, field.Int = FieldUnsigned, int64(parsed)
+	default:
+		if boolean, ok := parseBool(value); ok {
+			field.Type = FieldBoolean
+			if boolean {
+				field.Float = 1
+			}
+			break
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float field %q", raw)
+		}
+		field.Type, field.Float = FieldFloat, parsed
+	}
+	return field, nil
+}
+
+// parseBool разбирает логическое значение в написаниях line protocol
+func parseBool(value string) (bool, bool) {
+	switch value {
+	case "t", "T", "true", "True", "TRUE":
+		return true, true
+	case "f", "F", "false", "False", "FALSE":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// MetricName строит имя метрики из measurement, тегов и ключа поля:
+// measurement[.tag=value...].field, например cpu.host=server01.usage_idle
+func MetricName(measurement string, tags []Tag, field string) string {
+	var b strings.Builder
+	b.WriteString(measurement)
+	for _, tag := range tags {
+		b.WriteString("." + tag.Key + "=" + tag.Value)
+	}
+	b.WriteString("." + field)
+	return b.String()
+}
+
+// Metrics преобразует поля точек в метрики: целые поля (i, u) - counter, дробные и логические - gauge.
+// Строковые поля не имеют числового значения и возвращаются в skipped.
+func Metrics(points []Point) (metrics []models.Metrics, skipped []string) {
+	for _, point := range points {
+		for _, field := range point.Fields {
+			name := MetricName(point.Measurement, point.Tags, field.Key)
+			switch field.Type {
+			case FieldInteger, FieldUnsigned:
+				delta := field.Int
+				metrics = append(metrics, models.Metrics{ID: name, MType: models.Counter, Delta: &delta})
+			case FieldFloat, FieldBoolean:
+				value := field.Float
+				metrics = append(metrics, models.Metrics{ID: name, MType: models.Gauge, Value: &value})
+			default:
+				skipped = append(skipped, name)
+			}
+		}
+	}
+	return metrics, skipped
+}
+
+// splitUnescaped делит строку по разделителю, пропуская экранированные (\) символы
+// и, если quotes = true, разделители внутри строковых значений в кавычках
+func splitUnescaped(s string, sep byte, quotes bool) []string {
+	var parts []string
+	start, inQuotes := 0, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case quotes && s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// cutUnescaped делит строку по первому неэкранированному разделителю
+func cutUnescaped(s string, sep byte) (string, string, bool) {
+	parts := splitUnescaped(s, sep, false)
+	if len(parts) < 2 {
+		return s, "", false
+	}
+	return parts[0], s[len(parts[0])+1:], true
+}
+
+// unescapeReplacer снимает экранирование запятых, пробелов, знаков равенства и обратной косой черты
+var unescapeReplacer = strings.NewReplacer(`\,`, ",", `\ `, " ", `\=`, "=", `\\`, `\`)
+
+// unescape снимает экранирование имени measurement, тега или поля
+func unescape(s string) string {
+	return unescapeReplacer.Replace(s)
+}
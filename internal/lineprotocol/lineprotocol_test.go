@@ -0,0 +1,128 @@
+package lineprotocol
+
+import (
+	"testing"
+	"time"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	point, err := ParseLine(`cpu,region=eu,host=server01 usage_idle=98.5,procs=12i,uptime=3600u,online=t,status="ok, fine" 1700000000`, PrecisionSeconds)
+	require.NoError(t, err)
+
+	assert.Equal(t, "cpu", point.Measurement)
+	assert.Equal(t, []Tag{{Key: "host", Value: "server01"}, {Key: "region", Value: "eu"}}, point.Tags, "Tags should be sorted by key")
+	assert.Equal(t, []Field{
+		{Key: "usage_idle", Type: FieldFloat, Float: 98.5},
+		{Key: "procs", Type: FieldInteger, Int: 12},
+		{Key: "uptime", Type: FieldUnsigned, Int: 3600},
+		{Key: "online", Type: FieldBoolean, Float: 1},
+		{Key: "status", Type: FieldString, Str: "ok, fine"},
+	}, point.Fields)
+	assert.Equal(t, time.Unix(1700000000, 0), point.Time)
+}
+
+func TestParseLine_Escaping(t *testing.T) {
+	point, err := ParseLine(`disk\ io,path=C:\\data,mount\=point=a\,b read\ bytes=1i,note="say \"hi\""`, PrecisionNanoseconds)
+	require.NoError(t, err)
+
+	assert.Equal(t, "disk io", point.Measurement)
+	assert.Equal(t, []Tag{{Key: "mount=point", Value: "a,b"}, {Key: "path", Value: `C:\data`}}, point.Tags)
+	assert.Equal(t, "read bytes", point.Fields[0].Key)
+	assert.Equal(t, `say "hi"`, point.Fields[1].Str)
+	assert.True(t, point.Time.IsZero(), "Timestamp is optional")
+}
+
+func TestParseLine_Precision(t *testing.T) {
+	tests := []struct {
+		precision Precision
+		timestamp string
+	}{
+		{PrecisionNanoseconds, "1700000000000000000"},
+		{PrecisionMicroseconds, "1700000000000000"},
+		{PrecisionMilliseconds, "1700000000000"},
+		{PrecisionSeconds, "1700000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.precision), func(t *testing.T) {
+			point, err := ParseLine("mem used=1 "+tt.timestamp, tt.precision)
+			require.NoError(t, err)
+			assert.Equal(t, time.Unix(1700000000, 0), point.Time)
+		})
+	}
+
+	_, err := ParseLine("mem used=1 1700000000000000000", PrecisionSeconds)
+	assert.Error(t, err, "Timestamp overflow should be rejected")
+}
+
+func TestParsePrecision(t *testing.T) {
+	for value, expected := range map[string]Precision{"": PrecisionNanoseconds, "n": PrecisionNanoseconds, "u": PrecisionMicroseconds, "ms": PrecisionMilliseconds, "s": PrecisionSeconds} {
+		precision, err := ParsePrecision(value)
+		require.NoError(t, err)
+		assert.Equal(t, expected, precision)
+	}
+
+	_, err := ParsePrecision("h")
+	assert.Error(t, err)
+}
+
+func TestParseLine_Invalid(t *testing.T) {
+	for _, line := range []string{
+		"cpu",
+		"cpu usage",
+		",host=a usage=1",
+		"cpu,host usage=1",
+		"cpu,host= usage=1",
+		"cpu usage=",
+		"cpu usage=abc",
+		"cpu usage=1.5i",
+		"cpu usage=-1u",
+		`cpu status="unterminated`,
+		"cpu usage=1 yesterday",
+		"cpu usage=1 1700000000 extra",
+	} {
+		t.Run(line, func(t *testing.T) {
+			_, err := ParseLine(line, PrecisionNanoseconds)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	data := []byte("# telegraf batch\ncpu,host=a usage=1.5\n\nmem,host=a used=10i\n")
+
+	points, err := Parse(data, PrecisionNanoseconds)
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+
+	_, err = Parse([]byte("cpu usage=1\ncpu usage\n"), PrecisionNanoseconds)
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestMetrics(t *testing.T) {
+	points, err := Parse([]byte(`cpu,host=server01,region=eu usage_idle=98.5,procs=12i,online=false,status="ok"
+requests count=3u`), PrecisionNanoseconds)
+	require.NoError(t, err)
+
+	metrics, skipped := Metrics(points)
+	require.Len(t, metrics, 4)
+	assert.Equal(t, []string{"cpu.host=server01.region=eu.status"}, skipped)
+
+	assert.Equal(t, "cpu.host=server01.region=eu.usage_idle", metrics[0].ID)
+	assert.Equal(t, models.Gauge, metrics[0].MType)
+	assert.Equal(t, 98.5, *metrics[0].Value)
+
+	assert.Equal(t, "cpu.host=server01.region=eu.procs", metrics[1].ID)
+	assert.Equal(t, models.Counter, metrics[1].MType)
+	assert.Equal(t, int64(12), *metrics[1].Delta)
+
+	assert.Equal(t, models.Gauge, metrics[2].MType)
+	assert.Equal(t, 0.0, *metrics[2].Value)
+
+	assert.Equal(t, "requests.count", metrics[3].ID)
+	assert.Equal(t, int64(3), *metrics[3].Delta)
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+)
+
+// AuthMiddleware проверяет bearer токен из заголовка Authorization и требуемую область доступа.
+// Владелец токена сохраняется в контексте запроса (auth.PrincipalFromContext).
+// Запрос без токена или с неизвестным токеном - 401, с недостаточной областью доступа - 403;
+// при nil хранилище middleware ничего не делает.
+func AuthMiddleware(store *auth.Store, scope auth.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if store == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := auth.ParseBearer(r.Header.Get("Authorization"))
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrical"`)
+				http.Error(w, "Unauthorized: bearer token required", http.StatusUnauthorized)
+				return
+			}
+
+			principal, ok := store.Authenticate(token)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrical", error="invalid_token"`)
+				http.Error(w, "Unauthorized: invalid token", http.StatusUnauthorized)
+				return
+			}
+
+			if !principal.HasScope(scope) {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="metrical", error="insufficient_scope", scope="%s"`, scope))
+				http.Error(w, "Forbidden: insufficient token scope", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
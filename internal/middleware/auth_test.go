@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	store, err := auth.NewStore([]auth.Token{
+		{Name: "agent", Token: "write-token", Scope: auth.ScopeWrite},
+		{Name: "dashboard", Token: "read-token", Scope: auth.ScopeRead},
+		{Name: "root", Token: "admin-token", Scope: auth.ScopeAdmin},
+	})
+	require.NoError(t, err)
+
+	// Обработчик возвращает имя владельца токена из контекста
+	whoami := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		require.True(t, ok, "Principal should be available in request context")
+		w.Write([]byte(principal.Name))
+	})
+	handler := AuthMiddleware(store, auth.ScopeWrite)(whoami)
+
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "write token",
+			authorization:  "Bearer write-token",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "agent",
+		},
+		{
+			name:           "admin token",
+			authorization:  "Bearer admin-token",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "root",
+		},
+		{
+			name:           "read token",
+			authorization:  "Bearer read-token",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "unknown token",
+			authorization:  "Bearer other-token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "basic auth",
+			authorization:  "Basic dXNlcjpwYXNz",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/update", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_Disabled(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, authenticated := auth.PrincipalFromContext(r.Context())
+		assert.False(t, authenticated)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("POST", "/update", nil)
+	w := httptest.NewRecorder()
+	AuthMiddleware(nil, auth.ScopeWrite)(ok).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,350 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/IgorKilipenko/metrical/internal/compression"
+)
+
+const (
+	// DefaultMaxCompressedBodySize максимальный размер сжатого тела запроса по умолчанию
+	DefaultMaxCompressedBodySize int64 = 4 << 20
+	// DefaultMaxBodySize максимальный размер несжатого (распакованного) тела запроса по умолчанию
+	DefaultMaxBodySize int64 = 16 << 20
+	// DefaultMinResponseSize минимальный размер ответа, начиная с которого он сжимается
+	DefaultMinResponseSize = 1024
+)
+
+// CompressionConfig настройки сжатия ответов и распаковки запросов
+type CompressionConfig struct {
+	MaxCompressedSize   int64    // Максимальный размер сжатого тела в байтах (0 - без ограничений)
+	MaxDecompressedSize int64    // Максимальный размер несжатого или распакованного тела в байтах (0 - без ограничений)
+	MinResponseSize     int      // Ответы меньшего размера не сжимаются (0 - сжимать все)
+	Encodings           []string // Кодировки ответов в порядке предпочтения (пусто - все поддерживаемые)
+}
+
+// DefaultCompressionConfig возвращает настройки по умолчанию
+func DefaultCompressionConfig() *CompressionConfig {
+	return &CompressionConfig{
+		MaxCompressedSize:   DefaultMaxCompressedBodySize,
+		MaxDecompressedSize: DefaultMaxBodySize,
+		MinResponseSize:     DefaultMinResponseSize,
+		Encodings:           compression.Encodings(),
+	}
+}
+
+// Validate проверяет корректность настроек
+func (c *CompressionConfig) Validate() error {
+	if c.MaxCompressedSize < 0 {
+		return fmt.Errorf("max compressed body size cannot be negative")
+	}
+	if c.MaxDecompressedSize < 0 {
+		return fmt.Errorf("max decompressed body size cannot be negative")
+	}
+	if c.MinResponseSize < 0 {
+		return fmt.Errorf("min response size cannot be negative")
+	}
+	for _, encoding := range c.Encodings {
+		if !compression.Supported(encoding) {
+			return fmt.Errorf("unsupported encoding %q", encoding)
+		}
+	}
+	return nil
+}
+
+// CompressionMiddleware распаковывает тела запросов (gzip, deflate, zstd) и сжимает ответы
+// кодировкой, выбранной по Accept-Encoding с учетом q-значений.
+// Сжатое тело распаковывается потоково при чтении обработчиком; превышение любого ограничения
+// приводит к ошибке *http.MaxBytesError при чтении, а известный заранее Content-Length - к ответу 413.
+func CompressionMiddleware(config *CompressionConfig) func(http.Handler) http.Handler {
+	if config == nil {
+		config = DefaultCompressionConfig()
+	}
+	settings := *config
+	if len(settings.Encodings) == 0 {
+		settings.Encodings = compression.Encodings()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Обрабатываем входящие сжатые запросы
+			encoding := compression.Normalize(r.Header.Get("Content-Encoding"))
+			if encoding == compression.Identity {
+				r.Header.Del("Content-Encoding")
+				encoding = ""
+			}
+			if encoding != "" {
+				if !compression.Supported(encoding) {
+					w.Header().Set("Accept-Encoding", strings.Join(compression.Encodings(), ", "))
+					http.Error(w, "Unsupported Content-Encoding", http.StatusUnsupportedMediaType)
+					return
+				}
+				if exceedsLimit(r.ContentLength, settings.MaxCompressedSize) {
+					writeBodyTooLarge(w)
+					return
+				}
+
+				body, err := newDecompressedBody(w, r.Body, encoding, settings)
+				if err != nil {
+					if IsBodyTooLarge(err) {
+						writeBodyTooLarge(w)
+						return
+					}
+					http.Error(w, "Failed to read "+encoding+" content", http.StatusBadRequest)
+					return
+				}
+				defer body.Close()
+
+				// Заменяем тело запроса на потоковую распаковку (размер заранее неизвестен)
+				r.Body = body
+				r.ContentLength = -1
+				r.Header.Del("Content-Encoding")
+			} else if r.Body != nil && r.Body != http.NoBody {
+				if exceedsLimit(r.ContentLength, settings.MaxDecompressedSize) {
+					writeBodyTooLarge(w)
+					return
+				}
+				if settings.MaxDecompressedSize > 0 {
+					r.Body = http.MaxBytesReader(w, r.Body, settings.MaxDecompressedSize)
+				}
+			}
+
+			// Выбираем кодировку ответа
+			responseEncoding, identityAllowed := compression.Negotiate(r.Header.Get("Accept-Encoding"), settings.Encodings)
+			if responseEncoding == "" && !identityAllowed {
+				http.Error(w, "No acceptable content encoding", http.StatusNotAcceptable)
+				return
+			}
+
+			minSize := settings.MinResponseSize
+			if !identityAllowed {
+				// Клиент не принимает несжатый ответ - сжимаем независимо от размера
+				minSize = 0
+			}
+
+			cw := &compressResponseWriter{
+				ResponseWriter: w,
+				encoding:       responseEncoding,
+				minSize:        minSize,
+				head:           r.Method == http.MethodHead,
+			}
+			defer cw.Close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// IsBodyTooLarge проверяет, вызвана ли ошибка чтения тела превышением ограничения размера
+func IsBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// exceedsLimit проверяет объявленный размер тела (Content-Length) по ограничению
+func exceedsLimit(contentLength, limit int64) bool {
+	return limit > 0 && contentLength > limit
+}
+
+// writeBodyTooLarge отвечает 413 Request Entity Too Large
+func writeBodyTooLarge(w http.ResponseWriter) {
+	http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+}
+
+// decompressedBody потоково распаковывает тело запроса с ограничением размера
+type decompressedBody struct {
+	reader    io.ReadCloser
+	raw       io.ReadCloser
+	remaining int64 // Сколько байт еще можно распаковать (< 0 - без ограничений)
+	limit     int64
+	closed    bool
+}
+
+// newDecompressedBody создает распаковщик тела запроса
+func newDecompressedBody(w http.ResponseWriter, body io.ReadCloser, encoding string, settings CompressionConfig) (*decompressedBody, error) {
+	raw := body
+	if settings.MaxCompressedSize > 0 {
+		raw = http.MaxBytesReader(w, body, settings.MaxCompressedSize)
+	}
+
+	reader, err := compression.NewReader(encoding, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := int64(-1)
+	if settings.MaxDecompressedSize > 0 {
+		remaining = settings.MaxDecompressedSize
+	}
+
+	return &decompressedBody{
+		reader:    reader,
+		raw:       raw,
+		remaining: remaining,
+		limit:     settings.MaxDecompressedSize,
+	}, nil
+}
+
+// Read распаковывает данные; при превышении ограничения возвращает *http.MaxBytesError
+func (b *decompressedBody) Read(p []byte) (int, error) {
+	if b.closed {
+		return 0, errors.New("read on closed body")
+	}
+	if b.remaining < 0 {
+		return b.reader.Read(p)
+	}
+
+	// Читаем на байт больше ограничения, чтобы отличить превышение от ровного размера
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.reader.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = 0
+		return n, &http.MaxBytesError{Limit: b.limit}
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// Close возвращает распаковщик в пул и закрывает исходное тело (повторный вызов ничего не делает)
+func (b *decompressedBody) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+
+	err := b.reader.Close()
+	if rawErr := b.raw.Close(); err == nil {
+		err = rawErr
+	}
+	return err
+}
+
+// compressResponseWriter оборачивает http.ResponseWriter для сжатия ответа.
+// Начало тела буферизуется до minSize байт: короткие ответы передаются без сжатия.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding   string // Кодировка ответа (пустая строка - без сжатия, только заголовок Vary)
+	minSize    int
+	head       bool
+	statusCode int
+	buf        []byte
+	decided    bool           // Заголовки переданы, способ передачи тела выбран
+	writer     io.WriteCloser // Сжимающий writer (nil - тело передается без сжатия)
+}
+
+// WriteHeader запоминает статус; заголовки передаются при выборе способа передачи тела
+func (c *compressResponseWriter) WriteHeader(statusCode int) {
+	if c.statusCode != 0 {
+		return
+	}
+	c.statusCode = statusCode
+
+	// Ответы без тела передаются сразу
+	if c.head || statusCode < http.StatusOK || statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		c.decide(false)
+	}
+}
+
+// Write буферизует начало тела и затем передает его со сжатием или без
+func (c *compressResponseWriter) Write(data []byte) (int, error) {
+	if c.statusCode == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+
+	if !c.decided {
+		// Несжимаемый тип или уже закодированное тело передаются как есть
+		if c.encoding == "" || !isCompressibleContentType(c.Header().Get("Content-Type")) || c.Header().Get("Content-Encoding") != "" {
+			c.decide(false)
+		} else {
+			c.buf = append(c.buf, data...)
+			if len(c.buf) < c.minSize {
+				return len(data), nil
+			}
+			c.decide(true)
+			if err := c.flushBuffer(); err != nil {
+				return 0, err
+			}
+			return len(data), nil
+		}
+	}
+
+	if c.writer != nil {
+		return c.writer.Write(data)
+	}
+	return c.ResponseWriter.Write(data)
+}
+
+// decide передает заголовки и выбирает, сжимать ли тело
+func (c *compressResponseWriter) decide(compress bool) {
+	if c.decided {
+		return
+	}
+	c.decided = true
+
+	header := c.Header()
+	if isCompressibleContentType(header.Get("Content-Type")) {
+		header.Add("Vary", "Accept-Encoding")
+	}
+
+	if compress && c.encoding != "" {
+		writer, err := compression.NewWriter(c.encoding, c.ResponseWriter)
+		if err == nil {
+			header.Set("Content-Encoding", c.encoding)
+			header.Del("Content-Length")
+			c.writer = writer
+		}
+	}
+
+	if c.statusCode == 0 {
+		c.statusCode = http.StatusOK
+	}
+	c.ResponseWriter.WriteHeader(c.statusCode)
+}
+
+// flushBuffer передает буферизованное начало тела
+func (c *compressResponseWriter) flushBuffer() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	buf := c.buf
+	c.buf = nil
+
+	var err error
+	if c.writer != nil {
+		_, err = c.writer.Write(buf)
+	} else {
+		_, err = c.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Close передает остаток буфера и завершает сжатый поток.
+// Пустой ответ не дополняется заголовком кодировки.
+func (c *compressResponseWriter) Close() error {
+	if !c.decided {
+		// Обработчик ничего не записал или ответ короче minSize
+		c.decide(len(c.buf) > 0 && len(c.buf) >= c.minSize && isCompressibleContentType(c.Header().Get("Content-Type")))
+	}
+	if err := c.flushBuffer(); err != nil {
+		return err
+	}
+	if c.writer != nil {
+		return c.writer.Close()
+	}
+	return nil
+}
+
+// isCompressibleContentType проверяет, можно ли сжимать данный тип контента
+func isCompressibleContentType(contentType string) bool {
+	// Поддерживаем сжатие для JSON и HTML
+	return strings.Contains(contentType, "application/json") ||
+		strings.Contains(contentType, "text/html") ||
+		strings.Contains(contentType, "text/plain")
+}
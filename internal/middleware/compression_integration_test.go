@@ -10,8 +10,8 @@ import (
 	"testing"
 )
 
-// TestGzipMiddlewareIntegration тестирует интеграцию gzip middleware с реальными HTTP запросами
-func TestGzipMiddlewareIntegration(t *testing.T) {
+// TestCompressionMiddlewareIntegration тестирует интеграцию middleware сжатия с реальными HTTP запросами
+func TestCompressionMiddlewareIntegration(t *testing.T) {
 	// Создаем тестовый handler, который возвращает JSON
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Устанавливаем Content-Type для JSON
@@ -34,7 +34,7 @@ func TestGzipMiddlewareIntegration(t *testing.T) {
 	})
 
 	// Создаем middleware
-	middleware := GzipMiddleware()
+	middleware := CompressionMiddleware(compressAllConfig())
 	wrappedHandler := middleware(handler)
 
 	// Тест 1: Клиент поддерживает gzip, получаем сжатый ответ
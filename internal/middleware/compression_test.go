@@ -0,0 +1,570 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/compression"
+)
+
+// compressAllConfig возвращает настройки по умолчанию без порога размера ответа
+func compressAllConfig() *CompressionConfig {
+	config := DefaultCompressionConfig()
+	config.MinResponseSize = 0
+	return config
+}
+
+func TestCompressionMiddleware_Compression(t *testing.T) {
+	// Создаем тестовый handler
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"message": "test response"}`))
+	})
+
+	// Создаем middleware
+	middleware := CompressionMiddleware(compressAllConfig())
+	wrappedHandler := middleware(handler)
+
+	// Тест 1: Клиент поддерживает gzip
+	t.Run("client supports gzip", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		w := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(w, req)
+
+		// Проверяем, что ответ сжат
+		if w.Header().Get("Content-Encoding") != "gzip" {
+			t.Errorf("Expected Content-Encoding: gzip, got %s", w.Header().Get("Content-Encoding"))
+		}
+
+		// Проверяем, что тело ответа действительно сжато
+		body := w.Body.Bytes()
+		if len(body) == 0 {
+			t.Error("Response body is empty")
+		}
+
+		// Пытаемся распаковать ответ
+		gzReader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Errorf("Failed to create gzip reader: %v", err)
+		}
+		defer gzReader.Close()
+
+		uncompressed, err := io.ReadAll(gzReader)
+		if err != nil {
+			t.Errorf("Failed to decompress response: %v", err)
+		}
+
+		expected := `{"message": "test response"}`
+		if string(uncompressed) != expected {
+			t.Errorf("Expected %s, got %s", expected, string(uncompressed))
+		}
+	})
+
+	// Тест 2: Клиент не поддерживает gzip
+	t.Run("client does not support gzip", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test", nil)
+		// Не устанавливаем Accept-Encoding
+
+		w := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(w, req)
+
+		// Проверяем, что ответ не сжат
+		if w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("Expected no Content-Encoding, got %s", w.Header().Get("Content-Encoding"))
+		}
+
+		// Проверяем, что тело ответа не сжато
+		body := w.Body.String()
+		expected := `{"message": "test response"}`
+		if body != expected {
+			t.Errorf("Expected %s, got %s", expected, body)
+		}
+	})
+}
+
+func TestCompressionMiddleware_Decompression(t *testing.T) {
+	// Создаем тестовый handler
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Читаем тело запроса и возвращаем его обратно
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read body", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(body)
+	})
+
+	// Создаем middleware
+	middleware := CompressionMiddleware(compressAllConfig())
+	wrappedHandler := middleware(handler)
+
+	// Тест: Отправляем сжатый запрос
+	t.Run("decompress gzipped request", func(t *testing.T) {
+		// Создаем сжатые данные
+		var buf bytes.Buffer
+		gzWriter := gzip.NewWriter(&buf)
+		gzWriter.Write([]byte("compressed test data"))
+		gzWriter.Close()
+
+		req := httptest.NewRequest("POST", "/test", bytes.NewReader(buf.Bytes()))
+		req.Header.Set("Content-Encoding", "gzip")
+
+		w := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(w, req)
+
+		// Проверяем, что сервер корректно обработал сжатый запрос
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", w.Code)
+		}
+
+		body := w.Body.String()
+		expected := "compressed test data"
+		if body != expected {
+			t.Errorf("Expected %s, got %s", expected, body)
+		}
+	})
+}
+
+func TestCompressionMiddleware_ContentTypeFiltering(t *testing.T) {
+	// Создаем тестовый handler
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("binary data"))
+	})
+
+	// Создаем middleware
+	middleware := CompressionMiddleware(compressAllConfig())
+	wrappedHandler := middleware(handler)
+
+	// Тест: Не сжимаем бинарные типы контента
+	t.Run("do not compress binary content", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		w := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(w, req)
+
+		// Проверяем, что ответ не сжат для бинарного типа
+		if w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("Expected no Content-Encoding for binary content, got %s", w.Header().Get("Content-Encoding"))
+		}
+
+		// Тело передается без изменений
+		if w.Body.String() != "binary data" {
+			t.Errorf("Expected uncompressed body, got %q", w.Body.String())
+		}
+	})
+
+	// Тест: Пустой ответ без Content-Type не дополняется gzip заголовком
+	t.Run("empty response stays empty", func(t *testing.T) {
+		emptyHandler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		req := httptest.NewRequest("POST", "/update", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		w := httptest.NewRecorder()
+		emptyHandler.ServeHTTP(w, req)
+
+		if w.Body.Len() != 0 {
+			t.Errorf("Expected empty body, got %d bytes", w.Body.Len())
+		}
+	})
+}
+
+func TestIsCompressibleContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expected    bool
+	}{
+		{"application/json", true},
+		{"text/html", true},
+		{"text/plain", true},
+		{"image/png", false},
+		{"application/octet-stream", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		result := isCompressibleContentType(test.contentType)
+		if result != test.expected {
+			t.Errorf("For content type '%s': expected %v, got %v", test.contentType, test.expected, result)
+		}
+	}
+}
+
+// gzipBytes сжимает данные gzip
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	if _, err := gzWriter.Write(data); err != nil {
+		t.Fatalf("failed to compress data: %v", err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		t.Fatalf("failed to close gzip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCompressionMiddleware_BodyLimits(t *testing.T) {
+	// Обработчик читает тело целиком и отвечает 413 при превышении ограничения
+	handlerCalled := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalled = true
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			if IsBodyTooLarge(err) {
+				http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	})
+	wrappedHandler := CompressionMiddleware(&CompressionConfig{MaxCompressedSize: 1024, MaxDecompressedSize: 4096})(handler)
+
+	tests := []struct {
+		name           string
+		body           []byte
+		gzip           bool
+		contentLength  int64 // -1 - размер заранее неизвестен
+		expectedStatus int
+		handlerCalled  bool
+	}{
+		{
+			name:           "decompression bomb",
+			body:           make([]byte, 1<<20), // 1 MiB нулей сжимается примерно до 1 KiB
+			gzip:           true,
+			contentLength:  -1,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			handlerCalled:  true,
+		},
+		{
+			name:           "decompressed size equals limit",
+			body:           make([]byte, 4096),
+			gzip:           true,
+			contentLength:  -1,
+			expectedStatus: http.StatusOK,
+			handlerCalled:  true,
+		},
+		{
+			name:           "compressed content length exceeds limit",
+			body:           []byte("data"),
+			gzip:           true,
+			contentLength:  2048,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "plain content length exceeds limit",
+			body:           make([]byte, 5000),
+			contentLength:  5000,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "plain streamed body exceeds limit",
+			body:           make([]byte, 5000),
+			contentLength:  -1,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			handlerCalled:  true,
+		},
+		{
+			name:           "plain body within limit",
+			body:           []byte("small"),
+			contentLength:  5,
+			expectedStatus: http.StatusOK,
+			handlerCalled:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlerCalled = false
+			body := tt.body
+			if tt.gzip {
+				body = gzipBytes(t, body)
+			}
+
+			req := httptest.NewRequest("POST", "/update", bytes.NewReader(body))
+			req.ContentLength = tt.contentLength
+			if tt.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+			w := httptest.NewRecorder()
+
+			wrappedHandler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if handlerCalled != tt.handlerCalled {
+				t.Errorf("Expected handler called = %v, got %v", tt.handlerCalled, handlerCalled)
+			}
+		})
+	}
+}
+
+func TestCompressionMiddleware_InvalidGzip(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called for invalid gzip header")
+	})
+
+	req := httptest.NewRequest("POST", "/update", bytes.NewReader([]byte("not gzip")))
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	CompressionMiddleware(compressAllConfig())(handler).ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestCompressionMiddleware_PooledReadersAndWriters(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read body", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+	wrappedHandler := CompressionMiddleware(compressAllConfig())(handler)
+
+	// Последовательные запросы переиспользуют reader и writer из пула без смешивания данных
+	for _, payload := range []string{`{"id":"first"}`, `{"id":"second"}`, `{"id":"third"}`} {
+		req := httptest.NewRequest("POST", "/update", bytes.NewReader(gzipBytes(t, []byte(payload))))
+		req.Header.Set("Content-Encoding", "gzip")
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+
+		wrappedHandler.ServeHTTP(w, req)
+
+		gzReader, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("Failed to create gzip reader: %v", err)
+		}
+		body, err := io.ReadAll(gzReader)
+		if err != nil {
+			t.Fatalf("Failed to decompress response: %v", err)
+		}
+		if string(body) != payload {
+			t.Errorf("Expected %s, got %s", payload, body)
+		}
+	}
+}
+
+func TestCompressionConfig_Validate(t *testing.T) {
+	if err := (&CompressionConfig{Encodings: []string{"br"}}).Validate(); err == nil {
+		t.Error("Unsupported encoding should be invalid")
+	}
+	if err := (&CompressionConfig{MinResponseSize: -1}).Validate(); err == nil {
+		t.Error("Negative min response size should be invalid")
+	}
+	if err := DefaultCompressionConfig().Validate(); err != nil {
+		t.Errorf("Default config should be valid: %v", err)
+	}
+	if err := (&CompressionConfig{}).Validate(); err != nil {
+		t.Errorf("Zero limits should be valid: %v", err)
+	}
+	if err := (&CompressionConfig{MaxCompressedSize: -1}).Validate(); err == nil {
+		t.Error("Negative compressed limit should be invalid")
+	}
+	if err := (&CompressionConfig{MaxDecompressedSize: -1}).Validate(); err == nil {
+		t.Error("Negative decompressed limit should be invalid")
+	}
+}
+
+func BenchmarkCompressionMiddleware_Decompression(b *testing.B) {
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	gzWriter.Write(bytes.Repeat([]byte(`{"id":"Alloc","type":"gauge","value":1.5},`), 100))
+	gzWriter.Close()
+	payload := buf.Bytes()
+
+	handler := CompressionMiddleware(compressAllConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest("POST", "/updates", bytes.NewReader(payload))
+		req.Header.Set("Content-Encoding", "gzip")
+		req.Header.Set("Accept-Encoding", "gzip")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func TestCompressionMiddleware_RequestEncodings(t *testing.T) {
+	handler := CompressionMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "" {
+			t.Error("Content-Encoding should be removed after decompression")
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read body", http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	}))
+
+	payload := []byte(`{"id":"Alloc","type":"gauge","value":1.5}`)
+	for _, encoding := range append(compression.Encodings(), "x-gzip") {
+		t.Run(encoding, func(t *testing.T) {
+			compressed, err := compression.Compress(encoding, payload)
+			if err != nil {
+				t.Fatalf("Failed to compress payload: %v", err)
+			}
+
+			req := httptest.NewRequest("POST", "/update", bytes.NewReader(compressed))
+			req.Header.Set("Content-Encoding", encoding)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+			if w.Body.String() != string(payload) {
+				t.Errorf("Expected %s, got %s", payload, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("unsupported encoding", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/update", strings.NewReader("data"))
+		req.Header.Set("Content-Encoding", "br")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("Expected status 415, got %d", w.Code)
+		}
+		if w.Header().Get("Accept-Encoding") == "" {
+			t.Error("Expected Accept-Encoding with supported encodings")
+		}
+	})
+
+	t.Run("identity", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/update", strings.NewReader("plain"))
+		req.Header.Set("Content-Encoding", "identity")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK || w.Body.String() != "plain" {
+			t.Errorf("Expected plain body to pass through, got %d %q", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestCompressionMiddleware_ResponseNegotiation(t *testing.T) {
+	large := strings.Repeat(`{"id":"Alloc","type":"gauge","value":1.5}`, 50)
+	small := `{"status":"ok"}`
+
+	newHandler := func(body string) http.Handler {
+		return CompressionMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(body))
+		}))
+	}
+
+	tests := []struct {
+		name             string
+		acceptEncoding   string
+		body             string
+		expectedStatus   int
+		expectedEncoding string
+	}{
+		{name: "zstd preferred", acceptEncoding: "gzip, zstd", body: large, expectedStatus: http.StatusOK, expectedEncoding: "zstd"},
+		{name: "q-values", acceptEncoding: "zstd;q=0.1, deflate;q=0.9", body: large, expectedStatus: http.StatusOK, expectedEncoding: "deflate"},
+		{name: "gzip disabled by q=0", acceptEncoding: "gzip;q=0", body: large, expectedStatus: http.StatusOK, expectedEncoding: ""},
+		{name: "substring is not a match", acceptEncoding: "x-gzip-fake", body: large, expectedStatus: http.StatusOK, expectedEncoding: ""},
+		{name: "small response not compressed", acceptEncoding: "gzip", body: small, expectedStatus: http.StatusOK, expectedEncoding: ""},
+		{name: "identity forbidden compresses small response", acceptEncoding: "gzip, identity;q=0", body: small, expectedStatus: http.StatusOK, expectedEncoding: "gzip"},
+		{name: "nothing acceptable", acceptEncoding: "br, *;q=0", body: small, expectedStatus: http.StatusNotAcceptable, expectedEncoding: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			w := httptest.NewRecorder()
+			newHandler(tt.body).ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			encoding := w.Header().Get("Content-Encoding")
+			if encoding != tt.expectedEncoding {
+				t.Fatalf("Expected Content-Encoding %q, got %q", tt.expectedEncoding, encoding)
+			}
+			if w.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("Expected Vary: Accept-Encoding, got %q", w.Header().Get("Vary"))
+			}
+
+			body := w.Body.Bytes()
+			if encoding != "" {
+				var err error
+				if body, err = compression.Decompress(encoding, body); err != nil {
+					t.Fatalf("Failed to decompress response: %v", err)
+				}
+			}
+			if string(body) != tt.body {
+				t.Errorf("Unexpected response body: %q", body)
+			}
+		})
+	}
+}
+
+func TestCompressionMiddleware_StatusAndHeaders(t *testing.T) {
+	large := strings.Repeat("x", 2*DefaultMinResponseSize)
+	handler := CompressionMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", "2048")
+		w.WriteHeader(http.StatusCreated)
+		// Тело записывается частями меньше порога
+		for i := 0; i < len(large); i += 100 {
+			end := min(i+100, len(large))
+			w.Write([]byte(large[i:end]))
+		}
+	}))
+
+	req := httptest.NewRequest("POST", "/update", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", w.Code)
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip response, got %q", w.Header().Get("Content-Encoding"))
+	}
+	if w.Header().Get("Content-Length") != "" {
+		t.Error("Content-Length of uncompressed body must be removed")
+	}
+	body, err := compression.Decompress("gzip", w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to decompress response: %v", err)
+	}
+	if string(body) != large {
+		t.Error("Decompressed body does not match")
+	}
+}
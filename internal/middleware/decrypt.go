@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/IgorKilipenko/metrical/internal/encryption"
+)
+
+// DecryptMiddleware расшифровывает тела запросов с заголовком Content-Encryption.
+// Должен располагаться перед CompressionMiddleware: агент шифрует уже сжатые данные.
+// Запросы без заголовка передаются без изменений; при nil дешифраторе middleware ничего не делает.
+// maxBodySize ограничивает размер расшифрованного (сжатого) тела: зашифрованное тело длиннее
+// не более чем на накладные расходы шифрования, при превышении возвращается 413 (0 - без ограничений).
+func DecryptMiddleware(decryptor *encryption.Decryptor, maxBodySize int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if decryptor == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme := r.Header.Get(encryption.HeaderName)
+			if scheme == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if scheme != encryption.Scheme {
+				http.Error(w, "Unsupported encryption scheme", http.StatusBadRequest)
+				return
+			}
+
+			body := r.Body
+			if maxBodySize > 0 {
+				limit := maxBodySize + int64(decryptor.Overhead())
+				if exceedsLimit(r.ContentLength, limit) {
+					writeBodyTooLarge(w)
+					return
+				}
+				body = http.MaxBytesReader(w, r.Body, limit)
+			}
+
+			ciphertext, err := io.ReadAll(body)
+			if err != nil {
+				if IsBodyTooLarge(err) {
+					writeBodyTooLarge(w)
+					return
+				}
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+
+			plaintext, err := decryptor.Decrypt(ciphertext)
+			if err != nil {
+				http.Error(w, "Failed to decrypt request body", http.StatusBadRequest)
+				return
+			}
+
+			// Заменяем тело запроса на расшифрованное содержимое
+			r.Body = io.NopCloser(bytes.NewReader(plaintext))
+			r.ContentLength = int64(len(plaintext))
+			r.Header.Del(encryption.HeaderName)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
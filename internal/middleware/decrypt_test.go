@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/encryption"
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecryptMiddleware(t *testing.T) {
+	privateKeyPath, publicKeyPath := testutils.WriteTestRSAKeys(t)
+	encryptor, err := encryption.NewEncryptorFromFile(publicKeyPath)
+	require.NoError(t, err)
+	decryptor, err := encryption.NewDecryptorFromFile(privateKeyPath)
+	require.NoError(t, err)
+
+	payload := []byte(`{"id":"PollCount","type":"counter","delta":5}`)
+	ciphertext, err := encryptor.Encrypt(payload)
+	require.NoError(t, err)
+
+	// Обработчик возвращает полученное тело запроса
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get(encryption.HeaderName), "Encryption header should be removed after decryption")
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	})
+	handler := DecryptMiddleware(decryptor, 0)(echo)
+
+	tests := []struct {
+		name           string
+		body           []byte
+		scheme         string
+		expectedStatus int
+		expectedBody   []byte
+	}{
+		{
+			name:           "encrypted request",
+			body:           ciphertext,
+			scheme:         encryption.Scheme,
+			expectedStatus: http.StatusOK,
+			expectedBody:   payload,
+		},
+		{
+			name:           "plain request",
+			body:           payload,
+			expectedStatus: http.StatusOK,
+			expectedBody:   payload,
+		},
+		{
+			name:           "unsupported scheme",
+			body:           ciphertext,
+			scheme:         "rot13",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "corrupted ciphertext",
+			body:           ciphertext[:len(ciphertext)-1],
+			scheme:         encryption.Scheme,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/update", bytes.NewReader(tt.body))
+			if tt.scheme != "" {
+				req.Header.Set(encryption.HeaderName, tt.scheme)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != nil {
+				assert.Equal(t, tt.expectedBody, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestDecryptMiddleware_Disabled(t *testing.T) {
+	handler := DecryptMiddleware(nil, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/update", bytes.NewReader([]byte("data")))
+	req.Header.Set(encryption.HeaderName, encryption.Scheme)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, "Without private key requests are passed through")
+}
+
+func TestDecryptMiddleware_BodyTooLarge(t *testing.T) {
+	privateKeyPath, publicKeyPath := testutils.WriteTestRSAKeys(t)
+	encryptor, err := encryption.NewEncryptorFromFile(publicKeyPath)
+	require.NoError(t, err)
+	decryptor, err := encryption.NewDecryptorFromFile(privateKeyPath)
+	require.NoError(t, err)
+
+	const maxBodySize = 64
+	reached := false
+	handler := DecryptMiddleware(decryptor, maxBodySize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(payload []byte, hideLength bool) int {
+		ciphertext, err := encryptor.Encrypt(payload)
+		require.NoError(t, err)
+
+		var body io.Reader = bytes.NewReader(ciphertext)
+		if hideLength {
+			// Без Content-Length размер проверяется при чтении тела
+			body = io.MultiReader(body)
+		}
+		req := httptest.NewRequest("POST", "/update", body)
+		req.Header.Set(encryption.HeaderName, encryption.Scheme)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send(bytes.Repeat([]byte("a"), maxBodySize), false), "Body within the limit is decrypted")
+	assert.True(t, reached)
+
+	reached = false
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send(bytes.Repeat([]byte("a"), maxBodySize+1), false))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send(bytes.Repeat([]byte("a"), 4*maxBodySize), true))
+	assert.False(t, reached, "Oversized body must not reach the handler")
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/ratelimit"
+)
+
+// RateLimitMiddleware ограничивает частоту запросов каждого клиента.
+// Клиент определяется по имени токена (если запрос аутентифицирован), иначе по IP адреса соединения.
+// Заголовку X-Real-IP middleware доверяет только для соединений из trustedProxies: иначе клиент
+// обходил бы лимит, подставляя в заголовок новый адрес в каждом запросе. При превышении лимита
+// возвращается 429 с заголовком Retry-After; при nil ограничителе middleware ничего не делает.
+func RateLimitMiddleware(limiter *ratelimit.Limiter, trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, wait := limiter.Allow(clientKey(r, trustedProxies))
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(wait)))
+				http.Error(w, "Too Many Requests: rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientKey возвращает идентификатор клиента для ограничения частоты запросов
+func clientKey(r *http.Request, trustedProxies []*net.IPNet) string {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		return "token:" + principal.Name
+	}
+
+	ip := remoteIP(r)
+	if ip == nil {
+		return "addr:" + r.RemoteAddr
+	}
+	if containsIP(trustedProxies, ip) {
+		if realIP := net.ParseIP(strings.TrimSpace(r.Header.Get(RealIPHeader))); realIP != nil {
+			return "ip:" + realIP.String()
+		}
+	}
+	return "ip:" + ip.String()
+}
+
+// containsIP проверяет, входит ли IP в одну из подсетей
+func containsIP(subnets []*net.IPNet, ip net.IP) bool {
+	for _, subnet := range subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfterSeconds округляет время ожидания вверх до целых секунд (не меньше 1)
+func retryAfterSeconds(wait time.Duration) int {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
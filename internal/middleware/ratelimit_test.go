@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(&ratelimit.Config{Rate: 0.5, Burst: 2, MaxClients: 100})
+	require.NoError(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	_, proxies, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	handler := RateLimitMiddleware(limiter, []*net.IPNet{proxies})(ok)
+
+	send := func(remoteAddr, realIP string, principal *auth.Principal) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/update", nil)
+		req.RemoteAddr = remoteAddr
+		if realIP != "" {
+			req.Header.Set(RealIPHeader, realIP)
+		}
+		if principal != nil {
+			req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("limit by remote address", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, send("10.0.0.1:1234", "", nil).Code)
+		assert.Equal(t, http.StatusOK, send("10.0.0.1:1235", "", nil).Code)
+
+		w := send("10.0.0.1:1236", "", nil)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+		assert.Equal(t, http.StatusOK, send("10.0.0.2:1234", "", nil).Code, "Other clients should not be limited")
+	})
+
+	t.Run("limit by X-Real-IP from trusted proxy", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, send("127.0.0.1:1", "192.168.1.5", nil).Code)
+		assert.Equal(t, http.StatusOK, send("127.0.0.1:2", "192.168.1.5", nil).Code)
+		assert.Equal(t, http.StatusTooManyRequests, send("127.0.0.1:3", "192.168.1.5", nil).Code)
+		assert.Equal(t, http.StatusOK, send("127.0.0.1:4", "192.168.1.6", nil).Code)
+	})
+
+	t.Run("ignore X-Real-IP from untrusted client", func(t *testing.T) {
+		// Подмена X-Real-IP в каждом запросе не дает новой корзины
+		assert.Equal(t, http.StatusOK, send("10.0.0.9:1", "192.168.2.1", nil).Code)
+		assert.Equal(t, http.StatusOK, send("10.0.0.9:2", "192.168.2.2", nil).Code)
+		assert.Equal(t, http.StatusTooManyRequests, send("10.0.0.9:3", "192.168.2.3", nil).Code)
+	})
+
+	t.Run("limit by token", func(t *testing.T) {
+		principal := &auth.Principal{Name: "agent", Scope: auth.ScopeWrite}
+		assert.Equal(t, http.StatusOK, send("172.16.0.1:1", "", principal).Code)
+		// Токен ограничивается независимо от адреса
+		assert.Equal(t, http.StatusOK, send("172.16.0.2:1", "", principal).Code)
+		assert.Equal(t, http.StatusTooManyRequests, send("172.16.0.3:1", "", principal).Code)
+	})
+}
+
+func TestRateLimitMiddleware_NilLimiter(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := RateLimitMiddleware(nil, nil)(ok)
+
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/update", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 1, retryAfterSeconds(0))
+	assert.Equal(t, 1, retryAfterSeconds(100*time.Millisecond))
+	assert.Equal(t, 1, retryAfterSeconds(time.Second))
+	assert.Equal(t, 2, retryAfterSeconds(1500*time.Millisecond))
+}
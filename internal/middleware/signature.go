@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/signature"
+)
+
+// SignatureMiddleware проверяет подписи запросов общим ключом и подписывает ответы.
+// Должен располагаться после CompressionMiddleware: подписывается несжатое тело.
+//
+// Запросы POST обязаны содержать заголовок HashSHA256 с подписью тела
+// (для запросов без тела - подписью пути). Для остальных методов подпись проверяется,
+// если заголовок передан. Каждая метрика JSON тела со значением обязана содержать
+// корректное поле Hash, как и в gRPC API; метрики без значения (запрос POST /value) не подписываются.
+// При пустом ключе middleware ничего не делает.
+func SignatureMiddleware(key string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if key == "" {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				if IsBodyTooLarge(err) {
+					writeBodyTooLarge(w)
+					return
+				}
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := r.Header.Get(signature.HeaderName)
+			if hash == "" && r.Method == http.MethodPost {
+				http.Error(w, "Missing "+signature.HeaderName+" header", http.StatusBadRequest)
+				return
+			}
+			if hash != "" && !signature.Verify(signature.RequestData(r.URL.Path, body), key, hash) {
+				http.Error(w, "Invalid request signature", http.StatusBadRequest)
+				return
+			}
+
+			if err := verifyMetricHashes(r, body, key); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			// Ответ буферизуется, чтобы передать подпись в заголовке до тела
+			signed := &signedResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(signed, r)
+			signed.flush(key)
+		})
+	}
+}
+
+// metricHashError ошибка проверки подписи метрики
+type metricHashError struct {
+	id      string
+	missing bool // Поле Hash не заполнено
+}
+
+func (e metricHashError) Error() string {
+	if e.missing {
+		return "Missing hash for metric " + e.id
+	}
+	return "Invalid hash for metric " + e.id
+}
+
+// verifyMetricHashes проверяет поля Hash метрик в JSON теле запроса.
+// Тело, которое не удается разобрать, пропускается - его отклонит обработчик.
+func verifyMetricHashes(r *http.Request, body []byte, key string) error {
+	if !strings.Contains(r.Header.Get("Content-Type"), "application/json") || len(body) == 0 {
+		return nil
+	}
+
+	var metrics []models.Metrics
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(body, &metrics); err != nil {
+			return nil
+		}
+	} else {
+		var metric models.Metrics
+		if err := json.Unmarshal(body, &metric); err != nil {
+			return nil
+		}
+		metrics = append(metrics, metric)
+	}
+
+	for i := range metrics {
+		if metrics[i].Value == nil && metrics[i].Delta == nil {
+			continue
+		}
+		if metrics[i].Hash == "" {
+			return metricHashError{id: metrics[i].ID, missing: true}
+		}
+		if !signature.VerifyMetric(&metrics[i], key) {
+			return metricHashError{id: metrics[i].ID}
+		}
+	}
+	return nil
+}
+
+// signedResponseWriter буферизует ответ для вычисления подписи
+type signedResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+// WriteHeader запоминает статус ответа до отправки подписи
+func (s *signedResponseWriter) WriteHeader(statusCode int) {
+	if s.statusCode == 0 {
+		s.statusCode = statusCode
+	}
+}
+
+// Write накапливает тело ответа
+func (s *signedResponseWriter) Write(data []byte) (int, error) {
+	if s.statusCode == 0 {
+		s.statusCode = http.StatusOK
+	}
+	return s.body.Write(data)
+}
+
+// flush подписывает накопленный ответ и отправляет его клиенту
+func (s *signedResponseWriter) flush(key string) {
+	if s.statusCode == 0 {
+		s.statusCode = http.StatusOK
+	}
+
+	s.Header().Set(signature.HeaderName, signature.Sign(s.body.Bytes(), key))
+	s.ResponseWriter.WriteHeader(s.statusCode)
+	s.ResponseWriter.Write(s.body.Bytes())
+}
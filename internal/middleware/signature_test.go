@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/signature"
+	"github.com/stretchr/testify/assert"
+)
+
+const testKey = "secret"
+
+// signedMetricBody возвращает JSON метрики с подписью поля Hash
+func signedMetricBody(key string, delta int64) string {
+	metric := models.Metrics{ID: "PollCount", MType: models.Counter, Delta: &delta}
+	signature.SignMetric(&metric, key)
+
+	data, _ := json.Marshal(metric)
+	return string(data)
+}
+
+func TestSignatureMiddleware(t *testing.T) {
+	handler := SignatureMiddleware(testKey)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+
+	body := signedMetricBody(testKey, 5)
+	unsignedBody := `{"id":"PollCount","type":"counter","delta":5}`
+	unsignedBatch := "[" + body + `,{"id":"Alloc","type":"gauge","value":1.5}]`
+	readBody := `{"id":"PollCount","type":"counter"}`
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		hash           string
+		expectedStatus int
+	}{
+		{
+			name:           "valid signature",
+			method:         "POST",
+			path:           "/update",
+			body:           body,
+			hash:           signature.Sign([]byte(body), testKey),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing signature on write",
+			method:         "POST",
+			path:           "/update",
+			body:           body,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "signature with wrong key",
+			method:         "POST",
+			path:           "/update",
+			body:           body,
+			hash:           signature.Sign([]byte(body), "other"),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "metric hash mismatch",
+			method:         "POST",
+			path:           "/update",
+			body:           signedMetricBody("other", 5),
+			hash:           signature.Sign([]byte(signedMetricBody("other", 5)), testKey),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unsigned metric in signed body",
+			method:         "POST",
+			path:           "/update",
+			body:           unsignedBody,
+			hash:           signature.Sign([]byte(unsignedBody), testKey),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unsigned metric in signed batch",
+			method:         "POST",
+			path:           "/updates",
+			body:           unsignedBatch,
+			hash:           signature.Sign([]byte(unsignedBatch), testKey),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "read request without metric hash",
+			method:         "POST",
+			path:           "/value",
+			body:           readBody,
+			hash:           signature.Sign([]byte(readBody), testKey),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "empty body signs path",
+			method:         "POST",
+			path:           "/update/counter/PollCount/5",
+			hash:           signature.Sign([]byte("/update/counter/PollCount/5"), testKey),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "path signature does not match other path",
+			method:         "POST",
+			path:           "/update/counter/PollCount/500",
+			hash:           signature.Sign([]byte("/update/counter/PollCount/5"), testKey),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "read without signature",
+			method:         "GET",
+			path:           "/value/counter/PollCount",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.hash != "" {
+				req.Header.Set(signature.HeaderName, tt.hash)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, "Body: %s", w.Body.String())
+			if tt.expectedStatus == http.StatusOK {
+				// Ответ подписан тем же ключом
+				assert.True(t, signature.Verify(w.Body.Bytes(), testKey, w.Header().Get(signature.HeaderName)))
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestSignatureMiddleware_EmptyKey(t *testing.T) {
+	handler := SignatureMiddleware("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/update", strings.NewReader(`{"id":"PollCount","type":"counter","delta":5}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(signature.HeaderName), "Responses are not signed without key")
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIPHeader заголовок с IP адресом агента, отправившего запрос
+const RealIPHeader = "X-Real-IP"
+
+// TrustedSubnetMiddleware пропускает только запросы из доверенной подсети.
+// В подсети должен находиться адрес соединения и, если передан заголовок X-Real-IP, адрес из него:
+// заголовок задает клиент, поэтому одного X-Real-IP недостаточно. Запросы из других подсетей
+// и с некорректным X-Real-IP отклоняются с кодом 403; при nil подсети middleware ничего не делает.
+func TrustedSubnetMiddleware(subnet *net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if subnet == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !inSubnet(r, subnet) {
+				http.Error(w, "Forbidden: client is not in trusted subnet", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// inSubnet проверяет, что адрес соединения и X-Real-IP (если передан) входят в подсеть
+func inSubnet(r *http.Request, subnet *net.IPNet) bool {
+	if ip := remoteIP(r); ip == nil || !subnet.Contains(ip) {
+		return false
+	}
+	if realIP := strings.TrimSpace(r.Header.Get(RealIPHeader)); realIP != "" {
+		ip := net.ParseIP(realIP)
+		return ip != nil && subnet.Contains(ip)
+	}
+	return true
+}
+
+// remoteIP возвращает IP адреса соединения (nil, если адрес некорректен)
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedSubnetMiddleware(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("192.168.1.0/24")
+	require.NoError(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := TrustedSubnetMiddleware(subnet)(ok)
+
+	tests := []struct {
+		name           string
+		realIP         string
+		remoteAddr     string
+		expectedStatus int
+	}{
+		{
+			name:           "X-Real-IP in subnet",
+			realIP:         "192.168.1.10",
+			remoteAddr:     "192.168.1.1:12345",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Spoofed X-Real-IP from outside subnet",
+			realIP:         "192.168.1.10",
+			remoteAddr:     "203.0.113.7:12345",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "X-Real-IP outside subnet",
+			realIP:         "10.0.0.1",
+			remoteAddr:     "192.168.1.10:12345",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Invalid X-Real-IP",
+			realIP:         "not-an-ip",
+			remoteAddr:     "192.168.1.10:12345",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Remote address in subnet",
+			remoteAddr:     "192.168.1.20:12345",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Remote address outside subnet",
+			remoteAddr:     "172.16.0.1:12345",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/update", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				req.Header.Set(RealIPHeader, tt.realIP)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestTrustedSubnetMiddleware_Disabled(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := TrustedSubnetMiddleware(nil)(ok)
+
+	req := httptest.NewRequest("POST", "/update", nil)
+	req.Header.Set(RealIPHeader, "10.0.0.1")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
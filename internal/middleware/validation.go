@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/IgorKilipenko/metrical/internal/openapi"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+)
+
+// RequestValidationMiddleware проверяет запросы по спецификации OpenAPI.
+// Несоответствующие запросы отклоняются ответом application/problem+json: 400 с перечнем полей
+// в errors, 415 для неописанного Content-Type, 413 при превышении размера тела.
+// Тело читается после распаковки, поэтому middleware подключается после CompressionMiddleware;
+// при nil валидаторе middleware ничего не делает.
+func RequestValidationMiddleware(validator *openapi.Validator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if validator == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := validator.ValidateRequest(r)
+			if err == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var p *problem.Problem
+			var maxBytesErr *http.MaxBytesError
+			switch {
+			case errors.As(err, &p):
+			case errors.As(err, &maxBytesErr):
+				p = problem.New(http.StatusRequestEntityTooLarge, "request body too large")
+			default:
+				p = problem.New(http.StatusBadRequest, "failed to read request body")
+			}
+			p.Instance = r.URL.Path
+			problem.Write(w, p)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/openapi"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validationSpec = `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/value": {"post": {"requestBody": {"required": true, "content": {"application/json": {"schema": {
+      "type": "object",
+      "required": ["id", "type"],
+      "properties": {"id": {"type": "string"}, "type": {"type": "string", "enum": ["gauge", "counter"]}}
+    }}}}}}
+  }
+}`
+
+func TestRequestValidationMiddleware(t *testing.T) {
+	var received string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := RequestValidationMiddleware(openapi.NewValidator(openapi.MustLoad([]byte(validationSpec))))(next)
+
+	t.Run("valid request passes with body", func(t *testing.T) {
+		body := `{"id":"temperature","type":"gauge"}`
+		req := httptest.NewRequest(http.MethodPost, "/value", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, body, received)
+	})
+
+	t.Run("invalid request rejected with field errors", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/value", strings.NewReader(`{"type":"histogram"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+		var p problem.Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		assert.Equal(t, problem.TypeValidation, p.Type)
+		assert.Equal(t, "/value", p.Instance)
+		assert.Equal(t, []problem.FieldError{
+			{Field: "id", Value: "", Message: "is required"},
+			{Field: "type", Value: "histogram", Message: "must be one of gauge, counter"},
+		}, p.Errors)
+	})
+
+	t.Run("unsupported media type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/value", strings.NewReader(`id=temperature`))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+		assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		limited := func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.Body = http.MaxBytesReader(w, r.Body, 8)
+				next.ServeHTTP(w, r)
+			})
+		}
+		req := httptest.NewRequest(http.MethodPost, "/value", strings.NewReader(`{"id":"temperature","type":"gauge"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		limited(handler).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("path outside specification passes", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestRequestValidationMiddleware_NilValidator(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := RequestValidationMiddleware(nil)(next)
+
+	req := httptest.NewRequest(http.MethodPost, "/value", strings.NewReader(`not json`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,14 @@
+package models
+
+import "errors"
+
+// Типизированные ошибки предметной области.
+// Сервисы оборачивают их через fmt.Errorf("%w"), обработчики сопоставляют с HTTP статусами через errors.Is.
+var (
+	// ErrMetricNotFound метрика с указанными типом и именем не найдена
+	ErrMetricNotFound = errors.New("metric not found")
+	// ErrUnsupportedMetricType тип метрики отличается от gauge и counter
+	ErrUnsupportedMetricType = errors.New("unsupported metric type")
+	// ErrStorageUnavailable хранилище временно недоступно (например, потеряно соединение с БД)
+	ErrStorageUnavailable = errors.New("storage unavailable")
+)
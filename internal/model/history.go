@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Sample значение метрики в момент времени.
+// Для gauge заполняется Value, для counter - Delta (накопленное значение счетчика).
+type Sample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Delta     *int64    `json:"delta,omitempty"`
+	Value     *float64  `json:"value,omitempty"`
+}
+
+// MetricHistory история значений метрики за интервал
+type MetricHistory struct {
+	ID      string    `json:"id"`
+	MType   string    `json:"type"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Step    string    `json:"step,omitempty"` // Шаг прореживания (пустой - исходные значения)
+	Samples []Sample  `json:"samples"`
+}
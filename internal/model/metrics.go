@@ -1,28 +1,24 @@
 package models
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+
+	pkgmodels "github.com/IgorKilipenko/metrical/pkg/models"
+)
 
 const (
-	Counter = "counter"
-	Gauge   = "gauge"
+	Counter = pkgmodels.Counter
+	Gauge   = pkgmodels.Gauge
 )
 
 // Типы-алиасы для улучшения читаемости
 type GaugeMetrics map[string]float64
 type CounterMetrics map[string]int64
 
-// NOTE: Не усложняем пример, вводя иерархическую вложенность структур.
-// Органичиваясь плоской моделью.
-// Delta и Value объявлены через указатели,
-// что бы отличать значение "0", от не заданного значения
-// и соответственно не кодировать в структуру.
-type Metrics struct {
-	ID    string   `json:"id"`
-	MType string   `json:"type"`
-	Delta *int64   `json:"delta,omitempty"`
-	Value *float64 `json:"value,omitempty"`
-	Hash  string   `json:"hash,omitempty"`
-}
+// Metrics метрика в формате JSON API. Определена в pkg/models, чтобы тот же формат
+// использовали внешние клиенты (pkg/metricalclient).
+type Metrics = pkgmodels.Metrics
 
 // ValidationError представляет ошибку валидации метрики
 type ValidationError struct {
@@ -35,8 +31,8 @@ func (e ValidationError) Error() string {
 	return fmt.Sprintf("validation error for field '%s' with value '%s': %s", e.Field, e.Value, e.Message)
 }
 
-// IsValidationError проверяет, является ли ошибка ошибкой валидации
+// IsValidationError проверяет, является ли ошибка (или одна из обернутых в нее) ошибкой валидации
 func IsValidationError(err error) bool {
-	_, ok := err.(ValidationError)
-	return ok
+	var validationErr ValidationError
+	return errors.As(err, &validationErr)
 }
@@ -0,0 +1,30 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// docsPage страница документации: загружает /openapi.json и отображает операции без внешних зависимостей
+//
+//go:embed docs.html
+var docsPage []byte
+
+// SpecHandler отдает спецификацию в формате JSON
+func SpecHandler(spec []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		w.Write(spec)
+	}
+}
+
+// DocsHandler отдает страницу документации; страница загружает спецификацию с адреса /openapi.json
+func DocsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(docsPage)
+	}
+}
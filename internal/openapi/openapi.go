@@ -0,0 +1,394 @@
+// Package openapi разбирает спецификацию OpenAPI 3 HTTP API и проверяет по ней входящие запросы.
+//
+// Поддерживается подмножество спецификации, достаточное для API сервера: пути с параметрами,
+// параметры path, query и header, тела запросов и схемы с ключевыми словами type, nullable, enum,
+// required, properties, items, minLength, maxLength и pattern. Ссылки $ref допускаются только
+// на разделы components того же документа.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Document спецификация OpenAPI
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	routes []*route
+}
+
+// PathItem операции одного пути
+type PathItem struct {
+	Parameters []*Parameter `json:"parameters,omitempty"`
+
+	Get     *Operation `json:"get,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Options *Operation `json:"options,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+}
+
+// operations возвращает операции пути по HTTP методам
+func (p *PathItem) operations() map[string]*Operation {
+	operations := map[string]*Operation{
+		http.MethodGet:     p.Get,
+		http.MethodPut:     p.Put,
+		http.MethodPost:    p.Post,
+		http.MethodDelete:  p.Delete,
+		http.MethodOptions: p.Options,
+		http.MethodHead:    p.Head,
+		http.MethodPatch:   p.Patch,
+	}
+	for method, operation := range operations {
+		if operation == nil {
+			delete(operations, method)
+		}
+	}
+	return operations
+}
+
+// Operation операция API
+type Operation struct {
+	OperationID string       `json:"operationId,omitempty"`
+	Parameters  []*Parameter `json:"parameters,omitempty"`
+	RequestBody *RequestBody `json:"requestBody,omitempty"`
+}
+
+// Parameter параметр запроса
+type Parameter struct {
+	Ref      string  `json:"$ref,omitempty"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"` // path, query или header
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody тело запроса
+type RequestBody struct {
+	Ref      string               `json:"$ref,omitempty"`
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// MediaType схема тела для типа содержимого
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Schema схема значения
+type Schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	Enum       []any              `json:"enum,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	MinLength  *int               `json:"minLength,omitempty"`
+	MaxLength  *int               `json:"maxLength,omitempty"`
+	Pattern    string             `json:"pattern,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Components переиспользуемые части документа
+type Components struct {
+	Schemas       map[string]*Schema      `json:"schemas,omitempty"`
+	Parameters    map[string]*Parameter   `json:"parameters,omitempty"`
+	RequestBodies map[string]*RequestBody `json:"requestBodies,omitempty"`
+}
+
+// Route операция документа: HTTP метод и шаблон пути
+type Route struct {
+	Method string
+	Path   string
+}
+
+// String возвращает маршрут в виде "POST /update/{type}/{name}/{value}"
+func (r Route) String() string {
+	return r.Method + " " + r.Path
+}
+
+// route операция с разобранным шаблоном пути
+type route struct {
+	Route
+	segments  []string
+	operation *Operation
+}
+
+// Load разбирает спецификацию в формате JSON и разрешает ссылки $ref
+func Load(data []byte) (*Document, error) {
+	doc := &Document{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q", doc.OpenAPI)
+	}
+
+	r := &resolver{components: &doc.Components, resolved: make(map[*Schema]bool)}
+	for path, item := range doc.Paths {
+		if item == nil {
+			return nil, fmt.Errorf("path %s has no operations", path)
+		}
+		if err := r.parameters(item.Parameters); err != nil {
+			return nil, fmt.Errorf("path %s: %w", path, err)
+		}
+
+		for method, operation := range item.operations() {
+			if err := r.operation(operation); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			// Параметры пути наследуются операцией, если она не переопределяет их
+			for _, parameter := range item.Parameters {
+				if operation.parameter(parameter.In, parameter.Name) == nil {
+					operation.Parameters = append(operation.Parameters, parameter)
+				}
+			}
+
+			route := &route{
+				Route:     Route{Method: method, Path: path},
+				segments:  splitPath(path),
+				operation: operation,
+			}
+			if err := route.checkPathParameters(); err != nil {
+				return nil, err
+			}
+			doc.routes = append(doc.routes, route)
+		}
+	}
+
+	slices.SortFunc(doc.routes, func(a, b *route) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return doc, nil
+}
+
+// MustLoad разбирает спецификацию и паникует при ошибке; предназначен для спецификаций, встроенных в бинарный файл
+func MustLoad(data []byte) *Document {
+	doc, err := Load(data)
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+// Routes возвращает операции документа, упорядоченные по пути и методу
+func (d *Document) Routes() []Route {
+	routes := make([]Route, 0, len(d.routes))
+	for _, route := range d.routes {
+		routes = append(routes, route.Route)
+	}
+	return routes
+}
+
+// find возвращает операцию для метода и пути запроса и значения параметров пути.
+// Шаблон с фиксированным сегментом предпочитается шаблону с параметром в той же позиции.
+func (d *Document) find(method, path string) (*route, map[string]string) {
+	segments := splitPath(path)
+
+	var best *route
+	bestStatic := -1
+	for _, route := range d.routes {
+		if route.Method != method || len(route.segments) != len(segments) {
+			continue
+		}
+		static, ok := route.match(segments)
+		if ok && static > bestStatic {
+			best, bestStatic = route, static
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	values := make(map[string]string)
+	for i, segment := range best.segments {
+		if name, ok := pathParameterName(segment); ok {
+			values[name] = segments[i]
+		}
+	}
+	return best, values
+}
+
+// match сравнивает сегменты пути с шаблоном и возвращает число совпавших фиксированных сегментов
+func (r *route) match(segments []string) (int, bool) {
+	static := 0
+	for i, segment := range r.segments {
+		if _, ok := pathParameterName(segment); ok {
+			if segments[i] == "" {
+				return 0, false
+			}
+			continue
+		}
+		if segment != segments[i] {
+			return 0, false
+		}
+		static++
+	}
+	return static, true
+}
+
+// checkPathParameters проверяет, что каждый параметр шаблона пути описан как обязательный параметр path
+func (r *route) checkPathParameters() error {
+	for _, segment := range r.segments {
+		name, ok := pathParameterName(segment)
+		if !ok {
+			continue
+		}
+		parameter := r.operation.parameter("path", name)
+		if parameter == nil {
+			return fmt.Errorf("%s: path parameter %q is not described", r, name)
+		}
+		if !parameter.Required {
+			return fmt.Errorf("%s: path parameter %q must be required", r, name)
+		}
+	}
+	return nil
+}
+
+// parameter возвращает параметр операции по месту и имени
+func (o *Operation) parameter(in, name string) *Parameter {
+	for _, parameter := range o.Parameters {
+		if parameter.In == in && parameter.Name == name {
+			return parameter
+		}
+	}
+	return nil
+}
+
+// splitPath разбивает путь на сегменты без ведущего слеша ("/" - один пустой сегмент)
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+// pathParameterName возвращает имя параметра для сегмента шаблона вида {name}
+func pathParameterName(segment string) (string, bool) {
+	if len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+// resolver заменяет ссылки $ref объектами из components
+type resolver struct {
+	components *Components
+	resolved   map[*Schema]bool
+}
+
+// operation разрешает ссылки параметров и тела операции
+func (r *resolver) operation(operation *Operation) error {
+	if err := r.parameters(operation.Parameters); err != nil {
+		return err
+	}
+
+	if body := operation.RequestBody; body != nil && body.Ref != "" {
+		target, err := lookup(r.components.RequestBodies, body.Ref, "requestBodies")
+		if err != nil {
+			return err
+		}
+		operation.RequestBody = target
+	}
+	if body := operation.RequestBody; body != nil {
+		for mediaType, content := range body.Content {
+			schema, err := r.schema(content.Schema)
+			if err != nil {
+				return fmt.Errorf("request body %s: %w", mediaType, err)
+			}
+			body.Content[mediaType] = MediaType{Schema: schema}
+		}
+	}
+	return nil
+}
+
+// parameters разрешает ссылки параметров на месте
+func (r *resolver) parameters(parameters []*Parameter) error {
+	for i, parameter := range parameters {
+		if parameter.Ref != "" {
+			target, err := lookup(r.components.Parameters, parameter.Ref, "parameters")
+			if err != nil {
+				return err
+			}
+			parameter = target
+			parameters[i] = target
+		}
+
+		switch parameter.In {
+		case "path", "query", "header":
+		default:
+			return fmt.Errorf("parameter %q: unsupported location %q", parameter.Name, parameter.In)
+		}
+
+		schema, err := r.schema(parameter.Schema)
+		if err != nil {
+			return fmt.Errorf("parameter %q: %w", parameter.Name, err)
+		}
+		parameter.Schema = schema
+	}
+	return nil
+}
+
+// schema возвращает схему с разрешенными ссылками; вложенные схемы разрешаются один раз
+func (r *resolver) schema(schema *Schema) (*Schema, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	if schema.Ref != "" {
+		target, err := lookup(r.components.Schemas, schema.Ref, "schemas")
+		if err != nil {
+			return nil, err
+		}
+		schema = target
+	}
+	if r.resolved[schema] {
+		return schema, nil
+	}
+	r.resolved[schema] = true
+
+	if schema.Pattern != "" {
+		pattern, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", schema.Pattern, err)
+		}
+		schema.pattern = pattern
+	}
+
+	for name, property := range schema.Properties {
+		resolved, err := r.schema(property)
+		if err != nil {
+			return nil, fmt.Errorf("property %q: %w", name, err)
+		}
+		schema.Properties[name] = resolved
+	}
+
+	items, err := r.schema(schema.Items)
+	if err != nil {
+		return nil, fmt.Errorf("items: %w", err)
+	}
+	schema.Items = items
+	return schema, nil
+}
+
+// lookup возвращает объект components по ссылке вида #/components/<section>/<name>
+func lookup[T any](objects map[string]*T, ref, section string) (*T, error) {
+	prefix := "#/components/" + section + "/"
+	name, ok := strings.CutPrefix(ref, prefix)
+	if !ok {
+		return nil, fmt.Errorf("unsupported reference %q", ref)
+	}
+	object, ok := objects[name]
+	if !ok || object == nil {
+		return nil, fmt.Errorf("unresolved reference %q", ref)
+	}
+	return object, nil
+}
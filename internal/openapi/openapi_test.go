@@ -0,0 +1,186 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSpec спецификация для тестов: фиксированный путь рядом с шаблоном и ссылки на components
+const testSpec = `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/update/{type}/{name}": {
+      "parameters": [{"$ref": "#/components/parameters/Type"}],
+      "post": {
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer"}}
+        ]
+      }
+    },
+    "/update/gauge/{name}": {
+      "post": {
+        "operationId": "updateGauge",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}]
+      }
+    },
+    "/update": {
+      "post": {
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Metric"}}}
+        }
+      }
+    },
+    "/": {"get": {}}
+  },
+  "components": {
+    "parameters": {
+      "Type": {"name": "type", "in": "path", "required": true, "schema": {"$ref": "#/components/schemas/Type"}}
+    },
+    "schemas": {
+      "Type": {"type": "string", "enum": ["gauge", "counter"]},
+      "Metric": {
+        "type": "object",
+        "required": ["id", "type"],
+        "properties": {
+          "id": {"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+          "type": {"$ref": "#/components/schemas/Type"},
+          "delta": {"type": "integer", "nullable": true},
+          "value": {"type": "number"}
+        }
+      }
+    }
+  }
+}`
+
+func TestLoad(t *testing.T) {
+	doc, err := Load([]byte(testSpec))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Route{
+		{Method: http.MethodGet, Path: "/"},
+		{Method: http.MethodPost, Path: "/update"},
+		{Method: http.MethodPost, Path: "/update/gauge/{name}"},
+		{Method: http.MethodPost, Path: "/update/{type}/{name}"},
+	}, doc.Routes())
+
+	// Параметр пути наследуется операцией, ссылки заменены объектами components
+	route, values := doc.find(http.MethodPost, "/update/counter/requests")
+	require.NotNil(t, route)
+	assert.Equal(t, map[string]string{"type": "counter", "name": "requests"}, values)
+	parameter := route.operation.parameter("path", "type")
+	require.NotNil(t, parameter)
+	assert.Equal(t, []any{"gauge", "counter"}, parameter.Schema.Enum)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected string
+	}{
+		{
+			name:     "invalid JSON",
+			spec:     `{`,
+			expected: "failed to parse OpenAPI document",
+		},
+		{
+			name:     "unsupported version",
+			spec:     `{"swagger": "2.0", "paths": {}}`,
+			expected: "unsupported OpenAPI version",
+		},
+		{
+			name:     "unresolved reference",
+			spec:     `{"openapi": "3.0.3", "paths": {"/": {"get": {"parameters": [{"$ref": "#/components/parameters/Missing"}]}}}}`,
+			expected: "unresolved reference",
+		},
+		{
+			name:     "external reference",
+			spec:     `{"openapi": "3.0.3", "paths": {"/": {"get": {"parameters": [{"$ref": "common.json#/Type"}]}}}}`,
+			expected: "unsupported reference",
+		},
+		{
+			name:     "undescribed path parameter",
+			spec:     `{"openapi": "3.0.3", "paths": {"/value/{name}": {"get": {}}}}`,
+			expected: `path parameter "name" is not described`,
+		},
+		{
+			name:     "optional path parameter",
+			spec:     `{"openapi": "3.0.3", "paths": {"/value/{name}": {"get": {"parameters": [{"name": "name", "in": "path"}]}}}}`,
+			expected: `path parameter "name" must be required`,
+		},
+		{
+			name:     "invalid pattern",
+			spec:     `{"openapi": "3.0.3", "paths": {"/": {"get": {"parameters": [{"name": "q", "in": "query", "schema": {"pattern": "["}}]}}}}`,
+			expected: "invalid pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load([]byte(tt.spec))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	doc := MustLoad([]byte(testSpec))
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		operationID string
+		found       bool
+	}{
+		{name: "static segment preferred", method: http.MethodPost, path: "/update/gauge/temperature", operationID: "updateGauge", found: true},
+		{name: "template", method: http.MethodPost, path: "/update/counter/requests", found: true},
+		{name: "root", method: http.MethodGet, path: "/", found: true},
+		{name: "unknown method", method: http.MethodGet, path: "/update", found: false},
+		{name: "unknown path", method: http.MethodPost, path: "/update/gauge", found: false},
+		{name: "empty parameter", method: http.MethodPost, path: "/update/counter/", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, _ := doc.find(tt.method, tt.path)
+			if !tt.found {
+				assert.Nil(t, route)
+				return
+			}
+			require.NotNil(t, route)
+			assert.Equal(t, tt.operationID, route.operation.OperationID)
+		})
+	}
+}
+
+func TestLoad_APISpec(t *testing.T) {
+	doc, err := Load(api.OpenAPI)
+	require.NoError(t, err)
+	assert.NotEmpty(t, doc.Routes())
+}
+
+func TestHandlers(t *testing.T) {
+	spec := []byte(`{"openapi":"3.0.3"}`)
+
+	w := httptest.NewRecorder()
+	SpecHandler(spec)(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, string(spec), w.Body.String())
+
+	w = httptest.NewRecorder()
+	DocsHandler()(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/html"))
+	assert.Contains(t, w.Body.String(), `fetch("/openapi.json")`)
+}
@@ -0,0 +1,334 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/IgorKilipenko/metrical/internal/problem"
+)
+
+// jsonMediaType тип содержимого, тело которого проверяется по схеме
+const jsonMediaType = "application/json"
+
+// Validator проверяет запросы по спецификации
+type Validator struct {
+	doc *Document
+}
+
+// NewValidator создает валидатор запросов по документу (nil для nil документа)
+func NewValidator(doc *Document) *Validator {
+	if doc == nil {
+		return nil
+	}
+	return &Validator{doc: doc}
+}
+
+// ValidateRequest проверяет параметры и тело запроса по описанию операции.
+// Запросы к путям и методам, которых нет в спецификации, не проверяются (их отклоняет роутер).
+// Ошибки запроса возвращаются как *problem.Problem: 415 для неописанного типа содержимого,
+// 400 для некорректного JSON и ошибок схемы (с перечнем полей). Прочие ошибки возвращаются
+// без изменений, например *http.MaxBytesError при чтении слишком большого тела.
+// Прочитанное тело JSON подставляется обратно в r.Body.
+func (v *Validator) ValidateRequest(r *http.Request) error {
+	route, pathValues := v.doc.find(r.Method, r.URL.Path)
+	if route == nil {
+		return nil
+	}
+
+	var errs fieldErrors
+	query := r.URL.Query()
+	for _, parameter := range route.operation.Parameters {
+		var value string
+		var present bool
+		switch parameter.In {
+		case "path":
+			value, present = pathValues[parameter.Name]
+		case "query":
+			present = query.Has(parameter.Name)
+			value = query.Get(parameter.Name)
+		case "header":
+			value = r.Header.Get(parameter.Name)
+			present = value != ""
+		}
+
+		if !present {
+			if parameter.Required {
+				errs.add(parameter.Name, "", "is required")
+			}
+			continue
+		}
+		parameter.Schema.validateParameter(parameter.Name, value, &errs)
+	}
+
+	if body := route.operation.RequestBody; body != nil {
+		if err := v.validateBody(r, body, &errs); err != nil {
+			return err
+		}
+	}
+
+	return errs.problem()
+}
+
+// validateBody проверяет тип содержимого и тело JSON запроса
+func (v *Validator) validateBody(r *http.Request, body *RequestBody, errs *fieldErrors) error {
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil && contentType != "" {
+		mediaType = ""
+	}
+
+	content, ok := body.Content[mediaType]
+	if !ok {
+		if contentType == "" && !body.Required {
+			return nil
+		}
+		return &problem.Problem{
+			Type:   problem.TypeDefault,
+			Title:  http.StatusText(http.StatusUnsupportedMediaType),
+			Status: http.StatusUnsupportedMediaType,
+			Detail: fmt.Sprintf("Content-Type must be %s", strings.Join(mediaTypes(body), " or ")),
+		}
+	}
+	if mediaType != jsonMediaType || content.Schema == nil {
+		return nil
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if body.Required {
+			errs.add("body", "", "is required")
+		}
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return problem.New(http.StatusBadRequest, "Invalid JSON format")
+	}
+
+	content.Schema.validate("", value, errs)
+	return nil
+}
+
+// mediaTypes возвращает описанные типы содержимого тела в алфавитном порядке
+func mediaTypes(body *RequestBody) []string {
+	types := make([]string, 0, len(body.Content))
+	for mediaType := range body.Content {
+		types = append(types, mediaType)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// fieldErrors ошибки полей запроса
+type fieldErrors []problem.FieldError
+
+// add добавляет ошибку поля
+func (e *fieldErrors) add(field, value, message string) {
+	*e = append(*e, problem.FieldError{Field: field, Value: value, Message: message})
+}
+
+// problem возвращает описание ошибки валидации (nil, если ошибок нет)
+func (e fieldErrors) problem() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	details := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		details = append(details, fieldErr.Field+" "+fieldErr.Message)
+	}
+	return &problem.Problem{
+		Type:   problem.TypeValidation,
+		Title:  "Validation failed",
+		Status: http.StatusBadRequest,
+		Detail: "request does not match API specification: " + strings.Join(details, "; "),
+		Errors: e,
+	}
+}
+
+// validateParameter проверяет строковое значение параметра, приводя его к типу схемы
+func (s *Schema) validateParameter(field, raw string, errs *fieldErrors) {
+	if s == nil {
+		return
+	}
+
+	var value any = raw
+	switch s.Type {
+	case "integer", "number":
+		value = json.Number(raw)
+	case "boolean":
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs.add(field, raw, "must be a boolean")
+			return
+		}
+		value = parsed
+	}
+	s.validate(field, value, errs)
+}
+
+// validate проверяет значение JSON (числа - json.Number) и добавляет ошибки в errs.
+// field - путь к значению в теле: "id", "[0].value" (пустая строка - тело целиком).
+func (s *Schema) validate(field string, value any, errs *fieldErrors) {
+	if s == nil {
+		return
+	}
+
+	if value == nil {
+		if !s.Nullable && s.Type != "" {
+			errs.add(fieldName(field), "null", "must not be null")
+		}
+		return
+	}
+
+	if !s.checkType(value) {
+		errs.add(fieldName(field), formatValue(value), "must be of type "+s.Type)
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(allowed any) bool {
+		return formatValue(allowed) == formatValue(value)
+	}) {
+		allowed := make([]string, 0, len(s.Enum))
+		for _, item := range s.Enum {
+			allowed = append(allowed, formatValue(item))
+		}
+		errs.add(fieldName(field), formatValue(value), "must be one of "+strings.Join(allowed, ", "))
+		return
+	}
+
+	switch value := value.(type) {
+	case string:
+		s.validateString(field, value, errs)
+	case map[string]any:
+		s.validateObject(field, value, errs)
+	case []any:
+		for i, item := range value {
+			s.Items.validate(fmt.Sprintf("%s[%d]", field, i), item, errs)
+		}
+	}
+}
+
+// validateString проверяет длину и шаблон строки
+func (s *Schema) validateString(field, value string, errs *fieldErrors) {
+	field = fieldName(field)
+	length := utf8.RuneCountInString(value)
+	if s.MinLength != nil && length < *s.MinLength {
+		if *s.MinLength == 1 {
+			errs.add(field, value, "must not be empty")
+		} else {
+			errs.add(field, value, fmt.Sprintf("must be at least %d characters long", *s.MinLength))
+		}
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		errs.add(field, value, fmt.Sprintf("must be at most %d characters long", *s.MaxLength))
+	}
+	if s.pattern != nil && !s.pattern.MatchString(value) {
+		errs.add(field, value, "must match pattern "+s.Pattern)
+	}
+}
+
+// validateObject проверяет обязательные и описанные свойства объекта; прочие свойства допускаются
+func (s *Schema) validateObject(field string, value map[string]any, errs *fieldErrors) {
+	prefix := field
+	if prefix != "" {
+		prefix += "."
+	}
+
+	for _, name := range s.Required {
+		if _, ok := value[name]; !ok {
+			errs.add(prefix+name, "", "is required")
+		}
+	}
+
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if property, ok := value[name]; ok {
+			s.Properties[name].validate(prefix+name, property, errs)
+		}
+	}
+}
+
+// fieldName возвращает имя поля для описания ошибки; пустой путь обозначает тело запроса целиком
+func fieldName(field string) string {
+	if field == "" {
+		return "body"
+	}
+	return field
+}
+
+// checkType проверяет соответствие значения типу схемы (пустой тип допускает любое значение)
+func (s *Schema) checkType(value any) bool {
+	switch s.Type {
+	case "":
+		return true
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := number.Float64()
+		return err == nil
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := number.Int64()
+		return err == nil
+	default:
+		return false
+	}
+}
+
+// formatValue возвращает значение в текстовом виде для описания ошибки
+func formatValue(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case map[string]any, []any:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,196 @@
+package openapi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidator_ValidateRequest(t *testing.T) {
+	validator := NewValidator(MustLoad([]byte(testSpec)))
+	require.NotNil(t, validator)
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		contentType    string
+		body           string
+		expectedStatus int                  // 0 - запрос корректен
+		expectedErrors []problem.FieldError // Ожидаемые ошибки полей (nil - не проверяются)
+	}{
+		{
+			name:   "valid path parameters",
+			method: http.MethodPost,
+			target: "/update/counter/requests?limit=10",
+		},
+		{
+			name:           "path parameter outside enum",
+			method:         http.MethodPost,
+			target:         "/update/histogram/requests",
+			expectedStatus: http.StatusBadRequest,
+			expectedErrors: []problem.FieldError{{Field: "type", Value: "histogram", Message: "must be one of gauge, counter"}},
+		},
+		{
+			name:           "query parameter of wrong type",
+			method:         http.MethodPost,
+			target:         "/update/counter/requests?limit=ten",
+			expectedStatus: http.StatusBadRequest,
+			expectedErrors: []problem.FieldError{{Field: "limit", Value: "ten", Message: "must be of type integer"}},
+		},
+		{
+			name:   "path outside specification",
+			method: http.MethodDelete,
+			target: "/unknown",
+		},
+		{
+			name:        "valid body",
+			method:      http.MethodPost,
+			target:      "/update",
+			contentType: "application/json",
+			body:        `{"id":"requests","type":"counter","delta":5,"extra":true}`,
+		},
+		{
+			name:        "nullable property",
+			method:      http.MethodPost,
+			target:      "/update",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"id":"temperature","type":"gauge","delta":null,"value":1.5}`,
+		},
+		{
+			name:           "all body errors reported",
+			method:         http.MethodPost,
+			target:         "/update",
+			contentType:    "application/json",
+			body:           `{"id":"Temperature","delta":1.5,"value":null}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedErrors: []problem.FieldError{
+				{Field: "type", Value: "", Message: "is required"},
+				{Field: "delta", Value: "1.5", Message: "must be of type integer"},
+				{Field: "id", Value: "Temperature", Message: "must match pattern ^[a-z]+$"},
+				{Field: "value", Value: "null", Message: "must not be null"},
+			},
+		},
+		{
+			name:           "body of wrong type",
+			method:         http.MethodPost,
+			target:         "/update",
+			contentType:    "application/json",
+			body:           `[{"id":"requests"}]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedErrors: []problem.FieldError{{Field: "body", Value: `[{"id":"requests"}]`, Message: "must be of type object"}},
+		},
+		{
+			name:           "empty body",
+			method:         http.MethodPost,
+			target:         "/update",
+			contentType:    "application/json",
+			expectedStatus: http.StatusBadRequest,
+			expectedErrors: []problem.FieldError{{Field: "body", Value: "", Message: "is required"}},
+		},
+		{
+			name:           "invalid JSON",
+			method:         http.MethodPost,
+			target:         "/update",
+			contentType:    "application/json",
+			body:           `{"id":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "trailing data after JSON",
+			method:         http.MethodPost,
+			target:         "/update",
+			contentType:    "application/json",
+			body:           `{"id":"a","type":"gauge"} {}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "undescribed content type",
+			method:         http.MethodPost,
+			target:         "/update",
+			contentType:    "text/plain",
+			body:           `id=requests`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "missing content type",
+			method:         http.MethodPost,
+			target:         "/update",
+			body:           `{"id":"requests","type":"counter"}`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			err := validator.ValidateRequest(req)
+			if tt.expectedStatus == 0 {
+				require.NoError(t, err)
+
+				// Тело остается доступным обработчику
+				body, readErr := io.ReadAll(req.Body)
+				require.NoError(t, readErr)
+				assert.Equal(t, tt.body, string(body))
+				return
+			}
+
+			var p *problem.Problem
+			require.True(t, errors.As(err, &p), "expected problem, got %v", err)
+			assert.Equal(t, tt.expectedStatus, p.Status)
+			if tt.expectedErrors != nil {
+				assert.Equal(t, problem.TypeValidation, p.Type)
+				assert.Equal(t, tt.expectedErrors, p.Errors)
+			}
+		})
+	}
+}
+
+func TestValidator_ArrayItems(t *testing.T) {
+	doc := MustLoad([]byte(`{
+	  "openapi": "3.0.3",
+	  "paths": {"/updates": {"post": {"requestBody": {"required": true, "content": {"application/json": {
+	    "schema": {"type": "array", "items": {"type": "object", "required": ["id"], "properties": {"id": {"type": "string"}}}}
+	  }}}}}}
+	}`))
+
+	req := httptest.NewRequest(http.MethodPost, "/updates", strings.NewReader(`[{"id":"a"},{"id":1},{}]`))
+	req.Header.Set("Content-Type", "application/json")
+
+	err := NewValidator(doc).ValidateRequest(req)
+
+	var p *problem.Problem
+	require.True(t, errors.As(err, &p))
+	assert.Equal(t, []problem.FieldError{
+		{Field: "[1].id", Value: "1", Message: "must be of type string"},
+		{Field: "[2].id", Value: "", Message: "is required"},
+	}, p.Errors)
+}
+
+func TestValidator_BodyReadError(t *testing.T) {
+	validator := NewValidator(MustLoad([]byte(testSpec)))
+
+	req := httptest.NewRequest(http.MethodPost, "/update", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Body = http.MaxBytesReader(httptest.NewRecorder(), io.NopCloser(strings.NewReader(`{"id":"requests"}`)), 4)
+
+	err := validator.ValidateRequest(req)
+
+	var maxBytesErr *http.MaxBytesError
+	assert.True(t, errors.As(err, &maxBytesErr), "expected MaxBytesError, got %v", err)
+}
+
+func TestNewValidator_NilDocument(t *testing.T) {
+	assert.Nil(t, NewValidator(nil))
+}
@@ -0,0 +1,328 @@
+// Package otlp принимает метрики OpenTelemetry по протоколу OTLP/HTTP (protobuf и JSON).
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Типы содержимого OTLP/HTTP
+const (
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeJSON     = "application/json"
+)
+
+// AggregationTemporality временная агрегация Sum
+type AggregationTemporality int32
+
+// Значения перечисления opentelemetry.proto.metrics.v1.AggregationTemporality
+const (
+	TemporalityUnspecified AggregationTemporality = 0
+	TemporalityDelta       AggregationTemporality = 1
+	TemporalityCumulative  AggregationTemporality = 2
+)
+
+// FlagNoRecordedValue флаг точки без значения (DataPointFlags)
+const FlagNoRecordedValue uint32 = 1
+
+// ExportMetricsServiceRequest тело запроса POST /v1/metrics
+type ExportMetricsServiceRequest struct {
+	ResourceMetrics []ResourceMetrics `json:"resourceMetrics,omitempty"`
+}
+
+// ResourceMetrics метрики одного ресурса (сервиса, процесса, хоста)
+type ResourceMetrics struct {
+	Resource     Resource       `json:"resource"`
+	ScopeMetrics []ScopeMetrics `json:"scopeMetrics,omitempty"`
+}
+
+// Resource атрибуты ресурса
+type Resource struct {
+	Attributes []KeyValue `json:"attributes,omitempty"`
+}
+
+// ScopeMetrics метрики одной библиотеки инструментирования
+type ScopeMetrics struct {
+	Scope   InstrumentationScope `json:"scope"`
+	Metrics []Metric             `json:"metrics,omitempty"`
+}
+
+// InstrumentationScope библиотека инструментирования
+type InstrumentationScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// Metric метрика; заполнено ровно одно из полей данных
+type Metric struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Unit        string `json:"unit,omitempty"`
+
+	Gauge *Gauge `json:"gauge,omitempty"`
+	Sum   *Sum   `json:"sum,omitempty"`
+
+	Histogram            *UnsupportedData `json:"histogram,omitempty"`
+	ExponentialHistogram *UnsupportedData `json:"exponentialHistogram,omitempty"`
+	Summary              *UnsupportedData `json:"summary,omitempty"`
+}
+
+// Gauge мгновенные значения
+type Gauge struct {
+	DataPoints []NumberDataPoint `json:"dataPoints,omitempty"`
+}
+
+// Sum сумма (счетчик или UpDownCounter)
+type Sum struct {
+	DataPoints             []NumberDataPoint      `json:"dataPoints,omitempty"`
+	AggregationTemporality AggregationTemporality `json:"aggregationTemporality"`
+	IsMonotonic            bool                   `json:"isMonotonic,omitempty"`
+}
+
+// UnsupportedData данные Histogram, ExponentialHistogram и Summary: точки не разбираются, только учитываются
+type UnsupportedData struct {
+	DataPoints []json.RawMessage `json:"dataPoints,omitempty"`
+}
+
+// NumberDataPoint точка Gauge или Sum; значение задается AsDouble или AsInt
+type NumberDataPoint struct {
+	Attributes        []KeyValue
+	StartTimeUnixNano uint64
+	TimeUnixNano      uint64
+	AsDouble          *float64
+	AsInt             *int64
+	Flags             uint32
+}
+
+// Value возвращает значение точки; false - значение не записано
+func (p *NumberDataPoint) Value() (float64, bool) {
+	if p.Flags&FlagNoRecordedValue != 0 {
+		return 0, false
+	}
+	switch {
+	case p.AsDouble != nil:
+		return *p.AsDouble, true
+	case p.AsInt != nil:
+		return float64(*p.AsInt), true
+	default:
+		return 0, false
+	}
+}
+
+// numberDataPointJSON представление точки в OTLP/JSON: 64-битные числа могут передаваться строками
+type numberDataPointJSON struct {
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano jsonUint64 `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      jsonUint64 `json:"timeUnixNano,omitempty"`
+	AsDouble          *float64   `json:"asDouble,omitempty"`
+	AsInt             *jsonInt64 `json:"asInt,omitempty"`
+	Flags             uint32     `json:"flags,omitempty"`
+}
+
+// UnmarshalJSON разбирает точку в формате OTLP/JSON
+func (p *NumberDataPoint) UnmarshalJSON(data []byte) error {
+	var raw numberDataPointJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*p = NumberDataPoint{
+		Attributes:        raw.Attributes,
+		StartTimeUnixNano: uint64(raw.StartTimeUnixNano),
+		TimeUnixNano:      uint64(raw.TimeUnixNano),
+		AsDouble:          raw.AsDouble,
+		Flags:             raw.Flags,
+	}
+	if raw.AsInt != nil {
+		value := int64(*raw.AsInt)
+		p.AsInt = &value
+	}
+	return nil
+}
+
+// MarshalJSON сериализует точку в формате OTLP/JSON
+func (p NumberDataPoint) MarshalJSON() ([]byte, error) {
+	raw := numberDataPointJSON{
+		Attributes:        p.Attributes,
+		StartTimeUnixNano: jsonUint64(p.StartTimeUnixNano),
+		TimeUnixNano:      jsonUint64(p.TimeUnixNano),
+		AsDouble:          p.AsDouble,
+		Flags:             p.Flags,
+	}
+	if p.AsInt != nil {
+		value := jsonInt64(*p.AsInt)
+		raw.AsInt = &value
+	}
+	return json.Marshal(raw)
+}
+
+// KeyValue атрибут
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue значение атрибута; заполнено одно из полей
+type AnyValue struct {
+	StringValue *string
+	BoolValue   *bool
+	IntValue    *int64
+	DoubleValue *float64
+	ArrayValue  *ArrayValue
+	KvlistValue *KeyValueList
+	BytesValue  []byte
+}
+
+// anyValueJSON представление значения атрибута в OTLP/JSON
+type anyValueJSON struct {
+	StringValue *string       `json:"stringValue,omitempty"`
+	BoolValue   *bool         `json:"boolValue,omitempty"`
+	IntValue    *jsonInt64    `json:"intValue,omitempty"`
+	DoubleValue *float64      `json:"doubleValue,omitempty"`
+	ArrayValue  *ArrayValue   `json:"arrayValue,omitempty"`
+	KvlistValue *KeyValueList `json:"kvlistValue,omitempty"`
+	BytesValue  []byte        `json:"bytesValue,omitempty"`
+}
+
+// UnmarshalJSON разбирает значение атрибута в формате OTLP/JSON
+func (v *AnyValue) UnmarshalJSON(data []byte) error {
+	var raw anyValueJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*v = AnyValue{
+		StringValue: raw.StringValue,
+		BoolValue:   raw.BoolValue,
+		DoubleValue: raw.DoubleValue,
+		ArrayValue:  raw.ArrayValue,
+		KvlistValue: raw.KvlistValue,
+		BytesValue:  raw.BytesValue,
+	}
+	if raw.IntValue != nil {
+		value := int64(*raw.IntValue)
+		v.IntValue = &value
+	}
+	return nil
+}
+
+// MarshalJSON сериализует значение атрибута в формате OTLP/JSON
+func (v AnyValue) MarshalJSON() ([]byte, error) {
+	raw := anyValueJSON{
+		StringValue: v.StringValue,
+		BoolValue:   v.BoolValue,
+		DoubleValue: v.DoubleValue,
+		ArrayValue:  v.ArrayValue,
+		KvlistValue: v.KvlistValue,
+		BytesValue:  v.BytesValue,
+	}
+	if v.IntValue != nil {
+		value := jsonInt64(*v.IntValue)
+		raw.IntValue = &value
+	}
+	return json.Marshal(raw)
+}
+
+// ArrayValue массив значений атрибута
+type ArrayValue struct {
+	Values []AnyValue `json:"values,omitempty"`
+}
+
+// KeyValueList вложенный список атрибутов
+type KeyValueList struct {
+	Values []KeyValue `json:"values,omitempty"`
+}
+
+// StringAttribute создает строковый атрибут
+func StringAttribute(key, value string) KeyValue {
+	return KeyValue{Key: key, Value: AnyValue{StringValue: &value}}
+}
+
+// String возвращает значение атрибута в текстовом виде для имени метрики
+func (v AnyValue) String() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.IntValue != nil:
+		return strconv.FormatInt(*v.IntValue, 10)
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'g', -1, 64)
+	case v.ArrayValue != nil:
+		values := make([]string, 0, len(v.ArrayValue.Values))
+		for _, item := range v.ArrayValue.Values {
+			values = append(values, item.String())
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	case v.KvlistValue != nil:
+		values := make([]string, 0, len(v.KvlistValue.Values))
+		for _, item := range v.KvlistValue.Values {
+			values = append(values, item.Key+"="+item.Value.String())
+		}
+		return "{" + strings.Join(values, ",") + "}"
+	case v.BytesValue != nil:
+		return fmt.Sprintf("%x", v.BytesValue)
+	default:
+		return ""
+	}
+}
+
+// ExportMetricsServiceResponse ответ POST /v1/metrics
+type ExportMetricsServiceResponse struct {
+	PartialSuccess *ExportMetricsPartialSuccess `json:"partialSuccess,omitempty"`
+}
+
+// ExportMetricsPartialSuccess сведения о точках, отклоненных сервером
+type ExportMetricsPartialSuccess struct {
+	RejectedDataPoints int64  `json:"rejectedDataPoints,omitempty"`
+	ErrorMessage       string `json:"errorMessage,omitempty"`
+}
+
+// UnmarshalJSON разбирает запрос в формате OTLP/JSON
+func UnmarshalJSON(data []byte) (*ExportMetricsServiceRequest, error) {
+	req := &ExportMetricsServiceRequest{}
+	if err := json.Unmarshal(data, req); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal OTLP JSON request: %w", err)
+	}
+	return req, nil
+}
+
+// jsonInt64 int64, который в OTLP/JSON передается строкой или числом
+type jsonInt64 int64
+
+// UnmarshalJSON принимает "42" и 42
+func (v *jsonInt64) UnmarshalJSON(data []byte) error {
+	parsed, err := strconv.ParseInt(string(bytes.Trim(data, `"`)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid int64 value %s", data)
+	}
+	*v = jsonInt64(parsed)
+	return nil
+}
+
+// MarshalJSON сериализует значение строкой, как требует OTLP/JSON
+func (v jsonInt64) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + strconv.FormatInt(int64(v), 10) + `"`), nil
+}
+
+// jsonUint64 uint64, который в OTLP/JSON передается строкой или числом
+type jsonUint64 uint64
+
+// UnmarshalJSON принимает "42" и 42
+func (v *jsonUint64) UnmarshalJSON(data []byte) error {
+	parsed, err := strconv.ParseUint(string(bytes.Trim(data, `"`)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid uint64 value %s", data)
+	}
+	*v = jsonUint64(parsed)
+	return nil
+}
+
+// MarshalJSON сериализует значение строкой, как требует OTLP/JSON
+func (v jsonUint64) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + strconv.FormatUint(uint64(v), 10) + `"`), nil
+}
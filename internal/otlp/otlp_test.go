@@ -0,0 +1,128 @@
+package otlp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// otelJSON запрос в формате OTLP/JSON, как его отправляет OpenTelemetry SDK
+const otelJSON = `{
+  "resourceMetrics": [{
+    "resource": {"attributes": [
+      {"key": "service.name", "value": {"stringValue": "checkout"}},
+      {"key": "host.id", "value": {"intValue": "42"}}
+    ]},
+    "scopeMetrics": [{
+      "scope": {"name": "go.opentelemetry.io/otel/metric", "version": "1.0"},
+      "metrics": [
+        {"name": "queue.size", "gauge": {"dataPoints": [{"asDouble": 3.5, "timeUnixNano": "1700000000000000000"}]}},
+        {"name": "http.requests", "sum": {"aggregationTemporality": 2, "isMonotonic": true, "dataPoints": [
+          {"asInt": "12", "startTimeUnixNano": 1699999990000000000, "timeUnixNano": "1700000000000000000",
+           "attributes": [{"key": "method", "value": {"stringValue": "GET"}}, {"key": "ok", "value": {"boolValue": true}}]}
+        ]}},
+        {"name": "latency", "histogram": {"dataPoints": [{}, {}]}}
+      ]
+    }]
+  }]
+}`
+
+func TestUnmarshalJSON(t *testing.T) {
+	req, err := UnmarshalJSON([]byte(otelJSON))
+	require.NoError(t, err)
+
+	require.Len(t, req.ResourceMetrics, 1)
+	resource := req.ResourceMetrics[0].Resource
+	require.Len(t, resource.Attributes, 2)
+	assert.Equal(t, "checkout", resource.Attributes[0].Value.String())
+	require.NotNil(t, resource.Attributes[1].Value.IntValue)
+	assert.Equal(t, int64(42), *resource.Attributes[1].Value.IntValue)
+
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 3)
+
+	gauge := metrics[0].Gauge.DataPoints[0]
+	value, ok := gauge.Value()
+	assert.True(t, ok)
+	assert.Equal(t, 3.5, value)
+	assert.Equal(t, uint64(1700000000000000000), gauge.TimeUnixNano)
+
+	sum := metrics[1].Sum
+	assert.Equal(t, TemporalityCumulative, sum.AggregationTemporality)
+	assert.True(t, sum.IsMonotonic)
+	point := sum.DataPoints[0]
+	require.NotNil(t, point.AsInt)
+	assert.Equal(t, int64(12), *point.AsInt)
+	assert.Equal(t, uint64(1699999990000000000), point.StartTimeUnixNano, "Numbers are accepted as well as strings")
+	assert.Equal(t, "true", point.Attributes[1].Value.String())
+
+	assert.Len(t, metrics[2].Histogram.DataPoints, 2)
+}
+
+func TestUnmarshalJSON_Errors(t *testing.T) {
+	_, err := UnmarshalJSON([]byte(`{"resourceMetrics": [`))
+	assert.Error(t, err)
+
+	_, err = UnmarshalJSON([]byte(`{"resourceMetrics": [{"scopeMetrics": [{"metrics": [{"name": "x", "sum": {"dataPoints": [{"asInt": "1.5"}]}}]}]}]}`))
+	assert.Error(t, err)
+}
+
+func TestNumberDataPoint_JSONRoundTrip(t *testing.T) {
+	asInt := int64(-7)
+	point := NumberDataPoint{
+		Attributes:   []KeyValue{StringAttribute("k", "v")},
+		TimeUnixNano: 1700000000000000000,
+		AsInt:        &asInt,
+	}
+
+	data, err := json.Marshal(point)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"asInt":"-7"`)
+	assert.Contains(t, string(data), `"timeUnixNano":"1700000000000000000"`)
+
+	var decoded NumberDataPoint
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, point, decoded)
+}
+
+func TestNumberDataPoint_Value(t *testing.T) {
+	asDouble := 1.5
+	asInt := int64(2)
+
+	value, ok := (&NumberDataPoint{AsDouble: &asDouble}).Value()
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, value)
+
+	value, ok = (&NumberDataPoint{AsInt: &asInt}).Value()
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, value)
+
+	_, ok = (&NumberDataPoint{AsInt: &asInt, Flags: FlagNoRecordedValue}).Value()
+	assert.False(t, ok)
+
+	_, ok = (&NumberDataPoint{}).Value()
+	assert.False(t, ok)
+}
+
+func TestAnyValue_String(t *testing.T) {
+	s, b, i, d := "x", false, int64(-3), 0.25
+	tests := []struct {
+		value    AnyValue
+		expected string
+	}{
+		{AnyValue{StringValue: &s}, "x"},
+		{AnyValue{BoolValue: &b}, "false"},
+		{AnyValue{IntValue: &i}, "-3"},
+		{AnyValue{DoubleValue: &d}, "0.25"},
+		{AnyValue{BytesValue: []byte{0xca, 0xfe}}, "cafe"},
+		{AnyValue{ArrayValue: &ArrayValue{Values: []AnyValue{{StringValue: &s}, {IntValue: &i}}}}, "[x,-3]"},
+		{AnyValue{KvlistValue: &KeyValueList{Values: []KeyValue{StringAttribute("a", "b")}}}, "{a=b}"},
+		{AnyValue{}, ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.value.String())
+	}
+}
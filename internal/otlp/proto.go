@@ -0,0 +1,502 @@
+package otlp
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/IgorKilipenko/metrical/internal/protoutil"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Номера полей схемы opentelemetry/proto/metrics/v1/metrics.proto,
+// common/v1/common.proto и collector/metrics/v1/metrics_service.proto
+const (
+	fieldRequestResourceMetrics = 1
+
+	fieldResourceMetricsResource = 1
+	fieldResourceMetricsScope    = 2
+
+	fieldResourceAttributes = 1
+
+	fieldScopeMetricsScope   = 1
+	fieldScopeMetricsMetrics = 2
+
+	fieldScopeName    = 1
+	fieldScopeVersion = 2
+
+	fieldMetricName                 = 1
+	fieldMetricDescription          = 2
+	fieldMetricUnit                 = 3
+	fieldMetricGauge                = 5
+	fieldMetricSum                  = 7
+	fieldMetricHistogram            = 9
+	fieldMetricExponentialHistogram = 10
+	fieldMetricSummary              = 11
+
+	fieldDataPoints             = 1
+	fieldSumTemporality         = 2
+	fieldSumIsMonotonic         = 3
+	fieldPointStartTimeUnixNano = 2
+	fieldPointTimeUnixNano      = 3
+	fieldPointAsDouble          = 4
+	fieldPointAsInt             = 6
+	fieldPointAttributes        = 7
+	fieldPointFlags             = 8
+
+	fieldKeyValueKey   = 1
+	fieldKeyValueValue = 2
+
+	fieldAnyString = 1
+	fieldAnyBool   = 2
+	fieldAnyInt    = 3
+	fieldAnyDouble = 4
+	fieldAnyArray  = 5
+	fieldAnyKvlist = 6
+	fieldAnyBytes  = 7
+
+	fieldListValues = 1
+
+	fieldResponsePartialSuccess = 1
+	fieldPartialRejected        = 1
+	fieldPartialErrorMessage    = 2
+)
+
+// maxAttributeDepth ограничение вложенности массивов и списков в значениях атрибутов
+const maxAttributeDepth = 8
+
+// UnmarshalProto разбирает запрос в формате OTLP/protobuf. Неизвестные поля пропускаются.
+func UnmarshalProto(data []byte) (*ExportMetricsServiceRequest, error) {
+	req := &ExportMetricsServiceRequest{}
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if num != fieldRequestResourceMetrics || typ != protowire.BytesType {
+			return nil
+		}
+		resourceMetrics, err := unmarshalResourceMetrics(value)
+		if err != nil {
+			return fmt.Errorf("resourceMetrics %d: %w", len(req.ResourceMetrics), err)
+		}
+		req.ResourceMetrics = append(req.ResourceMetrics, resourceMetrics)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal OTLP protobuf request: %w", err)
+	}
+	return req, nil
+}
+
+// unmarshalResourceMetrics разбирает ResourceMetrics
+func unmarshalResourceMetrics(data []byte) (ResourceMetrics, error) {
+	var result ResourceMetrics
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case fieldResourceMetricsResource:
+			return protoutil.WalkFields(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if num != fieldResourceAttributes || typ != protowire.BytesType {
+					return nil
+				}
+				attribute, err := unmarshalKeyValue(value, 0)
+				if err != nil {
+					return fmt.Errorf("resource attribute: %w", err)
+				}
+				result.Resource.Attributes = append(result.Resource.Attributes, attribute)
+				return nil
+			})
+		case fieldResourceMetricsScope:
+			scopeMetrics, err := unmarshalScopeMetrics(value)
+			if err != nil {
+				return fmt.Errorf("scopeMetrics %d: %w", len(result.ScopeMetrics), err)
+			}
+			result.ScopeMetrics = append(result.ScopeMetrics, scopeMetrics)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// unmarshalScopeMetrics разбирает ScopeMetrics
+func unmarshalScopeMetrics(data []byte) (ScopeMetrics, error) {
+	var result ScopeMetrics
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case fieldScopeMetricsScope:
+			return protoutil.WalkFields(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				switch {
+				case num == fieldScopeName && typ == protowire.BytesType:
+					result.Scope.Name = string(value)
+				case num == fieldScopeVersion && typ == protowire.BytesType:
+					result.Scope.Version = string(value)
+				}
+				return nil
+			})
+		case fieldScopeMetricsMetrics:
+			metric, err := unmarshalMetric(value)
+			if err != nil {
+				return fmt.Errorf("metric %d: %w", len(result.Metrics), err)
+			}
+			result.Metrics = append(result.Metrics, metric)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// unmarshalMetric разбирает Metric
+func unmarshalMetric(data []byte) (Metric, error) {
+	var metric Metric
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		var err error
+		switch num {
+		case fieldMetricName:
+			metric.Name = string(value)
+		case fieldMetricDescription:
+			metric.Description = string(value)
+		case fieldMetricUnit:
+			metric.Unit = string(value)
+		case fieldMetricGauge:
+			metric.Gauge = &Gauge{}
+			metric.Gauge.DataPoints, err = unmarshalNumberDataPoints(value)
+		case fieldMetricSum:
+			metric.Sum, err = unmarshalSum(value)
+		case fieldMetricHistogram:
+			metric.Histogram, err = unmarshalUnsupported(value)
+		case fieldMetricExponentialHistogram:
+			metric.ExponentialHistogram, err = unmarshalUnsupported(value)
+		case fieldMetricSummary:
+			metric.Summary, err = unmarshalUnsupported(value)
+		}
+		return err
+	})
+	return metric, err
+}
+
+// unmarshalSum разбирает Sum
+func unmarshalSum(data []byte) (*Sum, error) {
+	sum := &Sum{}
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, _ []byte, scalar uint64) error {
+		switch {
+		case num == fieldSumTemporality && typ == protowire.VarintType:
+			sum.AggregationTemporality = AggregationTemporality(scalar)
+		case num == fieldSumIsMonotonic && typ == protowire.VarintType:
+			sum.IsMonotonic = scalar != 0
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sum.DataPoints, err = unmarshalNumberDataPoints(data)
+	return sum, err
+}
+
+// unmarshalUnsupported учитывает точки Histogram, ExponentialHistogram или Summary без разбора
+func unmarshalUnsupported(data []byte) (*UnsupportedData, error) {
+	result := &UnsupportedData{}
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if num == fieldDataPoints && typ == protowire.BytesType {
+			result.DataPoints = append(result.DataPoints, nil)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// unmarshalNumberDataPoints разбирает поле data_points сообщений Gauge и Sum
+func unmarshalNumberDataPoints(data []byte) ([]NumberDataPoint, error) {
+	var points []NumberDataPoint
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if num != fieldDataPoints || typ != protowire.BytesType {
+			return nil
+		}
+		point, err := unmarshalNumberDataPoint(value)
+		if err != nil {
+			return fmt.Errorf("dataPoint %d: %w", len(points), err)
+		}
+		points = append(points, point)
+		return nil
+	})
+	return points, err
+}
+
+// unmarshalNumberDataPoint разбирает NumberDataPoint; экземпляры (exemplars) пропускаются
+func unmarshalNumberDataPoint(data []byte) (NumberDataPoint, error) {
+	var point NumberDataPoint
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, scalar uint64) error {
+		switch {
+		case num == fieldPointStartTimeUnixNano && typ == protowire.Fixed64Type:
+			point.StartTimeUnixNano = scalar
+		case num == fieldPointTimeUnixNano && typ == protowire.Fixed64Type:
+			point.TimeUnixNano = scalar
+		case num == fieldPointAsDouble && typ == protowire.Fixed64Type:
+			asDouble := math.Float64frombits(scalar)
+			point.AsDouble, point.AsInt = &asDouble, nil
+		case num == fieldPointAsInt && typ == protowire.Fixed64Type:
+			asInt := int64(scalar)
+			point.AsInt, point.AsDouble = &asInt, nil
+		case num == fieldPointAttributes && typ == protowire.BytesType:
+			attribute, err := unmarshalKeyValue(value, 0)
+			if err != nil {
+				return fmt.Errorf("attribute: %w", err)
+			}
+			point.Attributes = append(point.Attributes, attribute)
+		case num == fieldPointFlags && typ == protowire.VarintType:
+			point.Flags = uint32(scalar)
+		}
+		return nil
+	})
+	return point, err
+}
+
+// unmarshalKeyValue разбирает KeyValue
+func unmarshalKeyValue(data []byte, depth int) (KeyValue, error) {
+	var kv KeyValue
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case fieldKeyValueKey:
+			kv.Key = string(value)
+		case fieldKeyValueValue:
+			anyValue, err := unmarshalAnyValue(value, depth)
+			if err != nil {
+				return err
+			}
+			kv.Value = anyValue
+		}
+		return nil
+	})
+	return kv, err
+}
+
+// unmarshalAnyValue разбирает AnyValue с ограничением глубины вложенности
+func unmarshalAnyValue(data []byte, depth int) (AnyValue, error) {
+	if depth > maxAttributeDepth {
+		return AnyValue{}, fmt.Errorf("attribute value nesting exceeds %d levels", maxAttributeDepth)
+	}
+
+	var result AnyValue
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, scalar uint64) error {
+		switch {
+		case num == fieldAnyString && typ == protowire.BytesType:
+			s := string(value)
+			result = AnyValue{StringValue: &s}
+		case num == fieldAnyBool && typ == protowire.VarintType:
+			b := scalar != 0
+			result = AnyValue{BoolValue: &b}
+		case num == fieldAnyInt && typ == protowire.VarintType:
+			i := int64(scalar)
+			result = AnyValue{IntValue: &i}
+		case num == fieldAnyDouble && typ == protowire.Fixed64Type:
+			d := math.Float64frombits(scalar)
+			result = AnyValue{DoubleValue: &d}
+		case num == fieldAnyBytes && typ == protowire.BytesType:
+			result = AnyValue{BytesValue: append([]byte{}, value...)}
+		case num == fieldAnyArray && typ == protowire.BytesType:
+			array := &ArrayValue{}
+			err := protoutil.WalkFields(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if num != fieldListValues || typ != protowire.BytesType {
+					return nil
+				}
+				item, err := unmarshalAnyValue(value, depth+1)
+				array.Values = append(array.Values, item)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			result = AnyValue{ArrayValue: array}
+		case num == fieldAnyKvlist && typ == protowire.BytesType:
+			list := &KeyValueList{}
+			err := protoutil.WalkFields(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if num != fieldListValues || typ != protowire.BytesType {
+					return nil
+				}
+				item, err := unmarshalKeyValue(value, depth+1)
+				list.Values = append(list.Values, item)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			result = AnyValue{KvlistValue: list}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// MarshalProto сериализует запрос в OTLP/protobuf (для клиентов и тестов)
+func (r *ExportMetricsServiceRequest) MarshalProto() []byte {
+	var b []byte
+	for _, resourceMetrics := range r.ResourceMetrics {
+		var rb []byte
+
+		var resource []byte
+		for _, attribute := range resourceMetrics.Resource.Attributes {
+			resource = protoutil.AppendMessage(resource, fieldResourceAttributes, marshalKeyValue(attribute))
+		}
+		rb = protoutil.AppendMessage(rb, fieldResourceMetricsResource, resource)
+
+		for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+			var sb []byte
+			var scope []byte
+			scope = protoutil.AppendString(scope, fieldScopeName, scopeMetrics.Scope.Name)
+			scope = protoutil.AppendString(scope, fieldScopeVersion, scopeMetrics.Scope.Version)
+			sb = protoutil.AppendMessage(sb, fieldScopeMetricsScope, scope)
+
+			for i := range scopeMetrics.Metrics {
+				sb = protoutil.AppendMessage(sb, fieldScopeMetricsMetrics, marshalMetric(&scopeMetrics.Metrics[i]))
+			}
+			rb = protoutil.AppendMessage(rb, fieldResourceMetricsScope, sb)
+		}
+		b = protoutil.AppendMessage(b, fieldRequestResourceMetrics, rb)
+	}
+	return b
+}
+
+// marshalMetric сериализует Metric
+func marshalMetric(metric *Metric) []byte {
+	var b []byte
+	b = protoutil.AppendString(b, fieldMetricName, metric.Name)
+	b = protoutil.AppendString(b, fieldMetricDescription, metric.Description)
+	b = protoutil.AppendString(b, fieldMetricUnit, metric.Unit)
+
+	if metric.Gauge != nil {
+		b = protoutil.AppendMessage(b, fieldMetricGauge, marshalNumberDataPoints(metric.Gauge.DataPoints))
+	}
+	if metric.Sum != nil {
+		sum := marshalNumberDataPoints(metric.Sum.DataPoints)
+		sum = protoutil.AppendVarint(sum, fieldSumTemporality, uint64(metric.Sum.AggregationTemporality))
+		if metric.Sum.IsMonotonic {
+			sum = protoutil.AppendVarint(sum, fieldSumIsMonotonic, 1)
+		}
+		b = protoutil.AppendMessage(b, fieldMetricSum, sum)
+	}
+	unsupported := []struct {
+		num  protowire.Number
+		data *UnsupportedData
+	}{
+		{fieldMetricHistogram, metric.Histogram},
+		{fieldMetricExponentialHistogram, metric.ExponentialHistogram},
+		{fieldMetricSummary, metric.Summary},
+	}
+	for _, item := range unsupported {
+		if item.data == nil {
+			continue
+		}
+		var ub []byte
+		for range item.data.DataPoints {
+			ub = protoutil.AppendMessage(ub, fieldDataPoints, nil)
+		}
+		b = protoutil.AppendMessage(b, item.num, ub)
+	}
+	return b
+}
+
+// marshalNumberDataPoints сериализует поле data_points
+func marshalNumberDataPoints(points []NumberDataPoint) []byte {
+	var b []byte
+	for _, point := range points {
+		var pb []byte
+		for _, attribute := range point.Attributes {
+			pb = protoutil.AppendMessage(pb, fieldPointAttributes, marshalKeyValue(attribute))
+		}
+		pb = protoutil.AppendFixed64(pb, fieldPointStartTimeUnixNano, point.StartTimeUnixNano)
+		pb = protoutil.AppendFixed64(pb, fieldPointTimeUnixNano, point.TimeUnixNano)
+		switch {
+		case point.AsDouble != nil:
+			pb = protoutil.AppendFixed64(pb, fieldPointAsDouble, math.Float64bits(*point.AsDouble))
+		case point.AsInt != nil:
+			pb = protoutil.AppendFixed64(pb, fieldPointAsInt, uint64(*point.AsInt))
+		}
+		pb = protoutil.AppendVarint(pb, fieldPointFlags, uint64(point.Flags))
+		b = protoutil.AppendMessage(b, fieldDataPoints, pb)
+	}
+	return b
+}
+
+// marshalKeyValue сериализует KeyValue
+func marshalKeyValue(kv KeyValue) []byte {
+	var b []byte
+	b = protoutil.AppendString(b, fieldKeyValueKey, kv.Key)
+	return protoutil.AppendMessage(b, fieldKeyValueValue, marshalAnyValue(kv.Value))
+}
+
+// marshalAnyValue сериализует AnyValue
+func marshalAnyValue(v AnyValue) []byte {
+	var b []byte
+	switch {
+	case v.StringValue != nil:
+		b = protowire.AppendTag(b, fieldAnyString, protowire.BytesType)
+		b = protowire.AppendString(b, *v.StringValue)
+	case v.BoolValue != nil:
+		b = protowire.AppendTag(b, fieldAnyBool, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(*v.BoolValue))
+	case v.IntValue != nil:
+		b = protowire.AppendTag(b, fieldAnyInt, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*v.IntValue))
+	case v.DoubleValue != nil:
+		b = protoutil.AppendFixed64(b, fieldAnyDouble, math.Float64bits(*v.DoubleValue))
+	case v.BytesValue != nil:
+		b = protowire.AppendTag(b, fieldAnyBytes, protowire.BytesType)
+		b = protowire.AppendBytes(b, v.BytesValue)
+	case v.ArrayValue != nil:
+		var ab []byte
+		for _, item := range v.ArrayValue.Values {
+			ab = protoutil.AppendMessage(ab, fieldListValues, marshalAnyValue(item))
+		}
+		b = protoutil.AppendMessage(b, fieldAnyArray, ab)
+	case v.KvlistValue != nil:
+		var lb []byte
+		for _, item := range v.KvlistValue.Values {
+			lb = protoutil.AppendMessage(lb, fieldListValues, marshalKeyValue(item))
+		}
+		b = protoutil.AppendMessage(b, fieldAnyKvlist, lb)
+	}
+	return b
+}
+
+// MarshalProto сериализует ответ в OTLP/protobuf
+func (r *ExportMetricsServiceResponse) MarshalProto() []byte {
+	if r.PartialSuccess == nil {
+		return []byte{}
+	}
+	var partial []byte
+	partial = protoutil.AppendVarint(partial, fieldPartialRejected, uint64(r.PartialSuccess.RejectedDataPoints))
+	partial = protoutil.AppendString(partial, fieldPartialErrorMessage, r.PartialSuccess.ErrorMessage)
+	return protoutil.AppendMessage(nil, fieldResponsePartialSuccess, partial)
+}
+
+// UnmarshalResponseProto разбирает ответ в формате OTLP/protobuf
+func UnmarshalResponseProto(data []byte) (*ExportMetricsServiceResponse, error) {
+	response := &ExportMetricsServiceResponse{}
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if num != fieldResponsePartialSuccess || typ != protowire.BytesType {
+			return nil
+		}
+		response.PartialSuccess = &ExportMetricsPartialSuccess{}
+		return protoutil.WalkFields(value, func(num protowire.Number, typ protowire.Type, value []byte, scalar uint64) error {
+			switch {
+			case num == fieldPartialRejected && typ == protowire.VarintType:
+				response.PartialSuccess.RejectedDataPoints = int64(scalar)
+			case num == fieldPartialErrorMessage && typ == protowire.BytesType:
+				response.PartialSuccess.ErrorMessage = string(value)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal OTLP protobuf response: %w", err)
+	}
+	return response, nil
+}
@@ -0,0 +1,80 @@
+package otlp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProto_RoundTrip(t *testing.T) {
+	req, err := UnmarshalJSON([]byte(otelJSON))
+	require.NoError(t, err)
+
+	// Вложенные значения атрибутов и все виды данных
+	s, d, b := "nested", 0.5, true
+	metrics := &req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	*metrics = append(*metrics,
+		Metric{Name: "attrs", Gauge: &Gauge{DataPoints: []NumberDataPoint{{
+			AsDouble: &d,
+			Flags:    FlagNoRecordedValue,
+			Attributes: []KeyValue{
+				{Key: "list", Value: AnyValue{ArrayValue: &ArrayValue{Values: []AnyValue{{StringValue: &s}, {DoubleValue: &d}}}}},
+				{Key: "map", Value: AnyValue{KvlistValue: &KeyValueList{Values: []KeyValue{{Key: "b", Value: AnyValue{BoolValue: &b}}}}}},
+				{Key: "raw", Value: AnyValue{BytesValue: []byte{1, 2}}},
+			},
+		}}}},
+		Metric{Name: "delta", Unit: "1", Description: "d", Sum: &Sum{AggregationTemporality: TemporalityDelta}},
+		Metric{Name: "exp", ExponentialHistogram: &UnsupportedData{DataPoints: []json.RawMessage{nil}}},
+		Metric{Name: "summary", Summary: &UnsupportedData{}},
+	)
+
+	decoded, err := UnmarshalProto(req.MarshalProto())
+	require.NoError(t, err)
+
+	// Точки неподдерживаемых видов учитываются без содержимого
+	for i := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		metric := &req.ResourceMetrics[0].ScopeMetrics[0].Metrics[i]
+		for _, data := range []*UnsupportedData{metric.Histogram, metric.ExponentialHistogram, metric.Summary} {
+			if data != nil {
+				for j := range data.DataPoints {
+					data.DataPoints[j] = nil
+				}
+			}
+		}
+	}
+	assert.Equal(t, req, decoded)
+}
+
+func TestUnmarshalProto_Errors(t *testing.T) {
+	data := (&ExportMetricsServiceRequest{ResourceMetrics: []ResourceMetrics{{
+		Resource: Resource{Attributes: []KeyValue{StringAttribute("service.name", "checkout")}},
+	}}}).MarshalProto()
+
+	_, err := UnmarshalProto(data[:len(data)-2])
+	assert.Error(t, err)
+
+	// Слишком глубокая вложенность значений атрибутов
+	value := AnyValue{}
+	for range maxAttributeDepth + 2 {
+		inner := value
+		value = AnyValue{ArrayValue: &ArrayValue{Values: []AnyValue{inner}}}
+	}
+	data = (&ExportMetricsServiceRequest{ResourceMetrics: []ResourceMetrics{{
+		Resource: Resource{Attributes: []KeyValue{{Key: "deep", Value: value}}},
+	}}}).MarshalProto()
+	_, err = UnmarshalProto(data)
+	assert.Error(t, err)
+}
+
+func TestResponse_Proto(t *testing.T) {
+	empty, err := UnmarshalResponseProto((&ExportMetricsServiceResponse{}).MarshalProto())
+	require.NoError(t, err)
+	assert.Nil(t, empty.PartialSuccess)
+
+	response := &ExportMetricsServiceResponse{PartialSuccess: &ExportMetricsPartialSuccess{RejectedDataPoints: 3, ErrorMessage: "latency: histogram metrics are not supported"}}
+	decoded, err := UnmarshalResponseProto(response.MarshalProto())
+	require.NoError(t, err)
+	assert.Equal(t, response, decoded)
+}
@@ -0,0 +1,585 @@
+package otlp
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/logger"
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/validation"
+)
+
+// maxReportedErrors сколько ошибок возвращается клиенту в partialSuccess.errorMessage
+const maxReportedErrors = 10
+
+// prefixAttributes атрибуты ресурса, значения которых образуют префикс имени метрики
+var prefixAttributes = []string{"service.namespace", "service.name"}
+
+// MetricsWriter получатель метрик (реализуется service.MetricsService)
+type MetricsWriter interface {
+	UpdateMetricsBatch(ctx context.Context, metrics []models.Metrics) error
+}
+
+// Result результат обработки запроса
+type Result struct {
+	DataPoints int      // Получено точек
+	Gauges     int      // Записано метрик gauge
+	Counters   int      // Записано метрик counter
+	Rejected   int      // Отклонено точек
+	Errors     []string // Первые ошибки отклоненных точек
+}
+
+// reject учитывает отклоненные точки
+func (r *Result) reject(points int, name string, err error) {
+	r.Rejected += points
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf("%s: %s", name, err))
+	}
+}
+
+// Response возвращает ответ OTLP; partialSuccess заполняется, если часть точек отклонена
+func (r *Result) Response() *ExportMetricsServiceResponse {
+	if r.Rejected == 0 {
+		return &ExportMetricsServiceResponse{}
+	}
+	return &ExportMetricsServiceResponse{PartialSuccess: &ExportMetricsPartialSuccess{
+		RejectedDataPoints: int64(r.Rejected),
+		ErrorMessage:       strings.Join(r.Errors, "; "),
+	}}
+}
+
+// Ограничения состояния потоков по умолчанию
+const (
+	// DefaultMaxStreams максимальное количество отслеживаемых потоков монотонных сумм по умолчанию
+	DefaultMaxStreams = 100000
+	// DefaultStreamTTL время хранения состояния потока без новых точек по умолчанию
+	DefaultStreamTTL = time.Hour
+)
+
+// Config ограничения состояния потоков монотонных сумм
+type Config struct {
+	MaxStreams int           // Максимальное количество отслеживаемых потоков (0 - без ограничений)
+	StreamTTL  time.Duration // Время хранения состояния потока без новых точек (0 - без ограничения)
+}
+
+// DefaultConfig возвращает ограничения по умолчанию
+func DefaultConfig() *Config {
+	return &Config{
+		MaxStreams: DefaultMaxStreams,
+		StreamTTL:  DefaultStreamTTL,
+	}
+}
+
+// Validate проверяет ограничения
+func (c *Config) Validate() error {
+	if c.MaxStreams < 0 {
+		return fmt.Errorf("OTLP max streams cannot be negative")
+	}
+	if c.StreamTTL < 0 {
+		return fmt.Errorf("OTLP stream TTL cannot be negative")
+	}
+	return nil
+}
+
+// streamValue значение монотонной суммы одного потока
+type streamValue struct {
+	start      uint64  // StartTimeUnixNano последней точки
+	value      float64 // Накопленное значение (для delta - сумма полученных приращений)
+	cumulative bool    // Поток передает накопленные значения
+}
+
+// streamState состояние потока монотонной суммы
+type streamState struct {
+	key         string
+	streamValue           // Последнее зафиксированное значение
+	known       bool      // Значение получено и записано хотя бы раз
+	seen        time.Time // Время последней записи
+	busy        bool      // Поток зарезервирован выполняемым запросом
+	element     *list.Element
+}
+
+// Receiver преобразует метрики OTLP в метрики metrical.
+//
+// Gauge и немонотонные cumulative Sum (UpDownCounter) становятся gauge. Монотонные Sum становятся
+// counter: для cumulative потоков Receiver хранит последнее значение и записывает прирост,
+// delta потоки записываются как есть. Поток определяется атрибутами ресурса, библиотекой
+// инструментирования, именем и атрибутами точки, поэтому несколько экземпляров сервиса
+// с одинаковым именем метрики суммируются в один counter. Потоки без новых точек дольше StreamTTL
+// и самые давние потоки сверх MaxStreams вытесняются.
+type Receiver struct {
+	writer    MetricsWriter
+	logger    logger.Logger
+	startedAt uint64 // Время создания в наносекундах Unix
+
+	mu           sync.Mutex
+	released     *sync.Cond              // Сигнал об освобождении зарезервированных потоков
+	streams      map[string]*streamState // Состояние потоков по ключу
+	order        *list.List              // Состояния в порядке последнего обращения (давние в начале)
+	evictedStart uint64                  // Наибольшее время начала вытесненного cumulative потока
+	maxStreams   int
+	streamTTL    time.Duration
+	nowFunc      func() time.Time // Источник времени (подменяется в тестах)
+}
+
+// NewReceiver создает приемник OTLP
+func NewReceiver(writer MetricsWriter, logger logger.Logger) (*Receiver, error) {
+	if writer == nil {
+		return nil, fmt.Errorf("metrics writer cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+
+	r := &Receiver{
+		writer:     writer,
+		logger:     logger,
+		startedAt:  uint64(time.Now().UnixNano()),
+		streams:    make(map[string]*streamState),
+		order:      list.New(),
+		maxStreams: DefaultMaxStreams,
+		streamTTL:  DefaultStreamTTL,
+		nowFunc:    time.Now,
+	}
+	r.released = sync.NewCond(&r.mu)
+	return r, nil
+}
+
+// SetLimits задает ограничения состояния потоков (0 - без ограничений)
+func (r *Receiver) SetLimits(maxStreams int, streamTTL time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.maxStreams = maxStreams
+	r.streamTTL = streamTTL
+	r.evictUnsafe(r.nowFunc())
+}
+
+// Len возвращает количество отслеживаемых потоков
+func (r *Receiver) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.streams)
+}
+
+// gaugePoint последнее значение gauge в запросе
+type gaugePoint struct {
+	value float64
+	time  uint64
+}
+
+// sumPoint точка монотонной суммы, прирост которой вычисляется после резервирования потока
+type sumPoint struct {
+	key        string
+	name       string
+	cumulative bool
+	start      uint64
+	value      float64
+}
+
+// batch метрики запроса, накапливаемые до записи
+type batch struct {
+	gauges     map[string]gaugePoint
+	gaugeNames []string
+	sums       []sumPoint
+	streamKeys []string // Ключи потоков в порядке появления
+	seen       map[string]bool
+}
+
+// setGauge запоминает значение gauge; более старая точка не заменяет новую
+func (b *batch) setGauge(name string, value float64, time uint64) {
+	current, ok := b.gauges[name]
+	if !ok {
+		b.gaugeNames = append(b.gaugeNames, name)
+	} else if time < current.time {
+		return
+	}
+	b.gauges[name] = gaugePoint{value: value, time: time}
+}
+
+// addSum запоминает точку монотонной суммы
+func (b *batch) addSum(point sumPoint) {
+	if !b.seen[point.key] {
+		b.seen[point.key] = true
+		b.streamKeys = append(b.streamKeys, point.key)
+	}
+	b.sums = append(b.sums, point)
+}
+
+// Write записывает метрики запроса одним пакетом через writer.
+// Неподдерживаемые и некорректные точки, а также отклоненные authorize, учитываются в Result;
+// ошибка возвращается, только если не удалось записать пакет.
+//
+// Блокировка удерживается только для резервирования потоков и фиксации их состояния: запись
+// в хранилище выполняется без нее. Запрос с уже зарезервированным потоком ждет завершения
+// другого запроса, поэтому приросты одного потока вычисляются от зафиксированного значения.
+func (r *Receiver) Write(ctx context.Context, req *ExportMetricsServiceRequest, authorize func(name string) error) (*Result, error) {
+	result := &Result{}
+	b := &batch{
+		gauges: make(map[string]gaugePoint),
+		seen:   make(map[string]bool),
+	}
+
+	for _, resourceMetrics := range req.ResourceMetrics {
+		prefix := resourcePrefix(resourceMetrics.Resource.Attributes)
+		resourceKey := attributesKey(resourceMetrics.Resource.Attributes)
+
+		for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+			for i := range scopeMetrics.Metrics {
+				metric := &scopeMetrics.Metrics[i]
+				streamPrefix := resourceKey + "\x00" + scopeMetrics.Scope.Name + "\x00"
+
+				switch {
+				case metric.Gauge != nil:
+					for _, point := range metric.Gauge.DataPoints {
+						addPoint(result, prefix, metric, point, authorize, func(name string, value float64) error {
+							b.setGauge(name, value, point.TimeUnixNano)
+							return nil
+						})
+					}
+				case metric.Sum != nil:
+					for _, point := range metric.Sum.DataPoints {
+						addPoint(result, prefix, metric, point, authorize, func(name string, value float64) error {
+							return addSum(b, metric.Sum, streamPrefix+name, name, value, point)
+						})
+					}
+				default:
+					points, kind := unsupportedPoints(metric)
+					if points > 0 {
+						result.DataPoints += points
+						result.reject(points, metric.Name, fmt.Errorf("%s metrics are not supported", kind))
+					}
+				}
+			}
+		}
+	}
+
+	states := r.reserve(b.streamKeys)
+	pending := make(map[string]streamValue, len(states))
+	written := false
+	defer func() { r.release(states, pending, written) }()
+
+	counters, counterNames := r.counterDeltas(b.sums, states, pending)
+
+	metrics := make([]models.Metrics, 0, len(b.gaugeNames)+len(counterNames))
+	for _, name := range b.gaugeNames {
+		value := b.gauges[name].value
+		metrics = append(metrics, models.Metrics{ID: name, MType: models.Gauge, Value: &value})
+	}
+	for _, name := range counterNames {
+		delta := counters[name]
+		metrics = append(metrics, models.Metrics{ID: name, MType: models.Counter, Delta: &delta})
+	}
+
+	// Отдельные метрики проверяются заранее, чтобы одна ошибка не отклоняла весь пакет
+	valid := metrics[:0]
+	for _, metric := range metrics {
+		if err := validation.ValidateMetric(&metric); err != nil {
+			result.reject(1, metric.ID, err)
+			continue
+		}
+		valid = append(valid, metric)
+	}
+
+	if len(valid) == 0 {
+		return result, nil
+	}
+	if err := r.writer.UpdateMetricsBatch(ctx, valid); err != nil {
+		return result, fmt.Errorf("failed to write OTLP batch: %w", err)
+	}
+	written = true
+
+	for _, metric := range valid {
+		if metric.MType == models.Gauge {
+			result.Gauges++
+		} else {
+			result.Counters++
+		}
+	}
+	return result, nil
+}
+
+// addPoint проверяет точку и передает ее значение в add
+func addPoint(result *Result, prefix string, metric *Metric, point NumberDataPoint,
+	authorize func(name string) error, add func(name string, value float64) error) {
+	result.DataPoints++
+
+	value, ok := point.Value()
+	if !ok {
+		return
+	}
+
+	name, err := MetricName(prefix, metric.Name, point.Attributes)
+	if err != nil {
+		result.reject(1, metric.Name, err)
+		return
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		result.reject(1, name, fmt.Errorf("non-finite value %v", value))
+		return
+	}
+	if authorize != nil {
+		if err := authorize(name); err != nil {
+			result.reject(1, name, err)
+			return
+		}
+	}
+
+	if err := add(name, value); err != nil {
+		result.reject(1, name, err)
+	}
+}
+
+// addSum добавляет точку Sum: монотонная сумма становится counter, немонотонная cumulative - gauge
+func addSum(b *batch, sum *Sum, key, name string, value float64, point NumberDataPoint) error {
+	temporality := sum.AggregationTemporality
+	if temporality != TemporalityDelta && temporality != TemporalityCumulative {
+		return fmt.Errorf("unspecified aggregation temporality")
+	}
+
+	if !sum.IsMonotonic {
+		if temporality == TemporalityDelta {
+			return fmt.Errorf("non-monotonic delta sums are not supported")
+		}
+		b.setGauge(name, value, point.TimeUnixNano)
+		return nil
+	}
+
+	if value < 0 || value >= math.MaxInt64 {
+		return fmt.Errorf("counter value %v is out of range", value)
+	}
+
+	b.addSum(sumPoint{
+		key:        key,
+		name:       name,
+		cumulative: temporality == TemporalityCumulative,
+		start:      point.StartTimeUnixNano,
+		value:      value,
+	})
+	return nil
+}
+
+// counterDeltas вычисляет приросты counter по точкам монотонных сумм и записывает новые значения
+// потоков в pending. Состояния зарезервированы запросом, поэтому читаются без блокировки;
+// evictedStart читается под блокировкой.
+func (r *Receiver) counterDeltas(points []sumPoint, states []*streamState, pending map[string]streamValue) (map[string]int64, []string) {
+	if len(points) == 0 {
+		return nil, nil
+	}
+
+	r.mu.Lock()
+	evictedStart := r.evictedStart
+	r.mu.Unlock()
+
+	reserved := make(map[string]*streamState, len(states))
+	for _, state := range states {
+		reserved[state.key] = state
+	}
+
+	counters := make(map[string]int64)
+	var names []string
+	for _, point := range points {
+		current, known := pending[point.key]
+		if !known {
+			state := reserved[point.key]
+			current, known = state.streamValue, state.known
+		}
+
+		var delta int64
+		if !point.cumulative {
+			// Сумма приращений хранится целиком, чтобы дробные приращения не терялись при округлении
+			total := current.value + point.value
+			delta = int64(math.Round(total) - math.Round(current.value))
+			current = streamValue{start: point.start, value: total}
+		} else {
+			switch {
+			case !known && point.start != 0 && (point.start < r.startedAt || point.start <= evictedStart):
+				// Поток начался до запуска сервера или до вытеснения другого потока: его значение
+				// могло быть уже учтено, поэтому первая точка только задает базу
+			case !known, point.start != current.start, point.value < current.value:
+				// Новый поток или сброс счетчика (изменилось время начала или значение уменьшилось)
+				delta = int64(math.Round(point.value))
+			default:
+				delta = int64(math.Round(point.value) - math.Round(current.value))
+			}
+			current = streamValue{start: point.start, value: point.value, cumulative: true}
+		}
+
+		pending[point.key] = current
+		if _, ok := counters[point.name]; !ok {
+			names = append(names, point.name)
+		}
+		counters[point.name] += delta
+	}
+	return counters, names
+}
+
+// reserve резервирует состояния потоков за запросом, ожидая завершения других запросов
+// с теми же потоками. Все потоки резервируются одновременно, поэтому запросы не блокируют друг друга по кругу.
+func (r *Receiver) reserve(keys []string) []*streamState {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for r.anyBusyUnsafe(keys) {
+		r.released.Wait()
+	}
+
+	states := make([]*streamState, len(keys))
+	for i, key := range keys {
+		state, ok := r.streams[key]
+		if !ok {
+			state = &streamState{key: key}
+			state.element = r.order.PushBack(state)
+			r.streams[key] = state
+		} else {
+			r.order.MoveToBack(state.element)
+		}
+		state.busy = true
+		states[i] = state
+	}
+	return states
+}
+
+// anyBusyUnsafe проверяет, зарезервирован ли один из потоков другим запросом (вызывается под блокировкой)
+func (r *Receiver) anyBusyUnsafe(keys []string) bool {
+	for _, key := range keys {
+		if state, ok := r.streams[key]; ok && state.busy {
+			return true
+		}
+	}
+	return false
+}
+
+// release снимает резервирование. Состояние потоков фиксируется только после успешной записи,
+// чтобы повтор запроса не терял прирост; потоки, так и не получившие значения, удаляются.
+func (r *Receiver) release(states []*streamState, pending map[string]streamValue, written bool) {
+	if len(states) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.nowFunc()
+	for _, state := range states {
+		state.busy = false
+		if value, ok := pending[state.key]; ok && written {
+			state.streamValue, state.known, state.seen = value, true, now
+		}
+		if !state.known {
+			r.removeUnsafe(state)
+		}
+	}
+	r.evictUnsafe(now)
+	r.released.Broadcast()
+}
+
+// removeUnsafe удаляет состояние потока (вызывается под блокировкой)
+func (r *Receiver) removeUnsafe(state *streamState) {
+	if r.streams[state.key] == state {
+		delete(r.streams, state.key)
+	}
+	r.order.Remove(state.element)
+}
+
+// evictUnsafe удаляет состояния потоков без точек дольше streamTTL и самые давние сверх maxStreams.
+// Время начала вытесненного cumulative потока запоминается, чтобы его следующая точка не была учтена
+// повторно. Зарезервированные потоки не вытесняются (вызывается под блокировкой).
+func (r *Receiver) evictUnsafe(now time.Time) {
+	for element := r.order.Front(); element != nil; {
+		next := element.Next()
+		state := element.Value.(*streamState)
+
+		expired := r.streamTTL > 0 && now.Sub(state.seen) >= r.streamTTL
+		overflow := r.maxStreams > 0 && len(r.streams) > r.maxStreams
+		if !expired && !overflow {
+			break
+		}
+		if !state.busy {
+			if state.cumulative && state.start > r.evictedStart {
+				r.evictedStart = state.start
+			}
+			r.removeUnsafe(state)
+		}
+		element = next
+	}
+}
+
+// MetricName строит имя метрики metrical: префикс ресурса, имя метрики OTLP и атрибуты точки
+// в порядке ключей: checkout + http.server.requests{method="GET"} -> checkout.http.server.requests.method=GET.
+// Атрибуты с пустым значением пропускаются.
+func MetricName(prefix, name string, attributes []KeyValue) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("metric name cannot be empty")
+	}
+
+	sorted := slices.Clone(attributes)
+	slices.SortStableFunc(sorted, func(a, b KeyValue) int { return strings.Compare(a.Key, b.Key) })
+
+	var b strings.Builder
+	if prefix != "" {
+		b.WriteString(prefix)
+		b.WriteByte('.')
+	}
+	b.WriteString(name)
+	for _, attribute := range sorted {
+		value := attribute.Value.String()
+		if value == "" {
+			continue
+		}
+		b.WriteByte('.')
+		b.WriteString(attribute.Key)
+		b.WriteByte('=')
+		b.WriteString(value)
+	}
+	return b.String(), nil
+}
+
+// resourcePrefix возвращает префикс имени из атрибутов service.namespace и service.name
+func resourcePrefix(attributes []KeyValue) string {
+	parts := make([]string, 0, len(prefixAttributes))
+	for _, key := range prefixAttributes {
+		for _, attribute := range attributes {
+			if attribute.Key == key {
+				if value := attribute.Value.String(); value != "" {
+					parts = append(parts, value)
+				}
+				break
+			}
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// attributesKey возвращает ключ набора атрибутов, не зависящий от их порядка
+func attributesKey(attributes []KeyValue) string {
+	pairs := make([]string, 0, len(attributes))
+	for _, attribute := range attributes {
+		pairs = append(pairs, attribute.Key+"="+attribute.Value.String())
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "\x00")
+}
+
+// unsupportedPoints возвращает число точек и вид неподдерживаемой метрики
+func unsupportedPoints(metric *Metric) (int, string) {
+	switch {
+	case metric.Histogram != nil:
+		return len(metric.Histogram.DataPoints), "histogram"
+	case metric.ExponentialHistogram != nil:
+		return len(metric.ExponentialHistogram.DataPoints), "exponential histogram"
+	case metric.Summary != nil:
+		return len(metric.Summary.DataPoints), "summary"
+	default:
+		return 0, "empty"
+	}
+}
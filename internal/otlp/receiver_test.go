@@ -0,0 +1,306 @@
+package otlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/repository"
+	"github.com/IgorKilipenko/metrical/internal/service"
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestReceiver создает приемник с хранилищем в памяти
+func newTestReceiver(t *testing.T) (*Receiver, *service.MetricsService) {
+	t.Helper()
+
+	mockLogger := testutils.NewMockLogger()
+	repo := repository.NewInMemoryMetricsRepository(mockLogger, "", false)
+	metricsService := service.NewMetricsService(repo, mockLogger)
+
+	receiver, err := NewReceiver(metricsService, mockLogger)
+	require.NoError(t, err)
+	return receiver, metricsService
+}
+
+// sumRequest создает запрос с одной монотонной суммой сервиса checkout
+func sumRequest(temporality AggregationTemporality, instance string, points ...NumberDataPoint) *ExportMetricsServiceRequest {
+	return &ExportMetricsServiceRequest{ResourceMetrics: []ResourceMetrics{{
+		Resource: Resource{Attributes: []KeyValue{
+			StringAttribute("service.name", "checkout"),
+			StringAttribute("service.instance.id", instance),
+		}},
+		ScopeMetrics: []ScopeMetrics{{Metrics: []Metric{{
+			Name: "orders",
+			Sum:  &Sum{AggregationTemporality: temporality, IsMonotonic: true, DataPoints: points},
+		}}}},
+	}}}
+}
+
+// point создает точку с дробным значением
+func point(start uint64, value float64) NumberDataPoint {
+	return NumberDataPoint{StartTimeUnixNano: start, TimeUnixNano: start + 1, AsDouble: &value}
+}
+
+// failingWriter хранилище, отклоняющее запись
+type failingWriter struct{}
+
+func (failingWriter) UpdateMetricsBatch(context.Context, []models.Metrics) error {
+	return models.ErrStorageUnavailable
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, DefaultConfig().Validate())
+	assert.NoError(t, (&Config{}).Validate(), "Zero disables the limits")
+	assert.Error(t, (&Config{MaxStreams: -1}).Validate())
+	assert.Error(t, (&Config{StreamTTL: -time.Second}).Validate())
+}
+
+func TestMetricName(t *testing.T) {
+	name, err := MetricName("checkout", "http.server.requests", []KeyValue{
+		StringAttribute("status", "200"),
+		StringAttribute("method", "GET"),
+		StringAttribute("empty", ""),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "checkout.http.server.requests.method=GET.status=200", name)
+
+	name, err = MetricName("", "up", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "up", name)
+
+	_, err = MetricName("checkout", "", nil)
+	assert.Error(t, err)
+}
+
+func TestResourcePrefix(t *testing.T) {
+	assert.Equal(t, "shop.checkout", resourcePrefix([]KeyValue{
+		StringAttribute("service.name", "checkout"),
+		StringAttribute("service.namespace", "shop"),
+		StringAttribute("host.name", "node-1"),
+	}))
+	assert.Empty(t, resourcePrefix([]KeyValue{StringAttribute("host.name", "node-1")}))
+}
+
+func TestReceiver_Write_JSON(t *testing.T) {
+	receiver, metricsService := newTestReceiver(t)
+	ctx := context.Background()
+
+	req, err := UnmarshalJSON([]byte(otelJSON))
+	require.NoError(t, err)
+
+	result, err := receiver.Write(ctx, req, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.DataPoints)
+	assert.Equal(t, 1, result.Gauges)
+	assert.Equal(t, 1, result.Counters)
+	assert.Equal(t, 2, result.Rejected, "Histogram points are rejected")
+
+	response := result.Response()
+	require.NotNil(t, response.PartialSuccess)
+	assert.Equal(t, int64(2), response.PartialSuccess.RejectedDataPoints)
+	assert.Contains(t, response.PartialSuccess.ErrorMessage, "latency: histogram metrics are not supported")
+
+	queue, _, err := metricsService.GetGauge(ctx, "checkout.queue.size")
+	require.NoError(t, err)
+	assert.Equal(t, 3.5, queue)
+
+	// Поток начался до запуска приемника: первая точка cumulative суммы только задает базу
+	requests, exists, err := metricsService.GetCounter(ctx, "checkout.http.requests.method=GET.ok=true")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Zero(t, requests)
+}
+
+func TestReceiver_Write_CumulativeSum(t *testing.T) {
+	receiver, metricsService := newTestReceiver(t)
+	ctx := context.Background()
+	start := receiver.startedAt + 1
+
+	write := func(req *ExportMetricsServiceRequest) int64 {
+		t.Helper()
+		_, err := receiver.Write(ctx, req, nil)
+		require.NoError(t, err)
+		orders, _, err := metricsService.GetCounter(ctx, "checkout.orders")
+		require.NoError(t, err)
+		return orders
+	}
+
+	assert.Equal(t, int64(10), write(sumRequest(TemporalityCumulative, "a", point(start, 10))), "New stream counts from zero")
+	assert.Equal(t, int64(15), write(sumRequest(TemporalityCumulative, "a", point(start, 15))))
+
+	// Второй экземпляр сервиса - отдельный поток с тем же именем метрики
+	assert.Equal(t, int64(19), write(sumRequest(TemporalityCumulative, "b", point(start, 4))))
+
+	// Сброс: изменилось время начала или значение уменьшилось
+	assert.Equal(t, int64(21), write(sumRequest(TemporalityCumulative, "a", point(start+100, 2))))
+	assert.Equal(t, int64(22), write(sumRequest(TemporalityCumulative, "a", point(start+100, 1))))
+}
+
+func TestReceiver_Write_DeltaSum(t *testing.T) {
+	receiver, metricsService := newTestReceiver(t)
+	ctx := context.Background()
+
+	// Дробные приращения не теряются при округлении
+	for range 4 {
+		_, err := receiver.Write(ctx, sumRequest(TemporalityDelta, "a", point(1, 0.5)), nil)
+		require.NoError(t, err)
+	}
+
+	orders, _, err := metricsService.GetCounter(ctx, "checkout.orders")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), orders)
+}
+
+func TestReceiver_Write_UpDownCounter(t *testing.T) {
+	receiver, metricsService := newTestReceiver(t)
+	ctx := context.Background()
+
+	req := sumRequest(TemporalityCumulative, "a", point(1, -3))
+	req.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Sum.IsMonotonic = false
+
+	result, err := receiver.Write(ctx, req, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Gauges)
+
+	orders, _, err := metricsService.GetGauge(ctx, "checkout.orders")
+	require.NoError(t, err)
+	assert.Equal(t, -3.0, orders)
+
+	req.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Sum.AggregationTemporality = TemporalityDelta
+	result, err = receiver.Write(ctx, req, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Rejected)
+}
+
+func TestReceiver_Write_Rejections(t *testing.T) {
+	receiver, _ := newTestReceiver(t)
+
+	asInt := int64(1)
+	req := sumRequest(TemporalityUnspecified, "a", point(1, 1))
+	metrics := &req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	*metrics = append(*metrics,
+		Metric{Name: "negative", Sum: &Sum{AggregationTemporality: TemporalityDelta, IsMonotonic: true, DataPoints: []NumberDataPoint{point(1, -1)}}},
+		Metric{Name: "", Gauge: &Gauge{DataPoints: []NumberDataPoint{{AsInt: &asInt}}}},
+		Metric{Name: "secret.temp", Gauge: &Gauge{DataPoints: []NumberDataPoint{{AsInt: &asInt}}}},
+		Metric{Name: "empty", Gauge: &Gauge{DataPoints: []NumberDataPoint{{Flags: FlagNoRecordedValue}}}},
+	)
+	authorize := func(name string) error {
+		if strings.HasPrefix(name, "checkout.secret.") {
+			return fmt.Errorf("metric is outside token prefix")
+		}
+		return nil
+	}
+
+	result, err := receiver.Write(context.Background(), req, authorize)
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.DataPoints)
+	assert.Equal(t, 4, result.Rejected, "Point without recorded value is skipped, not rejected")
+	assert.Zero(t, result.Gauges+result.Counters)
+	assert.Contains(t, result.Errors[0], "unspecified aggregation temporality")
+	assert.Contains(t, result.Errors[1], "out of range")
+	assert.Contains(t, result.Errors[2], "metric name cannot be empty")
+	assert.Contains(t, result.Errors[3], "outside token prefix")
+}
+
+func TestReceiver_Write_StorageErrorKeepsStreamState(t *testing.T) {
+	receiver, err := NewReceiver(failingWriter{}, testutils.NewMockLogger())
+	require.NoError(t, err)
+
+	_, err = receiver.Write(context.Background(), sumRequest(TemporalityCumulative, "a", point(1, 5)), nil)
+	assert.True(t, errors.Is(err, models.ErrStorageUnavailable))
+	assert.Empty(t, receiver.streams, "Failed batch must not advance stream state")
+}
+
+func TestReceiver_Write_EvictsStaleStreams(t *testing.T) {
+	receiver, metricsService := newTestReceiver(t)
+	ctx := context.Background()
+	start := receiver.startedAt + 1
+	now := time.Unix(1700000000, 0)
+	receiver.nowFunc = func() time.Time { return now }
+	receiver.SetLimits(1, time.Minute)
+
+	write := func(req *ExportMetricsServiceRequest) int64 {
+		t.Helper()
+		_, err := receiver.Write(ctx, req, nil)
+		require.NoError(t, err)
+		orders, _, err := metricsService.GetCounter(ctx, "checkout.orders")
+		require.NoError(t, err)
+		return orders
+	}
+
+	assert.Equal(t, int64(10), write(sumRequest(TemporalityCumulative, "a", point(start, 10))))
+	assert.Equal(t, int64(14), write(sumRequest(TemporalityCumulative, "b", point(start, 4))))
+	assert.Equal(t, 1, receiver.Len(), "Least recently used stream is evicted over MaxStreams")
+
+	// Вытесненный поток не учитывает накопленное значение повторно
+	assert.Equal(t, int64(14), write(sumRequest(TemporalityCumulative, "a", point(start, 15))))
+	assert.Equal(t, int64(19), write(sumRequest(TemporalityCumulative, "a", point(start, 20))))
+
+	receiver.SetLimits(0, time.Minute)
+	now = now.Add(2 * time.Minute)
+	write(sumRequest(TemporalityCumulative, "b", point(start, 6)))
+	assert.Equal(t, 1, receiver.Len(), "Stream without points longer than StreamTTL is evicted")
+}
+
+// blockingWriter задерживает первую запись до закрытия unblock
+type blockingWriter struct {
+	MetricsWriter
+	started chan struct{}
+	unblock chan struct{}
+	once    sync.Once
+}
+
+func (w *blockingWriter) UpdateMetricsBatch(ctx context.Context, metrics []models.Metrics) error {
+	first := false
+	w.once.Do(func() { first = true })
+	if first {
+		close(w.started)
+		<-w.unblock
+	}
+	return w.MetricsWriter.UpdateMetricsBatch(ctx, metrics)
+}
+
+func TestReceiver_Write_StorageWriteOutsideLock(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repo := repository.NewInMemoryMetricsRepository(mockLogger, "", false)
+	metricsService := service.NewMetricsService(repo, mockLogger)
+	writer := &blockingWriter{MetricsWriter: metricsService, started: make(chan struct{}), unblock: make(chan struct{})}
+	receiver, err := NewReceiver(writer, mockLogger)
+	require.NoError(t, err)
+	ctx := context.Background()
+	start := receiver.startedAt + 1
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, err := receiver.Write(ctx, sumRequest(TemporalityCumulative, "a", point(start, 10)), nil)
+		assert.NoError(t, err)
+	}()
+	<-writer.started
+
+	// Запрос с другим потоком не ждет медленную запись
+	_, err = receiver.Write(ctx, sumRequest(TemporalityCumulative, "b", point(start, 5)), nil)
+	require.NoError(t, err)
+
+	// Запрос с тем же потоком ждет фиксации состояния и записывает только прирост
+	go func() {
+		defer wg.Done()
+		_, err := receiver.Write(ctx, sumRequest(TemporalityCumulative, "a", point(start, 12)), nil)
+		assert.NoError(t, err)
+	}()
+	close(writer.unblock)
+	wg.Wait()
+
+	orders, _, err := metricsService.GetCounter(ctx, "checkout.orders")
+	require.NoError(t, err)
+	assert.Equal(t, int64(17), orders)
+}
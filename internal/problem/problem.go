@@ -0,0 +1,61 @@
+package problem
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/IgorKilipenko/metrical/pkg/models"
+)
+
+// ContentType тип содержимого ответа с описанием ошибки
+const ContentType = "application/problem+json"
+
+// Типы ошибок API. Клиенты различают ошибки по полю type, а не по тексту detail.
+const (
+	TypeDefault            = models.ProblemTypeDefault
+	TypeValidation         = models.ProblemTypeValidation
+	TypeUnsupportedType    = models.ProblemTypeUnsupportedType
+	TypeNotFound           = models.ProblemTypeNotFound
+	TypeStorageUnavailable = models.ProblemTypeStorageUnavailable
+)
+
+// FieldError описание ошибки отдельного поля запроса (тип pkg/models, общий с клиентами)
+type FieldError = models.FieldError
+
+// Problem описание ошибки по RFC 7807 (тип pkg/models, общий с клиентами)
+type Problem = models.Problem
+
+// New создает описание ошибки с типом about:blank и стандартным заголовком статуса
+func New(status int, detail string) *Problem {
+	return &Problem{
+		Type:   TypeDefault,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Write отправляет описание ошибки клиенту с заголовком Content-Type: application/problem+json
+func Write(w http.ResponseWriter, p *Problem) error {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
+}
+
+// Parse разбирает тело ответа с описанием ошибки.
+// Возвращает false, если тип содержимого отличается от application/problem+json или тело некорректно.
+func Parse(contentType string, body []byte) (*Problem, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != ContentType {
+		return nil, false
+	}
+
+	var p Problem
+	if err := json.Unmarshal(body, &p); err != nil || p.Status == 0 {
+		return nil, false
+	}
+	return &p, true
+}
@@ -0,0 +1,67 @@
+package problem
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	p := New(http.StatusNotImplemented, "metrics history is disabled")
+
+	assert.Equal(t, TypeDefault, p.Type)
+	assert.Equal(t, "Not Implemented", p.Title)
+	assert.Equal(t, http.StatusNotImplemented, p.Status)
+	assert.Equal(t, "metrics history is disabled", p.Error())
+
+	assert.Equal(t, "Internal Server Error", New(http.StatusInternalServerError, "").Error(), "Error falls back to title")
+}
+
+func TestWriteAndParse(t *testing.T) {
+	original := &Problem{
+		Type:     TypeValidation,
+		Title:    "Validation failed",
+		Status:   http.StatusBadRequest,
+		Detail:   "validation error for field 'id' with value '': is required",
+		Instance: "/update",
+		Errors:   []FieldError{{Field: "id", Value: "", Message: "is required"}},
+	}
+
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Length", "10")
+	require.NoError(t, Write(w, original))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Content-Length"), "Stale Content-Length must be removed")
+
+	parsed, ok := Parse(w.Header().Get("Content-Type"), w.Body.Bytes())
+	require.True(t, ok)
+	assert.Equal(t, original, parsed)
+}
+
+func TestParse_Rejects(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{name: "plain text", contentType: "text/plain; charset=utf-8", body: "Metric not found\n"},
+		{name: "regular json", contentType: "application/json", body: `{"status":404}`},
+		{name: "invalid json", contentType: ContentType, body: `{"status":`},
+		{name: "missing status", contentType: ContentType, body: `{"title":"Not Found"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := Parse(tt.contentType, []byte(tt.body))
+			assert.False(t, ok)
+		})
+	}
+
+	_, ok := Parse(ContentType+"; charset=utf-8", []byte(`{"status":404}`))
+	assert.True(t, ok, "Media type parameters should be ignored")
+}
@@ -0,0 +1,83 @@
+// Package protoutil содержит общие функции разбора protobuf сообщений без сгенерированного кода.
+package protoutil
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Visitor получает поле сообщения. Для полей BytesType передается содержимое,
+// для Varint, Fixed32 и Fixed64 - числовое значение.
+type Visitor func(num protowire.Number, typ protowire.Type, value []byte, scalar uint64) error
+
+// WalkFields обходит поля сообщения protobuf; группы пропускаются.
+// Возвращает ошибку разбора или первую ошибку visit.
+func WalkFields(data []byte, visit Visitor) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		var value []byte
+		var scalar uint64
+		skip := false
+		switch typ {
+		case protowire.VarintType:
+			scalar, n = protowire.ConsumeVarint(data)
+		case protowire.Fixed64Type:
+			scalar, n = protowire.ConsumeFixed64(data)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(data)
+			scalar = uint64(v)
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			skip = true
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if skip {
+			continue
+		}
+		if err := visit(num, typ, value, scalar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendMessage добавляет вложенное сообщение как поле BytesType
+func AppendMessage(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+// AppendString добавляет строковое поле; пустая строка не сериализуется, как в proto3
+func AppendString(b []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+// AppendVarint добавляет поле Varint; ноль не сериализуется, как в proto3
+func AppendVarint(b []byte, num protowire.Number, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+// AppendFixed64 добавляет поле Fixed64 (double, fixed64, sfixed64) без пропуска нулевого значения
+func AppendFixed64(b []byte, num protowire.Number, value uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, value)
+}
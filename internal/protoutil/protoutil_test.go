@@ -0,0 +1,56 @@
+package protoutil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type field struct {
+	num    protowire.Number
+	typ    protowire.Type
+	value  string
+	scalar uint64
+}
+
+func TestWalkFields(t *testing.T) {
+	var b []byte
+	b = AppendString(b, 1, "name")
+	b = AppendString(b, 2, "")
+	b = AppendVarint(b, 3, 150)
+	b = AppendVarint(b, 4, 0)
+	b = AppendFixed64(b, 5, 0)
+	b = protowire.AppendTag(b, 6, protowire.Fixed32Type)
+	b = protowire.AppendFixed32(b, 7)
+	b = AppendMessage(b, 7, AppendString(nil, 1, "nested"))
+	// Группа пропускается
+	b = protowire.AppendTag(b, 8, protowire.StartGroupType)
+	b = protowire.AppendTag(b, 8, protowire.EndGroupType)
+
+	var fields []field
+	err := WalkFields(b, func(num protowire.Number, typ protowire.Type, value []byte, scalar uint64) error {
+		fields = append(fields, field{num: num, typ: typ, value: string(value), scalar: scalar})
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []field{
+		{num: 1, typ: protowire.BytesType, value: "name"},
+		{num: 3, typ: protowire.VarintType, scalar: 150},
+		{num: 5, typ: protowire.Fixed64Type},
+		{num: 6, typ: protowire.Fixed32Type, scalar: 7},
+		{num: 7, typ: protowire.BytesType, value: string(AppendString(nil, 1, "nested"))},
+	}, fields)
+}
+
+func TestWalkFields_Errors(t *testing.T) {
+	data := AppendString(nil, 1, "truncated")
+	assert.Error(t, WalkFields(data[:len(data)-2], func(protowire.Number, protowire.Type, []byte, uint64) error { return nil }))
+
+	visitErr := errors.New("stop")
+	err := WalkFields(data, func(protowire.Number, protowire.Type, []byte, uint64) error { return visitErr })
+	assert.ErrorIs(t, err, visitErr)
+}
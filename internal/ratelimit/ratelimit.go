@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Config настройки ограничения частоты запросов одного клиента
+type Config struct {
+	Rate       float64 // Средняя частота запросов в секунду
+	Burst      int     // Максимальное количество запросов подряд
+	MaxClients int     // Максимальное количество отслеживаемых клиентов
+}
+
+// DefaultConfig возвращает настройки по умолчанию
+func DefaultConfig() *Config {
+	return &Config{
+		Rate:       10,
+		Burst:      20,
+		MaxClients: 10000,
+	}
+}
+
+// Validate проверяет корректность настроек
+func (c *Config) Validate() error {
+	if c.Rate <= 0 {
+		return fmt.Errorf("rate must be positive")
+	}
+	if c.Burst <= 0 {
+		return fmt.Errorf("burst must be positive")
+	}
+	if c.MaxClients <= 0 {
+		return fmt.Errorf("max clients must be positive")
+	}
+	return nil
+}
+
+// bucket корзина токенов клиента
+type bucket struct {
+	client  string
+	tokens  float64
+	last    time.Time
+	element *list.Element
+}
+
+// Limiter ограничитель частоты запросов по алгоритму token bucket с отдельной корзиной на клиента
+type Limiter struct {
+	config  Config
+	buckets map[string]*bucket
+	order   *list.List // Корзины в порядке последнего обращения (давние в начале)
+	mu      sync.Mutex
+	nowFunc func() time.Time // Источник времени (подменяется в тестах)
+}
+
+// NewLimiter создает ограничитель частоты запросов
+func NewLimiter(config *Config) (*Limiter, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Limiter{
+		config:  *config,
+		buckets: make(map[string]*bucket),
+		order:   list.New(),
+		nowFunc: time.Now,
+	}, nil
+}
+
+// Allow расходует токен клиента. Если токенов нет, возвращает false и время до появления следующего токена.
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.nowFunc()
+	b, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= l.config.MaxClients {
+			l.evictUnsafe(now)
+		}
+		b = &bucket{client: client, tokens: float64(l.config.Burst), last: now}
+		b.element = l.order.PushBack(b)
+		l.buckets[client] = b
+	} else {
+		l.order.MoveToBack(b.element)
+	}
+
+	// Пополняем корзину пропорционально прошедшему времени
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(l.config.Burst), b.tokens+elapsed*l.config.Rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.config.Rate * float64(time.Second))
+	return false, wait
+}
+
+// evictUnsafe удаляет корзины, успевшие наполниться (клиент давно не обращался).
+// Если таких нет, удаляет самую давнюю корзину. Корзины упорядочены по времени обращения,
+// поэтому просматривается только начало списка (вызывается под блокировкой).
+func (l *Limiter) evictUnsafe(now time.Time) {
+	refill := time.Duration(float64(l.config.Burst) / l.config.Rate * float64(time.Second))
+
+	for element := l.order.Front(); element != nil; element = l.order.Front() {
+		b := element.Value.(*bucket)
+		if now.Sub(b.last) < refill && len(l.buckets) < l.config.MaxClients {
+			break
+		}
+		l.order.Remove(element)
+		delete(l.buckets, b.client)
+	}
+}
+
+// Len возвращает количество отслеживаемых клиентов
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestLimiter создает ограничитель с управляемым временем
+func newTestLimiter(t *testing.T, config *Config) (*Limiter, *time.Time) {
+	t.Helper()
+
+	limiter, err := NewLimiter(config)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter.nowFunc = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestLimiter_Burst(t *testing.T) {
+	limiter, now := newTestLimiter(t, &Config{Rate: 2, Burst: 3, MaxClients: 10})
+
+	for i := 0; i < 3; i++ {
+		allowed, _ := limiter.Allow("agent")
+		assert.True(t, allowed, "Request %d should fit into burst", i+1)
+	}
+
+	allowed, wait := limiter.Allow("agent")
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, wait, "Next token should arrive after 1/rate seconds")
+
+	// Другие клиенты имеют собственные корзины
+	allowed, _ = limiter.Allow("other")
+	assert.True(t, allowed)
+
+	*now = now.Add(500 * time.Millisecond)
+	allowed, _ = limiter.Allow("agent")
+	assert.True(t, allowed, "Bucket should be refilled over time")
+}
+
+func TestLimiter_RefillCappedByBurst(t *testing.T) {
+	limiter, now := newTestLimiter(t, &Config{Rate: 10, Burst: 2, MaxClients: 10})
+
+	limiter.Allow("agent")
+	*now = now.Add(time.Hour)
+
+	allowedCount := 0
+	for i := 0; i < 5; i++ {
+		if allowed, _ := limiter.Allow("agent"); allowed {
+			allowedCount++
+		}
+	}
+	assert.Equal(t, 2, allowedCount, "Idle client should get at most burst requests")
+}
+
+func TestLimiter_MaxClients(t *testing.T) {
+	limiter, now := newTestLimiter(t, &Config{Rate: 1, Burst: 1, MaxClients: 2})
+
+	limiter.Allow("a")
+	*now = now.Add(100 * time.Millisecond)
+	limiter.Allow("b")
+	*now = now.Add(100 * time.Millisecond)
+	limiter.Allow("c")
+	assert.Equal(t, 2, limiter.Len(), "Oldest client should be evicted")
+
+	// Наполнившиеся корзины вытесняются в первую очередь
+	*now = now.Add(time.Minute)
+	limiter.Allow("d")
+	assert.LessOrEqual(t, limiter.Len(), 2)
+}
+
+func TestNewLimiter_Validation(t *testing.T) {
+	_, err := NewLimiter(&Config{Rate: 0, Burst: 1, MaxClients: 1})
+	assert.Error(t, err)
+	_, err = NewLimiter(&Config{Rate: 1, Burst: 0, MaxClients: 1})
+	assert.Error(t, err)
+	_, err = NewLimiter(&Config{Rate: 1, Burst: 1, MaxClients: 0})
+	assert.Error(t, err)
+
+	limiter, err := NewLimiter(nil)
+	require.NoError(t, err)
+	assert.Equal(t, *DefaultConfig(), limiter.config)
+}
+
+func TestLimiter_EvictsLeastRecentlyUsed(t *testing.T) {
+	limiter, now := newTestLimiter(t, &Config{Rate: 1, Burst: 2, MaxClients: 2})
+
+	limiter.Allow("a")
+	*now = now.Add(100 * time.Millisecond)
+	limiter.Allow("b")
+	*now = now.Add(100 * time.Millisecond)
+
+	// Обращение делает корзину "a" самой свежей, вытесняется "b"
+	limiter.Allow("a")
+	limiter.Allow("c")
+	assert.Equal(t, 2, limiter.Len())
+
+	allowed, _ := limiter.Allow("a")
+	assert.False(t, allowed, "Bucket of the recently used client must be kept")
+}
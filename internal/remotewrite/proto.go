@@ -0,0 +1,269 @@
+// Package remotewrite принимает метрики по протоколу Prometheus remote_write 1.0.
+package remotewrite
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/IgorKilipenko/metrical/internal/protoutil"
+	"github.com/klauspost/compress/s2"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// MetricType тип метрики из метаданных prometheus.MetricMetadata
+type MetricType int32
+
+// Значения перечисления prometheus.MetricMetadata.MetricType
+const (
+	MetricTypeUnknown        MetricType = 0
+	MetricTypeCounter        MetricType = 1
+	MetricTypeGauge          MetricType = 2
+	MetricTypeHistogram      MetricType = 3
+	MetricTypeGaugeHistogram MetricType = 4
+	MetricTypeSummary        MetricType = 5
+	MetricTypeInfo           MetricType = 6
+	MetricTypeStateset       MetricType = 7
+)
+
+// WriteRequest тело запроса remote_write (prometheus.WriteRequest)
+type WriteRequest struct {
+	Timeseries []TimeSeries
+	Metadata   []MetricMetadata
+}
+
+// TimeSeries временной ряд: набор меток и отсчеты
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+
+	// Нативные гистограммы не поддерживаются: учитывается только их наличие
+	Histograms int
+}
+
+// Label метка временного ряда
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample отсчет временного ряда; Timestamp в миллисекундах Unix
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// MetricMetadata метаданные семейства метрик
+type MetricMetadata struct {
+	Type             MetricType
+	MetricFamilyName string
+	Help             string
+	Unit             string
+}
+
+// Номера полей protobuf схемы prometheus/prompb/types.proto и remote.proto
+const (
+	fieldWriteRequestTimeseries = 1
+	fieldWriteRequestMetadata   = 3
+
+	fieldTimeSeriesLabels     = 1
+	fieldTimeSeriesSamples    = 2
+	fieldTimeSeriesHistograms = 4
+
+	fieldLabelName  = 1
+	fieldLabelValue = 2
+
+	fieldSampleValue     = 1
+	fieldSampleTimestamp = 2
+
+	fieldMetadataType       = 1
+	fieldMetadataFamilyName = 2
+	fieldMetadataHelp       = 4
+	fieldMetadataUnit       = 5
+)
+
+// Decode распаковывает тело запроса (snappy block format) и разбирает WriteRequest.
+// При превышении maxDecodedSize (0 - без ограничений) возвращает *http.MaxBytesError
+// до распаковки, по размеру из заголовка snappy блока.
+func Decode(body []byte, maxDecodedSize int64) (*WriteRequest, error) {
+	size, err := s2.DecodedLen(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snappy header: %w", err)
+	}
+	if maxDecodedSize > 0 && int64(size) > maxDecodedSize {
+		return nil, &http.MaxBytesError{Limit: maxDecodedSize}
+	}
+
+	data, err := s2.Decode(nil, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode snappy body: %w", err)
+	}
+	return Unmarshal(data)
+}
+
+// Encode сериализует запрос и сжимает его snappy, как это делает Prometheus
+func Encode(req *WriteRequest) []byte {
+	return s2.EncodeSnappy(nil, req.Marshal())
+}
+
+// Unmarshal разбирает WriteRequest из protobuf. Неизвестные поля пропускаются.
+func Unmarshal(data []byte) (*WriteRequest, error) {
+	req := &WriteRequest{}
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		switch {
+		case num == fieldWriteRequestTimeseries && typ == protowire.BytesType:
+			series, err := unmarshalTimeSeries(value)
+			if err != nil {
+				return fmt.Errorf("timeseries %d: %w", len(req.Timeseries), err)
+			}
+			req.Timeseries = append(req.Timeseries, series)
+		case num == fieldWriteRequestMetadata && typ == protowire.BytesType:
+			metadata, err := unmarshalMetadata(value)
+			if err != nil {
+				return fmt.Errorf("metadata %d: %w", len(req.Metadata), err)
+			}
+			req.Metadata = append(req.Metadata, metadata)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal write request: %w", err)
+	}
+	return req, nil
+}
+
+// Marshal сериализует запрос в protobuf
+func (r *WriteRequest) Marshal() []byte {
+	var b []byte
+	for i := range r.Timeseries {
+		b = protowire.AppendTag(b, fieldWriteRequestTimeseries, protowire.BytesType)
+		b = protowire.AppendBytes(b, r.Timeseries[i].marshal())
+	}
+	for i := range r.Metadata {
+		b = protowire.AppendTag(b, fieldWriteRequestMetadata, protowire.BytesType)
+		b = protowire.AppendBytes(b, r.Metadata[i].marshal())
+	}
+	return b
+}
+
+// marshal сериализует временной ряд
+func (s *TimeSeries) marshal() []byte {
+	var b []byte
+	for _, label := range s.Labels {
+		var lb []byte
+		lb = protowire.AppendTag(lb, fieldLabelName, protowire.BytesType)
+		lb = protowire.AppendString(lb, label.Name)
+		lb = protowire.AppendTag(lb, fieldLabelValue, protowire.BytesType)
+		lb = protowire.AppendString(lb, label.Value)
+
+		b = protowire.AppendTag(b, fieldTimeSeriesLabels, protowire.BytesType)
+		b = protowire.AppendBytes(b, lb)
+	}
+	for _, sample := range s.Samples {
+		var sb []byte
+		sb = protowire.AppendTag(sb, fieldSampleValue, protowire.Fixed64Type)
+		sb = protowire.AppendFixed64(sb, math.Float64bits(sample.Value))
+		sb = protowire.AppendTag(sb, fieldSampleTimestamp, protowire.VarintType)
+		sb = protowire.AppendVarint(sb, uint64(sample.Timestamp))
+
+		b = protowire.AppendTag(b, fieldTimeSeriesSamples, protowire.BytesType)
+		b = protowire.AppendBytes(b, sb)
+	}
+	for range s.Histograms {
+		b = protowire.AppendTag(b, fieldTimeSeriesHistograms, protowire.BytesType)
+		b = protowire.AppendBytes(b, nil)
+	}
+	return b
+}
+
+// marshal сериализует метаданные семейства
+func (m *MetricMetadata) marshal() []byte {
+	var b []byte
+	b = protowire.AppendTag(b, fieldMetadataType, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(m.Type))
+	b = protowire.AppendTag(b, fieldMetadataFamilyName, protowire.BytesType)
+	b = protowire.AppendString(b, m.MetricFamilyName)
+	if m.Help != "" {
+		b = protowire.AppendTag(b, fieldMetadataHelp, protowire.BytesType)
+		b = protowire.AppendString(b, m.Help)
+	}
+	if m.Unit != "" {
+		b = protowire.AppendTag(b, fieldMetadataUnit, protowire.BytesType)
+		b = protowire.AppendString(b, m.Unit)
+	}
+	return b
+}
+
+// unmarshalTimeSeries разбирает prometheus.TimeSeries; экземпляры (exemplars) пропускаются
+func unmarshalTimeSeries(data []byte) (TimeSeries, error) {
+	var series TimeSeries
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		switch {
+		case num == fieldTimeSeriesLabels && typ == protowire.BytesType:
+			label, err := unmarshalLabel(value)
+			if err != nil {
+				return fmt.Errorf("label: %w", err)
+			}
+			series.Labels = append(series.Labels, label)
+		case num == fieldTimeSeriesSamples && typ == protowire.BytesType:
+			sample, err := unmarshalSample(value)
+			if err != nil {
+				return fmt.Errorf("sample: %w", err)
+			}
+			series.Samples = append(series.Samples, sample)
+		case num == fieldTimeSeriesHistograms && typ == protowire.BytesType:
+			series.Histograms++
+		}
+		return nil
+	})
+	return series, err
+}
+
+// unmarshalLabel разбирает prometheus.Label
+func unmarshalLabel(data []byte) (Label, error) {
+	var label Label
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		switch {
+		case num == fieldLabelName && typ == protowire.BytesType:
+			label.Name = string(value)
+		case num == fieldLabelValue && typ == protowire.BytesType:
+			label.Value = string(value)
+		}
+		return nil
+	})
+	return label, err
+}
+
+// unmarshalSample разбирает prometheus.Sample
+func unmarshalSample(data []byte) (Sample, error) {
+	var sample Sample
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, _ []byte, scalar uint64) error {
+		switch {
+		case num == fieldSampleValue && typ == protowire.Fixed64Type:
+			sample.Value = math.Float64frombits(scalar)
+		case num == fieldSampleTimestamp && typ == protowire.VarintType:
+			sample.Timestamp = int64(scalar)
+		}
+		return nil
+	})
+	return sample, err
+}
+
+// unmarshalMetadata разбирает prometheus.MetricMetadata
+func unmarshalMetadata(data []byte) (MetricMetadata, error) {
+	var metadata MetricMetadata
+	err := protoutil.WalkFields(data, func(num protowire.Number, typ protowire.Type, value []byte, scalar uint64) error {
+		switch {
+		case num == fieldMetadataType && typ == protowire.VarintType:
+			metadata.Type = MetricType(scalar)
+		case num == fieldMetadataFamilyName && typ == protowire.BytesType:
+			metadata.MetricFamilyName = string(value)
+		case num == fieldMetadataHelp && typ == protowire.BytesType:
+			metadata.Help = string(value)
+		case num == fieldMetadataUnit && typ == protowire.BytesType:
+			metadata.Unit = string(value)
+		}
+		return nil
+	})
+	return metadata, err
+}
@@ -0,0 +1,79 @@
+package remotewrite
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	req := &WriteRequest{
+		Timeseries: []TimeSeries{
+			{
+				Labels:  []Label{{Name: NameLabel, Value: "up"}, {Name: "job", Value: "node"}},
+				Samples: []Sample{{Value: 1, Timestamp: 1700000000000}, {Value: math.Float64frombits(0x7ff0000000000002), Timestamp: -1}},
+			},
+			{
+				Labels:     []Label{{Name: NameLabel, Value: "latency"}},
+				Histograms: 1,
+			},
+		},
+		Metadata: []MetricMetadata{{Type: MetricTypeCounter, MetricFamilyName: "http_requests", Help: "Requests", Unit: "requests"}},
+	}
+
+	decoded, err := Decode(Encode(req), 0)
+	require.NoError(t, err)
+
+	require.Len(t, decoded.Timeseries, 2)
+	assert.Equal(t, req.Timeseries[0].Labels, decoded.Timeseries[0].Labels)
+	require.Len(t, decoded.Timeseries[0].Samples, 2)
+	assert.Equal(t, 1.0, decoded.Timeseries[0].Samples[0].Value)
+	assert.Equal(t, int64(1700000000000), decoded.Timeseries[0].Samples[0].Timestamp)
+	assert.True(t, math.IsNaN(decoded.Timeseries[0].Samples[1].Value))
+	assert.Equal(t, int64(-1), decoded.Timeseries[0].Samples[1].Timestamp)
+	assert.Equal(t, 1, decoded.Timeseries[1].Histograms)
+	assert.Equal(t, req.Metadata, decoded.Metadata)
+}
+
+func TestUnmarshal_SkipsUnknownFields(t *testing.T) {
+	var b []byte
+	// Неизвестное поле верхнего уровня (varint и fixed32)
+	b = protowire.AppendTag(b, 7, protowire.VarintType)
+	b = protowire.AppendVarint(b, 42)
+	b = protowire.AppendTag(b, 8, protowire.Fixed32Type)
+	b = protowire.AppendFixed32(b, 1)
+	b = append(b, (&WriteRequest{Timeseries: []TimeSeries{{Labels: []Label{{Name: NameLabel, Value: "x"}}}}}).Marshal()...)
+
+	req, err := Unmarshal(b)
+	require.NoError(t, err)
+	require.Len(t, req.Timeseries, 1)
+	assert.Equal(t, "x", req.Timeseries[0].Labels[0].Value)
+}
+
+func TestUnmarshal_Truncated(t *testing.T) {
+	data := (&WriteRequest{Timeseries: []TimeSeries{{Labels: []Label{{Name: NameLabel, Value: "metric"}}}}}).Marshal()
+
+	_, err := Unmarshal(data[:len(data)-3])
+	assert.Error(t, err)
+}
+
+func TestDecode_Errors(t *testing.T) {
+	t.Run("Not snappy", func(t *testing.T) {
+		_, err := Decode([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("Decoded size limit", func(t *testing.T) {
+		req := &WriteRequest{Timeseries: []TimeSeries{{Labels: []Label{{Name: NameLabel, Value: string(make([]byte, 1024))}}}}}
+
+		_, err := Decode(Encode(req), 512)
+		var maxBytesErr *http.MaxBytesError
+		require.True(t, errors.As(err, &maxBytesErr))
+		assert.Equal(t, int64(512), maxBytesErr.Limit)
+	})
+}
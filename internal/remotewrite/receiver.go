@@ -0,0 +1,522 @@
+package remotewrite
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/logger"
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/validation"
+)
+
+const (
+	// DefaultMaxBodySize максимальный размер сжатого тела запроса по умолчанию
+	DefaultMaxBodySize int64 = 8 << 20
+	// DefaultMaxDecodedSize максимальный размер распакованного тела запроса по умолчанию
+	DefaultMaxDecodedSize int64 = 32 << 20
+	// DefaultMaxSeries максимальное количество отслеживаемых рядов counter по умолчанию
+	DefaultMaxSeries = 100000
+	// DefaultSeriesTTL время хранения состояния ряда counter без новых отсчетов по умолчанию
+	DefaultSeriesTTL = time.Hour
+
+	// NameLabel метка с именем метрики
+	NameLabel = "__name__"
+
+	// maxReportedErrors сколько ошибок рядов возвращается клиенту в статистике
+	maxReportedErrors = 10
+)
+
+// Config ограничения размера запросов remote_write и состояния рядов counter
+type Config struct {
+	MaxBodySize    int64         // Максимальный размер сжатого тела запроса в байтах (0 - без ограничений)
+	MaxDecodedSize int64         // Максимальный размер распакованного тела запроса в байтах (0 - без ограничений)
+	MaxSeries      int           // Максимальное количество отслеживаемых рядов counter (0 - без ограничений)
+	SeriesTTL      time.Duration // Время хранения состояния ряда без новых отсчетов (0 - без ограничения)
+}
+
+// DefaultConfig возвращает ограничения по умолчанию
+func DefaultConfig() *Config {
+	return &Config{
+		MaxBodySize:    DefaultMaxBodySize,
+		MaxDecodedSize: DefaultMaxDecodedSize,
+		MaxSeries:      DefaultMaxSeries,
+		SeriesTTL:      DefaultSeriesTTL,
+	}
+}
+
+// Validate проверяет ограничения
+func (c *Config) Validate() error {
+	if c.MaxBodySize < 0 {
+		return fmt.Errorf("remote write max body size cannot be negative")
+	}
+	if c.MaxDecodedSize < 0 {
+		return fmt.Errorf("remote write max decoded size cannot be negative")
+	}
+	if c.MaxSeries < 0 {
+		return fmt.Errorf("remote write max series cannot be negative")
+	}
+	if c.SeriesTTL < 0 {
+		return fmt.Errorf("remote write series TTL cannot be negative")
+	}
+	return nil
+}
+
+// MetricsWriter получатель метрик (реализуется service.MetricsService)
+type MetricsWriter interface {
+	UpdateMetricsBatch(ctx context.Context, metrics []models.Metrics) error
+	GetCounter(ctx context.Context, name string) (int64, bool, error)
+}
+
+// Stats результат обработки запроса, включая ряды, которые не удалось записать
+type Stats struct {
+	Series   int      `json:"series"`           // Получено временных рядов
+	Samples  int      `json:"samples"`          // Получено отсчетов
+	Gauges   int      `json:"gauges"`           // Записано метрик gauge
+	Counters int      `json:"counters"`         // Записано метрик counter
+	Skipped  int      `json:"skipped"`          // Ряды без значений (только stale маркеры)
+	Failed   int      `json:"failed"`           // Ряды, отклоненные при проверке
+	Errors   []string `json:"errors,omitempty"` // Первые ошибки отклоненных рядов
+}
+
+// fail учитывает отклоненный ряд
+func (s *Stats) fail(name string, err error) {
+	s.Failed++
+	if len(s.Errors) < maxReportedErrors {
+		if name == "" {
+			s.Errors = append(s.Errors, err.Error())
+		} else {
+			s.Errors = append(s.Errors, fmt.Sprintf("%s: %s", name, err))
+		}
+	}
+}
+
+// counterState состояние ряда counter
+type counterState struct {
+	name    string
+	last    float64   // Последнее накопленное значение
+	known   bool      // Значение получено и записано хотя бы раз
+	seen    time.Time // Время последней записи
+	busy    bool      // Ряд зарезервирован выполняемым запросом
+	element *list.Element
+}
+
+// Receiver преобразует временные ряды Prometheus в метрики metrical.
+//
+// Prometheus передает накопленные значения counter, а metrical прибавляет к счетчику прирост,
+// поэтому Receiver хранит последнее полученное значение каждого counter и записывает разницу.
+// Состояние хранится в памяти: после перезапуска сервера или вытеснения ряда первое значение
+// сверяется с сохраненным счетчиком. Ряды без новых отсчетов дольше SeriesTTL и самые давние
+// ряды сверх MaxSeries вытесняются.
+type Receiver struct {
+	writer MetricsWriter
+	logger logger.Logger
+
+	mu        sync.Mutex
+	released  *sync.Cond               // Сигнал об освобождении зарезервированных рядов
+	counters  map[string]*counterState // Состояние counter по имени метрики
+	order     *list.List               // Состояния в порядке последнего обращения (давние в начале)
+	types     map[string]MetricType    // Типы семейств из метаданных предыдущих запросов
+	maxSeries int
+	seriesTTL time.Duration
+	nowFunc   func() time.Time // Источник времени (подменяется в тестах)
+}
+
+// NewReceiver создает приемник remote_write
+func NewReceiver(writer MetricsWriter, logger logger.Logger) (*Receiver, error) {
+	if writer == nil {
+		return nil, fmt.Errorf("metrics writer cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+
+	r := &Receiver{
+		writer:    writer,
+		logger:    logger,
+		counters:  make(map[string]*counterState),
+		order:     list.New(),
+		types:     make(map[string]MetricType),
+		maxSeries: DefaultMaxSeries,
+		seriesTTL: DefaultSeriesTTL,
+		nowFunc:   time.Now,
+	}
+	r.released = sync.NewCond(&r.mu)
+	return r, nil
+}
+
+// SetLimits задает ограничения состояния рядов counter (0 - без ограничений)
+func (r *Receiver) SetLimits(maxSeries int, seriesTTL time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.maxSeries = maxSeries
+	r.seriesTTL = seriesTTL
+	r.evictUnsafe(r.nowFunc())
+}
+
+// Len возвращает количество отслеживаемых рядов counter
+func (r *Receiver) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.counters)
+}
+
+// counterSeries отсчеты counter одной метрики metrical
+type counterSeries struct {
+	name    string
+	samples []Sample
+}
+
+// parsedRequest ряды запроса, разделенные на gauge и counter в порядке появления
+type parsedRequest struct {
+	gauges       map[string]float64
+	gaugeNames   []string
+	counters     map[string]*counterSeries
+	counterNames []string
+}
+
+// Write записывает ряды запроса одним пакетом через writer.
+// Некорректные ряды и ряды, отклоненные authorize, пропускаются и учитываются в статистике;
+// ошибка возвращается, только если не удалось записать пакет.
+//
+// Блокировка удерживается только для разбора типов и резервирования рядов counter: запись в хранилище
+// выполняется без нее. Зарезервированный ряд ждет завершения другого запроса с тем же рядом,
+// поэтому приросты одного counter вычисляются от зафиксированного значения.
+func (r *Receiver) Write(ctx context.Context, req *WriteRequest, authorize func(name string) error) (*Stats, error) {
+	stats, parsed := r.parse(req, authorize)
+
+	states := r.reserve(parsed.counterNames)
+	pending := make(map[string]float64, len(parsed.counterNames))
+	written := false
+	defer func() { r.release(states, pending, written) }()
+
+	metrics := make([]models.Metrics, 0, len(parsed.gaugeNames)+len(parsed.counterNames))
+	for _, name := range parsed.gaugeNames {
+		value := parsed.gauges[name]
+		metrics = append(metrics, models.Metrics{ID: name, MType: models.Gauge, Value: &value})
+	}
+
+	for i, name := range parsed.counterNames {
+		delta, last, err := r.counterDelta(ctx, parsed.counters[name], states[i])
+		if err != nil {
+			return stats, err
+		}
+		pending[name] = last
+		metrics = append(metrics, models.Metrics{ID: name, MType: models.Counter, Delta: &delta})
+	}
+
+	// Отдельные метрики проверяются заранее, чтобы одна ошибка не отклоняла весь пакет
+	valid := metrics[:0]
+	for _, metric := range metrics {
+		if err := validation.ValidateMetric(&metric); err != nil {
+			stats.fail(metric.ID, err)
+			delete(pending, metric.ID)
+			continue
+		}
+		valid = append(valid, metric)
+	}
+
+	if len(valid) == 0 {
+		return stats, nil
+	}
+	if err := r.writer.UpdateMetricsBatch(ctx, valid); err != nil {
+		return stats, fmt.Errorf("failed to write remote write batch: %w", err)
+	}
+	written = true
+
+	for _, metric := range valid {
+		if metric.MType == models.Gauge {
+			stats.Gauges++
+		} else {
+			stats.Counters++
+		}
+	}
+	return stats, nil
+}
+
+// parse разбирает ряды запроса на gauge и counter под блокировкой: тип ряда зависит от метаданных
+// предыдущих запросов
+func (r *Receiver) parse(req *WriteRequest, authorize func(name string) error) (*Stats, *parsedRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, metadata := range req.Metadata {
+		if metadata.MetricFamilyName != "" {
+			r.types[metadata.MetricFamilyName] = metadata.Type
+		}
+	}
+
+	stats := &Stats{Series: len(req.Timeseries)}
+	parsed := &parsedRequest{
+		gauges:   make(map[string]float64),
+		counters: make(map[string]*counterSeries),
+	}
+
+	for i := range req.Timeseries {
+		series := &req.Timeseries[i]
+		stats.Samples += len(series.Samples)
+
+		name, err := MetricName(series.Labels)
+		if err != nil {
+			stats.fail("", fmt.Errorf("timeseries %d: %w", i, err))
+			continue
+		}
+		if len(series.Samples) == 0 && series.Histograms > 0 {
+			stats.fail(name, fmt.Errorf("native histograms are not supported"))
+			continue
+		}
+
+		samples, err := usableSamples(series.Samples)
+		if err != nil {
+			stats.fail(name, err)
+			continue
+		}
+		if len(samples) == 0 {
+			stats.Skipped++
+			continue
+		}
+
+		if authorize != nil {
+			if err := authorize(name); err != nil {
+				stats.fail(name, err)
+				continue
+			}
+		}
+
+		if r.isCounter(labelValue(series.Labels, NameLabel)) {
+			if err := checkCounterSamples(samples); err != nil {
+				stats.fail(name, err)
+				continue
+			}
+			counter, ok := parsed.counters[name]
+			if !ok {
+				counter = &counterSeries{name: name}
+				parsed.counters[name] = counter
+				parsed.counterNames = append(parsed.counterNames, name)
+			}
+			counter.samples = append(counter.samples, samples...)
+			continue
+		}
+
+		// Хранилище не хранит метки времени, поэтому gauge получает самое новое значение
+		if _, ok := parsed.gauges[name]; !ok {
+			parsed.gaugeNames = append(parsed.gaugeNames, name)
+		}
+		parsed.gauges[name] = samples[len(samples)-1].Value
+	}
+
+	return stats, parsed
+}
+
+// reserve резервирует состояния рядов counter за запросом, ожидая завершения других запросов
+// с теми же рядами. Все ряды резервируются одновременно, поэтому запросы не блокируют друг друга по кругу.
+func (r *Receiver) reserve(names []string) []*counterState {
+	if len(names) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for r.anyBusyUnsafe(names) {
+		r.released.Wait()
+	}
+
+	states := make([]*counterState, len(names))
+	for i, name := range names {
+		state, ok := r.counters[name]
+		if !ok {
+			state = &counterState{name: name}
+			state.element = r.order.PushBack(state)
+			r.counters[name] = state
+		} else {
+			r.order.MoveToBack(state.element)
+		}
+		state.busy = true
+		states[i] = state
+	}
+	return states
+}
+
+// anyBusyUnsafe проверяет, зарезервирован ли один из рядов другим запросом (вызывается под блокировкой)
+func (r *Receiver) anyBusyUnsafe(names []string) bool {
+	for _, name := range names {
+		if state, ok := r.counters[name]; ok && state.busy {
+			return true
+		}
+	}
+	return false
+}
+
+// release снимает резервирование. Состояние counter фиксируется только после успешной записи,
+// чтобы повтор запроса не терял прирост; ряды, так и не получившие значения, удаляются.
+func (r *Receiver) release(states []*counterState, pending map[string]float64, written bool) {
+	if len(states) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.nowFunc()
+	for _, state := range states {
+		state.busy = false
+		if last, ok := pending[state.name]; ok && written {
+			state.last, state.known, state.seen = last, true, now
+		}
+		if !state.known {
+			r.removeUnsafe(state)
+		}
+	}
+	r.evictUnsafe(now)
+	r.released.Broadcast()
+}
+
+// removeUnsafe удаляет состояние ряда (вызывается под блокировкой)
+func (r *Receiver) removeUnsafe(state *counterState) {
+	if r.counters[state.name] == state {
+		delete(r.counters, state.name)
+	}
+	r.order.Remove(state.element)
+}
+
+// evictUnsafe удаляет состояния рядов без отсчетов дольше seriesTTL и самые давние сверх maxSeries.
+// Зарезервированные ряды не вытесняются (вызывается под блокировкой).
+func (r *Receiver) evictUnsafe(now time.Time) {
+	for element := r.order.Front(); element != nil; {
+		next := element.Next()
+		state := element.Value.(*counterState)
+
+		expired := r.seriesTTL > 0 && now.Sub(state.seen) >= r.seriesTTL
+		overflow := r.maxSeries > 0 && len(r.counters) > r.maxSeries
+		if !expired && !overflow {
+			break
+		}
+		if !state.busy {
+			r.removeUnsafe(state)
+		}
+		element = next
+	}
+}
+
+// counterDelta вычисляет прирост counter по отсчетам и возвращает последнее накопленное значение.
+// Значение меньше предыдущего считается сбросом счетчика, как в функции rate() Prometheus.
+// Состояние зарезервировано запросом, поэтому читается без блокировки.
+func (r *Receiver) counterDelta(ctx context.Context, series *counterSeries, state *counterState) (int64, float64, error) {
+	// Ряды могут приходить не по порядку времени внутри запроса
+	sort.SliceStable(series.samples, func(i, j int) bool {
+		return series.samples[i].Timestamp < series.samples[j].Timestamp
+	})
+
+	last, known := state.last, state.known
+	var delta int64
+	for _, sample := range series.samples {
+		value := math.Round(sample.Value)
+		switch {
+		case !known:
+			// Первое значение ряда: счетчик metrical догоняет накопленное значение
+			stored, exists, err := r.writer.GetCounter(ctx, series.name)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to read counter %s: %w", series.name, err)
+			}
+			if exists && value >= float64(stored) {
+				delta += int64(value) - stored
+			} else {
+				delta += int64(value)
+			}
+		case value >= math.Round(last):
+			delta += int64(value - math.Round(last))
+		default:
+			delta += int64(value)
+		}
+		last, known = sample.Value, true
+	}
+	return delta, last, nil
+}
+
+// isCounter определяет тип ряда по метаданным семейства или по суффиксу _total
+func (r *Receiver) isCounter(name string) bool {
+	if metricType, ok := r.types[name]; ok {
+		return metricType == MetricTypeCounter
+	}
+
+	family, isTotal := strings.CutSuffix(name, "_total")
+	if !isTotal {
+		return false
+	}
+	if metricType, ok := r.types[family]; ok {
+		return metricType == MetricTypeCounter
+	}
+	return true
+}
+
+// MetricName строит имя метрики metrical из метки __name__ и остальных меток в порядке ключей:
+// http_requests_total{code="200",method="get"} -> http_requests_total.code=200.method=get.
+// Метки с пустым значением пропускаются, как и в Prometheus.
+func MetricName(labels []Label) (string, error) {
+	name := labelValue(labels, NameLabel)
+	if name == "" {
+		return "", fmt.Errorf("missing %s label", NameLabel)
+	}
+
+	rest := make([]Label, 0, len(labels))
+	for _, label := range labels {
+		if label.Name != NameLabel && label.Value != "" {
+			rest = append(rest, label)
+		}
+	}
+	slices.SortFunc(rest, func(a, b Label) int { return strings.Compare(a.Name, b.Name) })
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, label := range rest {
+		b.WriteByte('.')
+		b.WriteString(label.Name)
+		b.WriteByte('=')
+		b.WriteString(label.Value)
+	}
+	return b.String(), nil
+}
+
+// labelValue возвращает значение метки или пустую строку
+func labelValue(labels []Label, name string) string {
+	for _, label := range labels {
+		if label.Name == name {
+			return label.Value
+		}
+	}
+	return ""
+}
+
+// usableSamples возвращает отсчеты по порядку времени без stale маркеров (NaN)
+func usableSamples(samples []Sample) ([]Sample, error) {
+	result := make([]Sample, 0, len(samples))
+	for _, sample := range samples {
+		if math.IsNaN(sample.Value) {
+			continue
+		}
+		if math.IsInf(sample.Value, 0) {
+			return nil, fmt.Errorf("non-finite value %v", sample.Value)
+		}
+		result = append(result, sample)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Timestamp < result[j].Timestamp })
+	return result, nil
+}
+
+// checkCounterSamples проверяет, что значения counter помещаются в int64 и не отрицательны
+func checkCounterSamples(samples []Sample) error {
+	for _, sample := range samples {
+		if sample.Value < 0 || sample.Value >= math.MaxInt64 {
+			return fmt.Errorf("counter value %v is out of range", sample.Value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/repository"
+	"github.com/IgorKilipenko/metrical/internal/service"
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestReceiver создает приемник с хранилищем в памяти
+func newTestReceiver(t *testing.T) (*Receiver, *service.MetricsService) {
+	t.Helper()
+
+	mockLogger := testutils.NewMockLogger()
+	repo := repository.NewInMemoryMetricsRepository(mockLogger, "", false)
+	metricsService := service.NewMetricsService(repo, mockLogger)
+
+	receiver, err := NewReceiver(metricsService, mockLogger)
+	require.NoError(t, err)
+	return receiver, metricsService
+}
+
+// series создает временной ряд с метками в виде пар имя/значение
+func series(labels []string, samples ...Sample) TimeSeries {
+	result := TimeSeries{Samples: samples}
+	for i := 0; i+1 < len(labels); i += 2 {
+		result.Labels = append(result.Labels, Label{Name: labels[i], Value: labels[i+1]})
+	}
+	return result
+}
+
+// failingWriter хранилище, отклоняющее запись
+type failingWriter struct{}
+
+func (failingWriter) UpdateMetricsBatch(context.Context, []models.Metrics) error {
+	return models.ErrStorageUnavailable
+}
+
+func (failingWriter) GetCounter(context.Context, string) (int64, bool, error) {
+	return 0, false, nil
+}
+
+func TestMetricName(t *testing.T) {
+	name, err := MetricName([]Label{
+		{Name: "method", Value: "get"},
+		{Name: NameLabel, Value: "http_requests_total"},
+		{Name: "code", Value: "200"},
+		{Name: "empty", Value: ""},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "http_requests_total.code=200.method=get", name)
+
+	_, err = MetricName([]Label{{Name: "job", Value: "node"}})
+	assert.Error(t, err)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, DefaultConfig().Validate())
+	assert.NoError(t, (&Config{}).Validate(), "Zero disables limits")
+	assert.Error(t, (&Config{MaxBodySize: -1}).Validate())
+	assert.Error(t, (&Config{MaxDecodedSize: -1}).Validate())
+	assert.Error(t, (&Config{MaxSeries: -1}).Validate())
+	assert.Error(t, (&Config{SeriesTTL: -time.Second}).Validate())
+}
+
+func TestReceiver_Write_GaugesAndCounters(t *testing.T) {
+	receiver, metricsService := newTestReceiver(t)
+	ctx := context.Background()
+
+	req := &WriteRequest{Timeseries: []TimeSeries{
+		series([]string{NameLabel, "node_load1", "instance", "a"}, Sample{Value: 0.7, Timestamp: 2}, Sample{Value: 0.5, Timestamp: 1}),
+		series([]string{NameLabel, "http_requests_total", "code", "200"}, Sample{Value: 10, Timestamp: 1}, Sample{Value: 15, Timestamp: 2}),
+		series([]string{NameLabel, "up"}, Sample{Value: math.Float64frombits(0x7ff0000000000002), Timestamp: 3}),
+	}}
+
+	stats, err := receiver.Write(ctx, req, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &Stats{Series: 3, Samples: 5, Gauges: 1, Counters: 1, Skipped: 1}, stats)
+
+	load, _, err := metricsService.GetGauge(ctx, "node_load1.instance=a")
+	require.NoError(t, err)
+	assert.Equal(t, 0.7, load, "Newest sample wins")
+
+	requests, _, err := metricsService.GetCounter(ctx, "http_requests_total.code=200")
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), requests, "Counter mirrors cumulative value")
+
+	// Следующий запрос добавляет только прирост, а уменьшение считается сбросом
+	req = &WriteRequest{Timeseries: []TimeSeries{
+		series([]string{NameLabel, "http_requests_total", "code", "200"}, Sample{Value: 20, Timestamp: 3}, Sample{Value: 4, Timestamp: 4}),
+	}}
+	_, err = receiver.Write(ctx, req, nil)
+	require.NoError(t, err)
+
+	requests, _, err = metricsService.GetCounter(ctx, "http_requests_total.code=200")
+	require.NoError(t, err)
+	assert.Equal(t, int64(15+5+4), requests)
+}
+
+func TestReceiver_Write_FirstSampleAlignsWithStoredCounter(t *testing.T) {
+	receiver, metricsService := newTestReceiver(t)
+	ctx := context.Background()
+
+	delta := int64(100)
+	require.NoError(t, metricsService.UpdateMetricJSON(ctx, &models.Metrics{ID: "jobs_total", MType: models.Counter, Delta: &delta}))
+
+	req := &WriteRequest{Timeseries: []TimeSeries{series([]string{NameLabel, "jobs_total"}, Sample{Value: 130, Timestamp: 1})}}
+	_, err := receiver.Write(ctx, req, nil)
+	require.NoError(t, err)
+
+	jobs, _, err := metricsService.GetCounter(ctx, "jobs_total")
+	require.NoError(t, err)
+	assert.Equal(t, int64(130), jobs)
+}
+
+func TestReceiver_Write_Metadata(t *testing.T) {
+	receiver, metricsService := newTestReceiver(t)
+	ctx := context.Background()
+
+	// Метаданные приходят отдельным запросом и запоминаются
+	_, err := receiver.Write(ctx, &WriteRequest{Metadata: []MetricMetadata{
+		{Type: MetricTypeCounter, MetricFamilyName: "process_cpu_seconds"},
+		{Type: MetricTypeGauge, MetricFamilyName: "queue_total"},
+	}}, nil)
+	require.NoError(t, err)
+
+	req := &WriteRequest{Timeseries: []TimeSeries{
+		series([]string{NameLabel, "process_cpu_seconds"}, Sample{Value: 3, Timestamp: 1}),
+		series([]string{NameLabel, "queue_total"}, Sample{Value: 8, Timestamp: 1}),
+	}}
+	stats, err := receiver.Write(ctx, req, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Gauges)
+	assert.Equal(t, 1, stats.Counters)
+
+	_, exists, err := metricsService.GetCounter(ctx, "process_cpu_seconds")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	_, exists, err = metricsService.GetGauge(ctx, "queue_total")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestReceiver_Write_PartialFailures(t *testing.T) {
+	receiver, metricsService := newTestReceiver(t)
+	ctx := context.Background()
+
+	req := &WriteRequest{Timeseries: []TimeSeries{
+		series([]string{"job", "node"}, Sample{Value: 1}),
+		{Labels: []Label{{Name: NameLabel, Value: "latency"}}, Histograms: 1},
+		series([]string{NameLabel, "temp"}, Sample{Value: math.Inf(1)}),
+		series([]string{NameLabel, "debt_total"}, Sample{Value: -5}),
+		series([]string{NameLabel, "secret.value"}, Sample{Value: 1}),
+		series([]string{NameLabel, "app.ok"}, Sample{Value: 2}),
+	}}
+	authorize := func(name string) error {
+		if strings.HasPrefix(name, "secret.") {
+			return fmt.Errorf("metric is outside token prefix")
+		}
+		return nil
+	}
+
+	stats, err := receiver.Write(ctx, req, authorize)
+	require.NoError(t, err)
+	assert.Equal(t, 6, stats.Series)
+	assert.Equal(t, 1, stats.Gauges)
+	assert.Equal(t, 5, stats.Failed)
+	require.Len(t, stats.Errors, 5)
+	assert.Contains(t, stats.Errors[0], "missing __name__ label")
+	assert.Contains(t, stats.Errors[1], "native histograms")
+	assert.Contains(t, stats.Errors[4], "secret.value")
+
+	value, _, err := metricsService.GetGauge(ctx, "app.ok")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, value)
+}
+
+func TestReceiver_Write_StorageErrorKeepsCounterState(t *testing.T) {
+	receiver, err := NewReceiver(failingWriter{}, testutils.NewMockLogger())
+	require.NoError(t, err)
+
+	req := &WriteRequest{Timeseries: []TimeSeries{series([]string{NameLabel, "jobs_total"}, Sample{Value: 7})}}
+	_, err = receiver.Write(context.Background(), req, nil)
+	assert.True(t, errors.Is(err, models.ErrStorageUnavailable))
+	assert.Empty(t, receiver.counters, "Failed batch must not advance counter state")
+}
+
+func TestReceiver_Write_EvictsStaleSeries(t *testing.T) {
+	receiver, metricsService := newTestReceiver(t)
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	receiver.nowFunc = func() time.Time { return now }
+	receiver.SetLimits(2, time.Minute)
+
+	write := func(name string, value float64) {
+		t.Helper()
+		req := &WriteRequest{Timeseries: []TimeSeries{series([]string{NameLabel, name}, Sample{Value: value})}}
+		_, err := receiver.Write(ctx, req, nil)
+		require.NoError(t, err)
+	}
+
+	write("a_total", 10)
+	write("b_total", 10)
+	write("c_total", 10)
+	assert.Equal(t, 2, receiver.Len(), "Least recently written series is evicted over MaxSeries")
+
+	now = now.Add(2 * time.Minute)
+	write("d_total", 1)
+	assert.Equal(t, 1, receiver.Len(), "Series without samples longer than TTL are evicted")
+
+	// Вытесненный ряд сверяется с сохраненным счетчиком, поэтому прирост не удваивается
+	write("a_total", 15)
+	value, _, err := metricsService.GetCounter(ctx, "a_total")
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), value)
+}
+
+// blockingWriter задерживает запись до закрытия unblock
+type blockingWriter struct {
+	MetricsWriter
+	started chan struct{}
+	unblock chan struct{}
+	once    sync.Once
+}
+
+func (w *blockingWriter) UpdateMetricsBatch(ctx context.Context, metrics []models.Metrics) error {
+	if metrics[0].ID == "slow_total" {
+		w.once.Do(func() { close(w.started) })
+		<-w.unblock
+	}
+	return w.MetricsWriter.UpdateMetricsBatch(ctx, metrics)
+}
+
+func TestReceiver_Write_StorageWriteOutsideLock(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repo := repository.NewInMemoryMetricsRepository(mockLogger, "", false)
+	metricsService := service.NewMetricsService(repo, mockLogger)
+	writer := &blockingWriter{MetricsWriter: metricsService, started: make(chan struct{}), unblock: make(chan struct{})}
+	receiver, err := NewReceiver(writer, mockLogger)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	request := func(name string, value float64) *WriteRequest {
+		return &WriteRequest{Timeseries: []TimeSeries{series([]string{NameLabel, name}, Sample{Value: value})}}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, err := receiver.Write(ctx, request("slow_total", 10), nil)
+		assert.NoError(t, err)
+	}()
+	<-writer.started
+
+	// Запрос с другим рядом не ждет медленную запись
+	_, err = receiver.Write(ctx, request("fast_total", 5), nil)
+	require.NoError(t, err)
+
+	// Запрос с тем же рядом ждет фиксации состояния и записывает только прирост
+	go func() {
+		defer wg.Done()
+		_, err := receiver.Write(ctx, request("slow_total", 12), nil)
+		assert.NoError(t, err)
+	}()
+	close(writer.unblock)
+	wg.Wait()
+
+	value, _, err := metricsService.GetCounter(ctx, "slow_total")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), value)
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/logger"
+	models "github.com/IgorKilipenko/metrical/internal/model"
+)
+
+// MetricsHistory интерфейс хранилища истории значений метрик
+type MetricsHistory interface {
+	// GetHistory возвращает значения метрики в интервале [from, to] в порядке возрастания времени.
+	// Второе значение сообщает, есть ли у метрики история.
+	GetHistory(ctx context.Context, metricType, name string, from, to time.Time) ([]models.Sample, bool, error)
+}
+
+// HistoryConfig настройки хранения истории
+type HistoryConfig struct {
+	Retention  time.Duration // Максимальный возраст значения
+	MaxSamples int           // Максимальное количество значений на метрику
+}
+
+// DefaultHistoryConfig возвращает настройки истории по умолчанию
+func DefaultHistoryConfig() *HistoryConfig {
+	return &HistoryConfig{
+		Retention:  time.Hour,
+		MaxSamples: 3600,
+	}
+}
+
+// Validate проверяет корректность настроек истории
+func (c *HistoryConfig) Validate() error {
+	if c.Retention <= 0 {
+		return fmt.Errorf("history retention must be positive")
+	}
+	if c.MaxSamples <= 0 {
+		return fmt.Errorf("history size must be positive")
+	}
+	return nil
+}
+
+// historyKey ключ истории метрики
+type historyKey struct {
+	mType string
+	name  string
+}
+
+// HistoryRepository декоратор репозитория, сохраняющий историю значений метрик в памяти.
+// Для gauge сохраняется записанное значение, для counter - накопленное значение после обновления.
+type HistoryRepository struct {
+	MetricsRepository
+	config  HistoryConfig
+	series  map[historyKey]*sampleRing
+	mu      sync.RWMutex
+	logger  logger.Logger
+	nowFunc func() time.Time // Источник времени (подменяется в тестах)
+}
+
+// NewHistoryRepository создает репозиторий с историей поверх существующего
+func NewHistoryRepository(repository MetricsRepository, config *HistoryConfig, logger logger.Logger) (*HistoryRepository, error) {
+	if repository == nil {
+		return nil, fmt.Errorf("repository cannot be nil")
+	}
+	if config == nil {
+		config = DefaultHistoryConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &HistoryRepository{
+		MetricsRepository: repository,
+		config:            *config,
+		series:            make(map[historyKey]*sampleRing),
+		logger:            logger,
+		nowFunc:           time.Now,
+	}, nil
+}
+
+// UpdateGauge обновляет gauge метрику и добавляет значение в историю
+func (r *HistoryRepository) UpdateGauge(ctx context.Context, name string, value float64) error {
+	if err := r.MetricsRepository.UpdateGauge(ctx, name, value); err != nil {
+		return err
+	}
+
+	r.record(models.Gauge, name, models.Sample{Value: &value})
+	return nil
+}
+
+// UpdateCounter обновляет counter метрику и добавляет накопленное значение в историю
+func (r *HistoryRepository) UpdateCounter(ctx context.Context, name string, value int64) error {
+	if err := r.MetricsRepository.UpdateCounter(ctx, name, value); err != nil {
+		return err
+	}
+
+	total, exists, err := r.MetricsRepository.GetCounter(ctx, name)
+	if err != nil || !exists {
+		// Обновление уже выполнено - отсутствие значения в истории не ошибка запроса
+		r.logger.Warn("failed to read counter total for history", "name", name, "error", err)
+		return nil
+	}
+
+	r.record(models.Counter, name, models.Sample{Delta: &total})
+	return nil
+}
+
+// UpdateBatch применяет пакет метрик и добавляет значения в историю.
+// Для counter в историю попадает накопленное значение после применения пакета.
+func (r *HistoryRepository) UpdateBatch(ctx context.Context, metrics []models.Metrics) error {
+	if err := r.MetricsRepository.UpdateBatch(ctx, metrics); err != nil {
+		return err
+	}
+
+	recorded := make(map[string]bool)
+	for _, metric := range metrics {
+		switch metric.MType {
+		case models.Gauge:
+			value := *metric.Value
+			r.record(models.Gauge, metric.ID, models.Sample{Value: &value})
+		case models.Counter:
+			// Несколько приращений одного счетчика в пакете дают одно значение истории
+			if recorded[metric.ID] {
+				continue
+			}
+			recorded[metric.ID] = true
+
+			total, exists, err := r.MetricsRepository.GetCounter(ctx, metric.ID)
+			if err != nil || !exists {
+				r.logger.Warn("failed to read counter total for history", "name", metric.ID, "error", err)
+				continue
+			}
+			r.record(models.Counter, metric.ID, models.Sample{Delta: &total})
+		}
+	}
+
+	return nil
+}
+
+// record добавляет значение в историю метрики
+func (r *HistoryRepository) record(metricType, name string, sample models.Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.nowFunc()
+	sample.Timestamp = now
+
+	key := historyKey{mType: metricType, name: name}
+	ring, ok := r.series[key]
+	if !ok {
+		ring = newSampleRing(r.config.MaxSamples)
+		r.series[key] = ring
+	}
+
+	ring.Push(sample)
+	ring.DropBefore(now.Add(-r.config.Retention))
+}
+
+// GetHistory возвращает историю метрики в интервале [from, to]
+func (r *HistoryRepository) GetHistory(ctx context.Context, metricType, name string, from, to time.Time) ([]models.Sample, bool, error) {
+	// Проверяем отмену контекста
+	select {
+	case <-ctx.Done():
+		r.logger.Debug("context cancelled during history retrieval", "type", metricType, "name", name)
+		return nil, false, ctx.Err()
+	default:
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ring, ok := r.series[historyKey{mType: metricType, name: name}]
+	if !ok {
+		return nil, false, nil
+	}
+
+	// Значения старше срока хранения не возвращаем, даже если они еще не вытеснены
+	if cutoff := r.nowFunc().Add(-r.config.Retention); from.Before(cutoff) {
+		from = cutoff
+	}
+
+	samples := ring.Range(from, to)
+	r.logger.Debug("retrieved metric history", "type", metricType, "name", name, "count", len(samples))
+	return samples, true, nil
+}
+
+// initialSampleRingSize начальный размер буфера значений одной метрики
+const initialSampleRingSize = 16
+
+// sampleRing кольцевой буфер значений ограниченного размера.
+// Буфер растет по мере поступления значений и начинает вытеснять старые только при
+// достижении емкости, поэтому редко обновляемые метрики не занимают MaxSamples значений.
+type sampleRing struct {
+	buf      []models.Sample
+	capacity int // Максимальное количество значений
+	start    int // Индекс самого старого значения
+	size     int // Количество значений в буфере
+}
+
+// newSampleRing создает кольцевой буфер заданной емкости
+func newSampleRing(capacity int) *sampleRing {
+	return &sampleRing{
+		buf:      make([]models.Sample, min(capacity, initialSampleRingSize)),
+		capacity: capacity,
+	}
+}
+
+// grow увеличивает буфер вдвое (не больше емкости), раскладывая значения по порядку
+func (s *sampleRing) grow() {
+	buf := make([]models.Sample, min(max(2*len(s.buf), initialSampleRingSize), s.capacity))
+	for i := 0; i < s.size; i++ {
+		buf[i] = s.at(i)
+	}
+	s.buf = buf
+	s.start = 0
+}
+
+// at возвращает i-е по возрасту значение
+func (s *sampleRing) at(i int) models.Sample {
+	return s.buf[(s.start+i)%len(s.buf)]
+}
+
+// Push добавляет значение, вытесняя самое старое при заполнении буфера
+func (s *sampleRing) Push(sample models.Sample) {
+	if s.size < s.capacity {
+		if s.size == len(s.buf) {
+			s.grow()
+		}
+		s.buf[(s.start+s.size)%len(s.buf)] = sample
+		s.size++
+		return
+	}
+
+	s.buf[s.start] = sample
+	s.start = (s.start + 1) % len(s.buf)
+}
+
+// DropBefore удаляет значения старше cutoff
+func (s *sampleRing) DropBefore(cutoff time.Time) {
+	for s.size > 0 && s.at(0).Timestamp.Before(cutoff) {
+		s.buf[s.start] = models.Sample{}
+		s.start = (s.start + 1) % len(s.buf)
+		s.size--
+	}
+}
+
+// Range возвращает копию значений в интервале [from, to]
+func (s *sampleRing) Range(from, to time.Time) []models.Sample {
+	result := make([]models.Sample, 0)
+	for i := 0; i < s.size; i++ {
+		sample := s.at(i)
+		if sample.Timestamp.Before(from) || sample.Timestamp.After(to) {
+			continue
+		}
+		result = append(result, sample)
+	}
+	return result
+}
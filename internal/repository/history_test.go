@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClock управляемый источник времени для тестов истории
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newTestHistoryRepository создает репозиторий с историей и управляемым временем
+func newTestHistoryRepository(t *testing.T, config *HistoryConfig) (*HistoryRepository, *testClock) {
+	t.Helper()
+
+	inner := NewInMemoryMetricsRepository(testutils.NewMockLogger(), testutils.TestMetricsFile, false)
+	repo, err := NewHistoryRepository(inner, config, testutils.NewMockLogger())
+	require.NoError(t, err)
+
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	repo.nowFunc = clock.Now
+	return repo, clock
+}
+
+func TestHistoryRepository_RecordsSamples(t *testing.T) {
+	repo, clock := newTestHistoryRepository(t, nil)
+	ctx := context.Background()
+	start := clock.Now()
+
+	require.NoError(t, repo.UpdateGauge(ctx, "HeapAlloc", 100))
+	clock.Advance(time.Second)
+	require.NoError(t, repo.UpdateGauge(ctx, "HeapAlloc", 200))
+	require.NoError(t, repo.UpdateCounter(ctx, "PollCount", 5))
+	clock.Advance(time.Second)
+	require.NoError(t, repo.UpdateCounter(ctx, "PollCount", 3))
+
+	// Последние значения по-прежнему доступны через исходный репозиторий
+	value, exists, err := repo.GetGauge(ctx, "HeapAlloc")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 200.0, value)
+
+	gauges, exists, err := repo.GetHistory(ctx, models.Gauge, "HeapAlloc", start, clock.Now())
+	require.NoError(t, err)
+	assert.True(t, exists)
+	require.Len(t, gauges, 2)
+	assert.Equal(t, 100.0, *gauges[0].Value)
+	assert.Equal(t, 200.0, *gauges[1].Value)
+	assert.Equal(t, start, gauges[0].Timestamp)
+
+	// Для counter сохраняется накопленное значение
+	counters, exists, err := repo.GetHistory(ctx, models.Counter, "PollCount", start, clock.Now())
+	require.NoError(t, err)
+	assert.True(t, exists)
+	require.Len(t, counters, 2)
+	assert.Equal(t, int64(5), *counters[0].Delta)
+	assert.Equal(t, int64(8), *counters[1].Delta)
+}
+
+func TestHistoryRepository_Range(t *testing.T) {
+	repo, clock := newTestHistoryRepository(t, nil)
+	ctx := context.Background()
+	start := clock.Now()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, repo.UpdateGauge(ctx, "HeapAlloc", float64(i)))
+		clock.Advance(time.Minute)
+	}
+
+	samples, _, err := repo.GetHistory(ctx, models.Gauge, "HeapAlloc", start.Add(time.Minute), start.Add(3*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, samples, 3, "Range bounds should be inclusive")
+	assert.Equal(t, 1.0, *samples[0].Value)
+	assert.Equal(t, 3.0, *samples[2].Value)
+
+	_, exists, err := repo.GetHistory(ctx, models.Counter, "HeapAlloc", start, clock.Now())
+	require.NoError(t, err)
+	assert.False(t, exists, "History is kept per metric type")
+}
+
+func TestHistoryRepository_MaxSamples(t *testing.T) {
+	repo, clock := newTestHistoryRepository(t, &HistoryConfig{Retention: time.Hour, MaxSamples: 3})
+	ctx := context.Background()
+	start := clock.Now()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, repo.UpdateGauge(ctx, "HeapAlloc", float64(i)))
+		clock.Advance(time.Second)
+	}
+
+	samples, _, err := repo.GetHistory(ctx, models.Gauge, "HeapAlloc", start, clock.Now())
+	require.NoError(t, err)
+	require.Len(t, samples, 3, "Oldest samples should be evicted")
+	assert.Equal(t, 2.0, *samples[0].Value)
+	assert.Equal(t, 4.0, *samples[2].Value)
+}
+
+func TestHistoryRepository_Retention(t *testing.T) {
+	repo, clock := newTestHistoryRepository(t, &HistoryConfig{Retention: 10 * time.Minute, MaxSamples: 100})
+	ctx := context.Background()
+	start := clock.Now()
+
+	require.NoError(t, repo.UpdateGauge(ctx, "HeapAlloc", 1))
+	clock.Advance(5 * time.Minute)
+	require.NoError(t, repo.UpdateGauge(ctx, "HeapAlloc", 2))
+	clock.Advance(6 * time.Minute)
+
+	// Первое значение старше срока хранения, хотя еще не вытеснено новой записью
+	samples, _, err := repo.GetHistory(ctx, models.Gauge, "HeapAlloc", start, clock.Now())
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, 2.0, *samples[0].Value)
+
+	clock.Advance(5 * time.Minute)
+	require.NoError(t, repo.UpdateGauge(ctx, "HeapAlloc", 3))
+
+	samples, _, err = repo.GetHistory(ctx, models.Gauge, "HeapAlloc", start, clock.Now())
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, 3.0, *samples[0].Value)
+}
+
+func TestHistoryRepository_FailedUpdateNotRecorded(t *testing.T) {
+	repo, clock := newTestHistoryRepository(t, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Error(t, repo.UpdateGauge(ctx, "HeapAlloc", 1))
+
+	_, exists, err := repo.GetHistory(context.Background(), models.Gauge, "HeapAlloc", clock.Now().Add(-time.Hour), clock.Now())
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestNewHistoryRepository_Validation(t *testing.T) {
+	inner := NewInMemoryMetricsRepository(testutils.NewMockLogger(), testutils.TestMetricsFile, false)
+
+	_, err := NewHistoryRepository(nil, nil, testutils.NewMockLogger())
+	assert.Error(t, err)
+
+	_, err = NewHistoryRepository(inner, &HistoryConfig{Retention: 0, MaxSamples: 10}, testutils.NewMockLogger())
+	assert.Error(t, err)
+
+	_, err = NewHistoryRepository(inner, &HistoryConfig{Retention: time.Minute, MaxSamples: 0}, testutils.NewMockLogger())
+	assert.Error(t, err)
+}
+
+func TestHistoryRepository_UpdateBatch(t *testing.T) {
+	repo, clock := newTestHistoryRepository(t, nil)
+	ctx := context.Background()
+	start := clock.Now()
+
+	value := 42.0
+	delta := int64(3)
+	require.NoError(t, repo.UpdateBatch(ctx, []models.Metrics{
+		{ID: "HeapAlloc", MType: models.Gauge, Value: &value},
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+		{ID: "PollCount", MType: models.Counter, Delta: &delta},
+	}))
+
+	gauges, _, err := repo.GetHistory(ctx, models.Gauge, "HeapAlloc", start, clock.Now())
+	require.NoError(t, err)
+	require.Len(t, gauges, 1)
+	assert.Equal(t, 42.0, *gauges[0].Value)
+
+	counters, _, err := repo.GetHistory(ctx, models.Counter, "PollCount", start, clock.Now())
+	require.NoError(t, err)
+	require.Len(t, counters, 1, "Repeated counter in a batch should produce a single sample")
+	assert.Equal(t, int64(6), *counters[0].Delta)
+}
+
+func TestSampleRing_GrowsOnDemand(t *testing.T) {
+	ring := newSampleRing(40)
+	assert.Len(t, ring.buf, initialSampleRingSize, "Ring should not allocate full capacity upfront")
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	push := func(i int) {
+		value := float64(i)
+		ring.Push(models.Sample{Timestamp: start.Add(time.Duration(i) * time.Second), Value: &value})
+	}
+
+	// Сдвигаем начало буфера до роста, чтобы значения при расширении были разложены заново
+	for i := 0; i < 10; i++ {
+		push(i)
+	}
+	ring.DropBefore(start.Add(5 * time.Second))
+	for i := 10; i < 50; i++ {
+		push(i)
+	}
+
+	assert.Len(t, ring.buf, 40, "Ring should not grow beyond capacity")
+	samples := ring.Range(start, start.Add(time.Hour))
+	require.Len(t, samples, 40)
+	for i, sample := range samples {
+		assert.Equal(t, float64(i+10), *sample.Value, "Samples should stay in order")
+	}
+}
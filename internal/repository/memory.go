@@ -2,13 +2,15 @@ package repository
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/IgorKilipenko/metrical/internal/logger"
 	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/validation"
 )
 
 // InMemoryMetricsRepository реализация репозитория в памяти
@@ -17,9 +19,12 @@ type InMemoryMetricsRepository struct {
 	Counters        models.CounterMetrics
 	mu              sync.RWMutex // Мьютекс для потокобезопасности
 	logger          logger.Logger
-	fileStoragePath string // Путь к файлу для сохранения/загрузки метрик
-	restore         bool   // Флаг для восстановления метрик из файла
-	syncSave        bool   // Флаг для синхронного сохранения при каждом обновлении
+	fileStoragePath string     // Путь к файлу для сохранения/загрузки метрик
+	restore         bool       // Флаг для восстановления метрик из файла
+	syncSave        bool       // Флаг для синхронного сохранения при каждом обновлении
+	wal             *walWriter // Журнал упреждающей записи (nil, если режим WAL выключен)
+	walSeq          uint64     // Номер последней записи журнала (сохраняется в снапшоте)
+	snapshotKeep    int        // Количество хранимых снапшотов (текущий + ротированные)
 }
 
 // NewInMemoryMetricsRepository создает новый экземпляр InMemoryMetricsRepository
@@ -31,6 +36,7 @@ func NewInMemoryMetricsRepository(logger logger.Logger, fileStoragePath string,
 		fileStoragePath: fileStoragePath,
 		restore:         restore,
 		syncSave:        false, // По умолчанию синхронное сохранение отключено
+		snapshotKeep:    DefaultSnapshotRetention,
 	}
 	if restore {
 		if err := repo.LoadFromFile(); err != nil {
@@ -47,6 +53,128 @@ func (r *InMemoryMetricsRepository) SetSyncSave(sync bool) {
 	r.syncSave = sync
 }
 
+// SetSnapshotRetention устанавливает количество хранимых снапшотов (текущий + ротированные).
+// Значения меньше 1 приводятся к 1 - хранится только текущий снапшот.
+func (r *InMemoryMetricsRepository) SetSnapshotRetention(keep int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if keep < 1 {
+		keep = 1
+	}
+	r.snapshotKeep = keep
+}
+
+// EnableWAL включает режим журнала упреждающей записи.
+// В этом режиме каждое обновление дописывает одну запись в журнал вместо перезаписи всего файла,
+// а SaveToFile выполняет контрольную точку: сохраняет снапшот и начинает новый журнал,
+// сдвигая прежний в сегменты вместе с ротированными снапшотами.
+func (r *InMemoryMetricsRepository) EnableWAL() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wal != nil {
+		return nil
+	}
+	if r.fileStoragePath == "" {
+		return fmt.Errorf("file storage path is required for WAL mode")
+	}
+
+	wal, err := openWAL(walPath(r.fileStoragePath))
+	if err != nil {
+		return err
+	}
+	r.wal = wal
+
+	r.logger.Info("write-ahead log enabled", "path", walPath(r.fileStoragePath))
+	return nil
+}
+
+// Close освобождает ресурсы репозитория (файл журнала)
+func (r *InMemoryMetricsRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.wal == nil {
+		return nil
+	}
+	err := r.wal.Close()
+	r.wal = nil
+	return err
+}
+
+// undoUnsafe запоминает текущие значения метрик и возвращает функцию, восстанавливающую их.
+// Используется для отката обновления в памяти, если его не удалось сохранить на диск:
+// иначе повтор запроса после ошибки применил бы приращение counter дважды
+// (вызывается под блокировкой записи).
+func (r *InMemoryMetricsRepository) undoUnsafe(metrics ...models.Metrics) func() {
+	gauges := make(map[string]*float64) // nil - метрики не было
+	counters := make(map[string]*int64)
+	for _, metric := range metrics {
+		switch metric.MType {
+		case models.Gauge:
+			if _, seen := gauges[metric.ID]; !seen {
+				gauges[metric.ID] = nil
+				if value, ok := r.Gauges[metric.ID]; ok {
+					gauges[metric.ID] = &value
+				}
+			}
+		case models.Counter:
+			if _, seen := counters[metric.ID]; !seen {
+				counters[metric.ID] = nil
+				if value, ok := r.Counters[metric.ID]; ok {
+					counters[metric.ID] = &value
+				}
+			}
+		}
+	}
+
+	return func() {
+		for name, value := range gauges {
+			if value == nil {
+				delete(r.Gauges, name)
+			} else {
+				r.Gauges[name] = *value
+			}
+		}
+		for name, value := range counters {
+			if value == nil {
+				delete(r.Counters, name)
+			} else {
+				r.Counters[name] = *value
+			}
+		}
+	}
+}
+
+// persistUnsafe фиксирует обновления на диске в соответствии с режимом сохранения
+// (вызывается под блокировкой записи)
+func (r *InMemoryMetricsRepository) persistUnsafe(records ...models.Metrics) error {
+	if r.wal != nil {
+		walRecords := make([]walRecord, len(records))
+		for i, record := range records {
+			walRecords[i] = walRecord{Seq: r.walSeq + uint64(i) + 1, Metrics: record}
+		}
+		if err := r.wal.Append(walRecords...); err != nil {
+			r.logger.Error("failed to append metric to WAL", "error", err)
+			return fmt.Errorf("failed to append metric to WAL: %w", err)
+		}
+		r.walSeq += uint64(len(records))
+		return nil
+	}
+
+	// Синхронное сохранение, если включено
+	if r.syncSave {
+		if err := r.saveToFileUnsafe(); err != nil {
+			r.logger.Error("failed to save metrics synchronously", "error", err)
+			return fmt.Errorf("failed to save metrics synchronously: %w", err)
+		}
+		r.logger.Debug("metrics saved synchronously", "updated", len(records))
+	}
+
+	return nil
+}
+
 // UpdateGauge обновляет значение gauge метрики
 func (r *InMemoryMetricsRepository) UpdateGauge(ctx context.Context, name string, value float64) error {
 	// Проверяем отмену контекста
@@ -60,6 +188,9 @@ func (r *InMemoryMetricsRepository) UpdateGauge(ctx context.Context, name string
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	record := models.Metrics{ID: name, MType: models.Gauge, Value: &value}
+	undo := r.undoUnsafe(record)
+
 	oldValue, exists := r.Gauges[name]
 	r.Gauges[name] = value
 
@@ -69,15 +200,10 @@ func (r *InMemoryMetricsRepository) UpdateGauge(ctx context.Context, name string
 		r.logger.Debug("created new gauge metric", "name", name, "value", value)
 	}
 
-	// Синхронное сохранение, если включено
-	if r.syncSave {
-		if err := r.saveToFileUnsafe(); err != nil {
-			r.logger.Error("failed to save metrics synchronously", "error", err)
-			return fmt.Errorf("failed to save metrics synchronously: %w", err)
-		}
-		r.logger.Debug("metrics saved synchronously after gauge update")
+	if err := r.persistUnsafe(record); err != nil {
+		undo()
+		return err
 	}
-
 	return nil
 }
 
@@ -94,20 +220,58 @@ func (r *InMemoryMetricsRepository) UpdateCounter(ctx context.Context, name stri
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	record := models.Metrics{ID: name, MType: models.Counter, Delta: &value}
+	undo := r.undoUnsafe(record)
+
 	oldValue := r.Counters[name]
 	r.Counters[name] += value
 
 	r.logger.Debug("updated counter metric", "name", name, "added_value", value, "old_total", oldValue, "new_total", r.Counters[name])
 
-	// Синхронное сохранение, если включено
-	if r.syncSave {
-		if err := r.saveToFileUnsafe(); err != nil {
-			r.logger.Error("failed to save metrics synchronously", "error", err)
-			return fmt.Errorf("failed to save metrics synchronously: %w", err)
+	if err := r.persistUnsafe(record); err != nil {
+		undo()
+		return err
+	}
+	return nil
+}
+
+// UpdateBatch применяет пакет метрик под одной блокировкой с одним сохранением на диск.
+// Пакет валидируется целиком до применения: при ошибке валидации или сохранения ни одна метрика не изменяется.
+func (r *InMemoryMetricsRepository) UpdateBatch(ctx context.Context, metrics []models.Metrics) error {
+	// Проверяем отмену контекста
+	select {
+	case <-ctx.Done():
+		r.logger.Debug("context cancelled during batch update", "count", len(metrics))
+		return ctx.Err()
+	default:
+	}
+
+	if err := validation.ValidateMetricsBatch(metrics); err != nil {
+		r.logger.Debug("batch validation failed", "count", len(metrics), "error", err)
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	undo := r.undoUnsafe(metrics...)
+	records := make([]models.Metrics, 0, len(metrics))
+	for _, metric := range metrics {
+		switch metric.MType {
+		case models.Gauge:
+			r.Gauges[metric.ID] = *metric.Value
+			records = append(records, models.Metrics{ID: metric.ID, MType: models.Gauge, Value: metric.Value})
+		case models.Counter:
+			r.Counters[metric.ID] += *metric.Delta
+			records = append(records, models.Metrics{ID: metric.ID, MType: models.Counter, Delta: metric.Delta})
 		}
-		r.logger.Debug("metrics saved synchronously after counter update")
 	}
 
+	r.logger.Debug("applied metrics batch", "count", len(records))
+	if err := r.persistUnsafe(records...); err != nil {
+		undo()
+		return err
+	}
 	return nil
 }
 
@@ -203,10 +367,12 @@ func (r *InMemoryMetricsRepository) GetAllCounters(ctx context.Context) (models.
 	return result, nil
 }
 
-// SaveToFile сохраняет все метрики в файл
+// SaveToFile сохраняет все метрики в файл.
+// В режиме WAL это контрольная точка: после записи снапшота начинается новый журнал.
+// Блокировка записи нужна, чтобы между снапшотом и очисткой журнала не попали новые записи.
 func (r *InMemoryMetricsRepository) SaveToFile() error {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	return r.saveToFileUnsafe()
 }
 
@@ -233,39 +399,79 @@ func (r *InMemoryMetricsRepository) saveToFileUnsafe() error {
 		})
 	}
 
-	// Кодируем в JSON
-	data, err := json.MarshalIndent(metrics, "", "  ")
+	// Кодируем снапшот с заголовком (версия, время, номер записи журнала, контрольная сумма).
+	// Номер журнала позволяет при восстановлении пропустить уже учтенные записи,
+	// если процесс упал между записью снапшота и очисткой журнала.
+	data, err := encodeSnapshot(metrics, time.Now(), r.walSeq)
 	if err != nil {
-		return fmt.Errorf("failed to marshal metrics to JSON: %w", err)
+		return err
 	}
 
-	// Записываем в файл
-	if err := os.WriteFile(r.fileStoragePath, data, 0644); err != nil {
+	// Атомарно записываем файл, сохраняя предыдущие снапшоты.
+	// Журнал сдвигается только после того, как новый снапшот надежно записан.
+	if err := writeSnapshotAtomic(r.fileStoragePath, data, r.snapshotKeep); err != nil {
 		return fmt.Errorf("failed to write metrics to file: %w", err)
 	}
 
+	// Записи журнала теперь содержатся в снапшоте, но нужны ротированным снапшотам
+	if err := r.rotateWALUnsafe(); err != nil {
+		return err
+	}
+
 	r.logger.Debug("metrics saved to file", "path", r.fileStoragePath, "count", len(metrics))
 	return nil
 }
 
-// LoadFromFile загружает метрики из файла
-func (r *InMemoryMetricsRepository) LoadFromFile() error {
-	// Проверяем существование файла
-	if _, err := os.Stat(r.fileStoragePath); os.IsNotExist(err) {
-		r.logger.Debug("metrics file does not exist, skipping load", "path", r.fileStoragePath)
-		return nil
+// rotateWALUnsafe сдвигает сегменты журнала вслед за снапшотами и открывает новый журнал
+// (вызывается под блокировкой записи)
+func (r *InMemoryMetricsRepository) rotateWALUnsafe() error {
+	if r.wal != nil {
+		if err := r.wal.Close(); err != nil {
+			return fmt.Errorf("failed to close WAL file: %w", err)
+		}
+	}
+
+	rotateErr := rotateWALSegments(r.fileStoragePath, r.snapshotKeep)
+
+	if r.wal != nil {
+		wal, err := openWAL(walPath(r.fileStoragePath))
+		if err != nil {
+			return errors.Join(rotateErr, err)
+		}
+		r.wal = wal
 	}
+	return rotateErr
+}
 
-	// Читаем файл
-	data, err := os.ReadFile(r.fileStoragePath)
+// LoadFromFile загружает метрики из файла и применяет поверх них журнал упреждающей записи.
+// Записи журнала с номером не больше сохраненного в снапшоте уже учтены в нем и пропускаются.
+// Если основной снапшот поврежден, используется самый свежий корректный из ротированных,
+// а поверх него применяются сохраненные сегменты журнала. Если записей журнала для такого
+// восстановления не хватает, потерянный диапазон пишется в лог с уровнем error.
+// Оборванная последняя запись журнала отбрасывается, а сам журнал усекается до корректной части.
+func (r *InMemoryMetricsRepository) LoadFromFile() error {
+	// Ищем самый свежий корректный снапшот (основной файл, затем ротированные)
+	snapshot, err := loadNewestValidSnapshot(r.fileStoragePath)
 	if err != nil {
-		return fmt.Errorf("failed to read metrics file: %w", err)
+		return fmt.Errorf("failed to load metrics snapshot: %w", err)
 	}
+	metrics := snapshot.Metrics
 
-	// Декодируем JSON
-	var metrics []models.Metrics
-	if err := json.Unmarshal(data, &metrics); err != nil {
-		return fmt.Errorf("failed to unmarshal metrics from JSON: %w", err)
+	if snapshot.Path == "" {
+		r.logger.Debug("metrics file does not exist, skipping snapshot load", "path", r.fileStoragePath)
+	}
+
+	// Читаем сегменты журнала и журнал, записанный после последнего снапшота
+	history, err := readWALHistory(r.fileStoragePath)
+	if err != nil {
+		return err
+	}
+	replay := history.Current
+	if replay.Torn {
+		r.logger.Warn("discarding torn last WAL record", "path", walPath(r.fileStoragePath), "valid_size", replay.ValidSize)
+		if err := os.Truncate(walPath(r.fileStoragePath), replay.ValidSize); err != nil {
+			return fmt.Errorf("failed to truncate torn WAL record: %w", err)
+		}
 	}
 
 	// Очищаем текущие метрики
@@ -289,6 +495,50 @@ func (r *InMemoryMetricsRepository) LoadFromFile() error {
 		}
 	}
 
-	r.logger.Debug("metrics loaded from file", "path", r.fileStoragePath, "count", len(metrics))
+	// Применяем журнал: gauge замещается, counter накапливается.
+	// Записи, уже учтенные в снапшоте, пропускаются; пропуск номеров означает потерю обновлений.
+	r.walSeq = snapshot.WALSeq
+	applied := 0
+	var gaps []walGap
+	for _, record := range history.Records {
+		if record.Seq != 0 {
+			if record.Seq <= r.walSeq {
+				continue
+			}
+			if record.Seq > r.walSeq+1 {
+				gaps = append(gaps, walGap{From: r.walSeq + 1, To: record.Seq - 1})
+			}
+			r.walSeq = record.Seq
+		}
+		applied++
+
+		switch record.MType {
+		case models.Gauge:
+			r.Gauges[record.ID] = *record.Value
+		case models.Counter:
+			r.Counters[record.ID] += *record.Delta
+		}
+	}
+
+	for _, gap := range gaps {
+		r.logger.Error("WAL records are missing, updates in this range are lost",
+			"from_seq", gap.From, "to_seq", gap.To, "snapshot", snapshot.Path)
+	}
+	if len(snapshot.Skipped) > 0 {
+		switch {
+		case history.Files == 0:
+			r.logger.Error("newest snapshot is corrupted, restored from older snapshot without WAL: updates saved after it are lost",
+				"path", snapshot.Path, "lost_after", snapshot.Timestamp, "skipped", snapshot.Skipped)
+		case len(gaps) > 0:
+			r.logger.Error("newest snapshot is corrupted, restored from older snapshot with incomplete WAL",
+				"path", snapshot.Path, "snapshot_time", snapshot.Timestamp, "skipped", snapshot.Skipped)
+		default:
+			r.logger.Warn("newest snapshot is corrupted, restored from older snapshot and WAL segments",
+				"path", snapshot.Path, "wal_from_seq", snapshot.WALSeq+1, "wal_to_seq", r.walSeq, "skipped", snapshot.Skipped)
+		}
+	}
+
+	r.logger.Debug("metrics loaded from file", "path", snapshot.Path, "count", len(metrics),
+		"wal_records", applied, "wal_skipped", len(history.Records)-applied)
 	return nil
 }
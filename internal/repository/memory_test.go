@@ -2,9 +2,12 @@ package repository
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	models "github.com/IgorKilipenko/metrical/internal/model"
 	"github.com/IgorKilipenko/metrical/internal/testutils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -225,3 +228,131 @@ func TestInMemoryMetricsRepository_ConcurrencyWithContext(t *testing.T) {
 	// Counter должен накопиться: 0+1+2+...+9 = 45
 	assert.Equal(t, int64(45), value)
 }
+
+func TestInMemoryMetricsRepository_UpdateBatch(t *testing.T) {
+	repo := NewInMemoryMetricsRepository(testutils.NewMockLogger(), testutils.TestMetricsFile, false)
+	ctx := context.Background()
+
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 1))
+
+	value := 23.5
+	delta := int64(5)
+	batch := []models.Metrics{
+		{ID: "temperature", MType: models.Gauge, Value: &value},
+		{ID: "requests", MType: models.Counter, Delta: &delta},
+		{ID: "requests", MType: models.Counter, Delta: &delta},
+	}
+	require.NoError(t, repo.UpdateBatch(ctx, batch))
+
+	gauge, exists, err := repo.GetGauge(ctx, "temperature")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 23.5, gauge)
+
+	counter, _, err := repo.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), counter, "Counter deltas should be added to the current value")
+}
+
+func TestInMemoryMetricsRepository_UpdateBatch_RejectsWholeBatch(t *testing.T) {
+	repo := NewInMemoryMetricsRepository(testutils.NewMockLogger(), testutils.TestMetricsFile, false)
+	ctx := context.Background()
+
+	value := 23.5
+	delta := int64(5)
+	batch := []models.Metrics{
+		{ID: "temperature", MType: models.Gauge, Value: &value},
+		{ID: "requests", MType: models.Counter, Delta: &delta},
+		{ID: "broken", MType: models.Counter}, // Нет delta
+	}
+
+	err := repo.UpdateBatch(ctx, batch)
+	require.Error(t, err)
+	assert.True(t, models.IsValidationError(err))
+
+	gauges, err := repo.GetAllGauges(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, gauges, "No metric from rejected batch should be applied")
+
+	counters, err := repo.GetAllCounters(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, counters, "No metric from rejected batch should be applied")
+}
+
+func TestInMemoryMetricsRepository_UpdateBatch_SyncSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	repo := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, false)
+	repo.SetSyncSave(true)
+
+	value := 1.5
+	delta := int64(2)
+	require.NoError(t, repo.UpdateBatch(ctx, []models.Metrics{
+		{ID: "temperature", MType: models.Gauge, Value: &value},
+		{ID: "requests", MType: models.Counter, Delta: &delta},
+	}))
+
+	// Один пакет - одно сохранение снапшота, без ротации предыдущих
+	_, err := os.Stat(rotatedSnapshotPath(path, 1))
+	assert.True(t, os.IsNotExist(err), "Batch should be saved with a single snapshot write")
+
+	restored := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, true)
+	counter, _, err := restored.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), counter)
+}
+
+func TestInMemoryMetricsRepository_PersistFailureRollsBack(t *testing.T) {
+	// Каталога нет, поэтому синхронное сохранение завершается ошибкой
+	path := filepath.Join(t.TempDir(), "missing", "metrics.json")
+	ctx := context.Background()
+
+	repo := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, false)
+	repo.Gauges["temperature"] = 20
+	repo.Counters["requests"] = 10
+	repo.SetSyncSave(true)
+
+	assert.Error(t, repo.UpdateGauge(ctx, "temperature", 25))
+	assert.Error(t, repo.UpdateCounter(ctx, "requests", 5))
+	assert.Error(t, repo.UpdateCounter(ctx, "new_counter", 1))
+
+	value := 1.5
+	delta := int64(2)
+	assert.Error(t, repo.UpdateBatch(ctx, []models.Metrics{
+		{ID: "humidity", MType: models.Gauge, Value: &value},
+		{ID: "requests", MType: models.Counter, Delta: &delta},
+		{ID: "requests", MType: models.Counter, Delta: &delta},
+	}))
+
+	// Несохраненные обновления откатываются, поэтому повтор запроса не удваивает counter
+	assert.Equal(t, models.GaugeMetrics{"temperature": 20}, repo.Gauges)
+	assert.Equal(t, models.CounterMetrics{"requests": 10}, repo.Counters)
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 5))
+	counter, _, err := repo.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), counter)
+}
+
+func TestInMemoryMetricsRepository_UpdateBatch_WAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	repo := newTestWALRepository(t, path)
+	value := 1.5
+	delta := int64(2)
+	batch := []models.Metrics{
+		{ID: "temperature", MType: models.Gauge, Value: &value},
+		{ID: "requests", MType: models.Counter, Delta: &delta},
+	}
+	require.NoError(t, repo.UpdateBatch(ctx, batch))
+	require.NoError(t, repo.UpdateBatch(ctx, batch))
+	require.NoError(t, repo.Close())
+
+	restored := newTestWALRepository(t, path)
+	counter, _, err := restored.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), counter, "Batch records should be replayed from WAL")
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/IgorKilipenko/metrical/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations_Sorted(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000002_add_index.up.sql":        {Data: []byte("CREATE INDEX ...")},
+		"000002_add_index.down.sql":      {Data: []byte("DROP INDEX ...")},
+		"000001_create_metrics.up.sql":   {Data: []byte("CREATE TABLE ...")},
+		"000010_later.up.sql":            {Data: []byte("SELECT 1")},
+		"README.md":                      {Data: []byte("docs")},
+		"000001_create_metrics.down.sql": {Data: []byte("DROP TABLE ...")},
+	}
+
+	loaded, err := LoadMigrations(fsys)
+	require.NoError(t, err)
+	require.Len(t, loaded, 3, "only up migrations should be loaded")
+
+	assert.Equal(t, int64(1), loaded[0].Version)
+	assert.Equal(t, "create_metrics", loaded[0].Name)
+	assert.Equal(t, "CREATE TABLE ...", loaded[0].UpSQL)
+	assert.Equal(t, int64(2), loaded[1].Version)
+	assert.Equal(t, int64(10), loaded[2].Version)
+}
+
+func TestLoadMigrations_InvalidNames(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "Missing description",
+			fsys: fstest.MapFS{"000001.up.sql": {Data: []byte("SELECT 1")}},
+		},
+		{
+			name: "Non-numeric version",
+			fsys: fstest.MapFS{"abc_create.up.sql": {Data: []byte("SELECT 1")}},
+		},
+		{
+			name: "Duplicate version",
+			fsys: fstest.MapFS{
+				"000001_a.up.sql": {Data: []byte("SELECT 1")},
+				"1_b.up.sql":      {Data: []byte("SELECT 1")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadMigrations(tt.fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	loaded, err := LoadMigrations(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded, "embedded migrations should not be empty")
+
+	assert.Equal(t, int64(1), loaded[0].Version)
+	assert.Contains(t, loaded[0].UpSQL, "CREATE TABLE IF NOT EXISTS metrics")
+}
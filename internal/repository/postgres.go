@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/IgorKilipenko/metrical/internal/logger"
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/validation"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SQL запросы репозитория
+const (
+	upsertGaugeQuery = `
+		INSERT INTO metrics (id, type, value, updated_at)
+		VALUES ($1, 'gauge', $2, now())
+		ON CONFLICT (type, id) DO UPDATE
+		SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at`
+
+	// Счетчик увеличивается атомарно на стороне БД, без чтения текущего значения
+	upsertCounterQuery = `
+		INSERT INTO metrics (id, type, delta, updated_at)
+		VALUES ($1, 'counter', $2, now())
+		ON CONFLICT (type, id) DO UPDATE
+		SET delta = metrics.delta + EXCLUDED.delta, updated_at = EXCLUDED.updated_at`
+
+	selectGaugeQuery    = `SELECT value FROM metrics WHERE type = 'gauge' AND id = $1`
+	selectCounterQuery  = `SELECT delta FROM metrics WHERE type = 'counter' AND id = $1`
+	selectGaugesQuery   = `SELECT id, value FROM metrics WHERE type = 'gauge'`
+	selectCountersQuery = `SELECT id, delta FROM metrics WHERE type = 'counter'`
+)
+
+// PostgresMetricsRepository реализация репозитория на основе PostgreSQL
+type PostgresMetricsRepository struct {
+	pool   *pgxpool.Pool
+	logger logger.Logger
+}
+
+// NewPostgresMetricsRepository создает новый экземпляр PostgresMetricsRepository.
+// Схема базы данных должна быть подготовлена заранее с помощью ApplyMigrations.
+func NewPostgresMetricsRepository(pool *pgxpool.Pool, logger logger.Logger) *PostgresMetricsRepository {
+	return &PostgresMetricsRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+// UpdateGauge обновляет значение gauge метрики
+func (r *PostgresMetricsRepository) UpdateGauge(ctx context.Context, name string, value float64) error {
+	// Проверяем отмену контекста
+	select {
+	case <-ctx.Done():
+		r.logger.Debug("context cancelled during gauge update", "name", name, "value", value)
+		return ctx.Err()
+	default:
+	}
+
+	if _, err := r.pool.Exec(ctx, upsertGaugeQuery, name, value); err != nil {
+		return storageError("failed to upsert gauge metric", err)
+	}
+
+	r.logger.Debug("upserted gauge metric", "name", name, "value", value)
+	return nil
+}
+
+// UpdateCounter добавляет значение к counter метрике
+func (r *PostgresMetricsRepository) UpdateCounter(ctx context.Context, name string, value int64) error {
+	// Проверяем отмену контекста
+	select {
+	case <-ctx.Done():
+		r.logger.Debug("context cancelled during counter update", "name", name, "value", value)
+		return ctx.Err()
+	default:
+	}
+
+	if _, err := r.pool.Exec(ctx, upsertCounterQuery, name, value); err != nil {
+		return storageError("failed to upsert counter metric", err)
+	}
+
+	r.logger.Debug("upserted counter metric", "name", name, "added_value", value)
+	return nil
+}
+
+// UpdateBatch применяет пакет метрик в одной транзакции.
+// Пакет валидируется целиком до обращения к БД; при ошибке транзакция откатывается.
+func (r *PostgresMetricsRepository) UpdateBatch(ctx context.Context, metrics []models.Metrics) error {
+	// Проверяем отмену контекста
+	select {
+	case <-ctx.Done():
+		r.logger.Debug("context cancelled during batch update", "count", len(metrics))
+		return ctx.Err()
+	default:
+	}
+
+	if err := validation.ValidateMetricsBatch(metrics); err != nil {
+		return err
+	}
+
+	// Обновляем строки в одном порядке, чтобы параллельные пакеты не взаимоблокировались
+	sorted := slices.Clone(metrics)
+	slices.SortStableFunc(sorted, func(a, b models.Metrics) int {
+		return cmp.Or(cmp.Compare(a.MType, b.MType), cmp.Compare(a.ID, b.ID))
+	})
+
+	batch := &pgx.Batch{}
+	for _, metric := range sorted {
+		switch metric.MType {
+		case models.Gauge:
+			batch.Queue(upsertGaugeQuery, metric.ID, *metric.Value)
+		case models.Counter:
+			batch.Queue(upsertCounterQuery, metric.ID, *metric.Delta)
+		}
+	}
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		return tx.SendBatch(ctx, batch).Close()
+	})
+	if err != nil {
+		return storageError("failed to apply metrics batch", err)
+	}
+
+	r.logger.Debug("applied metrics batch", "count", len(metrics))
+	return nil
+}
+
+// GetGauge возвращает значение gauge метрики
+func (r *PostgresMetricsRepository) GetGauge(ctx context.Context, name string) (float64, bool, error) {
+	// Проверяем отмену контекста
+	select {
+	case <-ctx.Done():
+		r.logger.Debug("context cancelled during gauge retrieval", "name", name)
+		return 0, false, ctx.Err()
+	default:
+	}
+
+	var value float64
+	err := r.pool.QueryRow(ctx, selectGaugeQuery, name).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		r.logger.Debug("gauge metric not found", "name", name)
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, storageError("failed to select gauge metric", err)
+	}
+
+	r.logger.Debug("retrieved gauge metric", "name", name, "value", value)
+	return value, true, nil
+}
+
+// GetCounter возвращает значение counter метрики
+func (r *PostgresMetricsRepository) GetCounter(ctx context.Context, name string) (int64, bool, error) {
+	// Проверяем отмену контекста
+	select {
+	case <-ctx.Done():
+		r.logger.Debug("context cancelled during counter retrieval", "name", name)
+		return 0, false, ctx.Err()
+	default:
+	}
+
+	var value int64
+	err := r.pool.QueryRow(ctx, selectCounterQuery, name).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		r.logger.Debug("counter metric not found", "name", name)
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, storageError("failed to select counter metric", err)
+	}
+
+	r.logger.Debug("retrieved counter metric", "name", name, "value", value)
+	return value, true, nil
+}
+
+// GetAllGauges возвращает все gauge метрики
+func (r *PostgresMetricsRepository) GetAllGauges(ctx context.Context) (models.GaugeMetrics, error) {
+	// Проверяем отмену контекста
+	select {
+	case <-ctx.Done():
+		r.logger.Debug("context cancelled during getAllGauges")
+		return nil, ctx.Err()
+	default:
+	}
+
+	rows, err := r.pool.Query(ctx, selectGaugesQuery)
+	if err != nil {
+		return nil, storageError("failed to select gauge metrics", err)
+	}
+	defer rows.Close()
+
+	result := make(models.GaugeMetrics)
+	for rows.Next() {
+		var name string
+		var value float64
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan gauge metric: %w", err)
+		}
+		result[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, storageError("failed to iterate gauge metrics", err)
+	}
+
+	r.logger.Debug("retrieved all gauge metrics", "count", len(result))
+	return result, nil
+}
+
+// GetAllCounters возвращает все counter метрики
+func (r *PostgresMetricsRepository) GetAllCounters(ctx context.Context) (models.CounterMetrics, error) {
+	// Проверяем отмену контекста
+	select {
+	case <-ctx.Done():
+		r.logger.Debug("context cancelled during getAllCounters")
+		return nil, ctx.Err()
+	default:
+	}
+
+	rows, err := r.pool.Query(ctx, selectCountersQuery)
+	if err != nil {
+		return nil, storageError("failed to select counter metrics", err)
+	}
+	defer rows.Close()
+
+	result := make(models.CounterMetrics)
+	for rows.Next() {
+		var name string
+		var value int64
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan counter metric: %w", err)
+		}
+		result[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, storageError("failed to iterate counter metrics", err)
+	}
+
+	r.logger.Debug("retrieved all counter metrics", "count", len(result))
+	return result, nil
+}
+
+// SaveToFile не требуется для PostgreSQL: данные сохраняются при каждом обновлении
+func (r *PostgresMetricsRepository) SaveToFile() error {
+	return nil
+}
+
+// LoadFromFile не требуется для PostgreSQL: данные читаются напрямую из БД
+func (r *PostgresMetricsRepository) LoadFromFile() error {
+	return nil
+}
+
+// SetSyncSave не влияет на PostgreSQL: каждая запись уже durable
+func (r *PostgresMetricsRepository) SetSyncSave(sync bool) {}
+
+// Ping проверяет доступность базы данных
+func (r *PostgresMetricsRepository) Ping(ctx context.Context) error {
+	return r.pool.Ping(ctx)
+}
+
+// Close закрывает пул подключений
+func (r *PostgresMetricsRepository) Close() {
+	r.pool.Close()
+}
+
+// storageError оборачивает ошибку запроса к БД.
+// Ошибки соединения дополнительно помечаются models.ErrStorageUnavailable, чтобы сервер ответил 503.
+func storageError(msg string, err error) error {
+	if isConnectionError(err) {
+		return fmt.Errorf("%s: %w: %w", msg, models.ErrStorageUnavailable, err)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// isConnectionError проверяет, вызвана ли ошибка недоступностью БД, а не самим запросом
+func isConnectionError(err error) bool {
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.Timeout(err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Класс 08 - ошибки соединения, 57P0x - остановка сервера, 53300 - исчерпан лимит соединений
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "57P") || pgErr.Code == "53300"
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/config/db"
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/IgorKilipenko/metrical/migrations"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPostgresRepository создает репозиторий на тестовой БД.
+// Тесты пропускаются, если переменная окружения TEST_DATABASE_DSN не задана.
+func newTestPostgresRepository(t *testing.T) *PostgresMetricsRepository {
+	t.Helper()
+
+	dsn := os.Getenv(testutils.TestDatabaseDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set, skipping PostgreSQL integration test", testutils.TestDatabaseDSNEnv)
+	}
+
+	ctx := context.Background()
+	pool, err := db.NewPool(ctx, db.NewConfig(dsn))
+	require.NoError(t, err, "Failed to connect to test database")
+
+	mockLogger := testutils.NewMockLogger()
+	require.NoError(t, ApplyMigrations(ctx, pool, migrations.FS, mockLogger), "Failed to apply migrations")
+
+	_, err = pool.Exec(ctx, "TRUNCATE metrics")
+	require.NoError(t, err, "Failed to clean metrics table")
+
+	repo := NewPostgresMetricsRepository(pool, mockLogger)
+	t.Cleanup(repo.Close)
+	return repo
+}
+
+func TestPostgresMetricsRepository_Gauge(t *testing.T) {
+	repo := newTestPostgresRepository(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.UpdateGauge(ctx, "temperature", 23.5))
+	require.NoError(t, repo.UpdateGauge(ctx, "temperature", 25.0))
+
+	value, exists, err := repo.GetGauge(ctx, "temperature")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 25.0, value, "Gauge value should be replaced")
+
+	_, exists, err = repo.GetGauge(ctx, "nonexistent")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestPostgresMetricsRepository_Counter(t *testing.T) {
+	repo := newTestPostgresRepository(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 100))
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 50))
+
+	value, exists, err := repo.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, int64(150), value, "Counter value should accumulate")
+}
+
+func TestPostgresMetricsRepository_SameNameDifferentTypes(t *testing.T) {
+	repo := newTestPostgresRepository(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.UpdateGauge(ctx, "shared", 1.5))
+	require.NoError(t, repo.UpdateCounter(ctx, "shared", 3))
+
+	gauges, err := repo.GetAllGauges(ctx)
+	require.NoError(t, err)
+	counters, err := repo.GetAllCounters(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.5, gauges["shared"])
+	assert.Equal(t, int64(3), counters["shared"])
+}
+
+func TestPostgresMetricsRepository_ConcurrentCounterUpserts(t *testing.T) {
+	repo := newTestPostgresRepository(t)
+	ctx := context.Background()
+
+	const numGoroutines = 20
+	var wg sync.WaitGroup
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, repo.UpdateCounter(ctx, "concurrent_counter", 1))
+		}()
+	}
+	wg.Wait()
+
+	value, exists, err := repo.GetCounter(ctx, "concurrent_counter")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, int64(numGoroutines), value, "Concurrent upserts must not lose increments")
+}
+
+func TestPostgresMetricsRepository_UpdateBatch(t *testing.T) {
+	repo := newTestPostgresRepository(t)
+	ctx := context.Background()
+
+	value := 23.5
+	delta := int64(5)
+	batch := []models.Metrics{
+		{ID: "temperature", MType: models.Gauge, Value: &value},
+		{ID: "requests", MType: models.Counter, Delta: &delta},
+		{ID: "requests", MType: models.Counter, Delta: &delta},
+	}
+	require.NoError(t, repo.UpdateBatch(ctx, batch))
+
+	gauge, _, err := repo.GetGauge(ctx, "temperature")
+	require.NoError(t, err)
+	assert.Equal(t, 23.5, gauge)
+
+	counter, _, err := repo.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), counter, "Counter deltas within a batch should accumulate")
+
+	// Некорректная метрика отклоняет весь пакет
+	invalid := []models.Metrics{
+		{ID: "requests", MType: models.Counter, Delta: &delta},
+		{ID: "broken", MType: models.Gauge},
+	}
+	assert.Error(t, repo.UpdateBatch(ctx, invalid))
+
+	counter, _, err = repo.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), counter, "Rejected batch must not be applied")
+}
+
+func TestPostgresMetricsRepository_ContextCancellation(t *testing.T) {
+	// Отмена контекста проверяется до обращения к пулу, поэтому БД не нужна
+	repo := NewPostgresMetricsRepository(nil, testutils.NewMockLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, repo.UpdateGauge(ctx, "test", 1))
+	assert.Equal(t, context.Canceled, repo.UpdateCounter(ctx, "test", 1))
+	assert.Equal(t, context.Canceled, repo.UpdateBatch(ctx, nil))
+
+	_, _, err := repo.GetGauge(ctx, "test")
+	assert.Equal(t, context.Canceled, err)
+
+	_, _, err = repo.GetCounter(ctx, "test")
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = repo.GetAllGauges(ctx)
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = repo.GetAllCounters(ctx)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestStorageError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		unavailable bool
+	}{
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}, unavailable: true},
+		{name: "server shutdown", err: &pgconn.PgError{Code: "57P01"}, unavailable: true},
+		{name: "too many connections", err: &pgconn.PgError{Code: "53300"}, unavailable: true},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}, unavailable: false},
+		{name: "plain error", err: errors.New("boom"), unavailable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := storageError("failed to select gauge metric", tt.err)
+			assert.ErrorIs(t, err, tt.err, "Original error must stay in the chain")
+			assert.Equal(t, tt.unavailable, errors.Is(err, models.ErrStorageUnavailable))
+		})
+	}
+}
+
+func TestPostgresMetricsRepository_Unreachable(t *testing.T) {
+	pool, err := pgxpool.New(context.Background(), "postgres://metrics@127.0.0.1:1/metrics?connect_timeout=1")
+	require.NoError(t, err)
+	repo := NewPostgresMetricsRepository(pool, testutils.NewMockLogger())
+	defer repo.Close()
+
+	_, _, err = repo.GetGauge(context.Background(), "Alloc")
+	assert.ErrorIs(t, err, models.ErrStorageUnavailable)
+}
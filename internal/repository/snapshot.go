@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+)
+
+// Параметры формата снапшота
+const (
+	// SnapshotFormatVersion текущая версия формата файла снапшота.
+	// Версия 1 - исходный формат без заголовка (JSON массив метрик).
+	SnapshotFormatVersion = 2
+
+	// DefaultSnapshotRetention количество хранимых снапшотов по умолчанию (текущий + ротированные)
+	DefaultSnapshotRetention = 3
+
+	// checksumPrefix префикс алгоритма в поле контрольной суммы
+	checksumPrefix = "sha256:"
+
+	// maxRotatedSnapshots ограничение перебора ротированных файлов при восстановлении
+	maxRotatedSnapshots = 100
+)
+
+// snapshotFile формат файла снапшота с заголовком.
+// Контрольная сумма считается по компактному JSON представлению поля metrics.
+type snapshotFile struct {
+	Version   int             `json:"version"`
+	Timestamp time.Time       `json:"timestamp"`
+	WALSeq    uint64          `json:"wal_seq,omitempty"` // Номер последней записи журнала, вошедшей в снапшот
+	Checksum  string          `json:"checksum"`
+	Metrics   json.RawMessage `json:"metrics"`
+}
+
+// snapshot содержимое декодированного снапшота
+type snapshot struct {
+	Metrics   []models.Metrics
+	Timestamp time.Time // Время записи (нулевое для формата версии 1)
+	WALSeq    uint64    // Номер последней записи журнала, вошедшей в снапшот
+}
+
+// encodeSnapshot кодирует метрики в файл снапшота с заголовком.
+// walSeq - номер последней записи журнала, уже учтенной в метриках.
+func encodeSnapshot(metrics []models.Metrics, timestamp time.Time, walSeq uint64) ([]byte, error) {
+	if metrics == nil {
+		metrics = []models.Metrics{}
+	}
+
+	payload, err := json.Marshal(metrics)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metrics to JSON: %w", err)
+	}
+
+	data, err := json.Marshal(snapshotFile{
+		Version:   SnapshotFormatVersion,
+		Timestamp: timestamp.UTC(),
+		WALSeq:    walSeq,
+		Checksum:  checksum(payload),
+		Metrics:   payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	return data, nil
+}
+
+// decodeSnapshot декодирует файл снапшота и проверяет контрольную сумму.
+// Файлы версии 1 (JSON массив без заголовка) читаются без проверки.
+func decodeSnapshot(data []byte) (*snapshot, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, errors.New("snapshot file is empty")
+	}
+
+	// Исходный формат без заголовка
+	if trimmed[0] == '[' {
+		var metrics []models.Metrics
+		if err := json.Unmarshal(trimmed, &metrics); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metrics from JSON: %w", err)
+		}
+		return &snapshot{Metrics: metrics}, nil
+	}
+
+	var file snapshotFile
+	if err := json.Unmarshal(trimmed, &file); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %w", err)
+	}
+	if file.Version != SnapshotFormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot format version: %d", file.Version)
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, file.Metrics); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot payload: %w", err)
+	}
+	if actual := checksum(compact.Bytes()); actual != file.Checksum {
+		return nil, fmt.Errorf("snapshot checksum mismatch: expected %s, got %s", file.Checksum, actual)
+	}
+
+	var metrics []models.Metrics
+	if err := json.Unmarshal(compact.Bytes(), &metrics); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metrics from JSON: %w", err)
+	}
+	return &snapshot{Metrics: metrics, Timestamp: file.Timestamp, WALSeq: file.WALSeq}, nil
+}
+
+// checksum вычисляет контрольную сумму данных
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return checksumPrefix + hex.EncodeToString(sum[:])
+}
+
+// rotatedSnapshotPath возвращает путь к ротированному снапшоту с номером n (1 - самый свежий)
+func rotatedSnapshotPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// writeSnapshotAtomic атомарно записывает снапшот: временный файл + fsync + rename.
+// Предыдущие снапшоты сдвигаются (path -> path.1 -> path.2 ...), всего хранится retention файлов.
+func writeSnapshotAtomic(path string, data []byte, retention int) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary snapshot file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	// Удаляем временный файл при любой ошибке до rename
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary snapshot file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary snapshot file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary snapshot file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return fmt.Errorf("failed to set snapshot file permissions: %w", err)
+	}
+
+	if err := rotateSnapshots(path, retention); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace snapshot file: %w", err)
+	}
+
+	return syncDir(dir)
+}
+
+// rotateSnapshots сдвигает существующие снапшоты, освобождая место для нового.
+// Самый старый снапшот сверх лимита перезаписывается.
+func rotateSnapshots(path string, retention int) error {
+	if retention <= 1 {
+		return nil
+	}
+
+	for n := retention - 1; n >= 1; n-- {
+		src := path
+		if n > 1 {
+			src = rotatedSnapshotPath(path, n-1)
+		}
+
+		if err := os.Rename(src, rotatedSnapshotPath(path, n)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate snapshot %s: %w", src, err)
+		}
+	}
+
+	return nil
+}
+
+// syncDir сбрасывает на диск метаданные директории, чтобы rename пережил падение
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot directory: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync snapshot directory: %w", err)
+	}
+	return nil
+}
+
+// snapshotLoadResult результат поиска корректного снапшота
+type snapshotLoadResult struct {
+	snapshot
+	Path    string   // Файл, из которого загружены метрики (пустой, если снапшотов нет)
+	Skipped []string // Поврежденные файлы, пропущенные при восстановлении
+}
+
+// loadNewestValidSnapshot загружает самый свежий корректный снапшот:
+// сначала основной файл, затем ротированные в порядке от новых к старым.
+// Отсутствие всех файлов не считается ошибкой.
+func loadNewestValidSnapshot(path string) (*snapshotLoadResult, error) {
+	result := &snapshotLoadResult{}
+	var errs []string
+	found := false
+
+	for n := 0; n <= maxRotatedSnapshots; n++ {
+		candidate := path
+		if n > 0 {
+			candidate = rotatedSnapshotPath(path, n)
+		}
+
+		data, err := os.ReadFile(candidate)
+		if os.IsNotExist(err) {
+			// Основной файл может отсутствовать после падения во время ротации
+			if n == 0 {
+				continue
+			}
+			break
+		}
+		found = true
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", candidate, err))
+			result.Skipped = append(result.Skipped, candidate)
+			continue
+		}
+
+		decoded, err := decodeSnapshot(data)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", candidate, err))
+			result.Skipped = append(result.Skipped, candidate)
+			continue
+		}
+
+		result.snapshot = *decoded
+		result.Path = candidate
+		return result, nil
+	}
+
+	if !found {
+		return result, nil
+	}
+	return nil, fmt.Errorf("no valid snapshot found: %s", strings.Join(errs, "; "))
+}
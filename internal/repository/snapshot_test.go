@@ -0,0 +1,323 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot_EncodeDecode(t *testing.T) {
+	value := 42.5
+	delta := int64(7)
+	metrics := []models.Metrics{
+		{ID: "temperature", MType: models.Gauge, Value: &value},
+		{ID: "requests", MType: models.Counter, Delta: &delta},
+	}
+
+	data, err := encodeSnapshot(metrics, time.Now(), 7)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"version":2`)
+	assert.Contains(t, string(data), `"checksum":"sha256:`)
+	assert.Contains(t, string(data), `"wal_seq":7`)
+
+	decoded, err := decodeSnapshot(data)
+	require.NoError(t, err)
+	assert.Equal(t, metrics, decoded.Metrics)
+	assert.Equal(t, uint64(7), decoded.WALSeq)
+}
+
+func TestSnapshot_Decode(t *testing.T) {
+	valid, err := encodeSnapshot(nil, time.Now(), 0)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		data        string
+		expectError bool
+	}{
+		{
+			name: "legacy format without header",
+			data: `[{"id":"requests","type":"counter","delta":3}]`,
+		},
+		{
+			name: "empty snapshot with header",
+			data: string(valid),
+		},
+		{
+			name:        "empty file",
+			data:        "",
+			expectError: true,
+		},
+		{
+			name:        "truncated file",
+			data:        string(valid[:len(valid)/2]),
+			expectError: true,
+		},
+		{
+			name:        "checksum mismatch",
+			data:        `{"version":2,"timestamp":"2024-01-01T00:00:00Z","checksum":"sha256:00","metrics":[]}`,
+			expectError: true,
+		},
+		{
+			name:        "unsupported version",
+			data:        `{"version":99,"timestamp":"2024-01-01T00:00:00Z","checksum":"","metrics":[]}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeSnapshot([]byte(tt.data))
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSnapshot_ModifiedPayloadDetected(t *testing.T) {
+	delta := int64(10)
+	data, err := encodeSnapshot([]models.Metrics{{ID: "requests", MType: models.Counter, Delta: &delta}}, time.Now(), 0)
+	require.NoError(t, err)
+
+	// Меняем значение, не пересчитывая контрольную сумму
+	tampered := strings.Replace(string(data), `"delta":10`, `"delta":11`, 1)
+	require.NotEqual(t, string(data), tampered)
+
+	_, err = decodeSnapshot([]byte(tampered))
+	assert.ErrorContains(t, err, "checksum mismatch")
+}
+
+func TestWriteSnapshotAtomic_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "metrics.json")
+
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, writeSnapshotAtomic(path, []byte{byte('0' + i)}, 3))
+	}
+
+	// Хранятся три последних снапшота: текущий и два предыдущих
+	expected := map[string]string{
+		path:                         "5",
+		rotatedSnapshotPath(path, 1): "4",
+		rotatedSnapshotPath(path, 2): "3",
+	}
+	for file, content := range expected {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data), "Unexpected content of %s", file)
+	}
+
+	_, err := os.Stat(rotatedSnapshotPath(path, 3))
+	assert.True(t, os.IsNotExist(err), "Snapshots beyond retention should not be kept")
+
+	// Временные файлы не должны оставаться в директории
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
+
+func TestWriteSnapshotAtomic_SingleSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	require.NoError(t, writeSnapshotAtomic(path, []byte("1"), 1))
+	require.NoError(t, writeSnapshotAtomic(path, []byte("2"), 1))
+
+	_, err := os.Stat(rotatedSnapshotPath(path, 1))
+	assert.True(t, os.IsNotExist(err), "No rotated snapshots should be kept with retention 1")
+}
+
+func TestInMemoryMetricsRepository_RestoreFallsBackToValidSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	repo := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, false)
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 5))
+	require.NoError(t, repo.SaveToFile())
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 5))
+	require.NoError(t, repo.SaveToFile())
+
+	// Имитируем поврежденный основной снапшот (например, оборванную запись)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data[:len(data)/2], 0644))
+
+	restored := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, true)
+	counter, exists, err := restored.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.True(t, exists, "Metrics should be restored from the previous snapshot")
+	assert.Equal(t, int64(5), counter)
+}
+
+// errorLogger запоминает сообщения уровня error
+type errorLogger struct {
+	testutils.MockLogger
+	messages []string
+}
+
+func (l *errorLogger) Error(msg string, args ...any) {
+	l.messages = append(l.messages, msg)
+}
+
+// corruptSnapshot обрезает файл снапшота, имитируя оборванную запись
+func corruptSnapshot(t *testing.T, path string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data[:len(data)/2], 0644))
+}
+
+func TestInMemoryMetricsRepository_RestoreFallbackReplaysWALSegments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	repo := newTestWALRepository(t, path)
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 5))
+	require.NoError(t, repo.SaveToFile())
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 5))
+	require.NoError(t, repo.UpdateGauge(ctx, "temperature", 20))
+	require.NoError(t, repo.SaveToFile())
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 1))
+	require.NoError(t, repo.Close())
+
+	corruptSnapshot(t, path)
+
+	log := &errorLogger{}
+	restored := NewInMemoryMetricsRepository(log, path, true)
+	counter, _, err := restored.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), counter, "Updates between the older and the corrupted snapshot should be replayed from WAL segment")
+
+	value, exists, err := restored.GetGauge(ctx, "temperature")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 20.0, value)
+	assert.Empty(t, log.messages, "Recovery with complete WAL is not a data loss")
+}
+
+func TestInMemoryMetricsRepository_RestoreFallbackReportsDataLoss(t *testing.T) {
+	t.Run("missing WAL segment", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "metrics.json")
+		ctx := context.Background()
+
+		repo := newTestWALRepository(t, path)
+		require.NoError(t, repo.UpdateCounter(ctx, "requests", 5))
+		require.NoError(t, repo.SaveToFile())
+		require.NoError(t, repo.UpdateCounter(ctx, "requests", 5))
+		require.NoError(t, repo.SaveToFile())
+		require.NoError(t, repo.UpdateCounter(ctx, "requests", 1))
+		require.NoError(t, repo.Close())
+
+		corruptSnapshot(t, path)
+		require.NoError(t, os.Remove(walSegmentPath(path, 1)))
+
+		log := &errorLogger{}
+		restored := NewInMemoryMetricsRepository(log, path, true)
+		counter, _, err := restored.GetCounter(ctx, "requests")
+		require.NoError(t, err)
+		assert.Equal(t, int64(6), counter)
+		assert.Contains(t, log.messages, "WAL records are missing, updates in this range are lost")
+	})
+
+	t.Run("without WAL", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "metrics.json")
+		ctx := context.Background()
+
+		repo := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, false)
+		require.NoError(t, repo.UpdateCounter(ctx, "requests", 5))
+		require.NoError(t, repo.SaveToFile())
+		require.NoError(t, repo.UpdateCounter(ctx, "requests", 5))
+		require.NoError(t, repo.SaveToFile())
+
+		corruptSnapshot(t, path)
+
+		log := &errorLogger{}
+		NewInMemoryMetricsRepository(log, path, true)
+		assert.Len(t, log.messages, 1, "Fallback without WAL should be reported as data loss")
+	})
+}
+
+func TestInMemoryMetricsRepository_WALSegmentsFollowSnapshotRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	repo := newTestWALRepository(t, path)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, repo.UpdateCounter(ctx, "requests", 1))
+		require.NoError(t, repo.SaveToFile())
+	}
+
+	// Три снапшота (текущий и два ротированных) - два сегмента журнала
+	for n := 1; n <= 2; n++ {
+		_, err := os.Stat(walSegmentPath(path, n))
+		assert.NoError(t, err, "WAL segment %d should be kept", n)
+	}
+	_, err := os.Stat(walSegmentPath(path, 3))
+	assert.True(t, os.IsNotExist(err), "WAL segments beyond snapshot retention should be removed")
+}
+
+func TestInMemoryMetricsRepository_RestoreWithoutMainSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	repo := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, false)
+	require.NoError(t, repo.UpdateGauge(ctx, "temperature", 21.5))
+	require.NoError(t, repo.SaveToFile())
+	require.NoError(t, repo.SaveToFile())
+
+	// Падение между ротацией и переименованием временного файла
+	require.NoError(t, os.Remove(path))
+
+	restored := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, true)
+	value, exists, err := restored.GetGauge(ctx, "temperature")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 21.5, value)
+}
+
+func TestInMemoryMetricsRepository_RestoreAllSnapshotsCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"version":2`), 0644))
+	require.NoError(t, os.WriteFile(rotatedSnapshotPath(path, 1), []byte(`[{"id":`), 0644))
+
+	repo := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, false)
+	assert.Error(t, repo.LoadFromFile(), "Corrupted snapshots must not be silently treated as empty storage")
+}
+
+func TestInMemoryMetricsRepository_RestoreLegacySnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	legacy := `[
+  {"id": "temperature", "type": "gauge", "value": 23.5},
+  {"id": "requests", "type": "counter", "delta": 100}
+]`
+	require.NoError(t, os.WriteFile(path, []byte(legacy), 0644))
+
+	repo := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, true)
+	ctx := context.Background()
+
+	counter, _, err := repo.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), counter)
+
+	// Следующее сохранение переводит файл в новый формат, старый остается в ротации
+	require.NoError(t, repo.SaveToFile())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"version":2`)
+
+	rotated, err := os.ReadFile(rotatedSnapshotPath(path, 1))
+	require.NoError(t, err)
+	assert.Equal(t, legacy, string(rotated))
+}
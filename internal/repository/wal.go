@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+)
+
+// walFileSuffix суффикс файла журнала относительно пути снапшота
+const walFileSuffix = ".wal"
+
+// walPath возвращает путь к журналу упреждающей записи для файла снапшота
+func walPath(fileStoragePath string) string {
+	return fileStoragePath + walFileSuffix
+}
+
+// walSegmentPath возвращает путь к сегменту журнала с номером n (1 - самый свежий).
+// Сегмент n содержит записи, сделанные между снапшотами path.n и path.(n-1) (для n = 1 - основным файлом).
+func walSegmentPath(fileStoragePath string, n int) string {
+	return fmt.Sprintf("%s.%d", walPath(fileStoragePath), n)
+}
+
+// rotateWALSegments сдвигает сегменты журнала вслед за снапшотами при контрольной точке:
+// текущий журнал становится сегментом 1, сегмент n - сегментом n+1. Хранится retention-1 сегментов,
+// чтобы восстановление от самого старого хранимого снапшота не теряло обновлений.
+func rotateWALSegments(fileStoragePath string, retention int) error {
+	if retention <= 1 {
+		if err := os.Remove(walPath(fileStoragePath)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove WAL file: %w", err)
+		}
+		return nil
+	}
+
+	// Самый старый сегмент относится к снапшоту, который вытесняется из ротации
+	if err := os.Remove(walSegmentPath(fileStoragePath, retention-1)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove WAL segment: %w", err)
+	}
+
+	rotated := false
+	for n := retention - 1; n >= 1; n-- {
+		src := walPath(fileStoragePath)
+		if n > 1 {
+			src = walSegmentPath(fileStoragePath, n-1)
+		}
+
+		err := os.Rename(src, walSegmentPath(fileStoragePath, n))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to rotate WAL segment %s: %w", src, err)
+		}
+		rotated = true
+	}
+
+	if !rotated {
+		return nil
+	}
+	return syncDir(filepath.Dir(fileStoragePath))
+}
+
+// walRecord запись журнала: метрика с порядковым номером.
+// Номера возрастают монотонно и не сбрасываются при очистке журнала, а снапшот хранит
+// номер последней вошедшей в него записи, поэтому повторное применение журнала поверх
+// снапшота не удваивает counter. Записи старого формата без номера имеют Seq = 0.
+type walRecord struct {
+	Seq uint64 `json:"seq,omitempty"`
+	models.Metrics
+}
+
+// walWriter дописывает записи в журнал упреждающей записи (write-ahead log).
+// Каждая запись - одна JSON строка walRecord, для counter хранится прирост.
+type walWriter struct {
+	file *os.File
+}
+
+// openWAL открывает журнал на дозапись, создавая файл при необходимости
+func openWAL(path string) (*walWriter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL file: %w", err)
+	}
+	return &walWriter{file: file}, nil
+}
+
+// Append записывает записи и сбрасывает их на диск одним fsync.
+// При ошибке журнал усекается до прежнего размера, чтобы неподтвержденные записи не применились при восстановлении.
+func (w *walWriter) Append(records ...walRecord) error {
+	var data []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to marshal WAL record: %w", err)
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+
+	info, err := w.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat WAL file: %w", err)
+	}
+
+	if _, err := w.file.Write(data); err != nil {
+		return errors.Join(fmt.Errorf("failed to append WAL record: %w", err), w.truncate(info.Size()))
+	}
+	if err := w.file.Sync(); err != nil {
+		return errors.Join(fmt.Errorf("failed to sync WAL file: %w", err), w.truncate(info.Size()))
+	}
+	return nil
+}
+
+// truncate отбрасывает записи после size
+func (w *walWriter) truncate(size int64) error {
+	if err := w.file.Truncate(size); err != nil {
+		return fmt.Errorf("failed to truncate WAL file: %w", err)
+	}
+	return nil
+}
+
+// Close закрывает файл журнала
+func (w *walWriter) Close() error {
+	return w.file.Close()
+}
+
+// walReplayResult результат чтения журнала
+type walReplayResult struct {
+	Records   []walRecord
+	ValidSize int64 // Размер корректной части журнала в байтах
+	Torn      bool  // Последняя запись была оборвана (например, при падении во время записи)
+}
+
+// readWAL читает все записи журнала.
+// Оборванная последняя запись (без перевода строки или с некорректным JSON) отбрасывается,
+// поврежденная запись в середине журнала считается ошибкой.
+func readWAL(path string) (*walReplayResult, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &walReplayResult{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL file: %w", err)
+	}
+	defer file.Close()
+
+	result := &walReplayResult{}
+	reader := bufio.NewReader(file)
+
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Данные без завершающего перевода строки - оборванная запись
+			if len(bytes.TrimSpace(line)) > 0 {
+				result.Torn = true
+			}
+			return result, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WAL file: %w", err)
+		}
+
+		var record walRecord
+		if err := json.Unmarshal(line, &record); err != nil || !isValidWALRecord(record.Metrics) {
+			if _, peekErr := reader.Peek(1); errors.Is(peekErr, io.EOF) {
+				result.Torn = true
+				return result, nil
+			}
+			return nil, fmt.Errorf("corrupted WAL record at line %d", lineNum)
+		}
+
+		result.Records = append(result.Records, record)
+		result.ValidSize += int64(len(line))
+	}
+}
+
+// walHistory записи сегментов журнала и текущего журнала в порядке записи
+type walHistory struct {
+	Records []walRecord
+	Current *walReplayResult // Текущий журнал (оборванная последняя запись усекается при загрузке)
+	Files   int              // Количество найденных файлов журнала
+}
+
+// readWALHistory читает сегменты журнала от старых к новым, затем текущий журнал.
+// Сегменты нужны, если основной снапшот поврежден и восстановление идет от ротированного.
+func readWALHistory(fileStoragePath string) (*walHistory, error) {
+	history := &walHistory{}
+
+	for n := maxRotatedSnapshots; n >= 1; n-- {
+		path := walSegmentPath(fileStoragePath, n)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+
+		segment, err := readWAL(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WAL segment %s: %w", path, err)
+		}
+		history.Records = append(history.Records, segment.Records...)
+		history.Files++
+	}
+
+	if _, err := os.Stat(walPath(fileStoragePath)); err == nil {
+		history.Files++
+	}
+	current, err := readWAL(walPath(fileStoragePath))
+	if err != nil {
+		return nil, err
+	}
+	history.Records = append(history.Records, current.Records...)
+	history.Current = current
+
+	return history, nil
+}
+
+// walGap диапазон номеров записей журнала, отсутствующих при восстановлении
+type walGap struct {
+	From uint64
+	To   uint64
+}
+
+// isValidWALRecord проверяет, что запись содержит значение, соответствующее типу
+func isValidWALRecord(record models.Metrics) bool {
+	switch record.MType {
+	case models.Gauge:
+		return record.ID != "" && record.Value != nil
+	case models.Counter:
+		return record.ID != "" && record.Delta != nil
+	default:
+		return false
+	}
+}
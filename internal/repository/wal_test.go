@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestWALRepository создает репозиторий в режиме WAL во временной директории
+func newTestWALRepository(t *testing.T, path string) *InMemoryMetricsRepository {
+	t.Helper()
+
+	repo := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, true)
+	require.NoError(t, repo.EnableWAL(), "Failed to enable WAL")
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestInMemoryMetricsRepository_WAL_ReplayAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	repo := newTestWALRepository(t, path)
+	require.NoError(t, repo.UpdateGauge(ctx, "temperature", 20.0))
+	require.NoError(t, repo.UpdateGauge(ctx, "temperature", 23.5))
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 10))
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 5))
+	require.NoError(t, repo.Close())
+
+	// Снапшот не записывался - данные есть только в журнале
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "Snapshot should not be written on each update in WAL mode")
+
+	restored := newTestWALRepository(t, path)
+
+	value, exists, err := restored.GetGauge(ctx, "temperature")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 23.5, value, "Gauge should have the last written value")
+
+	counter, exists, err := restored.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, int64(15), counter, "Counter deltas should be replayed")
+}
+
+func TestInMemoryMetricsRepository_WAL_CheckpointTruncatesLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	repo := newTestWALRepository(t, path)
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 10))
+	require.NoError(t, repo.SaveToFile(), "Checkpoint should succeed")
+
+	info, err := os.Stat(walPath(path))
+	require.NoError(t, err)
+	assert.Zero(t, info.Size(), "WAL should be empty after checkpoint")
+
+	// Обновления после контрольной точки попадают в журнал
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 7))
+	require.NoError(t, repo.Close())
+
+	restored := newTestWALRepository(t, path)
+	counter, _, err := restored.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(17), counter, "Snapshot and WAL must not double count")
+}
+
+func TestInMemoryMetricsRepository_WAL_CrashBeforeTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	repo := newTestWALRepository(t, path)
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 10))
+	require.NoError(t, repo.UpdateGauge(ctx, "temperature", 21.5))
+
+	// Имитируем падение между записью снапшота и очисткой журнала:
+	// после контрольной точки возвращаем журналу прежнее содержимое
+	walData, err := os.ReadFile(walPath(path))
+	require.NoError(t, err)
+	require.NoError(t, repo.SaveToFile())
+	require.NoError(t, repo.Close())
+	require.NoError(t, os.WriteFile(walPath(path), walData, 0644))
+
+	restored := newTestWALRepository(t, path)
+	counter, _, err := restored.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), counter, "WAL records included in the snapshot must not be applied twice")
+
+	// Новые записи продолжают нумерацию и применяются поверх снапшота
+	require.NoError(t, restored.UpdateCounter(ctx, "requests", 5))
+	require.NoError(t, restored.Close())
+
+	again := newTestWALRepository(t, path)
+	counter, _, err = again.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), counter)
+}
+
+func TestInMemoryMetricsRepository_WAL_LegacyRecordsWithoutSeq(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	require.NoError(t, os.WriteFile(walPath(path), []byte(`{"id":"requests","type":"counter","delta":3}
+`), 0644))
+
+	repo := newTestWALRepository(t, path)
+	counter, _, err := repo.GetCounter(context.Background(), "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), counter, "Records without sequence number should be applied")
+}
+
+func TestInMemoryMetricsRepository_WAL_TornLastRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	repo := newTestWALRepository(t, path)
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 3))
+	require.NoError(t, repo.Close())
+
+	// Имитируем падение во время записи: запись оборвана на середине
+	file, err := os.OpenFile(walPath(path), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"id":"requests","type":"coun`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	restored := newTestWALRepository(t, path)
+	counter, _, err := restored.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), counter, "Torn record should be discarded")
+
+	// Новые записи не должны склеиваться с оборванной
+	require.NoError(t, restored.UpdateCounter(ctx, "requests", 2))
+	require.NoError(t, restored.Close())
+
+	again := newTestWALRepository(t, path)
+	counter, _, err = again.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), counter)
+}
+
+func TestInMemoryMetricsRepository_WAL_CorruptedMiddleRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	content := `{"id":"a","type":"counter","delta":1}
+not a json record
+{"id":"a","type":"counter","delta":2}
+`
+	require.NoError(t, os.WriteFile(walPath(path), []byte(content), 0644))
+
+	repo := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, false)
+	err := repo.LoadFromFile()
+	assert.Error(t, err, "Corruption in the middle of the WAL must not be silently skipped")
+}
+
+func TestInMemoryMetricsRepository_WAL_ReplayedLogRemovedWithoutWALMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	repo := newTestWALRepository(t, path)
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 4))
+	require.NoError(t, repo.Close())
+
+	// Перезапуск без WAL: журнал применяется, а после снапшота удаляется
+	plain := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, true)
+	require.NoError(t, plain.SaveToFile())
+
+	_, err := os.Stat(walPath(path))
+	assert.True(t, os.IsNotExist(err), "Replayed WAL should be removed after snapshot")
+
+	restored := NewInMemoryMetricsRepository(testutils.NewMockLogger(), path, true)
+	counter, _, err := restored.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), counter)
+}
+
+func TestInMemoryMetricsRepository_WAL_AppendFailureRollsBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	ctx := context.Background()
+
+	repo := newTestWALRepository(t, path)
+	require.NoError(t, repo.UpdateCounter(ctx, "requests", 3))
+
+	// Закрытый файл журнала имитирует ошибку записи на диск
+	require.NoError(t, repo.wal.file.Close())
+	assert.Error(t, repo.UpdateCounter(ctx, "requests", 5))
+
+	counter, _, err := repo.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), counter, "Update that failed to reach the WAL must be rolled back")
+	assert.Equal(t, uint64(1), repo.walSeq)
+}
+
+func TestInMemoryMetricsRepository_EnableWAL_EmptyPath(t *testing.T) {
+	repo := NewInMemoryMetricsRepository(testutils.NewMockLogger(), "", false)
+	assert.Error(t, repo.EnableWAL())
+}
@@ -1,24 +1,90 @@
 package routes
 
 import (
+	"net"
 	"net/http"
 	"strings"
 
+	"github.com/IgorKilipenko/metrical/api"
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/encryption"
 	"github.com/IgorKilipenko/metrical/internal/handler"
 	"github.com/IgorKilipenko/metrical/internal/middleware"
+	"github.com/IgorKilipenko/metrical/internal/openapi"
+	"github.com/IgorKilipenko/metrical/internal/ratelimit"
 	"github.com/go-chi/chi/v5"
 )
 
+// Spec спецификация HTTP API (api/openapi.json); встроена в бинарный файл, поэтому ошибка
+// разбора обнаруживается тестами, а не при работе сервера
+var Spec = openapi.MustLoad(api.OpenAPI)
+
+// Config настройки маршрутов метрик
+type Config struct {
+	SigningKey string                // Общий ключ подписи HMAC-SHA256 (пустая строка - подпись отключена)
+	Decryptor  *encryption.Decryptor // Расшифровка тел запросов приватным ключом (nil - отключена)
+
+	TrustedSubnet     *net.IPNet // Подсеть, из которой разрешены запросы на запись (nil - без ограничений)
+	TrustedReadSubnet *net.IPNet // Подсеть, из которой разрешено чтение метрик (nil - без ограничений)
+
+	// Прокси, которым доверяется X-Real-IP при ограничении частоты (пусто - ключом служит адрес соединения)
+	TrustedProxies []*net.IPNet
+
+	Auth *auth.Store // API токены с областями доступа (nil - аутентификация отключена)
+
+	WriteLimiter *ratelimit.Limiter // Ограничение частоты запросов на запись для каждого клиента (nil - без ограничений)
+	ReadLimiter  *ratelimit.Limiter // Ограничение частоты запросов на чтение для каждого клиента (nil - без ограничений)
+
+	MaxBodySize           int64 // Максимальный размер несжатого тела запроса в байтах (0 - без ограничений)
+	MaxCompressedBodySize int64 // Максимальный размер сжатого тела запроса в байтах (0 - без ограничений)
+}
+
+// DefaultConfig возвращает настройки маршрутов по умолчанию
+func DefaultConfig() *Config {
+	return &Config{
+		MaxBodySize:           middleware.DefaultMaxBodySize,
+		MaxCompressedBodySize: middleware.DefaultMaxCompressedBodySize,
+	}
+}
+
+// UnsignedIngestionEnabled сообщает, регистрируются ли эндпоинты сторонних протоколов
+// (remote_write, line protocol, OTLP), которые не проверяют подпись. При заданном SigningKey
+// без API токенов они открыли бы запись в обход подписи, поэтому в этом случае отключаются.
+func (c *Config) UnsignedIngestionEnabled() bool {
+	return c.SigningKey == "" || c.Auth != nil
+}
+
 // SetupMetricsRoutes настраивает маршруты для метрик
 func SetupMetricsRoutes(handler *handler.MetricsHandler) *chi.Mux {
+	return SetupMetricsRoutesWithConfig(handler, DefaultConfig())
+}
+
+// SetupMetricsRoutesWithConfig настраивает маршруты для метрик с заданными настройками
+func SetupMetricsRoutesWithConfig(handler *handler.MetricsHandler, config *Config) *chi.Mux {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	// Запросы проверяются по спецификации после аутентификации и ограничения частоты,
+	// чтобы неаутентифицированные клиенты не получали подробностей об ошибках
+	validator := openapi.NewValidator(Spec)
+
+	// Расшифровка и распаковка тела (gzip, deflate, zstd) с ограничением размера; подключаются после
+	// проверки подсети, аутентификации и ограничения частоты, чтобы отклоненные запросы не тратили CPU
+	body := []func(http.Handler) http.Handler{
+		middleware.DecryptMiddleware(config.Decryptor, config.MaxCompressedBodySize),
+		middleware.CompressionMiddleware(&middleware.CompressionConfig{
+			MaxCompressedSize:   config.MaxCompressedBodySize,
+			MaxDecompressedSize: config.MaxBodySize,
+			MinResponseSize:     middleware.DefaultMinResponseSize,
+		}),
+	}
+
 	r := chi.NewRouter()
 
 	// Добавляем middleware для логирования
 	r.Use(middleware.LoggingMiddleware())
 
-	// Добавляем middleware для поддержки gzip
-	r.Use(middleware.GzipMiddleware())
-
 	// Настраиваем автоматическую обработку trailing slash
 	r.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -30,23 +96,91 @@ func SetupMetricsRoutes(handler *handler.MetricsHandler) *chi.Mux {
 		})
 	})
 
-	// Простые тестовые маршруты
-	r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("Router is working"))
+	if config.UnsignedIngestionEnabled() {
+		// Prometheus remote_write сжимает тело snappy и не подписывает запросы, поэтому эндпоинт
+		// обходит распаковку и проверку подписи и ограничивает размер тела сам
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.TrustedSubnetMiddleware(config.TrustedSubnet))
+			r.Use(middleware.AuthMiddleware(config.Auth, auth.ScopeWrite))
+			r.Use(middleware.RateLimitMiddleware(config.WriteLimiter, config.TrustedProxies))
+
+			r.Post("/api/v1/write", handler.RemoteWrite)
+		})
+
+		// Клиенты сторонних протоколов (Telegraf, OpenTelemetry SDK) сжимают тела запросов,
+		// но не подписывают их: запись проверяется подсетью и токеном
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.TrustedSubnetMiddleware(config.TrustedSubnet))
+			r.Use(middleware.AuthMiddleware(config.Auth, auth.ScopeWrite))
+			r.Use(middleware.RateLimitMiddleware(config.WriteLimiter, config.TrustedProxies))
+			r.Use(body...)
+
+			// Запись в формате InfluxDB line protocol (Telegraf outputs.influxdb_v2)
+			r.Post("/api/v2/write", handler.WriteLineProtocol)
+
+			// OTLP/HTTP экспорт метрик OpenTelemetry (protobuf и JSON)
+			r.Post("/v1/metrics", handler.WriteOTLPMetrics)
+		})
+	}
+
+	r.Group(func(r chi.Router) {
+		r.Use(body...)
+
+		// Спецификация OpenAPI и страница документации доступны без аутентификации
+		r.Get("/openapi.json", openapi.SpecHandler(api.OpenAPI))
+		r.Get("/docs", openapi.DocsHandler())
+
+		// Простые тестовые маршруты
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.SignatureMiddleware(config.SigningKey))
+
+			r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("Router is working"))
+			})
+
+			r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("pong"))
+			})
+		})
 	})
 
-	r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("pong"))
+	// Маршруты записи метрик доступны только из доверенной подсети
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.TrustedSubnetMiddleware(config.TrustedSubnet))
+		r.Use(middleware.AuthMiddleware(config.Auth, auth.ScopeWrite))
+		r.Use(middleware.RateLimitMiddleware(config.WriteLimiter, config.TrustedProxies))
+		r.Use(body...)
+		// Проверяем подписи запросов и подписываем ответы (после распаковки)
+		r.Use(middleware.SignatureMiddleware(config.SigningKey))
+		r.Use(middleware.RequestValidationMiddleware(validator))
+
+		r.Post("/update/{type}/{name}/{value}", handler.UpdateMetric)
+		r.Post("/update", handler.UpdateMetricJSON)
+		r.Post("/updates", handler.UpdateMetricsBatch)
 	})
 
-	// Основные маршруты метрик
-	r.Get("/", handler.GetAllMetrics)
-	r.Post("/update/{type}/{name}/{value}", handler.UpdateMetric)
-	r.Get("/value/{type}/{name}", handler.GetMetricValue)
+	// Маршруты чтения следуют отдельной политике
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.TrustedSubnetMiddleware(config.TrustedReadSubnet))
+		r.Use(middleware.AuthMiddleware(config.Auth, auth.ScopeRead))
+		r.Use(middleware.RateLimitMiddleware(config.ReadLimiter, config.TrustedProxies))
+		r.Use(body...)
+		r.Use(middleware.SignatureMiddleware(config.SigningKey))
+		r.Use(middleware.RequestValidationMiddleware(validator))
 
-	// JSON API маршруты
-	r.Post("/update", handler.UpdateMetricJSON)
-	r.Post("/value", handler.GetMetricJSON)
+		r.Get("/", handler.GetAllMetrics)
+		r.Get("/value/{type}/{name}", handler.GetMetricValue)
+		r.Post("/value", handler.GetMetricJSON)
+
+		// История значений метрик
+		r.Get("/api/v1/history/{type}/{name}", handler.GetMetricHistory)
+
+		// Состояние правил алертинга
+		r.Get("/api/v1/alerts", handler.GetAlerts)
+
+		// Метрики в формате Prometheus/OpenMetrics для scrape
+		r.Get("/metrics", handler.GetPrometheusMetrics)
+	})
 
 	return r
 }
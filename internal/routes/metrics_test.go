@@ -1,16 +1,31 @@
 package routes
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/IgorKilipenko/metrical/api"
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/encryption"
 	"github.com/IgorKilipenko/metrical/internal/handler"
+	"github.com/IgorKilipenko/metrical/internal/otlp"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+	"github.com/IgorKilipenko/metrical/internal/ratelimit"
+	"github.com/IgorKilipenko/metrical/internal/remotewrite"
 	"github.com/IgorKilipenko/metrical/internal/repository"
 	"github.com/IgorKilipenko/metrical/internal/service"
+	"github.com/IgorKilipenko/metrical/internal/signature"
 	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSetupMetricsRoutes(t *testing.T) {
@@ -80,6 +95,59 @@ func TestSetupMetricsRoutes(t *testing.T) {
 		}
 	})
 
+	// Тестируем POST /updates
+	t.Run("POST /updates", func(t *testing.T) {
+		body := `[{"id":"batch_gauge","type":"gauge","value":1.5},{"id":"batch_counter","type":"counter","delta":2}]`
+		req := httptest.NewRequest("POST", "/updates", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", w.Code)
+		}
+	})
+
+	// Тестируем GET /api/v1/history/{type}/{name} без хранения истории
+	t.Run("GET /api/v1/history/gauge/test", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/history/gauge/test", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("Expected status 501, got %d", w.Code)
+		}
+	})
+
+	// Тестируем GET /api/v1/alerts без правил алертинга
+	t.Run("GET /api/v1/alerts", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/alerts", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("Expected status 501, got %d", w.Code)
+		}
+	})
+
+	// Тестируем GET /metrics в формате Prometheus
+	t.Run("GET /metrics", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "# TYPE test gauge\ntest 123.45\n") {
+			t.Errorf("Expected gauge test in exposition, got %s", w.Body.String())
+		}
+	})
+
 	// Тестируем несуществующий маршрут
 	t.Run("GET /nonexistent", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/nonexistent", nil)
@@ -124,3 +192,486 @@ func TestSetupMetricsRoutes_JSONEndpoints(t *testing.T) {
 	// Проверяем, что роутер содержит маршруты (базовая проверка)
 	// Более детальная проверка маршрутов требует сложной настройки chi контекста
 }
+
+func TestSetupMetricsRoutesWithConfig_Signature(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repository := repository.NewInMemoryMetricsRepository(mockLogger, testutils.TestMetricsFile, false)
+	service := service.NewMetricsService(repository, mockLogger)
+	handler, err := handler.NewMetricsHandler(service, mockLogger)
+	if err != nil {
+		t.Fatalf("failed to create metrics handler: %v", err)
+	}
+
+	router := SetupMetricsRoutesWithConfig(handler, &Config{SigningKey: "secret"})
+	body := `{"id":"PollCount","type":"counter","delta":5}`
+
+	t.Run("unsigned update rejected", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/update", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		_, exists, _ := service.GetCounter(context.Background(), "PollCount")
+		assert.False(t, exists, "Unsigned update must not be applied")
+	})
+
+	t.Run("unsigned metric in signed body rejected", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/update", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(signature.HeaderName, signature.Sign([]byte(body), "secret"))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		_, exists, _ := service.GetCounter(context.Background(), "PollCount")
+		assert.False(t, exists, "Metric without hash must not be applied")
+	})
+
+	t.Run("signed update accepted", func(t *testing.T) {
+		signedBody := `{"id":"PollCount","type":"counter","delta":5,"hash":"` +
+			signature.Sign([]byte("PollCount:counter:5"), "secret") + `"}`
+		req := httptest.NewRequest("POST", "/update", strings.NewReader(signedBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(signature.HeaderName, signature.Sign([]byte(signedBody), "secret"))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, signature.Verify(w.Body.Bytes(), "secret", w.Header().Get(signature.HeaderName)))
+	})
+}
+
+func TestSetupMetricsRoutesWithConfig_Encryption(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repository := repository.NewInMemoryMetricsRepository(mockLogger, testutils.TestMetricsFile, false)
+	service := service.NewMetricsService(repository, mockLogger)
+	handler, err := handler.NewMetricsHandler(service, mockLogger)
+	if err != nil {
+		t.Fatalf("failed to create metrics handler: %v", err)
+	}
+
+	privateKeyPath, publicKeyPath := testutils.WriteTestRSAKeys(t)
+	decryptor, err := encryption.NewDecryptorFromFile(privateKeyPath)
+	if err != nil {
+		t.Fatalf("failed to load private key: %v", err)
+	}
+	encryptor, err := encryption.NewEncryptorFromFile(publicKeyPath)
+	if err != nil {
+		t.Fatalf("failed to load public key: %v", err)
+	}
+
+	router := SetupMetricsRoutesWithConfig(handler, &Config{Decryptor: decryptor})
+
+	// Тело сначала сжимается, затем шифруется - как в агенте
+	var compressed bytes.Buffer
+	gzWriter := gzip.NewWriter(&compressed)
+	gzWriter.Write([]byte(`{"id":"PollCount","type":"counter","delta":5}`))
+	gzWriter.Close()
+
+	ciphertext, err := encryptor.Encrypt(compressed.Bytes())
+	if err != nil {
+		t.Fatalf("failed to encrypt body: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/update", bytes.NewReader(ciphertext))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set(encryption.HeaderName, encryption.Scheme)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+	value, _, _ := service.GetCounter(context.Background(), "PollCount")
+	assert.Equal(t, int64(5), value)
+}
+
+func TestSetupMetricsRoutesWithConfig_TrustedSubnet(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repository := repository.NewInMemoryMetricsRepository(mockLogger, testutils.TestMetricsFile, false)
+	service := service.NewMetricsService(repository, mockLogger)
+	handler, err := handler.NewMetricsHandler(service, mockLogger)
+	if err != nil {
+		t.Fatalf("failed to create metrics handler: %v", err)
+	}
+
+	_, writeSubnet, _ := net.ParseCIDR("10.0.0.0/8")
+	_, readSubnet, _ := net.ParseCIDR("192.168.0.0/16")
+
+	do := func(router http.Handler, method, path, realIP string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = realIP + ":12345"
+		req.Header.Set("X-Real-IP", realIP)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("writes restricted, reads open", func(t *testing.T) {
+		router := SetupMetricsRoutesWithConfig(handler, &Config{TrustedSubnet: writeSubnet})
+
+		assert.Equal(t, http.StatusForbidden, do(router, "POST", "/update/counter/requests/1", "172.16.0.1"))
+		_, exists, _ := service.GetCounter(context.Background(), "requests")
+		assert.False(t, exists, "Update from untrusted subnet must not be applied")
+
+		assert.Equal(t, http.StatusOK, do(router, "POST", "/update/counter/requests/1", "10.1.2.3"))
+		assert.Equal(t, http.StatusOK, do(router, "GET", "/value/counter/requests", "172.16.0.1"))
+	})
+
+	t.Run("spoofed X-Real-IP", func(t *testing.T) {
+		router := SetupMetricsRoutesWithConfig(handler, &Config{TrustedSubnet: writeSubnet})
+
+		req := httptest.NewRequest("POST", "/update/gauge/spoofed/1", nil)
+		req.RemoteAddr = "172.16.0.1:12345"
+		req.Header.Set("X-Real-IP", "10.1.2.3")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, "Header inside subnet must not admit a connection from outside")
+		_, exists, _ := service.GetGauge(context.Background(), "spoofed")
+		assert.False(t, exists)
+	})
+
+	t.Run("separate read policy", func(t *testing.T) {
+		router := SetupMetricsRoutesWithConfig(handler, &Config{TrustedSubnet: writeSubnet, TrustedReadSubnet: readSubnet})
+
+		assert.Equal(t, http.StatusForbidden, do(router, "GET", "/value/counter/requests", "10.1.2.3"))
+		assert.Equal(t, http.StatusForbidden, do(router, "GET", "/", "10.1.2.3"))
+		assert.Equal(t, http.StatusOK, do(router, "GET", "/value/counter/requests", "192.168.1.1"))
+		assert.Equal(t, http.StatusForbidden, do(router, "POST", "/update/counter/requests/1", "192.168.1.1"))
+	})
+}
+
+func TestSetupMetricsRoutesWithConfig_Auth(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repository := repository.NewInMemoryMetricsRepository(mockLogger, testutils.TestMetricsFile, false)
+	service := service.NewMetricsService(repository, mockLogger)
+	handler, err := handler.NewMetricsHandler(service, mockLogger)
+	if err != nil {
+		t.Fatalf("failed to create metrics handler: %v", err)
+	}
+
+	store, err := auth.NewStore([]auth.Token{
+		{Name: "agent", Token: "write-token", Scope: auth.ScopeWrite, Prefix: "host1."},
+		{Name: "dashboard", Token: "read-token", Scope: auth.ScopeRead},
+	})
+	if err != nil {
+		t.Fatalf("failed to create token store: %v", err)
+	}
+	router := SetupMetricsRoutesWithConfig(handler, &Config{Auth: store})
+
+	do := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do("POST", "/update/counter/host1.requests/1", ""))
+	assert.Equal(t, http.StatusForbidden, do("POST", "/update/counter/host1.requests/1", "read-token"))
+	assert.Equal(t, http.StatusForbidden, do("POST", "/update/counter/host2.requests/1", "write-token"))
+	assert.Equal(t, http.StatusOK, do("POST", "/update/counter/host1.requests/1", "write-token"))
+
+	assert.Equal(t, http.StatusForbidden, do("GET", "/value/counter/host1.requests", "write-token"))
+	assert.Equal(t, http.StatusOK, do("GET", "/value/counter/host1.requests", "read-token"))
+	assert.Equal(t, http.StatusForbidden, do("GET", "/api/v1/alerts", "write-token"), "Alerts follow the read policy")
+	assert.Equal(t, http.StatusOK, do("GET", "/ping", ""), "Service endpoints stay public")
+}
+
+func TestSetupMetricsRoutesWithConfig_RateLimit(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repository := repository.NewInMemoryMetricsRepository(mockLogger, testutils.TestMetricsFile, false)
+	service := service.NewMetricsService(repository, mockLogger)
+	handler, err := handler.NewMetricsHandler(service, mockLogger)
+	if err != nil {
+		t.Fatalf("failed to create metrics handler: %v", err)
+	}
+
+	writeLimiter, err := ratelimit.NewLimiter(&ratelimit.Config{Rate: 1, Burst: 2, MaxClients: 10})
+	if err != nil {
+		t.Fatalf("failed to create rate limiter: %v", err)
+	}
+	router := SetupMetricsRoutesWithConfig(handler, &Config{WriteLimiter: writeLimiter})
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Real-IP", "10.0.0.1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, do("POST", "/update/counter/requests/1").Code)
+	assert.Equal(t, http.StatusOK, do("POST", "/update/counter/requests/1").Code)
+
+	w := do("POST", "/update/counter/requests/1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	value, _, _ := service.GetCounter(context.Background(), "requests")
+	assert.Equal(t, int64(2), value, "Limited request must not be applied")
+
+	// Чтение ограничивается отдельно
+	assert.Equal(t, http.StatusOK, do("GET", "/value/counter/requests").Code)
+
+	// Лимит проверяется до распаковки и проверки подписи: тело отклоненного запроса не читается
+	req := httptest.NewRequest("POST", "/updates", strings.NewReader("not gzip"))
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+	req.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestSetupMetricsRoutesWithConfig_LineProtocol(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repository := repository.NewInMemoryMetricsRepository(mockLogger, testutils.TestMetricsFile, false)
+	service := service.NewMetricsService(repository, mockLogger)
+	handler, err := handler.NewMetricsHandler(service, mockLogger)
+	if err != nil {
+		t.Fatalf("failed to create metrics handler: %v", err)
+	}
+
+	store, err := auth.NewStore([]auth.Token{{Name: "telegraf", Token: "telegraf-token", Scope: auth.ScopeWrite}})
+	if err != nil {
+		t.Fatalf("failed to create token store: %v", err)
+	}
+	router := SetupMetricsRoutesWithConfig(handler, &Config{Auth: store})
+
+	// Telegraf сжимает тело gzip и передает токен в схеме InfluxDB "Token"
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	gz.Write([]byte("cpu,host=server01 usage_idle=98.5,procs=12i 1700000000000\n"))
+	gz.Close()
+
+	req := httptest.NewRequest("POST", "/api/v2/write?org=metrical&bucket=telegraf&precision=ms", &body)
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Authorization", "Token telegraf-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code, "Body: %s", w.Body.String())
+
+	procs, exists, err := repository.GetCounter(context.Background(), "cpu.host=server01.procs")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, int64(12), procs)
+}
+
+func TestSetupMetricsRoutesWithConfig_RemoteWrite(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repository := repository.NewInMemoryMetricsRepository(mockLogger, testutils.TestMetricsFile, false)
+	service := service.NewMetricsService(repository, mockLogger)
+	handler, err := handler.NewMetricsHandler(service, mockLogger)
+	if err != nil {
+		t.Fatalf("failed to create metrics handler: %v", err)
+	}
+
+	store, err := auth.NewStore([]auth.Token{{Name: "prometheus", Token: "prometheus-token", Scope: auth.ScopeWrite}})
+	if err != nil {
+		t.Fatalf("failed to create token store: %v", err)
+	}
+	// Подпись включена, но Prometheus не передает HashSHA256: remote_write проверяется только токеном
+	router := SetupMetricsRoutesWithConfig(handler, &Config{Auth: store, SigningKey: "secret"})
+
+	body := remotewrite.Encode(&remotewrite.WriteRequest{Timeseries: []remotewrite.TimeSeries{{
+		Labels:  []remotewrite.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "node"}},
+		Samples: []remotewrite.Sample{{Value: 1, Timestamp: 1700000000000}},
+	}}})
+
+	send := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/write", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("Content-Encoding", "snappy")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, send("").Code)
+
+	w := send("prometheus-token")
+	assert.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+
+	up, exists, err := repository.GetGauge(context.Background(), "up.job=node")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 1.0, up)
+
+	// Остальные маршруты записи по-прежнему требуют подпись
+	req := httptest.NewRequest("POST", "/update/gauge/up/1", nil)
+	req.Header.Set("Authorization", "Bearer prometheus-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSetupMetricsRoutesWithConfig_OTLP(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repository := repository.NewInMemoryMetricsRepository(mockLogger, testutils.TestMetricsFile, false)
+	service := service.NewMetricsService(repository, mockLogger)
+	handler, err := handler.NewMetricsHandler(service, mockLogger)
+	if err != nil {
+		t.Fatalf("failed to create metrics handler: %v", err)
+	}
+
+	store, err := auth.NewStore([]auth.Token{{Name: "checkout", Token: "otel-token", Scope: auth.ScopeWrite}})
+	if err != nil {
+		t.Fatalf("failed to create token store: %v", err)
+	}
+	// OpenTelemetry SDK сжимает тело gzip и не передает HashSHA256
+	router := SetupMetricsRoutesWithConfig(handler, &Config{Auth: store, SigningKey: "secret"})
+
+	value := 42.0
+	payload := (&otlp.ExportMetricsServiceRequest{ResourceMetrics: []otlp.ResourceMetrics{{
+		Resource: otlp.Resource{Attributes: []otlp.KeyValue{otlp.StringAttribute("service.name", "checkout")}},
+		ScopeMetrics: []otlp.ScopeMetrics{{Metrics: []otlp.Metric{{
+			Name:  "queue.size",
+			Gauge: &otlp.Gauge{DataPoints: []otlp.NumberDataPoint{{AsDouble: &value}}},
+		}}}},
+	}}}).MarshalProto()
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	gz.Write(payload)
+	gz.Close()
+
+	req := httptest.NewRequest("POST", "/v1/metrics", &body)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Authorization", "Bearer otel-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, "Body: %s", w.Body.String())
+
+	queue, exists, err := repository.GetGauge(context.Background(), "checkout.queue.size")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, 42.0, queue)
+}
+
+func TestSetupMetricsRoutesWithConfig_UnsignedIngestionRequiresAuth(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repository := repository.NewInMemoryMetricsRepository(mockLogger, testutils.TestMetricsFile, false)
+	service := service.NewMetricsService(repository, mockLogger)
+	handler, err := handler.NewMetricsHandler(service, mockLogger)
+	require.NoError(t, err)
+
+	// Подпись включена, токены не настроены: эндпоинты без проверки подписи не регистрируются
+	config := &Config{SigningKey: "secret"}
+	assert.False(t, config.UnsignedIngestionEnabled())
+	router := SetupMetricsRoutesWithConfig(handler, config)
+
+	for _, path := range []string{"/api/v1/write", "/api/v2/write", "/v1/metrics"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest("POST", path, strings.NewReader("up 1"))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+	}
+
+	_, exists, err := repository.GetGauge(context.Background(), "up")
+	require.NoError(t, err)
+	assert.False(t, exists, "Unsigned write must not reach the repository")
+
+	// Без подписи или с токенами эндпоинты доступны
+	assert.True(t, (&Config{}).UnsignedIngestionEnabled())
+	store, err := auth.NewStore([]auth.Token{{Name: "telegraf", Token: "token", Scope: auth.ScopeWrite}})
+	require.NoError(t, err)
+	assert.True(t, (&Config{SigningKey: "secret", Auth: store}).UnsignedIngestionEnabled())
+}
+
+// TestSpecMatchesRoutes проверяет, что маршруты chi и операции api/openapi.json совпадают:
+// новый маршрут нужно описать в спецификации, а удаленный - убрать из нее
+func TestSpecMatchesRoutes(t *testing.T) {
+	router := SetupMetricsRoutes(&handler.MetricsHandler{})
+
+	var routes []string
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes = append(routes, method+" "+route)
+		return nil
+	})
+	require.NoError(t, err)
+
+	var documented []string
+	for _, route := range Spec.Routes() {
+		documented = append(documented, route.String())
+	}
+
+	assert.ElementsMatch(t, documented, routes, "routes in SetupMetricsRoutes and api/openapi.json differ")
+}
+
+func TestSetupMetricsRoutes_OpenAPI(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repository := repository.NewInMemoryMetricsRepository(mockLogger, testutils.TestMetricsFile, false)
+	service := service.NewMetricsService(repository, mockLogger)
+	handler, err := handler.NewMetricsHandler(service, mockLogger)
+	require.NoError(t, err)
+
+	store, err := auth.NewStore([]auth.Token{{Name: "agent", Token: "write-token", Scope: auth.ScopeWrite}})
+	require.NoError(t, err)
+	router := SetupMetricsRoutesWithConfig(handler, &Config{Auth: store})
+
+	t.Run("specification served without token", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/openapi.json", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, string(api.OpenAPI), w.Body.String())
+	})
+
+	t.Run("docs page served without token", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/docs", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "openapi.json")
+	})
+
+	t.Run("invalid request rejected before handler", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/update", strings.NewReader(`{"id":"temperature","type":"histogram","value":"hot"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer write-token")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+		var p problem.Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		assert.Equal(t, problem.TypeValidation, p.Type)
+		assert.Equal(t, []problem.FieldError{
+			{Field: "type", Value: "histogram", Message: "must be one of gauge, counter"},
+			{Field: "value", Value: "hot", Message: "must be of type number"},
+		}, p.Errors)
+	})
+
+	t.Run("validation runs after authentication", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/update/histogram/temperature/1", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/IgorKilipenko/metrical/internal/logger"
 	models "github.com/IgorKilipenko/metrical/internal/model"
@@ -10,6 +12,9 @@ import (
 	"github.com/IgorKilipenko/metrical/internal/validation"
 )
 
+// ErrHistoryDisabled возвращается, если хранилище не ведет историю значений метрик
+var ErrHistoryDisabled = errors.New("metrics history is disabled")
+
 // MetricsService сервис для работы с метриками
 type MetricsService struct {
 	repository repository.MetricsRepository
@@ -43,7 +48,7 @@ func (s *MetricsService) UpdateMetric(ctx context.Context, req *validation.Metri
 		return s.updateCounterMetric(ctx, req.Name, req.Value.(int64))
 	default:
 		s.logger.Error("unsupported metric type", "type", req.Type, "name", req.Name)
-		return fmt.Errorf("unsupported metric type: %s", req.Type)
+		return fmt.Errorf("%w: %s", models.ErrUnsupportedMetricType, req.Type)
 	}
 }
 
@@ -54,20 +59,39 @@ func (s *MetricsService) UpdateMetricJSON(ctx context.Context, metric *models.Me
 	switch metric.MType {
 	case models.Gauge:
 		if metric.Value == nil {
-			return fmt.Errorf("value is required for gauge metric")
+			return models.ValidationError{Field: "value", Value: "null", Message: "is required for gauge metric"}
 		}
 		return s.updateGaugeMetric(ctx, metric.ID, *metric.Value)
 	case models.Counter:
 		if metric.Delta == nil {
-			return fmt.Errorf("delta is required for counter metric")
+			return models.ValidationError{Field: "delta", Value: "null", Message: "is required for counter metric"}
 		}
 		return s.updateCounterMetric(ctx, metric.ID, *metric.Delta)
 	default:
 		s.logger.Error("unsupported metric type", "type", metric.MType, "id", metric.ID)
-		return fmt.Errorf("unsupported metric type: %s", metric.MType)
+		return fmt.Errorf("%w: %s", models.ErrUnsupportedMetricType, metric.MType)
 	}
 }
 
+// UpdateMetricsBatch обновляет пакет метрик из JSON.
+// Пакет применяется целиком: при ошибке валидации любой метрики не обновляется ни одна.
+func (s *MetricsService) UpdateMetricsBatch(ctx context.Context, metrics []models.Metrics) error {
+	s.logger.Info("updating metrics batch", "count", len(metrics))
+
+	if err := validation.ValidateMetricsBatch(metrics); err != nil {
+		s.logger.Warn("metrics batch validation failed", "count", len(metrics), "error", err)
+		return err
+	}
+
+	if err := s.repository.UpdateBatch(ctx, metrics); err != nil {
+		s.logger.Error("failed to update metrics batch", "count", len(metrics), "error", err)
+		return err
+	}
+
+	s.logger.Debug("metrics batch updated successfully", "count", len(metrics))
+	return nil
+}
+
 // updateGaugeMetric содержит бизнес-логику для обновления gauge метрик
 func (s *MetricsService) updateGaugeMetric(ctx context.Context, name string, value float64) error {
 	s.logger.Debug("updating gauge metric", "name", name, "value", value)
@@ -193,7 +217,7 @@ func (s *MetricsService) GetMetricJSON(ctx context.Context, metric *models.Metri
 			return nil, err
 		}
 		if !exists {
-			return nil, fmt.Errorf("gauge metric not found: %s", metric.ID)
+			return nil, fmt.Errorf("gauge %w: %s", models.ErrMetricNotFound, metric.ID)
 		}
 		result.Value = &value
 
@@ -203,13 +227,102 @@ func (s *MetricsService) GetMetricJSON(ctx context.Context, metric *models.Metri
 			return nil, err
 		}
 		if !exists {
-			return nil, fmt.Errorf("counter metric not found: %s", metric.ID)
+			return nil, fmt.Errorf("counter %w: %s", models.ErrMetricNotFound, metric.ID)
 		}
 		result.Delta = &value
 
 	default:
-		return nil, fmt.Errorf("unsupported metric type: %s", metric.MType)
+		return nil, fmt.Errorf("%w: %s", models.ErrUnsupportedMetricType, metric.MType)
 	}
 
 	return result, nil
 }
+
+// GetMetricHistory возвращает историю метрики в интервале [from, to].
+// При step > 0 значения прореживаются по интервалам длины step:
+// для gauge берется среднее значение интервала, для counter - последнее.
+func (s *MetricsService) GetMetricHistory(ctx context.Context, metricType, name string, from, to time.Time, step time.Duration) (*models.MetricHistory, bool, error) {
+	s.logger.Debug("getting metric history", "type", metricType, "name", name, "from", from, "to", to, "step", step)
+
+	history, ok := s.repository.(repository.MetricsHistory)
+	if !ok {
+		return nil, false, ErrHistoryDisabled
+	}
+
+	switch metricType {
+	case models.Gauge, models.Counter:
+		// Тип поддерживается
+	default:
+		return nil, false, fmt.Errorf("%w: %s", models.ErrUnsupportedMetricType, metricType)
+	}
+
+	samples, exists, err := history.GetHistory(ctx, metricType, name, from, to)
+	if err != nil {
+		s.logger.Error("failed to get metric history", "type", metricType, "name", name, "error", err)
+		return nil, false, err
+	}
+	if !exists {
+		s.logger.Debug("metric history not found", "type", metricType, "name", name)
+		return nil, false, nil
+	}
+
+	result := &models.MetricHistory{
+		ID:      name,
+		MType:   metricType,
+		From:    from,
+		To:      to,
+		Samples: samples,
+	}
+	if step > 0 {
+		result.Samples = downsampleSamples(samples, metricType, step)
+		result.Step = step.String()
+	}
+
+	s.logger.Debug("metric history retrieved", "type", metricType, "name", name, "count", len(result.Samples))
+	return result, true, nil
+}
+
+// downsampleSamples объединяет значения в интервалы длины step.
+// Время результирующего значения - начало интервала.
+func downsampleSamples(samples []models.Sample, metricType string, step time.Duration) []models.Sample {
+	result := make([]models.Sample, 0)
+
+	var (
+		bucket time.Time
+		sum    float64
+		count  int
+		last   models.Sample
+	)
+
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		sample := models.Sample{Timestamp: bucket}
+		if metricType == models.Gauge {
+			avg := sum / float64(count)
+			sample.Value = &avg
+		} else {
+			sample.Delta = last.Delta
+		}
+		result = append(result, sample)
+	}
+
+	for _, sample := range samples {
+		start := sample.Timestamp.Truncate(step)
+		if count > 0 && !start.Equal(bucket) {
+			flush()
+			sum, count = 0, 0
+		}
+
+		bucket = start
+		last = sample
+		if sample.Value != nil {
+			sum += *sample.Value
+		}
+		count++
+	}
+	flush()
+
+	return result
+}
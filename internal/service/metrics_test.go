@@ -467,6 +467,7 @@ func TestMetricsService_GetMetricJSON(t *testing.T) {
 		name        string
 		metric      *models.Metrics
 		expectError bool
+		expectedErr error
 		expected    *models.Metrics
 	}{
 		{
@@ -502,6 +503,7 @@ func TestMetricsService_GetMetricJSON(t *testing.T) {
 				MType: "gauge",
 			},
 			expectError: true,
+			expectedErr: models.ErrMetricNotFound,
 		},
 		{
 			name: "counter metric not found",
@@ -510,6 +512,7 @@ func TestMetricsService_GetMetricJSON(t *testing.T) {
 				MType: "counter",
 			},
 			expectError: true,
+			expectedErr: models.ErrMetricNotFound,
 		},
 		{
 			name: "unsupported metric type",
@@ -518,6 +521,7 @@ func TestMetricsService_GetMetricJSON(t *testing.T) {
 				MType: "invalid",
 			},
 			expectError: true,
+			expectedErr: models.ErrUnsupportedMetricType,
 		},
 	}
 
@@ -526,7 +530,7 @@ func TestMetricsService_GetMetricJSON(t *testing.T) {
 			result, err := service.GetMetricJSON(ctx, tt.metric)
 
 			if tt.expectError {
-				assert.Error(t, err)
+				assert.ErrorIs(t, err, tt.expectedErr)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expected.ID, result.ID)
@@ -541,3 +545,134 @@ func TestMetricsService_GetMetricJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricsService_GetMetricHistory(t *testing.T) {
+	inner := repository.NewInMemoryMetricsRepository(testutils.NewMockLogger(), testutils.TestMetricsFile, false)
+	history, err := repository.NewHistoryRepository(inner, nil, testutils.NewMockLogger())
+	require.NoError(t, err)
+
+	service := NewMetricsService(history, testutils.NewMockLogger())
+	ctx := context.Background()
+
+	require.NoError(t, service.UpdateMetric(ctx, &validation.MetricRequest{Type: models.Gauge, Name: "HeapAlloc", Value: 100.0}))
+	require.NoError(t, service.UpdateMetric(ctx, &validation.MetricRequest{Type: models.Gauge, Name: "HeapAlloc", Value: 200.0}))
+
+	from, to := time.Now().Add(-time.Minute), time.Now().Add(time.Minute)
+
+	t.Run("raw samples", func(t *testing.T) {
+		result, exists, err := service.GetMetricHistory(ctx, models.Gauge, "HeapAlloc", from, to, 0)
+		require.NoError(t, err)
+		assert.True(t, exists)
+		assert.Equal(t, "HeapAlloc", result.ID)
+		assert.Equal(t, models.Gauge, result.MType)
+		assert.Empty(t, result.Step)
+		require.Len(t, result.Samples, 2)
+	})
+
+	t.Run("downsampled", func(t *testing.T) {
+		result, exists, err := service.GetMetricHistory(ctx, models.Gauge, "HeapAlloc", from, to, time.Hour)
+		require.NoError(t, err)
+		assert.True(t, exists)
+		assert.Equal(t, "1h0m0s", result.Step)
+		assert.NotEmpty(t, result.Samples)
+		assert.LessOrEqual(t, len(result.Samples), 2)
+	})
+
+	t.Run("unknown metric", func(t *testing.T) {
+		_, exists, err := service.GetMetricHistory(ctx, models.Counter, "HeapAlloc", from, to, 0)
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		_, _, err := service.GetMetricHistory(ctx, "invalid", "HeapAlloc", from, to, 0)
+		assert.Error(t, err)
+	})
+}
+
+func TestMetricsService_GetMetricHistory_Disabled(t *testing.T) {
+	repository := repository.NewInMemoryMetricsRepository(testutils.NewMockLogger(), testutils.TestMetricsFile, false)
+	service := NewMetricsService(repository, testutils.NewMockLogger())
+
+	_, _, err := service.GetMetricHistory(context.Background(), models.Gauge, "HeapAlloc", time.Now().Add(-time.Hour), time.Now(), 0)
+	assert.ErrorIs(t, err, ErrHistoryDisabled)
+}
+
+func TestDownsampleSamples(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	gauge := func(offset time.Duration, value float64) models.Sample {
+		return models.Sample{Timestamp: base.Add(offset), Value: &value}
+	}
+	counter := func(offset time.Duration, delta int64) models.Sample {
+		return models.Sample{Timestamp: base.Add(offset), Delta: &delta}
+	}
+
+	t.Run("gauge average per bucket", func(t *testing.T) {
+		samples := []models.Sample{
+			gauge(0, 10),
+			gauge(20*time.Second, 20),
+			gauge(40*time.Second, 30),
+			gauge(70*time.Second, 100),
+			// Интервал без значений пропускается
+			gauge(190*time.Second, 5),
+		}
+
+		result := downsampleSamples(samples, models.Gauge, time.Minute)
+		require.Len(t, result, 3)
+		assert.Equal(t, base, result[0].Timestamp)
+		assert.Equal(t, 20.0, *result[0].Value)
+		assert.Equal(t, base.Add(time.Minute), result[1].Timestamp)
+		assert.Equal(t, 100.0, *result[1].Value)
+		assert.Equal(t, base.Add(3*time.Minute), result[2].Timestamp)
+		assert.Equal(t, 5.0, *result[2].Value)
+	})
+
+	t.Run("counter last value per bucket", func(t *testing.T) {
+		samples := []models.Sample{
+			counter(0, 1),
+			counter(30*time.Second, 5),
+			counter(65*time.Second, 9),
+		}
+
+		result := downsampleSamples(samples, models.Counter, time.Minute)
+		require.Len(t, result, 2)
+		assert.Equal(t, int64(5), *result[0].Delta)
+		assert.Equal(t, int64(9), *result[1].Delta)
+		assert.Nil(t, result[0].Value)
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		assert.Empty(t, downsampleSamples(nil, models.Gauge, time.Minute))
+	})
+}
+
+func TestMetricsService_UpdateMetricsBatch(t *testing.T) {
+	repository := repository.NewInMemoryMetricsRepository(testutils.NewMockLogger(), testutils.TestMetricsFile, false)
+	service := NewMetricsService(repository, testutils.NewMockLogger())
+	ctx := context.Background()
+
+	value := 42.5
+	delta := int64(3)
+
+	err := service.UpdateMetricsBatch(ctx, []models.Metrics{
+		{ID: "TestGauge", MType: models.Gauge, Value: &value},
+		{ID: "TestCounter", MType: models.Counter, Delta: &delta},
+	})
+	require.NoError(t, err)
+
+	counter, exists, err := service.GetCounter(ctx, "TestCounter")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, int64(3), counter)
+
+	// Пакет с некорректной метрикой отклоняется целиком
+	err = service.UpdateMetricsBatch(ctx, []models.Metrics{
+		{ID: "TestCounter", MType: models.Counter, Delta: &delta},
+		{ID: "", MType: models.Gauge, Value: &value},
+	})
+	assert.True(t, models.IsValidationError(err))
+
+	counter, _, err = service.GetCounter(ctx, "TestCounter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), counter, "Rejected batch must not be applied")
+}
@@ -0,0 +1,68 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+)
+
+// HeaderName заголовок с подписью тела запроса или ответа
+const HeaderName = "HashSHA256"
+
+// Sign возвращает подпись данных HMAC-SHA256 в hex формате
+func Sign(data []byte, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись данных за постоянное время
+func Verify(data []byte, key, hash string) bool {
+	expected, err := hex.DecodeString(hash)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(data)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// RequestData возвращает подписываемые данные запроса: тело или путь, если тело пустое
+// (например, для обновления метрики через параметры URL)
+func RequestData(path string, body []byte) []byte {
+	if len(body) == 0 {
+		return []byte(path)
+	}
+	return body
+}
+
+// MetricHash возвращает подпись метрики по строке "id:type:value" (для counter - delta)
+func MetricHash(metric *models.Metrics, key string) string {
+	return Sign([]byte(metricPayload(metric)), key)
+}
+
+// SignMetric заполняет поле Hash метрики
+func SignMetric(metric *models.Metrics, key string) {
+	metric.Hash = MetricHash(metric, key)
+}
+
+// VerifyMetric проверяет поле Hash метрики
+func VerifyMetric(metric *models.Metrics, key string) bool {
+	return Verify([]byte(metricPayload(metric)), key, metric.Hash)
+}
+
+// metricPayload возвращает подписываемое представление метрики
+func metricPayload(metric *models.Metrics) string {
+	value := ""
+	switch {
+	case metric.MType == models.Gauge && metric.Value != nil:
+		value = strconv.FormatFloat(*metric.Value, 'f', -1, 64)
+	case metric.MType == models.Counter && metric.Delta != nil:
+		value = strconv.FormatInt(*metric.Delta, 10)
+	}
+	return metric.ID + ":" + metric.MType + ":" + value
+}
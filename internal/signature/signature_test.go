@@ -0,0 +1,57 @@
+package signature
+
+import (
+	"testing"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVerify(t *testing.T) {
+	data := []byte(`[{"id":"PollCount","type":"counter","delta":5}]`)
+	hash := Sign(data, "secret")
+
+	assert.Len(t, hash, 64, "Hex encoded SHA256 should be 64 characters")
+	assert.Equal(t, hash, Sign(data, "secret"), "Signature should be deterministic")
+
+	tests := []struct {
+		name     string
+		data     []byte
+		key      string
+		hash     string
+		expected bool
+	}{
+		{name: "valid signature", data: data, key: "secret", hash: hash, expected: true},
+		{name: "wrong key", data: data, key: "other", hash: hash, expected: false},
+		{name: "modified data", data: append([]byte(" "), data...), key: "secret", hash: hash, expected: false},
+		{name: "invalid hex", data: data, key: "secret", hash: "not-hex", expected: false},
+		{name: "empty hash", data: data, key: "secret", hash: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Verify(tt.data, tt.key, tt.hash))
+		})
+	}
+}
+
+func TestSignMetric(t *testing.T) {
+	value := 42.5
+	delta := int64(5)
+
+	gauge := &models.Metrics{ID: "HeapAlloc", MType: models.Gauge, Value: &value}
+	counter := &models.Metrics{ID: "PollCount", MType: models.Counter, Delta: &delta}
+
+	SignMetric(gauge, "secret")
+	SignMetric(counter, "secret")
+
+	assert.Equal(t, Sign([]byte("HeapAlloc:gauge:42.5"), "secret"), gauge.Hash)
+	assert.Equal(t, Sign([]byte("PollCount:counter:5"), "secret"), counter.Hash)
+	assert.True(t, VerifyMetric(gauge, "secret"))
+	assert.True(t, VerifyMetric(counter, "secret"))
+
+	// Изменение значения после подписи делает подпись недействительной
+	*counter.Delta = 50
+	assert.False(t, VerifyMetric(counter, "secret"))
+	assert.False(t, VerifyMetric(gauge, "other"))
+}
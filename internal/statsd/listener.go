@@ -0,0 +1,176 @@
+// Package statsd принимает метрики по протоколу StatsD через UDP.
+package statsd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/IgorKilipenko/metrical/internal/logger"
+	models "github.com/IgorKilipenko/metrical/internal/model"
+)
+
+// MaxPacketSize максимальный размер UDP пакета
+const MaxPacketSize = 65535
+
+// MetricsWriter получатель метрик (реализуется service.MetricsService)
+type MetricsWriter interface {
+	UpdateMetricsBatch(ctx context.Context, metrics []models.Metrics) error
+	GetGauge(ctx context.Context, name string) (float64, bool, error)
+}
+
+// Listener принимает UDP пакеты StatsD и записывает метрики каждого пакета одним пакетным обновлением
+type Listener struct {
+	conn          net.PacketConn
+	service       MetricsWriter
+	logger        logger.Logger
+	trustedSubnet *net.IPNet // nil - пакеты принимаются с любого адреса
+}
+
+// Listen открывает UDP сокет на адресе addr (например, ":8125")
+func Listen(addr string, service MetricsWriter, logger logger.Logger) (*Listener, error) {
+	if service == nil {
+		return nil, fmt.Errorf("metrics service cannot be nil")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger cannot be nil")
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen statsd on %s: %w", addr, err)
+	}
+
+	return &Listener{
+		conn:    conn,
+		service: service,
+		logger:  logger,
+	}, nil
+}
+
+// Addr возвращает адрес UDP сокета
+func (l *Listener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// SetTrustedSubnet ограничивает прием пакетов адресами отправителей из подсети; nil снимает ограничение.
+// Вызывается до Serve.
+func (l *Listener) SetTrustedSubnet(subnet *net.IPNet) {
+	l.trustedSubnet = subnet
+}
+
+// Serve читает пакеты до отмены контекста; сокет закрывается при выходе
+func (l *Listener) Serve(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() { l.conn.Close() })
+	defer stop()
+	defer l.conn.Close()
+
+	l.logger.Info("statsd listener started", "addr", l.Addr().String())
+
+	buf := make([]byte, MaxPacketSize)
+	for {
+		n, remote, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				l.logger.Info("statsd listener stopped")
+				return nil
+			}
+			return fmt.Errorf("failed to read statsd packet: %w", err)
+		}
+
+		if !l.allowed(remote) {
+			l.logger.Warn("statsd packet from untrusted address dropped", "remote_addr", remote.String())
+			continue
+		}
+
+		if err := l.HandlePacket(ctx, buf[:n]); err != nil {
+			l.logger.Error("failed to store statsd packet", "remote_addr", remote.String(), "error", err)
+		}
+	}
+}
+
+// allowed сообщает, что адрес отправителя пакета находится в доверенной подсети
+func (l *Listener) allowed(remote net.Addr) bool {
+	if l.trustedSubnet == nil {
+		return true
+	}
+	addr, ok := remote.(*net.UDPAddr)
+	return ok && l.trustedSubnet.Contains(addr.IP)
+}
+
+// HandlePacket разбирает строки пакета и записывает их одним пакетным обновлением.
+// Некорректные строки пропускаются с предупреждением, не отменяя остальные.
+func (l *Listener) HandlePacket(ctx context.Context, packet []byte) error {
+	var metrics []models.Metrics
+	gauges := make(map[string]int) // Индекс gauge в пакете для относительных изменений
+
+	for _, line := range strings.Split(string(packet), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		metric, err := l.parseMetric(ctx, line, metrics, gauges)
+		if err != nil {
+			l.logger.Warn("invalid statsd line", "line", line, "error", err)
+			continue
+		}
+
+		if metric.MType == models.Gauge {
+			if i, ok := gauges[metric.ID]; ok {
+				metrics[i] = *metric
+				continue
+			}
+			gauges[metric.ID] = len(metrics)
+		}
+		metrics = append(metrics, *metric)
+	}
+
+	if len(metrics) == 0 {
+		return nil
+	}
+	return l.service.UpdateMetricsBatch(ctx, metrics)
+}
+
+// parseMetric преобразует строку в метрику; относительный gauge применяется к значению
+// из текущего пакета, а при его отсутствии - к значению из хранилища
+func (l *Listener) parseMetric(ctx context.Context, line string, batch []models.Metrics, gauges map[string]int) (*models.Metrics, error) {
+	sample, err := ParseLine(line)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := sample.Request()
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type == models.Counter {
+		delta := req.Value.(int64)
+		return &models.Metrics{ID: req.Name, MType: models.Counter, Delta: &delta}, nil
+	}
+
+	value := req.Value.(float64)
+	if sample.Relative() {
+		current, err := l.currentGauge(ctx, req.Name, batch, gauges)
+		if err != nil {
+			return nil, err
+		}
+		value += current
+	}
+	return &models.Metrics{ID: req.Name, MType: models.Gauge, Value: &value}, nil
+}
+
+// currentGauge возвращает текущее значение gauge (0, если метрики еще нет)
+func (l *Listener) currentGauge(ctx context.Context, name string, batch []models.Metrics, gauges map[string]int) (float64, error) {
+	if i, ok := gauges[name]; ok {
+		return *batch[i].Value, nil
+	}
+	value, _, err := l.service.GetGauge(ctx, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read gauge %s: %w", name, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,136 @@
+package statsd
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/repository"
+	"github.com/IgorKilipenko/metrical/internal/service"
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestListener создает слушатель на свободном локальном порту с хранилищем в памяти
+func newTestListener(t *testing.T) (*Listener, *service.MetricsService) {
+	t.Helper()
+
+	mockLogger := testutils.NewMockLogger()
+	repo := repository.NewInMemoryMetricsRepository(mockLogger, "", false)
+	metricsService := service.NewMetricsService(repo, mockLogger)
+
+	listener, err := Listen("127.0.0.1:0", metricsService, mockLogger)
+	require.NoError(t, err)
+	return listener, metricsService
+}
+
+func TestListener_HandlePacket(t *testing.T) {
+	listener, metricsService := newTestListener(t)
+	defer listener.conn.Close()
+	ctx := context.Background()
+
+	packet := "requests:1|c\nrequests:2|c|@0.5\nqueue.size:10|g\nqueue.size:+5|g\ndb.query:320|ms\n" +
+		"broken line\nusers:42|s\nrequests:1.5|c\n"
+	require.NoError(t, listener.HandlePacket(ctx, []byte(packet)))
+
+	requests, exists, err := metricsService.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	require.True(t, exists)
+	assert.Equal(t, int64(5), requests, "1 + 2/0.5")
+
+	queue, _, err := metricsService.GetGauge(ctx, "queue.size")
+	require.NoError(t, err)
+	assert.Equal(t, 15.0, queue, "Relative gauge applies to value from the same packet")
+
+	timer, _, err := metricsService.GetGauge(ctx, "db.query")
+	require.NoError(t, err)
+	assert.Equal(t, 320.0, timer)
+
+	// Относительное изменение в следующем пакете применяется к сохраненному значению
+	require.NoError(t, listener.HandlePacket(ctx, []byte("queue.size:-20|g")))
+	queue, _, err = metricsService.GetGauge(ctx, "queue.size")
+	require.NoError(t, err)
+	assert.Equal(t, -5.0, queue)
+
+	// Пакет без корректных строк ничего не записывает
+	assert.NoError(t, listener.HandlePacket(ctx, []byte("garbage\n\n")))
+}
+
+func TestListener_Serve(t *testing.T) {
+	listener, metricsService := newTestListener(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- listener.Serve(ctx) }()
+
+	conn, err := net.Dial("udp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("legacy.hits:7|c\nlegacy.temp:36.6|g"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		hits, exists, err := metricsService.GetCounter(context.Background(), "legacy.hits")
+		return err == nil && exists && hits == 7
+	}, time.Second, 10*time.Millisecond)
+
+	temp, _, err := metricsService.GetGauge(context.Background(), "legacy.temp")
+	require.NoError(t, err)
+	assert.Equal(t, 36.6, temp)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not stop after context cancellation")
+	}
+}
+
+func TestListen_Errors(t *testing.T) {
+	mockLogger := testutils.NewMockLogger()
+	repo := repository.NewInMemoryMetricsRepository(mockLogger, "", false)
+	metricsService := service.NewMetricsService(repo, mockLogger)
+
+	_, err := Listen("127.0.0.1:0", nil, mockLogger)
+	assert.Error(t, err)
+
+	_, err = Listen("invalid-address", metricsService, mockLogger)
+	assert.Error(t, err)
+}
+
+func TestListener_ServeTrustedSubnet(t *testing.T) {
+	listener, metricsService := newTestListener(t)
+	_, subnet, err := net.ParseCIDR("127.0.0.2/32")
+	require.NoError(t, err)
+	listener.SetTrustedSubnet(subnet)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go listener.Serve(ctx)
+
+	untrusted, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer untrusted.Close()
+	trusted, err := net.ListenPacket("udp", "127.0.0.2:0")
+	require.NoError(t, err)
+	defer trusted.Close()
+
+	// Пакеты обрабатываются по порядку, поэтому после приема второго первый уже отброшен
+	_, err = untrusted.WriteTo([]byte("untrusted.hits:5|c"), listener.Addr())
+	require.NoError(t, err)
+	_, err = trusted.WriteTo([]byte("trusted.hits:3|c"), listener.Addr())
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		hits, exists, err := metricsService.GetCounter(context.Background(), "trusted.hits")
+		return err == nil && exists && hits == 3
+	}, time.Second, 10*time.Millisecond)
+
+	_, exists, err := metricsService.GetCounter(context.Background(), "untrusted.hits")
+	require.NoError(t, err)
+	assert.False(t, exists, "Packet from address outside trusted subnet must be dropped")
+}
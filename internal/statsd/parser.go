@@ -0,0 +1,86 @@
+package statsd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/IgorKilipenko/metrical/internal/validation"
+)
+
+// Типы метрик StatsD
+const (
+	TypeCounter   = "c"
+	TypeGauge     = "g"
+	TypeTimer     = "ms"
+	TypeHistogram = "h" // Расширение DogStatsD, обрабатывается как таймер
+)
+
+// Sample разобранная строка StatsD вида name:value|type[|@rate][|#tags]
+type Sample struct {
+	Name       string
+	Type       string  // c, g, ms или h
+	Value      string  // Значение в исходном виде (для gauge может начинаться с '+' или '-')
+	SampleRate float64 // Частота выборки счетчика (1 - без прореживания)
+}
+
+// Relative сообщает, что строка изменяет gauge на величину, а не задает значение ("name:+4|g")
+func (s *Sample) Relative() bool {
+	return s.Type == TypeGauge && (strings.HasPrefix(s.Value, "+") || strings.HasPrefix(s.Value, "-"))
+}
+
+// ParseLine разбирает одну строку StatsD. Теги DogStatsD (|#...) и прочие расширения игнорируются.
+func ParseLine(line string) (*Sample, error) {
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid statsd line %q: expected name:value|type", line)
+	}
+
+	fields := strings.Split(rest, "|")
+	if len(fields) < 2 || fields[0] == "" {
+		return nil, fmt.Errorf("invalid statsd line %q: expected name:value|type", line)
+	}
+
+	sample := &Sample{Name: name, Value: fields[0], Type: fields[1], SampleRate: 1}
+	switch sample.Type {
+	case TypeCounter, TypeGauge, TypeTimer, TypeHistogram:
+	default:
+		return nil, fmt.Errorf("unsupported statsd metric type %q in line %q", sample.Type, line)
+	}
+
+	for _, field := range fields[2:] {
+		rate, ok := strings.CutPrefix(field, "@")
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(rate, 64)
+		if err != nil || parsed <= 0 || parsed > 1 {
+			return nil, fmt.Errorf("invalid sample rate %q in line %q", rate, line)
+		}
+		sample.SampleRate = parsed
+	}
+
+	return sample, nil
+}
+
+// Request преобразует строку в запрос обновления метрики с той же валидацией, что и legacy API.
+// Счетчик масштабируется на частоту выборки и округляется, таймер становится gauge.
+// Для относительного gauge (Relative) значение запроса - величина изменения текущего значения.
+func (s *Sample) Request() (*validation.MetricRequest, error) {
+	switch s.Type {
+	case TypeCounter:
+		value := s.Value
+		if s.SampleRate != 1 {
+			parsed, err := strconv.ParseFloat(s.Value, 64)
+			if err != nil {
+				return nil, models.ValidationError{Field: "value", Value: s.Value, Message: "must be a valid number"}
+			}
+			value = strconv.FormatFloat(math.Round(parsed/s.SampleRate), 'f', 0, 64)
+		}
+		return validation.ValidateMetricRequest(models.Counter, s.Name, value)
+	default:
+		return validation.ValidateMetricRequest(models.Gauge, s.Name, s.Value)
+	}
+}
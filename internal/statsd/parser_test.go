@@ -0,0 +1,90 @@
+package statsd
+
+import (
+	"testing"
+
+	models "github.com/IgorKilipenko/metrical/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected Sample
+	}{
+		{"counter", "requests:1|c", Sample{Name: "requests", Type: TypeCounter, Value: "1", SampleRate: 1}},
+		{"counter with sample rate", "requests:3|c|@0.1", Sample{Name: "requests", Type: TypeCounter, Value: "3", SampleRate: 0.1}},
+		{"gauge", "queue.size:42.5|g", Sample{Name: "queue.size", Type: TypeGauge, Value: "42.5", SampleRate: 1}},
+		{"relative gauge", "queue.size:-3|g", Sample{Name: "queue.size", Type: TypeGauge, Value: "-3", SampleRate: 1}},
+		{"timer", "db.query:320|ms", Sample{Name: "db.query", Type: TypeTimer, Value: "320", SampleRate: 1}},
+		{"dogstatsd tags", "requests:1|c|#env:prod,host:a", Sample{Name: "requests", Type: TypeCounter, Value: "1", SampleRate: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample, err := ParseLine(tt.line)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, *sample)
+		})
+	}
+}
+
+func TestParseLine_Invalid(t *testing.T) {
+	for _, line := range []string{
+		"requests",
+		":1|c",
+		"requests:1",
+		"requests:|c",
+		"users:42|s",
+		"requests:1|c|@0",
+		"requests:1|c|@2",
+		"requests:1|c|@fast",
+	} {
+		t.Run(line, func(t *testing.T) {
+			_, err := ParseLine(line)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSample_Request(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		expectedType  string
+		expectedValue any
+		relative      bool
+	}{
+		{"counter", "requests:5|c", models.Counter, int64(5), false},
+		{"sampled counter is scaled", "requests:3|c|@0.1", models.Counter, int64(30), false},
+		{"sampled counter is rounded", "requests:1|c|@0.3", models.Counter, int64(3), false},
+		{"gauge", "load:0.75|g", models.Gauge, 0.75, false},
+		{"relative gauge keeps delta", "load:+1.5|g", models.Gauge, 1.5, true},
+		{"timer maps to gauge", "db.query:320|ms", models.Gauge, 320.0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample, err := ParseLine(tt.line)
+			require.NoError(t, err)
+
+			req, err := sample.Request()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedType, req.Type)
+			assert.Equal(t, tt.expectedValue, req.Value)
+			assert.Equal(t, tt.relative, sample.Relative())
+		})
+	}
+
+	t.Run("validation errors", func(t *testing.T) {
+		for _, line := range []string{"requests:1.5|c", "requests:abc|c|@0.5", "load:high|g"} {
+			sample, err := ParseLine(line)
+			require.NoError(t, err)
+
+			_, err = sample.Request()
+			assert.True(t, models.IsValidationError(err), "line %q: %v", line, err)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package testutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// WriteTestRSAKeys генерирует пару RSA ключей и сохраняет ее во временный каталог теста.
+// Приватный ключ сохраняется в формате PKCS#8 ("PRIVATE KEY"), публичный - PKIX ("PUBLIC KEY").
+func WriteTestRSAKeys(t testing.TB) (privateKeyPath, publicKeyPath string) {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	dir := t.TempDir()
+	privateKeyPath = filepath.Join(dir, "private.pem")
+	publicKeyPath = filepath.Join(dir, "public.pem")
+
+	if err := os.WriteFile(privateKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		t.Fatalf("failed to write private key: %v", err)
+	}
+	if err := os.WriteFile(publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		t.Fatalf("failed to write public key: %v", err)
+	}
+
+	return privateKeyPath, publicKeyPath
+}
+
+// TestCertificates пути к тестовым сертификатам, подписанным одним CA
+type TestCertificates struct {
+	CAFile         string // Сертификат CA
+	ServerCertFile string // Сертификат сервера для localhost и 127.0.0.1
+	ServerKeyFile  string // Ключ сервера
+	ClientCertFile string // Клиентский сертификат для mTLS
+	ClientKeyFile  string // Ключ клиента
+}
+
+// WriteTestCertificates генерирует CA, сертификаты сервера и клиента (ECDSA P-256)
+// и сохраняет их в формате PEM во временный каталог теста.
+func WriteTestCertificates(t testing.TB) *TestCertificates {
+	t.Helper()
+
+	dir := t.TempDir()
+	certs := &TestCertificates{
+		CAFile:         filepath.Join(dir, "ca.pem"),
+		ServerCertFile: filepath.Join(dir, "server.pem"),
+		ServerKeyFile:  filepath.Join(dir, "server-key.pem"),
+		ClientCertFile: filepath.Join(dir, "client.pem"),
+		ClientKeyFile:  filepath.Join(dir, "client-key.pem"),
+	}
+
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := time.Now().Add(24 * time.Hour)
+
+	caKey := generateTestECDSAKey(t)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "metrical test CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+	writeTestPEM(t, certs.CAFile, "CERTIFICATE", caDER)
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage, certFile, keyFile string) {
+		key := generateTestECDSAKey(t)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    notBefore,
+			NotAfter:     notAfter,
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			DNSNames:     []string{"localhost"},
+			IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatalf("failed to create %s certificate: %v", name, err)
+		}
+		keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("failed to marshal %s key: %v", name, err)
+		}
+		writeTestPEM(t, certFile, "CERTIFICATE", der)
+		writeTestPEM(t, keyFile, "PRIVATE KEY", keyDER)
+	}
+
+	issue(2, "localhost", x509.ExtKeyUsageServerAuth, certs.ServerCertFile, certs.ServerKeyFile)
+	issue(3, "metrical-agent", x509.ExtKeyUsageClientAuth, certs.ClientCertFile, certs.ClientKeyFile)
+
+	return certs
+}
+
+// generateTestECDSAKey генерирует ключ ECDSA P-256
+func generateTestECDSAKey(t testing.TB) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ECDSA key: %v", err)
+	}
+	return key
+}
+
+// writeTestPEM сохраняет PEM блок в файл
+func writeTestPEM(t testing.TB, path, blockType string, der []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
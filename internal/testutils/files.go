@@ -8,6 +8,10 @@ const (
 	TestFlagFile    = "/tmp/test-flag-metrics.json"
 )
 
+// TestDatabaseDSNEnv переменная окружения с DSN тестовой базы PostgreSQL.
+// Интеграционные тесты репозитория пропускаются, если она не задана.
+const TestDatabaseDSNEnv = "TEST_DATABASE_DSN"
+
 // GetTestFilePath возвращает путь к тестовому файлу с указанным именем
 func GetTestFilePath(name string) string {
 	return "/tmp/test-" + name + "-metrics.json"
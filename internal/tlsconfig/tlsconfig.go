@@ -0,0 +1,85 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// MinVersion минимальная версия TLS для сервера и клиента
+const MinVersion = tls.VersionTLS12
+
+// NewServerConfig создает TLS конфигурацию сервера из сертификата и ключа в формате PEM.
+// Если задан clientCAFile, сервер требует клиентский сертификат, подписанный одним из CA бандла (mTLS).
+func NewServerConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("TLS certificate and key must be set together")
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   MinVersion,
+		Certificates: []tls.Certificate{certificate},
+	}
+
+	if clientCAFile != "" {
+		pool, err := LoadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// NewClientConfig создает TLS конфигурацию клиента.
+// caFile - бандл CA для проверки сертификата сервера (пустая строка - системные корневые сертификаты).
+// certFile и keyFile - клиентский сертификат для mTLS (задаются вместе или не задаются).
+func NewClientConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("TLS client certificate and key must be set together")
+	}
+
+	config := &tls.Config{
+		MinVersion: MinVersion,
+	}
+
+	if caFile != "" {
+		pool, err := LoadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if certFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// LoadCertPool загружает бандл сертификатов CA в формате PEM
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no valid certificates found in CA bundle %s", path)
+	}
+	return pool, nil
+}
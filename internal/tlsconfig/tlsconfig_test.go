@@ -0,0 +1,118 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTLSTestServer запускает HTTPS сервер с переданной конфигурацией
+func newTLSTestServer(t *testing.T, config *tls.Config) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = config
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// doRequest выполняет GET запрос клиентом с переданной TLS конфигурацией
+func doRequest(config *tls.Config, url string) error {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func TestNewServerConfig(t *testing.T) {
+	certs := testutils.WriteTestCertificates(t)
+
+	t.Run("TLS", func(t *testing.T) {
+		config, err := NewServerConfig(certs.ServerCertFile, certs.ServerKeyFile, "")
+		require.NoError(t, err)
+		assert.Equal(t, uint16(MinVersion), config.MinVersion)
+		assert.Equal(t, tls.NoClientCert, config.ClientAuth)
+		assert.Len(t, config.Certificates, 1)
+	})
+
+	t.Run("mutual TLS", func(t *testing.T) {
+		config, err := NewServerConfig(certs.ServerCertFile, certs.ServerKeyFile, certs.CAFile)
+		require.NoError(t, err)
+		assert.Equal(t, tls.RequireAndVerifyClientCert, config.ClientAuth)
+		assert.NotNil(t, config.ClientCAs)
+	})
+
+	t.Run("invalid settings", func(t *testing.T) {
+		_, err := NewServerConfig(certs.ServerCertFile, "", "")
+		assert.Error(t, err)
+
+		_, err = NewServerConfig(certs.ServerCertFile, certs.ClientKeyFile, "")
+		assert.Error(t, err, "Mismatched key should be rejected")
+
+		_, err = NewServerConfig(certs.ServerCertFile, certs.ServerKeyFile, filepath.Join(t.TempDir(), "missing.pem"))
+		assert.Error(t, err)
+	})
+}
+
+func TestNewClientConfig(t *testing.T) {
+	certs := testutils.WriteTestCertificates(t)
+
+	config, err := NewClientConfig("", "", "")
+	require.NoError(t, err)
+	assert.Nil(t, config.RootCAs, "System roots should be used without CA bundle")
+	assert.Empty(t, config.Certificates)
+
+	config, err = NewClientConfig(certs.CAFile, certs.ClientCertFile, certs.ClientKeyFile)
+	require.NoError(t, err)
+	assert.NotNil(t, config.RootCAs)
+	assert.Len(t, config.Certificates, 1)
+
+	_, err = NewClientConfig(certs.CAFile, certs.ClientCertFile, "")
+	assert.Error(t, err)
+}
+
+func TestLoadCertPool_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0600))
+
+	_, err := LoadCertPool(path)
+	assert.Error(t, err)
+}
+
+func TestMutualTLS_Handshake(t *testing.T) {
+	certs := testutils.WriteTestCertificates(t)
+
+	serverConfig, err := NewServerConfig(certs.ServerCertFile, certs.ServerKeyFile, certs.CAFile)
+	require.NoError(t, err)
+	server := newTLSTestServer(t, serverConfig)
+
+	t.Run("client with certificate", func(t *testing.T) {
+		clientConfig, err := NewClientConfig(certs.CAFile, certs.ClientCertFile, certs.ClientKeyFile)
+		require.NoError(t, err)
+		assert.NoError(t, doRequest(clientConfig, server.URL))
+	})
+
+	t.Run("client without certificate", func(t *testing.T) {
+		clientConfig, err := NewClientConfig(certs.CAFile, "", "")
+		require.NoError(t, err)
+		assert.Error(t, doRequest(clientConfig, server.URL))
+	})
+
+	t.Run("client without CA", func(t *testing.T) {
+		clientConfig, err := NewClientConfig("", certs.ClientCertFile, certs.ClientKeyFile)
+		require.NoError(t, err)
+		assert.Error(t, doRequest(clientConfig, server.URL), "Server certificate should not be trusted")
+	})
+}
@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"fmt"
 	"strconv"
 
 	models "github.com/IgorKilipenko/metrical/internal/model"
@@ -88,3 +89,48 @@ func ValidateMetricType(metricType string) error {
 	}
 	return nil
 }
+
+// ValidateMetric валидирует метрику в JSON формате: имя, тип и значение, соответствующее типу
+func ValidateMetric(metric *models.Metrics) error {
+	if err := ValidateMetricName(metric.ID); err != nil {
+		return models.ValidationError{Field: "id", Value: metric.ID, Message: "cannot be empty"}
+	}
+
+	if err := ValidateMetricType(metric.MType); err != nil {
+		return err
+	}
+
+	switch metric.MType {
+	case models.Gauge:
+		if metric.Value == nil {
+			return models.ValidationError{Field: "value", Value: "null", Message: "is required for gauge metric"}
+		}
+	case models.Counter:
+		if metric.Delta == nil {
+			return models.ValidationError{Field: "delta", Value: "null", Message: "is required for counter metric"}
+		}
+	}
+
+	return nil
+}
+
+// ValidateMetricsBatch валидирует пакет метрик целиком.
+// Возвращает ошибку для первой некорректной метрики с указанием ее индекса.
+func ValidateMetricsBatch(metrics []models.Metrics) error {
+	if len(metrics) == 0 {
+		return models.ValidationError{Field: "metrics", Value: "[]", Message: "batch cannot be empty"}
+	}
+
+	for i := range metrics {
+		if err := ValidateMetric(&metrics[i]); err != nil {
+			validationErr, ok := err.(models.ValidationError)
+			if !ok {
+				return err
+			}
+			validationErr.Field = fmt.Sprintf("metrics[%d].%s", i, validationErr.Field)
+			return validationErr
+		}
+	}
+
+	return nil
+}
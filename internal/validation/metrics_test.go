@@ -187,3 +187,83 @@ func TestValidateMetricType(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateMetric(t *testing.T) {
+	value := 23.5
+	delta := int64(10)
+
+	tests := []struct {
+		name    string
+		metric  models.Metrics
+		wantErr bool
+	}{
+		{
+			name:   "Valid gauge",
+			metric: models.Metrics{ID: "temperature", MType: models.Gauge, Value: &value},
+		},
+		{
+			name:   "Valid counter",
+			metric: models.Metrics{ID: "requests", MType: models.Counter, Delta: &delta},
+		},
+		{
+			name:    "Empty ID",
+			metric:  models.Metrics{MType: models.Gauge, Value: &value},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown type",
+			metric:  models.Metrics{ID: "temperature", MType: "unknown", Value: &value},
+			wantErr: true,
+		},
+		{
+			name:    "Gauge without value",
+			metric:  models.Metrics{ID: "temperature", MType: models.Gauge, Delta: &delta},
+			wantErr: true,
+		},
+		{
+			name:    "Counter without delta",
+			metric:  models.Metrics{ID: "requests", MType: models.Counter, Value: &value},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMetric(&tt.metric)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.True(t, models.IsValidationError(err))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateMetricsBatch(t *testing.T) {
+	value := 23.5
+	delta := int64(10)
+
+	t.Run("Valid batch", func(t *testing.T) {
+		err := ValidateMetricsBatch([]models.Metrics{
+			{ID: "temperature", MType: models.Gauge, Value: &value},
+			{ID: "requests", MType: models.Counter, Delta: &delta},
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Empty batch", func(t *testing.T) {
+		err := ValidateMetricsBatch(nil)
+		assert.True(t, models.IsValidationError(err))
+	})
+
+	t.Run("Invalid item reports index", func(t *testing.T) {
+		err := ValidateMetricsBatch([]models.Metrics{
+			{ID: "temperature", MType: models.Gauge, Value: &value},
+			{ID: "requests", MType: models.Counter},
+		})
+		assert.True(t, models.IsValidationError(err))
+		assert.Contains(t, err.Error(), "metrics[1].delta")
+	})
+}
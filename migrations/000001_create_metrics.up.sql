@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS metrics (
+    id         VARCHAR(255)     NOT NULL,
+    type       VARCHAR(16)      NOT NULL CHECK (type IN ('gauge', 'counter')),
+    delta      BIGINT,
+    value      DOUBLE PRECISION,
+    updated_at TIMESTAMPTZ      NOT NULL DEFAULT now(),
+    PRIMARY KEY (type, id),
+    CHECK (
+        (type = 'gauge' AND value IS NOT NULL AND delta IS NULL) OR
+        (type = 'counter' AND delta IS NOT NULL AND value IS NULL)
+    )
+);
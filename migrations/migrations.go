@@ -0,0 +1,12 @@
+// Package migrations содержит версионированные SQL-скрипты схемы базы данных.
+//
+// Файлы именуются по шаблону NNNNNN_описание.up.sql / NNNNNN_описание.down.sql
+// и встраиваются в бинарник, чтобы сервер мог применить их при старте.
+package migrations
+
+import "embed"
+
+// FS содержит все файлы миграций
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,278 @@
+package metricalclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/pkg/models"
+)
+
+// Значения настроек буфера по умолчанию
+const (
+	DefaultFlushInterval = 10 * time.Second
+	DefaultMaxBufferSize = 1000
+)
+
+// ErrBufferClosed возвращается при записи в закрытый буфер
+var ErrBufferClosed = errors.New("buffer is closed")
+
+// BufferConfig настройки буферизованной отправки
+type BufferConfig struct {
+	// FlushInterval - интервал фоновой отправки накопленных метрик
+	FlushInterval time.Duration
+
+	// MaxSize - число различных метрик в буфере, при котором отправка начинается досрочно
+	MaxSize int
+
+	// OnError - обработчик ошибок фоновой отправки (nil - ошибки не сообщаются).
+	// Вызывается из фоновой горутины буфера.
+	OnError func(error)
+}
+
+// DefaultBufferConfig возвращает настройки буфера по умолчанию
+func DefaultBufferConfig() *BufferConfig {
+	return &BufferConfig{
+		FlushInterval: DefaultFlushInterval,
+		MaxSize:       DefaultMaxBufferSize,
+	}
+}
+
+// Validate проверяет корректность настроек буфера
+func (c *BufferConfig) Validate() error {
+	if c.FlushInterval <= 0 {
+		return fmt.Errorf("flush interval must be positive")
+	}
+	if c.MaxSize <= 0 {
+		return fmt.Errorf("max buffer size must be positive")
+	}
+	return nil
+}
+
+// Buffer накапливает метрики в памяти и отправляет их пакетами (POST /updates) в фоне:
+// раз в FlushInterval или при накоплении MaxSize метрик. Для gauge сохраняется последнее
+// значение, приращения counter суммируются. Пакет, отправка которого завершилась неоднозначной
+// ошибкой, повторяется без изменений и с тем же ключом идемпотентности, пока сервер его не подтвердит;
+// новые метрики уходят следующим пакетом. Методы безопасны для использования из нескольких горутин.
+type Buffer struct {
+	client *Client
+	config BufferConfig
+
+	mu       sync.Mutex
+	gauges   map[string]float64
+	counters map[string]int64
+	closed   bool
+
+	flushMu sync.Mutex    // Пакеты отправляются по одному
+	pending *pendingBatch // Неподтвержденный пакет, защищен flushMu
+	full    chan struct{} // Сигнал досрочной отправки
+	done    chan struct{} // Закрывается при остановке буфера
+	stopped chan struct{} // Закрывается при завершении фоновой горутины
+
+	ctx    context.Context // Контекст фоновых отправок, отменяется при прерывании Close
+	cancel context.CancelFunc
+}
+
+// pendingBatch пакет метрик вместе с ключом идемпотентности, с которым он отправляется
+type pendingBatch struct {
+	metrics []models.Metrics
+	key     string
+}
+
+// NewBuffer создает буфер и запускает фоновую отправку (nil - настройки по умолчанию).
+// Буфер нужно закрыть методом Close, чтобы отправить оставшиеся метрики.
+func (c *Client) NewBuffer(config *BufferConfig) (*Buffer, error) {
+	if config == nil {
+		config = DefaultBufferConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid buffer configuration: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Buffer{
+		client:   c,
+		config:   *config,
+		gauges:   make(map[string]float64),
+		counters: make(map[string]int64),
+		full:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	go b.run()
+	return b, nil
+}
+
+// UpdateGauge сохраняет значение gauge метрики до следующей отправки
+func (b *Buffer) UpdateGauge(name string, value float64) error {
+	return b.add(func() { b.gauges[name] = value })
+}
+
+// AddCounter добавляет приращение counter метрики до следующей отправки
+func (b *Buffer) AddCounter(name string, delta int64) error {
+	return b.add(func() { b.counters[name] += delta })
+}
+
+// add изменяет буфер и запрашивает досрочную отправку при его заполнении
+func (b *Buffer) add(update func()) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrBufferClosed
+	}
+	update()
+	size := len(b.gauges) + len(b.counters)
+	b.mu.Unlock()
+
+	if size >= b.config.MaxSize {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush отправляет накопленные метрики одним пакетом. Сначала повторяется неподтвержденный
+// пакет предыдущей отправки; пока он не принят, новые метрики остаются в буфере.
+// Если сервер отклонил пакет (IsRejected), метрики возвращаются в буфер: приращения counter
+// суммируются с новыми, значения gauge восстанавливаются, если не были обновлены за время отправки.
+// После остальных ошибок сервер мог применить пакет, поэтому он сохраняется для повтора с тем же ключом.
+func (b *Buffer) Flush(ctx context.Context) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	if b.pending != nil {
+		if err := b.send(ctx, b.pending); err != nil {
+			return err
+		}
+	}
+
+	b.mu.Lock()
+	gauges, counters := b.gauges, b.counters
+	b.gauges = make(map[string]float64)
+	b.counters = make(map[string]int64)
+	b.mu.Unlock()
+
+	batch := make([]models.Metrics, 0, len(gauges)+len(counters))
+	for _, name := range sortedKeys(gauges) {
+		batch = append(batch, models.NewGauge(name, gauges[name]))
+	}
+	for _, name := range sortedKeys(counters) {
+		batch = append(batch, models.NewCounter(name, counters[name]))
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	key, err := NewIdempotencyKey()
+	if err != nil {
+		b.restore(batch)
+		return fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	return b.send(ctx, &pendingBatch{metrics: batch, key: key})
+}
+
+// send отправляет пакет с его ключом идемпотентности и решает его судьбу по результату
+// (вызывается под flushMu)
+func (b *Buffer) send(ctx context.Context, batch *pendingBatch) error {
+	err := b.client.UpdateBatch(WithIdempotencyKey(ctx, batch.key), batch.metrics)
+	switch {
+	case err == nil:
+		b.pending = nil
+	case IsRejected(err):
+		b.pending = nil
+		b.restore(batch.metrics)
+	default:
+		b.pending = batch
+	}
+	return err
+}
+
+// restore возвращает метрики отклоненного пакета в буфер
+func (b *Buffer) restore(metrics []models.Metrics) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, metric := range metrics {
+		switch metric.MType {
+		case models.Gauge:
+			if _, updated := b.gauges[metric.ID]; !updated {
+				b.gauges[metric.ID] = *metric.Value
+			}
+		case models.Counter:
+			b.counters[metric.ID] += *metric.Delta
+		}
+	}
+}
+
+// Close останавливает фоновую отправку и отправляет оставшиеся метрики.
+// Если ctx завершается раньше или последняя отправка не удалась, неотправленные метрики теряются.
+// Повторный вызов ничего не делает.
+func (b *Buffer) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	defer b.cancel()
+	close(b.done)
+
+	select {
+	case <-b.stopped:
+	case <-ctx.Done():
+		b.cancel()
+		<-b.stopped
+		return ctx.Err()
+	}
+
+	return b.Flush(ctx)
+}
+
+// run отправляет метрики по таймеру и при заполнении буфера до его закрытия
+func (b *Buffer) run() {
+	defer close(b.stopped)
+
+	ticker := time.NewTicker(b.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.full:
+		case <-b.done:
+			return
+		}
+
+		if err := b.Flush(b.ctx); err != nil && b.config.OnError != nil {
+			b.config.OnError(err)
+		}
+	}
+}
+
+// len возвращает число метрик в буфере
+func (b *Buffer) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.gauges) + len(b.counters)
+}
+
+// sortedKeys возвращает ключи в алфавитном порядке, чтобы пакеты были детерминированными
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,260 @@
+package metricalclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/middleware"
+	"github.com/IgorKilipenko/metrical/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchServer тестовый сервер, сохраняющий полученные пакеты
+type batchServer struct {
+	mu      sync.Mutex
+	batches [][]models.Metrics
+	fail    bool // Отвечать ошибкой, не принимая пакет
+}
+
+func (s *batchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	var batch []models.Metrics
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.batches = append(s.batches, batch)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *batchServer) setFail(fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+func (s *batchServer) received() [][]models.Metrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]models.Metrics(nil), s.batches...)
+}
+
+// newBufferedClient запускает тестовый сервер и создает клиента для него
+func newBufferedClient(t *testing.T) (*Client, *batchServer) {
+	t.Helper()
+
+	backend := &batchServer{}
+	server := httptest.NewServer(middleware.CompressionMiddleware(nil)(backend))
+	t.Cleanup(server.Close)
+
+	config := DefaultConfig()
+	config.ServerURL = server.URL
+	config.MaxAttempts = 1
+	client, err := New(config)
+	require.NoError(t, err)
+	return client, backend
+}
+
+func TestBuffer_Aggregation(t *testing.T) {
+	client, backend := newBufferedClient(t)
+	buffer, err := client.NewBuffer(&BufferConfig{FlushInterval: time.Hour, MaxSize: 100})
+	require.NoError(t, err)
+
+	require.NoError(t, buffer.UpdateGauge("temperature", 20))
+	require.NoError(t, buffer.UpdateGauge("temperature", 21.5))
+	require.NoError(t, buffer.AddCounter("requests", 2))
+	require.NoError(t, buffer.AddCounter("requests", 3))
+	assert.Equal(t, 2, buffer.len())
+
+	require.NoError(t, buffer.Flush(context.Background()))
+	assert.Zero(t, buffer.len())
+
+	// Пустой буфер не отправляется
+	require.NoError(t, buffer.Close(context.Background()))
+
+	batches := backend.received()
+	require.Len(t, batches, 1)
+	assert.Equal(t, []models.Metrics{
+		models.NewGauge("temperature", 21.5),
+		models.NewCounter("requests", 5),
+	}, batches[0], "Gauge keeps the last value, counter deltas are summed")
+}
+
+func TestBuffer_FlushFailure(t *testing.T) {
+	client, backend := newBufferedClient(t)
+	buffer, err := client.NewBuffer(&BufferConfig{FlushInterval: time.Hour, MaxSize: 100})
+	require.NoError(t, err)
+	defer buffer.Close(context.Background())
+
+	backend.setFail(true)
+	require.NoError(t, buffer.AddCounter("requests", 2))
+	require.NoError(t, buffer.UpdateGauge("temperature", 20))
+	require.Error(t, buffer.Flush(context.Background()))
+
+	// Метрики возвращаются в буфер: приращения суммируются, новое значение gauge не затирается
+	require.NoError(t, buffer.AddCounter("requests", 1))
+	require.NoError(t, buffer.UpdateGauge("temperature", 25))
+	assert.Equal(t, 2, buffer.len())
+
+	backend.setFail(false)
+	require.NoError(t, buffer.Flush(context.Background()))
+
+	batches := backend.received()
+	require.Len(t, batches, 1)
+	assert.Equal(t, []models.Metrics{
+		models.NewGauge("temperature", 25),
+		models.NewCounter("requests", 3),
+	}, batches[0])
+}
+
+// lostResponseClient выполняет запрос, но теряет ответ первых lost запросов,
+// как при таймауте после того, как сервер применил запись
+type lostResponseClient struct {
+	mu   sync.Mutex
+	lost int
+	keys []string
+}
+
+func (c *lostResponseClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys = append(c.keys, req.Header.Get(IdempotencyKeyHeader))
+	if c.lost > 0 {
+		c.lost--
+		resp.Body.Close()
+		return nil, errors.New("context deadline exceeded (Client.Timeout exceeded while awaiting headers)")
+	}
+	return resp, nil
+}
+
+func TestBuffer_AmbiguousFailure(t *testing.T) {
+	server := newMetricsServer(t, "", nil)
+	httpClient := &lostResponseClient{lost: 1}
+
+	config := DefaultConfig()
+	config.ServerURL = server.URL
+	config.HTTPClient = httpClient
+	config.MaxAttempts = 1
+	client, err := New(config)
+	require.NoError(t, err)
+
+	buffer, err := client.NewBuffer(&BufferConfig{FlushInterval: time.Hour, MaxSize: 100})
+	require.NoError(t, err)
+	defer buffer.Close(context.Background())
+
+	ctx := context.Background()
+	require.NoError(t, buffer.AddCounter("requests", 2))
+	require.Error(t, buffer.Flush(ctx), "Lost response should be reported")
+
+	// Новые приращения не смешиваются с неподтвержденным пакетом
+	require.NoError(t, buffer.AddCounter("requests", 3))
+	require.NoError(t, buffer.Flush(ctx))
+
+	counter, err := client.Get(ctx, models.Counter, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), *counter.Delta, "Batch applied before the timeout must not be applied again")
+
+	httpClient.mu.Lock()
+	defer httpClient.mu.Unlock()
+	require.Len(t, httpClient.keys, 4, "Lost batch, its retry, the next batch and Get")
+	assert.Equal(t, httpClient.keys[0], httpClient.keys[1], "Unacknowledged batch should be resent with the same key")
+	assert.NotEqual(t, httpClient.keys[1], httpClient.keys[2], "Next batch should get a new key")
+}
+
+func TestBuffer_BackgroundFlush(t *testing.T) {
+	t.Run("max size", func(t *testing.T) {
+		client, backend := newBufferedClient(t)
+		buffer, err := client.NewBuffer(&BufferConfig{FlushInterval: time.Hour, MaxSize: 2})
+		require.NoError(t, err)
+		defer buffer.Close(context.Background())
+
+		require.NoError(t, buffer.AddCounter("requests", 1))
+		require.NoError(t, buffer.AddCounter("errors", 1))
+
+		assert.Eventually(t, func() bool { return len(backend.received()) == 1 }, time.Second, 5*time.Millisecond,
+			"Full buffer should be flushed without waiting for interval")
+	})
+
+	t.Run("interval", func(t *testing.T) {
+		client, backend := newBufferedClient(t)
+		buffer, err := client.NewBuffer(&BufferConfig{FlushInterval: 10 * time.Millisecond, MaxSize: 100})
+		require.NoError(t, err)
+		defer buffer.Close(context.Background())
+
+		require.NoError(t, buffer.UpdateGauge("temperature", 20))
+
+		assert.Eventually(t, func() bool { return len(backend.received()) == 1 }, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("errors reported", func(t *testing.T) {
+		client, backend := newBufferedClient(t)
+		backend.setFail(true)
+
+		errs := make(chan error, 1)
+		buffer, err := client.NewBuffer(&BufferConfig{
+			FlushInterval: 10 * time.Millisecond,
+			MaxSize:       100,
+			OnError: func(err error) {
+				select {
+				case errs <- err:
+				default:
+				}
+			},
+		})
+		require.NoError(t, err)
+		defer buffer.Close(context.Background())
+
+		require.NoError(t, buffer.UpdateGauge("temperature", 20))
+
+		select {
+		case err := <-errs:
+			var statusErr *StatusError
+			assert.ErrorAs(t, err, &statusErr)
+		case <-time.After(time.Second):
+			t.Fatal("background flush error should be reported")
+		}
+	})
+}
+
+func TestBuffer_Close(t *testing.T) {
+	client, backend := newBufferedClient(t)
+	buffer, err := client.NewBuffer(nil)
+	require.NoError(t, err)
+
+	require.NoError(t, buffer.AddCounter("requests", 1))
+	require.NoError(t, buffer.Close(context.Background()))
+
+	require.Len(t, backend.received(), 1, "Close should flush remaining metrics")
+	assert.ErrorIs(t, buffer.AddCounter("requests", 1), ErrBufferClosed)
+	assert.ErrorIs(t, buffer.UpdateGauge("temperature", 1), ErrBufferClosed)
+	assert.NoError(t, buffer.Close(context.Background()), "Repeated Close should do nothing")
+}
+
+func TestClient_NewBuffer_InvalidConfig(t *testing.T) {
+	client, _ := newBufferedClient(t)
+
+	_, err := client.NewBuffer(&BufferConfig{FlushInterval: 0, MaxSize: 1})
+	assert.Error(t, err)
+
+	_, err = client.NewBuffer(&BufferConfig{FlushInterval: time.Second, MaxSize: 0})
+	assert.Error(t, err)
+}
@@ -0,0 +1,354 @@
+// Package metricalclient клиент HTTP API сервера метрик: запись gauge и counter метрик
+// по одной и пакетами, чтение значений и буферизованная фоновая отправка.
+package metricalclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/compression"
+	"github.com/IgorKilipenko/metrical/internal/encryption"
+	"github.com/IgorKilipenko/metrical/internal/signature"
+	"github.com/IgorKilipenko/metrical/pkg/models"
+)
+
+// IdempotencyKeyHeader заголовок с ключом идемпотентности записи.
+// Повторы одного запроса передают тот же ключ, и сервер не применяет приращения дважды.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RealIPHeader заголовок с IP адресом клиента, по которому сервер проверяет доверенную подсеть
+const RealIPHeader = "X-Real-IP"
+
+// maxErrorBodySize максимальный размер тела ответа с ошибкой, сохраняемого в StatusError
+const maxErrorBodySize = 1024
+
+// idempotencyKeyContextKey ключ контекста с ключом идемпотентности, заданным вызывающим
+type idempotencyKeyContextKey struct{}
+
+// WithIdempotencyKey возвращает контекст, в котором запросы записи передают заданный ключ
+// идемпотентности вместо нового. После неоднозначной ошибки (сервер мог применить запись,
+// но ответ не получен) те же метрики нужно отправить с тем же ключом, иначе сервер
+// применит приращения counter повторно.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// IdempotencyKeyFromContext возвращает ключ идемпотентности, заданный WithIdempotencyKey
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyContextKey{}).(string)
+	return key, ok && key != ""
+}
+
+// Client клиент HTTP API сервера метрик; безопасен для использования из нескольких горутин
+type Client struct {
+	config     Config
+	baseURL    string
+	httpClient HTTPClient
+	sleepFunc  func(context.Context, time.Duration) error // Ожидание между попытками (подменяется в тестах)
+}
+
+// New создает клиента с заданными настройками (nil - настройки по умолчанию)
+func New(config *Config) (*Client, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid client configuration: %w", err)
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config.TLSConfig
+		httpClient = &http.Client{Timeout: config.Timeout, Transport: transport}
+	}
+
+	return &Client{
+		config:     *config,
+		baseURL:    config.baseURL(),
+		httpClient: httpClient,
+	}, nil
+}
+
+// UpdateGauge устанавливает значение gauge метрики
+func (c *Client) UpdateGauge(ctx context.Context, name string, value float64) error {
+	return c.Update(ctx, models.NewGauge(name, value))
+}
+
+// AddCounter увеличивает counter метрику на delta
+func (c *Client) AddCounter(ctx context.Context, name string, delta int64) error {
+	return c.Update(ctx, models.NewCounter(name, delta))
+}
+
+// Update отправляет одну метрику (POST /update)
+func (c *Client) Update(ctx context.Context, metric models.Metrics) error {
+	if err := validateMetric(&metric); err != nil {
+		return err
+	}
+	c.signMetric(&metric)
+
+	if err := c.post(ctx, "/update", metric, true, nil); err != nil {
+		return fmt.Errorf("failed to update metric %s: %w", metric.ID, err)
+	}
+	return nil
+}
+
+// UpdateBatch отправляет метрики одним запросом (POST /updates); пустой пакет не отправляется
+func (c *Client) UpdateBatch(ctx context.Context, metrics []models.Metrics) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	batch := make([]models.Metrics, len(metrics))
+	for i := range metrics {
+		batch[i] = metrics[i]
+		if err := validateMetric(&batch[i]); err != nil {
+			return err
+		}
+		c.signMetric(&batch[i])
+	}
+
+	if err := c.post(ctx, "/updates", batch, true, nil); err != nil {
+		return fmt.Errorf("failed to send metrics batch: %w", err)
+	}
+	return nil
+}
+
+// Get возвращает метрику по типу и имени (POST /value).
+// Если метрика не найдена, ошибка соответствует ErrNotFound.
+func (c *Client) Get(ctx context.Context, metricType, name string) (*models.Metrics, error) {
+	request := models.Metrics{ID: name, MType: metricType}
+	if err := validateMetricID(&request); err != nil {
+		return nil, err
+	}
+
+	var metric models.Metrics
+	if err := c.post(ctx, "/value", request, false, &metric); err != nil {
+		return nil, fmt.Errorf("failed to get metric %s: %w", name, err)
+	}
+	return &metric, nil
+}
+
+// validateMetricID проверяет имя и тип метрики
+func validateMetricID(metric *models.Metrics) error {
+	if metric.ID == "" {
+		return fmt.Errorf("metric name cannot be empty")
+	}
+	if metric.MType != models.Gauge && metric.MType != models.Counter {
+		return fmt.Errorf("unknown metric type %q for %s", metric.MType, metric.ID)
+	}
+	return nil
+}
+
+// validateMetric проверяет, что метрика содержит значение своего типа
+func validateMetric(metric *models.Metrics) error {
+	if err := validateMetricID(metric); err != nil {
+		return err
+	}
+	if metric.MType == models.Gauge && metric.Value == nil {
+		return fmt.Errorf("gauge metric %s has no value", metric.ID)
+	}
+	if metric.MType == models.Counter && metric.Delta == nil {
+		return fmt.Errorf("counter metric %s has no delta", metric.ID)
+	}
+	return nil
+}
+
+// signMetric заполняет поле Hash метрики, если задан ключ подписи
+func (c *Client) signMetric(metric *models.Metrics) {
+	if c.config.Key != "" {
+		signature.SignMetric(metric, c.config.Key)
+	}
+}
+
+// post отправляет сжатый JSON на указанный путь сервера и декодирует ответ в out (nil - ответ
+// не разбирается). Запросы записи (write) получают ключ идемпотентности, общий для всех попыток:
+// из контекста (WithIdempotencyKey) или новый.
+func (c *Client) post(ctx context.Context, path string, payload any, write bool, out any) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	body, err := c.encodeBody(jsonData)
+	if err != nil {
+		return err
+	}
+
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set("Content-Encoding", c.config.contentEncoding())
+	header.Set("Accept-Encoding", compression.AcceptEncoding(c.config.contentEncoding()))
+	if c.config.Encryptor != nil {
+		header.Set(encryption.HeaderName, encryption.Scheme)
+	}
+	if write {
+		idempotencyKey, ok := IdempotencyKeyFromContext(ctx)
+		if !ok {
+			idempotencyKey, err = NewIdempotencyKey()
+			if err != nil {
+				return fmt.Errorf("failed to generate idempotency key: %w", err)
+			}
+		}
+		header.Set(IdempotencyKeyHeader, idempotencyKey)
+	}
+	if c.config.Token != "" {
+		header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+	if c.config.Key != "" {
+		// Подписывается несжатое тело запроса
+		header.Set(signature.HeaderName, signature.Sign(jsonData, c.config.Key))
+	}
+
+	requestURL := c.baseURL + path
+	if c.config.SendRealIP {
+		if serverURL, err := url.Parse(requestURL); err == nil {
+			if ip, err := OutboundIP(serverURL); err == nil {
+				header.Set(RealIPHeader, ip.String())
+			}
+		}
+	}
+
+	resp, err := c.doWithRetry(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header = header.Clone()
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := readBody(resp)
+	if err != nil {
+		return err
+	}
+
+	if c.config.Key != "" {
+		if err := c.verifyResponse(resp, data); err != nil {
+			return fmt.Errorf("failed to verify server response: %w", err)
+		}
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// encodeBody сжимает JSON кодировкой из настроек и шифрует результат, если задан Encryptor
+func (c *Client) encodeBody(jsonData []byte) ([]byte, error) {
+	body, err := compression.Compress(c.config.contentEncoding(), jsonData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress request: %w", err)
+	}
+
+	if c.config.Encryptor != nil {
+		if body, err = c.config.Encryptor.Encrypt(body); err != nil {
+			return nil, fmt.Errorf("failed to encrypt request: %w", err)
+		}
+	}
+	return body, nil
+}
+
+// verifyResponse проверяет подпись несжатого тела ответа сервера
+func (c *Client) verifyResponse(resp *http.Response, body []byte) error {
+	hash := resp.Header.Get(signature.HeaderName)
+	if hash == "" {
+		return fmt.Errorf("response is not signed")
+	}
+	if !signature.Verify(body, c.config.Key, hash) {
+		return fmt.Errorf("response signature mismatch")
+	}
+	return nil
+}
+
+// readBody читает тело ответа, распаковывая его по заголовку Content-Encoding
+func readBody(resp *http.Response) ([]byte, error) {
+	var reader io.Reader = resp.Body
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "" && encoding != compression.Identity {
+		decompressor, err := compression.NewReader(encoding, resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s reader: %w", encoding, err)
+		}
+		defer decompressor.Close()
+		reader = decompressor
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return body, nil
+}
+
+// readErrorBody читает начало тела ответа с ошибкой для диагностики (не более maxErrorBodySize байт).
+// При ошибке чтения возвращается прочитанная часть.
+func readErrorBody(resp *http.Response) []byte {
+	var reader io.Reader = resp.Body
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "" && encoding != compression.Identity {
+		if decompressor, err := compression.NewReader(encoding, resp.Body); err == nil {
+			defer decompressor.Close()
+			reader = decompressor
+		}
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(reader, maxErrorBodySize))
+	return body
+}
+
+// NewIdempotencyKey генерирует случайный ключ идемпотентности
+func NewIdempotencyKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// OutboundIP возвращает IP адрес интерфейса, через который клиент обращается к серверу.
+// UDP "соединение" только выбирает маршрут и не отправляет пакетов.
+func OutboundIP(serverURL *url.URL) (net.IP, error) {
+	port := serverURL.Port()
+	if port == "" {
+		port = "80"
+		if serverURL.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	conn, err := net.Dial("udp", net.JoinHostPort(serverURL.Hostname(), port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve route to server: %w", err)
+	}
+	defer conn.Close()
+
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return nil, fmt.Errorf("unexpected local address type %T", conn.LocalAddr())
+	}
+	return addr.IP, nil
+}
+
+// LoadEncryptor загружает публичный ключ сервера из PEM файла для шифрования тел запросов
+func LoadEncryptor(publicKeyPath string) (Encryptor, error) {
+	encryptor, err := encryption.NewEncryptorFromFile(publicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load public key: %w", err)
+	}
+	return encryptor, nil
+}
@@ -0,0 +1,250 @@
+package metricalclient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/IgorKilipenko/metrical/internal/auth"
+	"github.com/IgorKilipenko/metrical/internal/compression"
+	"github.com/IgorKilipenko/metrical/internal/encryption"
+	"github.com/IgorKilipenko/metrical/internal/handler"
+	"github.com/IgorKilipenko/metrical/internal/middleware"
+	"github.com/IgorKilipenko/metrical/internal/repository"
+	"github.com/IgorKilipenko/metrical/internal/routes"
+	"github.com/IgorKilipenko/metrical/internal/service"
+	"github.com/IgorKilipenko/metrical/internal/signature"
+	"github.com/IgorKilipenko/metrical/internal/testutils"
+	"github.com/IgorKilipenko/metrical/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMetricsServer запускает сервер метрик с подписью, аутентификацией по токенам и ключами идемпотентности
+func newMetricsServer(t *testing.T, key string, store *auth.Store) *httptest.Server {
+	t.Helper()
+
+	mockLogger := testutils.NewMockLogger()
+	metricsService := service.NewMetricsService(repository.NewInMemoryMetricsRepository(mockLogger, "", false), mockLogger)
+	metricsHandler, err := handler.NewMetricsHandler(metricsService, mockLogger)
+	require.NoError(t, err)
+	require.NoError(t, metricsHandler.EnableIdempotency(handler.DefaultIdempotencyConfig()))
+
+	config := routes.DefaultConfig()
+	config.SigningKey = key
+	config.Auth = store
+
+	server := httptest.NewServer(routes.SetupMetricsRoutesWithConfig(metricsHandler, config))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_Server(t *testing.T) {
+	store, err := auth.NewStore([]auth.Token{{Name: "service", Token: "rw-token", Scope: auth.ScopeAdmin}})
+	require.NoError(t, err)
+	server := newMetricsServer(t, "secret", store)
+
+	for _, enc := range compression.Encodings() {
+		t.Run(enc, func(t *testing.T) {
+			config := DefaultConfig()
+			config.ServerURL = server.URL
+			config.Key = "secret"
+			config.Token = "rw-token"
+			config.Compression = enc
+			client, err := New(config)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			name := "requests_" + enc
+			require.NoError(t, client.UpdateGauge(ctx, "temperature", 21.5))
+			require.NoError(t, client.AddCounter(ctx, name, 2))
+			require.NoError(t, client.UpdateBatch(ctx, []models.Metrics{
+				models.NewCounter(name, 3),
+				models.NewGauge("temperature", 22.5),
+			}))
+
+			gauge, err := client.Get(ctx, models.Gauge, "temperature")
+			require.NoError(t, err)
+			require.NotNil(t, gauge.Value)
+			assert.Equal(t, 22.5, *gauge.Value)
+
+			counter, err := client.Get(ctx, models.Counter, name)
+			require.NoError(t, err)
+			require.NotNil(t, counter.Delta)
+			assert.Equal(t, int64(5), *counter.Delta)
+
+			_, err = client.Get(ctx, models.Gauge, "missing")
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
+
+func TestClient_Server_Rejected(t *testing.T) {
+	store, err := auth.NewStore([]auth.Token{{Name: "service", Token: "rw-token", Scope: auth.ScopeAdmin}})
+	require.NoError(t, err)
+	server := newMetricsServer(t, "secret", store)
+
+	tests := []struct {
+		name           string
+		key            string
+		token          string
+		expectedStatus int
+	}{
+		{name: "without token", key: "secret", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong signing key", key: "other", token: "rw-token", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.ServerURL = server.URL
+			config.Key = tt.key
+			config.Token = tt.token
+			client, err := New(config)
+			require.NoError(t, err)
+
+			err = client.UpdateGauge(context.Background(), "temperature", 1)
+
+			var statusErr *StatusError
+			require.ErrorAs(t, err, &statusErr)
+			assert.Equal(t, tt.expectedStatus, statusErr.StatusCode)
+		})
+	}
+}
+
+func TestClient_Headers(t *testing.T) {
+	privateKeyPath, publicKeyPath := testutils.WriteTestRSAKeys(t)
+	decryptor, err := encryption.NewDecryptorFromFile(privateKeyPath)
+	require.NoError(t, err)
+	encryptor, err := LoadEncryptor(publicKeyPath)
+	require.NoError(t, err)
+
+	var (
+		mu       sync.Mutex
+		headers  http.Header
+		received []models.Metrics
+	)
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	})
+	stack := middleware.DecryptMiddleware(decryptor, 0)(middleware.CompressionMiddleware(nil)(inner))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = r.Header.Clone()
+		mu.Unlock()
+		stack.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	// Адрес без схемы: без TLSConfig используется http
+	config.ServerURL = strings.TrimPrefix(server.URL, "http://")
+	config.Token = "token"
+	config.Compression = compression.Zstd
+	config.Encryptor = encryptor
+	config.SendRealIP = true
+	client, err := New(config)
+	require.NoError(t, err)
+
+	require.NoError(t, client.UpdateBatch(context.Background(), []models.Metrics{models.NewGauge("Alloc", 1.5)}))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 1, "Server should decrypt and decode the batch")
+	assert.Equal(t, "Alloc", received[0].ID)
+	assert.Empty(t, received[0].Hash, "Metrics are not signed without key")
+
+	assert.Equal(t, "application/json", headers.Get("Content-Type"))
+	assert.Equal(t, compression.Zstd, headers.Get("Content-Encoding"))
+	assert.True(t, strings.HasPrefix(headers.Get("Accept-Encoding"), compression.Zstd))
+	assert.Equal(t, encryption.Scheme, headers.Get(encryption.HeaderName))
+	assert.Equal(t, "Bearer token", headers.Get("Authorization"))
+	assert.Equal(t, "127.0.0.1", headers.Get(RealIPHeader))
+	assert.NotEmpty(t, headers.Get(IdempotencyKeyHeader))
+	assert.Empty(t, headers.Get(signature.HeaderName))
+}
+
+func TestClient_Signing(t *testing.T) {
+	var received []models.Metrics
+	server := httptest.NewServer(middleware.CompressionMiddleware(nil)(middleware.SignatureMiddleware("secret")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			w.Write([]byte(`{"status":"ok"}`))
+		}))))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.ServerURL = server.URL
+	config.Key = "secret"
+	client, err := New(config)
+	require.NoError(t, err)
+
+	metrics := []models.Metrics{models.NewGauge("Alloc", 1.5), models.NewCounter("PollCount", 2)}
+	require.NoError(t, client.UpdateBatch(context.Background(), metrics))
+
+	require.Len(t, received, 2)
+	for _, metric := range received {
+		assert.True(t, signature.VerifyMetric(&metric, "secret"), "Metric %s should carry a valid hash", metric.ID)
+	}
+	for _, metric := range metrics {
+		assert.Empty(t, metric.Hash, "Caller's metrics should not be modified")
+	}
+}
+
+func TestClient_verifyResponse(t *testing.T) {
+	client := &Client{config: Config{Key: "secret"}}
+
+	newResponse := func(hash string) *http.Response {
+		resp := &http.Response{Header: make(http.Header), Body: io.NopCloser(strings.NewReader(""))}
+		if hash != "" {
+			resp.Header.Set(signature.HeaderName, hash)
+		}
+		return resp
+	}
+
+	assert.NoError(t, client.verifyResponse(newResponse(signature.Sign([]byte("ok"), "secret")), []byte("ok")))
+	assert.Error(t, client.verifyResponse(newResponse(""), []byte("ok")), "Unsigned response should be rejected")
+	assert.Error(t, client.verifyResponse(newResponse(signature.Sign([]byte("ok"), "secret")), []byte("tampered")))
+}
+
+func TestClient_encodeBody(t *testing.T) {
+	data := []byte(`[{"id":"Alloc","type":"gauge","value":42.5}]`)
+
+	for _, enc := range compression.Encodings() {
+		t.Run(enc, func(t *testing.T) {
+			client, err := New(&Config{ServerURL: "localhost:8080", Compression: enc, MaxAttempts: 1})
+			require.NoError(t, err)
+
+			body, err := client.encodeBody(data)
+			require.NoError(t, err)
+
+			decoded, err := compression.Decompress(enc, body)
+			require.NoError(t, err)
+			assert.Equal(t, data, decoded)
+		})
+	}
+}
+
+func TestClient_InvalidMetrics(t *testing.T) {
+	mock := &mockHTTPClient{}
+	client := newMockClient(t, mock, nil)
+	ctx := context.Background()
+
+	assert.Error(t, client.UpdateGauge(ctx, "", 1))
+	assert.Error(t, client.Update(ctx, models.Metrics{ID: "Alloc", MType: "histogram"}))
+	assert.Error(t, client.Update(ctx, models.Metrics{ID: "Alloc", MType: models.Gauge}), "Gauge without value")
+	assert.Error(t, client.UpdateBatch(ctx, []models.Metrics{{ID: "PollCount", MType: models.Counter}}), "Counter without delta")
+	_, err := client.Get(ctx, "histogram", "Alloc")
+	assert.Error(t, err)
+
+	assert.NoError(t, client.UpdateBatch(ctx, nil), "Empty batch is not sent")
+	assert.Empty(t, mock.requests, "Invalid metrics should not be sent")
+}
@@ -0,0 +1,140 @@
+package metricalclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/compression"
+)
+
+// Значения конфигурации по умолчанию
+const (
+	DefaultServerURL     = "http://localhost:8080"
+	DefaultCompression   = compression.Gzip
+	DefaultTimeout       = 10 * time.Second
+	DefaultMaxAttempts   = 3
+	DefaultRetryDelay    = 100 * time.Millisecond
+	DefaultMaxRetryDelay = 5 * time.Second
+	DefaultMaxRetryAfter = 30 * time.Second
+)
+
+// HTTPClient выполняет HTTP запросы (*http.Client или обертка над ним)
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Encryptor шифрует сжатое тело запроса публичным ключом сервера (см. LoadEncryptor)
+type Encryptor interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+}
+
+// Config настройки клиента
+type Config struct {
+	// ServerURL - адрес сервера: http(s)://host:port или host:port
+	// (без схемы используется https при заданном TLSConfig, иначе http)
+	ServerURL string
+
+	// Key - общий с сервером ключ подписи HMAC-SHA256 (пустая строка - подпись отключена)
+	Key string
+
+	// Token - API токен (пустая строка - без заголовка Authorization)
+	Token string
+
+	// Compression - кодировка тел запросов: gzip, deflate или zstd (пустая строка - gzip)
+	Compression string
+
+	// Timeout - таймаут одной попытки запроса (используется, если HTTPClient не задан)
+	Timeout time.Duration
+
+	// TLSConfig - настройки TLS соединения (используются, если HTTPClient не задан)
+	TLSConfig *tls.Config
+
+	// HTTPClient - HTTP клиент для запросов (nil - http.Client с Timeout и TLSConfig)
+	HTTPClient HTTPClient
+
+	// MaxAttempts - максимальное число попыток запроса, включая первую (1 - без повторов)
+	MaxAttempts int
+
+	// RetryDelay - пауза перед первым повтором; каждая следующая пауза удваивается
+	RetryDelay time.Duration
+
+	// MaxRetryDelay - максимальная пауза между попытками (0 - без ограничения)
+	MaxRetryDelay time.Duration
+
+	// MaxRetryAfter - максимальная пауза по заголовку Retry-After; если сервер просит ждать
+	// дольше, запрос не повторяется (0 - без ограничения)
+	MaxRetryAfter time.Duration
+
+	// Encryptor - шифрование тел запросов публичным ключом сервера (nil - отключено)
+	Encryptor Encryptor
+
+	// SendRealIP - передавать в заголовке X-Real-IP адрес интерфейса, через который клиент
+	// обращается к серверу (для проверки доверенной подсети)
+	SendRealIP bool
+}
+
+// DefaultConfig возвращает настройки клиента по умолчанию
+func DefaultConfig() *Config {
+	return &Config{
+		ServerURL:     DefaultServerURL,
+		Compression:   DefaultCompression,
+		Timeout:       DefaultTimeout,
+		MaxAttempts:   DefaultMaxAttempts,
+		RetryDelay:    DefaultRetryDelay,
+		MaxRetryDelay: DefaultMaxRetryDelay,
+		MaxRetryAfter: DefaultMaxRetryAfter,
+	}
+}
+
+// Validate проверяет корректность настроек
+func (c *Config) Validate() error {
+	if c.ServerURL == "" {
+		return fmt.Errorf("server URL cannot be empty")
+	}
+
+	if _, err := url.Parse(c.baseURL()); err != nil {
+		return fmt.Errorf("invalid server URL: %w", err)
+	}
+
+	if c.Compression != "" && !compression.Supported(c.Compression) {
+		return fmt.Errorf("unsupported compression %q: expected one of %s", c.Compression, strings.Join(compression.Encodings(), ", "))
+	}
+
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+
+	if c.MaxAttempts < 1 {
+		return fmt.Errorf("max attempts must be positive")
+	}
+
+	if c.RetryDelay < 0 || c.MaxRetryDelay < 0 || c.MaxRetryAfter < 0 {
+		return fmt.Errorf("retry delays cannot be negative")
+	}
+
+	return nil
+}
+
+// baseURL возвращает адрес сервера со схемой и без завершающего слэша
+func (c *Config) baseURL() string {
+	base := strings.TrimSuffix(c.ServerURL, "/")
+	if strings.HasPrefix(base, "http://") || strings.HasPrefix(base, "https://") {
+		return base
+	}
+	if c.TLSConfig != nil {
+		return "https://" + base
+	}
+	return "http://" + base
+}
+
+// contentEncoding возвращает кодировку тел запросов (по умолчанию gzip)
+func (c *Config) contentEncoding() string {
+	if c.Compression == "" {
+		return DefaultCompression
+	}
+	return compression.Normalize(c.Compression)
+}
@@ -0,0 +1,66 @@
+package metricalclient
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(*Config)
+		expectedErr string // Пустая строка - настройки корректны
+	}{
+		{name: "default config", modify: func(*Config) {}},
+		{name: "empty server URL", modify: func(c *Config) { c.ServerURL = "" }, expectedErr: "server URL cannot be empty"},
+		{name: "invalid server URL", modify: func(c *Config) { c.ServerURL = "http://local host:%zz" }, expectedErr: "invalid server URL"},
+		{name: "unsupported compression", modify: func(c *Config) { c.Compression = "br" }, expectedErr: "unsupported compression"},
+		{name: "empty compression", modify: func(c *Config) { c.Compression = "" }},
+		{name: "negative timeout", modify: func(c *Config) { c.Timeout = -time.Second }, expectedErr: "timeout cannot be negative"},
+		{name: "zero attempts", modify: func(c *Config) { c.MaxAttempts = 0 }, expectedErr: "max attempts must be positive"},
+		{name: "negative retry delay", modify: func(c *Config) { c.RetryDelay = -time.Second }, expectedErr: "retry delays cannot be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			tt.modify(config)
+
+			err := config.Validate()
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedErr)
+
+			_, err = New(config)
+			assert.Error(t, err, "New should reject invalid configuration")
+		})
+	}
+}
+
+func TestConfig_baseURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		serverURL string
+		tlsConfig *tls.Config
+		expected  string
+	}{
+		{name: "without scheme", serverURL: "localhost:8080", expected: "http://localhost:8080"},
+		{name: "without scheme with TLS", serverURL: "localhost:8080", tlsConfig: &tls.Config{}, expected: "https://localhost:8080"},
+		{name: "explicit scheme", serverURL: "https://metrics.example.com/", expected: "https://metrics.example.com"},
+		{name: "explicit http with TLS", serverURL: "http://localhost:8080", tlsConfig: &tls.Config{}, expected: "http://localhost:8080"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{ServerURL: tt.serverURL, TLSConfig: tt.tlsConfig}
+			assert.Equal(t, tt.expected, config.baseURL())
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package metricalclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/IgorKilipenko/metrical/internal/problem"
+	"github.com/IgorKilipenko/metrical/pkg/models"
+)
+
+// Problem описание ошибки сервера в формате application/problem+json (RFC 7807)
+type Problem = models.Problem
+
+// FieldError описание ошибки отдельного поля запроса в Problem.Errors
+type FieldError = models.FieldError
+
+// ErrNotFound метрика не найдена (сервер ответил 404); проверяется через errors.Is
+var ErrNotFound = errors.New("metric not found")
+
+// StatusError ответ сервера с кодом ошибки
+type StatusError struct {
+	StatusCode int
+	Body       string
+	Problem    *Problem // Описание ошибки, если сервер ответил application/problem+json
+}
+
+func (e *StatusError) Error() string {
+	if e.Problem != nil {
+		return fmt.Sprintf("server returned status %d: %s (%s)", e.StatusCode, e.Problem.Error(), e.Problem.Type)
+	}
+	return fmt.Sprintf("server returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// Is сообщает, что ответ 404 соответствует ErrNotFound
+func (e *StatusError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// IsRejected сообщает, что сервер отклонил запрос ответом 4xx и не применил его.
+// Такие метрики можно отправить заново с новым ключом идемпотентности. После остальных ошибок
+// (сетевые ошибки, таймауты, 5xx, неверная подпись ответа) сервер мог применить запись,
+// и повторять ее нужно с тем же ключом (WithIdempotencyKey).
+func IsRejected(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500
+}
+
+// newStatusError создает ошибку по коду и телу ответа, разбирая problem+json
+func newStatusError(resp *http.Response, body []byte) *StatusError {
+	statusErr := &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	if p, ok := problem.Parse(resp.Header.Get("Content-Type"), body); ok {
+		statusErr.Problem = p
+	}
+	return statusErr
+}
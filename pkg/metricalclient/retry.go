@@ -0,0 +1,143 @@
+package metricalclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// doWithRetry выполняет запрос, повторяя его при сетевых ошибках, 5xx и 429.
+// newRequest создает новый запрос с полной копией тела для каждой попытки.
+// Успешный ответ (2xx) возвращается открытым; остальные ответы закрываются и
+// возвращаются как *StatusError (после исчерпания попыток - обернутым).
+func (c *Client) doWithRetry(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		last := attempt >= c.config.MaxAttempts
+
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if last {
+				return nil, fmt.Errorf("failed after %d attempts: %w", attempt, err)
+			}
+			if err := c.sleep(ctx, c.backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		// Тело ответа читается для диагностики
+		statusErr := newStatusError(resp, readErrorBody(resp))
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			// Превышен лимит запросов - повторяем после паузы, указанной сервером
+			if last {
+				return nil, fmt.Errorf("rate limited after %d attempts: %w", attempt, statusErr)
+			}
+			if err := c.waitRetryAfter(ctx, resp, attempt); err != nil {
+				return nil, err
+			}
+		case resp.StatusCode >= 500 && resp.StatusCode < 600:
+			// Серверные ошибки повторяются, для 503 учитывается Retry-After
+			if last {
+				return nil, fmt.Errorf("server error after %d attempts: %w", attempt, statusErr)
+			}
+			if resp.StatusCode == http.StatusServiceUnavailable {
+				err = c.waitRetryAfter(ctx, resp, attempt)
+			} else {
+				err = c.sleep(ctx, c.backoff(attempt))
+			}
+			if err != nil {
+				return nil, err
+			}
+		default:
+			// Клиентские ошибки (4xx) и другие статусы не требуют повтора
+			return nil, statusErr
+		}
+	}
+}
+
+// backoff возвращает паузу после неудачной попытки attempt: RetryDelay, удваиваемая
+// с каждой попыткой и ограниченная MaxRetryDelay
+func (c *Client) backoff(attempt int) time.Duration {
+	maxDelay := c.config.MaxRetryDelay
+	delay := c.config.RetryDelay
+	for i := 1; i < attempt && (maxDelay == 0 || delay < maxDelay); i++ {
+		delay *= 2
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// waitRetryAfter ждет время из заголовка Retry-After (при его отсутствии - паузу backoff).
+// Если сервер просит ждать дольше MaxRetryAfter, возвращает ошибку без ожидания.
+func (c *Client) waitRetryAfter(ctx context.Context, resp *http.Response, attempt int) error {
+	delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return c.sleep(ctx, c.backoff(attempt))
+	}
+
+	if c.config.MaxRetryAfter > 0 && delay > c.config.MaxRetryAfter {
+		return fmt.Errorf("server requested retry after %s (status %d), exceeding limit %s", delay, resp.StatusCode, c.config.MaxRetryAfter)
+	}
+	return c.sleep(ctx, delay)
+}
+
+// sleep приостанавливает выполнение между попытками; отмена контекста прерывает ожидание
+func (c *Client) sleep(ctx context.Context, d time.Duration) error {
+	if c.sleepFunc != nil {
+		return c.sleepFunc(ctx, d)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRetryAfter разбирает заголовок Retry-After: количество секунд или HTTP-дату (RFC 9110)
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
@@ -0,0 +1,362 @@
+package metricalclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IgorKilipenko/metrical/internal/compression"
+	"github.com/IgorKilipenko/metrical/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockHTTPClient мок HTTPClient: возвращает заданные ответы и сохраняет запросы с телами
+type mockHTTPClient struct {
+	requests  []*http.Request
+	bodies    [][]byte
+	responses []*http.Response
+	errors    []error
+}
+
+func (m *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	m.requests = append(m.requests, req)
+	m.bodies = append(m.bodies, body)
+
+	i := len(m.requests) - 1
+	if i >= len(m.responses) {
+		return nil, errors.New("no more responses configured")
+	}
+	return m.responses[i], m.errors[i]
+}
+
+// newTestResponse создает тестовый HTTP ответ
+func newTestResponse(statusCode int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+// newMockClient создает клиента с моком: 2 попытки, пауза 1ms, ожидания записываются в delays
+func newMockClient(t *testing.T, mock *mockHTTPClient, delays *[]time.Duration) *Client {
+	t.Helper()
+
+	config := DefaultConfig()
+	config.HTTPClient = mock
+	config.MaxAttempts = 2
+	config.RetryDelay = time.Millisecond
+	client, err := New(config)
+	require.NoError(t, err)
+
+	client.sleepFunc = func(_ context.Context, d time.Duration) error {
+		if delays != nil {
+			*delays = append(*delays, d)
+		}
+		return nil
+	}
+	return client
+}
+
+func TestClient_Retry(t *testing.T) {
+	networkErr := errors.New("network error")
+
+	tests := []struct {
+		name          string
+		responses     []*http.Response
+		errors        []error
+		expectedCalls int
+		expectedError string // Пустая строка - запрос успешен
+	}{
+		{
+			name:          "success",
+			responses:     []*http.Response{newTestResponse(http.StatusOK, "ok")},
+			errors:        []error{nil},
+			expectedCalls: 1,
+		},
+		{
+			name:          "retry on 5xx",
+			responses:     []*http.Response{newTestResponse(http.StatusInternalServerError, "server error"), newTestResponse(http.StatusOK, "ok")},
+			errors:        []error{nil, nil},
+			expectedCalls: 2,
+		},
+		{
+			name:          "no retry on 4xx",
+			responses:     []*http.Response{newTestResponse(http.StatusNotFound, "not found")},
+			errors:        []error{nil},
+			expectedCalls: 1,
+			expectedError: "server returned status 404: not found",
+		},
+		{
+			name:          "max attempts exceeded",
+			responses:     []*http.Response{newTestResponse(http.StatusInternalServerError, "server error"), newTestResponse(http.StatusInternalServerError, "server error")},
+			errors:        []error{nil, nil},
+			expectedCalls: 2,
+			expectedError: "server error after 2 attempts: server returned status 500",
+		},
+		{
+			name:          "network error",
+			responses:     []*http.Response{nil, nil},
+			errors:        []error{networkErr, networkErr},
+			expectedCalls: 2,
+			expectedError: "failed after 2 attempts: network error",
+		},
+		{
+			name:          "rate limited",
+			responses:     []*http.Response{newTestResponse(http.StatusTooManyRequests, "slow down"), newTestResponse(http.StatusTooManyRequests, "slow down")},
+			errors:        []error{nil, nil},
+			expectedCalls: 2,
+			expectedError: "rate limited after 2 attempts",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockHTTPClient{responses: tt.responses, errors: tt.errors}
+			client := newMockClient(t, mock, nil)
+
+			err := client.UpdateGauge(context.Background(), "Alloc", 1.5)
+
+			assert.Len(t, mock.requests, tt.expectedCalls)
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedError)
+		})
+	}
+}
+
+func TestClient_Retry_StatusError(t *testing.T) {
+	resp := newTestResponse(http.StatusBadRequest,
+		`{"type":"urn:metrical:problem:validation","title":"Validation failed","status":400,"detail":"delta is required","errors":[{"field":"[1].delta","value":"null","message":"is required"}]}`)
+	resp.Header.Set("Content-Type", problem.ContentType)
+
+	mock := &mockHTTPClient{responses: []*http.Response{resp}, errors: []error{nil}}
+	err := newMockClient(t, mock, nil).AddCounter(context.Background(), "PollCount", 1)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+	require.NotNil(t, statusErr.Problem, "Problem details should be parsed from response")
+	assert.Equal(t, problem.TypeValidation, statusErr.Problem.Type)
+	require.Len(t, statusErr.Problem.Errors, 1)
+	assert.Equal(t, "[1].delta", statusErr.Problem.Errors[0].Field)
+	assert.Contains(t, err.Error(), "delta is required")
+	assert.False(t, errors.Is(err, ErrNotFound))
+
+	// Ответ 404 соответствует ErrNotFound
+	mock = &mockHTTPClient{responses: []*http.Response{newTestResponse(http.StatusNotFound, "")}, errors: []error{nil}}
+	_, err = newMockClient(t, mock, nil).Get(context.Background(), "gauge", "Alloc")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestClient_Retry_RetryAfter(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		retryAfter    string
+		expectedDelay time.Duration
+	}{
+		{
+			name:          "429 with seconds",
+			statusCode:    http.StatusTooManyRequests,
+			retryAfter:    "3",
+			expectedDelay: 3 * time.Second,
+		},
+		{
+			name:          "503 with seconds",
+			statusCode:    http.StatusServiceUnavailable,
+			retryAfter:    "2",
+			expectedDelay: 2 * time.Second,
+		},
+		{
+			name:          "429 without header",
+			statusCode:    http.StatusTooManyRequests,
+			expectedDelay: time.Millisecond,
+		},
+		{
+			name:          "500 ignores header",
+			statusCode:    http.StatusInternalServerError,
+			retryAfter:    "5",
+			expectedDelay: time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limited := newTestResponse(tt.statusCode, "slow down")
+			if tt.retryAfter != "" {
+				limited.Header.Set("Retry-After", tt.retryAfter)
+			}
+
+			mock := &mockHTTPClient{responses: []*http.Response{limited, newTestResponse(http.StatusOK, "ok")}, errors: []error{nil, nil}}
+			var delays []time.Duration
+			client := newMockClient(t, mock, &delays)
+
+			require.NoError(t, client.UpdateGauge(context.Background(), "Alloc", 1.5))
+			assert.Len(t, mock.requests, 2)
+			assert.Equal(t, []time.Duration{tt.expectedDelay}, delays)
+		})
+	}
+}
+
+func TestClient_Retry_RetryAfterExceedsLimit(t *testing.T) {
+	limited := newTestResponse(http.StatusTooManyRequests, "slow down")
+	limited.Header.Set("Retry-After", "3600")
+
+	mock := &mockHTTPClient{responses: []*http.Response{limited}, errors: []error{nil}}
+	client := newMockClient(t, mock, nil)
+	client.sleepFunc = func(_ context.Context, d time.Duration) error {
+		t.Fatalf("client should not wait %s", d)
+		return nil
+	}
+
+	err := client.UpdateGauge(context.Background(), "Alloc", 1.5)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "retry after 1h0m0s")
+	assert.Len(t, mock.requests, 1)
+}
+
+func TestClient_Retry_ResendsBody(t *testing.T) {
+	mock := &mockHTTPClient{
+		responses: []*http.Response{newTestResponse(http.StatusBadGateway, "bad gateway"), newTestResponse(http.StatusOK, "ok")},
+		errors:    []error{nil, nil},
+	}
+	client := newMockClient(t, mock, nil)
+
+	require.NoError(t, client.AddCounter(context.Background(), "PollCount", 5))
+	require.Len(t, mock.requests, 2)
+
+	// Каждая попытка отправляет полное тело и тот же ключ идемпотентности
+	key := mock.requests[0].Header.Get(IdempotencyKeyHeader)
+	assert.NotEmpty(t, key)
+	for i, req := range mock.requests {
+		body, err := compression.Decompress(compression.Gzip, mock.bodies[i])
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"PollCount","type":"counter","delta":5}`, string(body), "attempt %d", i+1)
+		assert.Equal(t, key, req.Header.Get(IdempotencyKeyHeader))
+	}
+}
+
+func TestClient_Retry_ContextCanceled(t *testing.T) {
+	mock := &mockHTTPClient{
+		responses: []*http.Response{newTestResponse(http.StatusInternalServerError, "server error"), newTestResponse(http.StatusOK, "ok")},
+		errors:    []error{nil, nil},
+	}
+	client := newMockClient(t, mock, nil)
+	client.sleepFunc = nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client.config.RetryDelay = time.Hour
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	err := client.UpdateGauge(ctx, "Alloc", 1.5)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, mock.requests, 1, "Canceled context should interrupt waiting between attempts")
+}
+
+func TestClient_backoff(t *testing.T) {
+	client := &Client{config: Config{RetryDelay: 100 * time.Millisecond, MaxRetryDelay: time.Second}}
+
+	assert.Equal(t, 100*time.Millisecond, client.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, client.backoff(2))
+	assert.Equal(t, 400*time.Millisecond, client.backoff(3))
+	assert.Equal(t, 800*time.Millisecond, client.backoff(4))
+	assert.Equal(t, time.Second, client.backoff(5), "Delay should be limited by MaxRetryDelay")
+	assert.Equal(t, time.Second, client.backoff(100))
+
+	client.config.MaxRetryDelay = 0
+	assert.Equal(t, 1600*time.Millisecond, client.backoff(5), "Zero MaxRetryDelay disables the limit")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value         string
+		expectedDelay time.Duration
+		expectedOK    bool
+	}{
+		{value: "", expectedOK: false},
+		{value: "0", expectedDelay: 0, expectedOK: true},
+		{value: " 120 ", expectedDelay: 2 * time.Minute, expectedOK: true},
+		{value: "-1", expectedOK: false},
+		{value: "soon", expectedOK: false},
+		{value: now.Add(90 * time.Second).Format(http.TimeFormat), expectedDelay: 90 * time.Second, expectedOK: true},
+		{value: now.Add(-time.Minute).Format(http.TimeFormat), expectedDelay: 0, expectedOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			delay, ok := parseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedDelay, delay)
+		})
+	}
+}
+
+// failingReader возвращает данные вместе с ошибкой чтения
+type failingReader struct {
+	io.Reader
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, _ := r.Reader.Read(p)
+	return n, r.err
+}
+
+func TestReadErrorBody(t *testing.T) {
+	compressed, err := compression.Compress(compression.Gzip, []byte("compressed error"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     io.Reader
+		expected string
+	}{
+		{
+			name:     "plain body",
+			body:     strings.NewReader("response body"),
+			expected: "response body",
+		},
+		{
+			name:     "compressed body",
+			encoding: compression.Gzip,
+			body:     strings.NewReader(string(compressed)),
+			expected: "compressed error",
+		},
+		{
+			name:     "read error keeps partial body",
+			body:     &failingReader{Reader: strings.NewReader("response body"), err: errors.New("read failed")},
+			expected: "response body",
+		},
+		{
+			name:     "long body is truncated",
+			body:     strings.NewReader(strings.Repeat("x", 2*maxErrorBodySize)),
+			expected: strings.Repeat("x", maxErrorBodySize),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: make(http.Header), Body: io.NopCloser(tt.body)}
+			if tt.encoding != "" {
+				resp.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			assert.Equal(t, tt.expected, string(readErrorBody(resp)))
+		})
+	}
+}
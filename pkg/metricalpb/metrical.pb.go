@@ -0,0 +1,743 @@
+// Контракт gRPC API сервера метрик.
+//
+// Код в pkg/metricalpb генерируется командой:
+//   protoc --go_out=. --go_opt=module=github.com/IgorKilipenko/metrical \
+//          --go-grpc_out=. --go-grpc_opt=module=github.com/IgorKilipenko/metrical \
+//          api/metrical.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: api/metrical.proto
+
+package metricalpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// MetricType тип метрики
+type MetricType int32
+
+const (
+	MetricType_METRIC_TYPE_UNSPECIFIED MetricType = 0
+	MetricType_METRIC_TYPE_GAUGE       MetricType = 1
+	MetricType_METRIC_TYPE_COUNTER     MetricType = 2
+)
+
+// Enum value maps for MetricType.
+var (
+	MetricType_name = map[int32]string{
+		0: "METRIC_TYPE_UNSPECIFIED",
+		1: "METRIC_TYPE_GAUGE",
+		2: "METRIC_TYPE_COUNTER",
+	}
+	MetricType_value = map[string]int32{
+		"METRIC_TYPE_UNSPECIFIED": 0,
+		"METRIC_TYPE_GAUGE":       1,
+		"METRIC_TYPE_COUNTER":     2,
+	}
+)
+
+func (x MetricType) Enum() *MetricType {
+	p := new(MetricType)
+	*p = x
+	return p
+}
+
+func (x MetricType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MetricType) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_metrical_proto_enumTypes[0].Descriptor()
+}
+
+func (MetricType) Type() protoreflect.EnumType {
+	return &file_api_metrical_proto_enumTypes[0]
+}
+
+func (x MetricType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MetricType.Descriptor instead.
+func (MetricType) EnumDescriptor() ([]byte, []int) {
+	return file_api_metrical_proto_rawDescGZIP(), []int{0}
+}
+
+// Metric метрика; для gauge задается value, для counter - delta
+type Metric struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type  MetricType             `protobuf:"varint,2,opt,name=type,proto3,enum=metrical.v1.MetricType" json:"type,omitempty"`
+	Delta *int64                 `protobuf:"varint,3,opt,name=delta,proto3,oneof" json:"delta,omitempty"`
+	Value *float64               `protobuf:"fixed64,4,opt,name=value,proto3,oneof" json:"value,omitempty"`
+	// Подпись HMAC-SHA256 строки "id:type:value" в hex (как поле hash JSON API)
+	Hash          string `protobuf:"bytes,5,opt,name=hash,proto3" json:"hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Metric) Reset() {
+	*x = Metric{}
+	mi := &file_api_metrical_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metric) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metric) ProtoMessage() {}
+
+func (x *Metric) ProtoReflect() protoreflect.Message {
+	mi := &file_api_metrical_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metric.ProtoReflect.Descriptor instead.
+func (*Metric) Descriptor() ([]byte, []int) {
+	return file_api_metrical_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Metric) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Metric) GetType() MetricType {
+	if x != nil {
+		return x.Type
+	}
+	return MetricType_METRIC_TYPE_UNSPECIFIED
+}
+
+func (x *Metric) GetDelta() int64 {
+	if x != nil && x.Delta != nil {
+		return *x.Delta
+	}
+	return 0
+}
+
+func (x *Metric) GetValue() float64 {
+	if x != nil && x.Value != nil {
+		return *x.Value
+	}
+	return 0
+}
+
+func (x *Metric) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+type UpdateMetricRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metric        *Metric                `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMetricRequest) Reset() {
+	*x = UpdateMetricRequest{}
+	mi := &file_api_metrical_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMetricRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMetricRequest) ProtoMessage() {}
+
+func (x *UpdateMetricRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_metrical_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMetricRequest.ProtoReflect.Descriptor instead.
+func (*UpdateMetricRequest) Descriptor() ([]byte, []int) {
+	return file_api_metrical_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *UpdateMetricRequest) GetMetric() *Metric {
+	if x != nil {
+		return x.Metric
+	}
+	return nil
+}
+
+type UpdateMetricResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metric        *Metric                `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMetricResponse) Reset() {
+	*x = UpdateMetricResponse{}
+	mi := &file_api_metrical_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMetricResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMetricResponse) ProtoMessage() {}
+
+func (x *UpdateMetricResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_metrical_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMetricResponse.ProtoReflect.Descriptor instead.
+func (*UpdateMetricResponse) Descriptor() ([]byte, []int) {
+	return file_api_metrical_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *UpdateMetricResponse) GetMetric() *Metric {
+	if x != nil {
+		return x.Metric
+	}
+	return nil
+}
+
+type UpdateMetricsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       []*Metric              `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMetricsRequest) Reset() {
+	*x = UpdateMetricsRequest{}
+	mi := &file_api_metrical_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMetricsRequest) ProtoMessage() {}
+
+func (x *UpdateMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_metrical_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMetricsRequest.ProtoReflect.Descriptor instead.
+func (*UpdateMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_api_metrical_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UpdateMetricsRequest) GetMetrics() []*Metric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type UpdateMetricsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Updated       int64                  `protobuf:"varint,1,opt,name=updated,proto3" json:"updated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMetricsResponse) Reset() {
+	*x = UpdateMetricsResponse{}
+	mi := &file_api_metrical_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMetricsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMetricsResponse) ProtoMessage() {}
+
+func (x *UpdateMetricsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_metrical_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMetricsResponse.ProtoReflect.Descriptor instead.
+func (*UpdateMetricsResponse) Descriptor() ([]byte, []int) {
+	return file_api_metrical_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UpdateMetricsResponse) GetUpdated() int64 {
+	if x != nil {
+		return x.Updated
+	}
+	return 0
+}
+
+type GetMetricRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          MetricType             `protobuf:"varint,2,opt,name=type,proto3,enum=metrical.v1.MetricType" json:"type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetricRequest) Reset() {
+	*x = GetMetricRequest{}
+	mi := &file_api_metrical_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetricRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetricRequest) ProtoMessage() {}
+
+func (x *GetMetricRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_metrical_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetricRequest.ProtoReflect.Descriptor instead.
+func (*GetMetricRequest) Descriptor() ([]byte, []int) {
+	return file_api_metrical_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetMetricRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetMetricRequest) GetType() MetricType {
+	if x != nil {
+		return x.Type
+	}
+	return MetricType_METRIC_TYPE_UNSPECIFIED
+}
+
+type GetMetricResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metric        *Metric                `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetricResponse) Reset() {
+	*x = GetMetricResponse{}
+	mi := &file_api_metrical_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetricResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetricResponse) ProtoMessage() {}
+
+func (x *GetMetricResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_metrical_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetricResponse.ProtoReflect.Descriptor instead.
+func (*GetMetricResponse) Descriptor() ([]byte, []int) {
+	return file_api_metrical_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetMetricResponse) GetMetric() *Metric {
+	if x != nil {
+		return x.Metric
+	}
+	return nil
+}
+
+type ListMetricsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Тип метрик (METRIC_TYPE_UNSPECIFIED - все типы)
+	Type          MetricType `protobuf:"varint,1,opt,name=type,proto3,enum=metrical.v1.MetricType" json:"type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMetricsRequest) Reset() {
+	*x = ListMetricsRequest{}
+	mi := &file_api_metrical_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMetricsRequest) ProtoMessage() {}
+
+func (x *ListMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_metrical_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMetricsRequest.ProtoReflect.Descriptor instead.
+func (*ListMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_api_metrical_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListMetricsRequest) GetType() MetricType {
+	if x != nil {
+		return x.Type
+	}
+	return MetricType_METRIC_TYPE_UNSPECIFIED
+}
+
+type ListMetricsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       []*Metric              `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMetricsResponse) Reset() {
+	*x = ListMetricsResponse{}
+	mi := &file_api_metrical_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMetricsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMetricsResponse) ProtoMessage() {}
+
+func (x *ListMetricsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_metrical_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMetricsResponse.ProtoReflect.Descriptor instead.
+func (*ListMetricsResponse) Descriptor() ([]byte, []int) {
+	return file_api_metrical_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListMetricsResponse) GetMetrics() []*Metric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type PushMetricsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       []*Metric              `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushMetricsRequest) Reset() {
+	*x = PushMetricsRequest{}
+	mi := &file_api_metrical_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushMetricsRequest) ProtoMessage() {}
+
+func (x *PushMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_metrical_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushMetricsRequest.ProtoReflect.Descriptor instead.
+func (*PushMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_api_metrical_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *PushMetricsRequest) GetMetrics() []*Metric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type PushMetricsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Получено пакетов и записано метрик
+	Batches       int64 `protobuf:"varint,1,opt,name=batches,proto3" json:"batches,omitempty"`
+	Updated       int64 `protobuf:"varint,2,opt,name=updated,proto3" json:"updated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PushMetricsResponse) Reset() {
+	*x = PushMetricsResponse{}
+	mi := &file_api_metrical_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PushMetricsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PushMetricsResponse) ProtoMessage() {}
+
+func (x *PushMetricsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_metrical_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PushMetricsResponse.ProtoReflect.Descriptor instead.
+func (*PushMetricsResponse) Descriptor() ([]byte, []int) {
+	return file_api_metrical_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PushMetricsResponse) GetBatches() int64 {
+	if x != nil {
+		return x.Batches
+	}
+	return 0
+}
+
+func (x *PushMetricsResponse) GetUpdated() int64 {
+	if x != nil {
+		return x.Updated
+	}
+	return 0
+}
+
+var File_api_metrical_proto protoreflect.FileDescriptor
+
+const file_api_metrical_proto_rawDesc = "" +
+	"\n" +
+	"\x12api/metrical.proto\x12\vmetrical.v1\"\xa3\x01\n" +
+	"\x06Metric\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12+\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x17.metrical.v1.MetricTypeR\x04type\x12\x19\n" +
+	"\x05delta\x18\x03 \x01(\x03H\x00R\x05delta\x88\x01\x01\x12\x19\n" +
+	"\x05value\x18\x04 \x01(\x01H\x01R\x05value\x88\x01\x01\x12\x12\n" +
+	"\x04hash\x18\x05 \x01(\tR\x04hashB\b\n" +
+	"\x06_deltaB\b\n" +
+	"\x06_value\"B\n" +
+	"\x13UpdateMetricRequest\x12+\n" +
+	"\x06metric\x18\x01 \x01(\v2\x13.metrical.v1.MetricR\x06metric\"C\n" +
+	"\x14UpdateMetricResponse\x12+\n" +
+	"\x06metric\x18\x01 \x01(\v2\x13.metrical.v1.MetricR\x06metric\"E\n" +
+	"\x14UpdateMetricsRequest\x12-\n" +
+	"\ametrics\x18\x01 \x03(\v2\x13.metrical.v1.MetricR\ametrics\"1\n" +
+	"\x15UpdateMetricsResponse\x12\x18\n" +
+	"\aupdated\x18\x01 \x01(\x03R\aupdated\"O\n" +
+	"\x10GetMetricRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12+\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x17.metrical.v1.MetricTypeR\x04type\"@\n" +
+	"\x11GetMetricResponse\x12+\n" +
+	"\x06metric\x18\x01 \x01(\v2\x13.metrical.v1.MetricR\x06metric\"A\n" +
+	"\x12ListMetricsRequest\x12+\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x17.metrical.v1.MetricTypeR\x04type\"D\n" +
+	"\x13ListMetricsResponse\x12-\n" +
+	"\ametrics\x18\x01 \x03(\v2\x13.metrical.v1.MetricR\ametrics\"C\n" +
+	"\x12PushMetricsRequest\x12-\n" +
+	"\ametrics\x18\x01 \x03(\v2\x13.metrical.v1.MetricR\ametrics\"I\n" +
+	"\x13PushMetricsResponse\x12\x18\n" +
+	"\abatches\x18\x01 \x01(\x03R\abatches\x12\x18\n" +
+	"\aupdated\x18\x02 \x01(\x03R\aupdated*Y\n" +
+	"\n" +
+	"MetricType\x12\x1b\n" +
+	"\x17METRIC_TYPE_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11METRIC_TYPE_GAUGE\x10\x01\x12\x17\n" +
+	"\x13METRIC_TYPE_COUNTER\x10\x022\xaf\x03\n" +
+	"\x0eMetricsService\x12S\n" +
+	"\fUpdateMetric\x12 .metrical.v1.UpdateMetricRequest\x1a!.metrical.v1.UpdateMetricResponse\x12V\n" +
+	"\rUpdateMetrics\x12!.metrical.v1.UpdateMetricsRequest\x1a\".metrical.v1.UpdateMetricsResponse\x12J\n" +
+	"\tGetMetric\x12\x1d.metrical.v1.GetMetricRequest\x1a\x1e.metrical.v1.GetMetricResponse\x12P\n" +
+	"\vListMetrics\x12\x1f.metrical.v1.ListMetricsRequest\x1a .metrical.v1.ListMetricsResponse\x12R\n" +
+	"\vPushMetrics\x12\x1f.metrical.v1.PushMetricsRequest\x1a .metrical.v1.PushMetricsResponse(\x01B=Z;github.com/IgorKilipenko/metrical/pkg/metricalpb;metricalpbb\x06proto3"
+
+var (
+	file_api_metrical_proto_rawDescOnce sync.Once
+	file_api_metrical_proto_rawDescData []byte
+)
+
+func file_api_metrical_proto_rawDescGZIP() []byte {
+	file_api_metrical_proto_rawDescOnce.Do(func() {
+		file_api_metrical_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_metrical_proto_rawDesc), len(file_api_metrical_proto_rawDesc)))
+	})
+	return file_api_metrical_proto_rawDescData
+}
+
+var file_api_metrical_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_metrical_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_api_metrical_proto_goTypes = []any{
+	(MetricType)(0),               // 0: metrical.v1.MetricType
+	(*Metric)(nil),                // 1: metrical.v1.Metric
+	(*UpdateMetricRequest)(nil),   // 2: metrical.v1.UpdateMetricRequest
+	(*UpdateMetricResponse)(nil),  // 3: metrical.v1.UpdateMetricResponse
+	(*UpdateMetricsRequest)(nil),  // 4: metrical.v1.UpdateMetricsRequest
+	(*UpdateMetricsResponse)(nil), // 5: metrical.v1.UpdateMetricsResponse
+	(*GetMetricRequest)(nil),      // 6: metrical.v1.GetMetricRequest
+	(*GetMetricResponse)(nil),     // 7: metrical.v1.GetMetricResponse
+	(*ListMetricsRequest)(nil),    // 8: metrical.v1.ListMetricsRequest
+	(*ListMetricsResponse)(nil),   // 9: metrical.v1.ListMetricsResponse
+	(*PushMetricsRequest)(nil),    // 10: metrical.v1.PushMetricsRequest
+	(*PushMetricsResponse)(nil),   // 11: metrical.v1.PushMetricsResponse
+}
+var file_api_metrical_proto_depIdxs = []int32{
+	0,  // 0: metrical.v1.Metric.type:type_name -> metrical.v1.MetricType
+	1,  // 1: metrical.v1.UpdateMetricRequest.metric:type_name -> metrical.v1.Metric
+	1,  // 2: metrical.v1.UpdateMetricResponse.metric:type_name -> metrical.v1.Metric
+	1,  // 3: metrical.v1.UpdateMetricsRequest.metrics:type_name -> metrical.v1.Metric
+	0,  // 4: metrical.v1.GetMetricRequest.type:type_name -> metrical.v1.MetricType
+	1,  // 5: metrical.v1.GetMetricResponse.metric:type_name -> metrical.v1.Metric
+	0,  // 6: metrical.v1.ListMetricsRequest.type:type_name -> metrical.v1.MetricType
+	1,  // 7: metrical.v1.ListMetricsResponse.metrics:type_name -> metrical.v1.Metric
+	1,  // 8: metrical.v1.PushMetricsRequest.metrics:type_name -> metrical.v1.Metric
+	2,  // 9: metrical.v1.MetricsService.UpdateMetric:input_type -> metrical.v1.UpdateMetricRequest
+	4,  // 10: metrical.v1.MetricsService.UpdateMetrics:input_type -> metrical.v1.UpdateMetricsRequest
+	6,  // 11: metrical.v1.MetricsService.GetMetric:input_type -> metrical.v1.GetMetricRequest
+	8,  // 12: metrical.v1.MetricsService.ListMetrics:input_type -> metrical.v1.ListMetricsRequest
+	10, // 13: metrical.v1.MetricsService.PushMetrics:input_type -> metrical.v1.PushMetricsRequest
+	3,  // 14: metrical.v1.MetricsService.UpdateMetric:output_type -> metrical.v1.UpdateMetricResponse
+	5,  // 15: metrical.v1.MetricsService.UpdateMetrics:output_type -> metrical.v1.UpdateMetricsResponse
+	7,  // 16: metrical.v1.MetricsService.GetMetric:output_type -> metrical.v1.GetMetricResponse
+	9,  // 17: metrical.v1.MetricsService.ListMetrics:output_type -> metrical.v1.ListMetricsResponse
+	11, // 18: metrical.v1.MetricsService.PushMetrics:output_type -> metrical.v1.PushMetricsResponse
+	14, // [14:19] is the sub-list for method output_type
+	9,  // [9:14] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_api_metrical_proto_init() }
+func file_api_metrical_proto_init() {
+	if File_api_metrical_proto != nil {
+		return
+	}
+	file_api_metrical_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_metrical_proto_rawDesc), len(file_api_metrical_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_metrical_proto_goTypes,
+		DependencyIndexes: file_api_metrical_proto_depIdxs,
+		EnumInfos:         file_api_metrical_proto_enumTypes,
+		MessageInfos:      file_api_metrical_proto_msgTypes,
+	}.Build()
+	File_api_metrical_proto = out.File
+	file_api_metrical_proto_goTypes = nil
+	file_api_metrical_proto_depIdxs = nil
+}
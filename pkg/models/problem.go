@@ -0,0 +1,35 @@
+package models
+
+// Типы ошибок API (поле Problem.Type). Клиенты различают ошибки по типу, а не по тексту detail.
+const (
+	ProblemTypeDefault            = "about:blank"
+	ProblemTypeValidation         = "urn:metrical:problem:validation"
+	ProblemTypeUnsupportedType    = "urn:metrical:problem:unsupported-metric-type"
+	ProblemTypeNotFound           = "urn:metrical:problem:not-found"
+	ProblemTypeStorageUnavailable = "urn:metrical:problem:storage-unavailable"
+)
+
+// FieldError описание ошибки отдельного поля запроса в Problem.Errors
+type FieldError struct {
+	Field   string `json:"field"`
+	Value   string `json:"value"`
+	Message string `json:"message"`
+}
+
+// Problem описание ошибки в формате application/problem+json (RFC 7807)
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Error возвращает текст ошибки, поэтому Problem можно передавать как error
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}